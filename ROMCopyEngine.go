@@ -1,227 +1,5162 @@
 package main
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/jkingsman/ROMCopyEngine/archive"
+	"github.com/jkingsman/ROMCopyEngine/artwork"
 	"github.com/jkingsman/ROMCopyEngine/cli_parsing"
 	"github.com/jkingsman/ROMCopyEngine/copy_funcs"
+	"github.com/jkingsman/ROMCopyEngine/datfile"
+	"github.com/jkingsman/ROMCopyEngine/dedupe"
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/device_state"
+	"github.com/jkingsman/ROMCopyEngine/disc_refs"
+	"github.com/jkingsman/ROMCopyEngine/disk_info"
+	"github.com/jkingsman/ROMCopyEngine/examples"
 	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+	"github.com/jkingsman/ROMCopyEngine/gamelist"
+	"github.com/jkingsman/ROMCopyEngine/launchbox"
 	"github.com/jkingsman/ROMCopyEngine/logging"
+	"github.com/jkingsman/ROMCopyEngine/platforms"
+	"github.com/jkingsman/ROMCopyEngine/profiles"
+	"github.com/jkingsman/ROMCopyEngine/romtags"
+	"github.com/jkingsman/ROMCopyEngine/saves"
+	"github.com/jkingsman/ROMCopyEngine/screenscraper"
+	"github.com/jkingsman/ROMCopyEngine/thumbnails"
 )
 
-func summarizeWarnConfirm(config *cli_parsing.Config) {
-	cli_parsing.PrintCLIOpts(config)
-	fmt.Println()
+// everything resolved about a mapping before any files are touched
+type mappingPlan struct {
+	// correlation ID for logs, e.g. 'm2' for the second mapping
+	id         string
+	mapping    cli_parsing.DirMapping
+	sourcePath string
+	destPath   string
+	opts       copy_funcs.CopyOptions
+	files      []copy_funcs.ResolvedFile
+	// set when files didn't fit in '--maxIndexMemory' and was left empty; the source is re-scanned by eachFile
+	// instead, and fileCount and totalBytes hold its totals
+	unindexed  bool
+	fileCount  int
+	totalBytes int64
+	// how each file (by RelPath) compares against the loaded DATs; only files with an extension used in the
+	// DATs, and zips holding such files, are included, and it's nil if no DATs were loaded
+	datMatches map[string]datfile.Match
+	// why each file (by RelPath) is considered a bad dump; only flagged files are included
+	badDumps map[string]string
+	// files (by RelPath) whose extension doesn't belong to the mapping's platform that will still be copied
+	strays []string
+	// groups of duplicate files that will all be copied; empty with '--dedupe', which leaves all but one out
+	duplicates []dedupe.Group
+	// the '--launchboxData' library, and the games in it among the files to be copied, by slash separated path
+	// relative to the destination folder; nil without '--launchboxData'
+	launchbox      *launchbox.Library
+	launchboxGames map[string]launchbox.Game
+	// with '--artworkSource', where the chosen Skraper media was moved, by slash separated path relative to the
+	// source folder, and the Skraper media folders left out, relative to the destination folder
+	mediaMoved   map[string]string
+	mediaLeftOut []string
+	// with '--perGameFolders', the files moved into their games' folders, from and to slash separated paths
+	// relative to the destination folder, as they'd otherwise have been copied
+	gameFolders map[string]string
+	// with '--splitAlpha', the alphabetical folder each file or folder at the top of the destination folder is
+	// moved into, by its name as it'd otherwise have been copied
+	alphaSplit map[string]string
+	// with '--maxPathLength', the files whose names were shortened to fit, from and to slash separated paths
+	// relative to the destination folder, as they'd otherwise have been copied
+	shortened map[string]string
+	// the protected files in the destination folder before the copy, by slash separated path relative to it,
+	// which renames and rewrites leave alone; the run's own copies of protected files aren't among them
+	protected []string
+	// the batch in the target's trash the run moves what it removes into with '--useTrash', shared by every mapping
+	trashBatch string
+	// the '--scrape' client, shared by every mapping so a used up quota stops them all; nil without '--scrape'
+	scraper *screenscraper.Client
+	// the '--fetchThumbnails' fetcher, shared by every mapping so requests are spaced out across the run; nil
+	// without '--fetchThumbnails'
+	thumbnails *thumbnails.Fetcher
+}
 
-	if !config.SkipConfirm && !config.DryRun {
-		if config.CleanTarget {
-			logging.LogWarning("You have chosen to run with the '--cleanTarget' option enabled. This will delete all contents from the following directories before copying:")
-			for _, mapping := range config.Mappings {
-				logging.Log(logging.Action, "", "• %s", filepath.Join(strings.TrimRight(config.TargetDir, "/\\"), strings.TrimLeft(mapping.Destination, "/\\")))
-			}
-			fmt.Println()
+// leaves a file out of the plan's copy after its contents or name have been inspected
+func (p *mappingPlan) omit(relPath string) {
+	if p.opts.Omit == nil {
+		p.opts.Omit = make(map[string]bool)
+	}
+	p.opts.Omit[relPath] = true
+	delete(p.datMatches, relPath)
+	delete(p.badDumps, relPath)
+}
+
+// calls fn with every file the plan will copy, from its index, or by re-scanning the source if it has none
+func (p *mappingPlan) eachFile(fn func(copy_funcs.ResolvedFile) error) error {
+	if p.unindexed {
+		return copy_funcs.WalkFiles(p.sourcePath, p.opts, fn)
+	}
+	for _, f := range p.files {
+		if err := fn(f); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		fmt.Println("[Hint: you can rerun this with '--dryRun' to see all operations that would be performed without performing them, or use '--skipConfirm' to skip this confirmation]")
-		if cli_parsing.GetConfirmation("All files will be copied as summarized above. If file names conflict, they will be overwritten. Are you sure you want to proceed?") {
-			logging.Log(logging.Base, "", "Beginning copy...")
-		} else {
-			logging.Log(logging.Base, "", "Copy cancelled. No operations performed.")
-			os.Exit(1)
+// number and total size of the files the plan will copy
+func (p *mappingPlan) totals() (int, int64) {
+	if p.unindexed {
+		return p.fileCount, p.totalBytes
+	}
+	var bytes int64
+	for _, f := range p.files {
+		bytes += f.Size
+	}
+	return len(p.files), bytes
+}
+
+// where '--useTrash' moves what's removed from destPath, the plan's destination folder or its staging folder
+func (p *mappingPlan) trash(config *cli_parsing.Config) file_operations.Trash {
+	relDir, _ := filepath.Rel(config.TargetDir, p.destPath)
+	return file_operations.Trash{RootDir: config.TargetDir, Batch: p.trashBatch, RelDir: relDir}
+}
+
+// whether the plan copies files under names other than their own in the source, leaving the cue sheets and game
+// lists that name them to be pointed at their new names
+func (p *mappingPlan) renamesFiles() bool {
+	return p.opts.SafeNaming() || len(p.gameFolders) > 0 || len(p.alphaSplit) > 0 || len(p.shortened) > 0
+}
+
+// drops omitted files from the plan's resolved file list
+func (p *mappingPlan) dropOmitted() {
+	kept := make([]copy_funcs.ResolvedFile, 0, len(p.files))
+	for _, f := range p.files {
+		if !p.opts.Omit[f.RelPath] {
+			kept = append(kept, f)
 		}
-	} else {
-		logging.Log(logging.Base, "", "-y passed; skipping confirmation... Let's rock!")
-		fmt.Println()
 	}
+	p.files = kept
 }
 
-func explodeDirs(config *cli_parsing.Config, destPath string) error {
-	logging.Log(logging.Action, "", "Exploding directories...")
-	for _, explodeDir := range config.ExplodeDirs {
-		if config.DryRun {
-			logging.LogDryRun(logging.Detail, logging.IconExplode, "If located, would have exploded %s into %s", explodeDir, destPath)
-			continue
+// rough memory cost of each file in a plan's index beyond its path: the ResolvedFile itself plus slice growth
+const indexEntryOverhead = 48
+
+// resolves the file set of every mapping up front so pre-flight checks can inspect it. Once the files indexed
+// across all mappings would exceed '--maxIndexMemory', the remaining mappings are only counted.
+func buildPlans(config *cli_parsing.Config, stream file_operations.StreamOptions, library *launchbox.Library) ([]mappingPlan, error) {
+	plans := make([]mappingPlan, 0, len(config.Mappings))
+	var indexSize int64
+	for i, mapping := range config.Mappings {
+		sourcePath, destPath := mappingPaths(config, mapping)
+
+		opts, err := copyOptions(config, mapping)
+		if err != nil {
+			return nil, err
 		}
-		found, err := file_operations.ExplodeFolder(destPath, explodeDir)
-		if !found {
-			continue
+		if library != nil {
+			opts.RegionsOf = func(relPath string) []string {
+				game, _ := library.Find(filepath.Base(relPath))
+				return game.Regions()
+			}
+		}
+		opts.Stream = stream
+		if config.Verify {
+			// each mapping reports its own verification failures
+			if opts.Stream.Verify, err = file_operations.NewVerifyReport(config.Checksum); err != nil {
+				return nil, err
+			}
 		}
 
-		if err != nil {
-			return fmt.Errorf("error exploding directory: %w", err)
+		if config.Manifest {
+			if opts.Stream.Checksums, err = file_operations.NewChecksumRecorder(config.Checksum); err != nil {
+				return nil, err
+			}
 		}
 
-		logging.Log(logging.Detail, logging.IconExplode, "Exploded %s into %s", explodeDir, destPath)
-	}
+		plan := mappingPlan{
+			id:         fmt.Sprintf("m%d", i+1),
+			mapping:    mapping,
+			sourcePath: sourcePath,
+			destPath:   destPath,
+			opts:       opts,
+			files:      make([]copy_funcs.ResolvedFile, 0),
+		}
+		var planIndexSize int64
+		err = copy_funcs.WalkFiles(sourcePath, opts, func(f copy_funcs.ResolvedFile) error {
+			plan.fileCount++
+			plan.totalBytes += f.Size
+			if plan.unindexed {
+				return nil
+			}
 
-	logging.LogComplete("Exploding")
-	return nil
-}
+			planIndexSize += int64(len(f.RelPath)) + indexEntryOverhead
+			if config.MaxIndexMemory > 0 && indexSize+planIndexSize > config.MaxIndexMemory {
+				// later, smaller mappings may still fit in what's left
+				plan.unindexed = true
+				plan.files = nil
+				return nil
+			}
+			plan.files = append(plan.files, f)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan %s: %w", sourcePath, err)
+		}
+		if !plan.unindexed {
+			indexSize += planIndexSize
+		}
 
-func processRenames(config *cli_parsing.Config, destPath string) error {
-	logging.Log(logging.Action, "", "Processing renames...")
-	for _, r := range config.Renames {
-		if config.DryRun {
-			logging.LogDryRun(logging.Detail, logging.IconRename, "If located in %s, would have renamed %s to %s", destPath, r.OldName, r.NewName)
-			continue
+		if library != nil {
+			if err := matchLaunchBox(config, library, &plan); err != nil {
+				return nil, err
+			}
+		}
+		if config.ArtworkSource != "" {
+			if err := selectSkraperMedia(config, &plan); err != nil {
+				return nil, err
+			}
+		}
+		if config.PerGameFolders {
+			if err := layoutGameFolders(&plan); err != nil {
+				return nil, err
+			}
+		}
+		// after game folders, so each game's folder is moved as one
+		if len(config.AlphaBuckets) > 0 {
+			if err := splitAlphabetically(config, &plan); err != nil {
+				return nil, err
+			}
+		}
+		// last, so it sees every other rename
+		if config.MaxPathLength > 0 {
+			if err := shortenLongPaths(config, &plan); err != nil {
+				return nil, err
+			}
 		}
 
-		oldPath := filepath.Join(destPath, r.OldName)
-		newPath := filepath.Join(destPath, r.NewName)
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
 
-		_, err := os.Stat(oldPath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				logging.Log(logging.Detail, logging.IconSkip, "Unable to locate %s in %s; skipping", r.OldName, destPath)
-				continue
+// looks up the plan's files in the LaunchBox library by name, and with '--launchboxTitles', renames those it
+// knows to their titles. Names two files would share, on the target or in the LaunchBox titles, are left alone,
+// as are discs and tracks named by a playlist or cue sheet, which would no longer be found under a new name.
+func matchLaunchBox(config *cli_parsing.Config, library *launchbox.Library, plan *mappingPlan) error {
+	found := make(map[string]launchbox.Game)
+	referenced := make(map[string]bool)
+	taken := make(map[string]int)
+	err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+		relPath := filepath.ToSlash(f.RelPath)
+		taken[strings.ToLower(relPath)]++
+		if game, ok := library.Find(path.Base(relPath)); ok {
+			found[relPath] = game
+		}
+		if disc_refs.IsSheet(relPath) {
+			data, err := os.ReadFile(filepath.Join(plan.sourcePath, f.RelPath))
+			if err != nil {
+				return err
+			}
+			for _, ref := range disc_refs.References(relPath, data) {
+				if refPath, ok := device_paths.Resolve(path.Dir(relPath), ref); ok {
+					referenced[strings.ToLower(refPath)] = true
+				}
 			}
-			return fmt.Errorf("error renaming item: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+	}
 
-		if err := os.Rename(oldPath, newPath); err != nil {
-			return fmt.Errorf("error renaming item: %w", err)
+	renamed := make(map[string]string)
+	if config.LaunchBoxTitles {
+		for relPath, game := range found {
+			newRelPath := path.Join(path.Dir(relPath), game.FileName(path.Ext(relPath)))
+			if newRelPath != relPath && !referenced[strings.ToLower(relPath)] {
+				renamed[relPath] = newRelPath
+				taken[strings.ToLower(newRelPath)]++
+			}
+		}
+		kept := 0
+		for relPath, newRelPath := range renamed {
+			if taken[strings.ToLower(newRelPath)] > 1 {
+				delete(renamed, relPath)
+				kept++
+			}
+		}
+		if kept > 0 {
+			logging.LogWarning("%d game(s) in %s keep their names, since their LaunchBox titles are shared with other files", kept, plan.mapping.Source)
 		}
-
-		logging.Log(logging.Detail, logging.IconRename, "Renamed %s to %s", r.OldName, r.NewName)
 	}
 
-	logging.LogComplete("Renames")
+	plan.launchbox = library
+	plan.launchboxGames = make(map[string]launchbox.Game, len(found))
+	plan.opts.Renamed = make(map[string]string, len(renamed))
+	for relPath, game := range found {
+		if newRelPath, ok := renamed[relPath]; ok {
+			plan.opts.Renamed[filepath.FromSlash(relPath)] = filepath.FromSlash(newRelPath)
+			relPath = newRelPath
+		}
+		plan.launchboxGames[relPath] = game
+	}
+	logging.Log(logging.Action, "", "%s: %d file(s) found in LaunchBox, %d to be copied under their titles", plan.mapping.Source, len(found), len(renamed))
 	return nil
 }
 
-func processRewrites(config *cli_parsing.Config, destPath string) error {
-	logging.Log(logging.Action, "", "Processing rewrites...")
-	for _, r := range config.FileRewrites {
-		if config.DryRun {
-			rewriteType := "literal"
-			if config.RewritesAreRegex {
-				rewriteType = "regex"
-			}
-			logging.LogDryRun(logging.Detail, logging.IconRewrite, "If files found matching glob '%s' located in %s, would have rewritten %s to %s via %s search", r.FileGlob, destPath, r.SearchPattern, r.ReplacePattern, rewriteType)
-			continue
-		}
-
-		found, err := file_operations.SearchAndReplace(destPath, r.FileGlob, r.SearchPattern, r.ReplacePattern, config.RewritesAreRegex)
+// moves each game at the top of the plan's destination folder into a folder of its own, along with its tracks,
+// discs, artwork, and manuals, by renaming them as they're copied
+func layoutGameFolders(plan *mappingPlan) error {
+	platform := platforms.ForMapping(plan.mapping.Source, plan.mapping.Destination)
+	if platform == nil {
+		logging.LogWarning("Can't tell which platform %s -> %s holds from its folder names, so which files are games; its games won't be put in folders of their own", plan.mapping.Source, plan.mapping.Destination)
+		return nil
+	}
 
-		if !found {
-			logging.Log(logging.Detail, logging.IconSkip, "No files matching glob '%s' in %s for rewrite of %s to %s; skipping...", r.FileGlob, destPath, r.SearchPattern, r.ReplacePattern)
-			continue
+	// the paths files would otherwise have been copied to, and the files they're copied from
+	befores := make([]string, 0)
+	sources := make(map[string]string)
+	err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+		before := filepath.ToSlash(f.RelPath)
+		if renamed, ok := plan.opts.Renamed[f.RelPath]; ok {
+			before = filepath.ToSlash(renamed)
 		}
+		befores = append(befores, before)
+		sources[before] = f.RelPath
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+	}
 
+	var readErr error
+	refs := func(sheet string) []string {
+		data, err := os.ReadFile(filepath.Join(plan.sourcePath, sources[sheet]))
 		if err != nil {
-			return fmt.Errorf("error rewriting %s to %s for glob %s: %w", r.SearchPattern, r.ReplacePattern, r.FileGlob, err)
+			readErr = err
+			return nil
 		}
+		return disc_refs.References(sheet, data)
 	}
-	logging.LogComplete("Rewrites")
+	moved := copy_funcs.GameFolders(befores, platform.IsGame, refs)
+	if readErr != nil {
+		return fmt.Errorf("unable to read %s: %w", plan.sourcePath, readErr)
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+
+	if plan.opts.Renamed == nil {
+		plan.opts.Renamed = make(map[string]string)
+	}
+	plan.gameFolders = moved
+	folders := make(map[string]bool)
+	for before, after := range moved {
+		plan.opts.Renamed[sources[before]] = filepath.FromSlash(after)
+		folders[path.Dir(after)] = true
+		if game, ok := plan.launchboxGames[before]; ok {
+			delete(plan.launchboxGames, before)
+			plan.launchboxGames[after] = game
+		}
+	}
+	logging.Log(logging.Action, "", "%s: %d file(s) to be copied into folders of their own for %d game(s)", plan.mapping.Source, len(moved), len(folders))
 	return nil
 }
 
-func processMapping(config *cli_parsing.Config, mapping cli_parsing.DirMapping) error {
-	sourcePath := filepath.Join(strings.TrimRight(config.SourceDir, "/\\"), strings.TrimLeft(mapping.Source, "/\\"))
-	destPath := filepath.Join(strings.TrimRight(config.TargetDir, "/\\"), strings.TrimLeft(mapping.Destination, "/\\"))
-
-	logging.Log(logging.Base, "", "Beginning operations for \033[1;34m%s -> %s\033[0m (%s -> %s)",
-		mapping.Source, mapping.Destination, sourcePath, destPath)
+// moves each file and folder at the top of the plan's destination folder into the '--splitAlpha' folder for its
+// first letter, by renaming what's copied into it. Hidden files and folders, game lists, and artwork and video
+// folders stay at the top, as do folders already named for a bucket, and anything whose new path is taken.
+func splitAlphabetically(config *cli_parsing.Config, plan *mappingPlan) error {
+	buckets := map[string]bool{copy_funcs.OtherBucket: true}
+	for _, bucket := range config.AlphaBuckets {
+		buckets[strings.ToLower(bucket.Name())] = true
+	}
+	artworkTop := strings.SplitN(filepath.ToSlash(filepath.Clean(config.ArtworkDir)), "/", 2)[0]
 
-	// Clean target directory if requested
-	if config.CleanTarget {
-		if err := cleanTargetDir(config, destPath); err != nil {
-			return err
+	// the paths files would otherwise have been copied to, by the files they're copied from
+	befores := make(map[string]string)
+	taken := make(map[string]bool)
+	err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+		before := filepath.ToSlash(f.RelPath)
+		if renamed, ok := plan.opts.Renamed[f.RelPath]; ok {
+			before = filepath.ToSlash(renamed)
 		}
+		befores[f.RelPath] = before
+		taken[strings.ToLower(before)] = true
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
 	}
 
-	// Copy files
-	logging.Log(logging.Action, "", "Beginning copy...")
-	filesCopied, err := copy_funcs.CopyFiles(sourcePath, destPath, config.CopyInclude, config.CopyExclude, config.DryRun)
-	if err != nil {
-		return fmt.Errorf("error copying files: %w", err)
+	// the files to move under each name at the top, which move together or not at all
+	tops := make(map[string][]string)
+	for relPath, before := range befores {
+		top, _, inFolder := strings.Cut(before, "/")
+		// only the top folder counts, so a game folder's own artwork goes with it
+		if strings.HasPrefix(top, ".") || buckets[strings.ToLower(top)] || strings.EqualFold(top, artworkTop) ||
+			(!inFolder && gamelist.IsListName(top)) || (inFolder && platforms.InMediaFolder(top+"/"+path.Base(before))) {
+			continue
+		}
+		tops[top] = append(tops[top], relPath)
 	}
-	logging.LogComplete("Copy")
 
-	if config.LoopbackCopy && len(filesCopied) > 0 {
-		logging.Log(logging.Action, "", "Beginning re-glob-and-copy-matches [ignoring excludes!!!]...")
-		globifiedFileList := copy_funcs.GlobifyFilenameOfPathList(filesCopied)
+	if plan.opts.Renamed == nil {
+		plan.opts.Renamed = make(map[string]string)
+	}
+	plan.alphaSplit = make(map[string]string)
+	kept := 0
+	for top, relPaths := range tops {
+		bucket := copy_funcs.AlphaFolder(top, config.AlphaBuckets)
+		free := true
+		for _, relPath := range relPaths {
+			free = free && !taken[strings.ToLower(path.Join(bucket, befores[relPath]))]
+		}
+		if !free {
+			kept++
+			continue
+		}
 
-		logging.Log(logging.Detail, logging.IconCopy, "Beginning loopback from %d glob(s): [%s]", len(filesCopied), strings.Join(globifiedFileList, ", "))
-		_, err := copy_funcs.CopyFiles(sourcePath, destPath, globifiedFileList, nil, config.DryRun)
-		if err != nil {
-			return fmt.Errorf("error copying files: %w", err)
+		plan.alphaSplit[top] = bucket
+		for _, relPath := range relPaths {
+			before := befores[relPath]
+			after := path.Join(bucket, before)
+			plan.opts.Renamed[relPath] = filepath.FromSlash(after)
+			if game, ok := plan.launchboxGames[before]; ok {
+				delete(plan.launchboxGames, before)
+				plan.launchboxGames[after] = game
+			}
 		}
-		logging.LogComplete("Re-glob-and-copy-matches")
 	}
 
-	// Post-copy operations
-	if err := runPostCopyOperations(config, destPath); err != nil {
-		return err
+	if kept > 0 {
+		logging.LogWarning("%d file(s) or folder(s) in %s stay at the top, since their alphabetical folders already have files of the same name", kept, plan.mapping.Source)
 	}
-
-	logging.Log(logging.Base, "", "Operations for %s -> %s complete!", mapping.Source, mapping.Destination)
+	logging.Log(logging.Action, "", "%s: %d file(s) or folder(s) to be copied into alphabetical folders", plan.mapping.Source, len(plan.alphaSplit))
 	return nil
 }
 
-func cleanTargetDir(config *cli_parsing.Config, destPath string) error {
-	if config.DryRun {
-		logging.LogDryRun(logging.Action, logging.IconClean, "Cleaning target directory...")
+// shortens the titles of the plan's files whose paths on the target would be longer than '--maxPathLength',
+// keeping their tags and extensions, so they're renamed as they're copied rather than failing to copy. A name
+// already taken gets a '~2', '~3', etc. after its title. With '--perGameFolders', a game's folder is shortened
+// along with the files named for it, so they still match. Files in folders too long to hold any name are warned
+// about instead.
+func shortenLongPaths(config *cli_parsing.Config, plan *mappingPlan) error {
+	destRoot := filepath.ToSlash(strings.Trim(plan.mapping.Destination, "/\\"))
+	tooLong := func(copied string) int {
+		return disk_info.PathLength(path.Join(destRoot, copied)) - config.MaxPathLength
+	}
+
+	taken := make(map[string]bool)
+	long := make([]string, 0)
+	err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+		copied := copiedPath(config, plan.opts, f.RelPath)
+		taken[strings.ToLower(copied)] = true
+		if tooLong(copied) > 0 {
+			long = append(long, f.RelPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+	}
+	if len(long) == 0 {
 		return nil
 	}
+	sort.Strings(long)
 
-	logging.Log(logging.Action, logging.IconClean, "Cleaning target directory...")
-	if err := file_operations.ClearDirectory(destPath); err != nil {
-		return fmt.Errorf("error cleaning target directory: %w", err)
+	if plan.opts.Renamed == nil {
+		plan.opts.Renamed = make(map[string]string)
+	}
+	plan.shortened = make(map[string]string)
+	shortened := make([]string, 0, len(long))
+	// the paths files shortened with their game's folder would otherwise have been copied to, by the files
+	shortenedFrom := make(map[string]string)
+	if len(plan.gameFolders) > 0 {
+		lines, err := shortenGameFolders(config, plan, tooLong, taken, shortenedFrom)
+		if err != nil {
+			return err
+		}
+		shortened = append(shortened, lines...)
+	}
+	unfixable := make([]string, 0)
+	for _, relPath := range long {
+		copied := copiedPath(config, plan.opts, relPath)
+		if tooLong(copied) <= 0 {
+			// fits now its game's folder is shorter
+			continue
+		}
+		name := ""
+		for n := 1; name == ""; n++ {
+			suffix := ""
+			if n > 1 {
+				suffix = fmt.Sprintf("~%d", n)
+			}
+			// a character cut may count twice, so cut until it fits
+			cut := tooLong(copied) + len(suffix)
+			candidate, ok := romtags.Shorten(path.Base(copied), cut, suffix)
+			for ok && tooLong(path.Join(path.Dir(copied), candidate)) > 0 {
+				cut += tooLong(path.Join(path.Dir(copied), candidate))
+				candidate, ok = romtags.Shorten(path.Base(copied), cut, suffix)
+			}
+			if !ok {
+				break
+			}
+			if !taken[strings.ToLower(path.Join(path.Dir(copied), candidate))] {
+				name = candidate
+			}
+		}
+		if name == "" {
+			unfixable = append(unfixable, path.Join(destRoot, copied))
+			continue
+		}
+		taken[strings.ToLower(path.Join(path.Dir(copied), name))] = true
+
+		// the path the file would otherwise have been copied to, before it's renamed to suit the target
+		before := filepath.ToSlash(relPath)
+		if renamed, ok := plan.opts.Renamed[relPath]; ok {
+			before = filepath.ToSlash(renamed)
+		}
+		after := path.Join(path.Dir(before), name)
+		plan.opts.Renamed[relPath] = filepath.FromSlash(after)
+		if from, ok := shortenedFrom[relPath]; ok {
+			before = from
+		}
+		plan.shortened[before] = after
+		shortened = append(shortened, fmt.Sprintf("%s -> %s", path.Join(destRoot, copied), name))
+	}
+
+	if len(shortened) > 0 {
+		logging.Log(logging.Action, "", "%s: %d file(s) or game folder(s) have paths on the target longer than %d characters and will be shortened:", plan.mapping.Source, len(shortened), config.MaxPathLength)
+		for _, line := range shortened {
+			logging.Log(logging.Detail, "", "• %s", line)
+		}
+	}
+	if len(unfixable) > 0 {
+		logging.LogWarning("%d file(s) in %s are in folders whose paths leave no room for their names within %d characters, and will likely fail to copy:", len(unfixable), plan.mapping.Source, config.MaxPathLength)
+		for _, unfixablePath := range unfixable {
+			logging.Log(logging.Action, "", "• %s", unfixablePath)
+		}
 	}
 	return nil
 }
 
-func runPostCopyOperations(config *cli_parsing.Config, destPath string) error {
-	// Explode directories if configured
-	if len(config.ExplodeDirs) > 0 {
-		if err := explodeDirs(config, destPath); err != nil {
-			return err
+// shortens the title of each '--perGameFolders' folder holding a file whose path on the target is too long, and
+// the titles of the files in it named for the game, by the same number of characters, until those files fit, or
+// as many of them as can. A
+// folder whose new name, or one of whose files' new paths, is taken gets a '~2', '~3', etc. after its title, as do
+// those files. Records what each file would otherwise have been copied to in shortenedFrom, and returns a line
+// describing each folder shortened. Files that still don't fit are left to be shortened on their own.
+func shortenGameFolders(config *cli_parsing.Config, plan *mappingPlan, tooLong func(copied string) int, taken map[string]bool, shortenedFrom map[string]string) ([]string, error) {
+	destRoot := filepath.ToSlash(strings.Trim(plan.mapping.Destination, "/\\"))
+	moved := make(map[string]bool, len(plan.gameFolders))
+	for _, after := range plan.gameFolders {
+		moved[after] = true
+	}
+	// whether before, where a file would otherwise have been copied, is in its game's folder, which may have been
+	// moved on into an alphabetical one
+	inGameFolder := func(before string) bool {
+		if moved[before] {
+			return true
 		}
+		bucket, rest, _ := strings.Cut(before, "/")
+		top, _, _ := strings.Cut(rest, "/")
+		return plan.alphaSplit[top] != "" && plan.alphaSplit[top] == bucket && moved[rest]
 	}
 
-	// Process renames if configured
-	if len(config.Renames) > 0 {
-		if err := processRenames(config, destPath); err != nil {
-			return err
+	// the files in each game's folder by the folder's path on the target
+	folders := make(map[string][]string)
+	err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+		before := filepath.ToSlash(f.RelPath)
+		if renamed, ok := plan.opts.Renamed[f.RelPath]; ok {
+			before = filepath.ToSlash(renamed)
+		}
+		if inGameFolder(before) {
+			copied := copiedPath(config, plan.opts, f.RelPath)
+			folders[path.Dir(copied)] = append(folders[path.Dir(copied)], f.RelPath)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
 	}
+	dirs := make([]string, 0, len(folders))
+	for dir := range folders {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
 
-	// Process rewrites if configured
-	if len(config.FileRewrites) > 0 {
-		if err := processRewrites(config, destPath); err != nil {
-			return err
+	lines := make([]string, 0)
+	for _, dir := range dirs {
+		relPaths := folders[dir]
+		sort.Strings(relPaths)
+		long := false
+		for _, relPath := range relPaths {
+			long = long || tooLong(copiedPath(config, plan.opts, relPath)) > 0
+		}
+		if !long {
+			continue
 		}
-	}
 
-	return nil
+		folder := path.Base(dir)
+		tagStart := strings.IndexAny(folder, "([")
+		if tagStart < 0 {
+			tagStart = len(folder)
+		}
+		title := strings.TrimRight(folder[:tagStart], " ")
+		// the new name of each file named for the game, and the folder itself, with the title newTitle
+		rename := func(name string, newTitle string) string {
+			if rest, ok := strings.CutPrefix(name, title); ok {
+				return newTitle + rest
+			}
+			return name
+		}
+
+		newTitle := ""
+		for n := 1; newTitle == ""; n++ {
+			suffix := ""
+			if n > 1 {
+				suffix = fmt.Sprintf("~%d", n)
+			}
+			// the least cut that fits every file named for the game that any cut fits
+			candidate := ""
+			fitted := make(map[string]bool)
+			for cut := len(suffix) + 1; ; cut++ {
+				shorter, ok := romtags.ShortenTitle(title, cut, suffix)
+				if !ok {
+					break
+				}
+				for _, relPath := range relPaths {
+					name := path.Base(copiedPath(config, plan.opts, relPath))
+					if !fitted[relPath] && strings.HasPrefix(name, title) && tooLong(path.Join(path.Dir(dir), rename(folder, shorter), rename(name, shorter))) <= 0 {
+						fitted[relPath] = true
+						candidate = shorter
+					}
+				}
+			}
+			if candidate == "" {
+				break
+			}
+			free := true
+			for _, relPath := range relPaths {
+				name := path.Base(copiedPath(config, plan.opts, relPath))
+				free = free && !taken[strings.ToLower(path.Join(path.Dir(dir), rename(folder, candidate), rename(name, candidate)))]
+			}
+			if free {
+				newTitle = candidate
+			}
+		}
+		if newTitle == "" {
+			continue
+		}
+
+		newFolder := rename(folder, newTitle)
+		for _, relPath := range relPaths {
+			copied := copiedPath(config, plan.opts, relPath)
+			taken[strings.ToLower(path.Join(path.Dir(dir), newFolder, rename(path.Base(copied), newTitle)))] = true
+
+			before := filepath.ToSlash(relPath)
+			if renamed, ok := plan.opts.Renamed[relPath]; ok {
+				before = filepath.ToSlash(renamed)
+			}
+			after := path.Join(path.Dir(path.Dir(before)), newFolder, rename(path.Base(before), newTitle))
+			plan.opts.Renamed[relPath] = filepath.FromSlash(after)
+			plan.shortened[before] = after
+			shortenedFrom[relPath] = before
+		}
+		lines = append(lines, fmt.Sprintf("%s/ -> %s/, with the %d file(s) in it", path.Join(destRoot, dir), newFolder, len(relPaths)))
+	}
+	return lines, nil
 }
 
-func main() {
-	intro := `   ___  ____  __  ________               ____          _
-  / _ \/ __ \/  |/  / ___/__  ___  __ __/ __/__  ___ _(_)__  ___
- / , _/ /_/ / /|_/ / /__/ _ \/ _ \/ // / _// _ \/ _ '/ / _ \/ -_)
-/_/|_|\____/_/  /_/\___/\___/ .__/\_, /___/_//_/\_, /_/_//_/\__/
-                           /_/   /___/         /___/`
-	fmt.Println(intro)
+// Skraper keeps each kind of media it scrapes in its own folder in this folder of each platform folder, e.g.
+// 'media/box2d'
+const skraperMediaDir = "media"
 
-	config, err := cli_parsing.ParseAndValidate()
+// copies the plan's Skraper media of the kind chosen with '--artworkSource' into '--artworkDir', and leaves the
+// other kinds out. Art that would land on a file of the same name stays where it is.
+func selectSkraperMedia(config *cli_parsing.Config, plan *mappingPlan) error {
+	taken := make(map[string]bool)
+	media := make(map[string][]string)
+	err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+		relPath := filepath.ToSlash(f.RelPath)
+		taken[strings.ToLower(filepath.ToSlash(plan.opts.DestRelPath(f.RelPath)))] = true
+		if parts := strings.SplitN(relPath, "/", 3); len(parts) == 3 && strings.EqualFold(parts[0], skraperMediaDir) {
+			media[parts[1]] = append(media[parts[1]], relPath)
+		}
+		return nil
+	})
 	if err != nil {
-		logging.LogError("Error: %v", err)
-		os.Exit(1)
+		return fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+	}
+	if len(media) == 0 {
+		return nil
 	}
 
-	summarizeWarnConfirm(config)
+	kinds := make([]string, 0, len(media))
+	for kind := range media {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
 
-	for _, mapping := range config.Mappings {
-		if err := processMapping(config, mapping); err != nil {
-			logging.LogError("Error: %v", err)
-			os.Exit(1)
+	plan.mediaMoved = make(map[string]string)
+	if plan.opts.Renamed == nil {
+		plan.opts.Renamed = make(map[string]string)
+	}
+	kept, leftOut := 0, 0
+	for _, kind := range kinds {
+		if !strings.EqualFold(kind, config.ArtworkSource) {
+			plan.mediaLeftOut = append(plan.mediaLeftOut, path.Join(skraperMediaDir, kind))
+			for _, relPath := range media[kind] {
+				plan.omit(filepath.FromSlash(relPath))
+				leftOut++
+			}
+			continue
+		}
+		for _, relPath := range media[kind] {
+			newRelPath := path.Join(filepath.ToSlash(config.ArtworkDir), strings.SplitN(relPath, "/", 3)[2])
+			if taken[strings.ToLower(newRelPath)] {
+				kept++
+				continue
+			}
+			taken[strings.ToLower(newRelPath)] = true
+			plan.opts.Renamed[filepath.FromSlash(relPath)] = filepath.FromSlash(newRelPath)
+			plan.mediaMoved[relPath] = newRelPath
+		}
+	}
+	plan.dropOmitted()
+
+	if len(plan.mediaMoved) == 0 && kept == 0 {
+		logging.LogWarning("%s has no Skraper '%s' media to copy into %s, only %s", plan.mapping.Source, config.ArtworkSource, config.ArtworkDir, strings.Join(plan.mediaLeftOut, ", "))
+	}
+	if kept > 0 {
+		logging.LogWarning("%d Skraper '%s' file(s) in %s stay in %s, since %s already has files of the same name", kept, config.ArtworkSource, plan.mapping.Source, skraperMediaDir, config.ArtworkDir)
+	}
+	logging.Log(logging.Action, "", "%s: %d Skraper '%s' file(s) to be copied into %s, %d other media file(s) left out", plan.mapping.Source, len(plan.mediaMoved), config.ArtworkSource, config.ArtworkDir, leftOut)
+	return nil
+}
+
+// warns about mappings too large to index in '--maxIndexMemory', which skip the checks that compare files
+// against each other, and rejects the options that can't work without an index
+func checkIndexLimits(config *cli_parsing.Config, plans []mappingPlan) error {
+	unindexed := make([]string, 0)
+	for _, plan := range plans {
+		if plan.unindexed {
+			unindexed = append(unindexed, plan.mapping.Source)
 		}
 	}
+	if len(unindexed) == 0 {
+		return nil
+	}
 
+	needsIndex := make([]string, 0)
+	if config.Dedupe {
+		needsIndex = append(needsIndex, "'--dedupe'")
+	}
+	if len(config.Dats) > 0 && len(config.RegionPriority) > 0 {
+		needsIndex = append(needsIndex, "'--regionPriority'")
+	}
+	if len(config.PreferLanguage) > 0 {
+		needsIndex = append(needsIndex, "'--preferLanguage'")
+	}
+	if config.PreferRevision != "" {
+		needsIndex = append(needsIndex, "'--preferRevision'")
+	}
+	if config.BadDumps == cli_parsing.BadDumpsSkip {
+		needsIndex = append(needsIndex, "'--badDumps skip'")
+	}
+	if config.StrictExtensions == cli_parsing.StrictExtensionsSkip {
+		needsIndex = append(needsIndex, "'--strictExtensions skip'")
+	}
+	if len(needsIndex) > 0 {
+		return fmt.Errorf("the source file index for %s doesn't fit in '--maxIndexMemory' (%s), but %s can't work without it; raise the limit",
+			strings.Join(unindexed, ", "), logging.FormatBytes(uint64(config.MaxIndexMemory)), strings.Join(needsIndex, ", "))
+	}
+
+	logging.LogWarning("The source file index for %s doesn't fit in '--maxIndexMemory' (%s); the source will be re-scanned instead, and duplicate, DAT, bad dump, and FAT32 limit checks are skipped for it",
+		strings.Join(unindexed, ", "), logging.FormatBytes(uint64(config.MaxIndexMemory)))
+	return nil
+}
+
+// prints the summary of and warnings about the plans, then asks to go ahead unless that's been approved; returns
+// whether the copy fits on the target, as far as can be told
+func summarizeWarnConfirm(config *cli_parsing.Config, plans []mappingPlan) (fits bool) {
+	scales := make([]cli_parsing.MappingScale, 0, len(plans))
+	for _, plan := range plans {
+		files, bytes := planTotals([]mappingPlan{plan})
+		scales = append(scales, cli_parsing.MappingScale{Files: files, Bytes: bytes})
+	}
+	cli_parsing.PrintCLIOpts(config, scales)
+	fmt.Println()
+
+	fits = true
+	if !config.SkipSpaceCheck {
+		fits = checkDiskSpace(config, plans)
+	}
+
+	checkFilesystemLimits(config, plans)
+	checkFolderSizes(config, plans)
+	checkReservedNames(config, plans)
+	checkIllegalNames(config, plans)
+	checkNameLengths(config, plans)
+	checkDatMatches(plans)
+	checkBadDumps(plans)
+	checkStrayExtensions(plans)
+	checkGameList(plans)
+	checkDuplicates(plans)
+	explainFilters(plans)
+
+	confirmCopy := !config.AutoApproves(cli_parsing.ConfirmCopy)
+	confirmClean := config.CleanTarget && !config.AutoApproves(cli_parsing.ConfirmClean)
+
+	if config.PlanOnly {
+		return fits
+	}
+	if !config.DryRun && (confirmCopy || confirmClean) {
+		if config.CleanTarget {
+			if config.UseTrash {
+				logging.LogWarning("You have chosen to run with the '--cleanTarget' option enabled. This will move all contents except save data and files matching '--protect' or '--cleanExclude' into the trash on the target from the following directories before copying:")
+			} else {
+				logging.LogWarning("You have chosen to run with the '--cleanTarget' option enabled. This will delete all contents except save data and files matching '--protect' or '--cleanExclude' from the following directories before copying:")
+			}
+			for _, plan := range plans {
+				logging.Log(logging.Action, "", "• %s", plan.destPath)
+			}
+			fmt.Println()
+		}
+
+		fmt.Println("[Hint: you can rerun this with '--dryRun' to see all operations that would be performed without performing them, or use '--skipConfirm' to skip this confirmation]")
+		prompt := "All files will be copied as summarized above. If file names conflict, they will be overwritten. Are you sure you want to proceed?"
+		// a routine copy was approved with '--yes copy', but deleting the target's contents still needs a say-so
+		if !confirmCopy {
+			prompt = "The directories listed above will be emptied before copying. Are you sure you want to proceed?"
+		}
+		if cli_parsing.GetConfirmation(prompt) {
+			logging.Log(logging.Base, "", "Beginning copy...")
+		} else {
+			logging.Log(logging.Base, "", "Copy cancelled. No operations performed.")
+			os.Exit(1)
+		}
+	} else {
+		logging.Log(logging.Base, "", "-y passed; skipping confirmation... Let's rock!")
+		fmt.Println()
+	}
+	return fits
+}
+
+// totals the files each mapping would copy and compares against free space on the target volume,
+// aborting or prompting if the copy won't fit. Returns whether it fits, as far as can be told.
+func checkDiskSpace(config *cli_parsing.Config, plans []mappingPlan) bool {
+	logging.Log(logging.Base, "", "Checking free space on target...")
+
+	var required int64
+	for _, plan := range plans {
+		files, mappingSize := plan.totals()
+
+		// files that get overwritten hand their space back
+		var reclaimed int64
+		if !config.CleanTarget {
+			err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+				if info, err := os.Stat(filepath.Join(plan.destPath, plan.opts.DestRelPath(f.RelPath))); err == nil && !info.IsDir() {
+					reclaimed += info.Size()
+				}
+				return nil
+			})
+			if err != nil {
+				logging.LogError("Error: unable to scan %s: %v", plan.sourcePath, err)
+				os.Exit(1)
+			}
+		}
+
+		// what's cleaned into the trash stays on the target, so only deleting it frees space
+		if config.CleanTarget && !config.UseTrash {
+			reclaimed = cleanedSize(config, plan.destPath)
+		}
+
+		// staging keeps the old contents around until the swap, and copies them into staging first when merging
+		if config.Transactional {
+			reclaimed = 0
+			if !config.CleanTarget {
+				mappingSize += dirSize(plan.destPath)
+			}
+		}
+
+		logging.Log(logging.Action, "", "%s -> %s: %d file(s), %s", plan.mapping.Source, plan.mapping.Destination, files, logging.FormatBytes(uint64(mappingSize)))
+		required += mappingSize - reclaimed
+	}
+
+	if required < 0 {
+		required = 0
+	}
+
+	free, err := disk_info.FreeSpace(config.TargetDir)
+	if err != nil {
+		logging.LogWarning("Unable to determine free space on target (%v); skipping space check", err)
+		fmt.Println()
+		return true
+	}
+
+	logging.Log(logging.Action, "", "Space required: %s; space available: %s", logging.FormatBytes(uint64(required)), logging.FormatBytes(free))
+	fmt.Println()
+
+	if uint64(required) <= free {
+		return true
+	}
+
+	logging.LogWarning("The files to be copied (%s) will not fit in the free space on the target (%s)!", logging.FormatBytes(uint64(required)), logging.FormatBytes(free))
+	fmt.Println()
+
+	if config.DryRun || config.PlanOnly {
+		return false
+	}
+
+	if config.AutoApproves(cli_parsing.ConfirmSpace) {
+		logging.LogWarning("Continuing anyway ('--yes space' passed)")
+		fmt.Println()
+		return false
+	}
+
+	if config.SkipConfirm && !containsString(config.Confirm, cli_parsing.ConfirmSpace) {
+		logging.LogError("Error: insufficient space on target; rerun with '--skipSpaceCheck' or '--yes space' to copy anyway")
+		os.Exit(1)
+	}
+
+	if !cli_parsing.GetConfirmation("The copy is likely to run out of space partway through. Are you sure you want to continue?") {
+		logging.Log(logging.Base, "", "Copy cancelled. No operations performed.")
+		os.Exit(1)
+	}
+	return false
+}
+
+// when the target is FAT32, lists every planned file or directory that would exceed FAT32's limits
+func checkFilesystemLimits(config *cli_parsing.Config, plans []mappingPlan) {
+	fsType, err := disk_info.FilesystemType(config.TargetDir)
+	if err != nil || fsType != disk_info.FilesystemFAT {
+		return
+	}
+
+	issues := fat32Issues(plans)
+	if len(issues) == 0 {
+		return
+	}
+
+	logging.LogWarning("The target appears to be FAT32, and %d item(s) exceed FAT32 limits; these will likely fail to copy or be unreadable on the device:", len(issues))
+	for _, issue := range issues {
+		logging.Log(logging.Action, "", "• %s: %s", issue.Path, issue.Reason)
+	}
+	fmt.Println()
+}
+
+// warns about platform folders holding more entries than the firmware detected on the target lists comfortably
+func checkFolderSizes(config *cli_parsing.Config, plans []mappingPlan) {
+	targetOS := disk_info.DetectTargetOS(config.TargetDir)
+	if targetOS == nil || targetOS.MaxFolderEntries == 0 {
+		return
+	}
+
+	crowded, err := crowdedFolders(config, plans, targetOS.MaxFolderEntries)
+	if err != nil {
+		logging.LogWarning("Couldn't count the entries in each platform folder: %v", err)
+		return
+	}
+	if len(crowded) == 0 {
+		return
+	}
+
+	logging.LogWarning("%s's game list gets slow past about %d entries in a folder, and %d folder(s) will have more:", targetOS.Name, targetOS.MaxFolderEntries, len(crowded))
+	for _, folder := range crowded {
+		logging.Log(logging.Action, "", "• %s: %d entries", folder.destination, folder.entries)
+	}
+	if len(config.AlphaBuckets) > 0 {
+		logging.Log(logging.Action, "", "Consider narrower '--alphaBuckets' ranges (e.g. 'A-C,D-F,G-I'), or trimming them with '--regions', '--excludeTags', or '--dedupe'")
+	} else {
+		logging.Log(logging.Action, "", "Consider splitting them into alphabetical subfolders (e.g. 'A-E', 'F-J') with '--splitAlpha', or trimming them with '--regions', '--excludeTags', or '--dedupe'")
+	}
+	fmt.Println()
+}
+
+// a destination platform folder and the number of entries a frontend would list in it
+type folderSize struct {
+	destination string
+	entries     int
+}
+
+// the platform folders, and with '--splitAlpha' their alphabetical folders, that will hold more than limit entries
+// at their top level: whatever the copy puts there (after explodes and renames) and, unless '--cleanTarget'
+// empties them first, what's there already. Hidden entries aren't counted, since frontends don't list them.
+func crowdedFolders(config *cli_parsing.Config, plans []mappingPlan, limit int) ([]folderSize, error) {
+	crowded := make([]folderSize, 0)
+	for _, plan := range plans {
+		buckets := make(map[string]bool)
+		for _, bucket := range plan.alphaSplit {
+			buckets[bucket] = true
+		}
+
+		// the entries in each folder, by its slash separated path relative to the platform folder
+		entries := map[string]map[string]bool{"": {}}
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			parts := strings.SplitN(copiedPath(config, plan.opts, f.RelPath), "/", 3)
+			if strings.HasPrefix(parts[0], ".") {
+				return nil
+			}
+			entries[""][parts[0]] = true
+			if buckets[parts[0]] && len(parts) > 1 && !strings.HasPrefix(parts[1], ".") {
+				if entries[parts[0]] == nil {
+					entries[parts[0]] = make(map[string]bool)
+				}
+				entries[parts[0]][parts[1]] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+		}
+
+		folders := make([]string, 0, len(entries))
+		for folder := range entries {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+		for _, folder := range folders {
+			if !config.CleanTarget {
+				existing, _ := os.ReadDir(filepath.Join(plan.destPath, filepath.FromSlash(folder)))
+				for _, entry := range existing {
+					if !strings.HasPrefix(entry.Name(), ".") {
+						entries[folder][entry.Name()] = true
+					}
+				}
+			}
+
+			if len(entries[folder]) > limit {
+				destination := path.Join(filepath.ToSlash(plan.mapping.Destination), folder)
+				crowded = append(crowded, folderSize{destination: destination, entries: len(entries[folder])})
+			}
+		}
+	}
+	return crowded, nil
+}
+
+// every planned file or directory that would exceed FAT32's limits
+func fat32Issues(plans []mappingPlan) []disk_info.FAT32Issue {
+	planned := make([]disk_info.PlannedFile, 0)
+	for _, plan := range plans {
+		destRoot := filepath.ToSlash(strings.Trim(plan.mapping.Destination, "/\\"))
+		for _, f := range plan.files {
+			planned = append(planned, disk_info.PlannedFile{
+				Path: path.Join(destRoot, filepath.ToSlash(f.RelPath)),
+				Size: f.Size,
+			})
+		}
+	}
+
+	return disk_info.CheckFAT32Limits(planned)
+}
+
+// number and total size of the files every mapping will copy
+func planTotals(plans []mappingPlan) (int, int64) {
+	var files int
+	var bytes int64
+	for _, plan := range plans {
+		planFiles, planBytes := plan.totals()
+		files += planFiles
+		bytes += planBytes
+	}
+	return files, bytes
+}
+
+// files named CON, NUL, etc. can't be created or opened on Windows, so when the target will be used from
+// Windows they're either renamed during the copy or the run is rejected before anything is copied
+func checkReservedNames(config *cli_parsing.Config, plans []mappingPlan) {
+	if !disk_info.WindowsAccessible(config.TargetDir) {
+		return
+	}
+
+	reserved, err := reservedNamePaths(plans)
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+	if len(reserved) == 0 {
+		return
+	}
+
+	if config.RenameReserved {
+		logging.Log(logging.Base, "", "%d item(s) use names reserved by Windows and will be renamed:", len(reserved))
+		for _, reservedPath := range reserved {
+			logging.Log(logging.Action, "", "• %s -> %s", reservedPath, disk_info.SafeWindowsPath(reservedPath))
+		}
+		fmt.Println()
+		return
+	}
+
+	logging.LogWarning("The target is accessible from Windows, and %d item(s) use names Windows reserves for devices (CON, PRN, AUX, NUL, COM1-9, LPT1-9); these can't be created or opened there:", len(reserved))
+	for _, reservedPath := range reserved {
+		logging.Log(logging.Action, "", "• %s", reservedPath)
+	}
+	fmt.Println()
+
+	if config.DryRun {
+		return
+	}
+
+	logging.LogError("Error: reserved file names in copy; rerun with '--renameReserved' to rename them (e.g. 'CON.nes' to 'CON_.nes'), or exclude them with '--copyExclude'")
+	os.Exit(1)
+}
+
+// names with characters like ':' and '?' can't be created on FAT32 or exFAT, so when the target is formatted with
+// one (or used from Windows, which forbids them on every filesystem) they're either sanitized during the copy or
+// the run is rejected before anything is copied. Names that sanitize to the same name are rejected either way.
+func checkIllegalNames(config *cli_parsing.Config, plans []mappingPlan) {
+	if !config.SanitizeNames && !disk_info.WindowsAccessible(config.TargetDir) {
+		return
+	}
+
+	illegal, err := illegalNamePaths(plans)
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+	if len(illegal) == 0 {
+		return
+	}
+
+	if config.SanitizeNames {
+		logging.Log(logging.Base, "", "%d item(s) have names FAT32 and exFAT don't allow and will be renamed:", len(illegal))
+		for _, illegalPath := range illegal {
+			logging.Log(logging.Action, "", "• %s -> %s", illegalPath, disk_info.SafeFATPath(illegalPath, config.SanitizeWith))
+		}
+		fmt.Println()
+
+		collisions, err := sanitizedCollisions(plans)
+		if err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		if len(collisions) == 0 {
+			return
+		}
+		logging.LogWarning("%d sanitized name(s) would overwrite another file copied to the same name:", len(collisions))
+		for _, collision := range collisions {
+			logging.Log(logging.Action, "", "• %s", collision)
+		}
+		fmt.Println()
+		if config.DryRun {
+			return
+		}
+		logging.LogError("Error: sanitized names collide; rerun with another '--sanitizeWith', or exclude one of each pair with '--copyExclude'")
+		os.Exit(1)
+	}
+
+	logging.LogWarning("The target's filesystem doesn't allow characters like ':' and '?' in names, and %d item(s) use them; these will fail to copy:", len(illegal))
+	for _, illegalPath := range illegal {
+		logging.Log(logging.Action, "", "• %s", illegalPath)
+	}
+	fmt.Println()
+
+	if config.DryRun {
+		return
+	}
+
+	logging.LogError("Error: file names the target can't store in copy; rerun with '--sanitizeNames' to replace the characters (e.g. 'Zelda: A Link to the Past.sfc' to 'Zelda_ A Link to the Past.sfc'), or exclude them with '--copyExclude'")
+	os.Exit(1)
+}
+
+// warns about files and folders whose names, as copied, are longer than '--maxNameLength' allows
+func checkNameLengths(config *cli_parsing.Config, plans []mappingPlan) {
+	if config.MaxNameLength == 0 {
+		return
+	}
+
+	long, err := longNamePaths(config, plans)
+	if err != nil {
+		logging.LogWarning("Couldn't check the length of names: %v", err)
+		return
+	}
+	if len(long) == 0 {
+		return
+	}
+
+	logging.LogWarning("%d item(s) have names longer than %d bytes, which the device likely can't open:", len(long), config.MaxNameLength)
+	for _, longPath := range long {
+		logging.Log(logging.Action, "", "• %s", longPath)
+	}
+	logging.Log(logging.Action, "", "Consider shortening them with '--rename', or leaving them out with '--copyExclude'")
+	fmt.Println()
+}
+
+// target-relative paths, after renames, of every planned file or folder whose own name is longer than
+// '--maxNameLength' bytes; a folder is listed once however many files are in it
+func longNamePaths(config *cli_parsing.Config, plans []mappingPlan) ([]string, error) {
+	long := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, plan := range plans {
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			parts := strings.Split(copiedPath(config, plan.opts, f.RelPath), "/")
+			for i, part := range parts {
+				if len(part) <= config.MaxNameLength {
+					continue
+				}
+				longPath := path.Join(filepath.ToSlash(plan.mapping.Destination), path.Join(parts[:i+1]...))
+				if !seen[longPath] {
+					seen[longPath] = true
+					long = append(long, longPath)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+		}
+	}
+	return long, nil
+}
+
+// target-relative paths of every planned file using a Windows device name
+func reservedNamePaths(plans []mappingPlan) ([]string, error) {
+	reserved := make([]string, 0)
+	for _, plan := range plans {
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			relPath := filepath.ToSlash(f.RelPath)
+			if disk_info.HasWindowsReservedName(relPath) {
+				reserved = append(reserved, path.Join(filepath.ToSlash(plan.mapping.Destination), relPath))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+		}
+	}
+	return reserved, nil
+}
+
+// target-relative paths of every planned file with a name FAT32 and exFAT don't allow
+func illegalNamePaths(plans []mappingPlan) ([]string, error) {
+	illegal := make([]string, 0)
+	for _, plan := range plans {
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			relPath := filepath.ToSlash(f.RelPath)
+			if disk_info.HasFATIllegalName(relPath) {
+				illegal = append(illegal, path.Join(filepath.ToSlash(plan.mapping.Destination), relPath))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+		}
+	}
+	return illegal, nil
+}
+
+// the target-relative paths of the planned files whose sanitized names are the same, ignoring case, as another
+// planned file's, each as 'a and b -> name'
+func sanitizedCollisions(plans []mappingPlan) ([]string, error) {
+	collisions := make([]string, 0)
+	for _, plan := range plans {
+		destRoot := filepath.ToSlash(plan.mapping.Destination)
+		seen := make(map[string]string)
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			destRelPath := filepath.ToSlash(plan.opts.DestRelPath(f.RelPath))
+			key := strings.ToLower(destRelPath)
+			// names that differ only in case already collide on FAT32 and exFAT, sanitized or not
+			if other, ok := seen[key]; ok && (disk_info.HasFATIllegalName(other) || disk_info.HasFATIllegalName(filepath.ToSlash(f.RelPath))) {
+				collisions = append(collisions, fmt.Sprintf("%s and %s -> %s", path.Join(destRoot, other), path.Join(destRoot, filepath.ToSlash(f.RelPath)), path.Join(destRoot, destRelPath)))
+				return nil
+			}
+			seen[key] = filepath.ToSlash(f.RelPath)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+		}
+	}
+	sort.Strings(collisions)
+	return collisions, nil
+}
+
+// loads the '--launchboxData' library; nil if none was given
+func loadLaunchBox(config *cli_parsing.Config) (*launchbox.Library, error) {
+	if config.LaunchBoxData == "" {
+		return nil, nil
+	}
+	library, err := launchbox.Load(config.LaunchBoxData)
+	if err != nil {
+		return nil, err
+	}
+	logging.Log(logging.Base, "", "Loaded LaunchBox library from %s (%d games)", config.LaunchBoxData, library.Len())
+	return library, nil
+}
+
+// loads the '--scrapeCredentials' and returns a ScreenScraper client using them; nil without '--scrape'
+func loadScraper(config *cli_parsing.Config) (*screenscraper.Client, error) {
+	if !config.Scrape {
+		return nil, nil
+	}
+	creds, err := screenscraper.LoadCredentials(config.ScrapeCredentials)
+	if err != nil {
+		return nil, err
+	}
+	return screenscraper.NewClient(creds), nil
+}
+
+// returns a libretro-thumbnails fetcher caching in the user cache directory, unless '--noCache'; nil without
+// '--fetchThumbnails'
+func loadThumbnails(config *cli_parsing.Config) *thumbnails.Fetcher {
+	if !config.FetchThumbnails {
+		return nil
+	}
+	if config.NoCache {
+		return thumbnails.NewFetcher("")
+	}
+	cacheDir, err := thumbnails.DefaultCacheDir()
+	if err != nil {
+		logging.LogWarning("%v; box art won't be cached", err)
+	}
+	return thumbnails.NewFetcher(cacheDir)
+}
+
+// loads every '--dat' file into a single matcher; nil if none were given
+func loadDats(config *cli_parsing.Config) (*datfile.Matcher, error) {
+	if len(config.Dats) == 0 {
+		return nil, nil
+	}
+
+	dats := make([]*datfile.Dat, 0, len(config.Dats))
+	for _, datPath := range config.Dats {
+		dat, err := datfile.Load(datPath)
+		if err != nil {
+			return nil, err
+		}
+		name := dat.Name
+		if name == "" {
+			name = filepath.Base(datPath)
+		}
+		logging.Log(logging.Base, "", "Loaded DAT %s (%d games)", name, len(dat.Games))
+		dats = append(dats, dat)
+	}
+	return datfile.NewMatcher(dats...), nil
+}
+
+// hashes every source file that looks like a ROM and looks it up in the DATs
+func matchDats(plans []mappingPlan, matcher *datfile.Matcher) error {
+	for i := range plans {
+		plan := &plans[i]
+		plan.datMatches = make(map[string]datfile.Match)
+
+		covered := make([]copy_funcs.ResolvedFile, 0, len(plan.files))
+		zipped := make([]copy_funcs.ResolvedFile, 0)
+		for _, f := range plan.files {
+			if matcher.Covers(f.RelPath) {
+				covered = append(covered, f)
+			} else if archive.IsZip(f.RelPath) {
+				zipped = append(zipped, f)
+			}
+		}
+		if len(covered)+len(zipped) == 0 {
+			continue
+		}
+
+		logging.Log(logging.Base, "", "Checking %d file(s) in %s against DATs...", len(covered)+len(zipped), plan.mapping.Source)
+		for _, f := range zipped {
+			match, ok, err := matchZip(filepath.Join(plan.sourcePath, f.RelPath), matcher)
+			if err != nil {
+				logging.LogWarning("Unable to check %s against DATs: %v", f.RelPath, err)
+				continue
+			}
+			if ok {
+				plan.datMatches[f.RelPath] = match
+			}
+		}
+		for _, f := range covered {
+			sums, err := plan.opts.Stream.Cache.HashFileAll(filepath.Join(plan.sourcePath, f.RelPath), file_operations.ChecksumCRC32, file_operations.ChecksumSHA1)
+			if err != nil {
+				return err
+			}
+			plan.datMatches[f.RelPath] = matcher.Match(f.RelPath, f.Size, hex.EncodeToString(sums[0]), hex.EncodeToString(sums[1]))
+		}
+	}
+	return nil
+}
+
+// matches the ROMs in a zip against the DATs by the CRC32s in its directory, without decompressing it. A zip of
+// several ROMs takes the first match that isn't verified, so a single bad member flags the whole archive.
+// Returns false if the zip holds no ROMs the DATs cover.
+func matchZip(filePath string, matcher *datfile.Matcher) (datfile.Match, bool, error) {
+	members, err := archive.ReadZip(file_operations.Filesystem(), filePath)
+	if err != nil {
+		return datfile.Match{}, false, err
+	}
+
+	var result datfile.Match
+	found := false
+	for _, member := range members {
+		if !matcher.Covers(member.Name) {
+			continue
+		}
+		match := matcher.Match(member.Name, member.Size, hex.EncodeToString(member.CRC32), "")
+		if !found || (result.Status == datfile.MatchVerified && match.Status != datfile.MatchVerified) {
+			result = match
+		}
+		found = true
+	}
+	return result, found, nil
+}
+
+// keeps only the preferred release of each game (1G1R): files are grouped by their DAT game's parent, and
+// files belonging to any release other than the preferred one are left out of the copy
+func selectOneGameOneRom(plans []mappingPlan, matcher *datfile.Matcher, regionPriority []string) {
+	if !matcher.HasClones() {
+		logging.LogWarning("None of the DATs record parent/clone relationships, so 1G1R can't group releases; every release will be copied. Use a parent/clone ('P/C') DAT.")
+		return
+	}
+
+	for i := range plans {
+		plan := &plans[i]
+
+		groups := make(map[string][]*datfile.Game)
+		for _, f := range plan.files {
+			match, ok := plan.datMatches[f.RelPath]
+			if !ok || match.Game == nil {
+				continue
+			}
+			parent := matcher.Parent(match.Game)
+			if !containsGame(groups[parent], match.Game) {
+				groups[parent] = append(groups[parent], match.Game)
+			}
+		}
+
+		preferred := make(map[string]*datfile.Game, len(groups))
+		for parent, candidates := range groups {
+			preferred[parent] = datfile.PreferredGame(candidates, regionPriority)
+		}
+
+		omitted := 0
+		for _, f := range plan.files {
+			match, ok := plan.datMatches[f.RelPath]
+			if !ok || match.Game == nil {
+				continue
+			}
+			if best := preferred[matcher.Parent(match.Game)]; best != match.Game {
+				plan.omit(f.RelPath)
+				omitted++
+				logging.LogVerbose(logging.Detail, logging.IconSkip, "1G1R: leaving out %s in favor of %s", f.RelPath, best.Name)
+			}
+		}
+
+		if omitted > 0 {
+			logging.Log(logging.Base, "", "1G1R: %s has %d game(s); leaving out %d file(s) from other releases", plan.mapping.Source, len(groups), omitted)
+		}
+		plan.dropOmitted()
+	}
+}
+
+// leaves out the language versions of each game that '--preferLanguage' doesn't choose
+func selectPreferredLanguages(plans []mappingPlan, preferred []string) {
+	for i := range plans {
+		plan := &plans[i]
+
+		relPaths := make([]string, 0, len(plan.files))
+		for _, f := range plan.files {
+			relPaths = append(relPaths, f.RelPath)
+		}
+		losers := romtags.PreferLanguage(relPaths, preferred)
+
+		for _, f := range plan.files {
+			if kept, ok := losers[f.RelPath]; ok {
+				logging.LogVerbose(logging.Detail, logging.IconSkip, "Preferred language: leaving out %s in favor of %s", f.RelPath, kept)
+				plan.omit(f.RelPath)
+			}
+		}
+		if len(losers) > 0 {
+			logging.Log(logging.Base, "", "Preferred language: leaving out %d file(s) from %s in other languages", len(losers), plan.mapping.Source)
+		}
+		plan.dropOmitted()
+	}
+}
+
+// leaves out the revisions of each game that '--preferRevision' doesn't choose
+func selectPreferredRevisions(plans []mappingPlan, latest bool) {
+	for i := range plans {
+		plan := &plans[i]
+
+		relPaths := make([]string, 0, len(plan.files))
+		for _, f := range plan.files {
+			relPaths = append(relPaths, f.RelPath)
+		}
+		losers := romtags.PreferRevision(relPaths, latest)
+
+		for _, f := range plan.files {
+			if kept, ok := losers[f.RelPath]; ok {
+				logging.LogVerbose(logging.Detail, logging.IconSkip, "Preferred revision: leaving out %s in favor of %s", f.RelPath, kept)
+				plan.omit(f.RelPath)
+			}
+		}
+		if len(losers) > 0 {
+			logging.Log(logging.Base, "", "Preferred revision: leaving out %d other revision(s) from %s", len(losers), plan.mapping.Source)
+		}
+		plan.dropOmitted()
+	}
+}
+
+// flags bad dumps among each mapping's files: those whose names carry the GoodTools bad ('[b]'), overdump
+// ('[o]'), or hack ('[h]') markers, and those a DAT lists as a bad dump. With '--badDumps skip' they're left
+// out of the copy; otherwise they're listed in the summary by checkBadDumps.
+func findBadDumps(plans []mappingPlan, mode string) {
+	if mode == cli_parsing.BadDumpsCopy {
+		return
+	}
+
+	for i := range plans {
+		plan := &plans[i]
+		plan.badDumps = make(map[string]string)
+		for _, f := range plan.files {
+			if reason := badDumpReason(f.RelPath, plan.datMatches); reason != "" {
+				plan.badDumps[f.RelPath] = reason
+			}
+		}
+
+		if mode != cli_parsing.BadDumpsSkip || len(plan.badDumps) == 0 {
+			continue
+		}
+		logging.Log(logging.Base, "", "%s: leaving out %d bad dump(s)", plan.mapping.Source, len(plan.badDumps))
+		for _, f := range plan.files {
+			if reason, ok := plan.badDumps[f.RelPath]; ok {
+				logging.LogVerbose(logging.Detail, logging.IconSkip, "Skipping bad dump %s (%s)", f.RelPath, reason)
+				plan.omit(f.RelPath)
+			}
+		}
+		plan.dropOmitted()
+	}
+}
+
+// checks each mapping's files against the extensions its platform's emulators load; with '--strictExtensions
+// skip' files that don't belong are left out of the copy, otherwise they're listed in the summary by
+// checkStrayExtensions
+func findStrayExtensions(plans []mappingPlan, mode string) error {
+	if mode == "" {
+		return nil
+	}
+
+	for i := range plans {
+		plan := &plans[i]
+		platform := platforms.ForMapping(plan.mapping.Source, plan.mapping.Destination)
+		if platform == nil {
+			logging.LogWarning("Can't tell which platform %s -> %s holds from its folder names; its file extensions won't be checked", plan.mapping.Source, plan.mapping.Destination)
+			continue
+		}
+
+		plan.strays = make([]string, 0)
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			if !platform.Accepts(f.RelPath) {
+				plan.strays = append(plan.strays, f.RelPath)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("unable to scan %s: %w", plan.sourcePath, err)
+		}
+
+		if mode != cli_parsing.StrictExtensionsSkip || len(plan.strays) == 0 {
+			continue
+		}
+		logging.Log(logging.Base, "", "%s: leaving out %d file(s) that don't belong to the %s", plan.mapping.Source, len(plan.strays), platform.Name)
+		for _, relPath := range plan.strays {
+			logging.LogVerbose(logging.Detail, logging.IconSkip, "Skipping %s (not a %s file)", relPath, platform.Name)
+			plan.omit(relPath)
+		}
+		plan.strays = nil
+		plan.dropOmitted()
+	}
+	return nil
+}
+
+// lists the files flagged by findStrayExtensions that will still be copied
+func checkStrayExtensions(plans []mappingPlan) {
+	for _, plan := range plans {
+		if len(plan.strays) == 0 {
+			continue
+		}
+
+		logging.LogWarning("%d file(s) in %s don't look like they belong to its platform; use '--strictExtensions skip' to leave them out:", len(plan.strays), plan.mapping.Source)
+		for _, relPath := range plan.strays {
+			logging.Log(logging.Action, "", "• %s", relPath)
+		}
+		fmt.Println()
+	}
+}
+
+// lists the lines of the '--gameList' file that didn't match a file in any mapping, which are usually typos
+func checkGameList(plans []mappingPlan) {
+	if len(plans) == 0 || plans[0].opts.GameList.Len() == 0 {
+		return
+	}
+
+	relPaths := make([]string, 0)
+	for _, plan := range plans {
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			relPaths = append(relPaths, f.RelPath)
+			return nil
+		})
+		if err != nil {
+			logging.LogWarning("Unable to check the game list against %s: %v", plan.mapping.Source, err)
+			return
+		}
+	}
+
+	unmatched := plans[0].opts.GameList.Unmatched(relPaths)
+	if len(unmatched) == 0 {
+		return
+	}
+	logging.LogWarning("%d game(s) in the game list didn't match any file:", len(unmatched))
+	for _, line := range unmatched {
+		logging.Log(logging.Action, "", "• %s", line)
+	}
+	fmt.Println()
+}
+
+// returns why the file at relPath is a bad dump, or "" if it isn't one
+func badDumpReason(relPath string, datMatches map[string]datfile.Match) string {
+	if match, ok := datMatches[relPath]; ok && match.Status == datfile.MatchBadDump {
+		return "DAT lists it as a bad dump"
+	}
+
+	tags := romtags.Parse(filepath.Base(relPath))
+	switch {
+	case tags.HasRelease([]string{"bad"}):
+		return "marked bad"
+	case tags.HasRelease([]string{"overdump"}):
+		return "marked overdump"
+	case tags.HasRelease([]string{"hack"}):
+		return "marked as a hack"
+	}
+	return ""
+}
+
+// lists the bad dumps flagged by findBadDumps that will still be copied
+func checkBadDumps(plans []mappingPlan) {
+	for _, plan := range plans {
+		if len(plan.badDumps) == 0 {
+			continue
+		}
+
+		relPaths := make([]string, 0, len(plan.badDumps))
+		for relPath := range plan.badDumps {
+			relPaths = append(relPaths, relPath)
+		}
+		sort.Strings(relPaths)
+
+		logging.LogWarning("%d bad dump(s) in %s will be copied; use '--badDumps skip' to leave them out:", len(relPaths), plan.mapping.Source)
+		for _, relPath := range relPaths {
+			logging.Log(logging.Action, "", "• %s (%s)", relPath, plan.badDumps[relPath])
+		}
+		fmt.Println()
+	}
+}
+
+// prints the active filters in the order they're evaluated, with how many source files each one rejects, so a
+// stack of includes, excludes, and tag filters can be sanity checked before copying
+func explainFilters(plans []mappingPlan) {
+	descriptions := make(map[copy_funcs.FilterStage]string)
+	rejected := make(map[copy_funcs.FilterStage]int)
+	total, selected := 0, 0
+	for _, plan := range plans {
+		for _, stage := range copy_funcs.FilterStages {
+			if description := plan.opts.Describe(stage); description != "" && descriptions[stage] == "" {
+				descriptions[stage] = description
+			}
+		}
+
+		counts, files, err := copy_funcs.CountRejections(plan.sourcePath, plan.opts)
+		if err != nil {
+			logging.LogWarning("Couldn't count the files filtered from %s: %v", plan.mapping.Source, err)
+			return
+		}
+		for stage, count := range counts {
+			rejected[stage] += count
+		}
+		total += files
+		planFiles, _ := plan.totals()
+		selected += planFiles
+	}
+
+	if len(descriptions) == 0 {
+		return
+	}
+
+	logging.Log(logging.Base, "", "Files are filtered in this order; a file is copied only if it passes every step:")
+	step := 0
+	for _, stage := range copy_funcs.FilterStages {
+		if descriptions[stage] == "" {
+			continue
+		}
+		step++
+		logging.Log(logging.Action, "", "%d. %s: %s [%d file(s) excluded]", step, stage, descriptions[stage], rejected[stage])
+	}
+	logging.Log(logging.Action, "", "=> %d of %d source file(s) will be copied", selected, total)
+	fmt.Println()
+}
+
+// looks for duplicate files within each mapping; with '--dedupe' all but the best of each group are left out of
+// the copy, otherwise they're listed in the summary by checkDuplicates
+func findDuplicates(plans []mappingPlan, dedupeEnabled bool) error {
+	for i := range plans {
+		plan := &plans[i]
+		fullHash := func(filePath string) ([]byte, error) {
+			return plan.opts.Stream.Cache.HashFile(filePath, file_operations.ChecksumSHA1)
+		}
+
+		groups, err := dedupe.Find(file_operations.Filesystem(), plan.sourcePath, plan.files, fullHash)
+		if err != nil {
+			return fmt.Errorf("failed to check %s for duplicates: %w", plan.mapping.Source, err)
+		}
+
+		if !dedupeEnabled {
+			plan.duplicates = groups
+			continue
+		}
+
+		omitted := 0
+		for _, group := range groups {
+			for _, relPath := range group.Files[1:] {
+				logging.LogVerbose(logging.Detail, logging.IconSkip, "Dedupe: leaving out %s in favor of %s (%s)", relPath, group.Files[0], group.Kind)
+				plan.omit(relPath)
+				omitted++
+			}
+		}
+		if omitted > 0 {
+			logging.Log(logging.Base, "", "Dedupe: leaving out %d duplicate file(s) from %s", omitted, plan.mapping.Source)
+		}
+		plan.dropOmitted()
+	}
+	return nil
+}
+
+// lists the duplicate files found by findDuplicates that will all be copied
+func checkDuplicates(plans []mappingPlan) {
+	for _, plan := range plans {
+		if len(plan.duplicates) == 0 {
+			continue
+		}
+
+		logging.LogWarning("%d group(s) of duplicate files in %s; rerun with '--dedupe' to copy only the first of each:", len(plan.duplicates), plan.mapping.Source)
+		for _, group := range plan.duplicates {
+			logging.Log(logging.Action, "", "• %s (%s)", strings.Join(group.Files, ", "), group.Kind)
+		}
+		fmt.Println()
+	}
+}
+
+func containsGame(games []*datfile.Game, game *datfile.Game) bool {
+	for _, g := range games {
+		if g == game {
+			return true
+		}
+	}
+	return false
+}
+
+// lists every source ROM that's a bad dump, doesn't match its DAT entry, or isn't in the DATs at all
+func checkDatMatches(plans []mappingPlan) {
+	for _, plan := range plans {
+		if len(plan.datMatches) == 0 {
+			continue
+		}
+
+		flagged := make(map[datfile.MatchStatus][]string)
+		for _, f := range plan.files {
+			match, ok := plan.datMatches[f.RelPath]
+			if ok && match.Status != datfile.MatchVerified {
+				flagged[match.Status] = append(flagged[match.Status], f.RelPath)
+			}
+		}
+
+		verified := len(plan.datMatches) - len(flagged[datfile.MatchBadDump]) - len(flagged[datfile.MatchMismatch]) - len(flagged[datfile.MatchUnknown])
+		logging.Log(logging.Base, "", "%s: %d of %d ROM(s) verified against DATs", plan.mapping.Source, verified, len(plan.datMatches))
+
+		// bad dumps are reported (or skipped) according to '--badDumps'
+		for _, status := range []datfile.MatchStatus{datfile.MatchMismatch, datfile.MatchUnknown} {
+			if len(flagged[status]) == 0 {
+				continue
+			}
+			logging.LogWarning("%d ROM(s) in %s: %s", len(flagged[status]), plan.mapping.Source, status)
+			for _, relPath := range flagged[status] {
+				if match := plan.datMatches[relPath]; match.Game != nil {
+					logging.Log(logging.Action, "", "• %s (DAT: %s)", relPath, match.Game.Name)
+				} else {
+					logging.Log(logging.Action, "", "• %s", relPath)
+				}
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// total size of all files beneath path; missing paths are size zero
+func dirSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// the total size of the files cleaning destPath would delete, leaving out the protected files it keeps
+func cleanedSize(config *cli_parsing.Config, destPath string) int64 {
+	cleared, _, err := file_operations.PlanClear(destPath, config.CleanKeeps())
+	if err != nil {
+		return 0
+	}
+	var size int64
+	for _, entry := range cleared {
+		size += entry.Size
+	}
+	return size
+}
+
+// builds the file selection options for a mapping from the config and any ignore files in the source tree
+func copyOptions(config *cli_parsing.Config, mapping cli_parsing.DirMapping) (copy_funcs.CopyOptions, error) {
+	opts := copy_funcs.CopyOptions{
+		Include:               config.CopyInclude,
+		Exclude:               config.CopyExclude,
+		GlobDialect:           config.GlobDialect,
+		DryRun:                config.DryRun,
+		SafeWindowsNames:      config.RenameReserved,
+		SafeFATNames:          config.SanitizeNames,
+		FATSubstitute:         config.SanitizeWith,
+		NameForm:              config.NormalizeNames,
+		DirMode:               config.DirMode,
+		SkipEmulatorArtifacts: config.SkipEmulatorArtifacts,
+		Regions:               config.Regions,
+		Languages:             config.Languages,
+		ExcludeTags:           config.ExcludeTags,
+		ResizeImages:          config.ResizeImages,
+	}
+	if config.ResizeImages != (artwork.Size{}) {
+		opts.IsArtwork = func(destRelPath string) bool { return isArtwork(config, destRelPath) }
+	}
+
+	if !config.SkipIgnoreFiles {
+		ignore, err := copy_funcs.LoadIgnoreRules(config.SourceDir, mapping.Source)
+		if err != nil {
+			return opts, fmt.Errorf("error loading %s files: %w", copy_funcs.IgnoreFileName, err)
+		}
+		opts.Ignore = ignore
+	}
+
+	if config.MaxErrors > 0 {
+		opts.Errors = copy_funcs.NewErrorBudget(config.MaxErrors)
+	}
+
+	// favorites are a subset of the game list, so '--onlyFavorites' wins if both are given
+	if config.OnlyFavorites || config.GamelistOnly {
+		sourcePath, _ := mappingPaths(config, mapping)
+		files, err := gamelist.ReferencedFiles(file_operations.Filesystem(), sourcePath, config.OnlyFavorites)
+		if err != nil {
+			return opts, fmt.Errorf("error reading %s: %w", gamelist.FileName, err)
+		}
+		if files == nil {
+			logging.LogWarning("%s has no %s to choose games from; none of its games will be copied", mapping.Source, gamelist.FileName)
+			files = make(map[string]bool)
+		}
+		opts.GamelistFiles = files
+	}
+
+	if config.GameList != "" {
+		gameList, err := copy_funcs.LoadGameList(config.GameList)
+		if err != nil {
+			return opts, err
+		}
+		opts.GameList = gameList
+	}
+
+	return opts, nil
+}
+
+// reports whether the file at destRelPath, relative to the destination platform folder, is in an artwork or video
+// folder, or '--artworkDir'
+func isArtwork(config *cli_parsing.Config, destRelPath string) bool {
+	if platforms.InMediaFolder(destRelPath) {
+		return true
+	}
+	rel, err := filepath.Rel(config.ArtworkDir, destRelPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// artwork written to the target other than by copying it, downscaled as '--resizeImages' asks. Art that can't be
+// read as an image is written as it is.
+func resizeArtwork(config *cli_parsing.Config, data []byte) []byte {
+	if config.ResizeImages == (artwork.Size{}) {
+		return data
+	}
+	resized, _, _ := artwork.Downscale(data, config.ResizeImages)
+	return resized
+}
+
+func mappingPaths(config *cli_parsing.Config, mapping cli_parsing.DirMapping) (string, string) {
+	sourcePath := filepath.Join(strings.TrimRight(config.SourceDir, "/\\"), strings.TrimLeft(mapping.Source, "/\\"))
+	destPath := filepath.Join(strings.TrimRight(config.TargetDir, "/\\"), strings.TrimLeft(mapping.Destination, "/\\"))
+	return sourcePath, destPath
+}
+
+func explodeDirs(config *cli_parsing.Config, destPath string) error {
+	logging.Log(logging.Action, "", "Exploding directories...")
+	for i, explodeDir := range config.ExplodeDirs {
+		logging.SetOperation(fmt.Sprintf("explode%d", i+1))
+		if config.DryRun {
+			if config.ExplodeRecursive {
+				logging.LogDryRun(logging.Detail, logging.IconExplode, "Would have exploded every %s folder in %s into the folder holding it", explodeDir, destPath)
+			} else {
+				logging.LogDryRun(logging.Detail, logging.IconExplode, "If located, would have exploded %s into %s", explodeDir, destPath)
+			}
+			continue
+		}
+		if config.ExplodeRecursive || file_operations.IsExplodeGlob(explodeDir) {
+			explode := file_operations.ExplodeMatchingFolders
+			if config.ExplodeRecursive {
+				explode = file_operations.ExplodeFoldersRecursive
+			}
+			exploded, err := explode(destPath, explodeDir)
+			if err != nil {
+				return fmt.Errorf("error exploding directory: %w", err)
+			}
+			if len(exploded) == 0 {
+				logging.Log(logging.Detail, logging.IconSkip, "No %s folders found in %s; skipping", explodeDir, destPath)
+			}
+			for _, folder := range exploded {
+				logging.Log(logging.Detail, logging.IconExplode, "Exploded %s into %s", folder, filepath.Join(destPath, filepath.Dir(folder)))
+			}
+			continue
+		}
+		found, err := file_operations.ExplodeFolder(destPath, explodeDir)
+		if !found {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("error exploding directory: %w", err)
+		}
+
+		logging.Log(logging.Detail, logging.IconExplode, "Exploded %s into %s", explodeDir, destPath)
+	}
+
+	logging.LogComplete("Exploding")
+	return nil
+}
+
+func processRenames(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	logging.Log(logging.Action, "", "Processing renames...")
+	renamed := make(map[string]string)
+	for i, r := range config.Renames {
+		logging.SetOperation(fmt.Sprintf("rename%d", i+1))
+		if config.DryRun {
+			logging.LogDryRun(logging.Detail, logging.IconRename, "If located in %s, would have renamed %s to %s", destPath, r.OldName, r.NewName)
+			continue
+		}
+
+		oldPath := filepath.Join(destPath, r.OldName)
+		newPath := filepath.Join(destPath, r.NewName)
+
+		_, err := file_operations.Filesystem().Stat(oldPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				logging.Log(logging.Detail, logging.IconSkip, "Unable to locate %s in %s; skipping", r.OldName, destPath)
+				continue
+			}
+			return fmt.Errorf("error renaming item: %w", err)
+		}
+
+		// renaming a protected file that was already on the target, or a folder holding one, would take it out
+		// from under its glob, and renaming onto one would overwrite it; what this run copied is renamed as asked
+		if file_operations.HoldsProtected(r.OldName, plan.protected) || file_operations.HoldsProtected(r.NewName, plan.protected) {
+			logging.Log(logging.Detail, logging.IconSkip, "Left %s as it is: renaming it to %s would move or overwrite protected files already on the target (save data or matching '--protect')", r.OldName, r.NewName)
+			continue
+		}
+
+		if err := file_operations.Filesystem().Rename(oldPath, newPath); err != nil {
+			return fmt.Errorf("error renaming item: %w", err)
+		}
+
+		logging.Log(logging.Detail, logging.IconRename, "Renamed %s to %s", r.OldName, r.NewName)
+		renamed[filepath.ToSlash(r.OldName)] = filepath.ToSlash(r.NewName)
+	}
+
+	logging.SetOperation("renames")
+	if err := fixCueReferences(destPath, renamed); err != nil {
+		return err
+	}
+
+	logging.LogComplete("Renames")
+	return nil
+}
+
+// points cue sheets in destPath at their tracks' new names; renamed maps old to new paths relative to destPath
+func fixCueReferences(destPath string, renamed map[string]string) error {
+	if _, err := file_operations.FixCueReferences(destPath, renamed); err != nil {
+		return fmt.Errorf("error updating cue sheets: %w", err)
+	}
+	return nil
+}
+
+func processRewrites(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	logging.Log(logging.Action, "", "Processing rewrites...")
+	for i, r := range config.FileRewrites {
+		logging.SetOperation(fmt.Sprintf("rewrite%d", i+1))
+		if config.DryRun {
+			rewriteType := "literal"
+			if config.RewritesAreRegex {
+				rewriteType = "regex"
+			}
+			logging.LogDryRun(logging.Detail, logging.IconRewrite, "If files found matching glob '%s' located in %s, would have rewritten %s to %s via %s search", r.FileGlob, destPath, r.SearchPattern, r.ReplacePattern, rewriteType)
+			continue
+		}
+
+		found, binary, err := file_operations.SearchAndReplace(destPath, r.FileGlob, r.SearchPattern, r.ReplacePattern, config.RewritesAreRegex, config.RewriteBinary, plan.protected)
+
+		if !found {
+			logging.Log(logging.Detail, logging.IconSkip, "No files matching glob '%s' in %s for rewrite of %s to %s; skipping...", r.FileGlob, destPath, r.SearchPattern, r.ReplacePattern)
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("error rewriting %s to %s for glob %s: %w", r.SearchPattern, r.ReplacePattern, r.FileGlob, err)
+		}
+
+		if len(binary) > 0 {
+			logging.LogWarning("Left %d file(s) matching glob '%s' unchanged as they look binary, not text (e.g. %s); check the glob, or pass '--rewriteBinary' to rewrite them anyway", len(binary), r.FileGlob, filepath.Base(binary[0]))
+		}
+	}
+	logging.LogComplete("Rewrites")
+	return nil
+}
+
+// deletes the files in destPath matching '--deleteAfter', other than save data and '--protect' matches, and the
+// folders they leave empty, into the trash with '--useTrash'
+func deleteAfter(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	logging.Log(logging.Action, "", "Deleting files matching '--deleteAfter'...")
+	logging.SetOperation("deleteAfter")
+	matches, kept, err := file_operations.FindMatching(destPath, config.DeleteAfter, config.Protect)
+	if err != nil {
+		return fmt.Errorf("error finding files to delete: %w", err)
+	}
+	if len(matches) == 0 {
+		logging.Log(logging.Detail, logging.IconSkip, "No files matching %s in %s; skipping", strings.Join(config.DeleteAfter, ", "), destPath)
+	}
+
+	verb := "deleted"
+	if config.UseTrash {
+		verb = "moved to the trash"
+	}
+	if config.DryRun {
+		for _, relPath := range matches {
+			logging.LogDryRun(logging.Detail, logging.IconClean, "Would have %s %s", verb, relPath)
+		}
+	} else {
+		remove := file_operations.DeleteFiles
+		if config.UseTrash {
+			remove = func(dir string, relPaths []string) error {
+				return file_operations.TrashFiles(dir, relPaths, plan.trash(config))
+			}
+		}
+		if err := remove(destPath, matches); err != nil {
+			return fmt.Errorf("error deleting files: %w", err)
+		}
+		for _, relPath := range matches {
+			logging.Log(logging.Detail, logging.IconClean, "%s %s", strings.ToUpper(verb[:1])+verb[1:], relPath)
+		}
+	}
+	logKeptProtected(kept, "save data or matching '--protect'")
+
+	logging.LogComplete("Deletions")
+	return nil
+}
+
+func processMapping(config *cli_parsing.Config, plan mappingPlan) error {
+	mapping, sourcePath, destPath, opts := plan.mapping, plan.sourcePath, plan.destPath, plan.opts
+
+	logging.SetMapping(plan.id, fmt.Sprintf("%s -> %s", mapping.Source, mapping.Destination))
+	logging.Log(logging.Base, "", "Beginning operations for \033[1;34m%s -> %s\033[0m (%s -> %s) [%s]",
+		mapping.Source, mapping.Destination, sourcePath, destPath, plan.id)
+
+	// Clean target directory if requested
+	if config.CleanTarget {
+		logging.SetOperation("clean")
+		if err := cleanTargetDir(config, plan, destPath); err != nil {
+			return err
+		}
+	}
+
+	if opts.Ignore.Len() > 0 {
+		logging.Log(logging.Action, "", "Honoring %d rule(s) from %s files", opts.Ignore.Len(), copy_funcs.IgnoreFileName)
+	}
+
+	if (len(config.Renames) > 0 || len(config.FileRewrites) > 0) && !config.DryRun {
+		protected, err := file_operations.FindProtected(destPath, config.Protect)
+		if err != nil {
+			return fmt.Errorf("error scanning target directory: %w", err)
+		}
+		plan.protected = protected
+	}
+
+	// the device's game lists, kept to merge the source's into once the copy has replaced them
+	var deviceLists map[string][]byte
+	if config.MergeGamelists != "" {
+		lists, err := gamelist.ReadLists(file_operations.Filesystem(), destPath)
+		if err != nil {
+			return err
+		}
+		deviceLists = lists
+	}
+
+	// Copy files
+	logging.Log(logging.Action, "", "Beginning copy...")
+	filesCopied, err := copy_funcs.CopyFiles(sourcePath, destPath, opts)
+	if err != nil {
+		return fmt.Errorf("error copying files: %w", err)
+	}
+	logging.SetOperation("")
+	if failures := opts.Errors.Failures(); len(failures) > 0 {
+		logging.LogWarning("Copy finished, but %d file(s) couldn't be copied:", len(failures))
+		for _, failure := range failures {
+			logging.Log(logging.Action, logging.IconError, "%s: %v", failure.RelPath, failure.Err)
+		}
+	} else {
+		logging.LogComplete("Copy")
+	}
+
+	if config.LoopbackCopy && len(filesCopied) > 0 {
+		logging.SetOperation("loopback")
+		logging.Log(logging.Action, "", "Beginning re-glob-and-copy-matches [ignoring excludes!!!]...")
+		globifiedFileList := copy_funcs.GlobifyFilenameOfPathList(filesCopied)
+
+		logging.Log(logging.Detail, logging.IconCopy, "Beginning loopback from %d glob(s): [%s]", len(filesCopied), strings.Join(globifiedFileList, ", "))
+		// everything but the patterns carries over, so what's left out, renamed, or moved on the first pass is
+		// left out, renamed, or moved again; the globs made from the copied files are always doublestar
+		loopbackOpts := opts
+		loopbackOpts.Include, loopbackOpts.Exclude, loopbackOpts.GlobDialect = globifiedFileList, nil, ""
+		_, err := copy_funcs.CopyFiles(sourcePath, destPath, loopbackOpts)
+		if err != nil {
+			return fmt.Errorf("error copying files: %w", err)
+		}
+		logging.SetOperation("")
+		logging.LogComplete("Re-glob-and-copy-matches")
+	}
+
+	// safe naming, game folders, and shortening rename tracks on the way over, leaving the sheets that name them
+	// pointing at nothing
+	if plan.renamesFiles() && !config.DryRun {
+		logging.SetOperation("sanitize")
+		renamed := make(map[string]string)
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			if destRelPath := opts.DestRelPath(f.RelPath); destRelPath != f.RelPath {
+				relPath, destRelPath := filepath.ToSlash(f.RelPath), filepath.ToSlash(destRelPath)
+				renamed[relPath] = destRelPath
+				// as a sheet copied alongside it, into a folder that was renamed too, names it
+				renamed[path.Join(path.Dir(destRelPath), path.Base(relPath))] = destRelPath
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("unable to scan %s: %w", sourcePath, err)
+		}
+		if err := fixCueReferences(destPath, renamed); err != nil {
+			return err
+		}
+	}
+
+	if opts.Stream.Verify != nil && !config.DryRun {
+		logging.SetOperation("verify")
+		if err := reportVerification(opts.Stream.Verify); err != nil {
+			return err
+		}
+	}
+
+	// Post-copy operations
+	if err := runPostCopyOperations(config, plan, destPath); err != nil {
+		return err
+	}
+
+	if len(plan.mediaMoved) > 0 || len(plan.mediaLeftOut) > 0 {
+		logging.SetOperation("gamelist")
+		if err := remapGamelistMedia(config, plan, destPath); err != nil {
+			return err
+		}
+	}
+
+	// after the media is remapped, so the game lists name the art where it was moved to before it's renamed
+	if plan.renamesFiles() && !config.DryRun {
+		logging.SetOperation("sanitize")
+		if err := renameGamelistPaths(config, plan, destPath); err != nil {
+			return err
+		}
+	}
+
+	// what ScreenScraper knows of the games the game list is missing, by slash separated path relative to destPath
+	var scraped map[string]gamelist.GameInfo
+	if plan.scraper != nil {
+		logging.SetOperation("scrape")
+		found, err := scrapeGames(config, plan, destPath)
+		if err != nil {
+			return err
+		}
+		scraped = found
+	}
+
+	// box art for games whose game list entries don't point at any, by slash separated path relative to destPath
+	var fetched map[string]string
+	if plan.thumbnails != nil {
+		logging.SetOperation("thumbnails")
+		art, err := fetchThumbnails(config, plan, destPath)
+		if err != nil {
+			return err
+		}
+		fetched = art
+	}
+
+	if config.GenerateGamelist || len(scraped) > 0 {
+		logging.SetOperation("gamelist")
+		if err := generateGamelist(config, plan, destPath, scraped); err != nil {
+			return err
+		}
+	}
+
+	if len(scraped) > 0 || len(fetched) > 0 {
+		details := make(map[string]gamelist.GameInfo, len(scraped)+len(fetched))
+		for relPath, info := range scraped {
+			details[relPath] = info
+		}
+		for relPath, image := range fetched {
+			info := details[relPath]
+			info.Image = image
+			details[relPath] = info
+		}
+		logging.SetOperation("gamelist")
+		if err := fillGamelist(config, plan, destPath, details); err != nil {
+			return err
+		}
+	}
+
+	if config.MetadataLang != "" || len(config.GamelistTags) > 0 {
+		logging.SetOperation("gamelist")
+		if err := filterGamelists(config, destPath); err != nil {
+			return err
+		}
+	}
+
+	if config.ConvertGamelist != "" {
+		logging.SetOperation("gamelist")
+		if err := convertGamelist(config, mapping, destPath); err != nil {
+			return err
+		}
+	}
+
+	if len(deviceLists) > 0 {
+		logging.SetOperation("merge")
+		mergeGamelists(config, mapping, destPath, deviceLists)
+	}
+
+	// filters leave game lists naming games that weren't copied, which the frontend shows as broken entries
+	if config.PruneGamelists && !config.DryRun {
+		logging.SetOperation("gamelist")
+		if err := pruneGamelists(mapping, destPath); err != nil {
+			return err
+		}
+	}
+
+	// filters and explodes can leave a disc's sheet behind without its tracks, which only shows on the device
+	if !config.DryRun {
+		logging.SetOperation("references")
+		if err := checkDiscReferences(destPath); err != nil {
+			return err
+		}
+	}
+
+	// after everything that writes art, so what's fetched and scraped goes with the rest
+	if folder, ok := config.MoveArtwork[mapping.Source]; ok {
+		logging.SetOperation("artwork")
+		if err := moveArtwork(config, destPath, folder); err != nil {
+			return err
+		}
+	}
+
+	if config.Manifest {
+		logging.SetOperation("manifest")
+		if err := writeManifest(config, destPath, opts.Stream.Checksums); err != nil {
+			return err
+		}
+	}
+
+	// last, so nothing written afterwards (the manifest included) keeps a time of its own
+	if config.Deterministic && !config.DryRun {
+		logging.SetOperation("stamp")
+		if err := file_operations.StampTree(destPath, config.BuildTime); err != nil {
+			return fmt.Errorf("error setting modification times: %w", err)
+		}
+	}
+
+	if config.Flush && !config.DryRun {
+		logging.SetOperation("flush")
+		logging.Log(logging.Action, "", "Flushing %s to disk...", destPath)
+		if err := file_operations.SyncTree(destPath); err != nil {
+			return fmt.Errorf("error flushing target: %w", err)
+		}
+		logging.LogComplete("Flush")
+	}
+
+	logging.SetOperation("")
+	logging.Log(logging.Base, "", "Operations for %s -> %s complete!", mapping.Source, mapping.Destination)
+	logging.ClearMapping()
+	return nil
+}
+
+// moves the artwork folder out of destPath into folder, relative to the target root, for firmware that shows box
+// art from there
+func moveArtwork(config *cli_parsing.Config, destPath string, folder string) error {
+	artworkPath := filepath.Join(destPath, config.ArtworkDir)
+	folderPath := filepath.Join(config.TargetDir, folder)
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have moved the artwork in %s to %s", artworkPath, folderPath)
+		return nil
+	}
+
+	moved, found, err := file_operations.MoveFolderContents(artworkPath, folderPath)
+	if err != nil {
+		return fmt.Errorf("error moving artwork: %w", err)
+	}
+	if !found {
+		logging.Log(logging.Detail, logging.IconSkip, "No '%s' folder to move artwork from; skipping", config.ArtworkDir)
+		return nil
+	}
+	logging.Log(logging.Action, "", "Moved %d artwork item(s) to %s", moved, folderPath)
+
+	// the platform folder is stamped and flushed later, but this is outside it
+	if config.Deterministic {
+		if err := file_operations.StampTree(folderPath, config.BuildTime); err != nil {
+			return fmt.Errorf("error setting modification times: %w", err)
+		}
+	}
+	if config.Flush {
+		if err := file_operations.SyncTree(folderPath); err != nil {
+			return fmt.Errorf("error flushing target: %w", err)
+		}
+	}
+	return nil
+}
+
+// copies the BIOS files in '--bios' to the profile's BIOS folder, checking those the platform table knows against
+// their known-good MD5s and naming them as emulators look for them, then lists the platforms copied whose games
+// won't boot without a BIOS file the target doesn't have
+func copyBios(config *cli_parsing.Config, plans []mappingPlan) error {
+	logging.SetOperation("bios")
+	defer logging.SetOperation("")
+	biosPath := filepath.Join(config.TargetDir, config.BiosFolder)
+	logging.Log(logging.Base, "", "Copying BIOS files from %s to %s...", config.BiosSource, biosPath)
+
+	// lowercase names of the BIOS files the target has, or will have once a dry run is done for real
+	present := make(map[string]bool)
+	copied := 0
+	err := filepath.Walk(config.BiosSource, func(filePath string, entry os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && filePath != config.BiosSource {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(config.BiosSource, filePath)
+		if err != nil {
+			return err
+		}
+
+		if platform, file := platforms.FindBios(entry.Name()); file != nil {
+			if len(file.MD5) > 0 {
+				sum, err := md5File(filePath)
+				if err != nil {
+					return err
+				}
+				if !file.Good(sum) {
+					logging.LogWarning("%s isn't a known-good %s BIOS (its MD5 is %s); %s games may not boot with it", relPath, platform.Name, sum, platform.Name)
+				}
+			}
+			// emulators on the device's filesystem may not find 'SCPH5501.BIN' when looking for 'scph5501.bin'
+			if entry.Name() != file.Name {
+				logging.Log(logging.Detail, "", "Renaming %s to %s, as emulators look for it", relPath, file.Name)
+				relPath = filepath.Join(filepath.Dir(relPath), file.Name)
+			}
+		}
+		present[strings.ToLower(filepath.Base(relPath))] = true
+
+		destPath := filepath.Join(biosPath, relPath)
+		if config.DryRun {
+			logging.LogDryRun(logging.Detail, logging.IconCopy, "Would have copied %s to %s", filePath, destPath)
+			copied++
+			return nil
+		}
+		if err := file_operations.Filesystem().MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return fmt.Errorf("failed to create BIOS folder %s: %w", filepath.Dir(destPath), err)
+		}
+		if err := file_operations.CopyFile(filePath, destPath); err != nil {
+			return err
+		}
+		logging.Log(logging.Detail, logging.IconCopy, "Copied %s", relPath)
+		copied++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error copying BIOS files: %w", err)
+	}
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have copied %d BIOS file(s) to %s", copied, biosPath)
+	} else {
+		logging.Log(logging.Action, logging.IconComplete, "Copied %d BIOS file(s) to %s", copied, biosPath)
+		if config.Deterministic {
+			if err := file_operations.StampTree(biosPath, config.BuildTime); err != nil {
+				return fmt.Errorf("error setting modification times: %w", err)
+			}
+		}
+		if config.Flush {
+			if err := file_operations.SyncTree(biosPath); err != nil {
+				return fmt.Errorf("error flushing target: %w", err)
+			}
+		}
+	}
+
+	// BIOS files already on the target, e.g. copied by an earlier run, count too
+	err = fsys.Walk(file_operations.Filesystem(), biosPath, func(filePath string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			present[strings.ToLower(info.Name())] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error reading BIOS folder %s: %w", biosPath, err)
+	}
+
+	reported := make(map[string]bool)
+	for _, plan := range plans {
+		platform := platforms.ForMapping(plan.mapping.Source, plan.mapping.Destination)
+		if platform == nil || reported[platform.Name] {
+			continue
+		}
+		reported[platform.Name] = true
+		missing := platform.MissingBios(present)
+		if len(missing) == 1 {
+			logging.LogWarning("%s games won't boot without %s in %s", platform.Name, missing[0], biosPath)
+		} else if len(missing) > 1 {
+			logging.LogWarning("%s games won't boot without one of %s in %s", platform.Name, strings.Join(missing, ", "), biosPath)
+		}
+	}
+	return nil
+}
+
+// returns the lowercase hex MD5 of the file at filePath
+func md5File(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s for hashing: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hasher := md5.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// records every file in the platform folder to its checksum manifest. Checksums recorded during the copy and
+// those in the previous manifest are reused, so only files changed since (e.g. by '--rewrite') are re-read.
+func writeManifest(config *cli_parsing.Config, destPath string, recorder *file_operations.ChecksumRecorder) error {
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have written a checksum manifest to %s", filepath.Join(destPath, device_state.ManifestFileName))
+		return nil
+	}
+
+	logging.Log(logging.Action, "", "Writing checksum manifest...")
+
+	known := make([]device_state.ManifestEntry, 0)
+	previous, err := device_state.LoadManifest(destPath)
+	if err != nil {
+		logging.LogWarning("%v; rebuilding it from the target", err)
+	} else if previous != nil && previous.Algorithm == recorder.Algorithm() {
+		known = append(known, previous.Files...)
+	}
+
+	absDest, err := filepath.Abs(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute destination path: %w", err)
+	}
+	for filePath, recorded := range recorder.Files() {
+		relPath, err := filepath.Rel(absDest, filePath)
+		if err != nil {
+			continue
+		}
+		known = append(known, device_state.ManifestEntry{
+			Path:    filepath.ToSlash(relPath),
+			Size:    recorded.Size,
+			ModTime: recorded.ModTime.UTC(),
+			Hash:    hex.EncodeToString(recorded.Sum),
+		})
+	}
+
+	manifest, hashed, err := device_state.BuildManifest(destPath, recorder.Algorithm(), known)
+	if err != nil {
+		return fmt.Errorf("error building checksum manifest: %w", err)
+	}
+	// every file is about to be given the build time, which a later run must see as unchanged
+	if config.Deterministic {
+		manifest.UpdatedAt = config.BuildTime
+		for i := range manifest.Files {
+			manifest.Files[i].ModTime = config.BuildTime
+		}
+	}
+	if err := manifest.Save(destPath); err != nil {
+		return err
+	}
+	if config.SigningKey != nil {
+		if err := device_state.SignManifest(destPath, config.SigningKey); err != nil {
+			return err
+		}
+	}
+
+	logging.Log(logging.Action, logging.IconComplete, "Recorded %d file(s) in %s (%d re-read from the target)", len(manifest.Files), device_state.ManifestFileName, hashed)
+	return nil
+}
+
+// records what each mapping copied in the target's state file, keeping what earlier runs recorded for mappings
+// this one didn't copy. Files are hashed from the source through the checksum cache, except those '--rewrite'
+// changed, which are hashed from the target.
+func writeTargetState(config *cli_parsing.Config, plans []mappingPlan, cache *file_operations.ChecksumCache) error {
+	statePath := device_state.TargetStatePath(config.TargetDir)
+	if config.DryRun {
+		logging.LogDryRun(logging.Base, "", "Would have recorded the copy in %s", statePath)
+		return nil
+	}
+
+	logging.Log(logging.Base, "", "Recording the copy in %s...", statePath)
+	state, err := device_state.LoadTargetState(config.TargetDir)
+	if err != nil {
+		logging.LogWarning("%v; starting a new one", err)
+	}
+	if state == nil {
+		state = device_state.NewTargetState()
+	}
+
+	sourceDir, err := filepath.Abs(config.SourceDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute source path: %w", err)
+	}
+	now := time.Now().UTC()
+	if config.Deterministic {
+		now = config.BuildTime
+	}
+	files := 0
+	for _, plan := range plans {
+		failed := make(map[string]bool)
+		for _, failure := range plan.opts.Errors.Failures() {
+			failed[failure.RelPath] = true
+		}
+
+		recorded := device_state.MappingState{
+			Source:      plan.mapping.Source,
+			Destination: plan.mapping.Destination,
+			SourceDir:   sourceDir,
+			CopiedAt:    now,
+			ConfigHash:  config.Hash(),
+			Args:        config.ExportedRecipe.Args,
+			Files:       make([]device_state.CopiedFile, 0),
+		}
+		err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+			relPath := copiedPath(config, plan.opts, f.RelPath)
+			if failed[f.RelPath] || deletedAfter(config, relPath) {
+				return nil
+			}
+
+			copied := device_state.CopiedFile{Path: relPath, Size: f.Size}
+			if sourcePath := filepath.ToSlash(f.RelPath); sourcePath != relPath {
+				copied.Source = sourcePath
+			}
+			hashPath := filepath.Join(plan.sourcePath, f.RelPath)
+			if editedAfterCopy(config, plan, relPath) {
+				hashPath = filepath.Join(plan.destPath, filepath.FromSlash(relPath))
+				info, err := file_operations.Filesystem().Stat(hashPath)
+				// a converted game list can be replaced by the frontend's own
+				if os.IsNotExist(err) && editedGamelist(config, plan, relPath) {
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("failed to get file info for %s: %w", hashPath, err)
+				}
+				copied.Size = info.Size()
+			}
+			sum, err := cache.HashFile(hashPath, device_state.HashAlgorithm)
+			if err != nil {
+				return err
+			}
+			copied.Hash = hex.EncodeToString(sum)
+			recorded.Files = append(recorded.Files, copied)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error recording %s: %w", plan.mapping.Destination, err)
+		}
+		state.Record(recorded)
+		files += len(recorded.Files)
+	}
+
+	state.UpdatedAt = now
+	if err := state.Save(config.TargetDir); err != nil {
+		return err
+	}
+	if config.Deterministic {
+		if err := file_operations.StampTree(filepath.Join(config.TargetDir, device_state.TargetStateDir), config.BuildTime); err != nil {
+			return err
+		}
+	}
+	logging.Log(logging.Base, logging.IconComplete, "Recorded %d file(s) across %d mapping(s) in %s", files, len(plans), device_state.TargetStateFileName)
+	return nil
+}
+
+// runs every mapping against a staging folder beside its destination, then swaps all staged folders into
+// place only once every mapping has succeeded. Any failure discards the staging folders and leaves the
+// target untouched; a swap that fails puts back the folders already swapped first.
+func processMappingsTransactionally(config *cli_parsing.Config, plans []mappingPlan) error {
+	discardAll := func() {
+		for _, plan := range plans {
+			if err := file_operations.DiscardStaging(plan.destPath); err != nil {
+				logging.LogWarning("%v", err)
+			}
+		}
+	}
+
+	for i, plan := range plans {
+		if err := checkDeadline(plans[i:]); err != nil {
+			logging.Log(logging.Base, "", "Discarding staged changes; the target has not been modified")
+			discardAll()
+			return err
+		}
+		logging.Log(logging.Base, "", "Staging %s -> %s in %s", plan.mapping.Source, plan.mapping.Destination, file_operations.StagingPath(plan.destPath))
+
+		stagingPath, err := file_operations.PrepareStaging(plan.destPath, !config.CleanTarget, config.CleanKeeps())
+		if err != nil {
+			discardAll()
+			return err
+		}
+
+		stagedPlan := plan
+		stagedPlan.destPath = stagingPath
+		if err := processMapping(config, stagedPlan); err != nil {
+			logging.Log(logging.Base, "", "Discarding staged changes; the target has not been modified")
+			discardAll()
+			return err
+		}
+	}
+
+	logging.Log(logging.Base, "", "All mappings staged; swapping staged folders into place...")
+	// the old contents of every folder are kept aside until all are swapped, so a failed swap can be undone
+	for i, plan := range plans {
+		logging.SetMapping(plan.id, fmt.Sprintf("%s -> %s", plan.mapping.Source, plan.mapping.Destination))
+		logging.SetOperation("swap")
+		if err := file_operations.SwapInStaging(plan.destPath); err != nil {
+			logging.ClearMapping()
+			undoSwaps(plans[:i])
+			discardAll()
+			return err
+		}
+	}
+
+	for _, plan := range plans {
+		logging.SetMapping(plan.id, fmt.Sprintf("%s -> %s", plan.mapping.Source, plan.mapping.Destination))
+		logging.SetOperation("swap")
+		finish := file_operations.FinishSwap
+		if config.CleanTarget && config.UseTrash {
+			// cleaning staged nothing but the protected files, so the old contents are what was cleaned
+			finish = func(destPath string) error { return file_operations.FinishSwapToTrash(destPath, plan.trash(config)) }
+		}
+		if err := finish(plan.destPath); err != nil {
+			return err
+		}
+		if config.Flush {
+			if err := file_operations.SyncTree(plan.destPath); err != nil {
+				return fmt.Errorf("error flushing target: %w", err)
+			}
+		}
+		logging.Log(logging.Action, logging.IconComplete, "Swapped in %s", plan.destPath)
+	}
+	logging.ClearMapping()
+	logging.LogComplete("Swap")
+
+	return nil
+}
+
+// puts back the old contents of the folders swapped in before a swap failed, newest first, and reports any left
+// holding their new contents
+func undoSwaps(swapped []mappingPlan) {
+	left := make([]string, 0)
+	for i := len(swapped) - 1; i >= 0; i-- {
+		if err := file_operations.UndoSwap(swapped[i].destPath); err != nil {
+			logging.LogWarning("%v", err)
+			left = append(left, swapped[i].destPath)
+		}
+	}
+	if len(left) == 0 {
+		logging.Log(logging.Base, "", "Put back the %d folder(s) already swapped and discarded staged changes; the target has not been modified", len(swapped))
+		return
+	}
+	logging.LogWarning("The target is only partly updated: %d folder(s) couldn't be put back, and may hold their new contents, with their old ones in a hidden '.romcopy-old' folder beside them; the rest hold their old contents:", len(left))
+	for _, destPath := range left {
+		logging.Log(logging.Action, "", "• %s", destPath)
+	}
+}
+
+// tallies what the doctor command found; any problem fails the run
+type doctorReport struct {
+	problems int
+	warnings int
+}
+
+func (r *doctorReport) ok(format string, args ...interface{}) {
+	logging.Log(logging.Action, logging.IconComplete, format, args...)
+}
+
+func (r *doctorReport) warn(format string, args ...interface{}) {
+	r.warnings++
+	logging.Log(logging.Action, logging.IconWarning, format, args...)
+}
+
+func (r *doctorReport) problem(format string, args ...interface{}) {
+	r.problems++
+	logging.Log(logging.Action, logging.IconError, format, args...)
+}
+
+// inspects the target and reports anything that would stop the mappings from working on the device: unknown
+// or incompatible firmware and filesystems, platform folders the firmware won't look in, insufficient space,
+// and names the filesystem can't hold
+func runDoctor(config *cli_parsing.Config, plans []mappingPlan) error {
+	report := &doctorReport{}
+	logging.Log(logging.Base, "", "Inspecting %s...", config.TargetDir)
+
+	if info, err := os.Stat(config.TargetDir); err != nil || !info.IsDir() {
+		report.problem("Target %s doesn't exist or isn't a directory; is the card mounted?", config.TargetDir)
+		return fmt.Errorf("doctor found %d problem(s)", report.problems)
+	}
+
+	targetOS := disk_info.DetectTargetOS(config.TargetDir)
+	switch {
+	case targetOS == nil:
+		report.warn("No known firmware found on the target; folder layout can't be checked")
+	case targetOS.Version != "":
+		report.ok("Firmware: %s %s", targetOS.Name, targetOS.Version)
+	default:
+		report.ok("Firmware: %s", targetOS.Name)
+	}
+
+	fsType, err := disk_info.FilesystemType(config.TargetDir)
+	switch {
+	case err != nil:
+		report.warn("Unable to determine the target's filesystem: %v", err)
+	case targetOS != nil && len(targetOS.Filesystems) > 0 && !containsString(targetOS.Filesystems, fsType):
+		report.problem("Filesystem is %s, but %s can only read %s", fsType, targetOS.Name, strings.Join(targetOS.Filesystems, " or "))
+	default:
+		report.ok("Filesystem: %s", fsType)
+	}
+
+	requiredFiles, required := planTotals(plans)
+	if free, err := disk_info.FreeSpace(config.TargetDir); err != nil {
+		report.warn("Unable to determine free space on the target: %v", err)
+	} else if uint64(required) > free {
+		report.problem("%d file(s) totalling %s won't fit in the %s free on the target", requiredFiles, logging.FormatBytes(uint64(required)), logging.FormatBytes(free))
+	} else if len(plans) > 0 {
+		report.ok("Free space: %s, %s needed for %d file(s) (before any overwrites)", logging.FormatBytes(free), logging.FormatBytes(uint64(required)), requiredFiles)
+	} else {
+		report.ok("Free space: %s", logging.FormatBytes(free))
+	}
+
+	for _, plan := range plans {
+		destination := filepath.ToSlash(plan.mapping.Destination)
+		if targetOS != nil && targetOS.RomsDir != "" {
+			top := strings.SplitN(destination, "/", 2)[0]
+			switch {
+			case top == targetOS.RomsDir && destination != top:
+				report.ok("%s -> %s is in %s's '%s' folder", plan.mapping.Source, destination, targetOS.Name, targetOS.RomsDir)
+			case strings.EqualFold(top, targetOS.RomsDir) && destination != top:
+				report.warn("%s -> %s: %s's games folder is '%s'; the case differs, which matters on case-sensitive filesystems", plan.mapping.Source, destination, targetOS.Name, targetOS.RomsDir)
+			default:
+				report.warn("%s -> %s is outside '%s', where %s looks for platform folders", plan.mapping.Source, destination, targetOS.RomsDir, targetOS.Name)
+			}
+		}
+
+		if _, err := os.Stat(plan.destPath); os.IsNotExist(err) {
+			report.warn("%s -> %s: the destination doesn't exist on the target yet; check that the device expects a platform folder by that name", plan.mapping.Source, destination)
+		}
+	}
+
+	if targetOS != nil && targetOS.MaxFolderEntries > 0 {
+		crowded, err := crowdedFolders(config, plans, targetOS.MaxFolderEntries)
+		if err != nil {
+			return err
+		}
+		for _, folder := range crowded {
+			report.warn("%s will hold %d entries, and %s's game list gets slow past about %d in a folder; consider splitting it into alphabetical subfolders", folder.destination, folder.entries, targetOS.Name, targetOS.MaxFolderEntries)
+		}
+	}
+
+	if fsType == disk_info.FilesystemFAT {
+		for _, issue := range fat32Issues(plans) {
+			report.problem("%s: %s", issue.Path, issue.Reason)
+		}
+	}
+
+	if !config.RenameReserved && disk_info.WindowsAccessible(config.TargetDir) {
+		reserved, err := reservedNamePaths(plans)
+		if err != nil {
+			return err
+		}
+		for _, reservedPath := range reserved {
+			report.problem("%s uses a name Windows reserves for devices; rerun with '--renameReserved' or exclude it", reservedPath)
+		}
+	}
+
+	if !config.SanitizeNames && disk_info.WindowsAccessible(config.TargetDir) {
+		illegal, err := illegalNamePaths(plans)
+		if err != nil {
+			return err
+		}
+		for _, illegalPath := range illegal {
+			report.problem("%s has a name with characters the target's filesystem doesn't allow; rerun with '--sanitizeNames' or exclude it", illegalPath)
+		}
+	}
+
+	stale, _, err := findArtifacts(config, plans)
+	if err != nil {
+		report.warn("Unable to check for files left by interrupted runs: %v", err)
+	} else if len(stale) > 0 {
+		report.warn("%d file(s) or folder(s) left by interrupted runs are taking %s; the next copy removes them", len(stale), logging.FormatBytes(uint64(artifactsSize(stale))))
+	}
+
+	fmt.Println()
+	if report.problems > 0 {
+		return fmt.Errorf("doctor found %d problem(s) and %d warning(s)", report.problems, report.warnings)
+	}
+
+	logging.Log(logging.Base, logging.IconComplete, "No problems found (%d warning(s))", report.warnings)
+	return nil
+}
+
+// prints the command line of the chosen example, or of every example, with placeholders filled from the
+// environment; with 'run', executes the chosen example with this binary instead
+func runExamples(config *cli_parsing.Config) error {
+	env := examples.DetectEnvironment(config.SourceDir, config.TargetDir)
+
+	chosen := examples.All
+	if config.ExampleName != "" {
+		example, _ := examples.Find(config.ExampleName)
+		chosen = []examples.Example{example}
+	}
+
+	if config.ExampleRun {
+		return runExample(config, chosen[0], env)
+	}
+
+	logging.Log(logging.Base, "", "Examples (%s):", env.Describe())
+	fmt.Println()
+	unfilled := make([]string, 0)
+	for _, example := range chosen {
+		args, missing := example.Fill(env)
+		for _, placeholder := range missing {
+			if !containsString(unfilled, placeholder) {
+				unfilled = append(unfilled, placeholder)
+			}
+		}
+
+		logging.Log(logging.Base, "", "%s: %s", example.Name, example.Description)
+		fmt.Println(examples.CommandLine(os.Args[0], args))
+		fmt.Println()
+	}
+
+	if len(unfilled) > 0 {
+		fmt.Printf("[Hint: pass %s along with 'examples' to fill in %s]\n", placeholderFlags(unfilled), strings.Join(unfilled, " and "))
+	}
+	fmt.Printf("[Hint: run an example with '%s examples run <name>', adding '--dryRun' to see what it would do first]\n", os.Args[0])
+	return nil
+}
+
+// runs example with this binary, passing along the flags that change how a run behaves rather than what it copies
+func runExample(config *cli_parsing.Config, example examples.Example, env examples.Environment) error {
+	args, missing := example.Fill(env)
+	if len(missing) > 0 {
+		return fmt.Errorf("example '%s' needs %s filled in; pass %s along with 'examples run'", example.Name, strings.Join(missing, " and "), placeholderFlags(missing))
+	}
+
+	if config.DryRun {
+		args = append(args, "--dryRun")
+	}
+	if config.SkipConfirm {
+		args = append(args, "--skipConfirm")
+	}
+	if len(config.Yes) > 0 {
+		args = append(args, "--yes", strings.Join(config.Yes, ","))
+	}
+	if len(config.Confirm) > 0 {
+		args = append(args, "--confirm", strings.Join(config.Confirm, ","))
+	}
+	if config.ExpectDevice != "" {
+		args = append(args, "--expectDevice", config.ExpectDevice)
+	}
+	if config.Verbose {
+		args = append(args, "--verbose")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("unable to find the ROMCopyEngine executable: %w", err)
+	}
+
+	logging.Log(logging.Base, "", "Running example '%s' (%s):", example.Name, env.Describe())
+	fmt.Println(examples.CommandLine(os.Args[0], args))
+	fmt.Println()
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("example '%s' failed: %w", example.Name, err)
+	}
+	return nil
+}
+
+// the flags that fill the given placeholders, e.g. "'--sourceDir' and '--targetDir'"
+func placeholderFlags(placeholders []string) string {
+	flags := make([]string, 0, len(placeholders))
+	for _, placeholder := range placeholders {
+		flags = append(flags, "'--"+strings.Trim(placeholder, "{}")+"'")
+	}
+	return strings.Join(flags, " and ")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// the plan as '--planOutput' writes it. Nothing in it depends on when it was made, so plans from the same
+// source, target, and options are identical and diff cleanly.
+type planFile struct {
+	SourceDir   string        `json:"sourceDir"`
+	TargetDir   string        `json:"targetDir"`
+	ConfigHash  string        `json:"configHash"`
+	CleanTarget bool          `json:"cleanTarget"`
+	Files       int           `json:"files"`
+	Bytes       int64         `json:"bytes"`
+	Mappings    []planMapping `json:"mappings"`
+}
+
+type planMapping struct {
+	Source      string     `json:"source"`
+	Destination string     `json:"destination"`
+	Files       int        `json:"files"`
+	Bytes       int64      `json:"bytes"`
+	Copies      []planCopy `json:"copies"`
+}
+
+type planCopy struct {
+	// slash separated, relative to the mapping's source folder
+	Source string `json:"source"`
+	// relative to the mapping's destination folder; omitted when it's the same as Source
+	Destination string `json:"destination,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// writes every file each plan would copy, and where to, to config.PlanOutput as JSON
+func writePlan(config *cli_parsing.Config, plans []mappingPlan) error {
+	files, bytes := planTotals(plans)
+	plan := planFile{
+		SourceDir:   config.SourceDir,
+		TargetDir:   config.TargetDir,
+		ConfigHash:  config.Hash(),
+		CleanTarget: config.CleanTarget,
+		Files:       files,
+		Bytes:       bytes,
+		Mappings:    make([]planMapping, 0, len(plans)),
+	}
+	for i := range plans {
+		files, bytes := plans[i].totals()
+		mapping := planMapping{Source: plans[i].mapping.Source, Destination: plans[i].mapping.Destination, Files: files, Bytes: bytes, Copies: make([]planCopy, 0, files)}
+		err := plans[i].eachFile(func(f copy_funcs.ResolvedFile) error {
+			copied := planCopy{Source: filepath.ToSlash(f.RelPath), Size: f.Size}
+			if destRelPath := filepath.ToSlash(plans[i].opts.DestRelPath(f.RelPath)); destRelPath != copied.Source {
+				copied.Destination = destRelPath
+			}
+			mapping.Copies = append(mapping.Copies, copied)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("unable to scan %s: %w", plans[i].sourcePath, err)
+		}
+		sort.Slice(mapping.Copies, func(a, b int) bool { return mapping.Copies[a].Source < mapping.Copies[b].Source })
+		plan.Mappings = append(plan.Mappings, mapping)
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(config.PlanOutput, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write plan %s: %w", config.PlanOutput, err)
+	}
+	logging.Log(logging.Base, logging.IconComplete, "Wrote the plan for %d file(s) to %s", files, config.PlanOutput)
+	return nil
+}
+
+// returns the temporary files and folders runs leave on the target that are there now: those stale enough to be
+// from interrupted runs, and those recent enough that a run may still be using them
+func findArtifacts(config *cli_parsing.Config, plans []mappingPlan) ([]file_operations.Artifact, []file_operations.Artifact, error) {
+	destPaths := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		destPaths = append(destPaths, plan.destPath)
+	}
+	artifacts, err := file_operations.FindArtifacts(config.TargetDir, destPaths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	stale := make([]file_operations.Artifact, 0)
+	recent := make([]file_operations.Artifact, 0)
+	for _, artifact := range artifacts {
+		if artifact.Stale(now) {
+			stale = append(stale, artifact)
+		} else {
+			recent = append(recent, artifact)
+		}
+	}
+	return stale, recent, nil
+}
+
+func artifactsSize(artifacts []file_operations.Artifact) int64 {
+	var size int64
+	for _, artifact := range artifacts {
+		size += artifact.Size
+	}
+	return size
+}
+
+// removes the partial files, staging folders, and capacity test files interrupted runs left on the target, so
+// they don't slowly use up the card. Anything modified too recently to be sure no other run is using it is left.
+func removeStaleArtifacts(config *cli_parsing.Config, plans []mappingPlan) error {
+	stale, recent, err := findArtifacts(config, plans)
+	if err != nil {
+		return fmt.Errorf("unable to check for files left by interrupted runs: %w", err)
+	}
+	if len(recent) > 0 {
+		logging.LogWarning("%d file(s) or folder(s) on the target look like another run's, written in the last %d minutes; leaving them in case it's still running:", len(recent), int(file_operations.StaleAfter.Minutes()))
+		for _, artifact := range recent {
+			logging.Log(logging.Action, "", "• %s (%s)", artifact.Path, artifact.Kind)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	if config.DryRun {
+		logging.LogDryRun(logging.Base, "", "Would have removed %d file(s) or folder(s) left by interrupted runs, taking %s:", len(stale), logging.FormatBytes(uint64(artifactsSize(stale))))
+		for _, artifact := range stale {
+			logging.Log(logging.Action, "", "• %s (%s)", artifact.Path, artifact.Kind)
+		}
+		return nil
+	}
+
+	logging.Log(logging.Base, "", "Removing %d file(s) or folder(s) left by interrupted runs, taking %s...", len(stale), logging.FormatBytes(uint64(artifactsSize(stale))))
+	for _, artifact := range stale {
+		if err := file_operations.RemoveArtifact(artifact); err != nil {
+			return err
+		}
+		logging.Log(logging.Action, logging.IconClean, "Removed %s (%s)", artifact.Path, artifact.Kind)
+	}
+	return nil
+}
+
+// the Op of the record 'undo' appends to a journal once it's reversed the changes in it
+const undoneOp = "undone"
+
+// from here on, records every change to the target in '--journal', moving what's replaced or removed into this
+// run's batch in the trash so 'undo' can put it back
+func startJournal(config *cli_parsing.Config, startedAt time.Time) (*device_state.Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(config.Journal), 0755); err != nil {
+		return nil, fmt.Errorf("unable to create journal directory %s: %w", filepath.Dir(config.Journal), err)
+	}
+	journal, err := device_state.OpenJournal(fsys.OS, config.Journal)
+	if err != nil {
+		return nil, err
+	}
+	backupDir := filepath.Join(file_operations.TrashPath(config.TargetDir), file_operations.NewTrashBatch(startedAt), file_operations.JournalBackupName)
+	file_operations.SetFilesystem(fsys.Journaled(file_operations.Filesystem(), backupDir, func(change fsys.Change) error {
+		return journal.Append(change)
+	}))
+	logging.Log(logging.Base, "", "Recording every change to the target in %s", config.Journal)
+	return journal, nil
+}
+
+// reverses the changes recorded in '--journal' since it was last undone, newest first, carrying on past any that
+// can't be undone
+func runUndo(config *cli_parsing.Config) error {
+	records, torn, err := device_state.ReadJournal(fsys.OS, config.Journal)
+	if err != nil {
+		return err
+	}
+	if torn > 0 {
+		logging.LogWarning("Skipped %d record(s) in %s cut short by a crash; the changes they were recording may need undoing by hand", torn, config.Journal)
+	}
+	changes := make([]fsys.Change, 0, len(records))
+	for _, raw := range records {
+		var record fsys.Change
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return fmt.Errorf("failed to parse journal %s: %w", config.Journal, err)
+		}
+		if record.Op == undoneOp {
+			changes = changes[:0]
+			continue
+		}
+		changes = append(changes, record)
+	}
+	if len(changes) == 0 {
+		logging.Log(logging.Base, "", "Nothing to undo in %s", config.Journal)
+		return nil
+	}
+
+	if config.DryRun {
+		logging.LogDryRun(logging.Base, "", "Would have undone %d change(s) recorded in %s:", len(changes), config.Journal)
+		for i := len(changes) - 1; i >= 0; i-- {
+			logging.LogDryRun(logging.Detail, logging.IconUndo, "%s", describeUndo(changes[i]))
+		}
+		return nil
+	}
+
+	logging.Log(logging.Base, "", "Undoing %d change(s) recorded in %s...", len(changes), config.Journal)
+	failed := 0
+	for i := len(changes) - 1; i >= 0; i-- {
+		if err := fsys.Undo(file_operations.Filesystem(), changes[i]); err != nil {
+			logging.LogWarning("Couldn't undo the %s of %s: %v", changes[i].Op, changes[i].Path, err)
+			failed++
+			continue
+		}
+		logging.LogVerbose(logging.Detail, logging.IconUndo, "%s", describeUndo(changes[i]))
+	}
+
+	// so undoing again doesn't try the same changes over what's been put back
+	journal, err := device_state.OpenJournal(fsys.OS, config.Journal)
+	if err != nil {
+		return err
+	}
+	if err := journal.Append(fsys.Change{Op: undoneOp}); err != nil {
+		journal.Close()
+		return err
+	}
+	if err := journal.Close(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("undid %d change(s), but %d couldn't be undone", len(changes)-failed, failed)
+	}
+	logging.Log(logging.Base, logging.IconComplete, "Undid %d change(s)", len(changes))
+	return nil
+}
+
+// what undoing change does, for the log
+func describeUndo(change fsys.Change) string {
+	switch change.Op {
+	case fsys.ChangeCreate:
+		return "Remove " + change.Path
+	case fsys.ChangeOverwrite:
+		return "Put back the earlier " + change.Path
+	case fsys.ChangeRename:
+		return fmt.Sprintf("Move %s back to %s", change.Path, change.From)
+	case fsys.ChangeDelete:
+		return "Put back " + change.Path
+	case fsys.ChangeMkdir:
+		return "Remove folder " + change.Path
+	case fsys.ChangeRmdir:
+		return "Recreate folder " + change.Path
+	}
+	return fmt.Sprintf("Undo the %s of %s", change.Op, change.Path)
+}
+
+// records the destination folders '--cleanTarget' is about to empty, and copies their metadata files, to a folder
+// for this run in '--backupBeforeClean'
+func backupBeforeClean(config *cli_parsing.Config, plans []mappingPlan, startedAt time.Time) error {
+	relDirs := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		relDir, err := filepath.Rel(config.TargetDir, plan.destPath)
+		if err != nil {
+			return fmt.Errorf("unable to back up %s: %w", plan.destPath, err)
+		}
+		relDirs = append(relDirs, relDir)
+	}
+	backupDir := filepath.Join(config.BackupBeforeClean, file_operations.NewTrashBatch(startedAt))
+
+	if config.DryRun {
+		logging.LogDryRun(logging.Base, "", "Would have recorded the folders being cleaned, and backed up their metadata files, to %s", backupDir)
+		return nil
+	}
+
+	logging.Log(logging.Base, "", "Recording and hashing the folders being cleaned; this reads every file in them and may take a while...")
+	snapshot, copied, err := device_state.Backup(config.TargetDir, relDirs, backupDir)
+	if err != nil {
+		return fmt.Errorf("error backing up the folders being cleaned: %w", err)
+	}
+	logging.Log(logging.Base, logging.IconComplete, "Recorded %d file(s) and backed up %d metadata file(s) to %s", len(snapshot.Files), len(copied), backupDir)
+	for _, relPath := range copied {
+		logging.LogVerbose(logging.Detail, "", "%s", relPath)
+	}
+	return nil
+}
+
+// permanently deletes what's been in the target's trash for longer than '--retainTrash'
+func pruneTrash(config *cli_parsing.Config) error {
+	if config.RetainTrash == 0 && !config.PurgeTrash {
+		return nil
+	}
+	batches, err := file_operations.ListTrash(config.TargetDir)
+	if err != nil {
+		return fmt.Errorf("unable to check the trash: %w", err)
+	}
+	expired := batches
+	since := "that went in more than " + cli_parsing.DescribeRetention(config.RetainTrash) + " ago"
+	if config.PurgeTrash {
+		since = "however recently it went in ('--purgeTrash')"
+	} else {
+		expired = file_operations.ExpiredTrash(batches, config.RetainTrash, time.Now())
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	files, size := 0, int64(0)
+	for _, batch := range expired {
+		files += batch.Files
+		size += batch.Size
+	}
+	if config.DryRun {
+		logging.LogDryRun(logging.Base, "", "Would have emptied %d file(s) from the trash, taking %s, %s:", files, logging.FormatBytes(uint64(size)), since)
+		for _, batch := range expired {
+			logging.Log(logging.Action, "", "• %s (%d file(s), %s)", batch.Path, batch.Files, logging.FormatBytes(uint64(batch.Size)))
+		}
+		return nil
+	}
+
+	logging.Log(logging.Base, "", "Emptying %d file(s) from the trash, taking %s, %s...", files, logging.FormatBytes(uint64(size)), since)
+	for _, batch := range expired {
+		if err := file_operations.RemoveTrashBatch(batch); err != nil {
+			return err
+		}
+		logging.Log(logging.Action, logging.IconClean, "Removed %s (%d file(s), %s)", batch.Path, batch.Files, logging.FormatBytes(uint64(batch.Size)))
+	}
+	return nil
+}
+
+// fills and verifies the target's free space, failing if the card can't hold what it claims to
+func runCapacityTest(config *cli_parsing.Config) error {
+	if config.DryRun {
+		logging.LogDryRun(logging.Base, "", "Would have tested the capacity of the target's free space")
+		return nil
+	}
+
+	logging.Log(logging.Base, "", "Testing target capacity; this writes to all free space on the card and may take a while...")
+	lastPercent := -1
+	result, err := disk_info.TestCapacity(config.TargetDir, 0, func(stage string, done uint64, total uint64) {
+		percent := 0
+		if total > 0 {
+			percent = int(done * 100 / total)
+		}
+		if percent/10 != lastPercent/10 || done == total {
+			logging.Log(logging.Action, "", "%s: %s of %s (%d%%)", stage, logging.FormatBytes(done), logging.FormatBytes(total), percent)
+			lastPercent = percent
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("capacity test failed: %w", err)
+	}
+
+	if !result.Passed() {
+		return fmt.Errorf("capacity test failed: only %s of %s written read back correctly (first bad data at offset %d); this card is likely counterfeit or failing and should not be trusted",
+			logging.FormatBytes(result.BytesVerified), logging.FormatBytes(result.BytesWritten), result.FirstBadOffset)
+	}
+
+	logging.Log(logging.Action, logging.IconComplete, "All %s written to the target read back correctly", logging.FormatBytes(result.BytesWritten))
+	logging.LogComplete("Capacity test")
+	fmt.Println()
+	return nil
+}
+
+func runMappings(config *cli_parsing.Config, plans []mappingPlan) error {
+	if config.Transactional && !config.DryRun {
+		return processMappingsTransactionally(config, plans)
+	}
+
+	for i, plan := range plans {
+		if err := checkDeadline(plans[i:]); err != nil {
+			return err
+		}
+		if err := processMapping(config, plan); err != nil {
+			if errors.Is(err, copy_funcs.ErrDeadline) {
+				checkDeadline(plans[i+1:])
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// fails with copy_funcs.ErrDeadline, listing the mappings that won't be started, once '--timeout' has run out
+func checkDeadline(remaining []mappingPlan) error {
+	if len(remaining) == 0 || !remaining[0].opts.PastDeadline() {
+		return nil
+	}
+	logging.ClearMapping()
+	logging.LogWarning("Out of time; %d mapping(s) won't be started:", len(remaining))
+	for _, plan := range remaining {
+		logging.Log(logging.Action, "", "• %s -> %s", plan.mapping.Source, plan.mapping.Destination)
+	}
+	return copy_funcs.ErrDeadline
+}
+
+// number of files tolerated by '--maxErrors' that failed to copy, across every mapping
+func failedCopies(plans []mappingPlan) int {
+	failed := 0
+	for _, plan := range plans {
+		failed += len(plan.opts.Errors.Failures())
+	}
+	return failed
+}
+
+// surfaces any signs of failing or counterfeit destination media seen during the run
+func reportMediaHealth(monitor *file_operations.HealthMonitor) {
+	warnings := monitor.Warnings()
+	if len(warnings) == 0 {
+		return
+	}
+
+	fmt.Println()
+	logging.LogWarning("The target showed signs of failing or counterfeit media during this run:")
+	for _, warning := range warnings {
+		logging.Log(logging.Action, "", "• %s", warning)
+	}
+	logging.Log(logging.Action, "", "Consider verifying the card with a capacity tester before trusting it with your library.")
+	fmt.Println()
+}
+
+// lists any files that failed verify-after-write, failing the mapping if there were any
+func reportVerification(report *file_operations.VerifyReport) error {
+	failures := report.Failures()
+	if len(failures) == 0 {
+		logging.Log(logging.Action, logging.IconComplete, "All %d copied file(s) verified against the source (%s)", report.Verified(), report.Algorithm())
+		return nil
+	}
+
+	logging.LogWarning("%d of %d file(s) did not read back from the target identical to the source (%s):", len(failures), report.Verified()+len(failures), report.Algorithm())
+	for _, failure := range failures {
+		logging.Log(logging.Action, logging.IconError, "%s: %s", failure.Path, failure.Reason)
+	}
+	return fmt.Errorf("%d file(s) failed verification; the destination media may be failing", len(failures))
+}
+
+func cleanTargetDir(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	if config.DryRun {
+		return listCleanDeletions(config, destPath)
+	}
+
+	// a new (possibly nested) destination is created by the copy, so there's nothing to clean yet
+	if _, err := os.Stat(destPath); os.IsNotExist(err) {
+		logging.Log(logging.Action, logging.IconSkip, "Target directory %s doesn't exist yet; nothing to clean", destPath)
+		return nil
+	}
+
+	var kept []string
+	var err error
+	if config.UseTrash {
+		logging.Log(logging.Action, logging.IconClean, "Cleaning target directory into the trash...")
+		kept, err = file_operations.TrashDirectory(destPath, config.CleanKeeps(), plan.trash(config))
+	} else {
+		logging.Log(logging.Action, logging.IconClean, "Cleaning target directory...")
+		kept, err = file_operations.ClearDirectory(destPath, config.CleanKeeps())
+	}
+	if err != nil {
+		return fmt.Errorf("error cleaning target directory: %w", err)
+	}
+	logKeptProtected(kept, cleanKeptReason(config))
+	if config.UseTrash {
+		logging.Log(logging.Action, "", "What was cleaned is in %s until the trash is emptied", filepath.Join(file_operations.TrashPath(config.TargetDir), plan.trashBatch))
+	}
+	return nil
+}
+
+// notes the protected files cleaning kept, and why, and with '--verbose', lists them
+func logKeptProtected(kept []string, reason string) {
+	if len(kept) == 0 {
+		return
+	}
+	logging.Log(logging.Action, logging.IconSkip, "Kept %d protected file(s) (%s)", len(kept), reason)
+	for _, relPath := range kept {
+		logging.LogVerbose(logging.Detail, "", "%s", relPath)
+	}
+}
+
+// why cleaning kept what it did, for logKeptProtected
+func cleanKeptReason(config *cli_parsing.Config) string {
+	if len(config.CleanExclude) > 0 {
+		return "save data or matching '--protect' or '--cleanExclude'"
+	}
+	return "save data or matching '--protect'"
+}
+
+// lists what cleaning destPath would delete: each entry at its top, with the number and size of the files in each
+// folder, and with '--verbose', every file; protected files are left out
+func listCleanDeletions(config *cli_parsing.Config, destPath string) error {
+	cleared, kept, err := file_operations.PlanClear(destPath, config.CleanKeeps())
+	if err != nil {
+		return fmt.Errorf("error listing target directory contents: %w", err)
+	}
+	logKeptProtected(kept, cleanKeptReason(config))
+	if len(cleared) == 0 {
+		logging.LogDryRun(logging.Action, logging.IconClean, "Would have cleaned target directory %s, which is empty or doesn't exist yet", destPath)
+		return nil
+	}
+
+	var files int
+	var size int64
+	for _, entry := range cleared {
+		files += len(entry.Files)
+		size += entry.Size
+	}
+	verb := "deleted"
+	if config.UseTrash {
+		verb = "moved to the trash"
+	}
+	logging.LogDryRun(logging.Action, logging.IconClean, "Would have %s %d file(s) totalling %s from %s:", verb, files, logging.FormatBytes(uint64(size)), destPath)
+	for _, entry := range cleared {
+		if !entry.IsDir {
+			logging.Log(logging.Action, "", "• %s (%s)", entry.Name, logging.FormatBytes(uint64(entry.Size)))
+			continue
+		}
+		logging.Log(logging.Action, "", "• %s/ (%d file(s), %s)", entry.Name, len(entry.Files), logging.FormatBytes(uint64(entry.Size)))
+		for _, relPath := range entry.Files {
+			logging.LogVerbose(logging.Detail, "", "%s", relPath)
+		}
+	}
+	return nil
+}
+
+func runPostCopyOperations(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	// Explode directories if configured
+	if len(config.ExplodeDirs) > 0 {
+		if err := explodeDirs(config, destPath); err != nil {
+			return err
+		}
+	}
+
+	// Process renames if configured
+	if len(config.Renames) > 0 {
+		if err := processRenames(config, plan, destPath); err != nil {
+			return err
+		}
+	}
+
+	// Process rewrites if configured
+	if len(config.FileRewrites) > 0 {
+		if err := processRewrites(config, plan, destPath); err != nil {
+			return err
+		}
+	}
+
+	// Delete leftover junk if configured, once it's had every chance to be renamed out of the way
+	if len(config.DeleteAfter) > 0 {
+		if err := deleteAfter(config, plan, destPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writes a game list to destPath listing the games in it, if it doesn't already have one. Runs after the post-copy
+// operations so the games and box art are found where they've ended up.
+func generateGamelist(config *cli_parsing.Config, plan mappingPlan, destPath string, scraped map[string]gamelist.GameInfo) error {
+	mapping := plan.mapping
+	platform := platforms.ForMapping(mapping.Source, mapping.Destination)
+	if platform == nil {
+		logging.LogWarning("Can't tell which platform %s -> %s holds from its folder names, so which files are games; no game list will be generated for it", mapping.Source, mapping.Destination)
+		return nil
+	}
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have generated %s for the %s games, if there isn't one", gamelist.FileName, platform.Name)
+		return nil
+	}
+
+	describe := func(relPath string) (gamelist.GameInfo, bool) {
+		info, ok := scraped[relPath]
+		return info, ok
+	}
+	if plan.launchbox != nil {
+		if _, err := file_operations.Filesystem().Stat(filepath.Join(destPath, gamelist.FileName)); os.IsNotExist(err) {
+			if err := copyLaunchBoxArt(config, plan, destPath); err != nil {
+				return err
+			}
+		}
+		describe = func(relPath string) (gamelist.GameInfo, bool) {
+			game, ok := plan.launchboxGames[relPath]
+			if !ok {
+				info, ok := scraped[relPath]
+				return info, ok
+			}
+			info := gamelist.GameInfo{Name: game.Title, Desc: game.Notes, Developer: game.Developer, Publisher: game.Publisher, Genre: game.Genre}
+			if !game.Released.IsZero() {
+				info.ReleaseDate = game.Released.Format("20060102T150405")
+			}
+			return info, true
+		}
+	}
+
+	result, generated, err := gamelist.Generate(file_operations.Filesystem(), destPath, devicePaths(config, mapping), platform.IsGame, config.ArtworkDir, describe)
+	if err != nil {
+		return fmt.Errorf("error generating game list: %w", err)
+	}
+	if generated {
+		logging.Log(logging.Action, "", "Generated %s: %d game(s), %d with box art from %s", gamelist.FileName, result.Games, result.Images, config.ArtworkDir)
+	} else {
+		logging.LogVerbose(logging.Action, logging.IconSkip, "%s already has a %s; not generating one", mapping.Destination, gamelist.FileName)
+	}
+	return nil
+}
+
+// looks the games copied to destPath that its game list has no entry, name, or image for up on ScreenScraper by
+// their hashes, copying their box art into '--artworkDir' unless they already have art there. Returns what was
+// found, by slash separated path relative to destPath. A failed lookup stops scraping for the rest of the run, but
+// not the run itself.
+func scrapeGames(config *cli_parsing.Config, plan mappingPlan, destPath string) (map[string]gamelist.GameInfo, error) {
+	mapping := plan.mapping
+	if plan.scraper.Err() != nil {
+		logging.LogVerbose(logging.Action, logging.IconSkip, "Not looking %s games up on ScreenScraper, which stopped answering earlier in the run", mapping.Destination)
+		return nil, nil
+	}
+	platform := platforms.ForMapping(mapping.Source, mapping.Destination)
+	if platform == nil || platform.ScreenScraperID == 0 {
+		logging.LogWarning("Can't tell which ScreenScraper system %s -> %s holds from its folder names; its games won't be looked up", mapping.Source, mapping.Destination)
+		return nil, nil
+	}
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have looked up the %s games missing from %s on ScreenScraper", platform.Name, gamelist.FileName)
+		return nil, nil
+	}
+
+	fs := file_operations.Filesystem()
+	games, err := gamelist.Games(fs, destPath, platform.IsGame)
+	if err != nil {
+		return nil, err
+	}
+	list, err := gamelist.Load(fs, destPath)
+	if err != nil {
+		return nil, err
+	}
+	folder := devicePaths(config, mapping)
+	folder.Names = append([]string{filepath.Base(destPath)}, folder.Names...)
+	listed := make(map[string]gamelist.Game)
+	if list != nil {
+		for _, game := range list.Games {
+			if relPath, ok := folder.Read(game.Path); ok {
+				listed[relPath] = game
+			}
+		}
+	}
+
+	language := config.MetadataLang
+	if language == "" {
+		language = "en"
+	}
+	artDir := filepath.Join(destPath, config.ArtworkDir)
+	scraped := make(map[string]gamelist.GameInfo)
+	missing, images := 0, 0
+	for _, relPath := range games {
+		if game, ok := listed[relPath]; ok && strings.TrimSpace(game.Name) != "" && strings.TrimSpace(game.Image) != "" {
+			continue
+		}
+		missing++
+
+		file, err := fs.Open(filepath.Join(destPath, filepath.FromSlash(relPath)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", relPath, err)
+		}
+		rom, err := screenscraper.HashRom(file, platform.ScreenScraperID, path.Base(relPath))
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+		game, found, err := plan.scraper.Lookup(rom)
+		if err != nil {
+			logging.LogWarning("Stopped looking games up on ScreenScraper for the rest of the run: %v", err)
+			break
+		}
+		if !found {
+			logging.LogVerbose(logging.Detail, logging.IconSkip, "ScreenScraper doesn't know %s", relPath)
+			continue
+		}
+
+		regions := append(screenscraper.RegionCodes(romtags.Parse(path.Base(relPath)).Regions), screenscraper.RegionCodes(config.Regions)...)
+		info := gamelist.GameInfo{
+			Name:      game.Name(regions),
+			Desc:      game.Synopsis(language),
+			Developer: game.Developer.Text,
+			Publisher: game.Publisher.Text,
+			Genre:     game.Genre(language),
+		}
+		if released := game.Released(regions); !released.IsZero() {
+			info.ReleaseDate = released.Format("20060102T150405")
+		}
+
+		stem := strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+		for _, ext := range []string{".png", ".jpg", ".jpeg"} {
+			if _, err := fs.Stat(filepath.Join(artDir, stem+ext)); err == nil {
+				info.Image = path.Join(filepath.ToSlash(config.ArtworkDir), stem+ext)
+			}
+		}
+		if art, ok := game.BoxArt(regions); ok && info.Image == "" {
+			ext := "." + strings.ToLower(art.Format)
+			if ext != ".png" && ext != ".jpg" && ext != ".jpeg" {
+				ext = ".png"
+			}
+			data, err := plan.scraper.Download(art)
+			if err != nil {
+				logging.LogWarning("Stopped looking games up on ScreenScraper for the rest of the run: %v", err)
+				scraped[relPath] = info
+				break
+			}
+			if err := fs.MkdirAll(artDir, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", artDir, err)
+			}
+			if err := fsys.WriteFileAtomic(fs, filepath.Join(artDir, stem+ext), resizeArtwork(config, data)); err != nil {
+				return nil, fmt.Errorf("failed to write box art for %s: %w", relPath, err)
+			}
+			info.Image = path.Join(filepath.ToSlash(config.ArtworkDir), stem+ext)
+			images++
+		}
+		logging.LogVerbose(logging.Detail, logging.IconComplete, "Found %s on ScreenScraper as %s", relPath, info.Name)
+		scraped[relPath] = info
+	}
+
+	if missing > 0 {
+		logging.Log(logging.Action, "", "Found %d of %d game(s) missing from %s on ScreenScraper, and copied box art for %d into %s", len(scraped), missing, gamelist.FileName, images, config.ArtworkDir)
+	}
+	return scraped, nil
+}
+
+// adds the details scraped or fetched for the games in destPath to its game list, where the list is missing them.
+// Runs after generating, which leaves a list it just wrote with nothing to add.
+func fillGamelist(config *cli_parsing.Config, plan mappingPlan, destPath string, details map[string]gamelist.GameInfo) error {
+	result, found, err := gamelist.FillList(file_operations.Filesystem(), destPath, devicePaths(config, plan.mapping), details)
+	if err != nil {
+		return fmt.Errorf("error adding details to the game list: %w", err)
+	}
+	if found && (result.Added > 0 || result.Filled > 0) {
+		logging.Log(logging.Action, "", "Added %d game(s) to %s, and filled in the details of %d", result.Added, gamelist.FileName, result.Filled)
+	}
+	return nil
+}
+
+// fetches box art from libretro-thumbnails into '--artworkDir' for the games copied to destPath that have none,
+// there or where their game list entry points. Returns the art of each game its entry doesn't point at, fetched or
+// already in '--artworkDir', slash separated, by the slash separated path of its game, both relative to destPath. A
+// failed fetch stops fetching for the rest of the run, but not the run itself.
+func fetchThumbnails(config *cli_parsing.Config, plan mappingPlan, destPath string) (map[string]string, error) {
+	mapping := plan.mapping
+	if plan.thumbnails.Err() != nil {
+		logging.LogVerbose(logging.Action, logging.IconSkip, "Not fetching box art for %s, since libretro-thumbnails stopped answering earlier in the run", mapping.Destination)
+		return nil, nil
+	}
+	platform := platforms.ForMapping(mapping.Source, mapping.Destination)
+	if platform == nil || platform.LibretroName == "" {
+		logging.LogWarning("Can't tell which libretro-thumbnails system %s -> %s holds from its folder names; no box art will be fetched for it", mapping.Source, mapping.Destination)
+		return nil, nil
+	}
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have fetched box art from libretro-thumbnails for the %s games without any", platform.Name)
+		return nil, nil
+	}
+
+	fs := file_operations.Filesystem()
+	games, err := gamelist.Games(fs, destPath, platform.IsGame)
+	if err != nil {
+		return nil, err
+	}
+	list, err := gamelist.Load(fs, destPath)
+	if err != nil {
+		return nil, err
+	}
+	folder := devicePaths(config, mapping)
+	folder.Names = append([]string{filepath.Base(destPath)}, folder.Names...)
+	// games whose game list entries point at art that's there
+	illustrated := make(map[string]bool)
+	if list != nil {
+		for _, game := range list.Games {
+			relPath, ok := folder.Read(game.Path)
+			imagePath, hasImage := folder.Read(game.Image)
+			if !ok || !hasImage {
+				continue
+			}
+			if _, err := fs.Stat(filepath.Join(destPath, filepath.FromSlash(imagePath))); err == nil {
+				illustrated[relPath] = true
+			}
+		}
+	}
+
+	artDir := filepath.Join(destPath, config.ArtworkDir)
+	art := make(map[string]string)
+	missing, fetched := 0, 0
+	for _, relPath := range games {
+		if illustrated[relPath] {
+			continue
+		}
+		stem := strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+		for _, ext := range []string{".png", ".jpg", ".jpeg"} {
+			if _, err := fs.Stat(filepath.Join(artDir, stem+ext)); err == nil {
+				art[relPath] = path.Join(filepath.ToSlash(config.ArtworkDir), stem+ext)
+				break
+			}
+		}
+		if _, ok := art[relPath]; ok {
+			continue
+		}
+		missing++
+
+		data, found, err := plan.thumbnails.BoxArt(platform.LibretroName, stem)
+		if err != nil {
+			logging.LogWarning("Stopped fetching box art from libretro-thumbnails for the rest of the run: %v", err)
+			break
+		}
+		if !found {
+			logging.LogVerbose(logging.Detail, logging.IconSkip, "libretro-thumbnails has no box art for %s", relPath)
+			continue
+		}
+		if err := fs.MkdirAll(artDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", artDir, err)
+		}
+		if err := fsys.WriteFileAtomic(fs, filepath.Join(artDir, stem+".png"), resizeArtwork(config, data)); err != nil {
+			return nil, fmt.Errorf("failed to write box art for %s: %w", relPath, err)
+		}
+		logging.LogVerbose(logging.Detail, logging.IconCopy, "Fetched box art for %s", relPath)
+		art[relPath] = path.Join(filepath.ToSlash(config.ArtworkDir), stem+".png")
+		fetched++
+	}
+
+	if missing > 0 {
+		logging.Log(logging.Action, "", "Fetched box art from libretro-thumbnails for %d of %d game(s) without any into %s", fetched, missing, config.ArtworkDir)
+	}
+	return art, nil
+}
+
+// copies the LaunchBox box art of each game copied to destPath into '--artworkDir', named like the game so the
+// generated game list finds it, unless the game already has art there
+func copyLaunchBoxArt(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	relPaths := make([]string, 0, len(plan.launchboxGames))
+	for relPath := range plan.launchboxGames {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+
+	fs := file_operations.Filesystem()
+	copied := 0
+	for _, relPath := range relPaths {
+		if _, err := fs.Stat(filepath.Join(destPath, filepath.FromSlash(relPath))); err != nil {
+			continue
+		}
+		stem := strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+		artDir := filepath.Join(destPath, config.ArtworkDir)
+		hasArt := false
+		for _, ext := range []string{".png", ".jpg", ".jpeg"} {
+			if _, err := fs.Stat(filepath.Join(artDir, stem+ext)); err == nil {
+				hasArt = true
+			}
+		}
+		if hasArt {
+			continue
+		}
+
+		artPath, err := plan.launchbox.BoxArt(plan.launchboxGames[relPath])
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(artPath))
+		if artPath == "" || (ext != ".png" && ext != ".jpg" && ext != ".jpeg") {
+			continue
+		}
+		data, err := os.ReadFile(artPath)
+		if err != nil {
+			return fmt.Errorf("failed to read LaunchBox box art %s: %w", artPath, err)
+		}
+		if err := fs.MkdirAll(artDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", artDir, err)
+		}
+		if err := fsys.WriteFileAtomic(fs, filepath.Join(artDir, stem+ext), resizeArtwork(config, data)); err != nil {
+			return fmt.Errorf("failed to copy LaunchBox box art for %s: %w", relPath, err)
+		}
+		logging.LogVerbose(logging.Detail, logging.IconCopy, "Copied LaunchBox box art for %s", relPath)
+		copied++
+	}
+	if copied > 0 {
+		logging.Log(logging.Action, "", "Copied box art for %d game(s) from LaunchBox into %s", copied, config.ArtworkDir)
+	}
+	return nil
+}
+
+// points the game lists copied to destPath at where '--artworkSource' moved their art, and drops their references
+// to the media left out. Runs after the post-copy operations so lists renamed on the way over are found.
+func remapGamelistMedia(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have pointed the game lists at the '%s' media in %s", config.ArtworkSource, config.ArtworkDir)
+		return nil
+	}
+
+	remapped, err := gamelist.RemapMediaLists(file_operations.Filesystem(), destPath, devicePaths(config, plan.mapping), plan.mediaMoved, plan.mediaLeftOut)
+	if err != nil {
+		return fmt.Errorf("error remapping game list media: %w", err)
+	}
+	names := make([]string, 0, len(remapped))
+	for name := range remapped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result := remapped[name]
+		logging.Log(logging.Action, "", "Pointed %s at the art in %s: %d game(s) remapped, %d reference(s) to media left out removed", name, config.ArtworkDir, result.Remapped, result.Dropped)
+	}
+	return nil
+}
+
+// points the game lists in destPath at the names the files and folders they name were given to suit the target
+// ('--maxPathLength', '--renameReserved', '--sanitizeNames', and '--normalizeNames')
+func renameGamelistPaths(config *cli_parsing.Config, plan mappingPlan, destPath string) error {
+	rename := func(relPath string) string {
+		if moved, ok := plan.gameFolders[relPath]; ok {
+			relPath = moved
+		}
+		if top, _, _ := strings.Cut(relPath, "/"); plan.alphaSplit[top] != "" {
+			relPath = path.Join(plan.alphaSplit[top], relPath)
+		}
+		if shortened, ok := plan.shortened[relPath]; ok {
+			relPath = shortened
+		}
+		return plan.opts.SafePath(relPath)
+	}
+	changed, err := gamelist.RenamePathsLists(file_operations.Filesystem(), destPath, devicePaths(config, plan.mapping), rename)
+	if err != nil {
+		return fmt.Errorf("error updating game lists: %w", err)
+	}
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logging.Log(logging.Action, "", "Pointed %d game(s) in %s at their names on the target", changed[name], name)
+	}
+	return nil
+}
+
+// trims the game lists in destPath to the details in config.MetadataLang and the elements in config.GamelistTags.
+// Runs before converting and merging, so only the source's entries are trimmed.
+func filterGamelists(config *cli_parsing.Config, destPath string) error {
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have trimmed the game lists to the chosen language and elements")
+		return nil
+	}
+
+	filtered, err := gamelist.FilterLists(file_operations.Filesystem(), destPath, config.MetadataLang, config.GamelistTags)
+	if err != nil {
+		return fmt.Errorf("error filtering game lists: %w", err)
+	}
+	names := make([]string, 0, len(filtered))
+	for name := range filtered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result := filtered[name]
+		logging.Log(logging.Action, "", "Trimmed %s: %d element(s) in other languages and %d other element(s) removed", name, result.OtherLanguages, result.Stripped)
+	}
+	return nil
+}
+
+// how the files written into a mapping's destination refer to the files beside them on the device: by the rules of
+// the firmware detected on the target, and, reading paths written elsewhere, by the platform folder's name on
+// either side of the mapping
+func devicePaths(config *cli_parsing.Config, mapping cli_parsing.DirMapping) device_paths.Folder {
+	folder := device_paths.Folder{
+		Path:  path.Clean(filepath.ToSlash(mapping.Destination)),
+		Names: []string{path.Base(filepath.ToSlash(mapping.Source)), path.Base(filepath.ToSlash(mapping.Destination))},
+	}
+	if targetOS := disk_info.DetectTargetOS(config.TargetDir); targetOS != nil {
+		folder.Rules.MountPath = targetOS.MountPath
+	}
+	return folder
+}
+
+// converts the game list copied to destPath to the format the target's frontend reads. Runs after the post-copy
+// operations, which may already have moved the images it points at, and before merging, so the converted list is
+// merged into the device's list of the same name.
+func convertGamelist(config *cli_parsing.Config, mapping cli_parsing.DirMapping, destPath string) error {
+	if config.ConvertGamelist == gamelist.FormatES {
+		if config.DryRun {
+			logging.LogDryRun(logging.Action, "", "Would have rewritten the paths in %s relative to the platform folder", gamelist.FileName)
+			return nil
+		}
+		result, found, err := gamelist.ConvertToES(file_operations.Filesystem(), destPath, devicePaths(config, mapping))
+		if err != nil {
+			return fmt.Errorf("error converting game list: %w", err)
+		}
+		if found {
+			logging.Log(logging.Action, "", "Converted %s for EmulationStation: %d game(s), %d entr(ies) dropped, %d path(s) rewritten",
+				gamelist.FileName, result.Games, result.Dropped, result.Rewritten)
+		}
+		return nil
+	}
+
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, "", "Would have converted %s to %s", gamelist.FileName, gamelist.MiyooFileName)
+		return nil
+	}
+
+	result, found, err := gamelist.ConvertToMiyoo(file_operations.Filesystem(), destPath, devicePaths(config, mapping))
+	if err != nil {
+		return fmt.Errorf("error converting game list: %w", err)
+	}
+	if found {
+		logging.Log(logging.Action, "", "Converted %s to %s: %d game(s), %d entr(ies) dropped, %d image(s) moved to %s",
+			gamelist.FileName, gamelist.MiyooFileName, result.Games, result.Dropped, result.MovedImages, gamelist.MiyooImageDir)
+	}
+	return nil
+}
+
+// merges the game lists now in destPath into what they held before the copy, deviceLists. Runs after the post-copy
+// operations so a list renamed or rewritten on the way over is merged under the name and paths the device uses.
+// A list that can't be merged is left as the copy made it, with a warning.
+func mergeGamelists(config *cli_parsing.Config, mapping cli_parsing.DirMapping, destPath string, deviceLists map[string][]byte) {
+	names := make([]string, 0, len(deviceLists))
+	for name := range deviceLists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if config.DryRun {
+			logging.LogDryRun(logging.Action, "", "Would have merged any game list copied over %s into the device's", name)
+			continue
+		}
+
+		result, err := gamelist.MergeInto(file_operations.Filesystem(), destPath, name, deviceLists[name], config.MergeGamelists,
+			path.Base(filepath.ToSlash(mapping.Source)), path.Base(filepath.ToSlash(mapping.Destination)))
+		if err != nil {
+			logging.LogWarning("%v; the source's list has replaced the device's", err)
+			continue
+		}
+		if result.Added > 0 || result.Updated > 0 {
+			logging.Log(logging.Action, "", "Merged %s into the device's: %d game(s) added, %d updated", name, result.Added, result.Updated)
+		}
+	}
+}
+
+// removes the entries for games that aren't in destPath from its game lists. Lists written on the device name
+// games by absolute paths through the platform folder, which may be named like either side of the mapping.
+func pruneGamelists(mapping cli_parsing.DirMapping, destPath string) error {
+	pruned, err := gamelist.PruneMissing(file_operations.Filesystem(), destPath, path.Base(filepath.ToSlash(mapping.Source)), path.Base(filepath.ToSlash(mapping.Destination)))
+	if err != nil {
+		return fmt.Errorf("error pruning game lists: %w", err)
+	}
+
+	names := make([]string, 0, len(pruned))
+	for name := range pruned {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logging.Log(logging.Action, "", "Removed %d entr(ies) for games not on the target from %s", len(pruned[name]), name)
+		for _, game := range pruned[name] {
+			logging.LogVerbose(logging.Detail, logging.IconSkip, "%s", game.Path)
+		}
+	}
+	return nil
+}
+
+// warns about every cue sheet, GDI, or M3U playlist in destPath that refers to files that aren't there
+func checkDiscReferences(destPath string) error {
+	dangling, err := disc_refs.Check(file_operations.Filesystem(), destPath)
+	if err != nil {
+		return fmt.Errorf("error checking disc references: %w", err)
+	}
+
+	for _, d := range dangling {
+		logging.LogWarning("%s refers to file(s) that aren't on the target: %s", d.Sheet, strings.Join(d.Missing, ", "))
+	}
+	return nil
+}
+
+// records the target's full tree to a file so it can be diffed against later without the device attached
+func runSnapshot(config *cli_parsing.Config) error {
+	if config.SnapshotSkipHashes {
+		logging.Log(logging.Base, "", "Recording %s (paths and sizes only)...", config.TargetDir)
+	} else {
+		logging.Log(logging.Base, "", "Recording and hashing %s; this reads every file on the target and may take a while...", config.TargetDir)
+	}
+
+	snapshot, err := device_state.TakeSnapshot(config.TargetDir, !config.SnapshotSkipHashes)
+	if err != nil {
+		return fmt.Errorf("error taking snapshot: %w", err)
+	}
+
+	if err := snapshot.Save(config.SnapshotOutput); err != nil {
+		return err
+	}
+
+	logging.Log(logging.Action, logging.IconComplete, "Wrote %d file(s) to %s", len(snapshot.Files), config.SnapshotOutput)
+	return nil
+}
+
+// syncs the save files on the target with '--backupDir' both ways, newest copy winning. What's replaced is moved
+// to the trash of its side rather than lost, in case the newest copy isn't the one that was wanted.
+func runSaves(config *cli_parsing.Config) error {
+	fs := file_operations.Filesystem()
+	if info, err := fs.Stat(config.TargetDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("target directory does not exist: %s", config.TargetDir)
+	}
+
+	logging.Log(logging.Base, "", "Syncing saves between %s and %s...", config.TargetDir, config.SavesBackupDir)
+	changes, err := saves.Plan(fs, config.TargetDir, config.SavesBackupDir)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		logging.Log(logging.Base, logging.IconComplete, "Saves are already in sync")
+		return nil
+	}
+
+	batch := file_operations.NewTrashBatch(time.Now())
+	backedUp, restored := 0, 0
+	for _, change := range changes {
+		fromDir, toDir, verb := config.TargetDir, config.SavesBackupDir, "Backed up"
+		if change.Direction == saves.ToDevice {
+			fromDir, toDir, verb = config.SavesBackupDir, config.TargetDir, "Restored"
+		}
+		replacing := ""
+		if change.Replaces {
+			replacing = ", replacing an older copy"
+		}
+		if config.DryRun {
+			logging.LogDryRun(logging.Action, logging.IconCopy, "Would have %s %s%s", strings.ToLower(verb), change.RelPath, replacing)
+		} else {
+			if change.Replaces {
+				if err := file_operations.MoveToTrash(toDir, batch, change.RelPath); err != nil {
+					return err
+				}
+			}
+			destPath := filepath.Join(toDir, change.RelPath)
+			if err := fs.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(destPath), err)
+			}
+			if err := file_operations.CopyFile(filepath.Join(fromDir, change.RelPath), destPath); err != nil {
+				return err
+			}
+			// the copy was saved when the original was, so the next sync sees both sides match
+			if err := fs.Chtimes(destPath, change.ModTime, change.ModTime); err != nil {
+				return fmt.Errorf("failed to set modification time of %s: %w", destPath, err)
+			}
+			logging.Log(logging.Action, logging.IconCopy, "%s %s%s", verb, change.RelPath, replacing)
+		}
+		if change.Direction == saves.ToDevice {
+			restored++
+		} else {
+			backedUp++
+		}
+	}
+
+	if config.DryRun {
+		logging.LogDryRun(logging.Base, "", "Would have backed up %d save(s) and restored %d", backedUp, restored)
+		return nil
+	}
+	logging.Log(logging.Base, logging.IconComplete, "Backed up %d save(s) to %s and restored %d to %s", backedUp, config.SavesBackupDir, restored, config.TargetDir)
+	for _, dir := range []string{config.TargetDir, config.SavesBackupDir} {
+		trashed := filepath.Join(file_operations.TrashPath(dir), batch)
+		if _, err := fs.Stat(trashed); err == nil {
+			logging.Log(logging.Action, "", "The older copies replaced are in %s", trashed)
+		}
+	}
+	return nil
+}
+
+// where the run history is kept: in '--stateDir' if given, otherwise the user config directory
+func historyPath(config *cli_parsing.Config) (string, error) {
+	if config.StateDir != "" {
+		return device_state.HistoryPath(config.StateDir), nil
+	}
+	return device_state.DefaultHistoryPath()
+}
+
+// adds a finished copy to the run history. Failing to is only worth a warning; the copy itself is what matters.
+func recordRun(config *cli_parsing.Config, identity *device_state.Identity, plans []mappingPlan, startedAt time.Time, runErr error) {
+	historyFile, err := historyPath(config)
+	if err != nil {
+		logging.LogWarning("Couldn't record this run in the history: %v", err)
+		return
+	}
+
+	targetDir, err := filepath.Abs(config.TargetDir)
+	if err != nil {
+		targetDir = config.TargetDir
+	}
+	mappings := make([]string, 0, len(config.Mappings))
+	for _, mapping := range config.Mappings {
+		mappings = append(mappings, mapping.Source+":"+mapping.Destination)
+	}
+	files, bytes := planTotals(plans)
+
+	run := device_state.Run{
+		StartedAt:   startedAt.UTC(),
+		FinishedAt:  time.Now().UTC(),
+		TargetDir:   targetDir,
+		SourceDir:   config.SourceDir,
+		Mappings:    mappings,
+		ConfigHash:  config.Hash(),
+		Succeeded:   runErr == nil,
+		Files:       files,
+		Bytes:       bytes,
+		FailedFiles: failedCopies(plans),
+	}
+	if identity != nil {
+		run.DeviceID, run.DeviceName = identity.ID, identity.Name
+	}
+	if runErr != nil {
+		run.Error = runErr.Error()
+	}
+
+	if err := device_state.RecordRun(fsys.OS, historyFile, run); err != nil {
+		logging.LogWarning("Couldn't record this run in the history: %v", err)
+	}
+}
+
+// the device runs are filtered to, as returned by device_state.Run.Device: the ID of the device at '--targetDir',
+// or if it has none, the path. Empty for every device.
+func historyTarget(config *cli_parsing.Config) (string, error) {
+	if config.TargetDir == "" {
+		return "", nil
+	}
+	identity, err := device_state.LoadIdentity(config.TargetDir)
+	if err != nil {
+		return "", err
+	}
+	if identity != nil {
+		return identity.ID, nil
+	}
+	if targetDir, err := filepath.Abs(config.TargetDir); err == nil {
+		return targetDir, nil
+	}
+	return config.TargetDir, nil
+}
+
+// reads the identity of the target, announcing which device is being synced to. Returns nil if it has none.
+func targetIdentity(config *cli_parsing.Config) (*device_state.Identity, error) {
+	identity, err := device_state.LoadIdentity(config.TargetDir)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case identity != nil && identity.Name != "" && config.DeviceName != "" && identity.Name != config.DeviceName:
+		logging.Log(logging.Base, "", "Syncing to '%s' (%s), which will be renamed '%s'", identity.Name, config.TargetDir, config.DeviceName)
+	case config.DeviceName != "":
+		logging.Log(logging.Base, "", "Syncing to '%s' (%s)", config.DeviceName, config.TargetDir)
+	case identity != nil && identity.Name != "":
+		logging.Log(logging.Base, "", "Syncing to '%s' (%s)", identity.Name, config.TargetDir)
+	}
+	return identity, nil
+}
+
+// refuses a target that isn't the device '--expectDevice' names, before anything on it can be touched
+func checkExpectedDevice(config *cli_parsing.Config, identity *device_state.Identity) error {
+	if config.ExpectDevice == "" {
+		return nil
+	}
+	if identity == nil {
+		return fmt.Errorf("expected the target to be '%s', but %s has no %s; refusing to copy to what may be the wrong device (name it with '--deviceName' first if it's the right one)", config.ExpectDevice, config.TargetDir, device_state.IdentityFileName)
+	}
+	if !strings.EqualFold(identity.Name, config.ExpectDevice) && !strings.EqualFold(identity.ID, config.ExpectDevice) {
+		name := identity.Name
+		if name == "" {
+			name = identity.ID
+		}
+		return fmt.Errorf("expected the target to be '%s', but %s is '%s'; refusing to copy to the wrong device", config.ExpectDevice, config.TargetDir, name)
+	}
+	return nil
+}
+
+// names the target in its identity file as '--deviceName' asks, creating the file if it doesn't have one yet,
+// and returns the identity the run is recorded under. Unnamed targets are left without one, so nothing is
+// written outside the platform folders unless asked for.
+func labelTarget(config *cli_parsing.Config, identity *device_state.Identity) (*device_state.Identity, error) {
+	if config.DeviceName == "" || (identity != nil && identity.Name == config.DeviceName) {
+		return identity, nil
+	}
+
+	if config.DryRun {
+		logging.LogDryRun(logging.Action, logging.IconRename, "Would have named the target '%s' in %s", config.DeviceName, device_state.IdentityFileName)
+		return identity, nil
+	}
+
+	if identity == nil {
+		created, err := device_state.NewIdentity(config.DeviceName)
+		if err != nil {
+			return nil, err
+		}
+		identity = created
+	}
+	identity.Name = config.DeviceName
+	if err := identity.Save(config.TargetDir); err != nil {
+		return nil, err
+	}
+
+	logging.Log(logging.Action, logging.IconRename, "Named the target '%s' in %s", identity.Name, device_state.IdentityFileName)
+	return identity, nil
+}
+
+// how a run ended, for the history and status tables
+func runResult(run device_state.Run) string {
+	if !run.Succeeded {
+		return "failed"
+	}
+	if run.FailedFiles > 0 {
+		return fmt.Sprintf("%d file(s) failed", run.FailedFiles)
+	}
+	return "ok"
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// logs runs as a table, with a column of when each target last succeeded if withLastSuccess is set, followed by
+// why any of them failed
+func logRuns(runs []device_state.Run, history []device_state.Run, withLastSuccess bool) {
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 0, 0, 3, ' ', 0)
+	header := "Device\tTarget\tStarted\tResult\tFiles\tSize\tTook\tConfig\tMappings"
+	if withLastSuccess {
+		header += "\tLast succeeded"
+	}
+	fmt.Fprintln(writer, header)
+
+	for _, run := range runs {
+		device := run.DeviceName
+		if device == "" {
+			device = "-"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s", device, run.TargetDir, run.StartedAt.Local().Format("2006-01-02 15:04"),
+			runResult(run), run.Files, logging.FormatBytes(uint64(run.Bytes)), run.Duration().Round(time.Second),
+			shortHash(run.ConfigHash), strings.Join(run.Mappings, ", "))
+		if withLastSuccess {
+			lastSuccess := "never"
+			if success, ok := device_state.LastSuccess(history, run.Device()); ok {
+				lastSuccess = fmt.Sprintf("%s (config %s)", success.StartedAt.Local().Format("2006-01-02 15:04"), shortHash(success.ConfigHash))
+			}
+			fmt.Fprintf(writer, "\t%s", lastSuccess)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	writer.Flush()
+	for _, line := range strings.Split(strings.TrimRight(table.String(), "\n"), "\n") {
+		logging.Log(logging.Action, "", "%s", line)
+	}
+
+	for _, run := range runs {
+		if !run.Succeeded {
+			logging.Log(logging.Action, "", "• %s at %s failed: %s", run.TargetDir, run.StartedAt.Local().Format("2006-01-02 15:04"), run.Error)
+		}
+	}
+}
+
+// lists the most recent copies, newest first, optionally only those to '--targetDir'
+func runHistory(config *cli_parsing.Config) error {
+	historyFile, err := historyPath(config)
+	if err != nil {
+		return err
+	}
+	history, err := device_state.ReadHistory(fsys.OS, historyFile)
+	if err != nil {
+		return err
+	}
+
+	target, err := historyTarget(config)
+	if err != nil {
+		return err
+	}
+	runs := make([]device_state.Run, 0)
+	for i := len(history) - 1; i >= 0 && (config.HistoryLimit == 0 || len(runs) < config.HistoryLimit); i-- {
+		if target == "" || history[i].Device() == target {
+			runs = append(runs, history[i])
+		}
+	}
+
+	if len(runs) == 0 {
+		logging.Log(logging.Base, "", "No copies recorded in %s yet", historyFile)
+		return nil
+	}
+	logging.Log(logging.Base, "", "Recent copies, newest first:")
+	logRuns(runs, history, false)
+	return nil
+}
+
+// downloads a profile from the profile repository into the user's profile directory
+func runProfileFetch(config *cli_parsing.Config) error {
+	dir, err := profiles.DefaultUserDir()
+	if err != nil {
+		return err
+	}
+
+	logging.Log(logging.Base, "", "Fetching profile '%s' from %s...", config.FetchProfile, config.FetchRepo)
+	data, err := profiles.Fetch(config.FetchRepo, config.FetchProfile, config.FetchSHA256)
+	if err != nil {
+		return err
+	}
+	filePath, err := profiles.Save(dir, config.FetchProfile, data)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	logging.Log(logging.Base, logging.IconComplete, "Saved profile '%s' to %s; use it with '--profile %s'", config.FetchProfile, filePath, strings.ToLower(config.FetchProfile))
+	logging.Log(logging.Action, "", "Its SHA-256 is %x; give it as '--sha256' to fetch this version again and no other", sum)
+	return nil
+}
+
+// shows the last copy to each target, optionally only '--targetDir', and when each last succeeded
+func runStatus(config *cli_parsing.Config) error {
+	historyFile, err := historyPath(config)
+	if err != nil {
+		return err
+	}
+	history, err := device_state.ReadHistory(fsys.OS, historyFile)
+	if err != nil {
+		return err
+	}
+
+	target, err := historyTarget(config)
+	if err != nil {
+		return err
+	}
+	runs := make([]device_state.Run, 0)
+	for _, last := range device_state.LastRuns(history) {
+		if target == "" || last.Device() == target {
+			runs = append(runs, last)
+		}
+	}
+
+	if len(runs) == 0 {
+		logging.Log(logging.Base, "", "No copies recorded in %s yet", historyFile)
+		return nil
+	}
+	logging.Log(logging.Base, "", "Last copy to each target:")
+	logRuns(runs, history, true)
+	return nil
+}
+
+// for each mapping, lists files that a copy would add or overwrite on the target, and target files that
+// aren't in the source. Compares against a snapshot when '--against' is given, otherwise the live target.
+func runDiff(config *cli_parsing.Config, plans []mappingPlan, cache *file_operations.ChecksumCache) error {
+	var snapshot *device_state.Snapshot
+	if config.DiffAgainst != "" {
+		var err error
+		if snapshot, err = device_state.LoadSnapshot(config.DiffAgainst); err != nil {
+			return err
+		}
+		logging.Log(logging.Base, "", "Comparing against snapshot of %s taken %s", snapshot.TargetDir, snapshot.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		if config.DiffHashes && snapshot.HashAlgorithm == "" {
+			logging.LogWarning("Snapshot %s was taken without hashes; comparing sizes only", config.DiffAgainst)
+		}
+	}
+
+	rows := make([]diffRow, 0, len(plans)+1)
+	total := diffRow{mapping: "Total"}
+	for _, plan := range plans {
+		logging.Log(logging.Base, "", "\033[1;34m%s -> %s\033[0m", plan.mapping.Source, plan.mapping.Destination)
+
+		var target map[string]device_state.FileState
+		if snapshot != nil {
+			target = snapshot.Subtree(plan.mapping.Destination)
+		} else {
+			var err error
+			if target, err = device_state.ScanTree(plan.destPath, config.DiffHashes); err != nil {
+				return fmt.Errorf("unable to scan %s: %w", plan.destPath, err)
+			}
+		}
+
+		// written by '--manifest' and '--signKey', not copied from the source
+		delete(target, device_state.ManifestFileName)
+		delete(target, device_state.SignatureFileName)
+
+		source, err := copiedStates(config, plan, target, config.DiffHashes, cache)
+		if err != nil {
+			return err
+		}
+
+		comparison := device_state.Compare(source, target)
+		row := diffRow{mapping: fmt.Sprintf("%s -> %s", plan.mapping.Source, plan.mapping.Destination)}
+		for _, f := range comparison.New {
+			logging.Log(logging.Action, "", "copy   %s (%s)", f.Path, logging.FormatBytes(uint64(f.Size)))
+			row.copy.add(f.Size)
+		}
+		for _, f := range comparison.Changed {
+			logging.Log(logging.Action, "", "update %s (%s)", f.Path, logging.FormatBytes(uint64(f.Size)))
+			row.update.add(f.Size)
+		}
+		for _, f := range comparison.Orphaned {
+			logging.Log(logging.Action, "", "delete %s (%s, not in source)", f.Path, logging.FormatBytes(uint64(f.Size)))
+			row.delete.add(f.Size)
+		}
+		row.unchanged = len(comparison.Unchanged)
+		if len(comparison.New)+len(comparison.Changed)+len(comparison.Orphaned) == 0 {
+			logging.Log(logging.Action, "", "up to date")
+		}
+
+		rows = append(rows, row)
+		total.copy.merge(row.copy)
+		total.update.merge(row.update)
+		total.delete.merge(row.delete)
+		total.unchanged += row.unchanged
+	}
+	if len(plans) > 1 {
+		rows = append(rows, total)
+	}
+
+	fmt.Println()
+	logging.Log(logging.Base, "", "Diff complete; a sync would:")
+	var table strings.Builder
+	writer := tabwriter.NewWriter(&table, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(writer, "Mapping\tCopy new\tUpdate changed\tDelete orphaned\tUnchanged")
+	for _, row := range rows {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%d\n", row.mapping, row.copy, row.update, row.delete, row.unchanged)
+	}
+	writer.Flush()
+	for _, line := range strings.Split(strings.TrimRight(table.String(), "\n"), "\n") {
+		logging.Log(logging.Action, "", "%s", line)
+	}
+	return nil
+}
+
+// one mapping's line in the diff summary table
+type diffRow struct {
+	mapping              string
+	copy, update, delete diffTally
+	unchanged            int
+}
+
+type diffTally struct {
+	files int
+	bytes int64
+}
+
+func (t *diffTally) add(size int64) {
+	t.files++
+	t.bytes += size
+}
+
+func (t *diffTally) merge(other diffTally) {
+	t.files += other.files
+	t.bytes += other.bytes
+}
+
+func (t diffTally) String() string {
+	if t.files == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d (%s)", t.files, logging.FormatBytes(uint64(t.bytes)))
+}
+
+// the states of a plan's source files keyed by where the copy puts them on the target, hashed if withHashes.
+// Files a '--rewrite' edits are expected to differ from the source, so they take the target's state if it has
+// them and are only checked for presence, and files '--deleteAfter' deletes aren't expected at all.
+func copiedStates(config *cli_parsing.Config, plan mappingPlan, target map[string]device_state.FileState, withHashes bool, cache *file_operations.ChecksumCache) (map[string]device_state.FileState, error) {
+	source := make(map[string]device_state.FileState, len(plan.files))
+	err := plan.eachFile(func(f copy_funcs.ResolvedFile) error {
+		state := device_state.FileState{Path: copiedPath(config, plan.opts, f.RelPath), Size: f.Size}
+		if deletedAfter(config, state.Path) {
+			return nil
+		}
+
+		if editedAfterCopy(config, plan, state.Path) {
+			copied, ok := target[state.Path]
+			// a converted game list can be replaced by the frontend's own
+			if !ok && editedGamelist(config, plan, state.Path) && config.ConvertGamelist != "" {
+				return nil
+			}
+			if ok {
+				state = copied
+			}
+		} else if withHashes {
+			sum, err := cache.HashFile(filepath.Join(plan.sourcePath, f.RelPath), device_state.HashAlgorithm)
+			if err != nil {
+				return err
+			}
+			state.Hash = hex.EncodeToString(sum)
+		}
+		source[state.Path] = state
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// what verifying the target found, across every mapping
+type verifyTotals struct {
+	missing, differing, extra, dangling int
+}
+
+// scans destPath for verifying against what should be there, hashing each file if withHashes is set
+func scanVerified(destPath string, withHashes bool) (map[string]device_state.FileState, error) {
+	target, err := device_state.ScanTree(destPath, withHashes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scan %s: %w", destPath, err)
+	}
+	// written by '--manifest' and '--signKey', not copied from the source
+	delete(target, device_state.ManifestFileName)
+	delete(target, device_state.SignatureFileName)
+	return target, nil
+}
+
+// compares what's expected in destPath, from the source named by from, against what target found there, and
+// checks its disc sheets, logging every difference and adding them to totals
+func verifyMapping(destPath string, from string, expected map[string]device_state.FileState, target map[string]device_state.FileState, totals *verifyTotals) error {
+	comparison := device_state.Compare(expected, target)
+	for _, f := range comparison.New {
+		logging.Log(logging.Action, "", "! %s (missing from target)", f.Path)
+	}
+	for _, f := range comparison.Changed {
+		if copied := target[f.Path]; copied.Size != f.Size {
+			logging.Log(logging.Action, "", "~ %s (%s in %s, %s on target)", f.Path, logging.FormatBytes(uint64(f.Size)), from, logging.FormatBytes(uint64(copied.Size)))
+		} else {
+			logging.Log(logging.Action, "", "~ %s (contents differ)", f.Path)
+		}
+	}
+	for _, f := range comparison.Orphaned {
+		logging.Log(logging.Action, "", "+ %s (not in %s)", f.Path, from)
+	}
+	logging.Log(logging.Action, "", "%d verified, %d missing, %d differing, %d extra",
+		len(comparison.Unchanged), len(comparison.New), len(comparison.Changed), len(comparison.Orphaned))
+
+	totals.missing += len(comparison.New)
+	totals.differing += len(comparison.Changed)
+	totals.extra += len(comparison.Orphaned)
+
+	dangling, err := disc_refs.Check(file_operations.Filesystem(), destPath)
+	if err != nil {
+		return fmt.Errorf("error checking disc references: %w", err)
+	}
+	for _, d := range dangling {
+		logging.Log(logging.Action, "", "! %s (refers to missing %s)", d.Sheet, strings.Join(d.Missing, ", "))
+	}
+	totals.dangling += len(dangling)
+	return nil
+}
+
+// reports the outcome of verifying against the source named by from, failing if anything expected is missing
+// or differs
+func verificationResult(totals verifyTotals, from string) error {
+	fmt.Println()
+	if totals.missing+totals.differing+totals.dangling > 0 {
+		return fmt.Errorf("verification failed: %d missing, %d differing, %d extra, %d sheet(s) with missing references", totals.missing, totals.differing, totals.extra, totals.dangling)
+	}
+	if totals.extra > 0 {
+		logging.LogWarning("%d file(s) on the target aren't in the %s", totals.extra, from)
+	}
+	logging.Log(logging.Base, logging.IconComplete, "Every file in the %s is on the target intact", from)
+	return nil
+}
+
+// checks a previous copy by comparing each mapping's source against what's on the target, accounting for the
+// explodes, renames, and safe Windows names the copy applied
+func runVerify(config *cli_parsing.Config, plans []mappingPlan, cache *file_operations.ChecksumCache) error {
+	withHashes := !config.VerifySkipHashes
+
+	var totals verifyTotals
+	for _, plan := range plans {
+		logging.Log(logging.Base, "", "\033[1;34m%s -> %s\033[0m", plan.mapping.Source, plan.mapping.Destination)
+
+		target, err := scanVerified(plan.destPath, withHashes)
+		if err != nil {
+			return err
+		}
+		source, err := copiedStates(config, plan, target, withHashes, cache)
+		if err != nil {
+			return err
+		}
+		if err := verifyMapping(plan.destPath, "source", source, target, &totals); err != nil {
+			return err
+		}
+	}
+	return verificationResult(totals, "source")
+}
+
+// checks a previous copy against what it recorded in the target's state file, for when the source isn't to hand.
+// Every mapping recorded is checked, or only those given.
+func runVerifyState(config *cli_parsing.Config) error {
+	state, err := device_state.LoadTargetState(config.TargetDir)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no state file at %s; copy with '--targetState' to write one", device_state.TargetStatePath(config.TargetDir))
+	}
+
+	mappings := state.Mappings
+	if len(config.Mappings) > 0 {
+		mappings = make([]device_state.MappingState, 0, len(config.Mappings))
+		for _, mapping := range config.Mappings {
+			recorded, ok := state.Mapping(mapping.Destination)
+			if !ok {
+				return fmt.Errorf("the state file has no copy to %s recorded", mapping.Destination)
+			}
+			mappings = append(mappings, recorded)
+		}
+	}
+
+	withHashes := !config.VerifySkipHashes
+	var totals verifyTotals
+	for _, recorded := range mappings {
+		_, destPath := mappingPaths(config, cli_parsing.DirMapping{Source: recorded.Source, Destination: recorded.Destination})
+		logging.Log(logging.Base, "", "\033[1;34m%s -> %s\033[0m (copied %s)", recorded.Source, recorded.Destination, recorded.CopiedAt.Local().Format("2006-01-02 15:04"))
+
+		target, err := scanVerified(destPath, withHashes)
+		if err != nil {
+			return err
+		}
+		expected := recorded.States()
+		if !withHashes {
+			for relPath, f := range expected {
+				f.Hash = ""
+				expected[relPath] = f
+			}
+		}
+		if err := verifyMapping(destPath, "state file", expected, target, &totals); err != nil {
+			return err
+		}
+	}
+	return verificationResult(totals, "state file")
+}
+
+// checks each mapping's platform folder against its signed manifest, for those receiving a pre-built card
+// without the source it was built from
+func runVerifySignatures(config *cli_parsing.Config) error {
+	var totalMissing, totalDiffering, totalExtra, unsigned int
+	for _, mapping := range config.Mappings {
+		_, destPath := mappingPaths(config, mapping)
+		logging.Log(logging.Base, "", "\033[1;34m%s\033[0m", mapping.Destination)
+
+		manifest, err := device_state.VerifyManifestSignature(destPath, config.VerifyKey)
+		if err != nil {
+			logging.Log(logging.Action, "", "! %v", err)
+			unsigned++
+			continue
+		}
+		if manifest == nil {
+			logging.Log(logging.Action, "", "! no manifest in %s", destPath)
+			unsigned++
+			continue
+		}
+
+		check, err := manifest.Check(destPath)
+		if err != nil {
+			return fmt.Errorf("unable to check %s: %w", destPath, err)
+		}
+		for _, relPath := range check.Missing {
+			logging.Log(logging.Action, "", "! %s (missing)", relPath)
+		}
+		for _, relPath := range check.Differing {
+			logging.Log(logging.Action, "", "~ %s (changed since signing)", relPath)
+		}
+		for _, relPath := range check.Extra {
+			logging.Log(logging.Action, "", "+ %s (added since signing)", relPath)
+		}
+		logging.Log(logging.Action, "", "Signature valid; %d verified, %d missing, %d changed, %d added",
+			len(manifest.Files)-len(check.Missing)-len(check.Differing), len(check.Missing), len(check.Differing), len(check.Extra))
+
+		totalMissing += len(check.Missing)
+		totalDiffering += len(check.Differing)
+		totalExtra += len(check.Extra)
+	}
+
+	fmt.Println()
+	if unsigned+totalMissing+totalDiffering+totalExtra > 0 {
+		return fmt.Errorf("verification failed: %d folder(s) without a valid signature, %d missing, %d changed, %d added", unsigned, totalMissing, totalDiffering, totalExtra)
+	}
+	logging.Log(logging.Base, logging.IconComplete, "Every platform folder matches its signed manifest")
+	return nil
+}
+
+// where a source file (relative to its mapping) ends up relative to the destination once the copy's explodes and
+// renames have run, as a slash-separated path
+func copiedPath(config *cli_parsing.Config, opts copy_funcs.CopyOptions, relPath string) string {
+	copied := filepath.ToSlash(opts.DestRelPath(relPath))
+
+	// explodes and renames only look at the top of the destination folder, in this order, unless explodes are
+	// recursive, when every folder path ending in the exploded one loses it
+	for _, explodeDir := range config.ExplodeDirs {
+		copied = file_operations.ExplodedPath(copied, explodeDir, config.ExplodeRecursive)
+	}
+	for _, r := range config.Renames {
+		oldName, newName := filepath.ToSlash(r.OldName), filepath.ToSlash(r.NewName)
+		if copied == oldName {
+			copied = newName
+		} else if rest, ok := strings.CutPrefix(copied, oldName+"/"); ok {
+			copied = newName + "/" + rest
+		}
+	}
+	return copied
+}
+
+// whether '--deleteAfter' deletes the file at the given slash-separated path relative to the destination
+func deletedAfter(config *cli_parsing.Config, copied string) bool {
+	return file_operations.IsProtected(copied, config.DeleteAfter) && !file_operations.IsProtected(copied, config.Protect)
+}
+
+// whether the run edits the game list at the given slash-separated path relative to the destination once it's
+// copied, by pruning, merging, converting, generating, filling, or filtering it, or by pointing it at artwork and
+// games moved or renamed, so what's on the target is expected to differ from the source's
+func editedGamelist(config *cli_parsing.Config, plan mappingPlan, copied string) bool {
+	if strings.Contains(copied, "/") || !gamelist.IsListName(copied) {
+		return false
+	}
+	return config.PruneGamelists || config.MergeGamelists != "" || config.ConvertGamelist != "" || config.GenerateGamelist ||
+		config.Scrape || config.FetchThumbnails || config.MetadataLang != "" || len(config.GamelistTags) > 0 ||
+		len(plan.mediaMoved) > 0 || len(plan.mediaLeftOut) > 0 || plan.renamesFiles()
+}
+
+// whether the file at the given slash-separated path relative to the destination is changed after it's copied, by
+// a '--rewrite' or as a game list, so it can only be checked against what's on the target
+func editedAfterCopy(config *cli_parsing.Config, plan mappingPlan, copied string) bool {
+	return rewritten(config, copied) || editedGamelist(config, plan, copied)
+}
+
+// whether a '--rewrite' edits the file at the given slash-separated path relative to the destination
+func rewritten(config *cli_parsing.Config, copied string) bool {
+	for _, r := range config.FileRewrites {
+		pattern := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(r.FileGlob)), "./")
+		if matched, _ := doublestar.Match(pattern, copied); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// loads the persistent cache of source checksums when this run will hash source files
+func loadChecksumCache(config *cli_parsing.Config) *file_operations.ChecksumCache {
+	verifyHashes := config.Command == cli_parsing.CommandVerify && !config.VerifySkipHashes
+	if config.NoCache || !(config.DiffHashes || verifyHashes || config.Verify || config.Manifest || config.TargetState || config.Dedupe || len(config.Dats) > 0) {
+		return nil
+	}
+
+	cachePath, err := file_operations.DefaultChecksumCachePath()
+	if err != nil {
+		logging.LogWarning("%v; checksums won't be cached", err)
+		return nil
+	}
+
+	cache, err := file_operations.LoadChecksumCache(cachePath)
+	if err != nil {
+		logging.LogWarning("%v; starting with an empty cache", err)
+	}
+	return cache
+}
+
+func saveChecksumCache(cache *file_operations.ChecksumCache) {
+	if err := cache.Save(); err != nil {
+		logging.LogWarning("Unable to save checksum cache: %v", err)
+	}
+}
+
+func main() {
+	intro := `   ___  ____  __  ________               ____          _
+  / _ \/ __ \/  |/  / ___/__  ___  __ __/ __/__  ___ _(_)__  ___
+ / , _/ /_/ / /|_/ / /__/ _ \/ _ \/ // / _// _ \/ _ '/ / _ \/ -_)
+/_/|_|\____/_/  /_/\___/\___/ .__/\_, /___/_//_/\_, /_/_//_/\__/
+                           /_/   /___/         /___/`
+	fmt.Println(intro)
+
+	config, err := cli_parsing.ParseAndValidate()
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	logging.SetVerbose(config.Verbose)
+	if config.LogFile != "" {
+		if err := logging.OpenLogFile(config.LogFile); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		defer logging.CloseLogFile()
+	}
+
+	if config.ExportRecipe != "" {
+		if err := cli_parsing.WriteRecipe(config.ExportRecipe, config.ExportedRecipe); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		logging.Log(logging.Base, logging.IconComplete, "Wrote this run's options to recipe %s", config.ExportRecipe)
+	}
+
+	// a dry run must never touch the target, so rather than trusting every operation to check, writes are
+	// made impossible
+	if config.DryRun || config.PlanOnly {
+		file_operations.SetFilesystem(fsys.ReadOnly(fsys.OS))
+	}
+
+	if config.Command == cli_parsing.CommandExamples {
+		if err := runExamples(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandHistory {
+		if err := runHistory(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandStatus {
+		if err := runStatus(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandProfile {
+		if err := runProfileFetch(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandSaves {
+		if err := runSaves(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandUndo {
+		if err := runUndo(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandSnapshot {
+		if err := runSnapshot(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandVerify && config.VerifyFromState {
+		if err := runVerifyState(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandVerify && config.VerifyKey != nil {
+		if err := runVerifySignatures(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// per-file logging is replaced by a progress bar unless asked for, or when there's no terminal to draw it on
+	var progress *logging.Progress
+	if !config.Verbose && !config.DryRun && config.Command == cli_parsing.CommandCopy && logging.StdoutIsTerminal() {
+		progress = logging.NewProgress()
+	}
+
+	cache := loadChecksumCache(config)
+
+	// shared by every mapping so limits and statistics apply to the run as a whole
+	stream := file_operations.StreamOptions{
+		Limiter:      file_operations.NewRateLimiter(config.BandwidthLimit),
+		StallTimeout: config.StallTimeout,
+		StallRetries: config.StallRetries,
+		Flush:        config.Flush,
+		Health:       file_operations.NewHealthMonitor(),
+		Progress:     progress,
+		Cache:        cache,
+		FileMode:     config.FileMode,
+	}
+
+	library, err := loadLaunchBox(config)
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+	plans, err := buildPlans(config, stream, library)
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+	scraper, err := loadScraper(config)
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+	fetcher := loadThumbnails(config)
+	for i := range plans {
+		plans[i].scraper, plans[i].thumbnails = scraper, fetcher
+	}
+	if err := checkIndexLimits(config, plans); err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if config.Command == cli_parsing.CommandDiff {
+		err := runDiff(config, plans, cache)
+		saveChecksumCache(cache)
+		if err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandVerify {
+		err := runVerify(config, plans, cache)
+		saveChecksumCache(cache)
+		if err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if config.Command == cli_parsing.CommandDoctor {
+		if err := runDoctor(config, plans); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := findStrayExtensions(plans, config.StrictExtensions); err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	matcher, err := loadDats(config)
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+	if matcher != nil {
+		if err := matchDats(plans, matcher); err != nil {
+			logging.LogError("Error checking DATs: %v", err)
+			os.Exit(1)
+		}
+	}
+	// bad dumps go first so 1G1R only chooses between good releases
+	findBadDumps(plans, config.BadDumps)
+	if matcher != nil && len(config.RegionPriority) > 0 {
+		selectOneGameOneRom(plans, matcher, config.RegionPriority)
+	}
+	if len(config.PreferLanguage) > 0 {
+		selectPreferredLanguages(plans, config.PreferLanguage)
+	}
+	if config.PreferRevision != "" {
+		selectPreferredRevisions(plans, config.PreferRevision == cli_parsing.PreferRevisionLatest)
+	}
+	if err := findDuplicates(plans, config.Dedupe); err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	identity, err := targetIdentity(config)
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+	if err := checkExpectedDevice(config, identity); err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	fits := summarizeWarnConfirm(config, plans)
+
+	if config.PlanOutput != "" {
+		if err := writePlan(config, plans); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+	if config.PlanOnly {
+		if !fits {
+			logging.LogError("Error: the copy won't fit on the target")
+			os.Exit(1)
+		}
+		files, bytes := planTotals(plans)
+		logging.Log(logging.Base, logging.IconComplete, "Plan complete: %d file(s), %s to copy; nothing was copied", files, logging.FormatBytes(uint64(bytes)))
+		return
+	}
+
+	if identity, err = labelTarget(config, identity); err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := removeStaleArtifacts(config, plans); err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if err := pruneTrash(config); err != nil {
+		logging.LogError("Error: %v", err)
+		os.Exit(1)
+	}
+
+	if config.TestCapacity {
+		if err := runCapacityTest(config); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	startedAt := time.Now()
+	if config.BackupBeforeClean != "" {
+		if err := backupBeforeClean(config, plans, startedAt); err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	var journal *device_state.Journal
+	if config.Journal != "" && !config.DryRun {
+		journal, err = startJournal(config, startedAt)
+		if err != nil {
+			logging.LogError("Error: %v", err)
+			os.Exit(1)
+		}
+	}
+	// os.Exit skips deferred calls, so the journal is closed by hand before every exit from here on, failed runs
+	// included
+	closeJournal := func() bool {
+		if journal == nil {
+			return true
+		}
+		if err := journal.Close(); err != nil {
+			logging.LogError("Error: failed to close journal %s: %v", config.Journal, err)
+			return false
+		}
+		return true
+	}
+	exit := func(code int) {
+		closeJournal()
+		os.Exit(code)
+	}
+
+	progress.SetTotal(planTotals(plans))
+	progress.Start()
+	for i := range plans {
+		plans[i].trashBatch = file_operations.NewTrashBatch(startedAt)
+		if config.Timeout > 0 {
+			plans[i].opts.Deadline = startedAt.Add(config.Timeout)
+		}
+	}
+	err = runMappings(config, plans)
+	progress.Stop()
+	if err == nil && config.TargetState {
+		logging.ClearMapping()
+		err = writeTargetState(config, plans, cache)
+	}
+	saveChecksumCache(cache)
+	if !config.DryRun {
+		recordRun(config, identity, plans, startedAt, err)
+	}
+	if errors.Is(err, copy_funcs.ErrDeadline) {
+		logging.ClearMapping()
+		if config.Transactional {
+			logging.LogError("Error: stopped after the run went past its '--timeout' of %s; nothing on the target was changed", config.Timeout)
+		} else {
+			logging.LogError("Error: stopped after the run went past its '--timeout' of %s; every file copied was finished, but later operations didn't run. Run again to finish the copy.", config.Timeout)
+		}
+		reportMediaHealth(stream.Health)
+		exit(1)
+	}
+	if err != nil {
+		logging.LogError("Error: %v", err)
+		reportMediaHealth(stream.Health)
+		exit(1)
+	}
+
+	if config.BiosSource != "" {
+		if err := copyBios(config, plans); err != nil {
+			logging.LogError("Error: %v", err)
+			reportMediaHealth(stream.Health)
+			exit(1)
+		}
+	}
+
+	reportMediaHealth(stream.Health)
+	if failed := failedCopies(plans); failed > 0 {
+		logging.LogError("Error: %d file(s) couldn't be copied; see the list after each mapping above", failed)
+		exit(1)
+	}
+	if !closeJournal() {
+		os.Exit(1)
+	}
 	logging.Log(logging.Base, "", "All transfers & processing completed successfully!")
 }
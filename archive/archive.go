@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// Member is a file stored in an archive, as listed in the archive's directory
+type Member struct {
+	// slash-separated path within the archive
+	Name string
+	// uncompressed size
+	Size int64
+	// CRC32 of the uncompressed contents, big-endian as DATs print it
+	CRC32 []byte
+}
+
+// IsZip reports whether fileName has a zip extension
+func IsZip(fileName string) bool {
+	return strings.EqualFold(path.Ext(fileName), ".zip")
+}
+
+// ReadZip lists the files in the zip archive at filePath from its central directory, which records every
+// member's size and CRC32, so nothing is decompressed. Directories are left out.
+func ReadZip(fs fsys.FS, filePath string) ([]Member, error) {
+	info, err := fs.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info for %s: %w", filePath, err)
+	}
+
+	file, err := fs.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	reader, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip directory of %s: %w", filePath, err)
+	}
+
+	members := make([]Member, 0, len(reader.File))
+	for _, entry := range reader.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		crc := make([]byte, 4)
+		binary.BigEndian.PutUint32(crc, entry.CRC32)
+		members = append(members, Member{Name: entry.Name, Size: int64(entry.UncompressedSize64), CRC32: crc})
+	}
+	return members, nil
+}
+
+// Fingerprint identifies an archive's contents by the sizes and CRC32s of its members regardless of their names,
+// order, or how they were compressed, so two archives of the same files share a fingerprint
+func Fingerprint(members []Member) string {
+	parts := make([]string, 0, len(members))
+	for _, m := range members {
+		parts = append(parts, fmt.Sprintf("%x:%d", m.CRC32, m.Size))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
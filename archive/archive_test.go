@@ -0,0 +1,94 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// builds a zip of the given files, in order, with the given compression method
+func buildZip(t *testing.T, method uint16, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		entry, err := writer.CreateHeader(&zip.FileHeader{Name: files[i], Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadZip(t *testing.T) {
+	mem := fsys.NewMemFS()
+	zipPath := filepath.Join(t.TempDir(), "Tetris (World).zip")
+	mem.MkdirAll(filepath.Dir(zipPath), 0755)
+	mem.WriteFile(zipPath, buildZip(t, zip.Deflate, "Tetris (World).gb", "tetris", "docs/", ""), 0644)
+
+	members, err := ReadZip(mem, zipPath)
+	if err != nil {
+		t.Fatalf("ReadZip() error = %v", err)
+	}
+
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE([]byte("tetris")))
+	expected := []Member{{Name: "Tetris (World).gb", Size: 6, CRC32: crc}}
+	if !reflect.DeepEqual(members, expected) {
+		t.Errorf("ReadZip() = %+v, want %+v", members, expected)
+	}
+
+	mem.WriteFile(zipPath, []byte("not a zip"), 0644)
+	if _, err := ReadZip(mem, zipPath); err == nil {
+		t.Error("ReadZip() of a corrupt zip should fail")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := t.TempDir()
+	mem.MkdirAll(dir, 0755)
+
+	zips := map[string][]byte{
+		"deflated.zip": buildZip(t, zip.Deflate, "a.nes", "aaa", "b.nes", "bb"),
+		// same contents under other names, in another order, uncompressed
+		"stored.zip": buildZip(t, zip.Store, "B.nes", "bb", "A.nes", "aaa"),
+		"other.zip":  buildZip(t, zip.Store, "a.nes", "aab", "b.nes", "bb"),
+	}
+	fingerprints := make(map[string]string)
+	for name, data := range zips {
+		mem.WriteFile(filepath.Join(dir, name), data, 0644)
+		members, err := ReadZip(mem, filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadZip(%s) error = %v", name, err)
+		}
+		fingerprints[name] = Fingerprint(members)
+	}
+
+	if fingerprints["deflated.zip"] != fingerprints["stored.zip"] {
+		t.Errorf("zips of the same files should share a fingerprint: %q vs %q", fingerprints["deflated.zip"], fingerprints["stored.zip"])
+	}
+	if fingerprints["deflated.zip"] == fingerprints["other.zip"] {
+		t.Errorf("zips of different files shouldn't share a fingerprint: %q", fingerprints["other.zip"])
+	}
+}
+
+func TestIsZip(t *testing.T) {
+	for name, want := range map[string]bool{"game.zip": true, "GAME.ZIP": true, "game.7z": false, "zip": false, "game.nes": false} {
+		if got := IsZip(name); got != want {
+			t.Errorf("IsZip(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
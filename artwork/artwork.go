@@ -0,0 +1,174 @@
+package artwork
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// the quality downscaled JPEGs are written at; high enough that nothing is lost that a small screen would show
+const jpegQuality = 90
+
+// Size is the width and height of an image, in pixels
+type Size struct {
+	Width  int
+	Height int
+}
+
+func (s Size) String() string {
+	return fmt.Sprintf("%dx%d", s.Width, s.Height)
+}
+
+// ParseSize reads a size written as 'WIDTHxHEIGHT', e.g. '250x360'
+func ParseSize(s string) (Size, error) {
+	width, height, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "x")
+	if !ok {
+		return Size{}, fmt.Errorf("expected WIDTHxHEIGHT, e.g. '250x360'")
+	}
+	w, errW := strconv.Atoi(width)
+	h, errH := strconv.Atoi(height)
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return Size{}, fmt.Errorf("the width and height must be whole numbers above 0")
+	}
+	return Size{Width: w, Height: h}, nil
+}
+
+// IsImage reports whether name is a PNG or JPEG by its extension, the images Downscale reads
+func IsImage(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+// Fit returns the largest size with the aspect ratio of size that fits within max, or size itself if it already
+// fits; images are never enlarged
+func Fit(size Size, max Size) Size {
+	if size.Width <= max.Width && size.Height <= max.Height {
+		return size
+	}
+	scale := math.Min(float64(max.Width)/float64(size.Width), float64(max.Height)/float64(size.Height))
+	fit := Size{Width: int(math.Round(float64(size.Width) * scale)), Height: int(math.Round(float64(size.Height) * scale))}
+	if fit.Width < 1 {
+		fit.Width = 1
+	}
+	if fit.Height < 1 {
+		fit.Height = 1
+	}
+	return fit
+}
+
+// Downscale returns the PNG or JPEG in data shrunk to fit within max, in the same format, and true; or data itself
+// and false if it already fits. Each pixel of the result is the average of the pixels it covers, which keeps box
+// art text legible where skipping pixels would leave it jagged.
+func Downscale(data []byte, max Size) ([]byte, bool, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return data, false, fmt.Errorf("unable to read image: %w", err)
+	}
+	fit := Fit(Size{Width: config.Width, Height: config.Height}, max)
+	if fit == (Size{Width: config.Width, Height: config.Height}) {
+		return data, false, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return data, false, fmt.Errorf("unable to read image: %w", err)
+	}
+	scaled := scale(img, fit)
+
+	var out bytes.Buffer
+	switch format {
+	case "png":
+		err = png.Encode(&out, scaled)
+	case "jpeg":
+		err = jpeg.Encode(&out, scaled, &jpeg.Options{Quality: jpegQuality})
+	default:
+		return data, false, fmt.Errorf("unable to resize %s images", format)
+	}
+	if err != nil {
+		return data, false, fmt.Errorf("unable to write resized image: %w", err)
+	}
+	return out.Bytes(), true, nil
+}
+
+// the source pixels one pixel of a downscaled row or column covers, from first, and how much each counts towards it
+type span struct {
+	first   int
+	weights []float32
+}
+
+// the spans of each of n pixels shrunk from srcLen
+func spans(srcLen int, n int) []span {
+	ratio := float64(srcLen) / float64(n)
+	out := make([]span, n)
+	for i := range out {
+		lo, hi := float64(i)*ratio, float64(i+1)*ratio
+		first, last := int(lo), int(math.Ceil(hi))
+		if last > srcLen {
+			last = srcLen
+		}
+		weights := make([]float32, last-first)
+		for j := range weights {
+			covered := math.Min(hi, float64(first+j+1)) - math.Max(lo, float64(first+j))
+			weights[j] = float32(covered / ratio)
+		}
+		out[i] = span{first: first, weights: weights}
+	}
+	return out
+}
+
+// shrinks img to size by averaging, first across then down. Averaging is done on premultiplied colors so
+// transparent pixels don't darken the edges next to them.
+func scale(img image.Image, size Size) *image.RGBA {
+	bounds := img.Bounds()
+	src, ok := img.(*image.RGBA)
+	if !ok || src.Bounds().Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	}
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+
+	// each source row shrunk to the new width, 4 channels a pixel
+	across := make([]float32, size.Width*srcHeight*4)
+	columns := spans(srcWidth, size.Width)
+	for y := 0; y < srcHeight; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, col := range columns {
+			var sum [4]float32
+			for j, weight := range col.weights {
+				pixel := row[(col.first+j)*4:]
+				for c := 0; c < 4; c++ {
+					sum[c] += float32(pixel[c]) * weight
+				}
+			}
+			copy(across[(y*size.Width+x)*4:], sum[:])
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, size.Width, size.Height))
+	for y, row := range spans(srcHeight, size.Height) {
+		for x := 0; x < size.Width; x++ {
+			var sum [4]float32
+			for j, weight := range row.weights {
+				pixel := across[((row.first+j)*size.Width+x)*4:]
+				for c := 0; c < 4; c++ {
+					sum[c] += pixel[c] * weight
+				}
+			}
+			out := dst.Pix[y*dst.Stride+x*4:]
+			for c := 0; c < 4; c++ {
+				out[c] = uint8(math.Min(255, math.Round(float64(sum[c]))))
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,137 @@
+package artwork
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Size
+		wantErr bool
+	}{
+		{"250x360", Size{250, 360}, false},
+		{" 640X480 ", Size{640, 480}, false},
+		{"250", Size{}, true},
+		{"250x", Size{}, true},
+		{"0x360", Size{}, true},
+		{"-250x360", Size{}, true},
+		{"2.5x360", Size{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFit(t *testing.T) {
+	max := Size{250, 360}
+	tests := []struct {
+		size Size
+		want Size
+	}{
+		// already fits, and isn't enlarged
+		{Size{200, 100}, Size{200, 100}},
+		{Size{250, 360}, Size{250, 360}},
+		// limited by the width
+		{Size{1000, 1000}, Size{250, 250}},
+		// limited by the height
+		{Size{500, 1440}, Size{125, 360}},
+		// never shrinks a side away entirely
+		{Size{10000, 1}, Size{250, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size.String(), func(t *testing.T) {
+			if got := Fit(tt.size, max); got != tt.want {
+				t.Errorf("Fit(%v, %v) = %v, want %v", tt.size, max, got, tt.want)
+			}
+		})
+	}
+}
+
+// an image of width by height whose left half is red and right half blue
+func halves(width int, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestDownscale(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, halves(40, 20)); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+
+	// a pixel across covers two source pixels, so the middle column blends red into blue
+	resized, ok, err := Downscale(pngData.Bytes(), Size{20, 20})
+	if err != nil || !ok {
+		t.Fatalf("Downscale() = %v, %v; want the image resized", ok, err)
+	}
+	img, format, err := image.Decode(bytes.NewReader(resized))
+	if err != nil || format != "png" {
+		t.Fatalf("Downscale() wrote %s, %v; want a PNG", format, err)
+	}
+	if got := img.Bounds().Size(); got != image.Pt(20, 10) {
+		t.Errorf("Downscale() size = %v, want 20x10", got)
+	}
+	if r, _, b, _ := img.At(0, 0).RGBA(); r>>8 != 255 || b != 0 {
+		t.Errorf("Downscale() left edge = %v, want red", img.At(0, 0))
+	}
+	if r, _, b, _ := img.At(19, 9).RGBA(); r != 0 || b>>8 != 255 {
+		t.Errorf("Downscale() right edge = %v, want blue", img.At(19, 9))
+	}
+
+	// an odd width splits a pixel between the halves
+	resized, _, err = Downscale(pngData.Bytes(), Size{3, 3})
+	if err != nil {
+		t.Fatalf("Downscale() error = %v", err)
+	}
+	img, _, _ = image.Decode(bytes.NewReader(resized))
+	if r, _, b, _ := img.At(1, 0).RGBA(); r>>8 < 120 || r>>8 > 135 || b>>8 < 120 || b>>8 > 135 {
+		t.Errorf("Downscale() middle = %v, want an even mix of red and blue", img.At(1, 0))
+	}
+
+	// JPEGs stay JPEGs
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, halves(400, 400), nil); err != nil {
+		t.Fatalf("failed to encode JPEG: %v", err)
+	}
+	resized, ok, err = Downscale(jpegData.Bytes(), Size{250, 360})
+	if err != nil || !ok {
+		t.Fatalf("Downscale() = %v, %v; want the image resized", ok, err)
+	}
+	if config, format, err := image.DecodeConfig(bytes.NewReader(resized)); err != nil || format != "jpeg" || config.Width != 250 || config.Height != 250 {
+		t.Errorf("Downscale() wrote a %dx%d %s, %v; want a 250x250 JPEG", config.Width, config.Height, format, err)
+	}
+
+	// images that already fit are left as they are
+	if same, ok, err := Downscale(pngData.Bytes(), Size{250, 360}); ok || err != nil || !bytes.Equal(same, pngData.Bytes()) {
+		t.Errorf("Downscale() of a small image = %v, %v; want it unchanged", ok, err)
+	}
+
+	if _, _, err := Downscale([]byte("not an image"), Size{250, 360}); err == nil {
+		t.Error("Downscale() of a non-image succeeded, want an error")
+	}
+}
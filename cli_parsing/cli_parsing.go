@@ -2,47 +2,458 @@ package cli_parsing
 
 import (
 	"bufio"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/jkingsman/ROMCopyEngine/artwork"
+	"github.com/jkingsman/ROMCopyEngine/copy_funcs"
+	"github.com/jkingsman/ROMCopyEngine/device_state"
+	"github.com/jkingsman/ROMCopyEngine/disk_info"
+	"github.com/jkingsman/ROMCopyEngine/examples"
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/gamelist"
+	"github.com/jkingsman/ROMCopyEngine/logging"
+	"github.com/jkingsman/ROMCopyEngine/profiles"
+	"github.com/jkingsman/ROMCopyEngine/romtags"
+	"github.com/jkingsman/ROMCopyEngine/saves"
+)
+
+// supported '--checksum' algorithms; the first is used by a plain '--verify'
+var checksumAlgorithms = file_operations.ChecksumAlgorithms
+
+// how '--badDumps' treats ROMs flagged as bad dumps
+const (
+	BadDumpsWarn = "warn"
+	BadDumpsSkip = "skip"
+	BadDumpsCopy = "copy"
+)
+
+var badDumpModes = []string{BadDumpsWarn, BadDumpsSkip, BadDumpsCopy}
+
+// how '--strictExtensions' treats files whose extension doesn't belong to their mapping's platform
+const (
+	StrictExtensionsWarn = "warn"
+	StrictExtensionsSkip = "skip"
 )
 
+var strictExtensionsModes = []string{StrictExtensionsWarn, StrictExtensionsSkip}
+
+// which revision of a game '--preferRevision' copies
+const (
+	PreferRevisionLatest   = "latest"
+	PreferRevisionEarliest = "earliest"
+)
+
+var preferRevisionModes = []string{PreferRevisionLatest, PreferRevisionEarliest}
+
+// operations '--yes' and '--confirm' choose whether to prompt for
+const (
+	// the copy as a whole
+	ConfirmCopy = "copy"
+	// emptying destination folders with '--cleanTarget'
+	ConfirmClean = "clean"
+	// continuing when the copy won't fit in the target's free space
+	ConfirmSpace = "space"
+)
+
+var confirmableOps = []string{ConfirmCopy, ConfirmClean, ConfirmSpace}
+
+// subcommands; copy is the default so existing invocations without a command keep working
+const (
+	CommandCopy     = "copy"
+	CommandSnapshot = "snapshot"
+	CommandDiff     = "diff"
+	CommandVerify   = "verify"
+	CommandDoctor   = "doctor"
+	CommandExamples = "examples"
+	CommandHistory  = "history"
+	CommandStatus   = "status"
+	CommandProfile  = "profile"
+	CommandSaves    = "saves"
+	CommandUndo     = "undo"
+)
+
+type CopyCmd struct{}
+
+type SnapshotCmd struct {
+	Output     string `help:"file to write the snapshot of the target to, e.g. 'card.json'" name:"output" type:"path" required:""`
+	SkipHashes bool   `help:"record only paths and sizes, skipping the (slow) hashing of every file on the target" optional:"" name:"skipHashes"`
+}
+
+type DiffCmd struct {
+	Against string `help:"a snapshot written by the 'snapshot' command to compare against instead of the live target, so the device doesn't need to be connected" name:"against" type:"existingfile"`
+	Hashes  bool   `help:"also hash source files and compare them against the target's hashes (requires a snapshot taken with hashes when using '--against'); slower, but catches same-size changes" optional:"" name:"hashes"`
+}
+
+type VerifyCmd struct {
+	SkipHashes bool   `help:"compare only which files exist and their sizes, skipping the (slow) hashing of every file on both sides" optional:"" name:"skipHashes"`
+	FromState  bool   `help:"instead of comparing against the source, check each mapping's destination folder against what the copy that wrote it recorded in the target's state file (see '--targetState'). Needs only '--targetDir'; every mapping recorded is checked unless mappings are given." optional:"" name:"fromState"`
+	Signature  string `help:"instead of comparing against the source, check each destination platform folder against its signed manifest (see '--signKey') using the given Ed25519 public key (PEM), reporting a bad signature or any file added, removed, or changed since it was signed. Needs only '--targetDir' and the mappings." name:"signature" type:"existingfile"`
+}
+
+type DoctorCmd struct{}
+
+type ExamplesCmd struct {
+	Args []string `arg:"" optional:"" help:"an example name to print its command line, or 'run' and an example name to run it; lists every example if omitted"`
+}
+
+type HistoryCmd struct {
+	Limit int `help:"how many of the most recent runs to list; 0 lists them all" name:"limit" default:"20"`
+}
+
+type StatusCmd struct{}
+
+type SavesCmd struct {
+	BackupDir string `help:"the folder on this computer that saves on the target are backed up to and restored from, e.g. '~/Saves/RG35XX'; created if it doesn't exist" name:"backupDir" type:"path" required:""`
+}
+
+type UndoCmd struct{}
+
+type ProfileCmd struct {
+	Fetch ProfileFetchCmd `cmd:"" help:"download a community-maintained profile from a profile repository into your profile directory, so '--profile' can use it; its checksum is checked against the repository's index, which only catches a damaged download, and against '--sha256' if given, which pins the exact file"`
+}
+
+type ProfileFetchCmd struct {
+	Name   string `arg:"" help:"the name of the profile to fetch, e.g. 'trimui'"`
+	SHA256 string `help:"the SHA-256 the profile's file must have, e.g. one noted when it was last fetched, so a profile that has changed since isn't installed" name:"sha256"`
+	Repo   string `help:"the URL of the profile repository's raw files, holding its 'index.yaml', e.g. 'https://raw.githubusercontent.com/<owner>/<repository>/main'; required, as no repository is built in" name:"repo"`
+}
+
 type CLI struct {
-	SourceDir        string   `help:"the source directory containing platform folders ('snes', 'gba', etc.) to be copied from e.g. 'C:\\ROMS' or '/home/ROMS'" name:"sourceDir" type:"path" required:""`
-	TargetDir        string   `help:"target directory (usually on device) containing platform folders ('snes', 'gba', etc.), e.g. 'J:\\' or '/media/usb-drive/'" name:"targetDir" type:"path" required:""`
-	Mappings         []string `help:"a mapping of source platform folder to destination platform folder for the ROMs in the format 'source:destination'. For example, '--mapping snes:SFC --mapping gg:GameGear' would copy the contents of the sourceDir's 'snes' folder to the targetDir's 'SFC' folder and the contents of the sourceDir's 'gg' folder to the targetDir's 'GameGear' folder." name:"mapping" required:"" type:"string"`
-	Renames          []string `help:"rename files or folders from a given name to a given name after copy. For example, '--rename gameslist.xml:miyoogameslist.xml' would rename all occurrences of 'gameslist.xml' in all folders to 'miyoogameslist.xml'; '--rename images:Imgs' could be used to rename image folders. Multiples of this flag are allowed." name:"rename" type:"string"`
-	CopyInclude      []string `help:"copy only files and folders within each mapping which match the given glob (for example, '--copyInclude '*_favorite*'' would only copy files/folders from each source folder containing the string 'favorite'; '--copyInclude '*.xml' would only copy XML files found in each source folder. Remember to single quote your glob to prevent shell expansion. Multiples of this flag are allowed, and will be processed as an OR relation (files matching any --copyInclude will be included). This supports globstar (e.g. '--copyInclude **/*.png' copies PNGs from all child directories, whereas '--copyInclude *.png' only copies top-level PNGs in the platform root)." name:"copyInclude" type:"string"`
-	CopyExclude      []string `help:"copy only files and folders within each mapping which do NOT match the given glob (for example, '--copyExclude '*.xml'' would copy all files and folders except those ending in '.xml'. Remember to single quote your glob to prevent shell expansion. Multiples of this flag are allowed, and will be processed as an AND relation (files matching any --copyExclude will be excluded). '--copyExclude' entries are processed after '--copyExclude' entries" name:"copyExclude" type:"string"`
-	ExplodeDirs      []string `help:"provides a directory name contained in a ROM folder that should have its contents copied to the parent directory for that system, then delete the empty folder. For example, '--explodeDir images' would copy the contents of the image directory into its parent folder. Commonly used to bring boxart images out of an 'images' directory and onto the same level as ROMs. Multiples of this flag are allowed." name:"explodeDir" type:"string"`
-	FileRewrites     []string `help:"for a given file glob, execute a find and replace on all matching files in the format <glob>:<search term>:<replace term>. Useful for fixing paths in XML files. Remember to single quote your globs to prevent shell expansion and don't glob '*' unless you want to rewrite binary ROMs. For example, '--rewrite '*.xml:../images:./images'' would replace all occurrences of the string '../images' to './images' in all XML files. Multiples of this flag are allowed." name:"rewrite" type:"string"`
-	RewritesAreRegex bool     `help:"when set, the search term in any --rewrite flag is interpreted as a Golang regular expression" optional:"" name:"rewritesAreRegex"`
-	CleanTarget      bool     `help:"delete all files in the destination platform folder before copying ROMs in" optional:"" name:"cleanTarget"`
-	SkipConfirm      bool     `help:"skip all confirmations and execute the copy process" optional:"" name:"skipConfirm"`
-	DryRun           bool     `help:"don't execute any file copies or operations; just print what would be done" optional:"" name:"dryRun"`
-	LoopbackCopy     bool     `help:"[EXPERIMENTAL/UNSAFE] when set, any files matched by --copyInclude will have the path and extension stripped, be globbified into '**/*<filename>*', and then serve as the --copyInclude for a repeated invocation. Intended to simplify copying off a device to set a --copyInclude for '**/*.sav' or similar, then also copy the ROMs correlated with those saves. Untested; use at your own risk." optional:"" name:"loopbackCopy"`
-	SkipSummary      bool     `help:"[EXPERIMENTAL/UNSAFE] do not display a summary of operations to be performed" optional:"" name:"skipSummary"`
+	Copy         CopyCmd     `cmd:"" default:"withargs" help:"copy ROMs from the source to the target according to the mappings (the default when no command is given)"`
+	Snapshot     SnapshotCmd `cmd:"" help:"record the target's full tree (paths, sizes, and hashes) to a file for later offline diffing"`
+	Diff         DiffCmd     `cmd:"" help:"list which files would be new, changed, or orphaned on the target (or a snapshot of it) compared to the source, without copying anything"`
+	VerifyTarget VerifyCmd   `cmd:"" name:"verify" help:"check a previous copy by comparing each mapping's source against the target, reporting files that are missing, extra, or differ in size or hash, without copying anything"`
+	Doctor       DoctorCmd   `cmd:"" help:"inspect the target (firmware, folder layout, filesystem, and free space) and report anything that would stop the mappings from working on the device, without copying anything"`
+	History      HistoryCmd  `cmd:"" help:"list recent copies, newest first, with their target, result, size, and config hash; give '--targetDir' to list only the copies to that target"`
+	Status       StatusCmd   `cmd:"" help:"show when each target was last synced, whether that sync succeeded, and with what config hash, e.g. to see which family member's device is out of date"`
+	Saves        SavesCmd    `cmd:"" help:"sync save files (battery saves like '.srm' and '.sav', and memory cards) both ways between the target and '--backupDir', so each side ends up with the newest copy of every save; run it before re-imaging a card and after, to put the saves back"`
+	Undo         UndoCmd     `cmd:"" help:"reverse the changes copies recorded in '--journal', newest first: copied files are removed, renames moved back, and overwritten and deleted files put back from the trash on the target. Changes that can't be undone, e.g. because the file has changed since, are listed and skipped."`
+	Profiles     ProfileCmd  `cmd:"" name:"profile" help:"manage device profiles beyond the built-in ones; 'profile fetch <name>' downloads one from the community profile repository"`
+	Examples     ExamplesCmd `cmd:"" help:"list ready-to-run command lines for common scenarios (e.g. 'examples miyoo-artwork'), with '--sourceDir', '--targetDir', and the firmware detected on the target filled in; 'examples run <name>' runs one"`
+
+	SourceDir             string        `help:"the source directory containing platform folders ('snes', 'gba', etc.) to be copied from e.g. 'C:\\ROMS' or '/home/ROMS'" name:"sourceDir" recipe:"-" type:"path"`
+	TargetDir             string        `help:"target directory (usually on device) containing platform folders ('snes', 'gba', etc.), e.g. 'J:\\' or '/media/usb-drive/'" name:"targetDir" recipe:"-" type:"path"`
+	Mappings              []string      `help:"a mapping of source platform folder to destination platform folder for the ROMs in the format 'source:destination'. For example, '--mapping snes:SFC --mapping gg:GameGear' would copy the contents of the sourceDir's 'snes' folder to the targetDir's 'SFC' folder and the contents of the sourceDir's 'gg' folder to the targetDir's 'GameGear' folder." name:"mapping" type:"string"`
+	Renames               []string      `help:"rename files or folders from a given name to a given name after copy. For example, '--rename gameslist.xml:miyoogameslist.xml' would rename all occurrences of 'gameslist.xml' in all folders to 'miyoogameslist.xml'; '--rename images:Imgs' could be used to rename image folders. Multiples of this flag are allowed." name:"rename" type:"string"`
+	CopyInclude           []string      `help:"copy only files and folders within each mapping which match the given glob (for example, '--copyInclude '*_favorite*'' would only copy files/folders from each source folder containing the string 'favorite'; '--copyInclude '*.xml' would only copy XML files found in each source folder. Remember to single quote your glob to prevent shell expansion. Multiples of this flag are allowed, and will be processed as an OR relation (files matching any --copyInclude will be included). This supports globstar (e.g. '--copyInclude **/*.png' copies PNGs from all child directories, whereas '--copyInclude *.png' only copies top-level PNGs in the platform root)." name:"copyInclude" type:"string"`
+	CopyExclude           []string      `help:"copy only files and folders within each mapping which do NOT match the given glob (for example, '--copyExclude '*.xml'' would copy all files and folders except those ending in '.xml'. Remember to single quote your glob to prevent shell expansion. Multiples of this flag are allowed, and will be processed as an AND relation (files matching any --copyExclude will be excluded). '--copyExclude' entries are processed after '--copyExclude' entries" name:"copyExclude" type:"string"`
+	GlobDialect           string        `help:"how '--copyInclude' and '--copyExclude' globs are matched: 'doublestar' (the default) matches the whole path within each platform folder, so '*.png' only matches top-level files and '**/*.png' matches at any depth; 'gitignore' reads them like .gitignore lines, so '*.png' matches at any depth, '/*.png' only at the top, 'media/' any folder named media, and matching a folder matches everything in it" name:"globDialect" default:"doublestar"`
+	OnlyFavorites         bool          `help:"copy only the games marked as favorites ('<favorite>true</favorite>') in the EmulationStation 'gamelist.xml' in each source platform folder, along with their image, video, marquee, and thumbnail, the discs of favorite '.m3u' playlists and cue sheets, and the game list itself. Platform folders without a 'gamelist.xml' copy nothing." optional:"" name:"onlyFavorites"`
+	GamelistOnly          bool          `help:"copy only the files the EmulationStation 'gamelist.xml' in each source platform folder refers to: the path, image, video, marquee, and thumbnail of every '<game>' and '<folder>' entry, the discs of '.m3u' playlists and cue sheets, and the game list itself, so orphaned ROMs and leftover junk stay behind. Platform folders without a 'gamelist.xml' copy nothing." optional:"" name:"gamelistOnly"`
+	GenerateGamelist      bool          `help:"after copying (and after '--explodeDir', '--rename', and '--rewrite'), write a minimal 'gamelist.xml' to each destination platform folder that doesn't have one, listing each game by its file name less tags (e.g. 'Super Metroid' for 'Super Metroid (Japan, USA) (En,Ja).sfc') with the box art named like it in '--artworkDir'. For sets that were never scraped. The platform is recognized by the source or destination folder name." optional:"" name:"generateGamelist"`
+	ArtworkDir            string        `help:"the folder within each platform folder, after any '--rename', that '--generateGamelist' looks for box art in, and '--artworkSource', '--scrape', and '--fetchThumbnails' copy it into, e.g. 'Imgs'" name:"artworkDir" default:"images"`
+	ArtworkSource         string        `help:"for sources scraped with Skraper, which keeps each kind of media in its own folder under 'media' in each platform folder (e.g. 'media/box2d', 'media/screenshot', 'media/video'): copy the given kind into '--artworkDir' instead, leave the other kinds out, and point the copied game lists' images at it, e.g. '--artworkSource box2d --artworkDir Imgs' for a device that shows one image per game from its 'Imgs' folder" name:"artworkSource"`
+	LaunchBoxData         string        `help:"a LaunchBox install (the folder holding its 'Data' and 'Images' folders) to look games up in by file name: '--regions' and '--languages' go by the region LaunchBox gives games whose file names don't say, '--launchboxTitles' copies games under their LaunchBox titles, and '--generateGamelist' names games by their LaunchBox titles, adds their descriptions, release dates, developers, publishers, and genres, and copies their LaunchBox box art into '--artworkDir'" name:"launchboxData" type:"existingdir"`
+	LaunchBoxTitles       bool          `help:"copy each game LaunchBox knows under its LaunchBox title, e.g. 'zelda3.sfc' as 'The Legend of Zelda - A Link to the Past.sfc'. Games sharing a title, and discs or tracks a playlist or cue sheet names, keep their names. Game lists and artwork copied from the source still refer to the old names, so this suits sets without them; see '--generateGamelist'. Needs '--launchboxData'." optional:"" name:"launchboxTitles"`
+	Scrape                bool          `help:"after copying (and after '--explodeDir', '--rename', and '--rewrite'), look the games in each destination platform folder that its 'gamelist.xml' has no entry, name, or image for up on ScreenScraper by their hashes, copy their box art into '--artworkDir', and add their names, descriptions, release dates, developers, publishers, and genres to the list, generating one if the folder has none. Needs '--scrapeCredentials'. The platform is recognized by the source or destination folder name." optional:"" name:"scrape"`
+	ScrapeCredentials     string        `help:"a YAML file of the credentials '--scrape' uses: 'devID' and 'devPassword', the developer credentials ScreenScraper issues, and optionally 'user' and 'password', a ScreenScraper account whose quota is used instead of the shared one" name:"scrapeCredentials" recipe:"-" type:"existingfile"`
+	FetchThumbnails       bool          `help:"after copying (and after '--explodeDir', '--rename', '--rewrite', and '--scrape'), fetch front box art from the libretro-thumbnails repository into '--artworkDir' for the games in each destination platform folder that have no artwork, matching them by file name (e.g. 'Super Metroid (Japan, USA) (En,Ja).png'), and point their 'gamelist.xml' entries at it. Requests are spaced out and what's fetched is cached in the user cache directory. The platform is recognized by the source or destination folder name." optional:"" name:"fetchThumbnails"`
+	ResizeImages          string        `help:"downscale the PNG and JPEG images copied into artwork and video folders (e.g. 'images', 'Imgs', 'media', 'boxart', and '--artworkDir'), and the box art '--scrape', '--fetchThumbnails', and '--launchboxData' copy in, to fit within the given width and height, keeping their proportions, e.g. '250x360' for a 640x480 handheld's box art. Full-resolution scrapes take up hundreds of MB and slow low-power frontends down. Images that already fit, and images that can't be read, are copied as they are." name:"resizeImages"`
+	MoveArtwork           []string      `help:"for firmware that shows box art from a folder of its own rather than the platform folders, e.g. muOS's 'MUOS/info/catalogue/<system>/box', move what each mapping copies into '--artworkDir' (and what '--scrape' and '--fetchThumbnails' fetch) into the given folder, relative to the target root, once it's done; given as 'source:folder', where source is the mapping's source platform folder, e.g. 'snes:MUOS/info/catalogue/Nintendo SNES - SFC/box'. Art already there of the same name is replaced. Set for you by profiles that need it." name:"moveArtwork" type:"string"`
+	MetadataLang          string        `help:"where the game lists copied to each destination platform folder give a game's description, genre, or other detail in several languages (e.g. '<desc lang=\"en\">' and '<desc lang=\"fr\">'), keep only the one in the given language, e.g. 'en'. A detail not given in that language is kept once, in its untranslated or first language." name:"metadataLang" type:"string"`
+	GamelistTags          []string      `help:"keep only the given elements in each entry of the game lists copied to each destination platform folder, comma separated, e.g. 'name,desc,image'; '<path>' is always kept. For frontends that crash on elements they don't know." name:"gamelistTags" sep:","`
+	ConvertGamelist       string        `help:"after copying (and after '--explodeDir', '--rename', and '--rewrite'), convert the 'gamelist.xml' at the top of each destination platform folder for the given frontend. 'miyoo' writes the 'miyoogamelist.xml' the Miyoo Mini's stock firmware and Onion read: each game keeps only its path, name, and image, folder entries are dropped, and images are moved into the 'Imgs' folder and pointed at there. 'es' rewrites the list in the form EmulationStation and its forks (e.g. Batocera and Knulli) read on any device: absolute paths from the device it was scraped on (e.g. '/home/pi/RetroPie/roms/snes/...') become relative ones starting with './', and entries and artwork outside the platform folder are dropped." name:"convertGamelist" type:"string"`
+	MergeGamelists        string        `help:"when a copy replaces a 'gamelist.xml' (or a variant like 'miyoogamelist.xml') already in a destination platform folder, merge the source's entries into the device's instead, so play counts and favorites recorded on the device aren't lost. Games only in the source's list are added and games only on the device are kept; for games in both, 'device' keeps the device's entry as it is, and 'source' takes the source's, keeping the device's favorite, hidden, play count, last played, and time played." name:"mergeGamelists" type:"string"`
+	PruneGamelists        bool          `help:"after copying, remove the entries for games that aren't on the target from each destination platform folder's 'gamelist.xml' (and variants like 'miyoogamelist.xml'), so a filtered copy doesn't leave the frontend showing broken entries. Everything else in the list is left as it was. On by default; use '--keepGamelistEntries' to leave game lists untouched." default:"true" negatable:"keepGamelistEntries" name:"pruneGamelists"`
+	GameList              string        `help:"copy only the games named in the given file, one name or glob per line (e.g. 'Super Metroid' or 'Zelda*'), along with the artwork, manuals, and disc tracks named after them and any '.xml' game lists. A line matches a file's name less its extension, or its title before the first tag, so 'Super Metroid' matches 'Super Metroid (Japan, USA) (En,Ja).sfc'. Lines starting with '#' are comments." name:"gameList" type:"existingfile"`
+	ExplodeDirs           []string      `help:"provides a directory name contained in a ROM folder that should have its contents copied to the parent directory for that system, then delete the empty folder. For example, '--explodeDir images' would copy the contents of the image directory into its parent folder. Commonly used to bring boxart images out of an 'images' directory and onto the same level as ROMs. May be a glob, matched without regard to case, e.g. 'im*g*' for 'images', 'imgs', and 'Imgs' across mixed sources. Multiples of this flag are allowed." name:"explodeDir" type:"string"`
+	ExplodeRecursive      bool          `help:"find the '--explodeDir' folders at any depth in each destination platform folder rather than only at its top (e.g. '--explodeDir images' finds 'PS1/Final Fantasy VII/images'), exploding each into the folder holding it, deepest first. A path given to '--explodeDir' matches where the end of a folder's path is that path." optional:"" name:"explodeRecursive"`
+	FileRewrites          []string      `help:"for a given file glob, execute a find and replace on all matching files in the format <glob>:<search term>:<replace term>. Useful for fixing paths in XML files. Remember to single quote your globs to prevent shell expansion and don't glob '*' unless you want to rewrite binary ROMs. For example, '--rewrite '*.xml:../images:./images'' would replace all occurrences of the string '../images' to './images' in all XML files. Multiples of this flag are allowed." name:"rewrite" type:"string"`
+	RewritesAreRegex      bool          `help:"when set, the search term in any --rewrite flag is interpreted as a Golang regular expression" optional:"" name:"rewritesAreRegex"`
+	RewriteBinary         bool          `help:"let --rewrite edit files that look binary (holding NUL bytes or mostly control characters), such as ROMs a mistyped glob matched; they're left unchanged otherwise" optional:"" name:"rewriteBinary"`
+	DeleteAfter           []string      `help:"a glob of files to delete from each destination platform folder once everything else has been copied, exploded, renamed, and rewritten, for junk that gets past '--copyExclude', e.g. '*.txt' or '**/Thumbs.db'. Globs are matched case-insensitively against the whole path within the platform folder, so '*.txt' matches only top-level files. Save data and '--protect' matches are kept, and folders left empty are removed. Multiples of this flag are allowed." name:"deleteAfter" type:"string"`
+	CleanTarget           bool          `help:"delete all files in the destination platform folder before copying ROMs in" optional:"" name:"cleanTarget"`
+	UseTrash              bool          `help:"move what '--cleanTarget' and '--deleteAfter' remove into the '.romcopy_trash' folder at the top of the target instead of deleting it, so cleaning the wrong folder can be undone by moving it back. It's kept for '--retainTrash', or until '--purgeTrash'." optional:"" name:"useTrash"`
+	BackupBeforeClean     string        `help:"before '--cleanTarget' empties any destination platform folder, record every file in the folders about to be cleaned, with its size and CRC32 hash, and copy their game lists, configs, cue sheets, and playlists (files up to 1 MiB), to a folder named for when the run started within the given directory, so a bad clean can be partly put back. The record is a snapshot 'diff --against' can read." name:"backupBeforeClean" type:"path" recipe:"-"`
+	Protect               []string      `help:"a glob, relative to each destination platform folder and matched regardless of case, for files no operation may delete, rename, or rewrite: '--cleanTarget', '--deleteAfter', '--rename', and '--rewrite' all leave them be, though renames and rewrites only spare those on the target before the copy, e.g. '--protect '**/*.cfg''. Save data is always protected: battery saves and memory cards (e.g. '**/*.srm'), save states ('**/*.state*'), and anything in a 'Saves' folder ('**/saves/**'), as are the trash and 'System Volume Information' folders operating systems keep ('**/.trash*/**', '**/system volume information/**'). Put it in a '--recipe' to keep the same list for every run. Multiples of this flag are allowed." name:"protect" type:"string"`
+	CleanExclude          []string      `help:"a glob, relative to each destination platform folder and matched regardless of case, for files '--cleanTarget' leaves in place, e.g. '--cleanExclude 'gamelist.xml'' to wipe the ROMs but keep the device's own metadata. Unlike '--protect', it only applies to cleaning. Multiples of this flag are allowed." name:"cleanExclude" type:"string"`
+	SkipConfirm           bool          `help:"skip all confirmations and execute the copy process" optional:"" name:"skipConfirm" recipe:"-"`
+	Yes                   []string      `help:"approve the given operations without prompting, comma separated: 'copy' (the copy as a whole), 'clean' (emptying destination folders with '--cleanTarget'), and 'space' (continuing when the copy won't fit in the free space). E.g. '--yes copy' auto-approves routine copies but still prompts before '--cleanTarget' deletes anything." name:"yes" recipe:"-" sep:","`
+	Confirm               []string      `help:"always prompt for the given operations (same names as '--yes'), even with '--skipConfirm' or '--yes'" name:"confirm" recipe:"-" sep:","`
+	DryRun                bool          `help:"don't execute any file copies or operations; just print what would be done" optional:"" name:"dryRun" recipe:"-"`
+	PlanOnly              bool          `help:"build the full plan of what would be copied, run the checks on it (space, filesystem limits, names, filters), print its summary, and exit without prompting or touching the target. Faster than '--dryRun', which walks through every operation, so suited to checking config changes in CI. Exits with an error if the copy won't fit." optional:"" name:"planOnly" recipe:"-"`
+	PlanOutput            string        `help:"write the plan, every file each mapping would copy and where to, as JSON to the given file, e.g. to diff in review; combine with '--planOnly' to stop there" name:"planOutput" recipe:"-" type:"path"`
+	LoopbackCopy          bool          `help:"[EXPERIMENTAL/UNSAFE] when set, any files matched by --copyInclude will have the path and extension stripped, be globbified into '**/*<filename>*', and then serve as the --copyInclude for a repeated invocation. Intended to simplify copying off a device to set a --copyInclude for '**/*.sav' or similar, then also copy the ROMs correlated with those saves. Untested; use at your own risk." optional:"" name:"loopbackCopy"`
+	SkipSummary           bool          `help:"[EXPERIMENTAL/UNSAFE] do not display a summary of operations to be performed" optional:"" name:"skipSummary" recipe:"-"`
+	SkipSpaceCheck        bool          `help:"skip the pre-flight check that the files to be copied will fit in the free space on the target volume" optional:"" name:"skipSpaceCheck" recipe:"-"`
+	SkipEmulatorArtifacts bool          `help:"leave out the 'saves', 'states', 'savestates', and 'screenshots' folders emulators create inside platform folders, so personal data and junk doesn't end up on the target. On by default; use '--copyEmulatorArtifacts' to copy them." default:"true" negatable:"copyEmulatorArtifacts" name:"skipEmulatorArtifacts"`
+	SkipIgnoreFiles       bool          `help:"do not honor '.romcopyignore' files (gitignore syntax) found in the source directory and platform folders" optional:"" name:"skipIgnoreFiles"`
+	Regions               []string      `help:"copy only ROMs whose file names are tagged with one of the given regions, e.g. 'USA,World' keeps 'Tetris (World).gb' and skips 'Tetris (Japan).gb'. Files without a region tag (artwork, gamelists, homebrew) are always copied." name:"regions" sep:","`
+	Languages             []string      `help:"copy only ROMs in one of the given languages, by the language tag in their file names (e.g. 'En' matches '(En,Fr,De)'), or, if the name has none, by the language of its region (e.g. '(Japan)' is Ja). Files with neither are always copied." name:"languages" sep:","`
+	PreferLanguage        []string      `help:"where a game is in a set in several language versions, e.g. 'Tintin (Europe) (En,Fr,De)' and 'Tintin (Europe) (Es,It)', copy only the versions in the first of the given languages any version is in, e.g. 'En,Fr'. Unlike '--languages', a game with no version in these languages is still copied, in every language it comes in." name:"preferLanguage" sep:","`
+	PreferRevision        string        `help:"where a game is in a set in several revisions, e.g. 'Zelda (USA)' and 'Zelda (USA) (Rev 1)', copy only one: 'latest' or 'earliest' (the original release, if it's there). Releases for different regions or languages, and betas, hacks, and the like, are kept apart." name:"preferRevision" type:"string"`
+	ExcludeTags           []string      `help:"skip ROMs whose file names mark them as any of the given release types, e.g. 'beta,proto,demo,sample,pirate'. Understands No-Intro tags like '(Beta)' and '(Unl)' and GoodTools flags like '[b1]' (bad), '[h]' (hack), '[t]' (trained), and '[T+Eng]' (translation)." name:"excludeTags" sep:","`
+	BandwidthLimit        string        `help:"limit copy throughput to the given rate, e.g. '10MB/s' or '500KB/s' (units are powers of 1024). Useful for cheap SD cards that overheat and stall, or for background syncs over a shared network link." name:"bwlimit" type:"string"`
+	StallTimeout          time.Duration `help:"abort a file copy if no data is written for this long (e.g. '30s' or '2m'), which usually means the destination media is failing. Set to 0 to wait forever." name:"stallTimeout" default:"2m"`
+	StallRetries          int           `help:"how many times to retry a file whose copy stalled before giving up" name:"stallRetries" default:"1"`
+	Timeout               time.Duration `help:"stop the run cleanly once it's been copying for this long, e.g. '2h' or '45m': the file being copied is finished, nothing more is started, and the run is recorded in the history as stopped. For scheduled overnight syncs that must be done before the device is needed. 0 for no limit." name:"timeout" default:"0"`
+	MaxErrors             int           `help:"let up to this many files in each mapping fail to copy (e.g. an unreadable file on a dying source drive) before giving up on it. Failures are logged as they happen and listed after the mapping, and the run still ends in an error, but the other files are copied. 0 stops at the first failure." name:"maxErrors" default:"0"`
+	Flush                 bool          `help:"fsync every copied file and flush the target at the end of each mapping, so removable media can be pulled as soon as a mapping reports complete; also times copies as they reach the media for the end-of-run health check, rather than as the OS buffers them" optional:"" name:"flush"`
+	Verify                bool          `help:"re-read every copied file from the target and compare its checksum against the source, reporting any file that didn't survive the trip. Combine with '--flush' so the re-read comes from the card rather than the OS cache." optional:"" name:"verify"`
+	Checksum              string        `help:"verify every copied file like '--verify', using the given checksum algorithm: 'crc32' (the default; hardware accelerated on most CPUs), 'xxhash' (fastest without CRC instructions, e.g. on low-power NAS CPUs), 'blake3' (cryptographic, and fast on CPUs with SIMD), 'md5', or 'sha1'. Also sets the algorithm '--manifest' uses." name:"checksum" aliases:"hashAlgo" type:"string"`
+	Manifest              bool          `help:"after copying, write a '.romcopyengine-checksums.json' manifest to each destination platform folder recording the name, size, and checksum of every file in it, so later verification and incremental syncs don't need to re-hash the source. Uses the '--checksum' algorithm (default 'crc32')." optional:"" name:"manifest"`
+	TargetState           bool          `help:"after copying, record what each mapping copied to the target, from where, with the hash of every file and the options used, in '.romcopyengine/state.json' at the top of the target, so 'verify --fromState' can check the target without the source. Mappings not in the run keep what earlier runs recorded." optional:"" name:"targetState"`
+	SignKey               string        `help:"sign each platform folder's checksum manifest with the given Ed25519 private key (PEM, e.g. from 'openssl genpkey -algorithm ed25519'), writing the signature beside it, so people receiving a pre-built card can check it with 'verify --signature'. Implies '--manifest'." name:"signKey" recipe:"-" type:"existingfile"`
+	Dats                  []string      `help:"a Logiqx XML DAT file (e.g. from No-Intro or Redump) to check source ROMs against before copying; ROMs whose checksums are marked as bad dumps, don't match the DAT entry of the same name, or aren't in the DAT at all are reported. Multiples of this flag are allowed (e.g. one per platform)." name:"dat" type:"existingfile"`
+	RegionPriority        []string      `help:"copy only the single best release of each game (1G1R), choosing between regional releases and revisions grouped by the parent/clone relationships in the '--dat' files, in the given order of region preference, e.g. 'USA,Europe,Japan'. Releases in none of the listed regions are only copied if there's no alternative." name:"regionPriority" sep:","`
+	BadDumps              string        `help:"what to do with ROMs flagged as bad dumps, by the GoodTools '[b]' (bad), '[o]' (overdump), or '[h]' (hack) markers in their names or by a '--dat' marking their checksum bad: 'warn' lists them before copying, 'skip' leaves them out, and 'copy' copies them without comment" name:"badDumps" default:"warn"`
+	StrictExtensions      string        `help:"check each mapping's files against the extensions its platform's emulators load, recognizing the platform by the source or destination folder name (e.g. 'gba' or 'SFC'): 'warn' lists files that don't belong, like a stray '.sfc' in the 'gba' folder or a '.txt' readme, and 'skip' leaves them out. Archives, '.m3u' playlists, game lists, and artwork folders are always allowed." name:"strictExtensions" type:"string"`
+	PlatformsFile         string        `help:"a YAML file teaching '--strictExtensions' and platform recognition about more platforms or correcting the built-in ones, laid out like the built-in table (platforms/platforms.yaml in the source): a list of platforms, each with a 'name', the 'folders' names it's kept in, the 'extensions' its emulators load, and whether it 'needsBios' or is 'multiDisc'. Platforms named like a built-in one replace it; the rest are added." name:"platforms" type:"existingfile"`
+	Dedupe                bool          `help:"copy only one file from each group of duplicates in a mapping: byte-identical files, and versions of the same game that differ only in revision or release tags (e.g. 'Zelda (USA).nes' and 'Zelda (USA) (Rev 1).nes'). The latest revision without beta, bad dump, or similar flags is kept. Without this, duplicates are only reported." optional:"" name:"dedupe"`
+	TestCapacity          bool          `help:"before copying, fill the target's free space with test files and read them back to detect fake-capacity SD cards. Existing files are untouched and the test files are removed afterwards. This can take a long time on large cards." optional:"" name:"testCapacity" recipe:"-"`
+	PerGameFolders        bool          `help:"copy each game at the top of a mapping into a folder of its own named for it (e.g. 'Tetris (World).gb' into 'Tetris (World)/'), as PortMaster and some MiSTer setups expect, along with the tracks and discs its cue sheet, GDI, or M3U playlist names and the files anywhere in the mapping named like it, such as its box art and manual (e.g. 'images/Tetris (World)-image.png'). Game lists and cue sheets are pointed at the new paths. The platform is recognized by the source or destination folder name." optional:"" name:"perGameFolders"`
+	SplitAlpha            bool          `help:"copy the games at the top of each mapping into alphabetical folders by their first letters, 'A-E', 'F-J', and so on (see '--alphaBuckets'), with the names that don't start with a letter (e.g. '1942.nes') in '#', so frontends on low-power devices don't choke on folders of thousands of files. Game lists and cue sheets are pointed at the new paths; hidden, artwork, and video folders and game lists stay where they are." optional:"" name:"splitAlpha"`
+	AlphaBuckets          string        `help:"the letter ranges '--splitAlpha' copies games into a folder for each of, comma-separated; together they must cover A to Z once, e.g. 'A-M,N-Z'" name:"alphaBuckets" default:"A-E,F-J,K-O,P-T,U-Z"`
+	RenameReserved        bool          `help:"rename files and folders with Windows device names (CON, PRN, AUX, NUL, COM1-9, LPT1-9, with or without an extension) by appending '_' to the name, e.g. 'CON.nes' becomes 'CON_.nes'. Without this, a copy to a Windows-accessible target that includes such names is rejected before anything is copied." optional:"" name:"renameReserved"`
+	SanitizeNames         bool          `help:"replace the characters FAT32 and exFAT don't allow in names (: ? * \" < > | \\ and control characters) with '--sanitizeWith' as files and folders are copied, and drop the dots and spaces names end in, which Windows drops; e.g. 'Zelda: A Link to the Past.sfc' becomes 'Zelda_ A Link to the Past.sfc'. Cue sheets and game lists naming the renamed files are pointed at their new names. Without this, a copy to a FAT32, exFAT, or NTFS target that includes such names is rejected before anything is copied." optional:"" name:"sanitizeNames"`
+	SanitizeWith          string        `help:"what '--sanitizeNames' replaces each character FAT32 and exFAT don't allow with; may be empty to drop them" name:"sanitizeWith" default:"_"`
+	NormalizeNames        string        `help:"the Unicode normalization form file and folder names are written in as they're copied: 'nfc' (the default) composes accented letters the way Linux-based handhelds and most software expect, so names from a Mac, which writes them decomposed, don't show up garbled or as duplicate entries; 'nfd', 'nfkc', and 'nfkd' are the other forms, and 'none' copies names as they are. Game lists and cue sheets naming renamed files are pointed at their new names." name:"normalizeNames" default:"nfc"`
+	MaxNameLength         int           `help:"before copying, warn about files and folders whose names, after any '--rename', are longer than the given number of bytes (in UTF-8), e.g. 255 for firmware running Linux, which can't open longer names even where FAT32 and exFAT could store them; non-Latin names take 2 to 4 bytes a character. 0 to not check." name:"maxNameLength" default:"0"`
+	MaxPathLength         int           `help:"shorten the names of files whose paths on the target, from its top (e.g. 'Roms/SFC/...'), would be longer than the given number of characters, e.g. 255 for FAT32 and exFAT, rather than failing partway through the copy. The game's title is cut short, keeping its tags and extension (e.g. 'Super Mario Bros. 3 (USA) (Rev 1).nes' becomes 'Super Mario (USA) (Rev 1).nes'), and cue sheets and game lists naming the file are pointed at the new name. 0 to not shorten." name:"maxPathLength" default:"0"`
+	DirMode               string        `help:"permissions for directories created on the target, in octal (e.g. '0755'), instead of the source's permissions less the umask. Useful when the source is a Windows mount whose permissions are meaningless." name:"dirMode" type:"string"`
+	FileMode              string        `help:"permissions for files copied to the target, in octal (e.g. '0644'), instead of the source's permissions less the umask" name:"fileMode" type:"string"`
+	MaxIndexMemory        string        `help:"cap the memory used to index source files before copying, e.g. '512MB' (units are powers of 1024). Mappings whose index won't fit are re-scanned from disk wherever they're needed instead of held in memory, and the checks that compare files against each other (duplicates, DATs, bad dumps, and FAT32 limits) are skipped for them; '--dedupe', '--regionPriority', and '--badDumps skip' need the index and are rejected. Useful for scraped libraries of millions of files on low-memory machines." name:"maxIndexMemory" type:"string"`
+	Deterministic         bool          `help:"make two runs from the same source produce byte-identical targets, e.g. to check a card build shared within a community: everything written is given the same modification time (1980-01-01, or the SOURCE_DATE_EPOCH environment variable's), the manifest's timestamps are fixed to it, and unless '--dirMode' or '--fileMode' say otherwise, directories are made 0755 and files 0644" optional:"" name:"deterministic"`
+	NoCache               bool          `help:"don't read or update the caches kept in the user cache directory: of source file checksums, which lets repeated hash comparisons and verifications skip re-hashing unchanged source files, and of the box art '--fetchThumbnails' fetches" optional:"" name:"noCache" recipe:"-" aliases:"no-cache"`
+	LogFile               string        `help:"also write every log message, including per-file detail, to the given file as JSON lines tagged with mapping and operation IDs (e.g. mapping 'm2' for the second '--mapping', operation 'rewrite1' for the first '--rewrite'), so errors late in a run can be traced back to what produced them" name:"logFile" recipe:"-" type:"path"`
+	DeviceName            string        `help:"name the target device, e.g. \"Dad's RG35XX\", in a '.romcopyengine-id' file at the top of the target holding the name and a random ID, replacing any name it was given before. Later runs to the device say so, and the run history tracks it by its ID, so 'history' and 'status' list it by name however it's mounted." name:"deviceName" recipe:"-" type:"string"`
+	ExpectDevice          string        `help:"refuse to copy unless the target is the device of the given name (or ID) set with '--deviceName', so a different card that happens to mount at the same path is never overwritten or cleaned. A target with no '.romcopyengine-id' file is refused too." name:"expectDevice" recipe:"-" type:"string"`
+	StateDir              string        `help:"directory the history of runs shown by the 'history' and 'status' commands is kept in, instead of 'ROMCopyEngine' in the user config directory" name:"stateDir" recipe:"-" type:"path"`
+	Journal               string        `help:"record every change the copy makes to the target (files copied, overwritten, renamed, rewritten, and deleted) to the given journal file on this computer, moving what's overwritten or deleted into the trash on the target rather than losing it, so 'undo --journal <file>' can reverse the copy until the trash is emptied (see '--retainTrash')" name:"journal" recipe:"-" type:"path"`
+	RetainTrash           string        `help:"how long to keep what's been moved to the '.romcopy_trash' folder at the top of the target instead of being deleted, e.g. '30d', '2w', or '12h'. At the start of each run, whatever went into the trash longer ago than this is deleted for good, so the trash doesn't fill the card. 0 keeps it forever." name:"retainTrash" default:"30d"`
+	PurgeTrash            bool          `help:"at the start of the run, delete everything in the '.romcopy_trash' folder at the top of the target for good, however recently it went in, e.g. once you've checked a '--useTrash' clean took what it should have" optional:"" name:"purgeTrash" recipe:"-"`
+	Profile               string        `help:"set the copy up for a device's firmware: map each platform folder in '--sourceDir' to the folder the firmware keeps that platform in (e.g. 'snes' to 'Roms/SFC'), and a 'bios' folder to where its emulators look for BIOS files, and apply the options the firmware needs, such as where it shows box art from and which game list it reads. Options and mappings given on the command line or by '--recipe' override or add to the profile's, and platform folders given a '--mapping' aren't mapped again. One of: 'onion' (OnionOS), 'garlicos' (GarlicOS), 'muos' (muOS), 'batocera' (Batocera), 'knulli' (Knulli), 'arkos' (ArkOS), 'rocknix' (ROCKNIX), 'emudeck' (EmuDeck), 'anbernic' (Anbernic stock firmware), or one downloaded with 'profile fetch'." name:"profile" recipe:"-"`
+	Bios                  string        `help:"a folder of BIOS files to copy to where the '--profile' firmware's emulators look for them, keeping any subfolders. Files the platform table knows (e.g. 'scph5501.bin') are checked against the MD5s of known-good copies and given the name emulators look for, and once the copy is done, platforms being copied whose games won't boot without a BIOS file the target doesn't have are listed." name:"bios" type:"existingdir" recipe:"-"`
+	Recipe                string        `help:"apply a recipe written by '--exportRecipe': its options are used as if given before the rest of the command line, so options given there override the recipe's, or for repeatable options like '--mapping', add to them. Files the recipe gives within the source are found in '--sourceDir'; any other file it needs, e.g. '--gameList', must be given on the command line." name:"recipe" type:"existingfile" recipe:"-"`
+	ExportRecipe          string        `help:"write the options of this run that shape the copy to a recipe file others can apply with '--recipe', e.g. to share a proven setup for a device. Directories, prompts, logging, signing keys, and other options tied to this machine or run are left out; files within '--sourceDir' are kept relative to it, and other files are left as placeholders for whoever applies it to fill in." name:"exportRecipe" type:"path" recipe:"-"`
+	Verbose               bool          `help:"log every file and directory as it's copied instead of showing a progress bar" optional:"" name:"verbose" recipe:"-"`
+	Transactional         bool          `help:"build each destination platform folder's complete new contents in a hidden staging folder on the target, then swap them all into place only once every mapping has succeeded, so an interrupted run never leaves a half-updated platform folder. Needs enough free space to hold the old and new contents at the same time. Art moved out of the platform folders with '--moveArtwork' and BIOS files copied with '--bios' are written straight to the target, outside staging, so they aren't covered." optional:"" name:"transactional"`
 }
 
 type Config struct {
-	SourceDir        string
-	TargetDir        string
-	Mappings         []DirMapping
-	Renames          []NameMapping
-	CopyInclude      []string
-	CopyExclude      []string
+	// which subcommand was run; one of the Command* constants
+	Command     string
+	SourceDir   string
+	TargetDir   string
+	Mappings    []DirMapping
+	Renames     []NameMapping
+	CopyInclude []string
+	CopyExclude []string
+	// how CopyInclude and CopyExclude are matched; one of copy_funcs.GlobDialects
+	GlobDialect      string
 	ExplodeDirs      []string
+	ExplodeRecursive bool
 	FileRewrites     []RewriteRule
 	RewritesAreRegex bool
-	CleanTarget      bool
-	SkipConfirm      bool
-	DryRun           bool
-	LoopbackCopy     bool
-	SkipSummary      bool
+	// rewrite files that look binary rather than leaving them unchanged
+	RewriteBinary bool
+	// globs, in slash form relative to each platform folder, for the files deleted once the copy is done
+	DeleteAfter []string
+	CleanTarget bool
+	// move what cleaning and '--deleteAfter' remove into the trash rather than deleting it
+	UseTrash bool
+	// directory a record and the metadata files of the folders being cleaned are backed up to first; empty for none
+	BackupBeforeClean string
+	// globs, in slash form relative to each platform folder, for the files cleaning never deletes; the save data
+	// globs, then '--protect'
+	Protect []string
+	// globs, in slash form relative to each platform folder, for the files cleaning leaves in place besides Protect
+	CleanExclude []string
+	SkipConfirm  bool
+	// operations approved without prompting and always prompted for, as confirmableOps names; see AutoApproves
+	Yes     []string
+	Confirm []string
+	DryRun  bool
+	// plan and check the copy, then stop
+	PlanOnly bool
+	// file to write the plan to as JSON; empty for none
+	PlanOutput      string
+	LoopbackCopy    bool
+	SkipSummary     bool
+	SkipSpaceCheck  bool
+	SkipIgnoreFiles bool
+	// leave out emulator saves, states, and screenshots folders
+	SkipEmulatorArtifacts bool
+	// name-tag filters; empty to copy every region or language
+	Regions   []string
+	Languages []string
+	// language preference for choosing between language versions of a game, most preferred first; empty to copy
+	// every version
+	PreferLanguage []string
+	// one of preferRevisionModes; empty to copy every revision
+	PreferRevision string
+	// release types to skip, as romtags.ReleaseTags names
+	ExcludeTags []string
+	// copy only games favorited in each platform's gamelist.xml
+	OnlyFavorites bool
+	// copy only files referred to by each platform's gamelist.xml
+	GamelistOnly bool
+	// write a game list for platform folders without one
+	GenerateGamelist bool
+	// the folder within each platform folder generated game lists look for box art in, and Skraper media is
+	// copied to
+	ArtworkDir string
+	// the kind of Skraper media, e.g. 'box2d', copied into ArtworkDir; empty to copy the media folder as it is
+	ArtworkSource string
+	// LaunchBox install to look games up in; empty for none
+	LaunchBoxData string
+	// copy games under their LaunchBox titles
+	LaunchBoxTitles bool
+	// look games missing from game lists up on ScreenScraper
+	Scrape bool
+	// the credentials file '--scrape' uses
+	ScrapeCredentials string
+	// fetch box art for games without any from libretro-thumbnails
+	FetchThumbnails bool
+	// the size to downscale copied artwork to fit within; zero to copy it as it is
+	ResizeImages artwork.Size
+	// the folder, relative to the target root, each mapping's ArtworkDir is moved into afterwards, by the
+	// mapping's source; empty for none
+	MoveArtwork map[string]string
+	// the language to keep game list details in where they're given in several; empty to keep every language
+	MetadataLang string
+	// the elements to keep in each game list entry, besides path; empty to keep them all
+	GamelistTags []string
+	// the format to convert copied game lists to; one of gamelist.ConvertFormats, or empty to leave them
+	ConvertGamelist string
+	// how to merge copied game lists into those already on the target; one of gamelist.MergePolicies, or empty to
+	// replace them
+	MergeGamelists string
+	// remove game list entries for games not on the target after copying
+	PruneGamelists bool
+	// file of the games to copy; empty to copy every game
+	GameList      string
+	Transactional bool
+	// bytes per second; 0 for unlimited
+	BandwidthLimit int64
+	StallTimeout   time.Duration
+	StallRetries   int
+	// how long the run may copy for before it stops; 0 for no limit
+	Timeout time.Duration
+	// files each mapping may fail to copy before it's aborted
+	MaxErrors    int
+	Flush        bool
+	TestCapacity bool
+	Verify       bool
+	Manifest     bool
+	// records what each mapping copied in the target's state file
+	TargetState bool
+	// signs each manifest when set
+	SigningKey ed25519.PrivateKey
+	// algorithm used by Verify and Manifest; one of checksumAlgorithms
+	Checksum string
+	// DAT files to check source ROMs against
+	Dats []string
+	// 1G1R region preference, most preferred first; empty to copy every release
+	RegionPriority []string
+	// one of badDumpModes
+	BadDumps string
+	// one of strictExtensionsModes; empty to copy files of any type
+	StrictExtensions string
+	// platform table merged over the built-in one; empty for just the built-in one
+	PlatformsFile string
+	// copy only the best of each group of duplicate files
+	Dedupe  bool
+	Verbose bool
+	// don't use the persistent source checksum cache
+	NoCache bool
+	// bytes the pre-flight source file index may use; 0 for unlimited
+	MaxIndexMemory int64
+	// JSON-lines structured log; empty for none
+	LogFile string
+	// where run history is kept; empty for the default in the user config directory
+	StateDir string
+	// journal file every change to the target is recorded in, or with 'undo', reversed from; empty for none
+	Journal string
+	// how long trash on the target is kept before it's pruned; 0 to keep it forever
+	RetainTrash time.Duration
+	// empty the trash on the target at the start of the run, however recently it went in
+	PurgeTrash bool
+	// device profile applied to the command line; empty for none
+	Profile string
+	// folder of BIOS files copied to BiosFolder; empty for none
+	BiosSource string
+	// the profile's BIOS folder, relative to the target root; only set with BiosSource
+	BiosFolder string
+	// recipe applied to the command line; empty for none
+	Recipe string
+	// file to write ExportedRecipe to; empty for none
+	ExportRecipe string
+	// this run's options as a recipe; only set with ExportRecipe or TargetState
+	ExportedRecipe *Recipe
+	// name to give the target in its identity file; empty to keep its current name
+	DeviceName string
+	// name or ID the target's identity file must have for a copy to go ahead; empty to copy to any target
+	ExpectDevice string
+	// copy each game at the top of a mapping, and the files that go with it, into a folder of its own
+	PerGameFolders bool
+	// copy the games at the top of a mapping into a folder for the bucket of their first letter; nil to not split
+	AlphaBuckets []copy_funcs.AlphaBucket
+	// rename Windows device names rather than rejecting them
+	RenameReserved bool
+	// replace characters FAT32 and exFAT don't allow in names with SanitizeWith as they're copied
+	SanitizeNames bool
+	SanitizeWith  string
+	// the Unicode normalization form names are written in, one of disk_info.NameForms; empty to copy them as they
+	// are
+	NormalizeNames string
+	// the longest name, in bytes, to copy without a warning; 0 to not check
+	MaxNameLength int
+	// the longest path, in UTF-16 code units from the top of the target, to copy without shortening the name; 0 to
+	// not shorten
+	MaxPathLength int
+	// permissions for created directories and copied files; 0 to use the source's, less the umask
+	DirMode  os.FileMode
+	FileMode os.FileMode
+	// give everything written BuildTime as its modification time and fixed permissions, so builds are reproducible
+	Deterministic bool
+	BuildTime     time.Time
+
+	// snapshot command
+	SnapshotOutput     string
+	SnapshotSkipHashes bool
+
+	// diff command
+	DiffAgainst string
+	DiffHashes  bool
+
+	// verify command
+	VerifySkipHashes bool
+	// public key to check signed manifests with instead of the source; empty to compare against the source
+	VerifySignature string
+	VerifyKey       ed25519.PublicKey
+	// check against the target's state file instead of the source
+	VerifyFromState bool
+
+	// history command; 0 to list every run
+	HistoryLimit int
+
+	// examples command; empty to list them all
+	ExampleName string
+	ExampleRun  bool
+
+	// saves command
+	SavesBackupDir string
+
+	// profile fetch command
+	FetchProfile string
+	// the SHA-256 the fetched profile must have; empty to trust the repository's index
+	FetchSHA256 string
+	FetchRepo   string
 }
 
 type DirMapping struct {
@@ -61,17 +472,102 @@ type RewriteRule struct {
 	ReplacePattern string
 }
 
+// AutoApproves reports whether op (one of the Confirm* constants) goes ahead without prompting. '--skipConfirm'
+// approves everything but running out of space, which a run that can't prompt aborts on instead.
+func (c *Config) AutoApproves(op string) bool {
+	if contains(c.Confirm, op) {
+		return false
+	}
+	if contains(c.Yes, op) {
+		return true
+	}
+	return c.SkipConfirm && op != ConfirmSpace
+}
+
+// Hash identifies the options that shape what a copy puts on a target, so targets synced the same way share a
+// hash. Which target it was, how the run was carried out (prompts, logging, dry runs, and the like), and which
+// command it was don't count.
+// CleanKeeps returns the globs for the files cleaning leaves in place: Protect, then CleanExclude
+func (c *Config) CleanKeeps() []string {
+	keeps := make([]string, 0, len(c.Protect)+len(c.CleanExclude))
+	return append(append(keeps, c.Protect...), c.CleanExclude...)
+}
+
+func (c *Config) Hash() string {
+	shaping := *c
+	shaping.Command, shaping.TargetDir, shaping.DeviceName, shaping.ExpectDevice = "", "", "", ""
+	shaping.SkipConfirm, shaping.Yes, shaping.Confirm = false, nil, nil
+	shaping.DryRun, shaping.SkipSummary, shaping.SkipSpaceCheck, shaping.TestCapacity = false, false, false, false
+	shaping.PlanOnly, shaping.PlanOutput = false, ""
+	shaping.Verbose, shaping.LogFile, shaping.NoCache, shaping.StateDir = false, "", false, ""
+	shaping.ScrapeCredentials = ""
+	shaping.RetainTrash, shaping.PurgeTrash, shaping.UseTrash, shaping.Timeout = 0, false, false, 0
+	shaping.BackupBeforeClean, shaping.Journal = "", ""
+	shaping.Profile, shaping.Recipe, shaping.ExportRecipe, shaping.ExportedRecipe = "", "", "", nil
+	shaping.BandwidthLimit, shaping.StallTimeout, shaping.StallRetries, shaping.MaxIndexMemory = 0, 0, 0, 0
+	shaping.SnapshotOutput, shaping.SnapshotSkipHashes = "", false
+	shaping.DiffAgainst, shaping.DiffHashes = "", false
+	shaping.VerifySkipHashes, shaping.VerifySignature, shaping.VerifyKey, shaping.VerifyFromState = false, "", nil, false
+	shaping.HistoryLimit, shaping.ExampleName, shaping.ExampleRun = 0, "", false
+	shaping.SavesBackupDir = ""
+	shaping.FetchProfile, shaping.FetchSHA256, shaping.FetchRepo = "", "", ""
+
+	// every field is plain data, so this can't fail
+	data, _ := json.Marshal(shaping)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func (c *Config) Validate() error {
-	if c.SourceDir == "" {
+	// examples only need the directories they're run with, which they check themselves, and the run history and
+	// profile repository need nothing at all
+	if c.Command == CommandExamples || c.Command == CommandHistory || c.Command == CommandStatus || c.Command == CommandProfile {
+		return nil
+	}
+	// the journal records where everything went
+	if c.Command == CommandUndo {
+		if c.Journal == "" {
+			return fmt.Errorf("'undo' needs the '--journal' a copy wrote")
+		}
+		return nil
+	}
+
+	// a snapshot and a save sync only look at the target
+	needsSource := c.Command != CommandSnapshot && c.Command != CommandSaves
+	// a doctor run can check the target on its own, or against mappings
+	if c.Command == CommandDoctor {
+		needsSource = len(c.Mappings) > 0
+	}
+	// a signed manifest stands in for the source
+	if c.Command == CommandVerify && c.VerifySignature != "" {
+		needsSource = false
+		if len(c.Mappings) == 0 {
+			return fmt.Errorf("at least one mapping is required")
+		}
+	}
+	// as does the target's state file, which also knows which mappings were copied
+	if c.Command == CommandVerify && c.VerifyFromState {
+		if c.VerifySignature != "" {
+			return fmt.Errorf("'--fromState' and '--signature' can't be used together")
+		}
+		needsSource = false
+	}
+	// a diff against a snapshot doesn't need the device connected
+	needsTarget := !(c.Command == CommandDiff && c.DiffAgainst != "")
+
+	if needsSource && c.SourceDir == "" {
 		return fmt.Errorf("source directory is required")
 	}
 
-	if c.TargetDir == "" {
+	if needsTarget && c.TargetDir == "" {
 		return fmt.Errorf("target directory is required")
 	}
 
 	// Validate mappings
-	if len(c.Mappings) == 0 {
+	if needsSource && len(c.Mappings) == 0 && c.Profile != "" {
+		return fmt.Errorf("the '%s' profile found no platform folders it has a folder for in %s; give a '--mapping'", c.Profile, c.SourceDir)
+	}
+	if needsSource && len(c.Mappings) == 0 {
 		return fmt.Errorf("at least one mapping is required")
 	}
 
@@ -80,32 +576,387 @@ func (c *Config) Validate() error {
 
 func ParseAndValidate() (*Config, error) {
 	var cli CLI
-	ctx := kong.Parse(&cli,
+	parser := kong.Must(&cli,
 		kong.Name("ROMCopyEngine"),
 		kong.Description("A tool for copying and transforming game ROM directories. See more at https://github.com/jkingsman/ROMCopyEngine."),
 		kong.UsageOnError(),
 	)
+	args, err := applyRecipe(parser.Model, os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+	// profiles map source folders by the platforms they hold, so the platform table is completed first
+	if err := loadPlatforms(args); err != nil {
+		return nil, err
+	}
+	if err := loadUserProfiles(); err != nil {
+		return nil, err
+	}
+	if args, err = applyProfile(parser.Model, args); err != nil {
+		return nil, err
+	}
+	ctx, err := parser.Parse(args)
+	parser.FatalIfErrorf(err)
 
 	if err := ctx.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid command line arguments: %w", err)
 	}
 
 	config := &Config{
-		SourceDir:        filepath.Clean(cli.SourceDir),
-		TargetDir:        filepath.Clean(cli.TargetDir),
-		CopyInclude:      cli.CopyInclude,
-		CopyExclude:      cli.CopyExclude,
-		ExplodeDirs:      cli.ExplodeDirs,
-		RewritesAreRegex: cli.RewritesAreRegex,
-		CleanTarget:      cli.CleanTarget,
-		SkipConfirm:      cli.SkipConfirm,
-		DryRun:           cli.DryRun,
-		LoopbackCopy:     cli.LoopbackCopy,
-		SkipSummary:      cli.SkipSummary,
+		Command:               strings.Fields(ctx.Command())[0],
+		SourceDir:             cleanPath(cli.SourceDir),
+		TargetDir:             cleanPath(cli.TargetDir),
+		CopyInclude:           cli.CopyInclude,
+		CopyExclude:           cli.CopyExclude,
+		ExplodeDirs:           cli.ExplodeDirs,
+		ExplodeRecursive:      cli.ExplodeRecursive,
+		RewritesAreRegex:      cli.RewritesAreRegex,
+		RewriteBinary:         cli.RewriteBinary,
+		CleanTarget:           cli.CleanTarget,
+		UseTrash:              cli.UseTrash,
+		BackupBeforeClean:     cleanPath(cli.BackupBeforeClean),
+		SkipConfirm:           cli.SkipConfirm,
+		DryRun:                cli.DryRun,
+		PlanOnly:              cli.PlanOnly,
+		PlanOutput:            cleanPath(cli.PlanOutput),
+		LoopbackCopy:          cli.LoopbackCopy,
+		SkipSummary:           cli.SkipSummary,
+		SkipSpaceCheck:        cli.SkipSpaceCheck,
+		SkipIgnoreFiles:       cli.SkipIgnoreFiles,
+		SkipEmulatorArtifacts: cli.SkipEmulatorArtifacts,
+		Regions:               trimAll(cli.Regions),
+		Languages:             trimAll(cli.Languages),
+		PreferLanguage:        trimAll(cli.PreferLanguage),
+		OnlyFavorites:         cli.OnlyFavorites,
+		GamelistOnly:          cli.GamelistOnly,
+		PruneGamelists:        cli.PruneGamelists,
+		GenerateGamelist:      cli.GenerateGamelist,
+		ArtworkDir:            filepath.Clean(strings.TrimSpace(cli.ArtworkDir)),
+		ArtworkSource:         strings.TrimSpace(cli.ArtworkSource),
+		LaunchBoxData:         cli.LaunchBoxData,
+		LaunchBoxTitles:       cli.LaunchBoxTitles,
+		Scrape:                cli.Scrape,
+		ScrapeCredentials:     cli.ScrapeCredentials,
+		FetchThumbnails:       cli.FetchThumbnails,
+		MetadataLang:          strings.TrimSpace(cli.MetadataLang),
+		GamelistTags:          trimAll(cli.GamelistTags),
+		GameList:              cli.GameList,
+		Transactional:         cli.Transactional,
+		StallTimeout:          cli.StallTimeout,
+		StallRetries:          cli.StallRetries,
+		Timeout:               cli.Timeout,
+		MaxErrors:             cli.MaxErrors,
+		Flush:                 cli.Flush,
+		TestCapacity:          cli.TestCapacity,
+		Verify:                cli.Verify,
+		Manifest:              cli.Manifest,
+		TargetState:           cli.TargetState,
+		Dats:                  cli.Dats,
+		PlatformsFile:         cli.PlatformsFile,
+		RegionPriority:        trimAll(cli.RegionPriority),
+		Dedupe:                cli.Dedupe,
+		Verbose:               cli.Verbose,
+		LogFile:               cleanPath(cli.LogFile),
+		StateDir:              cleanPath(cli.StateDir),
+		Journal:               cleanPath(cli.Journal),
+		Profile:               strings.ToLower(strings.TrimSpace(cli.Profile)),
+		BiosSource:            cleanPath(cli.Bios),
+		Recipe:                cleanPath(cli.Recipe),
+		ExportRecipe:          cleanPath(cli.ExportRecipe),
+		DeviceName:            strings.TrimSpace(cli.DeviceName),
+		ExpectDevice:          strings.TrimSpace(cli.ExpectDevice),
+		NoCache:               cli.NoCache,
+		PerGameFolders:        cli.PerGameFolders,
+		RenameReserved:        cli.RenameReserved,
+		SanitizeNames:         cli.SanitizeNames,
+		SanitizeWith:          cli.SanitizeWith,
+		MaxNameLength:         cli.MaxNameLength,
+		MaxPathLength:         cli.MaxPathLength,
+
+		SnapshotOutput:     cli.Snapshot.Output,
+		SnapshotSkipHashes: cli.Snapshot.SkipHashes,
+		DiffAgainst:        cli.Diff.Against,
+		DiffHashes:         cli.Diff.Hashes,
+		VerifySkipHashes:   cli.VerifyTarget.SkipHashes,
+		VerifySignature:    cleanPath(cli.VerifyTarget.Signature),
+		VerifyFromState:    cli.VerifyTarget.FromState,
+		HistoryLimit:       cli.History.Limit,
+		SavesBackupDir:     cleanPath(cli.Saves.BackupDir),
+		FetchProfile:       strings.TrimSpace(cli.Profiles.Fetch.Name),
+		FetchSHA256:        strings.ToLower(strings.TrimSpace(cli.Profiles.Fetch.SHA256)),
+		FetchRepo:          strings.TrimSpace(cli.Profiles.Fetch.Repo),
+	}
+
+	if config.Command == CommandExamples {
+		args := cli.Examples.Args
+		if len(args) > 0 && args[0] == "run" {
+			config.ExampleRun = true
+			args = args[1:]
+			if len(args) == 0 {
+				return nil, fmt.Errorf("'examples run' needs the name of an example to run; one of %s", strings.Join(examples.Names(), ", "))
+			}
+		}
+		if len(args) > 1 {
+			return nil, fmt.Errorf("too many arguments to 'examples': %s", strings.Join(args, " "))
+		}
+		if len(args) == 1 {
+			if _, ok := examples.Find(args[0]); !ok {
+				return nil, fmt.Errorf("unknown example '%s': must be one of %s", args[0], strings.Join(examples.Names(), ", "))
+			}
+			config.ExampleName = args[0]
+		}
+	}
+
+	if config.BiosSource != "" {
+		profile, ok := profiles.Find(config.Profile)
+		if !ok || profile.BiosDir == "" {
+			return nil, fmt.Errorf("'--bios' needs a '--profile' that knows where the firmware keeps BIOS files")
+		}
+		config.BiosFolder = profile.BiosDir
+	}
+
+	if config.Command == CommandProfile && config.FetchRepo == "" {
+		return nil, fmt.Errorf("'profile fetch' needs '--repo', the URL of the profile repository's raw files")
+	}
+	if config.FetchSHA256 != "" {
+		if sum, err := hex.DecodeString(config.FetchSHA256); err != nil || len(sum) != sha256.Size {
+			return nil, fmt.Errorf("invalid '--sha256' '%s': must be 64 hexadecimal digits", cli.Profiles.Fetch.SHA256)
+		}
+	}
+
+	if cli.StallTimeout < 0 || cli.StallRetries < 0 {
+		return nil, fmt.Errorf("stall timeout and stall retries cannot be negative")
+	}
+	if cli.Timeout < 0 {
+		return nil, fmt.Errorf("'--timeout' cannot be negative")
+	}
+	if cli.History.Limit < 0 {
+		return nil, fmt.Errorf("'--limit' cannot be negative")
+	}
+	if cli.MaxErrors < 0 {
+		return nil, fmt.Errorf("'--maxErrors' cannot be negative")
+	}
+	if cli.MaxNameLength < 0 {
+		return nil, fmt.Errorf("'--maxNameLength' cannot be negative")
+	}
+	if cli.MaxPathLength < 0 {
+		return nil, fmt.Errorf("'--maxPathLength' cannot be negative")
+	}
+
+	if cli.BandwidthLimit != "" {
+		limit, err := ParseByteSize(strings.TrimSuffix(strings.TrimSpace(cli.BandwidthLimit), "/s"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bandwidth limit '%s': %w", cli.BandwidthLimit, err)
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("invalid bandwidth limit '%s': must be greater than zero", cli.BandwidthLimit)
+		}
+		config.BandwidthLimit = limit
+	}
+
+	if cli.MaxIndexMemory != "" {
+		limit, err := ParseByteSize(cli.MaxIndexMemory)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index memory limit '%s': %w", cli.MaxIndexMemory, err)
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("invalid index memory limit '%s': must be greater than zero", cli.MaxIndexMemory)
+		}
+		config.MaxIndexMemory = limit
+	}
+
+	config.PurgeTrash = cli.PurgeTrash
+	if config.RetainTrash, err = parseRetention(cli.RetainTrash); err != nil {
+		return nil, fmt.Errorf("invalid trash retention '%s': %w", cli.RetainTrash, err)
+	}
+	if config.Yes, err = parseConfirmableOps("--yes", cli.Yes); err != nil {
+		return nil, err
+	}
+	if config.Confirm, err = parseConfirmableOps("--confirm", cli.Confirm); err != nil {
+		return nil, err
+	}
+
+	if config.DirMode, err = parseFileMode(cli.DirMode); err != nil {
+		return nil, fmt.Errorf("invalid directory mode '%s': %w", cli.DirMode, err)
+	}
+	if config.FileMode, err = parseFileMode(cli.FileMode); err != nil {
+		return nil, fmt.Errorf("invalid file mode '%s': %w", cli.FileMode, err)
+	}
+
+	if cli.Deterministic {
+		config.Deterministic = true
+		if config.BuildTime, err = file_operations.BuildTime(); err != nil {
+			return nil, err
+		}
+		// the source's permissions and the umask vary from machine to machine
+		if config.DirMode == 0 {
+			config.DirMode = 0755
+		}
+		if config.FileMode == 0 {
+			config.FileMode = 0644
+		}
+	}
+
+	if cli.Checksum != "" {
+		algorithm := strings.ToLower(strings.TrimSpace(cli.Checksum))
+		if !contains(checksumAlgorithms, algorithm) {
+			return nil, fmt.Errorf("invalid checksum algorithm '%s': must be one of %s", cli.Checksum, strings.Join(checksumAlgorithms, ", "))
+		}
+		config.Checksum = algorithm
+		config.Verify = true
+	} else if config.Verify || config.Manifest || cli.SignKey != "" {
+		config.Checksum = checksumAlgorithms[0]
+	}
+
+	if cli.SignKey != "" {
+		if config.SigningKey, err = device_state.LoadSigningKey(cli.SignKey); err != nil {
+			return nil, err
+		}
+		config.Manifest = true
+	}
+	if config.VerifySignature != "" {
+		if config.VerifyKey, err = device_state.LoadVerifyKey(config.VerifySignature); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tag := range cli.ExcludeTags {
+		release, ok := romtags.NormalizeReleaseTag(tag)
+		if !ok {
+			return nil, fmt.Errorf("invalid tag '%s' in '--excludeTags': must be one of %s", tag, strings.Join(romtags.ReleaseTags, ", "))
+		}
+		config.ExcludeTags = append(config.ExcludeTags, release)
+	}
+
+	config.GlobDialect = strings.ToLower(strings.TrimSpace(cli.GlobDialect))
+	if !contains(copy_funcs.GlobDialects, config.GlobDialect) {
+		return nil, fmt.Errorf("invalid glob dialect '%s': must be one of %s", cli.GlobDialect, strings.Join(copy_funcs.GlobDialects, ", "))
+	}
+
+	for _, explodeDir := range cli.ExplodeDirs {
+		if _, err := path.Match(filepath.ToSlash(explodeDir), ""); err != nil {
+			return nil, fmt.Errorf("invalid glob '%s' in '--explodeDir'", explodeDir)
+		}
+	}
+
+	for _, pattern := range cli.DeleteAfter {
+		pattern = filepath.ToSlash(strings.TrimPrefix(strings.TrimSpace(pattern), "./"))
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid glob '%s' in '--deleteAfter'", pattern)
+		}
+		config.DeleteAfter = append(config.DeleteAfter, pattern)
+	}
+
+	config.Protect = defaultProtect()
+	for _, pattern := range cli.Protect {
+		pattern = filepath.ToSlash(strings.TrimPrefix(strings.TrimSpace(pattern), "./"))
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid glob '%s' in '--protect'", pattern)
+		}
+		config.Protect = append(config.Protect, pattern)
+	}
+	for _, pattern := range cli.CleanExclude {
+		pattern = filepath.ToSlash(strings.TrimPrefix(strings.TrimSpace(pattern), "./"))
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid glob '%s' in '--cleanExclude'", pattern)
+		}
+		config.CleanExclude = append(config.CleanExclude, pattern)
+	}
+
+	if strings.Contains(cli.SanitizeWith, "/") || strings.IndexFunc(cli.SanitizeWith, disk_info.IsFATIllegalRune) >= 0 {
+		return nil, fmt.Errorf("invalid '--sanitizeWith' '%s': it can't hold '/' or any of the characters it replaces (%s)", cli.SanitizeWith, disk_info.FATIllegalChars)
+	}
+
+	if cli.SplitAlpha {
+		buckets, err := copy_funcs.ParseAlphaBuckets(cli.AlphaBuckets)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '--alphaBuckets' '%s': %w", cli.AlphaBuckets, err)
+		}
+		config.AlphaBuckets = buckets
+	}
+
+	config.NormalizeNames = strings.ToLower(strings.TrimSpace(cli.NormalizeNames))
+	if config.NormalizeNames == "none" {
+		config.NormalizeNames = ""
+	} else if _, ok := disk_info.NameForms[config.NormalizeNames]; !ok {
+		return nil, fmt.Errorf("invalid '--normalizeNames' form '%s': must be one of nfc, nfd, nfkc, nfkd, or none", cli.NormalizeNames)
+	}
+
+	config.BadDumps = strings.ToLower(strings.TrimSpace(cli.BadDumps))
+	if !contains(badDumpModes, config.BadDumps) {
+		return nil, fmt.Errorf("invalid '--badDumps' mode '%s': must be one of %s", cli.BadDumps, strings.Join(badDumpModes, ", "))
+	}
+
+	if config.LaunchBoxTitles && config.LaunchBoxData == "" {
+		return nil, fmt.Errorf("'--launchboxTitles' needs '--launchboxData' to look the titles up in")
+	}
+	if config.BackupBeforeClean != "" && !config.CleanTarget {
+		return nil, fmt.Errorf("'--backupBeforeClean' needs '--cleanTarget'; nothing is cleaned without it")
+	}
+	if cli.RewriteBinary && len(cli.FileRewrites) == 0 {
+		return nil, fmt.Errorf("'--rewriteBinary' needs a '--rewrite' to apply to binary files")
+	}
+
+	if config.Scrape && config.ScrapeCredentials == "" {
+		return nil, fmt.Errorf("'--scrape' needs '--scrapeCredentials' to look games up on ScreenScraper with")
+	}
+
+	if config.ArtworkSource != "" && (strings.ContainsAny(config.ArtworkSource, `/\`) || config.ArtworkSource == "." || config.ArtworkSource == "..") {
+		return nil, fmt.Errorf("invalid '--artworkSource' '%s': give the name of a folder in Skraper's 'media' folder, e.g. 'box2d'", cli.ArtworkSource)
+	}
+
+	if cli.ResizeImages != "" {
+		size, err := artwork.ParseSize(cli.ResizeImages)
+		if err != nil {
+			return nil, fmt.Errorf("invalid '--resizeImages' size '%s': %w", cli.ResizeImages, err)
+		}
+		config.ResizeImages = size
+	}
+
+	if (config.GenerateGamelist || config.ArtworkSource != "" || config.Scrape || config.FetchThumbnails || config.ResizeImages != (artwork.Size{}) || len(cli.MoveArtwork) > 0) && (filepath.IsAbs(config.ArtworkDir) || config.ArtworkDir == ".." || strings.HasPrefix(config.ArtworkDir, ".."+string(filepath.Separator))) {
+		return nil, fmt.Errorf("invalid '--artworkDir' '%s': must be a folder within each platform folder", cli.ArtworkDir)
+	}
+
+	for _, tag := range config.GamelistTags {
+		if strings.ContainsAny(tag, "<>/ \t") {
+			return nil, fmt.Errorf("invalid '--gamelistTags' element '%s': give element names alone, e.g. 'desc'", tag)
+		}
+	}
+
+	if cli.ConvertGamelist != "" {
+		config.ConvertGamelist = strings.ToLower(strings.TrimSpace(cli.ConvertGamelist))
+		if !contains(gamelist.ConvertFormats, config.ConvertGamelist) {
+			return nil, fmt.Errorf("invalid '--convertGamelist' format '%s': must be one of %s", cli.ConvertGamelist, strings.Join(gamelist.ConvertFormats, ", "))
+		}
+	}
+
+	if cli.MergeGamelists != "" {
+		config.MergeGamelists = strings.ToLower(strings.TrimSpace(cli.MergeGamelists))
+		if !contains(gamelist.MergePolicies, config.MergeGamelists) {
+			return nil, fmt.Errorf("invalid '--mergeGamelists' policy '%s': must be one of %s", cli.MergeGamelists, strings.Join(gamelist.MergePolicies, ", "))
+		}
+	}
+
+	if cli.PreferRevision != "" {
+		config.PreferRevision = strings.ToLower(strings.TrimSpace(cli.PreferRevision))
+		if !contains(preferRevisionModes, config.PreferRevision) {
+			return nil, fmt.Errorf("invalid '--preferRevision' mode '%s': must be one of %s", cli.PreferRevision, strings.Join(preferRevisionModes, ", "))
+		}
+	}
+
+	if cli.StrictExtensions != "" {
+		config.StrictExtensions = strings.ToLower(strings.TrimSpace(cli.StrictExtensions))
+		if !contains(strictExtensionsModes, config.StrictExtensions) {
+			return nil, fmt.Errorf("invalid '--strictExtensions' mode '%s': must be one of %s", cli.StrictExtensions, strings.Join(strictExtensionsModes, ", "))
+		}
+	}
+
+	if len(config.RegionPriority) > 0 && len(config.Dats) == 0 {
+		return nil, fmt.Errorf("'--regionPriority' needs a DAT with parent/clone relationships; add one with '--dat'")
 	}
 
 	// Validate source directory exists
-	if !isDirExists(config.SourceDir) {
+	if config.SourceDir != "" && !isDirExists(config.SourceDir) {
 		return nil, fmt.Errorf("source directory does not exist: %s", config.SourceDir)
 	}
 
@@ -117,17 +968,61 @@ func ParseAndValidate() (*Config, error) {
 			return nil, fmt.Errorf("invalid mapping format '%s': must be in format 'source:destination'", mapping)
 		}
 
+		// checking against signed manifests or the state file doesn't use the source
 		sourcePath := filepath.Join(config.SourceDir, parts[0])
-		if !isDirExists(sourcePath) {
+		if config.VerifySignature == "" && !config.VerifyFromState && !isDirExists(sourcePath) {
 			return nil, fmt.Errorf("source mapping directory does not exist: %s", sourcePath)
 		}
 
+		destination, err := normalizeDestination(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid mapping '%s': %w", mapping, err)
+		}
+
 		config.Mappings = append(config.Mappings, DirMapping{
 			Source:      parts[0],
-			Destination: parts[1],
+			Destination: destination,
 		})
 	}
 
+	if config.CleanTarget {
+		if err := checkCleanScopes(config.Mappings); err != nil {
+			return nil, err
+		}
+	}
+
+	// a staging folder sits beside its destination, which for the target itself is off the card, and swapping it
+	// in would rename the card's mount point
+	if config.Transactional {
+		for _, mapping := range config.Mappings {
+			if mapping.Destination == "" {
+				return nil, fmt.Errorf("'--transactional' can't be used with '%s:', which copies to the top of the target; give the mapping a destination folder", mapping.Source)
+			}
+		}
+	}
+
+	if len(cli.MoveArtwork) > 0 {
+		config.MoveArtwork = make(map[string]string, len(cli.MoveArtwork))
+	}
+	for _, move := range cli.MoveArtwork {
+		source, folder, ok := strings.Cut(move, ":")
+		if !ok || strings.TrimSpace(folder) == "" {
+			return nil, fmt.Errorf("invalid '--moveArtwork' format '%s': must be in format 'source:folder'", move)
+		}
+		mapped := false
+		for _, mapping := range config.Mappings {
+			mapped = mapped || mapping.Source == source
+		}
+		if !mapped {
+			return nil, fmt.Errorf("invalid '--moveArtwork' '%s': '%s' isn't the source of a '--mapping'", move, source)
+		}
+		destination, err := normalizeDestination(folder)
+		if err != nil || destination == "" {
+			return nil, fmt.Errorf("invalid '--moveArtwork' '%s': the folder must be within the target directory", move)
+		}
+		config.MoveArtwork[source] = destination
+	}
+
 	// Parse renames
 	config.Renames = make([]NameMapping, 0, len(cli.Renames))
 	for _, rename := range cli.Renames {
@@ -150,11 +1045,15 @@ func ParseAndValidate() (*Config, error) {
 			return nil, fmt.Errorf("invalid rewrite format '%s': must be in format 'glob:search:replace'", rewrite)
 		}
 
-		// If using regex, validate the pattern
+		// If using regex, validate the pattern and the groups the replacement refers to
 		if cli.RewritesAreRegex {
-			if _, err := regexp.Compile(parts[1]); err != nil {
+			search, err := regexp.Compile(parts[1])
+			if err != nil {
 				return nil, fmt.Errorf("invalid regex pattern '%s': %w", parts[1], err)
 			}
+			if err := file_operations.CheckReplacement(search, parts[2]); err != nil {
+				return nil, fmt.Errorf("invalid rewrite '%s': %w", rewrite, err)
+			}
 		}
 
 		config.FileRewrites = append(config.FileRewrites, RewriteRule{
@@ -168,10 +1067,24 @@ func ParseAndValidate() (*Config, error) {
 		return nil, err
 	}
 
+	if config.ExportRecipe != "" || config.TargetState {
+		if config.ExportedRecipe, err = exportRecipe(ctx, config.SourceDir); err != nil {
+			return nil, err
+		}
+	}
+
 	return config, nil
 }
 
-func PrintCLIOpts(config *Config) {
+// MappingScale is how much a mapping will copy, as found by scanning its source before anything is copied
+type MappingScale struct {
+	Files int
+	Bytes int64
+}
+
+// PrintCLIOpts summarizes the configuration for confirmation. scales holds the scale of each of config.Mappings,
+// in the same order; pass nil if the sources haven't been scanned.
+func PrintCLIOpts(config *Config, scales []MappingScale) {
 	if config.SkipSummary {
 		return
 	}
@@ -181,8 +1094,19 @@ func PrintCLIOpts(config *Config) {
 	fmt.Println()
 
 	fmt.Printf("Copy sources and destinations:\n")
-	for _, m := range config.Mappings {
-		fmt.Printf("  %s -> %s\n", filepath.Join(config.SourceDir, m.Source), filepath.Join(config.TargetDir, m.Destination))
+	var totalFiles int
+	var totalBytes int64
+	for i, m := range config.Mappings {
+		if len(scales) != len(config.Mappings) {
+			fmt.Printf("  %s -> %s\n", filepath.Join(config.SourceDir, m.Source), filepath.Join(config.TargetDir, m.Destination))
+			continue
+		}
+		fmt.Printf("  %s -> %s (%d file(s), %s)\n", filepath.Join(config.SourceDir, m.Source), filepath.Join(config.TargetDir, m.Destination), scales[i].Files, logging.FormatBytes(uint64(scales[i].Bytes)))
+		totalFiles += scales[i].Files
+		totalBytes += scales[i].Bytes
+	}
+	if len(scales) == len(config.Mappings) && len(scales) > 1 {
+		fmt.Printf("  Total: %d file(s), %s\n", totalFiles, logging.FormatBytes(uint64(totalBytes)))
 	}
 
 	if len(config.Renames) > 0 {
@@ -195,7 +1119,15 @@ func PrintCLIOpts(config *Config) {
 	if len(config.ExplodeDirs) > 0 {
 		fmt.Printf("Exploded directories:\n")
 		for _, e := range config.ExplodeDirs {
-			fmt.Printf("  • All directories named %s will have their contents copied to the parent platform folder\n", e)
+			named := "named " + e
+			if file_operations.IsExplodeGlob(e) {
+				named = "matching " + e
+			}
+			if config.ExplodeRecursive {
+				fmt.Printf("  • All directories %s, at any depth, will have their contents copied to the folder holding them\n", named)
+			} else {
+				fmt.Printf("  • All directories %s will have their contents copied to the parent platform folder\n", named)
+			}
 		}
 	}
 
@@ -210,10 +1142,29 @@ func PrintCLIOpts(config *Config) {
 		for _, r := range config.FileRewrites {
 			fmt.Printf("  • All files matching glob '%s' will have %s replaced with %s\n", r.FileGlob, r.SearchPattern, r.ReplacePattern)
 		}
+		if config.RewriteBinary {
+			fmt.Println("  • Files that look binary will be rewritten too")
+		}
+	}
+
+	if len(config.DeleteAfter) > 0 {
+		fmt.Printf("Deletions after copying (save data and '--protect' matches are kept):\n")
+		verb := "deleted"
+		if config.UseTrash {
+			verb = "moved to the trash"
+		}
+		for _, pattern := range config.DeleteAfter {
+			fmt.Printf("  • All files matching glob '%s' will be %s\n", pattern, verb)
+		}
 	}
 
 	if len(config.CopyInclude) > 0 || len(config.CopyExclude) > 0 {
 		fmt.Println("Copies:")
+		if config.GlobDialect == copy_funcs.GlobGitignore {
+			fmt.Println("• Globs are read like .gitignore lines: patterns without a '/' match at any depth, and matching a folder matches everything in it")
+		} else {
+			fmt.Println("• Globs are matched against the whole path within each platform folder: '*.png' matches only top-level files; use '**/*.png' for any depth")
+		}
 	}
 	if len(config.CopyInclude) > 0 {
 		fmt.Println("• Copy will include files/folders matching any of:")
@@ -229,27 +1180,421 @@ func PrintCLIOpts(config *Config) {
 		}
 	}
 
+	if len(config.Regions) > 0 {
+		fmt.Printf("Only ROMs tagged with these regions will be copied: %s\n", strings.Join(config.Regions, ", "))
+	}
+
+	if len(config.Languages) > 0 {
+		fmt.Printf("Only ROMs in these languages will be copied: %s\n", strings.Join(config.Languages, ", "))
+	}
+
+	if config.PreferRevision != "" {
+		fmt.Printf("Only the %s revision of each game will be copied\n", config.PreferRevision)
+	}
+
+	if config.OnlyFavorites {
+		fmt.Println("Only games marked as favorites in each platform folder's gamelist.xml, and their artwork, will be copied")
+	} else if config.GamelistOnly {
+		fmt.Println("Only files referred to by each platform folder's gamelist.xml will be copied")
+	}
+
+	if config.GameList != "" {
+		fmt.Printf("Only games listed in %s, and files named after them, will be copied\n", config.GameList)
+	}
+
+	if len(config.PreferLanguage) > 0 {
+		fmt.Printf("Only the best language version of each game will be copied, preferring languages in order: %s\n", strings.Join(config.PreferLanguage, ", "))
+	}
+
+	if len(config.ExcludeTags) > 0 {
+		fmt.Printf("ROMs tagged as any of these release types will be skipped: %s\n", strings.Join(config.ExcludeTags, ", "))
+	}
+
 	if config.CleanTarget {
-		fmt.Println("Target directory will be cleaned before copying")
+		if config.UseTrash {
+			fmt.Println("Target directory will be cleaned into the trash before copying, except for save data")
+		} else {
+			fmt.Println("Target directory will be cleaned before copying, except for save data")
+		}
+		if len(config.CleanExclude) > 0 {
+			fmt.Printf("Files matching these globs will be left in place by cleaning: %s\n", strings.Join(config.CleanExclude, ", "))
+		}
+		if config.BackupBeforeClean != "" {
+			fmt.Printf("What's being cleaned will be recorded, and its metadata files backed up, to %s first\n", config.BackupBeforeClean)
+		}
+	}
+
+	if protect := config.Protect[len(defaultProtect()):]; len(protect) > 0 {
+		fmt.Printf("Files matching these globs will be protected like save data, never cleaned, deleted, renamed, or rewritten: %s\n", strings.Join(protect, ", "))
 	}
 
-	if config.DryRun {
+	if config.Journal != "" {
+		fmt.Printf("Every change to the target will be recorded to %s, so 'undo' can reverse it\n", config.Journal)
+	}
+
+	if config.PlanOnly {
+		fmt.Println("Plan only; the copy will be planned and checked, and nothing copied or modified")
+	} else if config.DryRun {
 		fmt.Println("Dry run mode enabled; no files will be copied or modified")
 	}
 
+	if config.PlanOutput != "" {
+		fmt.Printf("The plan will be written to %s\n", config.PlanOutput)
+	}
+
+	if config.BandwidthLimit > 0 {
+		fmt.Printf("Copy throughput limited to %s/s\n", formatBytes(config.BandwidthLimit))
+	}
+
+	if config.Timeout > 0 {
+		fmt.Printf("The run will stop once it's been copying for %s\n", config.Timeout)
+	}
+
+	if config.MaxIndexMemory > 0 {
+		fmt.Printf("Source file index limited to %s of memory\n", formatBytes(config.MaxIndexMemory))
+	}
+
+	if config.MaxErrors > 0 {
+		fmt.Printf("Up to %d file(s) per mapping may fail to copy before the mapping is abandoned\n", config.MaxErrors)
+	}
+
+	if config.Flush {
+		fmt.Println("Flush enabled; files will be synced to disk as they're written and the target flushed after each mapping")
+	}
+
+	if config.Verify {
+		fmt.Printf("Verify enabled; every copied file will be re-read from the target and checked against the source (%s)\n", config.Checksum)
+	}
+
+	if config.Manifest {
+		fmt.Printf("Manifest enabled; a checksum manifest (%s) will be written to each destination platform folder\n", config.Checksum)
+	}
+	if config.TargetState {
+		fmt.Printf("What each mapping copies will be recorded in %s on the target\n", filepath.ToSlash(filepath.Join(device_state.TargetStateDir, device_state.TargetStateFileName)))
+	}
+
+	if config.SigningKey != nil {
+		fmt.Println("Manifests will be signed; recipients can check them with 'verify --signature'")
+	}
+
+	if len(config.Dats) > 0 {
+		fmt.Println("Source ROMs will be checked against DAT files:")
+		for _, dat := range config.Dats {
+			fmt.Printf("  • %s\n", dat)
+		}
+	}
+
+	if len(config.RegionPriority) > 0 {
+		fmt.Printf("1G1R enabled; only the best release of each game will be copied, preferring regions in order: %s\n", strings.Join(config.RegionPriority, ", "))
+	}
+
+	switch config.BadDumps {
+	case BadDumpsSkip:
+		fmt.Println("ROMs flagged as bad dumps (by '[b]', '[o]', '[h]' name markers or a DAT) will be skipped")
+	case BadDumpsCopy:
+		fmt.Println("ROMs flagged as bad dumps will be copied without warning")
+	}
+
+	switch config.StrictExtensions {
+	case StrictExtensionsWarn:
+		fmt.Println("Files whose extension doesn't belong to their mapping's platform will be listed")
+	case StrictExtensionsSkip:
+		fmt.Println("Files whose extension doesn't belong to their mapping's platform will be skipped")
+	}
+
+	if config.PlatformsFile != "" {
+		fmt.Printf("Platform table: built-in, extended by %s\n", config.PlatformsFile)
+	}
+
+	if config.Dedupe {
+		fmt.Println("Dedupe enabled; only the best of each group of identical files or versions of the same game will be copied")
+	}
+
+	if config.TestCapacity {
+		fmt.Println("Capacity test enabled; the target's free space will be filled and verified before copying")
+	}
+
+	if config.Transactional {
+		fmt.Println("Transactional mode enabled; platform folders will be staged on the target and swapped into place once all mappings succeed")
+	}
+
+	if config.PerGameFolders {
+		fmt.Println("Each game will be copied into a folder of its own, along with its tracks, artwork, and manuals")
+	}
+
+	if len(config.AlphaBuckets) > 0 {
+		names := make([]string, 0, len(config.AlphaBuckets)+1)
+		for _, bucket := range config.AlphaBuckets {
+			names = append(names, bucket.Name())
+		}
+		names = append(names, copy_funcs.OtherBucket)
+		fmt.Printf("Games will be copied into alphabetical folders: %s\n", strings.Join(names, ", "))
+	}
+
+	if config.RenameReserved {
+		fmt.Println("Files and folders with Windows device names (CON, NUL, etc.) will be renamed with a trailing '_'")
+	}
+
+	if config.SanitizeNames {
+		fmt.Printf("Characters FAT32 and exFAT don't allow in names will be replaced with '%s'\n", config.SanitizeWith)
+	}
+
+	if config.NormalizeNames != "" {
+		fmt.Printf("Names will be written in Unicode %s\n", strings.ToUpper(config.NormalizeNames))
+	}
+
+	if config.MaxNameLength > 0 {
+		fmt.Printf("Names longer than %d bytes will be warned about before copying\n", config.MaxNameLength)
+	}
+
+	if config.MaxPathLength > 0 {
+		fmt.Printf("Files with paths longer than %d characters will have their names shortened\n", config.MaxPathLength)
+	}
+
+	if config.DirMode != 0 || config.FileMode != 0 {
+		fmt.Printf("Permissions on the target will be set to %s for directories and %s for files\n", describeMode(config.DirMode), describeMode(config.FileMode))
+	}
+
+	if config.Deterministic {
+		fmt.Printf("Deterministic mode enabled; everything written will be timestamped %s\n", config.BuildTime.Format(time.RFC3339))
+	}
+
+	if config.NoCache {
+		fmt.Println("Caches disabled; source files will be re-hashed, and box art re-fetched, as needed")
+	}
+
+	if config.LogFile != "" {
+		fmt.Printf("Structured log will be written to %s\n", config.LogFile)
+	}
+
+	if config.ExpectDevice != "" {
+		fmt.Printf("Copying only if the target is '%s'\n", config.ExpectDevice)
+	}
+
+	if config.StateDir != "" {
+		fmt.Printf("Run history will be kept in %s\n", config.StateDir)
+	}
+
+	if config.PurgeTrash {
+		fmt.Println("Trash on the target will be emptied before copying")
+	} else if config.RetainTrash == 0 {
+		fmt.Println("Trash on the target will be kept forever")
+	} else if config.RetainTrash != DefaultRetainTrash {
+		fmt.Printf("Trash on the target will be kept for %s\n", DescribeRetention(config.RetainTrash))
+	}
+
+	if profile, ok := profiles.Find(config.Profile); ok {
+		fmt.Printf("Options and platform folders of the %s profile applied\n", profile.Title)
+	}
+
+	if config.BiosSource != "" {
+		fmt.Printf("BIOS files in %s will be checked and copied to '%s'\n", config.BiosSource, config.BiosFolder)
+	}
+
+	if config.Recipe != "" {
+		fmt.Printf("Options from recipe %s applied\n", config.Recipe)
+	}
+
+	if config.ExportRecipe != "" {
+		fmt.Printf("This run's options will be written to recipe %s\n", config.ExportRecipe)
+	}
+
+	if config.Verbose {
+		fmt.Println("Verbose logging enabled; every file will be logged as it's copied")
+	}
+
 	if config.SkipConfirm {
 		fmt.Println("Skip-confirm enabled; no warnings given before proceeding")
 	}
 
+	if len(config.Yes) > 0 {
+		fmt.Printf("Approved without prompting: %s\n", strings.Join(config.Yes, ", "))
+	}
+
+	if len(config.Confirm) > 0 {
+		fmt.Printf("Always prompted for: %s\n", strings.Join(config.Confirm, ", "))
+	}
+
 	if config.LoopbackCopy {
 		fmt.Println("Loopback mode enabled; copy will be run a second time, globbing to match filename of previously matched files")
 	}
 
+	if config.SkipSpaceCheck {
+		fmt.Println("Free space check disabled; copy will not be checked against available space on the target")
+	}
+
+	if config.SkipEmulatorArtifacts {
+		fmt.Println("Emulator 'saves', 'states', 'savestates', and 'screenshots' folders will be skipped (use '--copyEmulatorArtifacts' to copy them)")
+	}
+
+	if config.LaunchBoxData != "" {
+		fmt.Printf("Games will be looked up in the LaunchBox library in %s\n", config.LaunchBoxData)
+	}
+
+	if config.LaunchBoxTitles {
+		fmt.Println("Games will be copied under their LaunchBox titles")
+	}
+
+	if config.Scrape {
+		fmt.Printf("Games missing from game lists will be looked up on ScreenScraper, with box art copied into '%s'\n", config.ArtworkDir)
+	}
+
+	if config.FetchThumbnails {
+		fmt.Printf("Games without artwork will have box art fetched from libretro-thumbnails into '%s'\n", config.ArtworkDir)
+	}
+
+	if config.ResizeImages != (artwork.Size{}) {
+		fmt.Printf("Artwork will be downscaled to fit within %s as it's copied\n", config.ResizeImages)
+	}
+
+	if len(config.MoveArtwork) > 0 {
+		fmt.Printf("Artwork in '%s' will be moved out of %d platform folder(s) into the folders the firmware shows it from\n", config.ArtworkDir, len(config.MoveArtwork))
+	}
+
+	if config.ArtworkSource != "" {
+		fmt.Printf("Skraper '%s' media will be copied into '%s', and other Skraper media left out\n", config.ArtworkSource, config.ArtworkDir)
+	}
+
+	if config.GenerateGamelist {
+		fmt.Printf("Platform folders without a gamelist.xml will have one generated from their file names, with box art from '%s'\n", config.ArtworkDir)
+	}
+
+	if config.MetadataLang != "" {
+		fmt.Printf("Game list details given in several languages will be kept only in '%s'\n", config.MetadataLang)
+	}
+
+	if len(config.GamelistTags) > 0 {
+		fmt.Printf("Game list entries will keep only their path and: %s\n", strings.Join(config.GamelistTags, ", "))
+	}
+
+	if config.ConvertGamelist == gamelist.FormatMiyoo {
+		fmt.Printf("Game lists will be converted to the Miyoo's %s, with images moved into %s\n", gamelist.MiyooFileName, gamelist.MiyooImageDir)
+	} else if config.ConvertGamelist == gamelist.FormatES {
+		fmt.Println("Game lists will have their paths rewritten relative to their platform folders, as EmulationStation reads them")
+	}
+
+	if config.MergeGamelists == gamelist.MergeKeepDevice {
+		fmt.Println("Game lists already on the target will have new games from the source's merged into them, keeping the device's entries for games in both")
+	} else if config.MergeGamelists == gamelist.MergeKeepSource {
+		fmt.Println("Game lists already on the target will have the source's merged into them, taking the source's entries for games in both but keeping the device's play data")
+	}
+
+	if config.PruneGamelists {
+		fmt.Println("Game list entries for games not on the target will be removed after copying (use '--keepGamelistEntries' to leave them)")
+	}
+
+	if config.SkipIgnoreFiles {
+		fmt.Println("'.romcopyignore' files in the source directory will not be honored")
+	}
+
 	fmt.Println()
 
 	fmt.Printf("==== End Configuration ====\n")
 }
 
+// parses a human byte count like '10MB', '1.5G', '512k', or '4096' into bytes. Units are powers of 1024.
+func ParseByteSize(value string) (int64, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if value == "" {
+		return 0, fmt.Errorf("empty size")
+	}
+
+	multipliers := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"TIB", 1 << 40}, {"TB", 1 << 40}, {"T", 1 << 40},
+		{"GIB", 1 << 30}, {"GB", 1 << 30}, {"G", 1 << 30},
+		{"MIB", 1 << 20}, {"MB", 1 << 20}, {"M", 1 << 20},
+		{"KIB", 1 << 10}, {"KB", 1 << 10}, {"K", 1 << 10},
+		{"B", 1},
+	}
+
+	multiplier := 1.0
+	for _, m := range multipliers {
+		if strings.HasSuffix(value, m.suffix) {
+			multiplier = m.multiplier
+			value = strings.TrimSpace(strings.TrimSuffix(value, m.suffix))
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("'%s' is not a valid size", value)
+	}
+
+	return int64(number * multiplier), nil
+}
+
+// parses an octal permission string like '0755' or '644'; empty means no override and returns 0
+func parseFileMode(value string) (os.FileMode, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(strings.TrimPrefix(value, "0o"), 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return 0, fmt.Errorf("must be an octal permission between 0001 and 0777")
+	}
+	return os.FileMode(mode), nil
+}
+
+// how long trash is kept without '--retainTrash'; matches its default
+const DefaultRetainTrash = 30 * 24 * time.Hour
+
+// parses a retention period like '30d', '2w', or any duration time.ParseDuration accepts, like '12h'; '0' means
+// forever and returns 0
+func parseRetention(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		unit   time.Duration
+	}{
+		{"d", 24 * time.Hour}, {"w", 7 * 24 * time.Hour},
+	}
+	parse := time.ParseDuration
+	for _, u := range units {
+		if strings.HasSuffix(value, u.suffix) {
+			suffix, unit := u.suffix, u.unit
+			parse = func(value string) (time.Duration, error) {
+				count, err := strconv.ParseFloat(strings.TrimSuffix(value, suffix), 64)
+				return time.Duration(count * float64(unit)), err
+			}
+			break
+		}
+	}
+	retention, err := parse(value)
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("must be a positive period like '30d', '2w', or '12h', or 0 to keep trash forever")
+	}
+	return retention, nil
+}
+
+// DescribeRetention describes a retention period, in days where it's a whole number of them
+func DescribeRetention(retention time.Duration) string {
+	if retention%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d day(s)", retention/(24*time.Hour))
+	}
+	return retention.String()
+}
+
+func describeMode(mode os.FileMode) string {
+	if mode == 0 {
+		return "the source's permissions (less the umask)"
+	}
+	return fmt.Sprintf("%04o", uint32(mode))
+}
+
+func formatBytes(bytes int64) string {
+	return logging.FormatBytes(uint64(bytes))
+}
+
 func GetConfirmation(prompt string) bool {
 	reader := bufio.NewReader(os.Stdin)
 
@@ -274,6 +1619,93 @@ func GetConfirmation(prompt string) bool {
 	}
 }
 
+// mapping destinations may be nested (e.g. 'Roms/Consoles/SNES') and written with either separator; they're
+// normalized to a clean path relative to the target directory so joins, cleaning, and comparisons between
+// mappings all agree
+func normalizeDestination(destination string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(strings.ReplaceAll(destination, "\\", "/")))
+	cleaned = strings.TrimLeft(cleaned, string(filepath.Separator))
+	if cleaned == "." || cleaned == "" {
+		return "", nil
+	}
+
+	if cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("destination '%s' must be within the target directory", destination)
+	}
+
+	return cleaned, nil
+}
+
+// cleaning a destination that contains (or is) another mapping's destination would delete that mapping's files
+func checkCleanScopes(mappings []DirMapping) error {
+	for i, outer := range mappings {
+		for j, inner := range mappings {
+			if i == j {
+				continue
+			}
+
+			if outer.Destination == "" || inner.Destination == outer.Destination ||
+				strings.HasPrefix(inner.Destination, outer.Destination+string(filepath.Separator)) {
+				return fmt.Errorf("'--cleanTarget' can't be used when mapping destinations overlap: cleaning '%s' for '%s:%s' would delete files copied by '%s:%s'",
+					outer.Destination, outer.Source, outer.Destination, inner.Source, inner.Destination)
+			}
+		}
+	}
+	return nil
+}
+
+// trims whitespace from each value, dropping any left empty
+func trimAll(values []string) []string {
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
+
+// normalizes and validates the operations given to flag
+func parseConfirmableOps(flag string, ops []string) ([]string, error) {
+	parsed := make([]string, 0, len(ops))
+	for _, op := range ops {
+		op = strings.ToLower(strings.TrimSpace(op))
+		if !contains(confirmableOps, op) {
+			return nil, fmt.Errorf("invalid operation '%s' in '%s': must be one of %s", op, flag, strings.Join(confirmableOps, ", "))
+		}
+		parsed = append(parsed, op)
+	}
+	return parsed, nil
+}
+
+// the globs for the save data cleaning always keeps: battery saves and memory cards, save states, and anything in
+// a 'Saves' folder
+func defaultProtect() []string {
+	protect := make([]string, 0, len(saves.Extensions)+4)
+	for _, ext := range saves.Extensions {
+		protect = append(protect, "**/*"+ext)
+	}
+	// and what operating systems keep in folders of their own on the volumes they mount
+	return append(protect, "**/*.state*", "**/saves/**", "**/.trash*/**", "**/system volume information/**")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// like filepath.Clean, but leaves unset paths empty rather than turning them into "."
+func cleanPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Clean(path)
+}
+
 func isDirExists(path string) bool {
 	info, err := os.Stat(path)
 	if err != nil {
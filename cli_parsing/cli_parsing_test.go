@@ -1,9 +1,19 @@
 package cli_parsing
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/artwork"
+	"github.com/jkingsman/ROMCopyEngine/profiles"
 )
 
 func TestParseAndValidate(t *testing.T) {
@@ -24,6 +34,23 @@ func TestParseAndValidate(t *testing.T) {
 		}
 	}
 
+	if err := os.WriteFile(filepath.Join(sourceNes, "snap.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to create test snapshot: %v", err)
+	}
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	publicKeyPath := filepath.Join(tmpSource, "key.pub")
+	if err := os.WriteFile(publicKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatalf("Failed to create test key: %v", err)
+	}
+
 	tests := []struct {
 		name      string
 		args      []string
@@ -148,6 +175,44 @@ func TestParseAndValidate(t *testing.T) {
 			},
 			wantError: true,
 		},
+		{
+			name: "regex rewrite with capture groups",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--rewrite", "*.xml:<image>./images/(.*)</image>:<image>/mnt/SDCARD/Imgs/$1</image>",
+				"--rewrite", "*.xml:(\\w+)\\.png:${1}_small.png",
+				"--rewritesAreRegex",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if len(c.FileRewrites) != 2 || c.FileRewrites[0].ReplacePattern != "<image>/mnt/SDCARD/Imgs/$1</image>" {
+					t.Errorf("Expected the replacements to be kept as given, got %+v", c.FileRewrites)
+				}
+			},
+		},
+		{
+			name: "regex rewrite referring to a missing group",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--rewrite", "*.xml:(\\w+)\\.png:$1_small.png",
+				"--rewritesAreRegex",
+			},
+			wantError: true,
+		},
+		{
+			name: "literal rewrite with a dollar sign",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--rewrite", "*.txt:price:$1_small",
+			},
+			wantError: false,
+		},
 		{
 			name: "invalid rewrite format",
 			args: []string{
@@ -195,43 +260,1710 @@ func TestParseAndValidate(t *testing.T) {
 			},
 		},
 		{
-			name: "clean target and dry run",
+			name: "delete after copying",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--deleteAfter", "./*.txt",
+				"--deleteAfter", "**/Thumbs.db",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if len(c.DeleteAfter) != 2 || c.DeleteAfter[0] != "*.txt" {
+					t.Errorf("Expected globs '*.txt' and '**/Thumbs.db', got %v", c.DeleteAfter)
+				}
+			},
+		},
+		{
+			name: "clean into the trash",
 			args: []string{
 				"--sourceDir", tmpSource,
 				"--targetDir", tmpTarget,
 				"--mapping", "nes:NES",
 				"--cleanTarget",
-				"--dryRun",
+				"--useTrash",
+				"--purgeTrash",
 			},
 			wantError: false,
 			validate: func(t *testing.T, c *Config) {
-				if !c.CleanTarget {
-					t.Error("CleanTarget should be true")
+				if !c.CleanTarget || !c.UseTrash || !c.PurgeTrash {
+					t.Errorf("Expected cleanTarget, useTrash, and purgeTrash to be set, got %v, %v, %v", c.CleanTarget, c.UseTrash, c.PurgeTrash)
 				}
-				if !c.DryRun {
-					t.Error("DryRun should be true")
+			},
+		},
+		{
+			name: "clean excluding globs",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--cleanTarget",
+				"--protect", "**/*.cfg",
+				"--cleanExclude", "./gamelist.xml",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if len(c.CleanExclude) != 1 || c.CleanExclude[0] != "gamelist.xml" {
+					t.Errorf("Expected glob 'gamelist.xml', got %v", c.CleanExclude)
+				}
+				keeps := c.CleanKeeps()
+				if len(keeps) != len(c.Protect)+1 || keeps[len(keeps)-1] != "gamelist.xml" || keeps[len(keeps)-2] != "**/*.cfg" {
+					t.Errorf("Expected the protect globs then 'gamelist.xml', got %v", keeps)
 				}
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set command line arguments
-			os.Args = append([]string{"cmd"}, tt.args...)
-
-			// Parse configuration
-			config, err := ParseAndValidate()
-
-			// Check error status
-			if (err != nil) != tt.wantError {
-				t.Errorf("ParseAndValidate() error = %v, wantError %v", err, tt.wantError)
-				return
-			}
-
-			// If we don't expect an error and have a validation function, run it
-			if !tt.wantError && tt.validate != nil {
-				tt.validate(t, config)
+		{
+			name: "back up before cleaning",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--cleanTarget",
+				"--backupBeforeClean", filepath.Join(tmpTarget, "..", "backups"),
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.BackupBeforeClean != filepath.Join(filepath.Dir(tmpTarget), "backups") {
+					t.Errorf("Expected the backup directory to be cleaned up, got %s", c.BackupBeforeClean)
+				}
+			},
+		},
+		{
+			name: "back up before cleaning without cleaning",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--backupBeforeClean", filepath.Join(tmpTarget, "..", "backups"),
+			},
+			wantError: true,
+		},
+		{
+			name: "clean excluding an invalid glob",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--cleanExclude", "[gamelist.xml",
+			},
+			wantError: true,
+		},
+		{
+			name: "delete after copying with an invalid glob",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--deleteAfter", "[*.txt",
+			},
+			wantError: true,
+		},
+		{
+			name: "explode directories by glob",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--explodeDir", "im*g*",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if len(c.ExplodeDirs) != 1 || c.ExplodeDirs[0] != "im*g*" {
+					t.Errorf("Expected the explode dir glob 'im*g*', got %v", c.ExplodeDirs)
+				}
+			},
+		},
+		{
+			name: "explode directories by an invalid glob",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--explodeDir", "im[g",
+			},
+			wantError: true,
+		},
+		{
+			name: "explode directories at any depth",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--explodeDir", "images",
+				"--explodeRecursive",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.ExplodeRecursive || len(c.ExplodeDirs) != 1 {
+					t.Errorf("Expected 1 explode dir exploded at any depth, got %v with %v", c.ExplodeDirs, c.ExplodeRecursive)
+				}
+			},
+		},
+		{
+			name: "bandwidth limit",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--bwlimit", "10MB/s",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.BandwidthLimit != 10*1024*1024 {
+					t.Errorf("Expected bandwidth limit of 10MB/s, got %d", c.BandwidthLimit)
+				}
+			},
+		},
+		{
+			name: "invalid bandwidth limit",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--bwlimit", "fast",
+			},
+			wantError: true,
+		},
+		{
+			name: "index memory limit",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--maxIndexMemory", "256MB",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.MaxIndexMemory != 256*1024*1024 {
+					t.Errorf("Expected index memory limit of 256MB, got %d", c.MaxIndexMemory)
+				}
+			},
+		},
+		{
+			name: "zero index memory limit",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--maxIndexMemory", "0",
+			},
+			wantError: true,
+		},
+		{
+			name: "dir and file modes",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--dirMode", "0755",
+				"--fileMode", "644",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.DirMode != 0755 || c.FileMode != 0644 {
+					t.Errorf("Expected modes 0755 and 0644, got %o and %o", c.DirMode, c.FileMode)
+				}
+			},
+		},
+		{
+			name: "invalid file mode",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--fileMode", "0999",
+			},
+			wantError: true,
+		},
+		{
+			name: "emulator artifacts skipped by default",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.SkipEmulatorArtifacts {
+					t.Error("SkipEmulatorArtifacts should default to true")
+				}
+			},
+		},
+		{
+			name: "copy emulator artifacts",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--copyEmulatorArtifacts",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.SkipEmulatorArtifacts {
+					t.Error("SkipEmulatorArtifacts should be false")
+				}
+			},
+		},
+		{
+			name: "region priority",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--dat", filepath.Join(tmpSource, "nes", "snap.json"),
+				"--regionPriority", "USA, Europe,Japan",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if strings.Join(c.RegionPriority, "|") != "USA|Europe|Japan" {
+					t.Errorf("Expected regions USA, Europe, Japan, got %q", c.RegionPriority)
+				}
+			},
+		},
+		{
+			name: "region and language filters",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--regions", "USA, World",
+				"--languages", "En",
+				"--preferLanguage", "en, fr",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if strings.Join(c.Regions, "|") != "USA|World" {
+					t.Errorf("Expected regions USA, World, got %q", c.Regions)
+				}
+				if strings.Join(c.Languages, "|") != "En" {
+					t.Errorf("Expected languages En, got %q", c.Languages)
+				}
+				if strings.Join(c.PreferLanguage, "|") != "en|fr" {
+					t.Errorf("Expected preferred languages en, fr, got %q", c.PreferLanguage)
+				}
+			},
+		},
+		{
+			name: "exclude tags normalized",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--excludeTags", "Beta, prototype,unl",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if strings.Join(c.ExcludeTags, "|") != "beta|proto|unlicensed" {
+					t.Errorf("Expected tags beta, proto, unlicensed, got %q", c.ExcludeTags)
+				}
+			},
+		},
+		{
+			name: "unknown exclude tag",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--excludeTags", "beta,final",
+			},
+			wantError: true,
+		},
+		{
+			name: "bad dumps default to warn",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.BadDumps != BadDumpsWarn {
+					t.Errorf("Expected bad dumps mode %q, got %q", BadDumpsWarn, c.BadDumps)
+				}
+			},
+		},
+		{
+			name: "bad dumps skip",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--badDumps", "SKIP",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.BadDumps != BadDumpsSkip {
+					t.Errorf("Expected bad dumps mode %q, got %q", BadDumpsSkip, c.BadDumps)
+				}
+			},
+		},
+		{
+			name: "dedupe",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--dedupe",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.Dedupe {
+					t.Error("Dedupe should be true")
+				}
+			},
+		},
+		{
+			name: "invalid bad dumps mode",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--badDumps", "delete",
+			},
+			wantError: true,
+		},
+		{
+			name: "prefer revision",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--preferRevision", " Earliest",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.PreferRevision != PreferRevisionEarliest {
+					t.Errorf("Expected revision preference %q, got %q", PreferRevisionEarliest, c.PreferRevision)
+				}
+			},
+		},
+		{
+			name: "invalid prefer revision mode",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--preferRevision", "newest",
+			},
+			wantError: true,
+		},
+		{
+			name: "strict extensions",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--strictExtensions", "Skip",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.StrictExtensions != StrictExtensionsSkip {
+					t.Errorf("Expected strict extensions mode %q, got %q", StrictExtensionsSkip, c.StrictExtensions)
+				}
+			},
+		},
+		{
+			name: "invalid strict extensions mode",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--strictExtensions", "delete",
+			},
+			wantError: true,
+		},
+		{
+			name: "glob dialect",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--globDialect", "gitignore",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.GlobDialect != "gitignore" {
+					t.Errorf("Expected gitignore glob dialect, got %q", c.GlobDialect)
+				}
+			},
+		},
+		{
+			name: "invalid glob dialect",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--globDialect", "regex",
+			},
+			wantError: true,
+		},
+		{
+			name: "region priority without dat",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--regionPriority", "USA",
+			},
+			wantError: true,
+		},
+		{
+			name: "checksum implies verify",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--checksum", "SHA1",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.Verify || c.Checksum != "sha1" {
+					t.Errorf("Expected sha1 verification, got verify=%v checksum=%q", c.Verify, c.Checksum)
+				}
+			},
+		},
+		{
+			name: "hashAlgo alias",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--hashAlgo", "xxhash",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.Verify || c.Checksum != "xxhash" {
+					t.Errorf("Expected xxhash verification, got verify=%v checksum=%q", c.Verify, c.Checksum)
+				}
+			},
+		},
+		{
+			name: "verify defaults to crc32",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--verify",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Checksum != "crc32" {
+					t.Errorf("Expected crc32 checksum, got %q", c.Checksum)
+				}
+			},
+		},
+		{
+			name: "invalid checksum",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--checksum", "md4",
+			},
+			wantError: true,
+		},
+		{
+			name: "nested destination",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:/Roms/Consoles\\NES/",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if want := filepath.Join("Roms", "Consoles", "NES"); c.Mappings[0].Destination != want {
+					t.Errorf("Expected destination %q, got %q", want, c.Mappings[0].Destination)
+				}
+			},
+		},
+		{
+			name: "destination outside target",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:Roms/../../NES",
+			},
+			wantError: true,
+		},
+		{
+			name: "clean target with nested overlapping destinations",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:Roms",
+				"--mapping", "snes:Roms/SFC",
+				"--cleanTarget",
+			},
+			wantError: true,
+		},
+		{
+			name: "clean target with sibling nested destinations",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:Roms/NES",
+				"--mapping", "snes:Roms/NES2",
+				"--cleanTarget",
+			},
+			wantError: false,
+		},
+		{
+			name: "snapshot without source",
+			args: []string{
+				"snapshot",
+				"--targetDir", tmpTarget,
+				"--output", filepath.Join(tmpTarget, "snap.json"),
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandSnapshot {
+					t.Errorf("Expected command %q, got %q", CommandSnapshot, c.Command)
+				}
+				if c.SourceDir != "" {
+					t.Errorf("Expected empty source dir, got %q", c.SourceDir)
+				}
+			},
+		},
+		{
+			name: "snapshot missing target",
+			args: []string{
+				"snapshot",
+				"--output", filepath.Join(tmpTarget, "snap.json"),
+			},
+			wantError: true,
+		},
+		{
+			name: "diff against snapshot without target",
+			args: []string{
+				"diff",
+				"--sourceDir", tmpSource,
+				"--mapping", "nes:NES",
+				"--against", filepath.Join(tmpSource, "nes", "snap.json"),
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandDiff || c.DiffAgainst == "" {
+					t.Errorf("Expected diff against a snapshot, got %q against %q", c.Command, c.DiffAgainst)
+				}
+			},
+		},
+		{
+			name: "diff missing mapping",
+			args: []string{
+				"diff",
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+			},
+			wantError: true,
+		},
+		{
+			name: "doctor without mappings",
+			args: []string{
+				"doctor",
+				"--targetDir", tmpTarget,
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandDoctor {
+					t.Errorf("Expected command %q, got %q", CommandDoctor, c.Command)
+				}
+			},
+		},
+		{
+			name: "doctor missing target",
+			args: []string{
+				"doctor",
+				"--sourceDir", tmpSource,
+				"--mapping", "nes:NES",
+			},
+			wantError: true,
+		},
+		{
+			name: "granular confirmation",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--yes", "Copy, space",
+				"--confirm", "clean",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !reflect.DeepEqual(c.Yes, []string{ConfirmCopy, ConfirmSpace}) {
+					t.Errorf("Expected Yes [copy space], got %v", c.Yes)
+				}
+				if !reflect.DeepEqual(c.Confirm, []string{ConfirmClean}) {
+					t.Errorf("Expected Confirm [clean], got %v", c.Confirm)
+				}
+			},
+		},
+		{
+			name: "invalid confirmation operation",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--yes", "delete",
+			},
+			wantError: true,
+		},
+		{
+			name: "verify",
+			args: []string{
+				"verify",
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--skipHashes",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandVerify || !c.VerifySkipHashes {
+					t.Errorf("Expected verify without hashes, got %q skipHashes %v", c.Command, c.VerifySkipHashes)
+				}
+				if c.Verify {
+					t.Error("the verify command shouldn't set the '--verify' flag")
+				}
+			},
+		},
+		{
+			name: "verify signature without source",
+			args: []string{
+				"verify",
+				"--targetDir", tmpTarget,
+				"--mapping", "gg:GG",
+				"--signature", publicKeyPath,
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.VerifyKey == nil {
+					t.Error("Expected the signature key to be loaded")
+				}
+			},
+		},
+		{
+			name: "verify signature with a key that isn't one",
+			args: []string{
+				"verify",
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--signature", filepath.Join(sourceNes, "snap.json"),
+			},
+			wantError: true,
+		},
+		{
+			name: "verify missing mappings",
+			args: []string{
+				"verify",
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+			},
+			wantError: true,
+		},
+		{
+			name: "examples list",
+			args: []string{
+				"examples",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandExamples || c.ExampleName != "" || c.ExampleRun {
+					t.Errorf("Expected examples listing, got %q name %q run %v", c.Command, c.ExampleName, c.ExampleRun)
+				}
+			},
+		},
+		{
+			name: "examples run",
+			args: []string{
+				"examples", "run", "batocera-sync",
+				"--targetDir", tmpTarget,
+				"--dryRun",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.ExampleName != "batocera-sync" || !c.ExampleRun {
+					t.Errorf("Expected to run batocera-sync, got name %q run %v", c.ExampleName, c.ExampleRun)
+				}
+				if !c.DryRun {
+					t.Error("DryRun should be true")
+				}
+			},
+		},
+		{
+			name: "examples run without a name",
+			args: []string{
+				"examples", "run",
+			},
+			wantError: true,
+		},
+		{
+			name: "examples unknown name",
+			args: []string{
+				"examples", "nonexistent",
+			},
+			wantError: true,
+		},
+		{
+			name: "saves",
+			args: []string{
+				"saves",
+				"--targetDir", tmpTarget,
+				"--backupDir", filepath.Join(tmpSource, "saves"),
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandSaves || c.SavesBackupDir != filepath.Join(tmpSource, "saves") {
+					t.Errorf("Expected to sync saves with the backup, got %q with %q", c.Command, c.SavesBackupDir)
+				}
+			},
+		},
+		{
+			name: "transactional copy",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--transactional",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.Transactional {
+					t.Error("Expected a transactional copy")
+				}
+			},
+		},
+		{
+			name: "transactional copy to the top of the target",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:",
+				"--transactional",
+			},
+			wantError: true,
+		},
+		{
+			name: "copy with a journal",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--journal", filepath.Join(tmpSource, "..", "copy.jsonl"),
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Journal != filepath.Join(filepath.Dir(tmpSource), "copy.jsonl") {
+					t.Errorf("Expected the journal path to be cleaned up, got %q", c.Journal)
+				}
+			},
+		},
+		{
+			name: "undo",
+			args: []string{
+				"undo",
+				"--journal", filepath.Join(tmpSource, "copy.jsonl"),
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandUndo || c.Journal != filepath.Join(tmpSource, "copy.jsonl") {
+					t.Errorf("Expected to undo the journal, got %q with %q", c.Command, c.Journal)
+				}
+			},
+		},
+		{
+			name:      "undo without a journal",
+			args:      []string{"undo"},
+			wantError: true,
+		},
+		{
+			name: "rewrite binary files",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--rewrite", "*.nes:NES:SNES",
+				"--rewriteBinary",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.RewriteBinary {
+					t.Error("Expected binary files to be rewritten")
+				}
+			},
+		},
+		{
+			name: "rewrite binary files without a rewrite",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--rewriteBinary",
+			},
+			wantError: true,
+		},
+		{
+			name: "copy recording the target's state",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--targetState",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.TargetState || c.ExportedRecipe == nil || !reflect.DeepEqual(c.ExportedRecipe.Args, []string{"--mapping=nes:NES", "--targetState"}) {
+					t.Errorf("Expected the state to be recorded with the copy's options, got %v with %+v", c.TargetState, c.ExportedRecipe)
+				}
+			},
+		},
+		{
+			name: "verify from the state file without source",
+			args: []string{
+				"verify",
+				"--targetDir", tmpTarget,
+				"--fromState",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandVerify || !c.VerifyFromState {
+					t.Errorf("Expected to verify from the state file, got %q with %v", c.Command, c.VerifyFromState)
+				}
+			},
+		},
+		{
+			name: "verify from the state file with a signature",
+			args: []string{
+				"verify",
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--fromState",
+				"--signature", publicKeyPath,
+			},
+			wantError: true,
+		},
+		{
+			name: "profile fetch",
+			args: []string{
+				"profile", "fetch", "trimui",
+				"--sha256", strings.Repeat("AB", 32),
+				"--repo", "https://example.com/profiles/",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandProfile || c.FetchProfile != "trimui" {
+					t.Errorf("Expected to fetch trimui, got %q name %q", c.Command, c.FetchProfile)
+				}
+				if c.FetchSHA256 != strings.Repeat("ab", 32) {
+					t.Errorf("Expected the SHA-256 in lowercase, got %q", c.FetchSHA256)
+				}
+				if c.FetchRepo != "https://example.com/profiles/" {
+					t.Errorf("Expected the given profile repository, got %q", c.FetchRepo)
+				}
+			},
+		},
+		{
+			name:      "profile fetch without a repository",
+			args:      []string{"profile", "fetch", "trimui"},
+			wantError: true,
+		},
+		{
+			name: "profile fetch with an invalid SHA-256",
+			args: []string{
+				"profile", "fetch", "trimui",
+				"--sha256", "abc123",
+				"--repo", "https://example.com/profiles",
+			},
+			wantError: true,
+		},
+		{
+			name: "device name and expected device",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--deviceName", " Dad's RG35XX ",
+				"--expectDevice", "Dad's RG35XX",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.DeviceName != "Dad's RG35XX" || c.ExpectDevice != "Dad's RG35XX" {
+					t.Errorf("Expected device name and expected device 'Dad's RG35XX', got %q and %q", c.DeviceName, c.ExpectDevice)
+				}
+			},
+		},
+		{
+			name: "generate gamelist",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--generateGamelist",
+				"--artworkDir", "Imgs/",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.GenerateGamelist || c.ArtworkDir != "Imgs" {
+					t.Errorf("Expected game lists generated with art from 'Imgs', got %v and %q", c.GenerateGamelist, c.ArtworkDir)
+				}
+			},
+		},
+		{
+			name: "generate gamelist artwork outside the platform folder",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--generateGamelist",
+				"--artworkDir", "../images",
+			},
+			wantError: true,
+		},
+		{
+			name: "scrape",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "snes:SFC",
+				"--scrape",
+				"--scrapeCredentials", filepath.Join(sourceNes, "snap.json"),
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.Scrape || c.ScrapeCredentials != filepath.Join(sourceNes, "snap.json") {
+					t.Errorf("Scrape, ScrapeCredentials = %v, %q; want true and the credentials given", c.Scrape, c.ScrapeCredentials)
+				}
+			},
+		},
+		{
+			name: "fetch thumbnails",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "snes:SFC",
+				"--fetchThumbnails",
+				"--artworkDir", "Imgs",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.FetchThumbnails || c.ArtworkDir != "Imgs" {
+					t.Errorf("FetchThumbnails, ArtworkDir = %v, %q; want true and 'Imgs'", c.FetchThumbnails, c.ArtworkDir)
+				}
+			},
+		},
+		{
+			name: "resize images",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "snes:SFC",
+				"--resizeImages", "250x360",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.ResizeImages != (artwork.Size{Width: 250, Height: 360}) {
+					t.Errorf("ResizeImages = %v, want 250x360", c.ResizeImages)
+				}
+			},
+		},
+		{
+			name: "resize images without a height",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "snes:SFC",
+				"--resizeImages", "250",
+			},
+			wantError: true,
+		},
+		{
+			name: "scrape without credentials",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "snes:SFC",
+				"--scrape",
+			},
+			wantError: true,
+		},
+		{
+			name: "skraper artwork source",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "snes:SFC",
+				"--artworkSource", "box2d",
+				"--artworkDir", "Imgs",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.ArtworkSource != "box2d" || c.ArtworkDir != "Imgs" {
+					t.Errorf("Expected 'box2d' media copied into 'Imgs', got %q and %q", c.ArtworkSource, c.ArtworkDir)
+				}
+			},
+		},
+		{
+			name: "skraper artwork source with a path",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "snes:SFC",
+				"--artworkSource", "media/box2d",
+			},
+			wantError: true,
+		},
+		{
+			name: "gamelist language and tags",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--metadataLang", "en ",
+				"--gamelistTags", "name, desc,,image",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.MetadataLang != "en" || !reflect.DeepEqual(c.GamelistTags, []string{"name", "desc", "image"}) {
+					t.Errorf("Expected language 'en' and tags [name desc image], got %q and %v", c.MetadataLang, c.GamelistTags)
+				}
+			},
+		},
+		{
+			name: "gamelist tags invalid element",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--gamelistTags", "<desc>",
+			},
+			wantError: true,
+		},
+		{
+			name: "convert gamelist",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--convertGamelist", " Miyoo",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.ConvertGamelist != "miyoo" {
+					t.Errorf("Expected game list format 'miyoo', got %q", c.ConvertGamelist)
+				}
+			},
+		},
+		{
+			name: "convert gamelist invalid format",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--convertGamelist", "pegasus",
+			},
+			wantError: true,
+		},
+		{
+			name: "merge gamelists",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--mergeGamelists", "Device",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.MergeGamelists != "device" {
+					t.Errorf("Expected merge policy 'device', got %q", c.MergeGamelists)
+				}
+			},
+		},
+		{
+			name: "merge gamelists invalid policy",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--mergeGamelists", "newest",
+			},
+			wantError: true,
+		},
+		{
+			name: "launchbox titles",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--launchboxData", tmpSource,
+				"--launchboxTitles",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.LaunchBoxData != tmpSource || !c.LaunchBoxTitles {
+					t.Errorf("LaunchBoxData, LaunchBoxTitles = %q, %v; want %q, true", c.LaunchBoxData, c.LaunchBoxTitles, tmpSource)
+				}
+			},
+		},
+		{
+			name: "launchbox titles without launchbox data",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--launchboxTitles",
+			},
+			wantError: true,
+		},
+		{
+			name: "timeout",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--timeout", "2h",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Timeout != 2*time.Hour {
+					t.Errorf("Timeout = %s, want 2h", c.Timeout)
+				}
+			},
+		},
+		{
+			name: "negative timeout",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--timeout=-1m",
+			},
+			wantError: true,
+		},
+		{
+			name: "history needs no directories",
+			args: []string{
+				"history",
+				"--limit", "5",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandHistory || c.HistoryLimit != 5 {
+					t.Errorf("Expected history of 5 runs, got %q limit %d", c.Command, c.HistoryLimit)
+				}
+			},
+		},
+		{
+			name: "history negative limit",
+			args: []string{
+				"history",
+				"--limit=-1",
+			},
+			wantError: true,
+		},
+		{
+			name: "status",
+			args: []string{
+				"status",
+				"--stateDir", tmpSource,
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.Command != CommandStatus || c.StateDir != tmpSource {
+					t.Errorf("Expected status from %q, got %q from %q", tmpSource, c.Command, c.StateDir)
+				}
+			},
+		},
+		{
+			name: "clean target and dry run",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--cleanTarget",
+				"--dryRun",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.CleanTarget {
+					t.Error("CleanTarget should be true")
+				}
+				if !c.DryRun {
+					t.Error("DryRun should be true")
+				}
+				if c.Command != CommandCopy {
+					t.Errorf("Expected default command %q, got %q", CommandCopy, c.Command)
+				}
+			},
+		},
+		{
+			name: "protect",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--cleanTarget",
+				"--protect", "**/*.cfg",
+				"--protect", "./bios.bin",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				protect := c.Protect[len(c.Protect)-2:]
+				if !reflect.DeepEqual(protect, []string{"**/*.cfg", "bios.bin"}) {
+					t.Errorf("Expected the '--protect' globs after the defaults, got %v", c.Protect)
+				}
+				if !contains(c.Protect, "**/*.srm") || !contains(c.Protect, "**/saves/**") {
+					t.Errorf("Expected save data to be protected by default, got %v", c.Protect)
+				}
+				if !contains(c.Protect, "**/system volume information/**") {
+					t.Errorf("Expected volume metadata to be protected by default, got %v", c.Protect)
+				}
+			},
+		},
+		{
+			name: "protect with an invalid glob",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--protect", "[*.cfg",
+			},
+			wantError: true,
+		},
+		{
+			name: "per-game folders",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--perGameFolders",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.PerGameFolders {
+					t.Error("Expected games to be copied into folders of their own")
+				}
+			},
+		},
+		{
+			name: "alphabetical folders",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--splitAlpha",
+				"--alphaBuckets", "A-M,N-Z",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if len(c.AlphaBuckets) != 2 || c.AlphaBuckets[1].Name() != "N-Z" {
+					t.Errorf("Expected buckets A-M and N-Z, got %v", c.AlphaBuckets)
+				}
+			},
+		},
+		{
+			name: "alphabetical folders missing letters",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--splitAlpha",
+				"--alphaBuckets", "A-M",
+			},
+			wantError: true,
+		},
+		{
+			name: "sanitize names",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--sanitizeNames",
+				"--sanitizeWith", "-",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.SanitizeNames || c.SanitizeWith != "-" {
+					t.Errorf("Expected names sanitized with '-', got %v with %q", c.SanitizeNames, c.SanitizeWith)
+				}
+			},
+		},
+		{
+			name: "sanitize names with an illegal substitute",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--sanitizeNames",
+				"--sanitizeWith", "?",
+			},
+			wantError: true,
+		},
+		{
+			name: "normalize names",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--normalizeNames", "NFD",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.NormalizeNames != "nfd" {
+					t.Errorf("Expected names normalized to nfd, got %q", c.NormalizeNames)
+				}
+			},
+		},
+		{
+			name: "normalize names off",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--normalizeNames", "none",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.NormalizeNames != "" {
+					t.Errorf("Expected names copied as they are, got %q", c.NormalizeNames)
+				}
+			},
+		},
+		{
+			name: "normalize names with an unknown form",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--normalizeNames", "utf8",
+			},
+			wantError: true,
+		},
+		{
+			name: "max path length",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--maxPathLength", "255",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if c.MaxPathLength != 255 {
+					t.Errorf("Expected MaxPathLength 255, got %d", c.MaxPathLength)
+				}
+			},
+		},
+		{
+			name: "negative max path length",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--maxPathLength=-1",
+			},
+			wantError: true,
+		},
+		{
+			name: "plan only with output",
+			args: []string{
+				"--sourceDir", tmpSource,
+				"--targetDir", tmpTarget,
+				"--mapping", "nes:NES",
+				"--planOnly",
+				"--planOutput", "plan.json",
+			},
+			wantError: false,
+			validate: func(t *testing.T, c *Config) {
+				if !c.PlanOnly || c.DryRun {
+					t.Errorf("PlanOnly, DryRun = %v, %v; want true, false", c.PlanOnly, c.DryRun)
+				}
+				if !filepath.IsAbs(c.PlanOutput) || filepath.Base(c.PlanOutput) != "plan.json" {
+					t.Errorf("PlanOutput = %q, want an absolute path to plan.json", c.PlanOutput)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Set command line arguments
+			os.Args = append([]string{"cmd"}, tt.args...)
+
+			// Parse configuration
+			config, err := ParseAndValidate()
+
+			// Check error status
+			if (err != nil) != tt.wantError {
+				t.Errorf("ParseAndValidate() error = %v, wantError %v", err, tt.wantError)
+				return
+			}
+
+			// If we don't expect an error and have a validation function, run it
+			if !tt.wantError && tt.validate != nil {
+				tt.validate(t, config)
+			}
+		})
+	}
+}
+
+func TestAutoApproves(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   map[string]bool
+	}{
+		{
+			name:   "prompts by default",
+			config: Config{},
+			want:   map[string]bool{ConfirmCopy: false, ConfirmClean: false, ConfirmSpace: false},
+		},
+		{
+			name:   "skip confirm approves all but running out of space",
+			config: Config{SkipConfirm: true},
+			want:   map[string]bool{ConfirmCopy: true, ConfirmClean: true, ConfirmSpace: false},
+		},
+		{
+			name:   "yes approves only what it names",
+			config: Config{Yes: []string{ConfirmCopy, ConfirmSpace}},
+			want:   map[string]bool{ConfirmCopy: true, ConfirmClean: false, ConfirmSpace: true},
+		},
+		{
+			name:   "confirm overrides skip confirm and yes",
+			config: Config{SkipConfirm: true, Yes: []string{ConfirmClean}, Confirm: []string{ConfirmClean}},
+			want:   map[string]bool{ConfirmCopy: true, ConfirmClean: false, ConfirmSpace: false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for op, want := range tt.want {
+				if got := tt.config.AutoApproves(op); got != want {
+					t.Errorf("AutoApproves(%q) = %v, want %v", op, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfigHash(t *testing.T) {
+	base := Config{SourceDir: "/roms", TargetDir: "/media/card", Mappings: []DirMapping{{Source: "snes", Destination: "SFC"}}}
+
+	sameCopy := base
+	sameCopy.Command, sameCopy.DryRun, sameCopy.SkipConfirm, sameCopy.Verbose = CommandDiff, true, true, true
+	if base.Hash() != sameCopy.Hash() {
+		t.Error("Hash() changed with options that don't change what's copied")
+	}
+
+	otherCopy := base
+	otherCopy.Regions = []string{"USA"}
+	if base.Hash() == otherCopy.Hash() {
+		t.Error("Hash() didn't change with '--regions'")
+	}
+}
+
+func TestRecipe(t *testing.T) {
+	tmpSource := t.TempDir()
+	tmpTarget := t.TempDir()
+	otherSource := t.TempDir()
+	for _, dir := range []string{tmpSource, otherSource} {
+		if err := os.MkdirAll(filepath.Join(dir, "snes"), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "snes", "clones.dat"), []byte("<datafile/>"), 0644); err != nil {
+			t.Fatalf("Failed to create test DAT: %v", err)
+		}
+	}
+	gameList := filepath.Join(t.TempDir(), "games.txt")
+	if err := os.WriteFile(gameList, []byte("Zelda\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test game list: %v", err)
+	}
+	recipePath := filepath.Join(t.TempDir(), "miyoo.json")
+
+	os.Args = []string{"cmd",
+		"--sourceDir", tmpSource, "--targetDir", tmpTarget, "--mapping", "snes:SFC",
+		"--regions", "USA,Europe", "--keepGamelistEntries", "--dryRun", "--skipConfirm",
+		"--dat", filepath.Join(tmpSource, "snes", "clones.dat"), "--gameList", gameList,
+		"--exportRecipe", recipePath,
+	}
+	config, err := ParseAndValidate()
+	if err != nil {
+		t.Fatalf("ParseAndValidate() error = %v", err)
+	}
+	want := []string{"--mapping=snes:SFC", "--keepGamelistEntries", "--gameList={gameList}", "--regions=USA", "--regions=Europe", "--dat={sourceDir}/snes/clones.dat"}
+	if config.ExportedRecipe == nil || !reflect.DeepEqual(config.ExportedRecipe.Args, want) {
+		t.Fatalf("ExportedRecipe = %+v, want args %v", config.ExportedRecipe, want)
+	}
+	if err := WriteRecipe(recipePath, config.ExportedRecipe); err != nil {
+		t.Fatalf("WriteRecipe() error = %v", err)
+	}
+
+	// applied elsewhere, the recipe needs the files it left as placeholders
+	os.Args = []string{"cmd", "--recipe", recipePath, "--sourceDir", otherSource, "--targetDir", tmpTarget}
+	if _, err := ParseAndValidate(); err == nil || !strings.Contains(err.Error(), "'--gameList'") {
+		t.Errorf("ParseAndValidate() without the recipe's game list error = %v, want it asked for", err)
+	}
+
+	os.Args = append(os.Args, "--gameList", gameList, "--mapping", "gba:GBA")
+	if err := os.MkdirAll(filepath.Join(otherSource, "gba"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	config, err = ParseAndValidate()
+	if err != nil {
+		t.Fatalf("ParseAndValidate() with recipe error = %v", err)
+	}
+	if config.SourceDir != otherSource || config.DryRun || config.PruneGamelists || len(config.Mappings) != 2 || !reflect.DeepEqual(config.Regions, []string{"USA", "Europe"}) {
+		t.Errorf("ParseAndValidate() with recipe = %+v, want the recipe's options with the command line's", config)
+	}
+	if !reflect.DeepEqual(config.Dats, []string{filepath.Join(otherSource, "snes", "clones.dat")}) || config.GameList != gameList {
+		t.Errorf("Dats, GameList = %v, %q; want the DAT in the new source and the given game list", config.Dats, config.GameList)
+	}
+
+	// recipes can't choose where to copy, or skip prompts
+	if err := os.WriteFile(recipePath, []byte(`{"version": 1, "args": ["--skipConfirm"]}`), 0644); err != nil {
+		t.Fatalf("Failed to write recipe: %v", err)
+	}
+	os.Args = []string{"cmd", "--recipe", recipePath, "--sourceDir", otherSource, "--targetDir", tmpTarget, "--mapping", "gba:GBA"}
+	if _, err := ParseAndValidate(); err == nil {
+		t.Error("ParseAndValidate() with a recipe setting '--skipConfirm' succeeded")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	tmpSource := t.TempDir()
+	tmpTarget := t.TempDir()
+	for _, dir := range []string{"nes", "snes", "gba", "misc"} {
+		if err := os.MkdirAll(filepath.Join(tmpSource, dir), 0755); err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+	}
+
+	// the command line overrides the profile, and a platform folder it maps isn't mapped again
+	os.Args = []string{"cmd", "--profile", "onion", "--sourceDir", tmpSource, "--targetDir", tmpTarget, "--mapping", "nes:Roms/NES", "--artworkDir", "Boxart"}
+	config, err := ParseAndValidate()
+	if err != nil {
+		t.Fatalf("ParseAndValidate() error = %v", err)
+	}
+	want := []DirMapping{{Source: "gba", Destination: "Roms/GBA"}, {Source: "snes", Destination: "Roms/SFC"}, {Source: "nes", Destination: "Roms/NES"}}
+	if !reflect.DeepEqual(config.Mappings, want) {
+		t.Errorf("Mappings = %+v, want %+v", config.Mappings, want)
+	}
+	if config.ConvertGamelist != "miyoo" || config.ArtworkDir != "Boxart" || config.Profile != "onion" {
+		t.Errorf("ConvertGamelist, ArtworkDir, Profile = %q, %q, %q; want the profile's conversion and the given folder", config.ConvertGamelist, config.ArtworkDir, config.Profile)
+	}
+
+	// art is moved out to the firmware's catalogue for mapped platforms it has one for, unless it's moved already
+	os.Args = []string{"cmd", "--profile", "muos", "--sourceDir", tmpSource, "--targetDir", tmpTarget, "--mapping", "misc:ROMS/misc", "--moveArtwork", "gba:Art/GBA"}
+	config, err = ParseAndValidate()
+	if err != nil {
+		t.Fatalf("ParseAndValidate() error = %v", err)
+	}
+	wantMoves := map[string]string{
+		"nes":  filepath.FromSlash("MUOS/info/catalogue/Nintendo NES - Famicom/box"),
+		"snes": filepath.FromSlash("MUOS/info/catalogue/Nintendo SNES - SFC/box"),
+		"gba":  filepath.FromSlash("Art/GBA"),
+	}
+	if !reflect.DeepEqual(config.MoveArtwork, wantMoves) || config.MaxNameLength != 255 {
+		t.Errorf("MoveArtwork, MaxNameLength = %v, %d; want %v, 255", config.MoveArtwork, config.MaxNameLength, wantMoves)
+	}
+
+	os.Args = []string{"cmd", "--sourceDir", tmpSource, "--targetDir", tmpTarget, "--mapping", "nes:NES", "--moveArtwork", "snes:Art/SNES"}
+	if _, err := ParseAndValidate(); err == nil || !strings.Contains(err.Error(), "isn't the source of a '--mapping'") {
+		t.Errorf("ParseAndValidate() moving art of an unmapped folder error = %v, want it rejected", err)
+	}
+
+	// BIOS files from '--bios' go to the profile's BIOS folder, in place of the source's
+	biosDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpSource, "bios"), 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+	os.Args = []string{"cmd", "--profile", "onion", "--sourceDir", tmpSource, "--targetDir", tmpTarget, "--bios", biosDir}
+	config, err = ParseAndValidate()
+	if err != nil {
+		t.Fatalf("ParseAndValidate() error = %v", err)
+	}
+	if config.BiosSource != biosDir || config.BiosFolder != "BIOS" {
+		t.Errorf("BiosSource, BiosFolder = %q, %q; want %q, \"BIOS\"", config.BiosSource, config.BiosFolder, biosDir)
+	}
+	for _, mapping := range config.Mappings {
+		if mapping.Source == "bios" {
+			t.Errorf("Mappings = %+v, want the source's BIOS folder left to '--bios'", config.Mappings)
+		}
+	}
+
+	os.Args = []string{"cmd", "--profile", "garlicos", "--sourceDir", tmpSource, "--targetDir", tmpTarget, "--bios", biosDir}
+	if _, err := ParseAndValidate(); err == nil || !strings.Contains(err.Error(), "BIOS") {
+		t.Errorf("ParseAndValidate() with '--bios' and a profile without a BIOS folder error = %v, want it rejected", err)
+	}
+
+	// every built-in profile's options are ones it can set
+	for _, profile := range profiles.All {
+		os.Args = []string{"cmd", "--profile", profile.Name, "--sourceDir", tmpSource, "--targetDir", tmpTarget}
+		if _, err := ParseAndValidate(); err != nil {
+			t.Errorf("ParseAndValidate() with profile %s error = %v", profile.Name, err)
+		}
+	}
+
+	os.Args = []string{"cmd", "--profile", "toaster", "--sourceDir", tmpSource, "--targetDir", tmpTarget}
+	if _, err := ParseAndValidate(); err == nil || !strings.Contains(err.Error(), "onion") {
+		t.Errorf("ParseAndValidate() with an unknown profile error = %v, want the profiles listed", err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      int64
+		wantError bool
+	}{
+		{"4096", 4096, false},
+		{"10MB", 10 * 1024 * 1024, false},
+		{"10mb", 10 * 1024 * 1024, false},
+		{"1.5G", 1536 * 1024 * 1024, false},
+		{"512 KiB", 512 * 1024, false},
+		{"100B", 100, false},
+		{"", 0, true},
+		{"fast", 0, true},
+		{"-5MB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ParseByteSize(%q) error = %v, wantError %v", tt.input, err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("ParseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      time.Duration
+		wantError bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"1.5d", 36 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"0", 0, false},
+		{"", 0, false},
+		{"-3d", 0, true},
+		{"0d", 0, true},
+		{"forever", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseRetention(tt.input)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("parseRetention(%q) error = %v, wantError %v", tt.input, err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("parseRetention(%q) = %s, want %s", tt.input, got, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,108 @@
+package cli_parsing
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kong"
+
+	"github.com/jkingsman/ROMCopyEngine/platforms"
+	"github.com/jkingsman/ROMCopyEngine/profiles"
+)
+
+// loads the platform table named by '--platforms' in args, if any, so the folders a profile maps are recognized
+// by it
+func loadPlatforms(args []string) error {
+	files := givenFlags(args)["platforms"]
+	if len(files) == 0 {
+		return nil
+	}
+	return platforms.Load(kong.ExpandPath(files[len(files)-1]))
+}
+
+// adds the profiles fetched with 'profile fetch' to the built-in ones. Without a user config directory there are
+// none to add.
+func loadUserProfiles() error {
+	dir, err := profiles.DefaultUserDir()
+	if err != nil {
+		return nil
+	}
+	return profiles.LoadUserDir(dir)
+}
+
+// applies the profile named by '--profile' in args, if any, returning the arguments to parse: the profile's
+// options, and a mapping for each platform folder in '--sourceDir' the profile has a folder for and args doesn't
+// already map, followed by args, so options given on the command line or by a recipe override the profile's, or
+// for repeatable options, add to them. For a profile that shows box art from apart from the platform folders,
+// each mapped platform folder's art is moved there, unless args moves it already.
+func applyProfile(model *kong.Application, args []string) ([]string, error) {
+	given := givenFlags(args)
+	names := given["profile"]
+	if len(names) == 0 {
+		return args, nil
+	}
+	name := names[len(names)-1]
+	profile, ok := profiles.Find(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown profile '%s': must be one of %s", name, strings.Join(profiles.Names(), ", "))
+	}
+
+	flags := flagsByName(model)
+	applied := make([]string, 0, len(profile.Options)+len(args))
+	for _, option := range profile.Options {
+		optionName, _, _ := strings.Cut(strings.TrimPrefix(option, "--"), "=")
+		flag, ok := flags[optionName]
+		if !ok {
+			return nil, fmt.Errorf("profile '%s' has '%s', which isn't an option", profile.Name, option)
+		}
+		if flag.Tag.Get("recipe") == "-" {
+			return nil, fmt.Errorf("profile '%s' sets '--%s', which profiles can't", profile.Name, flag.Name)
+		}
+		applied = append(applied, option)
+	}
+
+	// without a source there's nothing to map, and the missing '--sourceDir' is reported once parsed
+	if sourceDirs := given["sourceDir"]; len(sourceDirs) > 0 {
+		mapped := make(map[string]bool)
+		for _, mapping := range given["mapping"] {
+			source, _, _ := strings.Cut(mapping, ":")
+			mapped[strings.TrimRight(source, "/\\")] = true
+		}
+		mappings, err := profile.Mappings(kong.ExpandPath(sourceDirs[len(sourceDirs)-1]), mapped)
+		if err != nil {
+			return nil, err
+		}
+		// BIOS files given with '--bios' are copied from there rather than from the source
+		if len(given["bios"]) > 0 {
+			kept := mappings[:0]
+			for _, mapping := range mappings {
+				if _, destination, _ := strings.Cut(mapping, ":"); destination != profile.BiosDir {
+					kept = append(kept, mapping)
+				}
+			}
+			mappings = kept
+		}
+		for _, mapping := range mappings {
+			applied = append(applied, "--mapping="+mapping)
+		}
+
+		moved := make(map[string]bool)
+		for _, move := range given["moveArtwork"] {
+			source, _, _ := strings.Cut(move, ":")
+			moved[source] = true
+		}
+		for _, mapping := range append(mappings, given["mapping"]...) {
+			source, _, _ := strings.Cut(mapping, ":")
+			platform := platforms.Find(path.Base(filepath.ToSlash(strings.TrimRight(source, "/\\"))))
+			if platform == nil || moved[source] {
+				continue
+			}
+			if folder, ok := profile.ArtworkFolder(platform); ok {
+				applied = append(applied, "--moveArtwork="+source+":"+folder)
+			}
+		}
+	}
+	return append(applied, args...), nil
+}
@@ -0,0 +1,225 @@
+package cli_parsing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/kong"
+)
+
+// RecipeVersion is the version of the recipe format '--exportRecipe' writes and '--recipe' reads
+const RecipeVersion = 1
+
+// in a recipe, stands for the '--sourceDir' it's applied with
+const sourceDirPlaceholder = "{sourceDir}"
+
+// Recipe is a shareable copy setup: the options of a run that shape the copy, less anything tied to the machine it
+// ran on. Options marked `recipe:"-"` (which directories, prompts, logging, and the like) are left out; files
+// within the source are given relative to a {sourceDir} placeholder, and any other file is replaced by a
+// placeholder named after its option, e.g. '--gameList={gameList}', for whoever applies the recipe to give.
+type Recipe struct {
+	Version int `json:"version"`
+	// '--name=value' options, one per element; repeatable options are given once per value
+	Args []string `json:"args"`
+}
+
+// exports the options set in ctx to a recipe, resolving files within sourceDir against it. Only the options every
+// command shares are exported; a recipe is for copies.
+func exportRecipe(ctx *kong.Context, sourceDir string) (*Recipe, error) {
+	recipe := &Recipe{Version: RecipeVersion, Args: make([]string, 0)}
+	for _, flag := range ctx.Model.Flags {
+		if flag.Hidden || flag.Name == "help" || flag.Tag.Get("recipe") == "-" {
+			continue
+		}
+
+		isDefault, err := hasDefault(flag)
+		if err != nil {
+			return nil, err
+		}
+		if isDefault {
+			continue
+		}
+
+		target := flag.Target
+		if target.Kind() == reflect.Bool {
+			switch {
+			case target.Bool():
+				recipe.Args = append(recipe.Args, "--"+flag.Name)
+			case flag.Tag.Negatable != "" && flag.Tag.Negatable != "_":
+				recipe.Args = append(recipe.Args, "--"+flag.Tag.Negatable)
+			default:
+				recipe.Args = append(recipe.Args, "--no-"+flag.Name)
+			}
+			continue
+		}
+
+		values := []string{fmt.Sprint(target.Interface())}
+		if target.Kind() == reflect.Slice {
+			values = make([]string, 0, target.Len())
+			for i := 0; i < target.Len(); i++ {
+				values = append(values, fmt.Sprint(target.Index(i).Interface()))
+			}
+		}
+		for _, value := range values {
+			switch flag.Tag.Type {
+			case "path", "existingfile", "existingdir":
+				value = recipePath(flag.Name, value, sourceDir)
+			}
+			if target.Kind() == reflect.Slice && flag.Tag.Sep != -1 {
+				value = strings.ReplaceAll(value, string(flag.Tag.Sep), `\`+string(flag.Tag.Sep))
+			}
+			recipe.Args = append(recipe.Args, "--"+flag.Name+"="+value)
+		}
+	}
+	return recipe, nil
+}
+
+// whether flag is unset, or set to its default
+func hasDefault(flag *kong.Flag) (bool, error) {
+	if !flag.HasDefault {
+		return flag.Target.IsZero() || (flag.Target.Kind() == reflect.Slice && flag.Target.Len() == 0), nil
+	}
+	defaultValue := reflect.New(flag.Target.Type()).Elem()
+	if err := flag.Parse(kong.ScanFromTokens(kong.Token{Type: kong.FlagValueToken, Value: flag.Default}), defaultValue); err != nil {
+		return false, fmt.Errorf("failed to read the default of '--%s': %w", flag.Name, err)
+	}
+	return reflect.DeepEqual(flag.Target.Interface(), defaultValue.Interface()), nil
+}
+
+// a file as a recipe gives it: relative to the source if it's within it, otherwise a placeholder for the option
+func recipePath(flagName string, filePath string, sourceDir string) string {
+	if sourceDir != "" {
+		if rel, err := filepath.Rel(sourceDir, filePath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return sourceDirPlaceholder + "/" + filepath.ToSlash(rel)
+		}
+	}
+	return "{" + flagName + "}"
+}
+
+// WriteRecipe writes recipe to filePath as indented JSON
+func WriteRecipe(filePath string, recipe *Recipe) error {
+	data, err := json.MarshalIndent(recipe, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recipe: %w", err)
+	}
+	if err := os.WriteFile(filePath, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write recipe %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// LoadRecipe reads a recipe written by WriteRecipe
+func LoadRecipe(filePath string) (*Recipe, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recipe %s: %w", filePath, err)
+	}
+	var recipe Recipe
+	if err := json.Unmarshal(data, &recipe); err != nil {
+		return nil, fmt.Errorf("failed to parse recipe %s: %w", filePath, err)
+	}
+	if recipe.Version != RecipeVersion {
+		return nil, fmt.Errorf("recipe %s is version %d; this version of ROMCopyEngine reads version %d", filePath, recipe.Version, RecipeVersion)
+	}
+	return &recipe, nil
+}
+
+// applies the recipe named by '--recipe' in args, if any, returning the arguments to parse: the recipe's options,
+// followed by args, so options given on the command line override the recipe's, or for repeatable options, add to
+// them. Placeholders are filled in from args: {sourceDir} from '--sourceDir', and an option whose value is a
+// placeholder is dropped in favor of the same option in args, which must give it.
+func applyRecipe(model *kong.Application, args []string) ([]string, error) {
+	given := givenFlags(args)
+	recipePaths := given["recipe"]
+	if len(recipePaths) == 0 {
+		return args, nil
+	}
+	recipePath := recipePaths[len(recipePaths)-1]
+	recipe, err := LoadRecipe(kong.ExpandPath(recipePath))
+	if err != nil {
+		return nil, err
+	}
+
+	flags := flagsByName(model)
+	applied := make([]string, 0, len(recipe.Args)+len(args))
+	needed := make(map[string]bool)
+	for _, arg := range recipe.Args {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		flag, ok := flags[name]
+		if !strings.HasPrefix(arg, "--") || !ok {
+			return nil, fmt.Errorf("recipe %s has '%s', which isn't an option", recipePath, arg)
+		}
+		if flag.Tag.Get("recipe") == "-" {
+			return nil, fmt.Errorf("recipe %s sets '--%s', which recipes can't; give it on the command line instead", recipePath, flag.Name)
+		}
+
+		if value == "{"+name+"}" {
+			if len(given[name]) == 0 {
+				needed[name] = true
+			}
+			continue
+		}
+		if strings.Contains(value, sourceDirPlaceholder) {
+			sourceDirs := given["sourceDir"]
+			if len(sourceDirs) == 0 {
+				needed["sourceDir"] = true
+				continue
+			}
+			value = strings.ReplaceAll(value, sourceDirPlaceholder, filepath.ToSlash(sourceDirs[len(sourceDirs)-1]))
+			arg = "--" + name + "=" + filepath.FromSlash(value)
+		}
+		applied = append(applied, arg)
+	}
+
+	if len(needed) > 0 {
+		names := make([]string, 0, len(needed))
+		for name := range needed {
+			names = append(names, "'--"+name+"'")
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("recipe %s needs files from this machine; give %s", recipePath, strings.Join(names, ", "))
+	}
+	return append(applied, args...), nil
+}
+
+// every option in model by the names it can be given as, including the negated names of negatable options
+func flagsByName(model *kong.Application) map[string]*kong.Flag {
+	flags := make(map[string]*kong.Flag)
+	for _, flag := range model.Flags {
+		flags[flag.Name] = flag
+		if flag.Tag.Negatable != "" && flag.Tag.Negatable != "_" {
+			flags[flag.Tag.Negatable] = flag
+		} else if flag.Tag.Negatable != "" {
+			flags["no-"+flag.Name] = flag
+		}
+	}
+	return flags
+}
+
+// the values of each '--name value' or '--name=value' option in args, by name, up to any '--'. Options that take
+// no value take the following argument for theirs; that's harmless here, where only options taking a value are
+// looked up.
+func givenFlags(args []string) map[string][]string {
+	given := make(map[string][]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg[2:], "=")
+		if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			i++
+			value = args[i]
+		}
+		given[name] = append(given[name], value)
+	}
+	return given
+}
@@ -0,0 +1,79 @@
+package copy_funcs
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// OtherBucket is the folder '--splitAlpha' puts names that don't start with a letter in, e.g. '1942.nes'
+const OtherBucket = "#"
+
+// AlphaBucket is a range of first letters whose games '--splitAlpha' puts in a folder of their own, e.g. 'A-E'
+type AlphaBucket struct {
+	First rune
+	Last  rune
+}
+
+// Name is the bucket's folder name, e.g. 'A-E', or 'Q' for a single letter
+func (b AlphaBucket) Name() string {
+	if b.First == b.Last {
+		return string(b.First)
+	}
+	return fmt.Sprintf("%c-%c", b.First, b.Last)
+}
+
+// ParseAlphaBuckets reads a comma-separated list of letter ranges, e.g. 'A-E,F-J,K-O,P-T,U-Z' or 'A-M,N-Z', in
+// either case. Together they must cover each letter from A to Z once.
+func ParseAlphaBuckets(spec string) ([]AlphaBucket, error) {
+	buckets := make([]AlphaBucket, 0)
+	covered := make(map[rune]string)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToUpper(strings.TrimSpace(part))
+		first, last, isRange := strings.Cut(part, "-")
+		if !isRange {
+			last = first
+		}
+		if len(first) != 1 || len(last) != 1 || first[0] < 'A' || first[0] > 'Z' || last[0] < first[0] || last[0] > 'Z' {
+			return nil, fmt.Errorf("'%s' isn't a letter or a range of letters like 'A-E'", part)
+		}
+
+		bucket := AlphaBucket{First: rune(first[0]), Last: rune(last[0])}
+		for letter := bucket.First; letter <= bucket.Last; letter++ {
+			if other, ok := covered[letter]; ok {
+				return nil, fmt.Errorf("'%c' is in both '%s' and '%s'", letter, other, bucket.Name())
+			}
+			covered[letter] = bucket.Name()
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	missing := ""
+	for letter := 'A'; letter <= 'Z'; letter++ {
+		if _, ok := covered[letter]; !ok {
+			missing += string(letter)
+		}
+	}
+	if missing != "" {
+		return nil, fmt.Errorf("no range has the letter(s) %s", missing)
+	}
+	return buckets, nil
+}
+
+// AlphaFolder returns the name of the bucket a file or folder name goes in by its first letter, accents aside
+// (e.g. 'Élan' goes in 'A-E'), or OtherBucket if it doesn't start with a letter from A to Z
+func AlphaFolder(name string, buckets []AlphaBucket) string {
+	decomposed := []rune(norm.NFD.String(name))
+	if len(decomposed) == 0 {
+		return OtherBucket
+	}
+	letter := unicode.ToUpper(decomposed[0])
+	for _, bucket := range buckets {
+		if letter >= bucket.First && letter <= bucket.Last {
+			return bucket.Name()
+		}
+	}
+	return OtherBucket
+}
@@ -0,0 +1,45 @@
+package copy_funcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAlphaBuckets(t *testing.T) {
+	buckets, err := ParseAlphaBuckets("a-m, N-Y,Z")
+	if err != nil {
+		t.Fatalf("ParseAlphaBuckets() error = %v", err)
+	}
+	want := []AlphaBucket{{'A', 'M'}, {'N', 'Y'}, {'Z', 'Z'}}
+	if !reflect.DeepEqual(buckets, want) {
+		t.Errorf("ParseAlphaBuckets() = %v, want %v", buckets, want)
+	}
+
+	for _, spec := range []string{"A-M", "A-M,M-Z", "A-Z,1-9", "E-A,F-Z", "AB-Z", ""} {
+		if _, err := ParseAlphaBuckets(spec); err == nil {
+			t.Errorf("ParseAlphaBuckets(%q) should have failed", spec)
+		}
+	}
+}
+
+func TestAlphaFolder(t *testing.T) {
+	buckets, err := ParseAlphaBuckets("A-E,F-J,K-O,P-T,U-Z")
+	if err != nil {
+		t.Fatalf("ParseAlphaBuckets() error = %v", err)
+	}
+	tests := map[string]string{
+		"Super Metroid (Japan, USA).sfc": "P-T",
+		"zelda.sfc":                      "U-Z",
+		"Final Fantasy VII":              "F-J",
+		"Élan.nes":                       "A-E",
+		"1942 (Japan, USA).nes":          OtherBucket,
+		"[BIOS] PS1.bin":                 OtherBucket,
+		"ポケモン.gb":                        OtherBucket,
+		"":                               OtherBucket,
+	}
+	for name, want := range tests {
+		if got := AlphaFolder(name, buckets); got != want {
+			t.Errorf("AlphaFolder(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
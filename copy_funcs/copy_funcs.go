@@ -1,20 +1,181 @@
 package copy_funcs
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
 
+	"github.com/jkingsman/ROMCopyEngine/artwork"
+	"github.com/jkingsman/ROMCopyEngine/disk_info"
 	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
 	"github.com/jkingsman/ROMCopyEngine/logging"
+	"github.com/jkingsman/ROMCopyEngine/romtags"
 )
 
+// '--copyInclude' and '--copyExclude' glob dialects
+const (
+	// the pattern is matched against the whole path relative to the mapping's source folder, so '*.png' only
+	// matches top-level files and '**/*.png' matches at any depth
+	GlobDoublestar = "doublestar"
+	// .gitignore semantics: a pattern without a slash matches the name at any depth, a leading slash anchors it
+	// to the mapping's source folder, a trailing slash matches only directories, and matching a directory
+	// matches everything inside it
+	GlobGitignore = "gitignore"
+)
+
+var GlobDialects = []string{GlobDoublestar, GlobGitignore}
+
+// ErrDeadline is returned by CopyFiles when it stops at CopyOptions.Deadline
+var ErrDeadline = errors.New("ran out of time")
+
+// CopyOptions controls which files are selected from a mapping's source folder and how they are copied
+type CopyOptions struct {
+	Include []string
+	Exclude []string
+	// how Include and Exclude patterns are matched; one of GlobDialects, with "" meaning GlobDoublestar
+	GlobDialect string
+	// rules loaded from .romcopyignore files; nil if ignore files are disabled
+	Ignore *IgnoreRules
+	DryRun bool
+	// files (by path relative to the source folder) left out of the copy after inspecting their contents,
+	// e.g. releases not chosen by 1G1R
+	Omit map[string]bool
+	// leave out the saves, states, and screenshots folders emulators create next to ROMs
+	SkipEmulatorArtifacts bool
+	// copy only files whose name tags (e.g. '(USA)', '(En,Fr)') include one of these regions or languages;
+	// files without the tag are always copied. Empty for no filtering.
+	Regions   []string
+	Languages []string
+	// skip files whose name tags mark them as any of these release types, as romtags.ReleaseTags names
+	ExcludeTags []string
+	// the regions of a file whose name doesn't tag any, e.g. from a LaunchBox library; nil if there's nothing
+	// else to go by
+	RegionsOf func(relPath string) []string
+	// copy only these files named by the platform's gamelist.xml, by slash separated path relative to the source
+	// folder, and anything in these folders; nil to copy every file
+	GamelistFiles map[string]bool
+	// copy only the games listed, and files named after them; nil to copy every game
+	GameList *GameList
+	// files copied under another name, by path relative to the source folder, e.g. games named by their LaunchBox
+	// titles
+	Renamed map[string]string
+	// rename files and folders with Windows device names (CON, NUL, etc.) as they're copied
+	SafeWindowsNames bool
+	// replace characters FAT32 and exFAT don't allow in names with FATSubstitute as files and folders are copied
+	SafeFATNames  bool
+	FATSubstitute string
+	// write names in this Unicode normalization form, one of disk_info.NameForms; empty to copy names as they are
+	NameForm string
+	// permissions given to each destination directory; 0 to copy the source's permissions, less the umask
+	DirMode os.FileMode
+	// how file contents are written to the destination
+	Stream file_operations.StreamOptions
+	// downscale PNG and JPEG files IsArtwork reports, by path relative to the destination folder, to fit within
+	// this size as they're copied; zero to copy them as they are
+	ResizeImages artwork.Size
+	IsArtwork    func(destRelPath string) bool
+	// files that may fail to copy before the copy is aborted; nil to abort on the first
+	Errors *ErrorBudget
+	// no file is started after this, though one already being copied is finished; zero for no deadline
+	Deadline time.Time
+}
+
+// PastDeadline reports whether the copy has run past its deadline
+func (o CopyOptions) PastDeadline() bool {
+	return !o.Deadline.IsZero() && time.Now().After(o.Deadline)
+}
+
+// selects reports whether relPath passes every configured filter
+func (o CopyOptions) selects(relPath string, isDir bool) bool {
+	_, rejected := o.rejection(relPath, isDir)
+	return !rejected
+}
+
+// matchesTags reports whether the file at relPath passes the region, language, and release type filters
+func (o CopyOptions) matchesTags(relPath string) bool {
+	if len(o.Regions) == 0 && len(o.Languages) == 0 && len(o.ExcludeTags) == 0 {
+		return true
+	}
+
+	tags := romtags.Parse(filepath.Base(relPath))
+	if len(tags.Regions) == 0 && o.RegionsOf != nil {
+		tags.Regions = o.RegionsOf(relPath)
+	}
+	if len(o.Regions) > 0 && len(tags.Regions) > 0 && !tags.InRegion(o.Regions) {
+		return false
+	}
+	if len(o.Languages) > 0 && len(tags.SpokenLanguages()) > 0 && !tags.InLanguage(o.Languages) {
+		return false
+	}
+	if tags.HasRelease(o.ExcludeTags) {
+		return false
+	}
+	return true
+}
+
+// folders emulators (RetroArch, standalone cores, etc.) create inside platform folders for personal data
+var emulatorArtifactDirs = map[string]bool{
+	"saves":       true,
+	"states":      true,
+	"savestates":  true,
+	"screenshots": true,
+}
+
+// isEmulatorArtifact reports whether relPath is, or is inside, an emulator artifact folder
+func isEmulatorArtifact(relPath string, isDir bool) bool {
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	if !isDir {
+		parts = parts[:len(parts)-1]
+	}
+	for _, part := range parts {
+		if emulatorArtifactDirs[strings.ToLower(part)] {
+			return true
+		}
+	}
+	return false
+}
+
+// DestRelPath maps a path relative to the source to where it's written relative to the destination
+func (o CopyOptions) DestRelPath(relPath string) string {
+	if renamed, ok := o.Renamed[relPath]; ok {
+		relPath = renamed
+	}
+	if !o.SafeNaming() {
+		return relPath
+	}
+	return filepath.FromSlash(o.SafePath(filepath.ToSlash(relPath)))
+}
+
+// SafeNaming reports whether files and folders are renamed to suit the target as they're copied
+func (o CopyOptions) SafeNaming() bool {
+	return o.SafeWindowsNames || o.SafeFATNames || o.NameForm != ""
+}
+
+// SafePath returns the slash separated relPath as it's renamed to suit the target: normalized to NameForm, with
+// the characters FAT32 and exFAT don't allow replaced, and with Windows device names renamed, as chosen
+func (o CopyOptions) SafePath(relPath string) string {
+	if o.NameForm != "" {
+		relPath = disk_info.NormalizePath(relPath, o.NameForm)
+	}
+	if o.SafeFATNames {
+		relPath = disk_info.SafeFATPath(relPath, o.FATSubstitute)
+	}
+	if o.SafeWindowsNames {
+		relPath = disk_info.SafeWindowsPath(relPath)
+	}
+	return relPath
+}
+
 // shouldIncludeDir determines if a directory should be included based on:
 // 1. If it's empty and matches the include/exclude rules
 // 2. If it contains any files that match the include/exclude rules
-func shouldIncludeDir(dirPath string, absSource string, includes []string, excludes []string) (bool, error) {
+func shouldIncludeDir(dirPath string, absSource string, opts CopyOptions) (bool, error) {
 	// First check if the directory itself matches the rules (for empty directories)
 	relPath, err := filepath.Rel(absSource, dirPath)
 	if err != nil {
@@ -25,11 +186,12 @@ func shouldIncludeDir(dirPath string, absSource string, includes []string, exclu
 		return true, nil
 	}
 
-	dirShouldBeIncluded := shouldInclude(relPath, includes, excludes)
+	dirShouldBeIncluded := opts.selects(relPath, true)
+	destDirRel := opts.DestRelPath(relPath)
 
 	// Check if the directory has any matching files
 	hasMatchingFiles := false
-	err = filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+	err = fsys.Walk(file_operations.Filesystem(), dirPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -44,8 +206,8 @@ func shouldIncludeDir(dirPath string, absSource string, includes []string, exclu
 			return fmt.Errorf("failed to get relative path for %s: %w", path, err)
 		}
 
-		// If we find a matching file, mark it and stop walking
-		if !info.IsDir() && shouldInclude(relPath, includes, excludes) {
+		// If we find a matching file that's copied into the directory, not renamed out of it, mark it and stop walking
+		if !info.IsDir() && opts.selects(relPath, false) && isWithin(opts.DestRelPath(relPath), destDirRel) {
 			hasMatchingFiles = true
 			return filepath.SkipAll
 		}
@@ -58,7 +220,7 @@ func shouldIncludeDir(dirPath string, absSource string, includes []string, exclu
 	}
 
 	// Check if directory is empty
-	entries, err := os.ReadDir(dirPath)
+	entries, err := file_operations.Filesystem().ReadDir(dirPath)
 	if err != nil {
 		return false, fmt.Errorf("failed to read directory %s: %w", dirPath, err)
 	}
@@ -70,7 +232,7 @@ func shouldIncludeDir(dirPath string, absSource string, includes []string, exclu
 	return (isEmpty && dirShouldBeIncluded) || hasMatchingFiles, nil
 }
 
-func CopyFiles(sourcePath string, destPath string, copyInclude []string, copyExclude []string, dryRun bool) ([]string, error) {
+func CopyFiles(sourcePath string, destPath string, opts CopyOptions) ([]string, error) {
 	// Track copied files
 	copiedFiles := make([]string, 0)
 
@@ -84,43 +246,33 @@ func CopyFiles(sourcePath string, destPath string, copyInclude []string, copyExc
 		return nil, fmt.Errorf("failed to get absolute destination path: %w", err)
 	}
 
-	// First pass: collect all directories that should be created
-	dirsToCreate := make(map[string]os.FileMode)
-	err = filepath.Walk(absSource, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return fmt.Errorf("error accessing path %s: %w", path, err)
-		}
+	// the directories above the item being copied, outermost first. Only its ancestors are kept, so memory
+	// grows with the depth of the tree rather than its size.
+	ancestors := make([]pendingDir, 0)
 
-		if !info.IsDir() {
+	// an overridden mode is applied exactly, including to directories that already exist; otherwise MkdirAll
+	// masks the source's mode with the umask
+	createDir := func(dir *pendingDir) error {
+		if dir.created {
 			return nil
 		}
-
-		shouldInclude, err := shouldIncludeDir(path, absSource, copyInclude, copyExclude)
-		if err != nil {
+		mode := dir.mode
+		if opts.DirMode != 0 {
+			mode = opts.DirMode
+		}
+		if err := file_operations.Filesystem().MkdirAll(dir.destPath, mode); err != nil {
 			return err
 		}
-
-		if shouldInclude {
-			relPath, err := filepath.Rel(absSource, path)
-			if err != nil {
-				return fmt.Errorf("failed to get relative path for %s: %w", path, err)
-			}
-
-			if relPath != "." {
-				destDir := filepath.Join(absDest, relPath)
-				dirsToCreate[destDir] = info.Mode()
+		if opts.DirMode != 0 {
+			if err := file_operations.Filesystem().Chmod(dir.destPath, opts.DirMode); err != nil {
+				return fmt.Errorf("failed to set permissions on %s: %w", dir.destPath, err)
 			}
 		}
-
+		dir.created = true
 		return nil
-	})
-
-	if err != nil {
-		return nil, err
 	}
 
-	// Second pass: copy files and create necessary directories
-	err = filepath.Walk(absSource, func(path string, info os.FileInfo, err error) error {
+	err = fsys.Walk(file_operations.Filesystem(), absSource, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return fmt.Errorf("error accessing path %s: %w", path, err)
 		}
@@ -130,51 +282,83 @@ func CopyFiles(sourcePath string, destPath string, copyInclude []string, copyExc
 			return fmt.Errorf("failed to get relative path for %s: %w", path, err)
 		}
 
-		if relPath == "." {
-			return nil
+		// directories are walked before their contents, so once those that aren't ancestors of this path are
+		// dropped, the last one left is its parent
+		for len(ancestors) > 0 && !isWithin(path, ancestors[len(ancestors)-1].sourcePath) {
+			ancestors = ancestors[:len(ancestors)-1]
 		}
 
-		destFile := filepath.Join(absDest, relPath)
+		destFile := filepath.Join(absDest, opts.DestRelPath(relPath))
 
 		if info.IsDir() {
-			if mode, exists := dirsToCreate[destFile]; exists {
-				if dryRun {
+			shouldInclude, err := shouldIncludeDir(path, absSource, opts)
+			if err != nil {
+				return err
+			}
+			dir := pendingDir{sourcePath: path, destPath: destFile, mode: info.Mode().Perm(), included: shouldInclude}
+
+			// the destination root is created (with any missing parents, for nested destinations) when the
+			// first top-level file is copied
+			if shouldInclude && relPath != "." {
+				if opts.DryRun {
 					logging.LogDryRun(logging.Detail, logging.IconFolder, "Creating dir: %s", destFile)
 				} else {
-					logging.Log(logging.Detail, logging.IconFolder, "Creating dir: %s", destFile)
-					if err := os.MkdirAll(destFile, mode); err != nil {
+					logging.LogVerbose(logging.Detail, logging.IconFolder, "Creating dir: %s", destFile)
+					if err := createDir(&dir); err != nil {
 						return fmt.Errorf("failed to create directory %s: %w", destFile, err)
 					}
 				}
 			}
+			ancestors = append(ancestors, dir)
 			return nil
 		}
 
-		if !shouldInclude(relPath, copyInclude, copyExclude) {
-			logging.Log(logging.Detail, logging.IconSkip, "Skipping file: %s", relPath)
+		if !opts.selects(relPath, false) {
+			logging.LogVerbose(logging.Detail, logging.IconSkip, "Skipping file: %s", relPath)
 			return nil
 		}
 
-		if dryRun {
+		if opts.PastDeadline() {
+			return ErrDeadline
+		}
+
+		logging.NextOperation("f")
+		if opts.DryRun {
 			logging.LogDryRun(logging.Detail, logging.IconCopy, "Copying file: %s -> %s",
 				filepath.Join(filepath.Base(absSource), relPath),
-				filepath.Join(filepath.Base(absDest), relPath))
+				filepath.Join(filepath.Base(absDest), opts.DestRelPath(relPath)))
 			copiedFiles = append(copiedFiles, destFile)
 		} else {
-			logging.Log(logging.Detail, logging.IconCopy, "Copying file: %s -> %s",
+			logging.LogVerbose(logging.Detail, logging.IconCopy, "Copying file: %s -> %s",
 				filepath.Join(filepath.Base(absSource), relPath),
-				filepath.Join(filepath.Base(absDest), relPath))
+				filepath.Join(filepath.Base(absDest), opts.DestRelPath(relPath)))
 
-			// Create parent directory if it's in our list of directories to create
-			parentDir := filepath.Dir(destFile)
-			if mode, exists := dirsToCreate[parentDir]; exists {
-				if err := os.MkdirAll(parentDir, mode); err != nil {
+			// Create the parent directory if it's one that should be created, or for a file renamed into another
+			// directory, that one
+			if len(ancestors) > 0 && ancestors[len(ancestors)-1].included && ancestors[len(ancestors)-1].destPath == filepath.Dir(destFile) {
+				if err := createDir(&ancestors[len(ancestors)-1]); err != nil {
+					return fmt.Errorf("failed to create directories for %s: %w", destFile, err)
+				}
+			} else if len(ancestors) > 0 && ancestors[len(ancestors)-1].destPath != filepath.Dir(destFile) {
+				if err := createDir(&pendingDir{destPath: filepath.Dir(destFile), mode: 0755}); err != nil {
 					return fmt.Errorf("failed to create directories for %s: %w", destFile, err)
 				}
 			}
-			if err := file_operations.CopyFile(path, destFile); err != nil {
-				return err
+			stream := opts.Stream
+			if opts.ResizeImages != (artwork.Size{}) && artwork.IsImage(relPath) && opts.IsArtwork != nil && opts.IsArtwork(opts.DestRelPath(relPath)) {
+				stream.ResizeImage = opts.ResizeImages
+			}
+			opts.Stream.Progress.StartFile(relPath)
+			if err := file_operations.CopyFileWithOptions(path, destFile, stream); err != nil {
+				if err := opts.Errors.spend(relPath, err); err != nil {
+					return err
+				}
+				logging.LogError("Failed to copy %s; carrying on: %v", relPath, err)
+				opts.Stream.Progress.RestartFile()
+				opts.Stream.Progress.FinishFile()
+				return nil
 			}
+			opts.Stream.Progress.FinishFile()
 			copiedFiles = append(copiedFiles, destFile)
 		}
 
@@ -188,6 +372,73 @@ func CopyFiles(sourcePath string, destPath string, copyInclude []string, copyExc
 	return copiedFiles, nil
 }
 
+// ResolvedFile is a single source file that would be copied for a mapping
+type ResolvedFile struct {
+	// path relative to the mapping's source folder
+	RelPath string
+	Size    int64
+}
+
+// a directory CopyFiles has walked into
+type pendingDir struct {
+	sourcePath string
+	destPath   string
+	mode       os.FileMode
+	// whether it should exist on the target; see shouldIncludeDir
+	included bool
+	created  bool
+}
+
+// reports whether path is inside dir
+func isWithin(path string, dir string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// WalkFiles walks sourcePath and calls fn with every file that CopyFiles would copy with the same options, in
+// lexical order, without touching the destination or holding the file list in memory
+func WalkFiles(sourcePath string, opts CopyOptions, fn func(ResolvedFile) error) error {
+	absSource, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute source path: %w", err)
+	}
+
+	return fsys.Walk(file_operations.Filesystem(), absSource, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", path, err)
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(absSource, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", path, err)
+		}
+
+		if opts.selects(relPath, false) {
+			return fn(ResolvedFile{RelPath: relPath, Size: info.Size()})
+		}
+
+		return nil
+	})
+}
+
+// ResolveFiles returns every file that CopyFiles would copy with the same options, without touching the
+// destination. Used for pre-flight checks.
+func ResolveFiles(sourcePath string, opts CopyOptions) ([]ResolvedFile, error) {
+	resolved := make([]ResolvedFile, 0)
+	err := WalkFiles(sourcePath, opts, func(f ResolvedFile) error {
+		resolved = append(resolved, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resolved, nil
+}
+
 func GlobifyFilenameOfPathList(paths []string) []string {
 	for i, path := range paths {
 
@@ -202,6 +453,48 @@ func GlobifyFilenameOfPathList(paths []string) []string {
 	return paths
 }
 
+// shouldIncludeGitignore is shouldInclude with patterns read as .gitignore lines
+func shouldIncludeGitignore(relPath string, isDir bool, includes []string, excludes []string) bool {
+	relPath = filepath.ToSlash(relPath)
+	included := len(includes) == 0
+
+	for _, pattern := range includes {
+		if gitignoreMatch(pattern, relPath, isDir) {
+			included = true
+			break
+		}
+	}
+
+	if !included {
+		return false
+	}
+
+	for _, pattern := range excludes {
+		if gitignoreMatch(pattern, relPath, isDir) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reports whether a .gitignore-style pattern matches relPath or any directory containing it
+func gitignoreMatch(pattern string, relPath string, isDir bool) bool {
+	rule, ok := parseIgnoreLine(filepath.ToSlash(pattern), "")
+	// negation only makes sense in a list of rules, which include and exclude globs aren't
+	if !ok || rule.negate {
+		return false
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if rule.matches(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return rule.matches(relPath, isDir)
+}
+
 func shouldInclude(path string, includes []string, excludes []string) bool {
 	path = filepath.ToSlash(path)
 	included := len(includes) == 0
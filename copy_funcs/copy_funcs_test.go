@@ -1,9 +1,21 @@
 package copy_funcs
 
 import (
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/artwork"
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
 )
 
 func TestShouldInclude(t *testing.T) {
@@ -75,6 +87,40 @@ func TestShouldInclude(t *testing.T) {
 	}
 }
 
+func TestShouldIncludeGitignore(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		isDir    bool
+		includes []string
+		excludes []string
+		want     bool
+	}{
+		{"unanchored include matches at any depth", "media/images/game.png", false, []string{"*.png"}, nil, true},
+		{"anchored include only matches at the top", "media/game.png", false, []string{"/*.png"}, nil, false},
+		{"anchored include at the top", "game.png", false, []string{"/*.png"}, nil, true},
+		{"excluded folder excludes its contents", "media/images/game.png", false, nil, []string{"media"}, false},
+		{"directory-only pattern skips files", "media", false, nil, []string{"media/"}, true},
+		{"directory-only pattern excludes contents", "media/game.png", false, nil, []string{"media/"}, false},
+		{"included folder includes its contents", "images/game.png", false, []string{"images/"}, nil, true},
+		{"unmatched", "game.nes", false, []string{"*.png"}, nil, false},
+		{"directory itself", "images", true, []string{"images/"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldIncludeGitignore(tt.path, tt.isDir, tt.includes, tt.excludes); got != tt.want {
+				t.Errorf("shouldIncludeGitignore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the same patterns read as doublestar globs only match the top level
+	if shouldInclude("media/images/game.png", []string{"*.png"}, nil) {
+		t.Error("doublestar '*.png' should not match nested files")
+	}
+}
+
 func TestShouldIncludeDir(t *testing.T) {
 	// Create temporary test directory structure
 	tmpDir, err := os.MkdirTemp("", "test-*")
@@ -166,7 +212,7 @@ func TestShouldIncludeDir(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := shouldIncludeDir(tt.dirPath, tmpDir, tt.includes, tt.excludes)
+			got, err := shouldIncludeDir(tt.dirPath, tmpDir, CopyOptions{Include: tt.includes, Exclude: tt.excludes})
 			if err != nil {
 				t.Errorf("shouldIncludeDir() error = %v", err)
 				return
@@ -374,7 +420,7 @@ func TestCopyFiles(t *testing.T) {
 			os.RemoveAll(destDir)
 			os.MkdirAll(destDir, 0755)
 
-			_, err := CopyFiles(sourceDir, destDir, tt.includes, tt.excludes, tt.dryRun)
+			_, err := CopyFiles(sourceDir, destDir, CopyOptions{Include: tt.includes, Exclude: tt.excludes, DryRun: tt.dryRun})
 			if err != nil {
 				t.Errorf("CopyFiles() error = %v", err)
 				return
@@ -416,3 +462,533 @@ func TestCopyFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestCopyFilesCreatesNestedDestination(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := filepath.Join(t.TempDir(), "Roms", "Consoles", "SNES")
+
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	for _, name := range []string{"game.sfc", "images/game.png"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	for _, name := range []string{"game.sfc", "images/game.png"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("expected %s to be copied into the nested destination: %v", name, err)
+		}
+	}
+}
+
+func TestCopyFilesReturnsToParentAfterSubdirectory(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	// 'a/z.sfc' is walked after everything in 'a/b', so its parent has to be remembered past the subdirectory
+	names := []string{"a/b/c/deep.sfc", "a/b/mid.sfc", "a/z.sfc", "top.sfc"}
+	for _, name := range names {
+		path := filepath.Join(sourceDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	copied, err := CopyFiles(sourceDir, destDir, CopyOptions{})
+	if err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+	if len(copied) != len(names) {
+		t.Errorf("CopyFiles() copied %d files, want %d", len(copied), len(names))
+	}
+
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("expected %s to be copied: %v", name, err)
+		}
+	}
+}
+
+func TestCopyFilesErrorBudget(t *testing.T) {
+	names := []string{"a.sfc", "b.sfc", "c.sfc", "d.sfc"}
+	setup := func(t *testing.T, blocked ...string) (string, string) {
+		sourceDir := t.TempDir()
+		destDir := t.TempDir()
+		for _, name := range names {
+			if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0644); err != nil {
+				t.Fatalf("failed to create file %s: %v", name, err)
+			}
+		}
+		// a directory where the copy belongs can't be written over, so the file fails to copy
+		for _, name := range blocked {
+			if err := os.Mkdir(filepath.Join(destDir, name), 0755); err != nil {
+				t.Fatalf("failed to block %s: %v", name, err)
+			}
+		}
+		return sourceDir, destDir
+	}
+
+	t.Run("within budget", func(t *testing.T) {
+		sourceDir, destDir := setup(t, "b.sfc")
+		budget := NewErrorBudget(1)
+		copied, err := CopyFiles(sourceDir, destDir, CopyOptions{Errors: budget})
+		if err != nil {
+			t.Fatalf("CopyFiles() error = %v", err)
+		}
+		if len(copied) != 3 {
+			t.Errorf("CopyFiles() copied %d files, want 3", len(copied))
+		}
+		if failures := budget.Failures(); len(failures) != 1 || failures[0].RelPath != "b.sfc" {
+			t.Errorf("Failures() = %v, want just b.sfc", failures)
+		}
+	})
+
+	t.Run("over budget", func(t *testing.T) {
+		sourceDir, destDir := setup(t, "b.sfc", "c.sfc")
+		if _, err := CopyFiles(sourceDir, destDir, CopyOptions{Errors: NewErrorBudget(1)}); err == nil {
+			t.Fatal("CopyFiles() error = nil, want the copy to give up at the second failure")
+		}
+		if _, err := os.Stat(filepath.Join(destDir, "d.sfc")); !os.IsNotExist(err) {
+			t.Error("expected the copy to stop before d.sfc")
+		}
+	})
+
+	t.Run("no budget", func(t *testing.T) {
+		sourceDir, destDir := setup(t, "b.sfc")
+		if _, err := CopyFiles(sourceDir, destDir, CopyOptions{}); err == nil {
+			t.Fatal("CopyFiles() error = nil, want the first failure")
+		}
+	})
+}
+
+func TestCopyFilesDeadline(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "a.sfc"), []byte("a"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{Deadline: time.Now().Add(-time.Second)}); !errors.Is(err, ErrDeadline) {
+		t.Fatalf("CopyFiles() past its deadline error = %v, want ErrDeadline", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "a.sfc")); !os.IsNotExist(err) {
+		t.Error("expected nothing to be copied past the deadline")
+	}
+
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{Deadline: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("CopyFiles() within its deadline error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "a.sfc")); err != nil {
+		t.Errorf("expected a.sfc to be copied within the deadline: %v", err)
+	}
+}
+
+func TestCopyFilesInMemory(t *testing.T) {
+	mem := fsys.NewMemFS()
+	file_operations.SetFilesystem(mem)
+	defer file_operations.SetFilesystem(fsys.OS)
+
+	// only used as absolute paths inside the in-memory filesystem; nothing is written beneath them on disk
+	root := t.TempDir()
+	sourceDir := filepath.Join(root, "source")
+	destDir := filepath.Join(root, "dest")
+
+	files := map[string]string{
+		"game.nes":         "rom",
+		"gamelist.xml":     "<path>./game.nes</path>",
+		"saves/game.srm":   "save",
+		"media/game.png":   "image",
+		IgnoreFileName:     "saves/",
+		"media/readme.txt": "notes",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(sourceDir, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		if err := mem.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	ignore, err := LoadIgnoreRules(sourceDir, ".")
+	if err != nil {
+		t.Fatalf("LoadIgnoreRules() error = %v", err)
+	}
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{Ignore: ignore, Exclude: []string{"**/*.txt"}}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+	if _, _, err := file_operations.SearchAndReplace(destDir, "*.xml", "./", "/roms/nes/", false, false, nil); err != nil {
+		t.Fatalf("SearchAndReplace() error = %v", err)
+	}
+
+	for name, want := range map[string]string{"game.nes": "rom", "media/game.png": "image", "gamelist.xml": "<path>/roms/nes/game.nes</path>"} {
+		data, err := mem.ReadFile(filepath.Join(destDir, filepath.FromSlash(name)))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	for _, name := range []string{"saves", "media/readme.txt"} {
+		if _, err := mem.Stat(filepath.Join(destDir, filepath.FromSlash(name))); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be copied", name)
+		}
+	}
+
+	if entries, err := os.ReadDir(root); err != nil || len(entries) != 0 {
+		t.Errorf("expected nothing to be written to disk, found %v (%v)", entries, err)
+	}
+}
+
+func TestCopyFilesSafeWindowsNames(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(sourceDir, "aux"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	for _, name := range []string{"CON.nes", "aux/game.nes", "Contra.nes"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	renamed := map[string]string{"Contra.nes": "Contra - Hard Corps.nes", filepath.Join("aux", "game.nes"): filepath.Join("aux", "NUL.nes")}
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{SafeWindowsNames: true, Renamed: renamed}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	for _, name := range []string{"CON_.nes", "aux_/NUL_.nes", "Contra - Hard Corps.nes"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("expected %s to exist: %v", name, err)
+		}
+	}
+	for _, name := range []string{"CON.nes", "aux", "Contra.nes"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to have been renamed", name)
+		}
+	}
+}
+
+func TestCopyFilesSafeFATNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows can't create the source files")
+	}
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(sourceDir, "Hacks: Vol. 1"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	for _, name := range []string{"Zelda: A Link to the Past.sfc", "Hacks: Vol. 1/Mario?.sfc", "Contra.sfc"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{SafeFATNames: true, FATSubstitute: "-"}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	for _, name := range []string{"Zelda- A Link to the Past.sfc", "Hacks- Vol. 1/Mario-.sfc", "Contra.sfc"} {
+		if _, err := os.Stat(filepath.Join(destDir, name)); err != nil {
+			t.Errorf("expected %s to exist: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(destDir, "Hacks: Vol. 1")); !os.IsNotExist(err) {
+		t.Error("expected 'Hacks: Vol. 1' to have been renamed")
+	}
+}
+
+func TestCopyFilesNormalizesNames(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	decomposed := "Poke\u0301mon Rouge.gb"
+	if err := os.WriteFile(filepath.Join(sourceDir, decomposed), []byte("rom"), 0644); err != nil {
+		t.Fatalf("failed to create file %s: %v", decomposed, err)
+	}
+
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{NameForm: "nfc"}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(destDir)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "Pok\u00e9mon Rouge.gb" {
+		t.Errorf("expected just the composed name to be copied, got %v (%v)", entries, err)
+	}
+}
+
+func TestCopyFilesRenamedIntoAnotherDir(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	moved := filepath.Join("media", "box2d", "Zelda.png")
+	if err := os.MkdirAll(filepath.Join(sourceDir, "media", "box2d"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, moved), []byte("art"), 0644); err != nil {
+		t.Fatalf("failed to create file %s: %v", moved, err)
+	}
+
+	renamed := map[string]string{moved: filepath.Join("Imgs", "Zelda.png")}
+	if _, err := CopyFiles(sourceDir, destDir, CopyOptions{Renamed: renamed}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(destDir, "Imgs", "Zelda.png")); err != nil {
+		t.Errorf("expected Imgs/Zelda.png to exist: %v", err)
+	}
+	// the folder it was renamed out of isn't left behind empty
+	if _, err := os.Stat(filepath.Join(destDir, "media")); !os.IsNotExist(err) {
+		t.Errorf("expected media not to be created, got %v", err)
+	}
+}
+
+func TestCopyFilesResizeImages(t *testing.T) {
+	sourceDir := t.TempDir()
+	destDir := t.TempDir()
+
+	var art bytes.Buffer
+	if err := png.Encode(&art, image.NewRGBA(image.Rect(0, 0, 600, 600))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(sourceDir, "images"), 0755); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	for _, name := range []string{"images/Zelda.png", "Zelda.png"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), art.Bytes(), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	opts := CopyOptions{
+		ResizeImages: artwork.Size{Width: 250, Height: 360},
+		IsArtwork:    func(relPath string) bool { return strings.HasPrefix(filepath.ToSlash(relPath), "images/") },
+	}
+	if _, err := CopyFiles(sourceDir, destDir, opts); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	for name, want := range map[string]int{"images/Zelda.png": 250, "Zelda.png": 600} {
+		data, err := os.ReadFile(filepath.Join(destDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if config, err := png.DecodeConfig(bytes.NewReader(data)); err != nil || config.Width != want {
+			t.Errorf("%s is %d wide, %v; want %d", name, config.Width, err, want)
+		}
+	}
+}
+
+func TestCopyFilesModeOverrides(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows only supports the read-only attribute")
+	}
+
+	sourceDir := t.TempDir()
+	destDir := filepath.Join(t.TempDir(), "NES")
+
+	// permissions as they often appear on a Windows mount
+	if err := os.MkdirAll(filepath.Join(sourceDir, "hacks"), 0777); err != nil {
+		t.Fatalf("failed to create source dir: %v", err)
+	}
+	for _, name := range []string{"Contra.nes", "hacks/Contra Hack.nes"} {
+		path := filepath.Join(sourceDir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+		if err := os.Chmod(path, 0777); err != nil {
+			t.Fatalf("failed to chmod %s: %v", name, err)
+		}
+	}
+
+	opts := CopyOptions{DirMode: 0750}
+	opts.Stream.FileMode = 0640
+	if _, err := CopyFiles(sourceDir, destDir, opts); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	expected := map[string]os.FileMode{
+		"":                      0750,
+		"hacks":                 0750,
+		"Contra.nes":            0640,
+		"hacks/Contra Hack.nes": 0640,
+	}
+	for name, mode := range expected {
+		info, err := os.Stat(filepath.Join(destDir, name))
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", name, err)
+		}
+		if info.Mode().Perm() != mode {
+			t.Errorf("%s has mode %o, want %o", name, info.Mode().Perm(), mode)
+		}
+	}
+}
+
+func TestMatchesTags(t *testing.T) {
+	opts := CopyOptions{Regions: []string{"USA", "World"}, Languages: []string{"En"}}
+	tests := []struct {
+		relPath string
+		want    bool
+	}{
+		{"Tetris (World).gb", true},
+		{"Zelda (USA) (Rev 1).nes", true},
+		{"Zelda (Japan).nes", false},
+		{"Asterix (Europe) (En,Fr).sms", false},
+		{"Dragon Quest (USA) (Es).nes", false},
+		{"Street Fighter (USA, Japan).sfc", true},
+		{"images/Zelda (Japan)-image.png", false},
+		{"Homebrew.nes", true},
+		{"gamelist.xml", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			if got := opts.matchesTags(tt.relPath); got != tt.want {
+				t.Errorf("matchesTags(%q) = %v, want %v", tt.relPath, got, tt.want)
+			}
+		})
+	}
+
+	excludeOpts := CopyOptions{ExcludeTags: []string{"beta", "bad", "hack"}}
+	for relPath, want := range map[string]bool{
+		"Mega Man 2 (USA) (Beta).nes": false,
+		"Contra (U) [b1].nes":         false,
+		"SMB (W) [hM04].nes":          false,
+		"Contra (U) [!].nes":          true,
+		"Mega Man 2 (USA).nes":        true,
+	} {
+		if got := excludeOpts.matchesTags(relPath); got != want {
+			t.Errorf("matchesTags(%q) with excluded tags = %v, want %v", relPath, got, want)
+		}
+	}
+
+	// regions from elsewhere are only used for files whose names don't tag any
+	opts.RegionsOf = func(relPath string) []string {
+		return map[string][]string{"zelda3.sfc": {"Japan"}, "smw.sfc": {"USA"}, "Tetris (World).gb": {"Japan"}}[relPath]
+	}
+	for relPath, want := range map[string]bool{"zelda3.sfc": false, "smw.sfc": true, "Tetris (World).gb": true, "homebrew.sfc": true} {
+		if got := opts.matchesTags(relPath); got != want {
+			t.Errorf("matchesTags(%q) with regions looked up = %v, want %v", relPath, got, want)
+		}
+	}
+
+	if !(CopyOptions{}).matchesTags("Zelda (Japan).nes") {
+		t.Error("matchesTags() without filters should match everything")
+	}
+}
+
+func TestIsEmulatorArtifact(t *testing.T) {
+	tests := []struct {
+		relPath  string
+		isDir    bool
+		artifact bool
+	}{
+		{"saves", true, true},
+		{"States", true, true},
+		{"saves/game.srm", false, true},
+		{"hacks/screenshots/shot.png", false, true},
+		{"game.sfc", false, false},
+		{"saves.txt", false, false},
+		{"images/saves", false, false},
+		{"savestate_guide", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			if got := isEmulatorArtifact(filepath.FromSlash(tt.relPath), tt.isDir); got != tt.artifact {
+				t.Errorf("isEmulatorArtifact(%q, %v) = %v, want %v", tt.relPath, tt.isDir, got, tt.artifact)
+			}
+		})
+	}
+}
+
+func TestResolveFiles(t *testing.T) {
+	sourceDir := t.TempDir()
+
+	files := map[string]string{
+		"game1.sfc":         "12345",
+		"game2.sfc":         "123",
+		"notes.txt":         "1",
+		"images/game1.png":  "1234567",
+		"images/readme.txt": "12",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(sourceDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		includes  []string
+		excludes  []string
+		wantCount int
+		wantSize  int64
+	}{
+		{"everything", nil, nil, 5, 18},
+		{"only roms", []string{"*.sfc"}, nil, 2, 8},
+		{"exclude text", nil, []string{"**/*.txt"}, 3, 15},
+		{"nothing matches", []string{"*.gba"}, nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := ResolveFiles(sourceDir, CopyOptions{Include: tt.includes, Exclude: tt.excludes})
+			if err != nil {
+				t.Fatalf("ResolveFiles() error = %v", err)
+			}
+
+			var totalSize int64
+			for _, f := range resolved {
+				totalSize += f.Size
+			}
+
+			if len(resolved) != tt.wantCount {
+				t.Errorf("ResolveFiles() returned %d files, want %d", len(resolved), tt.wantCount)
+			}
+			if totalSize != tt.wantSize {
+				t.Errorf("ResolveFiles() total size = %d, want %d", totalSize, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestWalkFilesStopsOnError(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, name := range []string{"a.sfc", "b.sfc", "c.sfc"} {
+		if err := os.WriteFile(filepath.Join(sourceDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	stop := errors.New("stop")
+	seen := make([]string, 0)
+	err := WalkFiles(sourceDir, CopyOptions{}, func(f ResolvedFile) error {
+		seen = append(seen, f.RelPath)
+		if f.RelPath == "b.sfc" {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("WalkFiles() error = %v, want %v", err, stop)
+	}
+	if want := []string{"a.sfc", "b.sfc"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("WalkFiles() visited %v, want %v", seen, want)
+	}
+}
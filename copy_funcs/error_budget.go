@@ -0,0 +1,42 @@
+package copy_funcs
+
+import "fmt"
+
+// CopyFailure is a file that couldn't be copied but was tolerated by an ErrorBudget
+type CopyFailure struct {
+	// path relative to the mapping's source folder
+	RelPath string
+	Err     error
+}
+
+// ErrorBudget lets a mapping's copy carry on past a limited number of files that fail to copy, so one unreadable
+// file doesn't cost a whole run. A nil budget tolerates none.
+type ErrorBudget struct {
+	max      int
+	failures []CopyFailure
+}
+
+// NewErrorBudget creates a budget that tolerates up to max failed files
+func NewErrorBudget(max int) *ErrorBudget {
+	return &ErrorBudget{max: max}
+}
+
+// Failures returns the failed files tolerated so far
+func (b *ErrorBudget) Failures() []CopyFailure {
+	if b == nil {
+		return nil
+	}
+	return b.failures
+}
+
+// records a file that failed to copy, returning an error to abort the copy with if that exhausts the budget
+func (b *ErrorBudget) spend(relPath string, err error) error {
+	if b == nil || b.max == 0 {
+		return err
+	}
+	if len(b.failures) >= b.max {
+		return fmt.Errorf("giving up after %d file(s) failed to copy, more than '--maxErrors' allows (%d); the last: %w", len(b.failures)+1, b.max, err)
+	}
+	b.failures = append(b.failures, CopyFailure{RelPath: relPath, Err: err})
+	return nil
+}
@@ -0,0 +1,184 @@
+package copy_funcs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// FilterStage is one step of file selection. Stages are evaluated in the order declared, and a file is copied
+// only if it passes every one.
+type FilterStage int
+
+const (
+	StageIgnoreFiles FilterStage = iota
+	StageEmulatorArtifacts
+	// files left out after their contents or names were inspected (bad dumps, 1G1R, preferred languages and
+	// revisions, dedupe, Skraper media)
+	StageOmitted
+	StageTags
+	StageGamelistXML
+	StageGameList
+	StageInclude
+	StageExclude
+)
+
+func (s FilterStage) String() string {
+	switch s {
+	case StageIgnoreFiles:
+		return "ignore files"
+	case StageEmulatorArtifacts:
+		return "emulator artifacts"
+	case StageOmitted:
+		return "inspection"
+	case StageTags:
+		return "name tags"
+	case StageGamelistXML:
+		return "gamelist.xml"
+	case StageGameList:
+		return "game list"
+	case StageInclude:
+		return "include"
+	case StageExclude:
+		return "exclude"
+	}
+	return fmt.Sprintf("FilterStage(%d)", int(s))
+}
+
+// FilterStages lists every stage in evaluation order
+var FilterStages = []FilterStage{StageIgnoreFiles, StageEmulatorArtifacts, StageOmitted, StageTags, StageGamelistXML, StageGameList, StageInclude, StageExclude}
+
+// rejection returns the first stage relPath fails, or false if it passes them all
+func (o CopyOptions) rejection(relPath string, isDir bool) (FilterStage, bool) {
+	if o.Ignore.Ignored(relPath, isDir) {
+		return StageIgnoreFiles, true
+	}
+	if o.SkipEmulatorArtifacts && isEmulatorArtifact(relPath, isDir) {
+		return StageEmulatorArtifacts, true
+	}
+	if !isDir && o.Omit[relPath] {
+		return StageOmitted, true
+	}
+	if !isDir && !o.matchesTags(relPath) {
+		return StageTags, true
+	}
+	if !isDir && o.GamelistFiles != nil && !inGamelist(o.GamelistFiles, relPath) {
+		return StageGamelistXML, true
+	}
+	if !o.GameList.Selects(relPath, isDir) {
+		return StageGameList, true
+	}
+
+	matches := func(includes []string, excludes []string) bool {
+		if o.GlobDialect == GlobGitignore {
+			return shouldIncludeGitignore(relPath, isDir, includes, excludes)
+		}
+		return shouldInclude(relPath, includes, excludes)
+	}
+	if !matches(o.Include, nil) {
+		return StageInclude, true
+	}
+	if !matches(nil, o.Exclude) {
+		return StageExclude, true
+	}
+	return 0, false
+}
+
+// reports whether relPath is one of the gamelist files, which are slash separated, or inside a listed folder
+func inGamelist(files map[string]bool, relPath string) bool {
+	for relPath = filepath.ToSlash(relPath); relPath != "."; relPath = path.Dir(relPath) {
+		if files[relPath] {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe explains what the stage does under these options, or returns "" if the stage is inactive
+func (o CopyOptions) Describe(stage FilterStage) string {
+	switch stage {
+	case StageIgnoreFiles:
+		if o.Ignore.Len() > 0 {
+			return "paths matched by .romcopyignore rules are excluded"
+		}
+	case StageEmulatorArtifacts:
+		if o.SkipEmulatorArtifacts {
+			return "saves, states, savestates, and screenshots folders are excluded"
+		}
+	case StageOmitted:
+		if len(o.Omit) > 0 {
+			return "bad dumps, releases not chosen by 1G1R, '--preferLanguage', or '--preferRevision', duplicates, and Skraper media not chosen by '--artworkSource' are excluded"
+		}
+	case StageTags:
+		conditions := make([]string, 0, 3)
+		if len(o.Regions) > 0 {
+			conditions = append(conditions, "region tag is one of "+strings.Join(o.Regions, ", "))
+		}
+		if len(o.Languages) > 0 {
+			conditions = append(conditions, "language is one of "+strings.Join(o.Languages, ", "))
+		}
+		if len(o.ExcludeTags) > 0 {
+			conditions = append(conditions, "not tagged "+strings.Join(o.ExcludeTags, ", "))
+		}
+		if len(conditions) > 0 && o.RegionsOf != nil {
+			return "must match: " + strings.Join(conditions, "; ") + " (untagged files pass, unless LaunchBox gives their region)"
+		}
+		if len(conditions) > 0 {
+			return "must match: " + strings.Join(conditions, "; ") + " (untagged files pass)"
+		}
+	case StageGamelistXML:
+		if o.GamelistFiles != nil {
+			return "must be a game in the platform's gamelist.xml (a favorite, with '--onlyFavorites'), or its artwork, video, or discs"
+		}
+	case StageGameList:
+		if o.GameList.Len() > 0 {
+			return fmt.Sprintf("must be one of the %d game(s) in %s, or named after one, or an '.xml' game list", o.GameList.Len(), o.GameList.filePath)
+		}
+	case StageInclude:
+		if len(o.Include) > 0 {
+			return "must match ANY of: " + strings.Join(o.Include, ", ")
+		}
+	case StageExclude:
+		if len(o.Exclude) > 0 {
+			return "must match NONE of: " + strings.Join(o.Exclude, ", ")
+		}
+	}
+	return ""
+}
+
+// CountRejections walks sourcePath and returns how many files each stage rejects (each file is counted only
+// against the first stage it fails) along with the total number of files
+func CountRejections(sourcePath string, opts CopyOptions) (map[FilterStage]int, int, error) {
+	rejected := make(map[FilterStage]int)
+	total := 0
+
+	err := fsys.Walk(file_operations.Filesystem(), sourcePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(sourcePath, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", path, err)
+		}
+
+		total++
+		if stage, ok := opts.rejection(relPath, false); ok {
+			rejected[stage]++
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+	return rejected, total, nil
+}
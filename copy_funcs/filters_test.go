@@ -0,0 +1,114 @@
+package copy_funcs
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestRejection(t *testing.T) {
+	opts := CopyOptions{
+		SkipEmulatorArtifacts: true,
+		Omit:                  map[string]bool{"Bad (USA) [b].nes": true},
+		Regions:               []string{"USA"},
+		Include:               []string{"**/*.nes", "**/*.txt"},
+		Exclude:               []string{"**/*.txt", "**/Hack*"},
+	}
+
+	tests := []struct {
+		relPath   string
+		wantStage FilterStage
+		wantOk    bool
+	}{
+		{"Game (USA).nes", 0, false},
+		{"Game.nes", 0, false},
+		{"saves/Game (USA).srm", StageEmulatorArtifacts, true},
+		{"Bad (USA) [b].nes", StageOmitted, true},
+		// fails the tags and include stages, but only the first is reported
+		{"Game (Japan).png", StageTags, true},
+		{"Game (USA).png", StageInclude, true},
+		{"notes.txt", StageExclude, true},
+		{"Hack (USA).nes", StageExclude, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			stage, ok := opts.rejection(tt.relPath, false)
+			if ok != tt.wantOk || (ok && stage != tt.wantStage) {
+				t.Errorf("rejection(%q) = %v, %v; want %v, %v", tt.relPath, stage, ok, tt.wantStage, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestInGamelist(t *testing.T) {
+	files := map[string]bool{"Game (USA).sfc": true, "media/images/Game (USA).png": true, "Chrono Trigger": true}
+
+	tests := []struct {
+		relPath string
+		want    bool
+	}{
+		{"Game (USA).sfc", true},
+		{filepath.Join("media", "images", "Game (USA).png"), true},
+		{filepath.Join("Chrono Trigger", "disc.bin"), true},
+		{"Other (USA).sfc", false},
+		{filepath.Join("media", "images", "Other (USA).png"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			if got := inGamelist(files, tt.relPath); got != tt.want {
+				t.Errorf("inGamelist(%q) = %v, want %v", tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if got := (CopyOptions{}).Describe(StageInclude); got != "" {
+		t.Errorf("Describe(StageInclude) with no includes = %q; want empty", got)
+	}
+
+	opts := CopyOptions{Include: []string{"*.nes", "*.zip"}, Exclude: []string{"*.txt"}}
+	if got, want := opts.Describe(StageInclude), "must match ANY of: *.nes, *.zip"; got != want {
+		t.Errorf("Describe(StageInclude) = %q; want %q", got, want)
+	}
+	if got, want := opts.Describe(StageExclude), "must match NONE of: *.txt"; got != want {
+		t.Errorf("Describe(StageExclude) = %q; want %q", got, want)
+	}
+}
+
+func TestCountRejections(t *testing.T) {
+	mem := fsys.NewMemFS()
+	file_operations.SetFilesystem(mem)
+	defer file_operations.SetFilesystem(fsys.OS)
+
+	sourceDir := filepath.Join(t.TempDir(), "source")
+	for _, name := range []string{"a.nes", "b.nes", "c.txt", "d.png", "Hack.nes", "saves/a.srm"} {
+		filePath := filepath.Join(sourceDir, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		if err := mem.WriteFile(filePath, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	opts := CopyOptions{SkipEmulatorArtifacts: true, Include: []string{"**/*.nes", "**/*.txt"}, Exclude: []string{"**/*.txt", "Hack*"}}
+	rejected, total, err := CountRejections(sourceDir, opts)
+	if err != nil {
+		t.Fatalf("CountRejections() error = %v", err)
+	}
+
+	if total != 6 {
+		t.Errorf("total = %d; want 6", total)
+	}
+	want := map[FilterStage]int{StageEmulatorArtifacts: 1, StageInclude: 1, StageExclude: 2}
+	for _, stage := range FilterStages {
+		if rejected[stage] != want[stage] {
+			t.Errorf("rejected[%v] = %d; want %d", stage, rejected[stage], want[stage])
+		}
+	}
+}
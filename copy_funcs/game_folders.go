@@ -0,0 +1,100 @@
+package copy_funcs
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// GameFolders gives each game at the top of a platform folder a folder of its own, named for the game less its
+// extension, and moves what goes with it in beside it: the tracks and discs its sheet refers to (and theirs), and
+// files anywhere in the platform folder named like it or one of those, artwork suffix aside, e.g.
+// 'images/Tetris (World)-image.png' or 'manuals/Tetris (World).pdf'. relPaths are slash separated and relative
+// to the platform folder, isGame reports whether one is a game, and refs returns the files a sheet refers to, as
+// written in it. Playlists claim their discs first, then sheets their tracks, then each game left is its own.
+// Returns the new path of each file moved by its old one; a file whose new path is taken stays where it is.
+func GameFolders(relPaths []string, isGame func(relPath string) bool, refs func(sheet string) []string) map[string]string {
+	byLower := make(map[string]string, len(relPaths))
+	games := make([]string, 0)
+	for _, relPath := range relPaths {
+		byLower[strings.ToLower(relPath)] = relPath
+		if !strings.Contains(relPath, "/") && isGame(relPath) {
+			games = append(games, relPath)
+		}
+	}
+	rank := func(relPath string) int {
+		switch strings.ToLower(path.Ext(relPath)) {
+		case ".m3u":
+			return 0
+		case ".cue", ".gdi":
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(games, func(i, j int) bool {
+		if rank(games[i]) != rank(games[j]) {
+			return rank(games[i]) < rank(games[j])
+		}
+		return games[i] < games[j]
+	})
+
+	// the game each file goes with, and the game each lowercased name goes with
+	owner := make(map[string]string)
+	named := make(map[string]string)
+	for _, game := range games {
+		if _, ok := owner[game]; ok || gameStem(game) == "" {
+			continue
+		}
+		members := []string{game}
+		owner[game] = game
+		for i := 0; i < len(members); i++ {
+			if _, ok := named[gameStem(members[i])]; !ok {
+				named[gameStem(members[i])] = game
+			}
+			if rank(members[i]) == 2 {
+				continue
+			}
+			for _, ref := range refs(members[i]) {
+				ref, ok := byLower[strings.ToLower(path.Clean(strings.ReplaceAll(ref, "\\", "/")))]
+				if _, owned := owner[ref]; !ok || owned || strings.Contains(ref, "/") {
+					continue
+				}
+				owner[ref] = game
+				members = append(members, ref)
+			}
+		}
+	}
+	for _, relPath := range relPaths {
+		if _, ok := owner[relPath]; ok {
+			continue
+		}
+		if game, ok := named[gameStem(relPath)]; ok {
+			owner[relPath] = game
+		}
+	}
+
+	taken := make(map[string]bool, len(relPaths))
+	for lower := range byLower {
+		taken[lower] = true
+	}
+	moved := make(map[string]string)
+	for _, relPath := range relPaths {
+		game, ok := owner[relPath]
+		if !ok {
+			continue
+		}
+		game = path.Base(game)
+		newPath := path.Join(strings.TrimSuffix(game, path.Ext(game)), path.Base(relPath))
+		if newPath == relPath || taken[strings.ToLower(newPath)] {
+			continue
+		}
+		taken[strings.ToLower(newPath)] = true
+		moved[relPath] = newPath
+	}
+	return moved
+}
+
+// the lowercased name of a file less its extension and any artwork suffix
+func gameStem(relPath string) string {
+	return gameNames(relPath)[0]
+}
@@ -0,0 +1,58 @@
+package copy_funcs
+
+import (
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGameFolders(t *testing.T) {
+	relPaths := []string{
+		"Final Fantasy VII.m3u",
+		"Final Fantasy VII (Disc 1).cue",
+		"Final Fantasy VII (Disc 1) (Track 1).bin",
+		"Final Fantasy VII (Disc 2).cue",
+		"Final Fantasy VII (Disc 2) (Track 1).bin",
+		"Crash Bandicoot.chd",
+		"Crash Bandicoot.txt",
+		"images/Crash Bandicoot-image.png",
+		"images/Final Fantasy VII (Disc 1).png",
+		"manuals/Crash Bandicoot.pdf",
+		"Imgs/Crash Bandicoot.png",
+		"Crash Bandicoot/Crash Bandicoot.png",
+		"Spyro/Spyro.chd",
+		"gamelist.xml",
+		"readme.txt",
+	}
+	sheets := map[string][]string{
+		"Final Fantasy VII.m3u":          {"Final Fantasy VII (Disc 1).cue", "./Final Fantasy VII (Disc 2).cue"},
+		"Final Fantasy VII (Disc 1).cue": {"Final Fantasy VII (Disc 1) (Track 1).bin"},
+		"Final Fantasy VII (Disc 2).cue": {"Final Fantasy VII (Disc 2) (Track 1).bin", "Missing.bin"},
+	}
+	isGame := func(relPath string) bool {
+		switch path.Ext(relPath) {
+		case ".m3u", ".cue", ".bin", ".chd":
+			return !strings.Contains(relPath, "images/")
+		}
+		return false
+	}
+
+	got := GameFolders(relPaths, isGame, func(sheet string) []string { return sheets[sheet] })
+	want := map[string]string{
+		"Final Fantasy VII.m3u":                    "Final Fantasy VII/Final Fantasy VII.m3u",
+		"Final Fantasy VII (Disc 1).cue":           "Final Fantasy VII/Final Fantasy VII (Disc 1).cue",
+		"Final Fantasy VII (Disc 1) (Track 1).bin": "Final Fantasy VII/Final Fantasy VII (Disc 1) (Track 1).bin",
+		"Final Fantasy VII (Disc 2).cue":           "Final Fantasy VII/Final Fantasy VII (Disc 2).cue",
+		"Final Fantasy VII (Disc 2) (Track 1).bin": "Final Fantasy VII/Final Fantasy VII (Disc 2) (Track 1).bin",
+		"images/Final Fantasy VII (Disc 1).png":    "Final Fantasy VII/Final Fantasy VII (Disc 1).png",
+		"Crash Bandicoot.chd":                      "Crash Bandicoot/Crash Bandicoot.chd",
+		"Crash Bandicoot.txt":                      "Crash Bandicoot/Crash Bandicoot.txt",
+		"images/Crash Bandicoot-image.png":         "Crash Bandicoot/Crash Bandicoot-image.png",
+		"manuals/Crash Bandicoot.pdf":              "Crash Bandicoot/Crash Bandicoot.pdf",
+		// 'Imgs/Crash Bandicoot.png' stays, since a file of its name is already in the game's folder
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GameFolders() =\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,123 @@
+package copy_funcs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/romtags"
+)
+
+// suffixes EmulationStation and its forks give downloaded artwork, e.g. 'Tetris (World)-image.png'
+var artworkSuffixes = []string{"-image", "-thumb", "-marquee", "-video"}
+
+type gameListEntry struct {
+	// as written in the file, for messages
+	line string
+	// lowercased glob
+	pattern string
+}
+
+// GameList is the games named in a '--gameList' file, one name or glob per line, e.g. 'Super Metroid' or
+// 'Zelda*'. Blank lines and lines starting with '#' are skipped.
+type GameList struct {
+	filePath string
+	entries  []gameListEntry
+}
+
+// LoadGameList reads a game list from filePath
+func LoadGameList(filePath string) (*GameList, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open game list %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	list := &GameList{filePath: filePath}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern := strings.ToLower(line)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob '%s' on line %d of game list %s: %w", line, lineNumber, filePath, err)
+		}
+		list.entries = append(list.entries, gameListEntry{line: line, pattern: pattern})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read game list %s: %w", filePath, err)
+	}
+	return list, nil
+}
+
+// Len returns the number of games listed
+func (g *GameList) Len() int {
+	if g == nil {
+		return 0
+	}
+	return len(g.entries)
+}
+
+// Selects reports whether relPath (relative to the mapping's source folder) is one of the listed games, or
+// belongs with one: a file is selected if its name, less the extension, or its title (the name up to the first
+// tag, e.g. 'Super Metroid' for 'Super Metroid (Japan, USA) (En,Ja).sfc') matches a line of the list. Artwork,
+// manuals, and cue sheet tracks named after a game therefore come along with it, and '.xml' game lists are
+// always selected.
+func (g *GameList) Selects(relPath string, isDir bool) bool {
+	if isDir || g.Len() == 0 || strings.EqualFold(filepath.Ext(relPath), ".xml") {
+		return true
+	}
+
+	names := gameNames(relPath)
+	for _, entry := range g.entries {
+		if entry.matches(names) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unmatched returns the lines of the list that select none of relPaths
+func (g *GameList) Unmatched(relPaths []string) []string {
+	found := make([]bool, len(g.entries))
+	for _, relPath := range relPaths {
+		names := gameNames(relPath)
+		for i, entry := range g.entries {
+			found[i] = found[i] || entry.matches(names)
+		}
+	}
+
+	unmatched := make([]string, 0)
+	for i, entry := range g.entries {
+		if !found[i] {
+			unmatched = append(unmatched, entry.line)
+		}
+	}
+	return unmatched
+}
+
+// the lowercased names a file can be listed by: its name less the extension and any artwork suffix, and its title
+func gameNames(relPath string) []string {
+	name := filepath.Base(relPath)
+	stem := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	for _, suffix := range artworkSuffixes {
+		stem = strings.TrimSuffix(stem, suffix)
+	}
+	return []string{stem, strings.ToLower(romtags.Parse(stem).Title)}
+}
+
+func (e gameListEntry) matches(names []string) bool {
+	for _, name := range names {
+		if matched, _ := path.Match(e.pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package copy_funcs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeGameList(t *testing.T, content string) string {
+	t.Helper()
+	listPath := filepath.Join(t.TempDir(), "favorites.txt")
+	if err := os.WriteFile(listPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create game list: %v", err)
+	}
+	return listPath
+}
+
+func TestGameListSelects(t *testing.T) {
+	list, err := LoadGameList(writeGameList(t, "\ufeff# travel card\nSuper Metroid\n\nzelda*\r\nTetris (World) (Rev 1)\nMissing Game\n"))
+	if err != nil {
+		t.Fatalf("LoadGameList() error = %v", err)
+	}
+	if list.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4", list.Len())
+	}
+
+	tests := []struct {
+		relPath string
+		want    bool
+	}{
+		{"Super Metroid (Japan, USA) (En,Ja).sfc", true},
+		{"Imgs/Super Metroid (Japan, USA) (En,Ja).png", true},
+		{"downloaded_images/Super Metroid-image.jpg", true},
+		{"Zelda no Densetsu (Japan).sfc", true},
+		{"Legend of Zelda, The (USA).sfc", false},
+		{"Tetris (World) (Rev 1).gb", true},
+		{"Tetris (World).gb", false},
+		{"Super Mario World (USA).sfc", false},
+		{"miyoogamelist.xml", true},
+		{"readme.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			if got := list.Selects(tt.relPath, false); got != tt.want {
+				t.Errorf("Selects(%q) = %v, want %v", tt.relPath, got, tt.want)
+			}
+		})
+	}
+
+	if !list.Selects("Imgs", true) {
+		t.Error("Selects() = false for a directory")
+	}
+
+	unmatched := list.Unmatched([]string{"Super Metroid (Japan, USA) (En,Ja).sfc", "Zelda no Densetsu (Japan).sfc"})
+	if want := []string{"Tetris (World) (Rev 1)", "Missing Game"}; !reflect.DeepEqual(unmatched, want) {
+		t.Errorf("Unmatched() = %v, want %v", unmatched, want)
+	}
+}
+
+func TestLoadGameListRejectsBadGlobs(t *testing.T) {
+	_, err := LoadGameList(writeGameList(t, "Super Metroid\nZelda [\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("LoadGameList() error = %v, want one naming line 2", err)
+	}
+}
@@ -0,0 +1,208 @@
+package copy_funcs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// name of the per-directory ignore file honored in the source tree
+const IgnoreFileName = ".romcopyignore"
+
+type ignoreRule struct {
+	// directory containing the ignore file, slash-separated and relative to the source root ("" for the root)
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// IgnoreRules holds every .romcopyignore rule that applies to a single mapping. Rules use gitignore
+// syntax: patterns are relative to the directory containing the ignore file, '!' re-includes, a trailing
+// '/' matches directories only, and the last matching rule wins.
+type IgnoreRules struct {
+	// mapping source folder relative to the source root, used to translate mapping-relative paths
+	prefix string
+	rules  []ignoreRule
+}
+
+// LoadIgnoreRules reads the .romcopyignore in sourceRoot (if any) plus every .romcopyignore found
+// within the mapping's source folder
+func LoadIgnoreRules(sourceRoot string, mappingSource string) (*IgnoreRules, error) {
+	ignore := &IgnoreRules{prefix: strings.Trim(filepath.ToSlash(filepath.Clean(mappingSource)), "/")}
+	if ignore.prefix == "." {
+		ignore.prefix = ""
+	}
+
+	if err := ignore.loadFile(filepath.Join(sourceRoot, IgnoreFileName), ""); err != nil {
+		return nil, err
+	}
+
+	mappingRoot := filepath.Join(sourceRoot, mappingSource)
+	err := fsys.Walk(file_operations.Filesystem(), mappingRoot, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", filePath, err)
+		}
+
+		if info.IsDir() || info.Name() != IgnoreFileName {
+			return nil
+		}
+
+		relDir, err := filepath.Rel(sourceRoot, filepath.Dir(filePath))
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", filePath, err)
+		}
+
+		return ignore.loadFile(filePath, filepath.ToSlash(relDir))
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ignore, nil
+}
+
+func (r *IgnoreRules) loadFile(filePath string, base string) error {
+	file, err := file_operations.Filesystem().Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open ignore file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	if base == "." {
+		base = ""
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text(), base); ok {
+			r.rules = append(r.rules, rule)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read ignore file %s: %w", filePath, err)
+	}
+
+	return nil
+}
+
+func parseIgnoreLine(line string, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// a slash anywhere but the end anchors the pattern to the ignore file's directory
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	rule.pattern = line
+	return rule, true
+}
+
+// Len returns the number of rules loaded
+func (r *IgnoreRules) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.rules)
+}
+
+// Ignored reports whether relPath (relative to the mapping's source folder) is excluded by the ignore
+// rules. Anything inside an ignored directory is ignored too, and the ignore files themselves are never copied.
+func (r *IgnoreRules) Ignored(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	if !isDir && path.Base(relPath) == IgnoreFileName {
+		return true
+	}
+
+	if r == nil || len(r.rules) == 0 {
+		return false
+	}
+
+	fullPath := relPath
+	if r.prefix != "" {
+		fullPath = r.prefix + "/" + relPath
+	}
+
+	// as with git, a file can't be re-included if one of its parent directories is ignored
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		ancestor := strings.Join(parts[:i], "/")
+		if r.prefix != "" {
+			ancestor = r.prefix + "/" + ancestor
+		}
+		if r.matches(ancestor, true) {
+			return true
+		}
+	}
+
+	return r.matches(fullPath, isDir)
+}
+
+// last matching rule wins
+func (r *IgnoreRules) matches(fullPath string, isDir bool) bool {
+	ignored := false
+	for _, rule := range r.rules {
+		if rule.matches(fullPath, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matches reports whether the rule's pattern matches fullPath, ignoring negation
+func (rule ignoreRule) matches(fullPath string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+
+	subPath := fullPath
+	if rule.base != "" {
+		if !strings.HasPrefix(fullPath, rule.base+"/") {
+			return false
+		}
+		subPath = fullPath[len(rule.base)+1:]
+	}
+
+	pattern := rule.pattern
+	if !rule.anchored {
+		pattern = "**/" + pattern
+	}
+
+	matched, _ := doublestar.Match(pattern, subPath)
+	return matched
+}
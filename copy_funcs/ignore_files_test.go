@@ -0,0 +1,144 @@
+package copy_funcs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		wantOk bool
+		want   ignoreRule
+	}{
+		{"blank", "   ", false, ignoreRule{}},
+		{"comment", "# curated out", false, ignoreRule{}},
+		{"simple glob", "*.txt", true, ignoreRule{pattern: "*.txt"}},
+		{"negation", "!keep.txt", true, ignoreRule{pattern: "keep.txt", negate: true}},
+		{"escaped bang", `\!important.txt`, true, ignoreRule{pattern: "!important.txt"}},
+		{"directory only", "saves/", true, ignoreRule{pattern: "saves", dirOnly: true}},
+		{"anchored", "/media/videos", true, ignoreRule{pattern: "media/videos", anchored: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseIgnoreLine(tt.line, "")
+			if ok != tt.wantOk {
+				t.Fatalf("parseIgnoreLine() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && got != tt.want {
+				t.Errorf("parseIgnoreLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreRules(t *testing.T) {
+	sourceRoot := t.TempDir()
+
+	// sourceRoot/
+	//   ├── .romcopyignore        (*.bak)
+	//   └── snes/
+	//       ├── .romcopyignore    (*.txt, !keep.txt, saves/, /media/videos)
+	//       └── hacks/
+	//           └── .romcopyignore (*.sfc)
+	ignoreFiles := map[string]string{
+		".romcopyignore":                  "*.bak\n",
+		"snes/.romcopyignore":             "# junk\n*.txt\n!keep.txt\nsaves/\n/media/videos\n",
+		"snes/hacks/.romcopyignore":       "*.sfc\n",
+		"snes/hacks/not_a_hack/readme.md": "",
+	}
+	for name, content := range ignoreFiles {
+		path := filepath.Join(sourceRoot, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", path, err)
+		}
+	}
+
+	rules, err := LoadIgnoreRules(sourceRoot, "snes")
+	if err != nil {
+		t.Fatalf("LoadIgnoreRules() error = %v", err)
+	}
+
+	if rules.Len() != 6 {
+		t.Errorf("expected 6 rules, got %d", rules.Len())
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"game.sfc", false, false},
+		{"game.sfc.bak", false, true},
+		{"notes.txt", false, true},
+		{"keep.txt", false, false},
+		{"deep/nested/notes.txt", false, true},
+		{"saves", true, true},
+		{"saves/game.srm", false, true},
+		{"saves.sfc", false, false},
+		{"media/videos", true, true},
+		{"media/videos/game.mp4", false, true},
+		{"other/media/videos/game.mp4", false, false},
+		{"hacks/game.sfc", false, true},
+		{"hacks/not_a_hack/readme.md", false, false},
+		{".romcopyignore", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := rules.Ignored(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyFilesHonorsIgnoreRules(t *testing.T) {
+	sourceRoot := t.TempDir()
+	destDir := t.TempDir()
+
+	files := map[string]string{
+		"gba/.romcopyignore":   "*.txt\nsaves/\n",
+		"gba/game.gba":         "rom",
+		"gba/readme.txt":       "junk",
+		"gba/saves/game.sav":   "save",
+		"gba/images/game.png":  "image",
+		"gba/images/notes.txt": "junk",
+	}
+	for name, content := range files {
+		path := filepath.Join(sourceRoot, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", path, err)
+		}
+	}
+
+	rules, err := LoadIgnoreRules(sourceRoot, "gba")
+	if err != nil {
+		t.Fatalf("LoadIgnoreRules() error = %v", err)
+	}
+
+	if _, err := CopyFiles(filepath.Join(sourceRoot, "gba"), destDir, CopyOptions{Ignore: rules}); err != nil {
+		t.Fatalf("CopyFiles() error = %v", err)
+	}
+
+	for _, want := range []string{"game.gba", "images/game.png"} {
+		if _, err := os.Stat(filepath.Join(destDir, want)); err != nil {
+			t.Errorf("expected %s to be copied", want)
+		}
+	}
+
+	for _, missing := range []string{".romcopyignore", "readme.txt", "saves", "images/notes.txt"} {
+		if _, err := os.Stat(filepath.Join(destDir, missing)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be ignored", missing)
+		}
+	}
+}
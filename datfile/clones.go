@@ -0,0 +1,70 @@
+package datfile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/romtags"
+)
+
+// Parent returns the name of the game at the root of game's clone-of chain; parents are their own root.
+// Every regional release and revision of a game shares the same parent.
+func (m *Matcher) Parent(game *Game) string {
+	seen := make(map[string]bool)
+	for game.CloneOf != "" && !seen[game.Name] {
+		seen[game.Name] = true
+		parent, ok := m.games[game.CloneOf]
+		if !ok {
+			// the DAT references a parent it doesn't list; group under the referenced name
+			return game.CloneOf
+		}
+		game = parent
+	}
+	return game.Name
+}
+
+// HasClones reports whether any loaded DAT records parent/clone relationships
+func (m *Matcher) HasClones() bool {
+	for _, game := range m.games {
+		if game.CloneOf != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// PreferredGame picks the single best release from candidates, which should all share a parent. Releases are
+// ranked by the first of their regions to appear in regionPriority (case-insensitive); releases in none of
+// the regions rank last. Ties go to the parent over clones, then to the first name alphabetically.
+func PreferredGame(candidates []*Game, regionPriority []string) *Game {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	ranked := make([]*Game, len(candidates))
+	copy(ranked, candidates)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		rankI, rankJ := regionRank(ranked[i], regionPriority), regionRank(ranked[j], regionPriority)
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		if (ranked[i].CloneOf == "") != (ranked[j].CloneOf == "") {
+			return ranked[i].CloneOf == ""
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	return ranked[0]
+}
+
+// position in regionPriority of the best-ranked region in the game's name
+func regionRank(game *Game, regionPriority []string) int {
+	best := len(regionPriority)
+	for _, region := range romtags.Parse(game.Name).Regions {
+		for i, preferred := range regionPriority {
+			if i < best && strings.EqualFold(region, strings.TrimSpace(preferred)) {
+				best = i
+			}
+		}
+	}
+	return best
+}
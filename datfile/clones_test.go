@@ -0,0 +1,62 @@
+package datfile
+
+import (
+	"strings"
+	"testing"
+)
+
+const clonesDat = `<datafile>
+	<game name="Sonic (USA, Europe)"><rom name="Sonic (USA, Europe).md" size="1" crc="00000001"/></game>
+	<game name="Sonic (Japan)" cloneof="Sonic (USA, Europe)"><rom name="Sonic (Japan).md" size="1" crc="00000002"/></game>
+	<game name="Sonic (Brazil)" cloneof="Sonic (Japan)"><rom name="Sonic (Brazil).md" size="1" crc="00000003"/></game>
+	<game name="Columns (Japan)" cloneof="Columns (World)"><rom name="Columns (Japan).md" size="1" crc="00000004"/></game>
+</datafile>`
+
+func TestParent(t *testing.T) {
+	dat, err := Parse(strings.NewReader(clonesDat))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	matcher := NewMatcher(dat)
+
+	expected := map[string]string{
+		"Sonic (USA, Europe)": "Sonic (USA, Europe)",
+		"Sonic (Japan)":       "Sonic (USA, Europe)",
+		"Sonic (Brazil)":      "Sonic (USA, Europe)",
+		"Columns (Japan)":     "Columns (World)",
+	}
+	for i := range dat.Games {
+		game := &dat.Games[i]
+		if got := matcher.Parent(game); got != expected[game.Name] {
+			t.Errorf("Parent(%q) = %q, want %q", game.Name, got, expected[game.Name])
+		}
+	}
+
+	if !matcher.HasClones() {
+		t.Error("expected HasClones() to be true")
+	}
+}
+
+func TestPreferredGame(t *testing.T) {
+	usa := &Game{Name: "Sonic (USA, Europe)"}
+	japan := &Game{Name: "Sonic (Japan)", CloneOf: "Sonic (USA, Europe)"}
+	brazil := &Game{Name: "Sonic (Brazil)", CloneOf: "Sonic (USA, Europe)"}
+	candidates := []*Game{brazil, japan, usa}
+
+	tests := []struct {
+		priority []string
+		want     *Game
+	}{
+		{[]string{"USA", "Europe", "Japan"}, usa},
+		{[]string{"japan", "usa"}, japan},
+		{[]string{"Europe"}, usa},
+		{[]string{"Korea"}, usa},
+		{nil, usa},
+	}
+
+	for _, tt := range tests {
+		if got := PreferredGame(candidates, tt.priority); got != tt.want {
+			t.Errorf("PreferredGame(%v) = %s, want %s", tt.priority, got.Name, tt.want.Name)
+		}
+	}
+}
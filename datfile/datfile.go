@@ -0,0 +1,141 @@
+package datfile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ROM dump statuses used by No-Intro and Redump
+const (
+	RomStatusGood     = "good"
+	RomStatusBadDump  = "baddump"
+	RomStatusNoDump   = "nodump"
+	RomStatusVerified = "verified"
+)
+
+// Dat is a parsed Logiqx XML DAT file, as published by No-Intro and Redump
+type Dat struct {
+	Name        string
+	Description string
+	Version     string
+	Games       []Game
+}
+
+// Game is a single game entry in a DAT, made up of one or more ROMs (e.g. a disc image's .cue and .bin files)
+type Game struct {
+	Name        string
+	Description string
+	// name of the parent game if this is a clone (e.g. a regional variant); empty for parents
+	CloneOf string
+	Roms    []Rom
+}
+
+// Rom is a single file belonging to a game
+type Rom struct {
+	Name string
+	Size int64
+	// lowercase hex digests; any may be empty if the DAT doesn't list it
+	CRC  string
+	MD5  string
+	SHA1 string
+	// one of the RomStatus* constants; empty means good
+	Status string
+}
+
+// mirrors the Logiqx XML structure; MAME-derived DATs use 'machine' rather than 'game'
+type xmlDatafile struct {
+	Header struct {
+		Name        string `xml:"name"`
+		Description string `xml:"description"`
+		Version     string `xml:"version"`
+	} `xml:"header"`
+	Games    []xmlGame `xml:"game"`
+	Machines []xmlGame `xml:"machine"`
+}
+
+type xmlGame struct {
+	Name        string   `xml:"name,attr"`
+	CloneOf     string   `xml:"cloneof,attr"`
+	Description string   `xml:"description"`
+	Roms        []xmlRom `xml:"rom"`
+}
+
+type xmlRom struct {
+	Name   string `xml:"name,attr"`
+	Size   int64  `xml:"size,attr"`
+	CRC    string `xml:"crc,attr"`
+	MD5    string `xml:"md5,attr"`
+	SHA1   string `xml:"sha1,attr"`
+	Status string `xml:"status,attr"`
+}
+
+// Load reads and parses the DAT file at filePath
+func Load(filePath string) (*Dat, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DAT file %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	dat, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse DAT file %s: %w", filePath, err)
+	}
+	return dat, nil
+}
+
+// Parse reads a Logiqx XML DAT
+func Parse(r io.Reader) (*Dat, error) {
+	var raw xmlDatafile
+	decoder := xml.NewDecoder(r)
+	// some DAT tools declare encodings other than UTF-8, but in practice the content is ASCII
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) { return input, nil }
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	dat := &Dat{
+		Name:        strings.TrimSpace(raw.Header.Name),
+		Description: strings.TrimSpace(raw.Header.Description),
+		Version:     strings.TrimSpace(raw.Header.Version),
+		Games:       make([]Game, 0, len(raw.Games)+len(raw.Machines)),
+	}
+
+	for _, rawGame := range append(raw.Games, raw.Machines...) {
+		game := Game{
+			Name:        rawGame.Name,
+			Description: strings.TrimSpace(rawGame.Description),
+			CloneOf:     rawGame.CloneOf,
+			Roms:        make([]Rom, 0, len(rawGame.Roms)),
+		}
+		for _, rawRom := range rawGame.Roms {
+			game.Roms = append(game.Roms, Rom{
+				Name:   rawRom.Name,
+				Size:   rawRom.Size,
+				CRC:    normalizeHash(rawRom.CRC),
+				MD5:    normalizeHash(rawRom.MD5),
+				SHA1:   normalizeHash(rawRom.SHA1),
+				Status: strings.ToLower(strings.TrimSpace(rawRom.Status)),
+			})
+		}
+		dat.Games = append(dat.Games, game)
+	}
+
+	if len(dat.Games) == 0 {
+		return nil, fmt.Errorf("no games found; is this a Logiqx XML DAT?")
+	}
+
+	return dat, nil
+}
+
+// DATs write hashes in either case, and CRCs sometimes without leading zeros
+func normalizeHash(hash string) string {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if hash != "" && len(hash) < 8 {
+		hash = strings.Repeat("0", 8-len(hash)) + hash
+	}
+	return hash
+}
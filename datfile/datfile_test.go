@@ -0,0 +1,72 @@
+package datfile
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDat = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE datafile PUBLIC "-//Logiqx//DTD ROM Management Datafile//EN" "http://www.logiqx.com/dats/datafile.dtd">
+<datafile>
+	<header>
+		<name>Nintendo - Nintendo Entertainment System (Headered)</name>
+		<description>Nintendo - Nintendo Entertainment System (Headered)</description>
+		<version>20240101-000000</version>
+	</header>
+	<game name="Good Game (USA)">
+		<description>Good Game (USA)</description>
+		<rom name="Good Game (USA).nes" size="8" crc="71C35F48" sha1="078BA8710C0A78150C00D245CC792B4557EE1B32" status="verified"/>
+	</game>
+	<game name="Good Game (Europe)" cloneof="Good Game (USA)">
+		<description>Good Game (Europe)</description>
+		<rom name="Good Game (Europe).nes" size="5" crc="d9583520"/>
+	</game>
+	<game name="Bad Game (Japan)">
+		<description>Bad Game (Japan)</description>
+		<rom name="Bad Game (Japan).nes" size="7" crc="087ef64d" sha1="8d381f25bab61a7e9d799ad3b1e6f13a9bac5bb3" status="baddump"/>
+	</game>
+</datafile>
+`
+
+func TestParse(t *testing.T) {
+	dat, err := Parse(strings.NewReader(testDat))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if dat.Name != "Nintendo - Nintendo Entertainment System (Headered)" || dat.Version != "20240101-000000" {
+		t.Errorf("unexpected header: %q %q", dat.Name, dat.Version)
+	}
+	if len(dat.Games) != 3 {
+		t.Fatalf("expected 3 games, got %d", len(dat.Games))
+	}
+
+	rom := dat.Games[0].Roms[0]
+	if rom.CRC != "71c35f48" || rom.SHA1 != "078ba8710c0a78150c00d245cc792b4557ee1b32" || rom.Size != 8 {
+		t.Errorf("hashes weren't normalized: %+v", rom)
+	}
+	if dat.Games[1].CloneOf != "Good Game (USA)" {
+		t.Errorf("expected clone of 'Good Game (USA)', got %q", dat.Games[1].CloneOf)
+	}
+	if dat.Games[2].Roms[0].Status != RomStatusBadDump {
+		t.Errorf("expected bad dump status, got %q", dat.Games[2].Roms[0].Status)
+	}
+}
+
+func TestParseMachines(t *testing.T) {
+	dat, err := Parse(strings.NewReader(`<datafile><machine name="pacman"><rom name="pacman.6e" size="4096" crc="c1e6ab10"/></machine></datafile>`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(dat.Games) != 1 || dat.Games[0].Name != "pacman" {
+		t.Errorf("expected machine entries to be read as games, got %+v", dat.Games)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"not xml", "<datafile></datafile>"} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%q) expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package datfile
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MatchStatus is how a file compares against the loaded DATs
+type MatchStatus int
+
+const (
+	// the file's checksums match a known good dump
+	MatchVerified MatchStatus = iota
+	// the file's checksums match a dump the DAT marks as bad
+	MatchBadDump
+	// a ROM by this name is in the DAT, but the file's checksums don't match it; usually a corrupt or
+	// modified (e.g. patched or headered) dump
+	MatchMismatch
+	// neither the file's checksums nor its name are in the DAT
+	MatchUnknown
+)
+
+func (s MatchStatus) String() string {
+	switch s {
+	case MatchVerified:
+		return "verified"
+	case MatchBadDump:
+		return "bad dump"
+	case MatchMismatch:
+		return "checksum mismatch"
+	default:
+		return "unknown"
+	}
+}
+
+// Match is the result of looking a file up in the loaded DATs
+type Match struct {
+	Status MatchStatus
+	// the game and ROM matched; nil for MatchUnknown
+	Game *Game
+	Rom  *Rom
+}
+
+// a ROM along with the game it belongs to
+type datEntry struct {
+	game *Game
+	rom  *Rom
+}
+
+// Matcher looks up files by checksum across one or more DATs
+type Matcher struct {
+	bySHA1    map[string][]datEntry
+	byCRCSize map[string][]datEntry
+	byName    map[string][]datEntry
+	// every game by name, to follow clone-of relationships
+	games map[string]*Game
+	// lowercase extensions (e.g. '.nes') of every ROM in the DATs, to tell ROMs apart from other files
+	extensions map[string]bool
+}
+
+// NewMatcher indexes every ROM in dats
+func NewMatcher(dats ...*Dat) *Matcher {
+	m := &Matcher{
+		bySHA1:     make(map[string][]datEntry),
+		byCRCSize:  make(map[string][]datEntry),
+		byName:     make(map[string][]datEntry),
+		games:      make(map[string]*Game),
+		extensions: make(map[string]bool),
+	}
+
+	for _, dat := range dats {
+		for gi := range dat.Games {
+			game := &dat.Games[gi]
+			m.games[game.Name] = game
+			for ri := range game.Roms {
+				rom := &game.Roms[ri]
+				entry := datEntry{game: game, rom: rom}
+
+				if rom.SHA1 != "" {
+					m.bySHA1[rom.SHA1] = append(m.bySHA1[rom.SHA1], entry)
+				}
+				if rom.CRC != "" {
+					key := crcSizeKey(rom.CRC, rom.Size)
+					m.byCRCSize[key] = append(m.byCRCSize[key], entry)
+				}
+				name := strings.ToLower(path.Base(strings.ReplaceAll(rom.Name, "\\", "/")))
+				m.byName[name] = append(m.byName[name], entry)
+				if ext := path.Ext(name); ext != "" {
+					m.extensions[ext] = true
+				}
+			}
+		}
+	}
+
+	return m
+}
+
+func crcSizeKey(crc string, size int64) string {
+	return fmt.Sprintf("%s:%d", crc, size)
+}
+
+// Covers reports whether fileName has an extension used by any ROM in the DATs. Files that aren't covered
+// (artwork, gamelists, etc.) aren't expected to be in the DAT at all.
+func (m *Matcher) Covers(fileName string) bool {
+	return m.extensions[strings.ToLower(path.Ext(fileName))]
+}
+
+// Match looks up a file by its name, size, and lowercase hex CRC32 and SHA1. sha1 may be empty if it wasn't
+// computed, in which case the match rests on CRC32 and size alone.
+func (m *Matcher) Match(fileName string, size int64, crc string, sha1 string) Match {
+	if sha1 != "" {
+		if entries := m.bySHA1[sha1]; len(entries) > 0 {
+			return matchEntry(entries[0])
+		}
+	}
+
+	for _, entry := range m.byCRCSize[crcSizeKey(crc, size)] {
+		// a CRC collision with a different SHA1 isn't the same dump
+		if sha1 == "" || entry.rom.SHA1 == "" || entry.rom.SHA1 == sha1 {
+			return matchEntry(entry)
+		}
+	}
+
+	if entries := m.byName[strings.ToLower(path.Base(fileName))]; len(entries) > 0 {
+		return Match{Status: MatchMismatch, Game: entries[0].game, Rom: entries[0].rom}
+	}
+
+	return Match{Status: MatchUnknown}
+}
+
+func matchEntry(entry datEntry) Match {
+	status := MatchVerified
+	if entry.rom.Status == RomStatusBadDump {
+		status = MatchBadDump
+	}
+	return Match{Status: status, Game: entry.game, Rom: entry.rom}
+}
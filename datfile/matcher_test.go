@@ -0,0 +1,59 @@
+package datfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	dat, err := Parse(strings.NewReader(testDat))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	matcher := NewMatcher(dat)
+
+	tests := []struct {
+		name     string
+		fileName string
+		size     int64
+		crc      string
+		sha1     string
+		status   MatchStatus
+		game     string
+	}{
+		{"by sha1 under another name", "renamed.nes", 8, "71c35f48", "078ba8710c0a78150c00d245cc792b4557ee1b32", MatchVerified, "Good Game (USA)"},
+		{"by crc and size without sha1", "Good Game (Europe).nes", 5, "d9583520", "", MatchVerified, "Good Game (Europe)"},
+		{"crc only dat entry", "Good Game (Europe).nes", 5, "d9583520", "d0941e68da8f38151ff86a61fc59f7c5cf9fcaa2", MatchVerified, "Good Game (Europe)"},
+		{"bad dump", "Bad Game (Japan).nes", 7, "087ef64d", "8d381f25bab61a7e9d799ad3b1e6f13a9bac5bb3", MatchBadDump, "Bad Game (Japan)"},
+		{"crc collision with different sha1", "x.nes", 8, "71c35f48", "0000000000000000000000000000000000000000", MatchUnknown, ""},
+		{"known name with other contents", "good game (usa).nes", 8, "deadbeef", "", MatchMismatch, "Good Game (USA)"},
+		{"unknown", "Homebrew.nes", 3, "12345678", "", MatchUnknown, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := matcher.Match(tt.fileName, tt.size, tt.crc, tt.sha1)
+			if match.Status != tt.status {
+				t.Errorf("Match() status = %s, want %s", match.Status, tt.status)
+			}
+			if tt.game != "" && (match.Game == nil || match.Game.Name != tt.game) {
+				t.Errorf("Match() game = %+v, want %s", match.Game, tt.game)
+			}
+		})
+	}
+}
+
+func TestMatcherCovers(t *testing.T) {
+	dat, err := Parse(strings.NewReader(testDat))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	matcher := NewMatcher(dat)
+
+	if !matcher.Covers("Homebrew.NES") {
+		t.Error("expected .nes files to be covered")
+	}
+	if matcher.Covers("gamelist.xml") {
+		t.Error("expected .xml files not to be covered")
+	}
+}
@@ -0,0 +1,211 @@
+package dedupe
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/archive"
+	"github.com/jkingsman/ROMCopyEngine/copy_funcs"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+	"github.com/jkingsman/ROMCopyEngine/romtags"
+)
+
+// Kind is how the files in a Group duplicate each other
+type Kind int
+
+const (
+	// the files are byte-for-byte identical, or are zips of identical files
+	Identical Kind = iota
+	// the files are releases of the same game for the same regions, differing only in revision or release tags,
+	// e.g. 'Zelda (USA).nes' and 'Zelda (USA) (Rev 1).nes'
+	Versions
+)
+
+func (k Kind) String() string {
+	if k == Identical {
+		return "identical"
+	}
+	return "versions of the same game"
+}
+
+// Group is a set of files that duplicate each other
+type Group struct {
+	Kind Kind
+	// paths relative to the mapping's source folder, best first: the first is the one to keep
+	Files []string
+}
+
+// files that differ are almost always told apart by their first bytes (ROM headers hold the title), so only
+// files that agree on this much are hashed in full
+const prefixSize = 64 * 1024
+
+// Find looks for duplicates among files, which are relative to sourcePath. Identical files are found by content:
+// files of equal size and extension are compared by their first 64KB, and only those that agree there are
+// hashed in full with fullHash, so a large source isn't read end to end. Zips are instead compared by the sizes
+// and CRC32s of their members, read from their directories, so the same ROMs zipped differently still match.
+// Versions are then found by name among the files left after keeping the best of each identical group.
+func Find(fs fsys.FS, sourcePath string, files []copy_funcs.ResolvedFile, fullHash func(path string) ([]byte, error)) ([]Group, error) {
+	groups := make([]Group, 0)
+	duplicate := make(map[string]bool)
+
+	// files are only duplicates if they play the same role, so a ROM is never dropped for matching, say, a
+	// placeholder image
+	type sizeKey struct {
+		size int64
+		ext  string
+	}
+	bySize := make(map[sizeKey][]string)
+	byContents := make(map[string][]string)
+	for _, f := range files {
+		if f.Size > 0 && archive.IsZip(f.RelPath) {
+			// a zip that can't be read is compared byte for byte like any other file
+			if members, err := archive.ReadZip(fs, filepath.Join(sourcePath, f.RelPath)); err == nil && len(members) > 0 {
+				fingerprint := archive.Fingerprint(members)
+				byContents[fingerprint] = append(byContents[fingerprint], f.RelPath)
+				continue
+			}
+		}
+		if f.Size > 0 {
+			key := sizeKey{size: f.Size, ext: strings.ToLower(filepath.Ext(f.RelPath))}
+			bySize[key] = append(bySize[key], f.RelPath)
+		}
+	}
+
+	for key, sameSize := range bySize {
+		if len(sameSize) < 2 {
+			continue
+		}
+
+		byPrefix, err := groupByHash(sameSize, func(relPath string) ([]byte, error) {
+			return hashPrefix(fs, filepath.Join(sourcePath, relPath))
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, samePrefix := range byPrefix {
+			identical := [][]string{samePrefix}
+			// the prefix is the whole file for small files
+			if key.size > prefixSize {
+				identical, err = groupByHash(samePrefix, func(relPath string) ([]byte, error) {
+					return fullHash(filepath.Join(sourcePath, relPath))
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			for _, same := range identical {
+				sortBest(same)
+				groups = append(groups, Group{Kind: Identical, Files: same})
+				for _, relPath := range same[1:] {
+					duplicate[relPath] = true
+				}
+			}
+		}
+	}
+
+	for _, same := range byContents {
+		if len(same) < 2 {
+			continue
+		}
+		sortBest(same)
+		groups = append(groups, Group{Kind: Identical, Files: same})
+		for _, relPath := range same[1:] {
+			duplicate[relPath] = true
+		}
+	}
+
+	byGame := make(map[string][]string)
+	for _, f := range files {
+		if duplicate[f.RelPath] {
+			continue
+		}
+		if key, ok := gameKey(f.RelPath); ok {
+			byGame[key] = append(byGame[key], f.RelPath)
+		}
+	}
+	for _, versions := range byGame {
+		if len(versions) > 1 {
+			sortBest(versions)
+			groups = append(groups, Group{Kind: Versions, Files: versions})
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Files[0] < groups[j].Files[0] })
+	return groups, nil
+}
+
+// splits relPaths into groups of two or more with the same hash; files with a unique hash are dropped
+func groupByHash(relPaths []string, hash func(relPath string) ([]byte, error)) ([][]string, error) {
+	byHash := make(map[string][]string)
+	for _, relPath := range relPaths {
+		sum, err := hash(relPath)
+		if err != nil {
+			return nil, err
+		}
+		byHash[string(sum)] = append(byHash[string(sum)], relPath)
+	}
+
+	groups := make([][]string, 0)
+	for _, same := range byHash {
+		if len(same) > 1 {
+			groups = append(groups, same)
+		}
+	}
+	return groups, nil
+}
+
+func hashPrefix(fs fsys.FS, filePath string) ([]byte, error) {
+	file, err := fs.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hasher := sha1.New()
+	if _, err := io.CopyN(hasher, file, prefixSize); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return hasher.Sum(nil), nil
+}
+
+// files are versions of the same game if they're in the same folder with the same extension, title, and regions
+func gameKey(relPath string) (string, bool) {
+	name := filepath.Base(relPath)
+	tags := romtags.Parse(name)
+	if tags.Title == "" {
+		return "", false
+	}
+
+	key := strings.Join([]string{
+		filepath.ToSlash(filepath.Dir(relPath)),
+		tags.Title,
+		strings.Join(tags.Regions, ","),
+		filepath.Ext(name),
+	}, "\x00")
+	return strings.ToLower(key), true
+}
+
+// orders relPaths best first: fewest release flags (beta, bad dump, etc.), then latest revision, then shortest
+// name, then alphabetically
+func sortBest(relPaths []string) {
+	sort.Slice(relPaths, func(i, j int) bool {
+		a := romtags.Parse(filepath.Base(relPaths[i]))
+		b := romtags.Parse(filepath.Base(relPaths[j]))
+		if len(a.Releases) != len(b.Releases) {
+			return len(a.Releases) < len(b.Releases)
+		}
+		if cmp := romtags.CompareRevisions(a.Revision, b.Revision); cmp != 0 {
+			return cmp > 0
+		}
+		if len(relPaths[i]) != len(relPaths[j]) {
+			return len(relPaths[i]) < len(relPaths[j])
+		}
+		return relPaths[i] < relPaths[j]
+	})
+}
@@ -0,0 +1,114 @@
+package dedupe
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/copy_funcs"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestFind(t *testing.T) {
+	mem := fsys.NewMemFS()
+	sourceDir := filepath.Join(t.TempDir(), "nes")
+	mem.MkdirAll(filepath.Join(sourceDir, "images"), 0755)
+
+	big := bytes.Repeat([]byte("x"), prefixSize+10)
+	bigVariant := append(append([]byte(nil), big[:prefixSize+5]...), []byte("yyyyy")...)
+	contents := map[string][]byte{
+		"Zelda (USA).nes":                []byte("zelda"),
+		"Zelda (USA) (Rev 1).nes":        []byte("zelda rev 1"),
+		"Zelda (USA) (Beta) (Rev 2).nes": []byte("zelda beta"),
+		"Zelda (Japan).nes":              []byte("zelda jp"),
+		"Copy of Metroid.nes":            []byte("metroid"),
+		"Metroid.nes":                    []byte("metroid"),
+		"Kirby.nes":                      []byte("kirby!!"),
+		"Big (USA).nes":                  big,
+		"Big Copy.nes":                   big,
+		"Big Hack.nes":                   bigVariant,
+		"images/Zelda (USA).png":         []byte("zelda"),
+	}
+
+	files := make([]copy_funcs.ResolvedFile, 0)
+	for name, data := range contents {
+		mem.WriteFile(filepath.Join(sourceDir, filepath.FromSlash(name)), data, 0644)
+		files = append(files, copy_funcs.ResolvedFile{RelPath: filepath.FromSlash(name), Size: int64(len(data))})
+	}
+
+	fullHashes := 0
+	fullHash := func(path string) ([]byte, error) {
+		fullHashes++
+		data, err := mem.ReadFile(path)
+		sum := sha1.Sum(data)
+		return sum[:], err
+	}
+
+	groups, err := Find(mem, sourceDir, files, fullHash)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	expected := []Group{
+		{Kind: Identical, Files: []string{"Big Copy.nes", "Big (USA).nes"}},
+		{Kind: Identical, Files: []string{"Metroid.nes", "Copy of Metroid.nes"}},
+		{Kind: Versions, Files: []string{"Zelda (USA) (Rev 1).nes", "Zelda (USA).nes", "Zelda (USA) (Beta) (Rev 2).nes"}},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Find() = %+v, want %+v", groups, expected)
+	}
+
+	// only the three large files agree on their first 64KB, and so need hashing in full
+	if fullHashes != 3 {
+		t.Errorf("hashed %d files in full, want 3", fullHashes)
+	}
+}
+
+func TestFindZips(t *testing.T) {
+	mem := fsys.NewMemFS()
+	sourceDir := filepath.Join(t.TempDir(), "nes")
+	mem.MkdirAll(sourceDir, 0755)
+
+	zipOf := func(name string, content string, method uint16) []byte {
+		var buf bytes.Buffer
+		writer := zip.NewWriter(&buf)
+		entry, _ := writer.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		entry.Write([]byte(content))
+		writer.Close()
+		return buf.Bytes()
+	}
+	contents := map[string][]byte{
+		"Tetris.zip":         zipOf("Tetris.nes", "tetris", zip.Deflate),
+		"Tetris (Alt).zip":   zipOf("Tetris (Alt).nes", "tetris", zip.Store),
+		"Tetris Hack.zip":    zipOf("Tetris.nes", "tetriz", zip.Deflate),
+		"Not Really Zip.zip": []byte("plain"),
+		"Plain Copy.zip":     []byte("plain"),
+	}
+
+	files := make([]copy_funcs.ResolvedFile, 0)
+	for name, data := range contents {
+		mem.WriteFile(filepath.Join(sourceDir, name), data, 0644)
+		files = append(files, copy_funcs.ResolvedFile{RelPath: name, Size: int64(len(data))})
+	}
+
+	groups, err := Find(mem, sourceDir, files, func(path string) ([]byte, error) {
+		t.Errorf("zips shouldn't be hashed in full, but %s was", path)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+
+	// the zips of the same ROM match despite their different bytes; files that aren't really zips are compared
+	// byte for byte
+	expected := []Group{
+		{Kind: Identical, Files: []string{"Plain Copy.zip", "Not Really Zip.zip"}},
+		{Kind: Identical, Files: []string{"Tetris.zip", "Tetris (Alt).zip"}},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Find() = %+v, want %+v", groups, expected)
+	}
+}
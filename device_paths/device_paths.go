@@ -0,0 +1,133 @@
+package device_paths
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Style is how a file written for the device (a game list, playlist, or cue sheet) spells the path of a file it
+// refers to
+type Style int
+
+const (
+	// relative to the referring file's folder and starting with './', e.g. './images/Zelda.png'; what
+	// EmulationStation and its forks write in game lists
+	DotRelative Style = iota
+	// relative to the referring file's folder, e.g. 'discs/Final Fantasy VII (Disc 1).chd'; what playlists and
+	// cue sheets use
+	Relative
+	// where the device sees the file once the target is mounted, e.g. '/mnt/SDCARD/Roms/SFC/Zelda.sfc'
+	Absolute
+)
+
+// Rules are what the device expects of paths written for it
+type Rules struct {
+	// where the device mounts the target, e.g. '/mnt/SDCARD'; empty if unknown, in which case Absolute paths
+	// are written DotRelative instead
+	MountPath string
+	// how game lists refer to games and artwork
+	ListStyle Style
+}
+
+// Folder is a platform folder on the target, as the files written into it refer to it
+type Folder struct {
+	// slash separated and relative to the target root, e.g. 'Roms/SFC'; empty if unknown, in which case Absolute
+	// paths are written DotRelative instead
+	Path string
+	// other names the folder has gone by, e.g. 'snes' on the device a game list was scraped on, for reading
+	// absolute paths; the last part of Path is always one
+	Names []string
+	Rules Rules
+}
+
+// Write returns how a file in the slash separated folder from, relative to the platform folder, should refer to
+// relPath, also relative to the platform folder
+func (f Folder) Write(from string, relPath string, style Style) string {
+	relPath = path.Clean(relPath)
+	if style == Absolute {
+		if f.Rules.MountPath != "" && f.Path != "" {
+			return path.Join(f.Rules.MountPath, f.Path, relPath)
+		}
+		style = DotRelative
+	}
+
+	if from = path.Clean(from); from != "." {
+		if rel, err := filepath.Rel(filepath.FromSlash(from), filepath.FromSlash(relPath)); err == nil {
+			relPath = filepath.ToSlash(rel)
+		}
+	}
+	if style == DotRelative && !strings.HasPrefix(relPath, "../") {
+		return "./" + relPath
+	}
+	return relPath
+}
+
+// Read turns a path as a file at the top of the folder writes it into one relative to the folder, slash
+// separated. Relative paths are taken as they are; absolute ones (e.g. '/home/pi/RetroPie/roms/snes/x.sfc' or
+// '~/roms/snes/x.sfc') are resolved from where the device mounts the folder, or failing that, from the last part
+// of them named like the folder. It returns false if the path is empty or isn't within the folder.
+func (f Folder) Read(written string) (string, bool) {
+	written = strings.ReplaceAll(strings.TrimSpace(written), "\\", "/")
+	if written == "" {
+		return "", false
+	}
+	if isAbs(written) {
+		rest, ok := f.trimAbs(written)
+		if !ok {
+			return "", false
+		}
+		written = rest
+	}
+	return within(path.Clean(written))
+}
+
+// strips the part of an absolute path up to the folder
+func (f Folder) trimAbs(written string) (string, bool) {
+	lower := strings.ToLower(written)
+	if f.Rules.MountPath != "" && f.Path != "" {
+		prefix := strings.ToLower(path.Join(f.Rules.MountPath, f.Path)) + "/"
+		if strings.HasPrefix(lower, prefix) {
+			return written[len(prefix):], true
+		}
+	}
+
+	names := f.Names
+	if f.Path != "" {
+		names = append([]string{path.Base(f.Path)}, names...)
+	}
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		marker := "/" + strings.ToLower(name) + "/"
+		if cut := strings.LastIndex(lower, marker); cut >= 0 {
+			return written[cut+len(marker):], true
+		}
+	}
+	return "", false
+}
+
+// Resolve returns the file a reference in a file in the slash separated folder from, relative to the platform
+// folder, points to, also relative to the platform folder; the inverse of Write with Relative or DotRelative.
+// References may use either slash; it returns false for URLs, absolute paths, and references outside the folder.
+func Resolve(from string, ref string) (string, bool) {
+	ref = strings.ReplaceAll(strings.TrimSpace(ref), "\\", "/")
+	if ref == "" || strings.Contains(ref, "://") || isAbs(ref) {
+		return "", false
+	}
+	return within(path.Join(from, ref))
+}
+
+// whether a slash separated path is absolute on the device or any machine a file was made on, including
+// Windows drive letters and home-relative paths
+func isAbs(written string) bool {
+	return strings.HasPrefix(written, "/") || strings.HasPrefix(written, "~/") || (len(written) > 1 && written[1] == ':')
+}
+
+func within(relPath string) (string, bool) {
+	if relPath == "." || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		return "", false
+	}
+	return relPath, true
+}
@@ -0,0 +1,94 @@
+package device_paths
+
+import "testing"
+
+func TestWrite(t *testing.T) {
+	onion := Folder{Path: "Roms/SFC", Rules: Rules{MountPath: "/mnt/SDCARD"}}
+	tests := []struct {
+		name    string
+		folder  Folder
+		from    string
+		relPath string
+		style   Style
+		want    string
+	}{
+		{"game list", onion, ".", "Zelda.sfc", DotRelative, "./Zelda.sfc"},
+		{"game list artwork", onion, ".", "Imgs/Zelda.png", DotRelative, "./Imgs/Zelda.png"},
+		{"playlist", onion, ".", "discs/FF7 (Disc 1).chd", Relative, "discs/FF7 (Disc 1).chd"},
+		{"sheet in a subfolder", onion, "discs", "tracks/Track 1.bin", Relative, "../tracks/Track 1.bin"},
+		{"sheet beside its track", onion, "discs", "discs/Track 1.bin", Relative, "Track 1.bin"},
+		{"absolute", onion, "discs", "Zelda.sfc", Absolute, "/mnt/SDCARD/Roms/SFC/Zelda.sfc"},
+		{"absolute on an unknown device", Folder{Path: "Roms/SFC"}, ".", "Zelda.sfc", Absolute, "./Zelda.sfc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.folder.Write(tt.from, tt.relPath, tt.style); got != tt.want {
+				t.Errorf("Write(%q, %q) = %q, want %q", tt.from, tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	folder := Folder{Path: "Roms/SFC", Names: []string{"snes"}, Rules: Rules{MountPath: "/mnt/SDCARD"}}
+	tests := []struct {
+		written string
+		want    string
+		wantOk  bool
+	}{
+		{"./Game.sfc", "Game.sfc", true},
+		{"media/images/Game.png", "media/images/Game.png", true},
+		{".\\media\\Game.png", "media/Game.png", true},
+		{"/home/pi/RetroPie/roms/SNES/sub/Game.sfc", "sub/Game.sfc", true},
+		{"~/roms/snes/Game.sfc", "Game.sfc", true},
+		{"/mnt/SDCARD/Roms/SFC/Imgs/Game.png", "Imgs/Game.png", true},
+		{"/userdata/roms/sfc/Game.sfc", "Game.sfc", true},
+		{"/media/usb/other/Game.sfc", "", false},
+		{"../gba/Game.gba", "", false},
+		{" ", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.written, func(t *testing.T) {
+			got, ok := folder.Read(tt.written)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Read(%q) = %q, %v; want %q, %v", tt.written, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+
+	// what's written can be read back
+	for _, style := range []Style{DotRelative, Relative, Absolute} {
+		if got, ok := folder.Read(folder.Write(".", "sub/Game.sfc", style)); got != "sub/Game.sfc" || !ok {
+			t.Errorf("Read(Write(%v)) = %q, %v; want the path written", style, got, ok)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		from   string
+		ref    string
+		want   string
+		wantOk bool
+	}{
+		{".", "Game (Track 1).bin", "Game (Track 1).bin", true},
+		{".", "discs\\FF7 (Disc 1).cue", "discs/FF7 (Disc 1).cue", true},
+		{"discs", "../tracks/Track 1.bin", "tracks/Track 1.bin", true},
+		{".", "./Imgs/Game.png", "Imgs/Game.png", true},
+		{".", "../psx/Game.cue", "", false},
+		{".", "/mnt/SDCARD/Roms/PS/Game.cue", "", false},
+		{".", "C:\\Games\\Game.cue", "", false},
+		{".", "http://example.com/Game.cue", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, ok := Resolve(tt.from, tt.ref)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("Resolve(%q, %q) = %q, %v; want %q, %v", tt.from, tt.ref, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
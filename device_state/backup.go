@@ -0,0 +1,96 @@
+package device_state
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupSnapshotName is the name of the snapshot Backup writes at the top of its backup folder
+const BackupSnapshotName = "snapshot.json"
+
+// BackupMaxSize is the largest file Backup copies; anything bigger is a game or media, not metadata
+const BackupMaxSize = 1 << 20
+
+// the extensions of the files Backup copies: game lists, frontend and emulator configs, and the sheets and
+// playlists that tie discs together, which are small and can't be copied back from the source
+var backupExtensions = map[string]bool{
+	".xml": true, ".json": true, ".txt": true, ".cfg": true, ".ini": true, ".conf": true, ".opt": true,
+	".yml": true, ".yaml": true, ".cue": true, ".gdi": true, ".m3u": true,
+}
+
+// IsBackedUp reports whether Backup copies a file of the given name and size
+func IsBackedUp(name string, size int64) bool {
+	return size <= BackupMaxSize && backupExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// Backup records every file in the folders relDirs within targetDir, with their hashes, to a snapshot at
+// BackupSnapshotName in backupDir, and copies the small metadata files among them into backupDir at the same
+// paths, so what was there can be partly put back after a bad run. Paths in the snapshot are relative to
+// targetDir, so it can be diffed against like one from TakeSnapshot. Returns the snapshot and the slash
+// separated paths of the files copied.
+func Backup(targetDir string, relDirs []string, backupDir string) (*Snapshot, []string, error) {
+	snapshot := &Snapshot{
+		Version:       SnapshotVersion,
+		CreatedAt:     time.Now().UTC(),
+		TargetDir:     targetDir,
+		HashAlgorithm: HashAlgorithm,
+		Files:         make([]FileState, 0),
+	}
+	copied := make([]string, 0)
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create backup directory %s: %w", backupDir, err)
+	}
+
+	for _, relDir := range relDirs {
+		relDir = filepath.ToSlash(relDir)
+		files, err := ScanTree(filepath.Join(targetDir, filepath.FromSlash(relDir)), true)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, f := range files {
+			f.Path = path.Join(relDir, f.Path)
+			snapshot.Files = append(snapshot.Files, f)
+			if !IsBackedUp(f.Path, f.Size) {
+				continue
+			}
+			if err := copyBackup(filepath.Join(targetDir, filepath.FromSlash(f.Path)), filepath.Join(backupDir, filepath.FromSlash(f.Path))); err != nil {
+				return nil, nil, err
+			}
+			copied = append(copied, f.Path)
+		}
+	}
+	sort.Slice(snapshot.Files, func(i, j int) bool { return snapshot.Files[i].Path < snapshot.Files[j].Path })
+	sort.Strings(copied)
+
+	if err := snapshot.Save(filepath.Join(backupDir, BackupSnapshotName)); err != nil {
+		return nil, nil, err
+	}
+	return snapshot, copied, nil
+}
+
+func copyBackup(srcPath string, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory %s: %w", filepath.Dir(destPath), err)
+	}
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for backup: %w", srcPath, err)
+	}
+	defer src.Close()
+
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create backup %s: %w", destPath, err)
+	}
+	if _, err := io.Copy(dest, src); err != nil {
+		dest.Close()
+		return fmt.Errorf("failed to back up %s: %w", srcPath, err)
+	}
+	return dest.Close()
+}
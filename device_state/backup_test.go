@@ -0,0 +1,56 @@
+package device_state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBackup(t *testing.T) {
+	targetDir := t.TempDir()
+	createTree(t, targetDir, map[string]string{
+		"Roms/SFC/game.sfc":          "rom data",
+		"Roms/SFC/gamelist.xml":      "<gameList/>",
+		"Roms/SFC/images/game.png":   "image",
+		"Roms/PS/Game (USA).cue":     "FILE \"Game (USA).bin\" BINARY",
+		"Roms/PS/Game (USA).bin":     "track",
+		"Roms/PS/huge.xml":           strings.Repeat("x", BackupMaxSize+1),
+		"Roms/GB/untouched.cfg":      "not being cleaned",
+		"Roms/SFC/Saves/game.srm":    "save",
+		"Roms/SFC/retroarch.cfg.bak": "backup",
+	})
+	backupDir := filepath.Join(t.TempDir(), "backup")
+
+	snapshot, copied, err := Backup(targetDir, []string{"Roms/SFC", filepath.FromSlash("Roms/PS")}, backupDir)
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	want := []string{"Roms/PS/Game (USA).cue", "Roms/SFC/gamelist.xml"}
+	if !reflect.DeepEqual(copied, want) {
+		t.Errorf("Backup() copied %v, want %v", copied, want)
+	}
+	for _, relPath := range want {
+		got, err := os.ReadFile(filepath.Join(backupDir, filepath.FromSlash(relPath)))
+		original, _ := os.ReadFile(filepath.Join(targetDir, filepath.FromSlash(relPath)))
+		if err != nil || string(got) != string(original) {
+			t.Errorf("backup of %s = %q, %v; want %q", relPath, got, err, original)
+		}
+	}
+
+	if len(snapshot.Files) != 8 || snapshot.Files[0].Path != "Roms/PS/Game (USA).bin" {
+		t.Fatalf("Backup() recorded %+v, want the 8 files in the folders, sorted", snapshot.Files)
+	}
+	loaded, err := LoadSnapshot(filepath.Join(backupDir, BackupSnapshotName))
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	if f, ok := loaded.Subtree("Roms/SFC")["game.sfc"]; !ok || f.Size != 8 || f.Hash == "" {
+		t.Errorf("snapshot has %+v for game.sfc, want its size and hash", f)
+	}
+	if _, ok := loaded.Subtree("Roms/GB")["untouched.cfg"]; ok {
+		t.Error("snapshot has a file from a folder not backed up")
+	}
+}
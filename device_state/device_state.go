@@ -0,0 +1,219 @@
+package device_state
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// bump when the snapshot format changes incompatibly
+const SnapshotVersion = 1
+
+// hash recorded for each file in a snapshot
+const HashAlgorithm = "crc32"
+
+// FileState is what we know about a single file on one side of a comparison
+type FileState struct {
+	// slash-separated path relative to the scanned directory
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// lowercase hex digest; empty if hashes weren't collected
+	Hash string `json:"hash,omitempty"`
+}
+
+// Snapshot is a point-in-time record of a target's full tree that can be diffed against later without
+// the device attached
+type Snapshot struct {
+	Version       int         `json:"version"`
+	CreatedAt     time.Time   `json:"createdAt"`
+	TargetDir     string      `json:"targetDir"`
+	HashAlgorithm string      `json:"hashAlgorithm,omitempty"`
+	Files         []FileState `json:"files"`
+}
+
+// TakeSnapshot records every file beneath targetDir, hashing each one if withHashes is set
+func TakeSnapshot(targetDir string, withHashes bool) (*Snapshot, error) {
+	files, err := ScanTree(targetDir, withHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		TargetDir: targetDir,
+		Files:     make([]FileState, 0, len(files)),
+	}
+	if withHashes {
+		snapshot.HashAlgorithm = HashAlgorithm
+	}
+
+	for _, f := range files {
+		snapshot.Files = append(snapshot.Files, f)
+	}
+	sort.Slice(snapshot.Files, func(i, j int) bool { return snapshot.Files[i].Path < snapshot.Files[j].Path })
+
+	return snapshot, nil
+}
+
+// Save writes the snapshot as indented JSON
+func (s *Snapshot) Save(filePath string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write snapshot %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by Save
+func LoadSnapshot(filePath string) (*Snapshot, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", filePath, err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", filePath, err)
+	}
+
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot %s has unsupported version %d (expected %d)", filePath, snapshot.Version, SnapshotVersion)
+	}
+
+	return &snapshot, nil
+}
+
+// Subtree returns the snapshot's files beneath dir (relative to the snapshot's target), keyed by their
+// path relative to dir
+func (s *Snapshot) Subtree(dir string) map[string]FileState {
+	prefix := strings.Trim(path.Clean(filepath.ToSlash(dir)), "/")
+	if prefix == "." {
+		prefix = ""
+	}
+
+	files := make(map[string]FileState)
+	for _, f := range s.Files {
+		relPath := f.Path
+		if prefix != "" {
+			if !strings.HasPrefix(f.Path, prefix+"/") {
+				continue
+			}
+			relPath = f.Path[len(prefix)+1:]
+		}
+
+		f.Path = relPath
+		files[relPath] = f
+	}
+	return files
+}
+
+// ScanTree walks dir and returns every file in it keyed by slash-separated relative path. A missing dir
+// is treated as empty.
+func ScanTree(dir string, withHashes bool) (map[string]FileState, error) {
+	files := make(map[string]FileState)
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return files, nil
+	}
+
+	err := filepath.Walk(dir, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", walkPath, err)
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, walkPath)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", walkPath, err)
+		}
+
+		state := FileState{Path: filepath.ToSlash(relPath), Size: info.Size()}
+		if withHashes {
+			if state.Hash, err = HashFile(walkPath); err != nil {
+				return err
+			}
+		}
+
+		files[state.Path] = state
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// HashFile returns the lowercase hex CRC32 of the file at filePath
+func HashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s for hashing: %w", filePath, err)
+	}
+	defer file.Close()
+
+	hasher := crc32.NewIEEE()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+// Comparison describes how a source file set differs from what's on a target
+type Comparison struct {
+	// in the source but not on the target
+	New []FileState
+	// on both sides but with a different size (or hash, when both sides have one)
+	Changed   []FileState
+	Unchanged []FileState
+	// on the target but not in the source
+	Orphaned []FileState
+}
+
+// Compare diffs source against target; both are keyed by the same relative paths. Entries in the
+// result carry the source's state, except Orphaned which carries the target's.
+func Compare(source map[string]FileState, target map[string]FileState) Comparison {
+	var result Comparison
+
+	for relPath, src := range source {
+		dst, exists := target[relPath]
+		switch {
+		case !exists:
+			result.New = append(result.New, src)
+		case src.Size != dst.Size:
+			result.Changed = append(result.Changed, src)
+		case src.Hash != "" && dst.Hash != "" && src.Hash != dst.Hash:
+			result.Changed = append(result.Changed, src)
+		default:
+			result.Unchanged = append(result.Unchanged, src)
+		}
+	}
+
+	for relPath, dst := range target {
+		if _, exists := source[relPath]; !exists {
+			result.Orphaned = append(result.Orphaned, dst)
+		}
+	}
+
+	for _, list := range [][]FileState{result.New, result.Changed, result.Unchanged, result.Orphaned} {
+		sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	}
+
+	return result
+}
@@ -0,0 +1,120 @@
+package device_state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func createTree(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", path, err)
+		}
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	targetDir := t.TempDir()
+	createTree(t, targetDir, map[string]string{
+		"SFC/game.sfc":        "rom data",
+		"SFC/images/game.png": "image",
+		"FC/other.nes":        "nes rom",
+	})
+
+	snapshot, err := TakeSnapshot(targetDir, true)
+	if err != nil {
+		t.Fatalf("TakeSnapshot() error = %v", err)
+	}
+
+	if len(snapshot.Files) != 3 {
+		t.Fatalf("snapshot has %d files, want 3", len(snapshot.Files))
+	}
+	if snapshot.Files[0].Path != "FC/other.nes" {
+		t.Errorf("snapshot files not sorted; first is %q", snapshot.Files[0].Path)
+	}
+	for _, f := range snapshot.Files {
+		if f.Hash == "" {
+			t.Errorf("expected hash for %s", f.Path)
+		}
+	}
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := snapshot.Save(snapshotPath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadSnapshot(snapshotPath)
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+
+	subtree := loaded.Subtree("SFC")
+	if len(subtree) != 2 {
+		t.Fatalf("Subtree(SFC) has %d files, want 2", len(subtree))
+	}
+	if f, ok := subtree["images/game.png"]; !ok || f.Size != 5 {
+		t.Errorf("Subtree(SFC) missing or wrong entry for images/game.png: %+v", f)
+	}
+}
+
+func TestLoadSnapshotRejectsUnknownVersion(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := os.WriteFile(snapshotPath, []byte(`{"version": 999, "files": []}`), 0644); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+
+	if _, err := LoadSnapshot(snapshotPath); err == nil {
+		t.Error("LoadSnapshot() expected error for unsupported version")
+	}
+}
+
+func TestScanTreeMissingDir(t *testing.T) {
+	files, err := ScanTree(filepath.Join(t.TempDir(), "missing"), false)
+	if err != nil {
+		t.Fatalf("ScanTree() error = %v", err)
+	}
+	if len(files) != 0 {
+		t.Errorf("ScanTree() of missing dir returned %d files", len(files))
+	}
+}
+
+func TestCompare(t *testing.T) {
+	source := map[string]FileState{
+		"new.sfc":      {Path: "new.sfc", Size: 10},
+		"resized.sfc":  {Path: "resized.sfc", Size: 20},
+		"rehashed.sfc": {Path: "rehashed.sfc", Size: 30, Hash: "aaaa"},
+		"same.sfc":     {Path: "same.sfc", Size: 40, Hash: "bbbb"},
+		"unhashed.sfc": {Path: "unhashed.sfc", Size: 50},
+	}
+	target := map[string]FileState{
+		"resized.sfc":  {Path: "resized.sfc", Size: 21},
+		"rehashed.sfc": {Path: "rehashed.sfc", Size: 30, Hash: "cccc"},
+		"same.sfc":     {Path: "same.sfc", Size: 40, Hash: "bbbb"},
+		"unhashed.sfc": {Path: "unhashed.sfc", Size: 50, Hash: "dddd"},
+		"orphan.sfc":   {Path: "orphan.sfc", Size: 60},
+	}
+
+	result := Compare(source, target)
+
+	check := func(name string, got []FileState, want []string) {
+		if len(got) != len(want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+		for i := range want {
+			if got[i].Path != want[i] {
+				t.Errorf("%s[%d] = %q, want %q", name, i, got[i].Path, want[i])
+			}
+		}
+	}
+
+	check("New", result.New, []string{"new.sfc"})
+	check("Changed", result.Changed, []string{"rehashed.sfc", "resized.sfc"})
+	check("Unchanged", result.Unchanged, []string{"same.sfc", "unhashed.sfc"})
+	check("Orphaned", result.Orphaned, []string{"orphan.sfc"})
+}
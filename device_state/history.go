@@ -0,0 +1,133 @@
+package device_state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// Run is the summary of one copy to a target, kept in the run history
+type Run struct {
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	// absolute path of the target directory
+	TargetDir string `json:"targetDir"`
+	// from the target's identity file; empty for runs made before it had one
+	DeviceID   string `json:"deviceId,omitempty"`
+	DeviceName string `json:"deviceName,omitempty"`
+	SourceDir  string `json:"sourceDir"`
+	// as given on the command line, e.g. 'snes:SFC'
+	Mappings []string `json:"mappings"`
+	// identifies the options the run was made with, so a sync made differently from the last one stands out
+	ConfigHash string `json:"configHash"`
+	Succeeded  bool   `json:"succeeded"`
+	// why the run failed; empty if it succeeded
+	Error string `json:"error,omitempty"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+	// files '--maxErrors' let fail to copy
+	FailedFiles int `json:"failedFiles,omitempty"`
+}
+
+// Device returns what the run's target is known by in the history: its device ID, or for runs recorded before
+// it had one, its path
+func (r Run) Device() string {
+	if r.DeviceID != "" {
+		return r.DeviceID
+	}
+	return r.TargetDir
+}
+
+// Duration returns how long the run took
+func (r Run) Duration() time.Duration {
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// DefaultHistoryPath returns where the run history lives in the user's config directory, which unlike the cache
+// directory isn't cleared by the system to free space
+func DefaultHistoryPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate user config directory: %w", err)
+	}
+	return HistoryPath(filepath.Join(configDir, "ROMCopyEngine")), nil
+}
+
+// HistoryPath returns where the run history lives in stateDir
+func HistoryPath(stateDir string) string {
+	return filepath.Join(stateDir, "history.jsonl")
+}
+
+// RecordRun appends run to the history at filePath, creating it and its directory if needed
+func RecordRun(fs fsys.FS, filePath string, run Run) error {
+	if err := fs.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory %s: %w", filepath.Dir(filePath), err)
+	}
+
+	journal, err := OpenJournal(fs, filePath)
+	if err != nil {
+		return err
+	}
+	if err := journal.Append(run); err != nil {
+		journal.Close()
+		return err
+	}
+	return journal.Close()
+}
+
+// ReadHistory returns the runs in the history at filePath, oldest first. A missing history has no runs.
+func ReadHistory(fs fsys.FS, filePath string) ([]Run, error) {
+	records, _, err := ReadJournal(fs, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]Run, 0, len(records))
+	for _, record := range records {
+		var run Run
+		if err := json.Unmarshal(record, &run); err != nil {
+			return nil, fmt.Errorf("failed to parse history %s: %w", filePath, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// LastRuns returns the most recent of runs to each device, ordered by device name, then target
+func LastRuns(runs []Run) []Run {
+	last := make(map[string]Run)
+	for _, run := range runs {
+		if previous, ok := last[run.Device()]; !ok || !run.StartedAt.Before(previous.StartedAt) {
+			last[run.Device()] = run
+		}
+	}
+
+	targets := make([]Run, 0, len(last))
+	for _, run := range last {
+		targets = append(targets, run)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].DeviceName != targets[j].DeviceName {
+			return targets[i].DeviceName < targets[j].DeviceName
+		}
+		return targets[i].TargetDir < targets[j].TargetDir
+	})
+	return targets
+}
+
+// LastSuccess returns the most recent successful run to device, as returned by Run.Device
+func LastSuccess(runs []Run, device string) (Run, bool) {
+	var found Run
+	ok := false
+	for _, run := range runs {
+		if run.Device() == device && run.Succeeded && (!ok || !run.StartedAt.Before(found.StartedAt)) {
+			found, ok = run, true
+		}
+	}
+	return found, ok
+}
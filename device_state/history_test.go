@@ -0,0 +1,56 @@
+package device_state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestRunHistory(t *testing.T) {
+	mem := fsys.NewMemFS()
+	historyPath := HistoryPath(filepath.Join(t.TempDir(), "state"))
+
+	if runs, err := ReadHistory(mem, historyPath); err != nil || len(runs) != 0 {
+		t.Fatalf("ReadHistory() of a missing history = %v, %v; want no runs", runs, err)
+	}
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	runs := []Run{
+		{StartedAt: start, FinishedAt: start.Add(time.Minute), TargetDir: "/media/alice", ConfigHash: "aaa", Succeeded: true, Files: 10},
+		{StartedAt: start.Add(time.Hour), TargetDir: "/media/bob", ConfigHash: "bbb", Succeeded: true},
+		{StartedAt: start.Add(2 * time.Hour), TargetDir: "/media/alice", ConfigHash: "ccc", Error: "card pulled"},
+		// a labeled card, mounted in two places; its runs are kept apart from alice's unlabeled ones
+		{StartedAt: start.Add(3 * time.Hour), TargetDir: "/media/usb", DeviceID: "1234", DeviceName: "Carol's RG35XX", ConfigHash: "ddd", Succeeded: true},
+		{StartedAt: start.Add(4 * time.Hour), TargetDir: "/media/alice", DeviceID: "1234", DeviceName: "Carol's RG35XX", ConfigHash: "eee", Error: "card pulled"},
+	}
+	for _, run := range runs {
+		if err := RecordRun(mem, historyPath, run); err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	read, err := ReadHistory(mem, historyPath)
+	if err != nil {
+		t.Fatalf("ReadHistory() error = %v", err)
+	}
+	if len(read) != 5 || read[0].Files != 10 || read[2].Error != "card pulled" || read[0].Duration() != time.Minute {
+		t.Fatalf("ReadHistory() = %+v, want the recorded runs", read)
+	}
+
+	last := LastRuns(read)
+	if len(last) != 3 || last[0].ConfigHash != "ccc" || last[1].ConfigHash != "bbb" || last[2].ConfigHash != "eee" {
+		t.Errorf("LastRuns() = %+v, want alice's failed run, bob's, then carol's failed run", last)
+	}
+
+	if success, ok := LastSuccess(read, "/media/alice"); !ok || success.ConfigHash != "aaa" {
+		t.Errorf("LastSuccess() = %+v, %v; want alice's first run", success, ok)
+	}
+	if success, ok := LastSuccess(read, "1234"); !ok || success.TargetDir != "/media/usb" {
+		t.Errorf("LastSuccess() = %+v, %v; want carol's run from /media/usb", success, ok)
+	}
+	if _, ok := LastSuccess(read, "/media/carol"); ok {
+		t.Error("LastSuccess() found a run to a target never synced")
+	}
+}
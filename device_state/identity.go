@@ -0,0 +1,70 @@
+package device_state
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// written to the top of the target; hidden so devices don't list it
+const IdentityFileName = ".romcopyengine-id"
+
+// Identity names a target device, so it's recognized however and wherever it's mounted
+type Identity struct {
+	// random, and never changed once written
+	ID string `json:"id"`
+	// assigned by the user, e.g. "Dad's RG35XX"; may be empty
+	Name      string    `json:"name,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// NewIdentity creates an identity with a fresh random ID
+func NewIdentity(name string) (*Identity, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate device ID: %w", err)
+	}
+	return &Identity{ID: hex.EncodeToString(id), Name: name, CreatedAt: time.Now().UTC()}, nil
+}
+
+// LoadIdentity reads the identity at the top of targetDir. A target without one isn't an error; nil is returned.
+func LoadIdentity(targetDir string) (*Identity, error) {
+	identityPath := filepath.Join(targetDir, IdentityFileName)
+	data, err := file_operations.Filesystem().ReadFile(identityPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device identity %s: %w", identityPath, err)
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(data, &identity); err != nil {
+		return nil, fmt.Errorf("failed to parse device identity %s: %w", identityPath, err)
+	}
+	if identity.ID == "" {
+		return nil, fmt.Errorf("device identity %s has no ID", identityPath)
+	}
+	return &identity, nil
+}
+
+// Save writes the identity to the top of targetDir as indented JSON, atomically
+func (i *Identity) Save(targetDir string) error {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode device identity: %w", err)
+	}
+
+	identityPath := filepath.Join(targetDir, IdentityFileName)
+	if err := fsys.WriteFileAtomic(file_operations.Filesystem(), identityPath, data); err != nil {
+		return fmt.Errorf("failed to write device identity %s: %w", identityPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package device_state
+
+import "testing"
+
+func TestIdentity(t *testing.T) {
+	targetDir := t.TempDir()
+	if identity, err := LoadIdentity(targetDir); identity != nil || err != nil {
+		t.Fatalf("LoadIdentity() of an unlabeled target = %v, %v; want nil, nil", identity, err)
+	}
+
+	identity, err := NewIdentity("Dad's RG35XX")
+	if err != nil {
+		t.Fatalf("NewIdentity() error = %v", err)
+	}
+	if err := identity.Save(targetDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadIdentity(targetDir)
+	if err != nil {
+		t.Fatalf("LoadIdentity() error = %v", err)
+	}
+	if loaded.ID != identity.ID || len(loaded.ID) != 32 || loaded.Name != "Dad's RG35XX" {
+		t.Errorf("LoadIdentity() = %+v, want %+v", loaded, identity)
+	}
+
+	other, _ := NewIdentity("")
+	if other.ID == identity.ID || other.Name != "" {
+		t.Errorf("NewIdentity() = %+v, want a fresh unnamed ID", other)
+	}
+}
@@ -0,0 +1,99 @@
+package device_state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// Journal is an append-only log of JSON records, one per line. Every record is written with a single write and
+// synced before Append returns, so a record that Append reported as written survives the process being killed,
+// and a write cut short by a crash can only ever damage the last line, which ReadJournal skips.
+// Safe for concurrent use.
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	file fsys.File
+}
+
+// OpenJournal opens the journal at filePath for appending, creating it if needed
+func OpenJournal(fs fsys.FS, filePath string) (*Journal, error) {
+	existing, err := fs.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read journal %s: %w", filePath, err)
+	}
+
+	file, err := fs.Append(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", filePath, err)
+	}
+
+	// a record torn by a crash is ended here so it can't run into the next one and corrupt it too
+	if len(existing) > 0 && existing[len(existing)-1] != '\n' {
+		if _, err := file.Write([]byte("\n")); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to repair journal %s: %w", filePath, err)
+		}
+	}
+
+	return &Journal{path: filePath, file: file}, nil
+}
+
+// Append writes record as a line of JSON and waits for it to reach the disk
+func (j *Journal) Append(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal record: %w", err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.file.Write(line); err != nil {
+		return fmt.Errorf("failed to write to journal %s: %w", j.path, err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync journal %s: %w", j.path, err)
+	}
+	return nil
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.file.Close()
+}
+
+// ReadJournal returns every intact record in the journal at filePath, in the order they were written, along
+// with how many torn records (left by a crash mid-write) were skipped. A missing journal has no records.
+func ReadJournal(fs fsys.FS, filePath string) ([]json.RawMessage, int, error) {
+	data, err := fs.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read journal %s: %w", filePath, err)
+	}
+
+	records := make([]json.RawMessage, 0)
+	torn := 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			torn++
+			continue
+		}
+		records = append(records, json.RawMessage(line))
+	}
+
+	return records, torn, nil
+}
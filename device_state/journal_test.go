@@ -0,0 +1,107 @@
+package device_state
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+type testRecord struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+}
+
+func readTestRecords(t *testing.T, fs fsys.FS, journalPath string) ([]testRecord, int) {
+	raw, torn, err := ReadJournal(fs, journalPath)
+	if err != nil {
+		t.Fatalf("ReadJournal() error = %v", err)
+	}
+
+	records := make([]testRecord, 0, len(raw))
+	for _, line := range raw {
+		var record testRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("failed to decode record %s: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, torn
+}
+
+func TestJournalConcurrentAppends(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+	journal, err := OpenJournal(fsys.OS, journalPath)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := journal.Append(testRecord{Op: "copy", Path: fmt.Sprintf("game%02d.nes", i)}); err != nil {
+				t.Errorf("Append() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	journal.Close()
+
+	records, torn := readTestRecords(t, fsys.OS, journalPath)
+	if torn != 0 || len(records) != 20 {
+		t.Fatalf("read %d records (%d torn), want 20 intact", len(records), torn)
+	}
+	paths := make([]string, 0, len(records))
+	for _, record := range records {
+		paths = append(paths, record.Path)
+	}
+	sort.Strings(paths)
+	for i, p := range paths {
+		if want := fmt.Sprintf("game%02d.nes", i); p != want {
+			t.Errorf("record %d = %s, want %s", i, p, want)
+		}
+	}
+}
+
+func TestJournalTornRecord(t *testing.T) {
+	mem := fsys.NewMemFS()
+	journalPath := filepath.Join(t.TempDir(), "journal.jsonl")
+	if err := mem.MkdirAll(filepath.Dir(journalPath), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	// a run killed partway through writing its second record
+	if err := mem.WriteFile(journalPath, []byte(`{"op":"copy","path":"a.nes"}`+"\n"+`{"op":"copy","pa`), 0644); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+
+	journal, err := OpenJournal(mem, journalPath)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+	if err := journal.Append(testRecord{Op: "copy", Path: "b.nes"}); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	journal.Close()
+
+	records, torn := readTestRecords(t, mem, journalPath)
+	if torn != 1 {
+		t.Errorf("torn = %d, want 1", torn)
+	}
+	if len(records) != 2 || records[0].Path != "a.nes" || records[1].Path != "b.nes" {
+		t.Errorf("records = %+v, want a.nes then b.nes", records)
+	}
+}
+
+func TestReadJournalMissing(t *testing.T) {
+	records, torn, err := ReadJournal(fsys.OS, filepath.Join(t.TempDir(), "missing.jsonl"))
+	if err != nil || len(records) != 0 || torn != 0 {
+		t.Errorf("ReadJournal() = %v, %d, %v; want nothing", records, torn, err)
+	}
+}
@@ -0,0 +1,192 @@
+package device_state
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// written to each platform folder on the target; hidden so devices don't list it as a game
+const ManifestFileName = ".romcopyengine-checksums.json"
+
+// bump when the manifest format changes incompatibly
+const ManifestVersion = 1
+
+// ManifestEntry records a single file in a platform folder as of the last run that wrote the manifest
+type ManifestEntry struct {
+	// slash-separated path relative to the platform folder
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	// lets a later run trust the hash without re-reading the file if the file hasn't been touched since
+	ModTime time.Time `json:"modTime"`
+	// lowercase hex digest using the manifest's algorithm
+	Hash string `json:"hash"`
+}
+
+// Manifest lists the name, size, and hash of every file in a platform folder on the target, so the folder can
+// be verified or incrementally synced later without re-hashing the source
+type Manifest struct {
+	Version   int             `json:"version"`
+	Algorithm string          `json:"algorithm"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+	Files     []ManifestEntry `json:"files"`
+}
+
+// LoadManifest reads the manifest in dir. A missing manifest isn't an error; nil is returned.
+func LoadManifest(dir string) (*Manifest, error) {
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	data, err := file_operations.Filesystem().ReadFile(manifestPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", manifestPath, err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", manifestPath, err)
+	}
+
+	if manifest.Version != ManifestVersion {
+		return nil, fmt.Errorf("manifest %s has unsupported version %d (expected %d)", manifestPath, manifest.Version, ManifestVersion)
+	}
+
+	return &manifest, nil
+}
+
+// Save writes the manifest into dir as indented JSON. The old manifest is replaced atomically, so a run killed
+// partway through the write leaves it intact.
+func (m *Manifest) Save(dir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	if err := fsys.WriteFileAtomic(file_operations.Filesystem(), manifestPath, data); err != nil {
+		return fmt.Errorf("failed to write manifest %s: %w", manifestPath, err)
+	}
+	return nil
+}
+
+// BuildManifest records every file in dir using algorithm. Hashes are taken from known entries (e.g. checksums
+// recorded during the copy, or a previous manifest) when a file's size and modification time show it hasn't
+// changed since; anything else is hashed from dir. A known entry is also matched by name, size, and
+// modification time alone, so files moved by post-copy operations like '--explodeDir' keep their hash.
+// Returns the manifest and how many files had to be hashed.
+func BuildManifest(dir string, algorithm string, known []ManifestEntry) (*Manifest, int, error) {
+	byPath := make(map[string]ManifestEntry, len(known))
+	byName := make(map[string][]ManifestEntry)
+	for _, entry := range known {
+		byPath[entry.Path] = entry
+		byName[path.Base(entry.Path)] = append(byName[path.Base(entry.Path)], entry)
+	}
+
+	manifest := &Manifest{
+		Version:   ManifestVersion,
+		Algorithm: algorithm,
+		UpdatedAt: time.Now().UTC(),
+		Files:     make([]ManifestEntry, 0),
+	}
+	hashed := 0
+
+	err := filepath.Walk(dir, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", walkPath, err)
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, walkPath)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", walkPath, err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == ManifestFileName || relPath == SignatureFileName {
+			return nil
+		}
+
+		entry := ManifestEntry{Path: relPath, Size: info.Size(), ModTime: info.ModTime().UTC()}
+		if hash, ok := knownHash(entry, byPath, byName); ok {
+			entry.Hash = hash
+		} else {
+			if entry.Hash, err = hashFileWith(walkPath, algorithm); err != nil {
+				return err
+			}
+			hashed++
+		}
+
+		manifest.Files = append(manifest.Files, entry)
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	return manifest, hashed, nil
+}
+
+// finds the hash of an unchanged file by its path, or failing that, by a single unambiguous match on name
+func knownHash(entry ManifestEntry, byPath map[string]ManifestEntry, byName map[string][]ManifestEntry) (string, bool) {
+	unchanged := func(candidate ManifestEntry) bool {
+		return candidate.Hash != "" && candidate.Size == entry.Size && candidate.ModTime.Equal(entry.ModTime)
+	}
+
+	if candidate, ok := byPath[entry.Path]; ok {
+		if unchanged(candidate) {
+			return candidate.Hash, true
+		}
+		return "", false
+	}
+
+	var match *ManifestEntry
+	for _, candidate := range byName[path.Base(entry.Path)] {
+		if !unchanged(candidate) {
+			continue
+		}
+		if match != nil {
+			return "", false
+		}
+		candidate := candidate
+		match = &candidate
+	}
+
+	if match == nil {
+		return "", false
+	}
+	return match.Hash, true
+}
+
+// returns the lowercase hex digest of the file at filePath using algorithm
+func hashFileWith(filePath string, algorithm string) (string, error) {
+	hasher, err := file_operations.NewChecksum(algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s for hashing: %w", filePath, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,133 @@
+package device_state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildManifest(t *testing.T) {
+	dir := t.TempDir()
+	createTree(t, dir, map[string]string{
+		"game.sfc":         "rom data",
+		"game.png":         "image",
+		"gamelist.xml":     "<gameList/>",
+		ManifestFileName:   "{}",
+		"sub/untouched.sf": "other",
+	})
+
+	stat := func(name string) os.FileInfo {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", name, err)
+		}
+		return info
+	}
+
+	known := []ManifestEntry{
+		// unchanged since it was recorded; the bogus hash proves it was reused rather than re-read
+		{Path: "game.sfc", Size: 8, ModTime: stat("game.sfc").ModTime(), Hash: "reused"},
+		// moved up from images/ by an explode; matched by name
+		{Path: "images/game.png", Size: 5, ModTime: stat("game.png").ModTime(), Hash: "moved"},
+		// rewritten since it was recorded
+		{Path: "gamelist.xml", Size: 11, ModTime: stat("gamelist.xml").ModTime().Add(-time.Minute), Hash: "stale"},
+	}
+
+	manifest, hashed, err := BuildManifest(dir, "crc32", known)
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+
+	if hashed != 2 {
+		t.Errorf("expected 2 files to be hashed, got %d", hashed)
+	}
+
+	crc, err := HashFile(filepath.Join(dir, "gamelist.xml"))
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+	expected := map[string]string{
+		"game.png":         "moved",
+		"game.sfc":         "reused",
+		"gamelist.xml":     crc,
+		"sub/untouched.sf": "",
+	}
+
+	if len(manifest.Files) != len(expected) {
+		t.Fatalf("manifest has %d files, want %d: %v", len(manifest.Files), len(expected), manifest.Files)
+	}
+	for _, entry := range manifest.Files {
+		want, ok := expected[entry.Path]
+		if !ok {
+			t.Errorf("unexpected manifest entry %q", entry.Path)
+			continue
+		}
+		if want != "" && entry.Hash != want {
+			t.Errorf("%s hash = %q, want %q", entry.Path, entry.Hash, want)
+		}
+		if entry.Hash == "" {
+			t.Errorf("%s has no hash", entry.Path)
+		}
+	}
+}
+
+func TestBuildManifestAmbiguousName(t *testing.T) {
+	dir := t.TempDir()
+	createTree(t, dir, map[string]string{"cover.png": "image"})
+	info, err := os.Stat(filepath.Join(dir, "cover.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	known := []ManifestEntry{
+		{Path: "a/cover.png", Size: 5, ModTime: info.ModTime(), Hash: "first"},
+		{Path: "b/cover.png", Size: 5, ModTime: info.ModTime(), Hash: "second"},
+	}
+
+	manifest, hashed, err := BuildManifest(dir, "crc32", known)
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+	if hashed != 1 || manifest.Files[0].Hash == "first" || manifest.Files[0].Hash == "second" {
+		t.Errorf("ambiguous name match should be re-hashed, got %q (%d hashed)", manifest.Files[0].Hash, hashed)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	missing, err := LoadManifest(dir)
+	if err != nil || missing != nil {
+		t.Fatalf("LoadManifest() on a folder without a manifest = %v, %v; want nil, nil", missing, err)
+	}
+
+	createTree(t, dir, map[string]string{"game.gba": "rom"})
+	manifest, _, err := BuildManifest(dir, "md5", nil)
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+	if err := manifest.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadManifest(dir)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if loaded.Algorithm != "md5" || len(loaded.Files) != 1 || loaded.Files[0].Hash != manifest.Files[0].Hash {
+		t.Errorf("loaded manifest %+v doesn't match saved %+v", loaded, manifest)
+	}
+	if !loaded.Files[0].ModTime.Equal(manifest.Files[0].ModTime) {
+		t.Errorf("mod time didn't survive a round trip: %v != %v", loaded.Files[0].ModTime, manifest.Files[0].ModTime)
+	}
+
+	// the manifest never lists itself
+	rebuilt, _, err := BuildManifest(dir, "md5", loaded.Files)
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+	if len(rebuilt.Files) != 1 {
+		t.Errorf("rebuilt manifest has %d files, want 1", len(rebuilt.Files))
+	}
+}
@@ -0,0 +1,193 @@
+package device_state
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+)
+
+// written beside the manifest by SignManifest; holds the base64 Ed25519 signature of the manifest's bytes
+const SignatureFileName = ManifestFileName + ".sig"
+
+// ErrBadSignature is returned by VerifyManifestSignature when the manifest wasn't signed by the given key or
+// has been changed since
+var ErrBadSignature = errors.New("manifest signature doesn't match")
+
+// LoadSigningKey reads an Ed25519 private key from a PEM-encoded PKCS #8 file, as written by
+// 'openssl genpkey -algorithm ed25519'
+func LoadSigningKey(keyPath string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(keyPath, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", keyPath, err)
+	}
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s isn't an Ed25519 key", keyPath)
+	}
+	return privateKey, nil
+}
+
+// LoadVerifyKey reads an Ed25519 public key from a PEM-encoded PKIX file, as written by
+// 'openssl pkey -pubout'
+func LoadVerifyKey(keyPath string) (ed25519.PublicKey, error) {
+	block, err := readPEM(keyPath, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", keyPath, err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s isn't an Ed25519 key", keyPath)
+	}
+	return publicKey, nil
+}
+
+// returns the first PEM block of the given type in the file at keyPath
+func readPEM(keyPath string, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", keyPath, err)
+	}
+
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == blockType {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("no '%s' PEM block in %s", blockType, keyPath)
+}
+
+// SignManifest signs the manifest in dir with key and writes the signature beside it
+func SignManifest(dir string, key ed25519.PrivateKey) error {
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	data, err := file_operations.Filesystem().ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest %s: %w", manifestPath, err)
+	}
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n"
+	signaturePath := filepath.Join(dir, SignatureFileName)
+	if err := file_operations.Filesystem().WriteFile(signaturePath, []byte(signature), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest signature %s: %w", signaturePath, err)
+	}
+	return nil
+}
+
+// VerifyManifestSignature checks that the manifest in dir was signed by key and hasn't changed since, returning
+// it if so. Returns an error wrapping ErrBadSignature if the signature doesn't match.
+func VerifyManifestSignature(dir string, key ed25519.PublicKey) (*Manifest, error) {
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	data, err := file_operations.Filesystem().ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %w", manifestPath, err)
+	}
+
+	signaturePath := filepath.Join(dir, SignatureFileName)
+	encoded, err := file_operations.Filesystem().ReadFile(signaturePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest signature %s: %w", signaturePath, err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode manifest signature %s: %w", signaturePath, err)
+	}
+
+	if !ed25519.Verify(key, data, signature) {
+		return nil, fmt.Errorf("%s: %w", manifestPath, ErrBadSignature)
+	}
+
+	// loaded only once the signature shows the bytes can be trusted
+	return LoadManifest(dir)
+}
+
+// ManifestCheck is how the files in a platform folder compare against its manifest; each list holds slash
+// separated paths relative to the folder, sorted
+type ManifestCheck struct {
+	// in the manifest but not the folder
+	Missing []string
+	// whose size or hash isn't what the manifest records
+	Differing []string
+	// in the folder but not the manifest
+	Extra []string
+}
+
+// Check hashes every file in dir and compares it against the manifest, ignoring the manifest and its signature
+func (m *Manifest) Check(dir string) (*ManifestCheck, error) {
+	check := &ManifestCheck{Missing: make([]string, 0), Differing: make([]string, 0), Extra: make([]string, 0)}
+
+	recorded := make(map[string]ManifestEntry, len(m.Files))
+	for _, entry := range m.Files {
+		recorded[entry.Path] = entry
+	}
+
+	seen := make(map[string]bool, len(m.Files))
+	err := filepath.Walk(dir, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", walkPath, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, walkPath)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", walkPath, err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == ManifestFileName || relPath == SignatureFileName {
+			return nil
+		}
+
+		entry, ok := recorded[relPath]
+		if !ok {
+			check.Extra = append(check.Extra, relPath)
+			return nil
+		}
+		seen[relPath] = true
+
+		if entry.Size != info.Size() {
+			check.Differing = append(check.Differing, relPath)
+			return nil
+		}
+		hash, err := hashFileWith(walkPath, m.Algorithm)
+		if err != nil {
+			return err
+		}
+		if hash != entry.Hash {
+			check.Differing = append(check.Differing, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range m.Files {
+		if !seen[entry.Path] {
+			check.Missing = append(check.Missing, entry.Path)
+		}
+	}
+
+	sort.Strings(check.Missing)
+	sort.Strings(check.Differing)
+	sort.Strings(check.Extra)
+	return check, nil
+}
@@ -0,0 +1,148 @@
+package device_state
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writes a fresh key pair as PEM files in dir, returning their paths
+func writeKeyPair(t *testing.T, dir string) (string, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey() error = %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+
+	privatePath := filepath.Join(dir, "key.pem")
+	publicPath := filepath.Join(dir, "key.pub.pem")
+	if err := os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		t.Fatalf("failed to write private key: %v", err)
+	}
+	if err := os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		t.Fatalf("failed to write public key: %v", err)
+	}
+	return privatePath, publicPath
+}
+
+func TestSignAndVerifyManifest(t *testing.T) {
+	keyDir := t.TempDir()
+	privatePath, publicPath := writeKeyPair(t, keyDir)
+	_, otherPublicPath := writeKeyPair(t, t.TempDir())
+
+	privateKey, err := LoadSigningKey(privatePath)
+	if err != nil {
+		t.Fatalf("LoadSigningKey() error = %v", err)
+	}
+	publicKey, err := LoadVerifyKey(publicPath)
+	if err != nil {
+		t.Fatalf("LoadVerifyKey() error = %v", err)
+	}
+	otherKey, err := LoadVerifyKey(otherPublicPath)
+	if err != nil {
+		t.Fatalf("LoadVerifyKey() error = %v", err)
+	}
+	if _, err := LoadVerifyKey(privatePath); err == nil {
+		t.Errorf("LoadVerifyKey() of a private key should fail")
+	}
+
+	dir := t.TempDir()
+	createTree(t, dir, map[string]string{"game.sfc": "rom data", "images/game.png": "image"})
+
+	manifest, _, err := BuildManifest(dir, "crc32", nil)
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+	if err := manifest.Save(dir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := SignManifest(dir, privateKey); err != nil {
+		t.Fatalf("SignManifest() error = %v", err)
+	}
+
+	// the signature isn't recorded in a rebuilt manifest
+	rebuilt, _, err := BuildManifest(dir, "crc32", nil)
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+	if len(rebuilt.Files) != 2 {
+		t.Errorf("rebuilt manifest has %d files, want 2: %v", len(rebuilt.Files), rebuilt.Files)
+	}
+
+	verified, err := VerifyManifestSignature(dir, publicKey)
+	if err != nil {
+		t.Fatalf("VerifyManifestSignature() error = %v", err)
+	}
+	if len(verified.Files) != 2 {
+		t.Errorf("verified manifest has %d files, want 2", len(verified.Files))
+	}
+
+	if _, err := VerifyManifestSignature(dir, otherKey); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyManifestSignature() with another key error = %v, want ErrBadSignature", err)
+	}
+
+	manifestPath := filepath.Join(dir, ManifestFileName)
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if err := os.WriteFile(manifestPath, append(data, ' '), 0644); err != nil {
+		t.Fatalf("failed to tamper with manifest: %v", err)
+	}
+	if _, err := VerifyManifestSignature(dir, publicKey); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyManifestSignature() of a changed manifest error = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestManifestCheck(t *testing.T) {
+	dir := t.TempDir()
+	createTree(t, dir, map[string]string{
+		"game.sfc":        "rom data",
+		"images/game.png": "image",
+		"gamelist.xml":    "<gameList/>",
+	})
+
+	manifest, _, err := BuildManifest(dir, "crc32", nil)
+	if err != nil {
+		t.Fatalf("BuildManifest() error = %v", err)
+	}
+
+	createTree(t, dir, map[string]string{
+		// same size, different contents
+		"game.sfc":  "ROM DATA",
+		"extra.sfc": "added",
+		// never reported
+		ManifestFileName:  "{}",
+		SignatureFileName: "sig",
+	})
+	if err := os.Remove(filepath.Join(dir, "images", "game.png")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	check, err := manifest.Check(dir)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	want := &ManifestCheck{
+		Missing:   []string{"images/game.png"},
+		Differing: []string{"game.sfc"},
+		Extra:     []string{"extra.sfc"},
+	}
+	if !reflect.DeepEqual(check, want) {
+		t.Errorf("Check() = %+v, want %+v", check, want)
+	}
+}
@@ -0,0 +1,154 @@
+package device_state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/file_operations"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// the folder at the top of the target that holds its state file; hidden so devices don't list it
+const TargetStateDir = ".romcopyengine"
+
+// the name of the state file in TargetStateDir
+const TargetStateFileName = "state.json"
+
+// bump when the state format changes incompatibly
+const TargetStateVersion = 1
+
+// CopiedFile is a file a mapping copied to the target
+type CopiedFile struct {
+	// slash-separated path relative to the mapping's destination folder
+	Path string `json:"path"`
+	// slash-separated path relative to the mapping's source folder; empty if it's the same as Path
+	Source string `json:"source,omitempty"`
+	Size   int64  `json:"size"`
+	// lowercase hex digest using the state's algorithm, of the file as it was left on the target
+	Hash string `json:"hash"`
+}
+
+// SourcePath returns the file's slash-separated path relative to the mapping's source folder
+func (f CopiedFile) SourcePath() string {
+	if f.Source != "" {
+		return f.Source
+	}
+	return f.Path
+}
+
+// MappingState records the last copy made for one mapping
+type MappingState struct {
+	// the mapping as given on the command line, e.g. 'snes' and 'SFC'
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// absolute path of the source directory the mapping was copied from
+	SourceDir string    `json:"sourceDir"`
+	CopiedAt  time.Time `json:"copiedAt"`
+	// identifies the options the copy was made with, as in the run history
+	ConfigHash string `json:"configHash"`
+	// the copy's options as recipe arguments, so it can be repeated
+	Args  []string     `json:"args"`
+	Files []CopiedFile `json:"files"`
+}
+
+// States returns the mapping's files as they should be found in its destination folder, keyed by path, for
+// comparing against a scan of it
+func (m MappingState) States() map[string]FileState {
+	files := make(map[string]FileState, len(m.Files))
+	for _, f := range m.Files {
+		files[f.Path] = FileState{Path: f.Path, Size: f.Size, Hash: f.Hash}
+	}
+	return files
+}
+
+// TargetState records, on the target itself, what each mapping last copied to it and from where, so it can be
+// verified without the source and a later copy can tell what it already holds
+type TargetState struct {
+	Version       int            `json:"version"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+	HashAlgorithm string         `json:"hashAlgorithm"`
+	Mappings      []MappingState `json:"mappings"`
+}
+
+// NewTargetState returns an empty state
+func NewTargetState() *TargetState {
+	return &TargetState{Version: TargetStateVersion, HashAlgorithm: HashAlgorithm, Mappings: make([]MappingState, 0)}
+}
+
+// TargetStatePath returns where the state file of the target at targetDir lives
+func TargetStatePath(targetDir string) string {
+	return filepath.Join(targetDir, TargetStateDir, TargetStateFileName)
+}
+
+// LoadTargetState reads the state file of the target at targetDir. A missing state file isn't an error; nil is
+// returned.
+func LoadTargetState(targetDir string) (*TargetState, error) {
+	statePath := TargetStatePath(targetDir)
+	data, err := file_operations.Filesystem().ReadFile(statePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", statePath, err)
+	}
+
+	var state TargetState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", statePath, err)
+	}
+
+	if state.Version != TargetStateVersion {
+		return nil, fmt.Errorf("state file %s has unsupported version %d (expected %d)", statePath, state.Version, TargetStateVersion)
+	}
+	if state.HashAlgorithm != HashAlgorithm {
+		return nil, fmt.Errorf("state file %s has unsupported hash algorithm '%s' (expected '%s')", statePath, state.HashAlgorithm, HashAlgorithm)
+	}
+
+	return &state, nil
+}
+
+// Mapping returns what's recorded for the mapping copied to destination
+func (s *TargetState) Mapping(destination string) (MappingState, bool) {
+	for _, m := range s.Mappings {
+		if m.Destination == destination {
+			return m, true
+		}
+	}
+	return MappingState{}, false
+}
+
+// Record replaces what's recorded for mapping's destination with mapping, keeping the mappings sorted by
+// destination
+func (s *TargetState) Record(mapping MappingState) {
+	sort.Slice(mapping.Files, func(i, j int) bool { return mapping.Files[i].Path < mapping.Files[j].Path })
+	for i, m := range s.Mappings {
+		if m.Destination == mapping.Destination {
+			s.Mappings[i] = mapping
+			return
+		}
+	}
+	s.Mappings = append(s.Mappings, mapping)
+	sort.Slice(s.Mappings, func(i, j int) bool { return s.Mappings[i].Destination < s.Mappings[j].Destination })
+}
+
+// Save writes the state file of the target at targetDir as indented JSON, creating its folder if needed. The old
+// state file is replaced atomically, so a run killed partway through the write leaves it intact.
+func (s *TargetState) Save(targetDir string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state file: %w", err)
+	}
+
+	statePath := TargetStatePath(targetDir)
+	if err := file_operations.Filesystem().MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", filepath.Dir(statePath), err)
+	}
+	if err := fsys.WriteFileAtomic(file_operations.Filesystem(), statePath, data); err != nil {
+		return fmt.Errorf("failed to write state file %s: %w", statePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package device_state
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTargetState(t *testing.T) {
+	targetDir := t.TempDir()
+
+	if state, err := LoadTargetState(targetDir); err != nil || state != nil {
+		t.Fatalf("LoadTargetState() of a target without one = %+v, %v; want nil, nil", state, err)
+	}
+
+	state := NewTargetState()
+	state.Record(MappingState{Source: "snes", Destination: "SFC", Files: []CopiedFile{
+		{Path: "game.sfc", Size: 8, Hash: "aaaa"},
+		{Path: "Game.sfc", Source: "Game/Game.sfc", Size: 4, Hash: "bbbb"},
+	}})
+	state.Record(MappingState{Source: "gb", Destination: "GB", Files: []CopiedFile{{Path: "old.gb", Size: 1, Hash: "cccc"}}})
+	// a later copy of a mapping replaces what was recorded for it
+	state.Record(MappingState{Source: "gameboy", Destination: "GB", Files: []CopiedFile{{Path: "new.gb", Size: 2, Hash: "dddd"}}})
+	if err := state.Save(targetDir); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(TargetStatePath(targetDir)); err != nil {
+		t.Fatalf("state file not written: %v", err)
+	}
+
+	loaded, err := LoadTargetState(targetDir)
+	if err != nil {
+		t.Fatalf("LoadTargetState() error = %v", err)
+	}
+	if len(loaded.Mappings) != 2 || loaded.Mappings[0].Destination != "GB" || loaded.Mappings[1].Destination != "SFC" {
+		t.Fatalf("loaded mappings %+v, want GB then SFC", loaded.Mappings)
+	}
+
+	gb, ok := loaded.Mapping("GB")
+	if !ok || gb.Source != "gameboy" || len(gb.Files) != 1 || gb.Files[0].Path != "new.gb" {
+		t.Errorf("Mapping(\"GB\") = %+v, %v; want only the later copy", gb, ok)
+	}
+	sfc, _ := loaded.Mapping("SFC")
+	if sfc.Files[0].Path != "Game.sfc" || sfc.Files[0].SourcePath() != "Game/Game.sfc" || sfc.Files[1].SourcePath() != "game.sfc" {
+		t.Errorf("SFC files = %+v, want them sorted with their source paths", sfc.Files)
+	}
+	want := map[string]FileState{
+		"game.sfc": {Path: "game.sfc", Size: 8, Hash: "aaaa"},
+		"Game.sfc": {Path: "Game.sfc", Size: 4, Hash: "bbbb"},
+	}
+	if got := sfc.States(); !reflect.DeepEqual(got, want) {
+		t.Errorf("States() = %v, want %v", got, want)
+	}
+	if _, ok := loaded.Mapping("NES"); ok {
+		t.Error("Mapping() found a mapping never recorded")
+	}
+
+	if err := os.WriteFile(TargetStatePath(targetDir), []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatalf("failed to write state file: %v", err)
+	}
+	if _, err := LoadTargetState(targetDir); err == nil {
+		t.Error("LoadTargetState() of an unsupported version should fail")
+	}
+}
@@ -0,0 +1,138 @@
+package disc_refs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// Dangling is a cue sheet, GDI, or M3U playlist that refers to files that don't exist
+type Dangling struct {
+	// path of the sheet relative to the checked directory
+	Sheet string
+	// the references that couldn't be found, as written in the sheet
+	Missing []string
+}
+
+// IsSheet reports whether fileName is a cue sheet, GDI, or M3U playlist, by extension
+func IsSheet(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".cue", ".gdi", ".m3u":
+		return true
+	}
+	return false
+}
+
+// References returns the files a sheet refers to, as written in it, by the sheet's extension:
+//   - .cue: the file named by each FILE command, e.g. 'FILE "Game (Track 1).bin" BINARY'
+//   - .gdi: the file named on each track line after the first (track count) line, e.g.
+//     '1 0 4 2352 "Game (Track 1).bin" 0'
+//   - .m3u: every line that isn't blank or a '#' comment
+func References(fileName string, data []byte) []string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	refs := make([]string, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for lineNum := 0; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		switch ext {
+		case ".cue":
+			fields := splitFields(line)
+			if len(fields) >= 2 && strings.EqualFold(fields[0], "FILE") {
+				refs = append(refs, fields[1])
+			}
+		case ".gdi":
+			fields := splitFields(line)
+			// the first line is the number of tracks
+			if lineNum > 0 && len(fields) >= 5 {
+				refs = append(refs, fields[4])
+			}
+		case ".m3u":
+			if !strings.HasPrefix(line, "#") {
+				refs = append(refs, line)
+			}
+		}
+	}
+	return refs
+}
+
+// splits a line on whitespace, keeping double-quoted fields (which may contain spaces) together
+func splitFields(line string) []string {
+	fields := make([]string, 0)
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] == '"' {
+			if end := strings.IndexByte(line[1:], '"'); end >= 0 {
+				fields = append(fields, line[1:end+1])
+				line = line[end+2:]
+				continue
+			}
+		}
+		end := strings.IndexAny(line, " \t")
+		if end < 0 {
+			end = len(line)
+		}
+		fields = append(fields, line[:end])
+		line = line[end:]
+	}
+	return fields
+}
+
+// Check finds every sheet beneath dir and returns those referring to files that don't exist, sorted by path.
+// References are resolved relative to the sheet, and may use either slash; URLs are skipped.
+func Check(fs fsys.FS, dir string) ([]Dangling, error) {
+	dangling := make([]Dangling, 0)
+
+	err := fsys.Walk(fs, dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", filePath, err)
+		}
+		if info.IsDir() || !IsSheet(filePath) {
+			return nil
+		}
+
+		data, err := fs.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+
+		missing := make([]string, 0)
+		for _, ref := range References(filePath, data) {
+			if strings.Contains(ref, "://") {
+				continue
+			}
+			refPath := filepath.FromSlash(path.Clean(strings.ReplaceAll(ref, "\\", "/")))
+			if !filepath.IsAbs(refPath) {
+				refPath = filepath.Join(filepath.Dir(filePath), refPath)
+			}
+			if _, err := fs.Stat(refPath); os.IsNotExist(err) {
+				missing = append(missing, ref)
+			}
+		}
+
+		if len(missing) > 0 {
+			relPath, err := filepath.Rel(dir, filePath)
+			if err != nil {
+				return fmt.Errorf("failed to get relative path for %s: %w", filePath, err)
+			}
+			dangling = append(dangling, Dangling{Sheet: relPath, Missing: missing})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(dangling, func(i, j int) bool { return dangling[i].Sheet < dangling[j].Sheet })
+	return dangling, nil
+}
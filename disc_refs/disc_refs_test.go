@@ -0,0 +1,88 @@
+package disc_refs
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		data     string
+		want     []string
+	}{
+		{
+			name:     "cue",
+			fileName: "Game.cue",
+			data:     "\xef\xbb\xbfFILE \"Game (Track 1).bin\" BINARY\r\n  TRACK 01 MODE2/2352\r\n    INDEX 01 00:00:00\r\nfile track2.bin BINARY\r\n",
+			want:     []string{"Game (Track 1).bin", "track2.bin"},
+		},
+		{
+			name:     "gdi",
+			fileName: "Game.GDI",
+			data:     "3\n1 0 4 2352 track01.bin 0\n2 756 0 2352 \"Track 02.raw\" 0\n3 45000 4 2352 track03.bin 0\n",
+			want:     []string{"track01.bin", "Track 02.raw", "track03.bin"},
+		},
+		{
+			name:     "m3u",
+			fileName: "Game.m3u",
+			data:     "#EXTM3U\n\nmultidisk/Game (Disc 1).cue\r\n.\\multidisk\\Game (Disc 2).cue\n",
+			want:     []string{"multidisk/Game (Disc 1).cue", ".\\multidisk\\Game (Disc 2).cue"},
+		},
+		{
+			name:     "other",
+			fileName: "Game.bin",
+			data:     "FILE \"x.bin\" BINARY",
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := References(tt.fileName, []byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("References() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "PS1")
+
+	files := map[string]string{
+		"Complete.cue":                  "FILE \"Complete.bin\" BINARY",
+		"Complete.bin":                  "",
+		"Broken.cue":                    "FILE \"Broken (Track 1).bin\" BINARY\nFILE \"Broken (Track 2).bin\" BINARY",
+		"Broken (Track 1).bin":          "",
+		"Game.m3u":                      "multidisk/Game (Disc 1).cue\nmultidisk\\Game (Disc 2).cue",
+		"multidisk/Game (Disc 1).cue":   "FILE \"Game (Disc 1).bin\" BINARY",
+		"multidisk/Game (Disc 1).bin":   "",
+		"Streamed.m3u":                  "http://example.com/disc.cue",
+		"dreamcast/Game.gdi":            "1\n1 0 4 2352 track01.bin 0",
+		"dreamcast/unrelated/notes.txt": "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		mem.MkdirAll(filepath.Dir(filePath), 0755)
+		mem.WriteFile(filePath, []byte(content), 0644)
+	}
+
+	dangling, err := Check(mem, dir)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	expected := []Dangling{
+		{Sheet: "Broken.cue", Missing: []string{"Broken (Track 2).bin"}},
+		{Sheet: "Game.m3u", Missing: []string{"multidisk\\Game (Disc 2).cue"}},
+		{Sheet: filepath.Join("dreamcast", "Game.gdi"), Missing: []string{"track01.bin"}},
+	}
+	if !reflect.DeepEqual(dangling, expected) {
+		t.Errorf("Check() = %+v, want %+v", dangling, expected)
+	}
+}
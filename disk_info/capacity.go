@@ -0,0 +1,185 @@
+package disk_info
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// test files stay well under FAT32's 4GB limit
+	capacityTestFileSize = 256 * 1024 * 1024
+	capacityBlockSize    = 1024 * 1024
+	// left free so the filesystem itself doesn't run out of room mid-test
+	capacityTestReserve = 16 * 1024 * 1024
+)
+
+// begins the name of each file the capacity test writes to the top of the target; hidden so devices don't list
+// them. They're removed once the test finishes, so any found later are from a test that was interrupted.
+const CapacityTestFilePrefix = ".romcopy-capacity-test-"
+
+// CapacityResult summarizes a fake-capacity test
+type CapacityResult struct {
+	BytesWritten  uint64
+	BytesVerified uint64
+	// offset (across all test files) of the first block that didn't read back correctly; -1 if none
+	FirstBadOffset int64
+}
+
+// Passed reports whether every byte written read back intact
+func (r CapacityResult) Passed() bool {
+	return r.FirstBadOffset < 0 && r.BytesVerified == r.BytesWritten
+}
+
+// TestCapacity fills the free space in dir with pattern files (up to maxBytes, or all free space if maxBytes
+// is 0), reads them back to verify every block, then deletes them. Counterfeit cards that report more
+// capacity than they have silently wrap or discard writes past their real size, which shows up as blocks
+// that don't read back. progress is called after each test file is written and verified.
+//
+// Existing files are never touched. Note that verification of a card smaller than system memory may be
+// served from the OS cache; the test is most meaningful for cards larger than RAM.
+func TestCapacity(dir string, maxBytes uint64, progress func(stage string, done uint64, total uint64)) (CapacityResult, error) {
+	result := CapacityResult{FirstBadOffset: -1}
+
+	free, err := FreeSpace(dir)
+	if err != nil {
+		return result, err
+	}
+
+	total := uint64(0)
+	if free > capacityTestReserve {
+		total = free - capacityTestReserve
+	}
+	if maxBytes > 0 && maxBytes < total {
+		total = maxBytes
+	}
+	total -= total % capacityBlockSize
+
+	files := make([]string, 0)
+	defer func() {
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}()
+
+	// write phase
+	var block uint64
+	for result.BytesWritten < total {
+		size := uint64(capacityTestFileSize)
+		if remaining := total - result.BytesWritten; remaining < size {
+			size = remaining
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("%s%04d.bin", CapacityTestFilePrefix, len(files)))
+		files = append(files, path)
+
+		if err := writePatternFile(path, block, size); err != nil {
+			return result, err
+		}
+
+		block += size / capacityBlockSize
+		result.BytesWritten += size
+		if progress != nil {
+			progress("write", result.BytesWritten, total)
+		}
+	}
+
+	// verify phase
+	block = 0
+	for _, path := range files {
+		verified, badOffset, err := verifyPatternFile(path, block)
+		if err != nil {
+			return result, err
+		}
+
+		if badOffset >= 0 && result.FirstBadOffset < 0 {
+			result.FirstBadOffset = int64(block*capacityBlockSize) + badOffset
+		}
+		result.BytesVerified += verified
+		block += uint64(capacityTestFileSize / capacityBlockSize)
+		if progress != nil {
+			progress("verify", result.BytesVerified, total)
+		}
+	}
+
+	return result, nil
+}
+
+func writePatternFile(path string, firstBlock uint64, size uint64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create capacity test file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriterSize(file, capacityBlockSize)
+	buf := make([]byte, capacityBlockSize)
+	for i := uint64(0); i < size/capacityBlockSize; i++ {
+		fillPattern(buf, firstBlock+i)
+		if _, err := writer.Write(buf); err != nil {
+			return fmt.Errorf("failed to write capacity test file %s: %w", path, err)
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write capacity test file %s: %w", path, err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync capacity test file %s: %w", path, err)
+	}
+	return nil
+}
+
+// returns the number of bytes that verified and the offset within the file of the first bad block (-1 if none)
+func verifyPatternFile(path string, firstBlock uint64) (uint64, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, -1, fmt.Errorf("failed to open capacity test file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, capacityBlockSize)
+	expected := make([]byte, capacityBlockSize)
+	actual := make([]byte, capacityBlockSize)
+
+	var verified uint64
+	badOffset := int64(-1)
+	for i := uint64(0); ; i++ {
+		n, err := io.ReadFull(reader, actual)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return verified, badOffset, fmt.Errorf("failed to read capacity test file %s: %w", path, err)
+		}
+
+		fillPattern(expected, firstBlock+i)
+		if n == capacityBlockSize && string(actual) == string(expected) {
+			verified += capacityBlockSize
+		} else if badOffset < 0 {
+			badOffset = int64(i * capacityBlockSize)
+		}
+
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+
+	return verified, badOffset, nil
+}
+
+// fills buf with a pseudo-random stream seeded by the block number, so a card that wraps writes around
+// to earlier addresses returns data that can't match
+func fillPattern(buf []byte, block uint64) {
+	state := block*0x9E3779B97F4A7C15 + 1
+	for i := 0; i+8 <= len(buf); i += 8 {
+		// xorshift64
+		state ^= state << 13
+		state ^= state >> 7
+		state ^= state << 17
+		binary.LittleEndian.PutUint64(buf[i:], state)
+	}
+}
@@ -0,0 +1,78 @@
+package disk_info
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFillPatternDiffersPerBlock(t *testing.T) {
+	a := make([]byte, capacityBlockSize)
+	b := make([]byte, capacityBlockSize)
+	fillPattern(a, 0)
+	fillPattern(b, 1)
+	if string(a) == string(b) {
+		t.Error("pattern for different blocks should differ")
+	}
+
+	fillPattern(b, 0)
+	if string(a) != string(b) {
+		t.Error("pattern for the same block should be deterministic")
+	}
+}
+
+func TestTestCapacity(t *testing.T) {
+	dir := t.TempDir()
+
+	result, err := TestCapacity(dir, 3*capacityBlockSize, nil)
+	if err != nil {
+		t.Fatalf("TestCapacity() error = %v", err)
+	}
+
+	if !result.Passed() {
+		t.Errorf("TestCapacity() on healthy storage failed: %+v", result)
+	}
+	if result.BytesWritten != 3*capacityBlockSize {
+		t.Errorf("TestCapacity() wrote %d bytes, want %d", result.BytesWritten, 3*capacityBlockSize)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read test dir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), CapacityTestFilePrefix) {
+			t.Errorf("capacity test file %s was not cleaned up", entry.Name())
+		}
+	}
+}
+
+func TestVerifyPatternFileDetectsWrappedData(t *testing.T) {
+	dir := t.TempDir()
+	path := dir + "/pattern.bin"
+
+	if err := writePatternFile(path, 0, 2*capacityBlockSize); err != nil {
+		t.Fatalf("writePatternFile() error = %v", err)
+	}
+
+	// simulate a fake card wrapping the second block onto the first
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read pattern file: %v", err)
+	}
+	copy(content[capacityBlockSize:], content[:capacityBlockSize])
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatalf("failed to corrupt pattern file: %v", err)
+	}
+
+	verified, badOffset, err := verifyPatternFile(path, 0)
+	if err != nil {
+		t.Fatalf("verifyPatternFile() error = %v", err)
+	}
+	if verified != capacityBlockSize {
+		t.Errorf("verifyPatternFile() verified %d bytes, want %d", verified, capacityBlockSize)
+	}
+	if badOffset != capacityBlockSize {
+		t.Errorf("verifyPatternFile() bad offset = %d, want %d", badOffset, capacityBlockSize)
+	}
+}
@@ -0,0 +1,72 @@
+package disk_info
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// normalized filesystem names returned by FilesystemType for the filesystems we have special handling for
+const (
+	// FAT12/16/32 can't be told apart on every platform; removable media of any real size is FAT32
+	FilesystemFAT   = "fat32"
+	FilesystemExFAT = "exfat"
+	FilesystemNTFS  = "ntfs"
+)
+
+// FilesystemType returns a lowercase name for the filesystem holding path (e.g. "fat32", "exfat", "ext4").
+// As with FreeSpace, the nearest existing parent is inspected if path doesn't exist yet.
+func FilesystemType(path string) (string, error) {
+	existing, err := nearestExistingDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	fsType, err := filesystemType(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to read filesystem type for %s: %w", existing, err)
+	}
+
+	return fsType, nil
+}
+
+// FreeSpace returns the number of bytes available to the current user on the volume holding path.
+// If path does not exist yet (e.g. a destination platform folder that will be created during copy),
+// the nearest existing parent directory is inspected instead.
+func FreeSpace(path string) (uint64, error) {
+	existing, err := nearestExistingDir(path)
+	if err != nil {
+		return 0, err
+	}
+
+	free, err := freeSpace(existing)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read free space for %s: %w", existing, err)
+	}
+
+	return free, nil
+}
+
+// walks up from path until it finds a directory that exists
+func nearestExistingDir(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path for %s: %w", path, err)
+	}
+
+	for {
+		info, err := os.Stat(absPath)
+		if err == nil && info.IsDir() {
+			return absPath, nil
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to access %s: %w", absPath, err)
+		}
+
+		parent := filepath.Dir(absPath)
+		if parent == absPath {
+			return "", fmt.Errorf("no existing parent directory found for %s", path)
+		}
+		absPath = parent
+	}
+}
@@ -0,0 +1,43 @@
+//go:build darwin || freebsd
+
+package disk_info
+
+import (
+	"strings"
+	"syscall"
+)
+
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	// field widths differ between platforms, so normalize before multiplying
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
+
+func filesystemType(path string) (string, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", err
+	}
+
+	var name strings.Builder
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name.WriteByte(byte(c))
+	}
+
+	switch name.String() {
+	case "msdos", "msdosfs":
+		return FilesystemFAT, nil
+	case "exfat":
+		return FilesystemExFAT, nil
+	case "ntfs":
+		return FilesystemNTFS, nil
+	}
+	return strings.ToLower(name.String()), nil
+}
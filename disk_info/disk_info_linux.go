@@ -0,0 +1,46 @@
+//go:build linux
+
+package disk_info
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// statfs f_type magic numbers for filesystems commonly found on ROM hosts and handheld SD cards
+var linuxFilesystemMagic = map[int64]string{
+	0x4d44:     FilesystemFAT,
+	0x2011bab0: FilesystemExFAT,
+	0x5346544e: FilesystemNTFS,
+	0x65735546: "fuseblk",
+	0xef53:     "ext4",
+	0x9123683e: "btrfs",
+	0x58465342: "xfs",
+	0x01021994: "tmpfs",
+	0x6969:     "nfs",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x794c7630: "overlayfs",
+	0xf2f52010: "f2fs",
+}
+
+func freeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
+
+func filesystemType(path string) (string, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", err
+	}
+
+	if name, ok := linuxFilesystemMagic[int64(stat.Type)]; ok {
+		return name, nil
+	}
+	return fmt.Sprintf("unknown (0x%x)", stat.Type), nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package disk_info
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// other platforms can't be inspected, so the space and filesystem checks are skipped there
+
+func freeSpace(path string) (uint64, error) {
+	return 0, fmt.Errorf("reading free space is unsupported on %s", runtime.GOOS)
+}
+
+func filesystemType(path string) (string, error) {
+	return "", fmt.Errorf("reading the filesystem type is unsupported on %s", runtime.GOOS)
+}
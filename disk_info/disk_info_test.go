@@ -0,0 +1,49 @@
+package disk_info
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNearestExistingDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "existing")
+	if err := os.MkdirAll(existing, 0755); err != nil {
+		t.Fatalf("Failed to create test directory: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"existing directory", existing, existing},
+		{"missing child", filepath.Join(existing, "missing"), existing},
+		{"missing nested children", filepath.Join(existing, "a", "b", "c"), existing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nearestExistingDir(tt.path)
+			if err != nil {
+				t.Fatalf("nearestExistingDir() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("nearestExistingDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	free, err := FreeSpace(filepath.Join(tmpDir, "not", "yet", "created"))
+	if err != nil {
+		t.Fatalf("FreeSpace() error = %v", err)
+	}
+	if free == 0 {
+		t.Errorf("FreeSpace() = 0, expected some free space on temp volume")
+	}
+}
@@ -0,0 +1,81 @@
+//go:build windows
+
+package disk_info
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                 = syscall.NewLazyDLL("kernel32.dll")
+	procGetDiskFreeSpaceEx   = kernel32.NewProc("GetDiskFreeSpaceExW")
+	procGetVolumeInformation = kernel32.NewProc("GetVolumeInformationW")
+	procGetVolumePathName    = kernel32.NewProc("GetVolumePathNameW")
+)
+
+func freeSpace(path string) (uint64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytesAvailable uint64
+	ret, _, callErr := procGetDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeBytesAvailable)),
+		0,
+		0,
+	)
+	if ret == 0 {
+		return 0, callErr
+	}
+
+	return freeBytesAvailable, nil
+}
+
+func filesystemType(path string) (string, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+
+	// GetVolumeInformation wants the volume root (e.g. 'J:\'), not an arbitrary folder
+	volumeRoot := make([]uint16, syscall.MAX_PATH+1)
+	ret, _, callErr := procGetVolumePathName.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&volumeRoot[0])),
+		uintptr(len(volumeRoot)),
+	)
+	if ret == 0 {
+		return "", callErr
+	}
+
+	fsName := make([]uint16, syscall.MAX_PATH+1)
+	ret, _, callErr = procGetVolumeInformation.Call(
+		uintptr(unsafe.Pointer(&volumeRoot[0])),
+		0,
+		0,
+		0,
+		0,
+		0,
+		uintptr(unsafe.Pointer(&fsName[0])),
+		uintptr(len(fsName)),
+	)
+	if ret == 0 {
+		return "", callErr
+	}
+
+	switch name := syscall.UTF16ToString(fsName); strings.ToUpper(name) {
+	case "FAT32", "FAT", "FAT16":
+		return FilesystemFAT, nil
+	case "EXFAT":
+		return FilesystemExFAT, nil
+	case "NTFS":
+		return FilesystemNTFS, nil
+	default:
+		return strings.ToLower(name), nil
+	}
+}
@@ -0,0 +1,104 @@
+package disk_info
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// FAT32 hard limits that commonly trip up ROM copies to handheld SD cards
+const (
+	FAT32MaxFileSize = 4*1024*1024*1024 - 1
+	// 32-byte directory entries per directory, including '.' and '..'
+	FAT32MaxDirEntries = 65536
+	// UTF-16 code units in a long file name, and the practical limit for a full path on most devices
+	FAT32MaxPathLength = 255
+	// UTF-16 code units stored in each long file name directory entry
+	fat32CharsPerLFNEntry = 13
+)
+
+// PlannedFile is a file that is about to be written to a FAT32 volume
+type PlannedFile struct {
+	// slash-separated path relative to the volume (or target directory) root
+	Path string
+	Size int64
+}
+
+// FAT32Issue is a planned file or directory that would exceed a FAT32 limit
+type FAT32Issue struct {
+	Path   string
+	Reason string
+}
+
+// CheckFAT32Limits returns every planned file larger than 4GB, every directory that would exceed the
+// FAT32 directory entry limit, and every path longer than 255 characters
+func CheckFAT32Limits(files []PlannedFile) []FAT32Issue {
+	issues := make([]FAT32Issue, 0)
+
+	// directory -> names of its direct children (files and subdirectories)
+	children := make(map[string]map[string]bool)
+	addChild := func(dir string, name string) {
+		if children[dir] == nil {
+			children[dir] = make(map[string]bool)
+		}
+		children[dir][name] = true
+	}
+
+	for _, f := range files {
+		filePath := strings.Trim(path.Clean(f.Path), "/")
+
+		if f.Size > FAT32MaxFileSize {
+			issues = append(issues, FAT32Issue{
+				Path:   filePath,
+				Reason: fmt.Sprintf("file is %d bytes; FAT32 cannot store files of 4GB or larger", f.Size),
+			})
+		}
+
+		if length := utf16Len(filePath); length > FAT32MaxPathLength {
+			issues = append(issues, FAT32Issue{
+				Path:   filePath,
+				Reason: fmt.Sprintf("path is %d characters; the limit is %d", length, FAT32MaxPathLength),
+			})
+		}
+
+		// register the file and every intermediate directory with its parent
+		for current := filePath; current != "." && current != ""; current = path.Dir(current) {
+			addChild(path.Dir(current), path.Base(current))
+		}
+	}
+
+	dirs := make([]string, 0, len(children))
+	for dir := range children {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		// '.' and '..' entries, plus a short name entry and long name entries for each child
+		entries := 2
+		for name := range children[dir] {
+			entries += 1 + (utf16Len(name)+fat32CharsPerLFNEntry-1)/fat32CharsPerLFNEntry
+		}
+
+		if entries > FAT32MaxDirEntries {
+			issues = append(issues, FAT32Issue{
+				Path:   dir,
+				Reason: fmt.Sprintf("directory would hold %d item(s) using %d directory entries; FAT32 allows %d", len(children[dir]), entries, FAT32MaxDirEntries),
+			})
+		}
+	}
+
+	return issues
+}
+
+// PathLength returns the length of a path as FAT32 and exFAT count it, in UTF-16 code units; a character
+// outside the Basic Multilingual Plane, like an emoji, counts as two
+func PathLength(filePath string) int {
+	return utf16Len(filePath)
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
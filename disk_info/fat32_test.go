@@ -0,0 +1,74 @@
+package disk_info
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCheckFAT32Limits(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      []PlannedFile
+		wantIssues []string
+	}{
+		{
+			name: "nothing to report",
+			files: []PlannedFile{
+				{Path: "SFC/game.sfc", Size: 4 * 1024 * 1024},
+				{Path: "SFC/images/game.png", Size: 1024},
+			},
+			wantIssues: []string{},
+		},
+		{
+			name: "file over 4GB",
+			files: []PlannedFile{
+				{Path: "PS2/huge.iso", Size: FAT32MaxFileSize + 1},
+				{Path: "PS2/just_fits.iso", Size: FAT32MaxFileSize},
+			},
+			wantIssues: []string{"PS2/huge.iso"},
+		},
+		{
+			name: "path too long",
+			files: []PlannedFile{
+				{Path: "PS1/" + strings.Repeat("a", 252), Size: 1},
+				{Path: "PS1/" + strings.Repeat("b", 251), Size: 1},
+			},
+			wantIssues: []string{"PS1/" + strings.Repeat("a", 252)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := CheckFAT32Limits(tt.files)
+			if len(issues) != len(tt.wantIssues) {
+				t.Fatalf("CheckFAT32Limits() returned %d issues (%v), want %d", len(issues), issues, len(tt.wantIssues))
+			}
+			for i, want := range tt.wantIssues {
+				if issues[i].Path != want {
+					t.Errorf("issue %d path = %q, want %q", i, issues[i].Path, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckFAT32LimitsDirectoryEntries(t *testing.T) {
+	// 20-character names take 1 short + 2 long name entries each, so ~21845 files fill a directory
+	files := make([]PlannedFile, 0, 22000)
+	for i := 0; i < 22000; i++ {
+		files = append(files, PlannedFile{Path: fmt.Sprintf("ARCADE/game_%08d.zip", i), Size: 1})
+	}
+
+	issues := CheckFAT32Limits(files)
+	if len(issues) != 1 {
+		t.Fatalf("CheckFAT32Limits() returned %d issues, want 1", len(issues))
+	}
+	if issues[0].Path != "ARCADE" {
+		t.Errorf("issue path = %q, want %q", issues[0].Path, "ARCADE")
+	}
+
+	if issues := CheckFAT32Limits(files[:21000]); len(issues) != 0 {
+		t.Errorf("CheckFAT32Limits() with 21000 files returned %d issues, want 0", len(issues))
+	}
+}
@@ -0,0 +1,62 @@
+package disk_info
+
+import (
+	"path"
+	"strings"
+)
+
+// FATIllegalChars are the characters FAT32, exFAT, and NTFS (through Windows) don't allow in names, besides the
+// control characters below ' '
+const FATIllegalChars = `"*:<>?\|`
+
+// IsFATIllegalRune reports whether r can't be used in a FAT32 or exFAT name
+func IsFATIllegalRune(r rune) bool {
+	return r < ' ' || strings.ContainsRune(FATIllegalChars, r)
+}
+
+// IsFATIllegalName reports whether a single file or folder name can't be created as it is on FAT32 or exFAT:
+// it has an illegal character, or ends in a dot or space, which Windows drops, so the file can't be opened by
+// the name it was written with
+func IsFATIllegalName(name string) bool {
+	return strings.IndexFunc(name, IsFATIllegalRune) >= 0 || strings.TrimRight(name, ". ") != name
+}
+
+// HasFATIllegalName reports whether any component of a slash-separated path can't be created as it is on FAT32
+// or exFAT
+func HasFATIllegalName(filePath string) bool {
+	for _, part := range strings.Split(filePath, "/") {
+		if part != "." && part != ".." && IsFATIllegalName(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeFATPath replaces each illegal character in every component of a slash-separated path with substitute
+// and drops the dots and spaces they end in, e.g. 'Zelda: A Link to the Past?.sfc' becomes
+// 'Zelda_ A Link to the Past_.sfc'. A component left empty becomes substitute, or '_' if that's empty too.
+func SafeFATPath(filePath string, substitute string) string {
+	parts := strings.Split(filePath, "/")
+	for i, part := range parts {
+		if part == "." || part == ".." || !IsFATIllegalName(part) {
+			continue
+		}
+
+		var safe strings.Builder
+		for _, r := range part {
+			if IsFATIllegalRune(r) {
+				safe.WriteString(substitute)
+			} else {
+				safe.WriteRune(r)
+			}
+		}
+		parts[i] = strings.TrimRight(safe.String(), ". ")
+		if parts[i] == "" {
+			parts[i] = strings.TrimRight(substitute, ". ")
+		}
+		if parts[i] == "" {
+			parts[i] = "_"
+		}
+	}
+	return path.Join(parts...)
+}
@@ -0,0 +1,34 @@
+package disk_info
+
+import "testing"
+
+func TestSafeFATPath(t *testing.T) {
+	tests := []struct {
+		path       string
+		substitute string
+		want       string
+	}{
+		{"Zelda: A Link to the Past.sfc", "_", "Zelda_ A Link to the Past.sfc"},
+		{"Who Framed Roger Rabbit?/Disc 1.cue", "_", "Who Framed Roger Rabbit_/Disc 1.cue"},
+		{`"Weird" <Al> *|\.nes`, "", "Weird Al .nes"},
+		{"Mr. Do!.nes", "-", "Mr. Do!.nes"},
+		{"Hacks./Mario .", "_", "Hacks/Mario"},
+		{"???/game.nes", "", "_/game.nes"},
+		{"tab\there.nes", " ", "tab here.nes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := SafeFATPath(tt.path, tt.substitute); got != tt.want {
+				t.Errorf("SafeFATPath(%q, %q) = %q, want %q", tt.path, tt.substitute, got, tt.want)
+			}
+			if HasFATIllegalName(SafeFATPath(tt.path, tt.substitute)) {
+				t.Errorf("SafeFATPath(%q, %q) still contains an illegal name", tt.path, tt.substitute)
+			}
+		})
+	}
+
+	if !HasFATIllegalName("hacks/Zelda?.sfc") || HasFATIllegalName("hacks/Zelda.sfc") || HasFATIllegalName("../Zelda.sfc") {
+		t.Error("HasFATIllegalName() didn't check every path component")
+	}
+}
@@ -0,0 +1,140 @@
+package disk_info
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TargetOS is a handheld firmware or frontend recognized from the files it keeps on the target
+type TargetOS struct {
+	Name string
+	// first line of the firmware's version file; empty if it has none or it couldn't be read
+	Version string
+	// folder (relative to the target root) the firmware looks for platform folders in; empty if unknown
+	RomsDir string
+	// where the firmware mounts the target, e.g. '/mnt/SDCARD', for files that refer to others by absolute path;
+	// empty if unknown
+	MountPath string
+	// filesystems (as returned by FilesystemType) the firmware can read the card in; empty if any will do
+	Filesystems []string
+	// entries in a single folder past which the firmware's game list gets slow to open and scroll; 0 if
+	// it copes with any number
+	MaxFolderEntries int
+}
+
+// what identifies each firmware on a card
+type knownTargetOS struct {
+	name string
+	// slash-separated paths relative to the target root; any one being present identifies the firmware
+	markers     []string
+	versionFile string
+	romsDir     string
+	mountPath   string
+	filesystems []string
+	// see TargetOS.MaxFolderEntries
+	maxFolderEntries int
+}
+
+// checked in order, so firmware installed on top of another (e.g. Onion over the Miyoo stock firmware) comes
+// first
+var knownTargetOSes = []knownTargetOS{
+	{
+		name:        "Onion",
+		markers:     []string{".tmp_update/onionVersion"},
+		versionFile: ".tmp_update/onionVersion/version.txt",
+		romsDir:     "Roms",
+		mountPath:   "/mnt/SDCARD",
+		filesystems: []string{FilesystemFAT},
+		// keeps the stock firmware's MainUI game list, which lags badly past a couple thousand entries
+		maxFolderEntries: 2000,
+	},
+	{
+		name:        "MinUI",
+		markers:     []string{"MinUI.zip", ".system/version.txt"},
+		versionFile: ".system/version.txt",
+		romsDir:     "Roms",
+		mountPath:   "/mnt/SDCARD",
+		filesystems: []string{FilesystemFAT},
+	},
+	{
+		name:        "spruce",
+		markers:     []string{"spruce"},
+		romsDir:     "Roms",
+		mountPath:   "/mnt/SDCARD",
+		filesystems: []string{FilesystemFAT},
+		// also MainUI
+		maxFolderEntries: 2000,
+	},
+	{
+		name:        "muOS",
+		markers:     []string{"MUOS"},
+		romsDir:     "ROMS",
+		mountPath:   "/mnt/mmc",
+		filesystems: []string{FilesystemFAT, FilesystemExFAT},
+	},
+	{
+		name:      "Batocera",
+		markers:   []string{"system/batocera.conf", "batocera-boot.conf"},
+		romsDir:   "roms",
+		mountPath: "/userdata",
+	},
+	{
+		name:             "Miyoo stock firmware",
+		markers:          []string{"miyoo/app"},
+		romsDir:          "Roms",
+		mountPath:        "/mnt/SDCARD",
+		filesystems:      []string{FilesystemFAT},
+		maxFolderEntries: 2000,
+	},
+}
+
+// DetectTargetOS looks for the files known firmwares keep on their cards and returns the first match, or nil
+// if targetDir doesn't look like any of them
+func DetectTargetOS(targetDir string) *TargetOS {
+	for _, known := range knownTargetOSes {
+		if !anyExists(targetDir, known.markers) {
+			continue
+		}
+
+		detected := &TargetOS{
+			Name:        known.name,
+			RomsDir:     known.romsDir,
+			MountPath:   known.mountPath,
+			Filesystems: known.filesystems,
+
+			MaxFolderEntries: known.maxFolderEntries,
+		}
+		if known.versionFile != "" {
+			detected.Version = readFirstLine(filepath.Join(targetDir, filepath.FromSlash(known.versionFile)))
+		}
+		return detected
+	}
+
+	return nil
+}
+
+func anyExists(root string, relPaths []string) bool {
+	for _, relPath := range relPaths {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(relPath))); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the trimmed first line of a file, or "" if it can't be read
+func readFirstLine(filePath string) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package disk_info
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectTargetOS(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    string
+		version string
+		// expected MountPath
+		mountPath string
+		// expected MaxFolderEntries
+		maxEntries int
+	}{
+		{
+			name: "onion over stock firmware",
+			files: map[string]string{
+				".tmp_update/onionVersion/version.txt": "4.3.1-1\n",
+				"miyoo/app/MainUI":                     "",
+			},
+			want:       "Onion",
+			version:    "4.3.1-1",
+			mountPath:  "/mnt/SDCARD",
+			maxEntries: 2000,
+		},
+		{
+			name:       "stock firmware",
+			files:      map[string]string{"miyoo/app/MainUI": ""},
+			want:       "Miyoo stock firmware",
+			mountPath:  "/mnt/SDCARD",
+			maxEntries: 2000,
+		},
+		{
+			name:      "batocera share",
+			files:     map[string]string{"system/batocera.conf": ""},
+			want:      "Batocera",
+			mountPath: "/userdata",
+		},
+		{
+			name:  "unknown",
+			files: map[string]string{"Roms/GBA/game.gba": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			for name, content := range tt.files {
+				filePath := filepath.Join(targetDir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+					t.Fatalf("failed to create dir for %s: %v", name, err)
+				}
+				if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to create %s: %v", name, err)
+				}
+			}
+
+			detected := DetectTargetOS(targetDir)
+			if tt.want == "" {
+				if detected != nil {
+					t.Errorf("DetectTargetOS() = %q, want nil", detected.Name)
+				}
+				return
+			}
+
+			if detected == nil || detected.Name != tt.want || detected.Version != tt.version || detected.MountPath != tt.mountPath || detected.MaxFolderEntries != tt.maxEntries {
+				t.Errorf("DetectTargetOS() = %+v, want %s %s", detected, tt.want, tt.version)
+			}
+		})
+	}
+}
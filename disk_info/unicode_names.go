@@ -0,0 +1,25 @@
+package disk_info
+
+import (
+	"golang.org/x/text/unicode/norm"
+)
+
+// NameForms are the Unicode normalization forms names can be written in. macOS writes names decomposed (NFD,
+// 'e' then a combining accent), while Linux takes names byte for byte and most software writes them composed
+// (NFC, 'é'), so a name from a Mac can look garbled on a handheld, or sit beside its composed twin as a second
+// file.
+var NameForms = map[string]norm.Form{
+	"nfc":  norm.NFC,
+	"nfd":  norm.NFD,
+	"nfkc": norm.NFKC,
+	"nfkd": norm.NFKD,
+}
+
+// NormalizePath writes a path in the named form of NameForms; a form it doesn't name leaves it as it is
+func NormalizePath(filePath string, form string) string {
+	f, ok := NameForms[form]
+	if !ok {
+		return filePath
+	}
+	return f.String(filePath)
+}
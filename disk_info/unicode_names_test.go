@@ -0,0 +1,28 @@
+package disk_info
+
+import "testing"
+
+func TestNormalizePath(t *testing.T) {
+	decomposed := "Poke\u0301mon/Poke\u0301mon Rouge.gb"
+	composed := "Pok\u00e9mon/Pok\u00e9mon Rouge.gb"
+	tests := []struct {
+		path string
+		form string
+		want string
+	}{
+		{decomposed, "nfc", composed},
+		{composed, "nfc", composed},
+		{composed, "nfd", decomposed},
+		{"Ｚｅｌｄａ.sfc", "nfkc", "Zelda.sfc"},
+		{decomposed, "", decomposed},
+		{"Zelda.sfc", "nfd", "Zelda.sfc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.form+" "+tt.path, func(t *testing.T) {
+			if got := NormalizePath(tt.path, tt.form); got != tt.want {
+				t.Errorf("NormalizePath(%q, %q) = %q, want %q", tt.path, tt.form, got, tt.want)
+			}
+		})
+	}
+}
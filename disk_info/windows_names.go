@@ -0,0 +1,67 @@
+package disk_info
+
+import (
+	"path"
+	"runtime"
+	"strings"
+)
+
+// device names Windows reserves in every folder, with or without an extension ('CON.nes' is as off-limits as 'CON')
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// WindowsAccessible reports whether files under path will be read or written by Windows, either because
+// we're running on it or because the target is formatted with a Windows filesystem (e.g. an SD card that
+// will also be plugged into a Windows PC)
+func WindowsAccessible(path string) bool {
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	fsType, err := FilesystemType(path)
+	if err != nil {
+		return false
+	}
+	return fsType == FilesystemFAT || fsType == FilesystemExFAT || fsType == FilesystemNTFS
+}
+
+// IsWindowsReservedName reports whether a single file or folder name is a Windows device name
+func IsWindowsReservedName(name string) bool {
+	stem := name
+	if dot := strings.Index(stem, "."); dot >= 0 {
+		stem = stem[:dot]
+	}
+	// Windows ignores trailing spaces, so 'CON .txt' is reserved too
+	return windowsReservedNames[strings.ToUpper(strings.TrimRight(stem, " "))]
+}
+
+// HasWindowsReservedName reports whether any component of a slash-separated path is a Windows device name
+func HasWindowsReservedName(filePath string) bool {
+	for _, part := range strings.Split(filePath, "/") {
+		if IsWindowsReservedName(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// SafeWindowsPath renames every reserved component of a slash-separated path by appending '_' to its stem,
+// e.g. 'homebrew/CON.nes' becomes 'homebrew/CON_.nes'. Other components are unchanged.
+func SafeWindowsPath(filePath string) string {
+	parts := strings.Split(filePath, "/")
+	for i, part := range parts {
+		if !IsWindowsReservedName(part) {
+			continue
+		}
+
+		if dot := strings.Index(part, "."); dot >= 0 {
+			parts[i] = part[:dot] + "_" + part[dot:]
+		} else {
+			parts[i] = part + "_"
+		}
+	}
+	return path.Join(parts...)
+}
@@ -0,0 +1,59 @@
+package disk_info
+
+import "testing"
+
+func TestIsWindowsReservedName(t *testing.T) {
+	tests := []struct {
+		name     string
+		reserved bool
+	}{
+		{"CON", true},
+		{"con", true},
+		{"Nul.nes", true},
+		{"AUX.tar.gz", true},
+		{"COM1", true},
+		{"lpt9.txt", true},
+		{"CON .txt", true},
+		{"COM0", false},
+		{"COM10", false},
+		{"CONSOLE.nes", false},
+		{"Contra.nes", false},
+		{"game.CON", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsWindowsReservedName(tt.name); got != tt.reserved {
+				t.Errorf("IsWindowsReservedName(%q) = %v, want %v", tt.name, got, tt.reserved)
+			}
+		})
+	}
+}
+
+func TestSafeWindowsPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"homebrew/CON.nes", "homebrew/CON_.nes"},
+		{"aux/prn", "aux_/prn_"},
+		{"nul/game.nes", "nul_/game.nes"},
+		{"Contra (USA).nes", "Contra (USA).nes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := SafeWindowsPath(tt.path); got != tt.want {
+				t.Errorf("SafeWindowsPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+			if HasWindowsReservedName(SafeWindowsPath(tt.path)) {
+				t.Errorf("SafeWindowsPath(%q) still contains a reserved name", tt.path)
+			}
+		})
+	}
+
+	if !HasWindowsReservedName("homebrew/CON.nes") || HasWindowsReservedName("homebrew/Contra.nes") {
+		t.Error("HasWindowsReservedName() didn't check every path component")
+	}
+}
@@ -0,0 +1,210 @@
+package examples
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/disk_info"
+)
+
+// Example is a ready-to-run command line for a common scenario. Its arguments may contain placeholders, which
+// Fill replaces with values from the user's environment:
+//   - {sourceDir}: the '--sourceDir' given alongside the examples command
+//   - {targetDir}: the '--targetDir' given alongside the examples command
+//   - {romsDir}: the folder the firmware detected on the target keeps platform folders in, or the example's
+//     DefaultRomsDir if none was detected
+type Example struct {
+	Name        string
+	Description string
+	// used for {romsDir} when no firmware is detected on the target
+	DefaultRomsDir string
+	Args           []string
+}
+
+// All lists every example in the order they're shown
+var All = []Example{
+	{
+		Name:           "miyoo-artwork",
+		Description:    "Miyoo Mini artwork fix: copy ROMs with Skraper artwork, moving the 'images' folders to the 'Imgs' folders the Miyoo shows box art from and converting game lists to the Miyoo's",
+		DefaultRomsDir: "Roms",
+		Args: []string{
+			"--sourceDir", "{sourceDir}",
+			"--targetDir", "{targetDir}",
+			"--mapping", "nes:{romsDir}/FC",
+			"--mapping", "snes:{romsDir}/SFC",
+			"--mapping", "gb:{romsDir}/GB",
+			"--mapping", "gbc:{romsDir}/GBC",
+			"--mapping", "gba:{romsDir}/GBA",
+			"--mapping", "megadrive:{romsDir}/MD",
+			"--mapping", "psx:{romsDir}/PS",
+			"--rename", "images:Imgs",
+			"--convertGamelist", "miyoo",
+		},
+	},
+	{
+		Name:           "batocera-sync",
+		Description:    "Batocera full sync: replace every platform folder on the card with the source's, swapping them in only once everything has copied, and verify every file afterwards",
+		DefaultRomsDir: "roms",
+		Args: []string{
+			"--sourceDir", "{sourceDir}",
+			"--targetDir", "{targetDir}",
+			"--mapping", "nes:{romsDir}/nes",
+			"--mapping", "snes:{romsDir}/snes",
+			"--mapping", "gb:{romsDir}/gb",
+			"--mapping", "gbc:{romsDir}/gbc",
+			"--mapping", "gba:{romsDir}/gba",
+			"--mapping", "megadrive:{romsDir}/megadrive",
+			"--mapping", "psx:{romsDir}/psx",
+			"--cleanTarget",
+			"--transactional",
+			"--flush",
+			"--verify",
+		},
+	},
+	{
+		Name:           "favorites-card",
+		Description:    "Favorites-only card: copy only files tagged '_favorite' in their names, one copy of each game, leaving out betas, prototypes, and demos",
+		DefaultRomsDir: "Roms",
+		Args: []string{
+			"--sourceDir", "{sourceDir}",
+			"--targetDir", "{targetDir}",
+			"--mapping", "nes:{romsDir}/FC",
+			"--mapping", "snes:{romsDir}/SFC",
+			"--mapping", "gba:{romsDir}/GBA",
+			"--copyInclude", "**/*_favorite*",
+			"--excludeTags", "beta,proto,demo",
+			"--dedupe",
+		},
+	},
+}
+
+// Find returns the example with the given name
+func Find(name string) (Example, bool) {
+	for _, example := range All {
+		if strings.EqualFold(example.Name, name) {
+			return example, true
+		}
+	}
+	return Example{}, false
+}
+
+// Names lists the name of every example
+func Names() []string {
+	names := make([]string, 0, len(All))
+	for _, example := range All {
+		names = append(names, example.Name)
+	}
+	return names
+}
+
+// Environment holds the values placeholders are filled from; empty values leave their placeholders in place
+type Environment struct {
+	SourceDir string
+	TargetDir string
+	// folder the firmware on the target keeps platform folders in
+	RomsDir string
+	// name of the firmware detected on the target, for display
+	TargetOS string
+}
+
+// DetectEnvironment fills an Environment from the given directories, looking for known firmware on the target
+func DetectEnvironment(sourceDir string, targetDir string) Environment {
+	env := Environment{SourceDir: sourceDir, TargetDir: targetDir}
+	if targetDir != "" {
+		if targetOS := disk_info.DetectTargetOS(targetDir); targetOS != nil {
+			env.TargetOS = targetOS.Name
+			env.RomsDir = targetOS.RomsDir
+		}
+	}
+	return env
+}
+
+// Fill returns the example's arguments with placeholders replaced by values from env, along with the names of
+// any placeholders left unfilled
+func (e Example) Fill(env Environment) ([]string, []string) {
+	romsDir := env.RomsDir
+	if romsDir == "" {
+		romsDir = e.DefaultRomsDir
+	}
+	values := []struct{ placeholder, value string }{
+		{"{sourceDir}", env.SourceDir},
+		{"{targetDir}", env.TargetDir},
+		{"{romsDir}", romsDir},
+	}
+
+	args := make([]string, 0, len(e.Args))
+	missing := make([]string, 0)
+	for _, arg := range e.Args {
+		for _, v := range values {
+			if !strings.Contains(arg, v.placeholder) {
+				continue
+			}
+			if v.value == "" {
+				if !containsString(missing, v.placeholder) {
+					missing = append(missing, v.placeholder)
+				}
+				continue
+			}
+			arg = strings.ReplaceAll(arg, v.placeholder, filepath.ToSlash(v.value))
+		}
+		args = append(args, arg)
+	}
+	return args, missing
+}
+
+// CommandLine formats a command and its arguments for pasting into the current platform's shell, one flag per
+// line
+func CommandLine(command string, args []string) string {
+	continuation := " \\\n    "
+	if runtime.GOOS == "windows" {
+		continuation = " ^\n    "
+	}
+
+	var sb strings.Builder
+	sb.WriteString(quote(command))
+	for i := 0; i < len(args); i++ {
+		sb.WriteString(continuation)
+		sb.WriteString(quote(args[i]))
+		// a flag's value stays on its line
+		if strings.HasPrefix(args[i], "--") && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			i++
+			sb.WriteString(" ")
+			sb.WriteString(quote(args[i]))
+		}
+	}
+	return sb.String()
+}
+
+// quotes arg if the shell would otherwise expand or split it
+func quote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t'\"*?[]{}$&|;<>()!`~#\\^%") {
+		return arg
+	}
+	if runtime.GOOS == "windows" {
+		return `"` + strings.ReplaceAll(arg, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe returns a one-line summary of env for display, e.g. "Onion detected on /mnt/sd"
+func (env Environment) Describe() string {
+	switch {
+	case env.TargetDir == "":
+		return "no '--targetDir' given"
+	case env.TargetOS == "":
+		return fmt.Sprintf("no known firmware detected on %s", env.TargetDir)
+	default:
+		return fmt.Sprintf("%s detected on %s", env.TargetOS, env.TargetDir)
+	}
+}
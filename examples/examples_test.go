@@ -0,0 +1,84 @@
+package examples
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestFill(t *testing.T) {
+	example := Example{
+		Name:           "test",
+		DefaultRomsDir: "Roms",
+		Args:           []string{"--sourceDir", "{sourceDir}", "--targetDir", "{targetDir}", "--mapping", "nes:{romsDir}/FC"},
+	}
+
+	args, missing := example.Fill(Environment{SourceDir: "/roms", TargetDir: "/mnt/sd"})
+	if want := []string{"--sourceDir", "/roms", "--targetDir", "/mnt/sd", "--mapping", "nes:Roms/FC"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Fill() = %v; want %v", args, want)
+	}
+	if len(missing) != 0 {
+		t.Errorf("Fill() missing = %v; want none", missing)
+	}
+
+	// the detected firmware's folder wins over the example's default, and unfilled placeholders are kept
+	args, missing = example.Fill(Environment{TargetDir: "/mnt/sd", RomsDir: "ROMS"})
+	if want := []string{"--sourceDir", "{sourceDir}", "--targetDir", "/mnt/sd", "--mapping", "nes:ROMS/FC"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Fill() = %v; want %v", args, want)
+	}
+	if want := []string{"{sourceDir}"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("Fill() missing = %v; want %v", missing, want)
+	}
+}
+
+func TestDetectEnvironment(t *testing.T) {
+	targetDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(targetDir, "system"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(targetDir, "system", "batocera.conf"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := DetectEnvironment("/roms", targetDir)
+	if env.TargetOS != "Batocera" || env.RomsDir != "roms" {
+		t.Errorf("DetectEnvironment() = %+v; want Batocera with 'roms'", env)
+	}
+
+	if env := DetectEnvironment("/roms", ""); env.TargetOS != "" || env.RomsDir != "" {
+		t.Errorf("DetectEnvironment() without a target = %+v; want nothing detected", env)
+	}
+}
+
+func TestFind(t *testing.T) {
+	for _, example := range All {
+		found, ok := Find(example.Name)
+		if !ok || found.Name != example.Name {
+			t.Errorf("Find(%q) = %v, %v", example.Name, found.Name, ok)
+		}
+	}
+	if _, ok := Find("MIYOO-ARTWORK"); !ok {
+		t.Error("Find() should ignore case")
+	}
+	if _, ok := Find("nonexistent"); ok {
+		t.Error("Find(nonexistent) should fail")
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("quoting is for POSIX shells")
+	}
+
+	got := CommandLine("romcopyengine", []string{"--mapping", "nes:FC", "--dedupe", "--copyInclude", "**/*_favorite*", "--rewrite", "*.xml:it's:its"})
+	want := "romcopyengine \\\n" +
+		"    --mapping nes:FC \\\n" +
+		"    --dedupe \\\n" +
+		"    --copyInclude '**/*_favorite*' \\\n" +
+		"    --rewrite '*.xml:it'\\''s:its'"
+	if got != want {
+		t.Errorf("CommandLine() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package file_operations
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"lukechampine.com/blake3"
+)
+
+// checksum algorithms available for verify-after-write
+const (
+	ChecksumCRC32 = "crc32"
+	// 64-bit xxHash (XXH64); not cryptographic, but far faster than SHA1 on CPUs without hashing instructions
+	ChecksumXXHash = "xxhash"
+	// 256-bit BLAKE3, using SIMD instructions where the CPU has them
+	ChecksumBLAKE3 = "blake3"
+	ChecksumMD5    = "md5"
+	ChecksumSHA1   = "sha1"
+)
+
+// ChecksumAlgorithms lists the supported checksum algorithms, roughly fastest first
+var ChecksumAlgorithms = []string{ChecksumCRC32, ChecksumXXHash, ChecksumBLAKE3, ChecksumMD5, ChecksumSHA1}
+
+// NewChecksum returns a fresh hash for the named algorithm
+func NewChecksum(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumCRC32:
+		return crc32.NewIEEE(), nil
+	case ChecksumXXHash:
+		return xxhash.New(), nil
+	case ChecksumBLAKE3:
+		return blake3.New(32, nil), nil
+	case ChecksumMD5:
+		return md5.New(), nil
+	case ChecksumSHA1:
+		return sha1.New(), nil
+	default:
+		return nil, fmt.Errorf("unknown checksum algorithm '%s'", algorithm)
+	}
+}
+
+// hashingReader hashes everything read through it, so a copy can checksum its source in the same pass
+// rather than reading it twice
+type hashingReader struct {
+	reader io.Reader
+	hash   hash.Hash
+}
+
+func newHashingReader(reader io.Reader, hash hash.Hash) *hashingReader {
+	return &hashingReader{reader: reader, hash: hash}
+}
+
+func (r *hashingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.hash.Write(p[:n])
+	}
+	return n, err
+}
+
+// Sum returns the checksum of everything read so far
+func (r *hashingReader) Sum() []byte {
+	return r.hash.Sum(nil)
+}
+
+// RecordedChecksum is the state of a destination file as it was written
+type RecordedChecksum struct {
+	Size    int64
+	ModTime time.Time
+	Sum     []byte
+}
+
+// ChecksumRecorder remembers the checksum of every file copied, computed from the source stream during the
+// copy, so a manifest can be written without hashing anything a second time. It is safe for concurrent use.
+type ChecksumRecorder struct {
+	mu        sync.Mutex
+	algorithm string
+	files     map[string]RecordedChecksum
+}
+
+// NewChecksumRecorder creates a recorder for the given algorithm, one of ChecksumAlgorithms
+func NewChecksumRecorder(algorithm string) (*ChecksumRecorder, error) {
+	if _, err := NewChecksum(algorithm); err != nil {
+		return nil, err
+	}
+	return &ChecksumRecorder{algorithm: algorithm, files: make(map[string]RecordedChecksum)}, nil
+}
+
+// Algorithm returns the checksum algorithm files are recorded with
+func (r *ChecksumRecorder) Algorithm() string {
+	if r == nil {
+		return ""
+	}
+	return r.algorithm
+}
+
+// Files returns the recorded checksums keyed by destination path
+func (r *ChecksumRecorder) Files() map[string]RecordedChecksum {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	files := make(map[string]RecordedChecksum, len(r.files))
+	for path, file := range r.files {
+		files[path] = file
+	}
+	return files
+}
+
+// validated by NewChecksumRecorder, so this can't fail
+func (r *ChecksumRecorder) newHash() hash.Hash {
+	hasher, _ := NewChecksum(r.algorithm)
+	return hasher
+}
+
+func (r *ChecksumRecorder) record(destPath string, sum []byte) error {
+	info, err := targetFS.Stat(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to get destination file info for %s: %w", destPath, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files[destPath] = RecordedChecksum{Size: info.Size(), ModTime: info.ModTime(), Sum: sum}
+	return nil
+}
@@ -0,0 +1,204 @@
+package file_operations
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// bump when the cache format changes; older caches are discarded rather than migrated
+const checksumCacheVersion = 1
+
+type checksumCacheEntry struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	Hash    string `json:"hash"`
+}
+
+type checksumCacheFile struct {
+	Version int                           `json:"version"`
+	Entries map[string]checksumCacheEntry `json:"entries"`
+}
+
+// ChecksumCache remembers source file checksums between runs, keyed by path, size, and modification time,
+// so a large library doesn't have to be re-hashed when nothing in it has changed. It is safe for concurrent
+// use, and all methods are safe to call on a nil *ChecksumCache, which caches nothing.
+type ChecksumCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]checksumCacheEntry
+	dirty   bool
+}
+
+// DefaultChecksumCachePath returns where the cache lives in the user's cache directory
+func DefaultChecksumCachePath() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "ROMCopyEngine", "checksums.json"), nil
+}
+
+// LoadChecksumCache reads the cache at cachePath; a missing cache starts empty. If the cache can't be parsed,
+// an empty cache is returned along with the error so the caller can warn and carry on.
+func LoadChecksumCache(cachePath string) (*ChecksumCache, error) {
+	cache := &ChecksumCache{path: cachePath, entries: make(map[string]checksumCacheEntry)}
+
+	data, err := os.ReadFile(cachePath)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return cache, fmt.Errorf("failed to read checksum cache %s: %w", cachePath, err)
+	}
+
+	var file checksumCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return cache, fmt.Errorf("failed to parse checksum cache %s: %w", cachePath, err)
+	}
+	if file.Version == checksumCacheVersion && file.Entries != nil {
+		cache.entries = file.Entries
+	}
+
+	return cache, nil
+}
+
+func checksumCacheKey(filePath string, algorithm string) string {
+	if absPath, err := filepath.Abs(filePath); err == nil {
+		filePath = absPath
+	}
+	return algorithm + ":" + filePath
+}
+
+// Lookup returns the cached checksum of filePath if it was recorded with the same size and modification time
+func (c *ChecksumCache) Lookup(filePath string, info os.FileInfo, algorithm string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[checksumCacheKey(filePath, algorithm)]
+	if !ok || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+		return nil, false
+	}
+
+	sum, err := hex.DecodeString(entry.Hash)
+	if err != nil {
+		return nil, false
+	}
+	return sum, true
+}
+
+// Store records the checksum of filePath as of the given file info
+func (c *ChecksumCache) Store(filePath string, info os.FileInfo, algorithm string, sum []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[checksumCacheKey(filePath, algorithm)] = checksumCacheEntry{
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    hex.EncodeToString(sum),
+	}
+	c.dirty = true
+}
+
+// HashFile returns the checksum of filePath, from the cache if the file is unchanged since it was last hashed
+func (c *ChecksumCache) HashFile(filePath string, algorithm string) ([]byte, error) {
+	sums, err := c.HashFileAll(filePath, algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return sums[0], nil
+}
+
+// HashFileAll returns the checksums of filePath using each of algorithms, in order. Checksums are taken from
+// the cache where possible; any that aren't cached are computed together in a single read of the file.
+func (c *ChecksumCache) HashFileAll(filePath string, algorithms ...string) ([][]byte, error) {
+	info, err := targetFS.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info for %s: %w", filePath, err)
+	}
+
+	sums := make([][]byte, len(algorithms))
+	hashers := make(map[int]hash.Hash)
+	writers := make([]io.Writer, 0, len(algorithms))
+	for i, algorithm := range algorithms {
+		if sum, ok := c.Lookup(filePath, info, algorithm); ok {
+			sums[i] = sum
+			continue
+		}
+
+		hasher, err := NewChecksum(algorithm)
+		if err != nil {
+			return nil, err
+		}
+		hashers[i] = hasher
+		writers = append(writers, hasher)
+	}
+
+	if len(writers) == 0 {
+		return sums, nil
+	}
+
+	file, err := targetFS.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s for hashing: %w", filePath, err)
+	}
+	defer file.Close()
+
+	if _, err := io.Copy(io.MultiWriter(writers...), file); err != nil {
+		return nil, fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+
+	for i, hasher := range hashers {
+		sums[i] = hasher.Sum(nil)
+		c.Store(filePath, info, algorithms[i], sums[i])
+	}
+	return sums, nil
+}
+
+// Save writes the cache back to disk if anything was added. The file is replaced atomically so an
+// interrupted save can't corrupt it.
+func (c *ChecksumCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(checksumCacheFile{Version: checksumCacheVersion, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode checksum cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checksum cache directory: %w", err)
+	}
+
+	tempPath := fsys.TempPath(c.path)
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checksum cache %s: %w", tempPath, err)
+	}
+	if err := os.Rename(tempPath, c.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to replace checksum cache %s: %w", c.path, err)
+	}
+
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,141 @@
+package file_operations
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChecksumCacheRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+	cachePath := filepath.Join(tempDir, "cache", "checksums.json")
+	romPath := filepath.Join(tempDir, "game.nes")
+	if err := os.WriteFile(romPath, []byte("rom data"), 0644); err != nil {
+		t.Fatalf("failed to create rom: %v", err)
+	}
+
+	cache, err := LoadChecksumCache(cachePath)
+	if err != nil {
+		t.Fatalf("LoadChecksumCache() on a missing cache error = %v", err)
+	}
+
+	sum, err := cache.HashFile(romPath, ChecksumCRC32)
+	if err != nil {
+		t.Fatalf("HashFile() error = %v", err)
+	}
+	if got := hex.EncodeToString(sum); got != "0fb56a77" {
+		t.Errorf("HashFile() = %s, want 0fb56a77", got)
+	}
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	reloaded, err := LoadChecksumCache(cachePath)
+	if err != nil {
+		t.Fatalf("LoadChecksumCache() error = %v", err)
+	}
+	info, _ := os.Stat(romPath)
+	if cached, ok := reloaded.Lookup(romPath, info, ChecksumCRC32); !ok || hex.EncodeToString(cached) != "0fb56a77" {
+		t.Errorf("Lookup() after reload = %x, %v", cached, ok)
+	}
+	if _, ok := reloaded.Lookup(romPath, info, ChecksumMD5); ok {
+		t.Error("Lookup() matched an entry recorded with a different algorithm")
+	}
+}
+
+func TestChecksumCacheInvalidation(t *testing.T) {
+	tempDir := t.TempDir()
+	romPath := filepath.Join(tempDir, "game.nes")
+	if err := os.WriteFile(romPath, []byte("rom data"), 0644); err != nil {
+		t.Fatalf("failed to create rom: %v", err)
+	}
+
+	cache, _ := LoadChecksumCache(filepath.Join(tempDir, "checksums.json"))
+	info, _ := os.Stat(romPath)
+	// a bogus hash proves HashFile trusts the cache rather than re-reading the file
+	cache.Store(romPath, info, ChecksumCRC32, []byte{0xde, 0xad, 0xbe, 0xef})
+
+	sum, err := cache.HashFile(romPath, ChecksumCRC32)
+	if err != nil || hex.EncodeToString(sum) != "deadbeef" {
+		t.Errorf("HashFile() = %x, %v; expected the cached hash", sum, err)
+	}
+
+	newTime := info.ModTime().Add(time.Hour)
+	if err := os.Chtimes(romPath, newTime, newTime); err != nil {
+		t.Fatalf("failed to touch rom: %v", err)
+	}
+	sum, err = cache.HashFile(romPath, ChecksumCRC32)
+	if err != nil || hex.EncodeToString(sum) != "0fb56a77" {
+		t.Errorf("HashFile() = %x, %v; expected a fresh hash after the file was modified", sum, err)
+	}
+
+	if err := os.WriteFile(romPath, []byte("rom data, patched"), 0644); err != nil {
+		t.Fatalf("failed to rewrite rom: %v", err)
+	}
+	if err := os.Chtimes(romPath, newTime, newTime); err != nil {
+		t.Fatalf("failed to touch rom: %v", err)
+	}
+	info, _ = os.Stat(romPath)
+	if _, ok := cache.Lookup(romPath, info, ChecksumCRC32); ok {
+		t.Error("Lookup() matched a file whose size changed")
+	}
+}
+
+func TestChecksumCacheHashFileAll(t *testing.T) {
+	tempDir := t.TempDir()
+	romPath := filepath.Join(tempDir, "game.nes")
+	if err := os.WriteFile(romPath, []byte("rom data"), 0644); err != nil {
+		t.Fatalf("failed to create rom: %v", err)
+	}
+
+	cache, _ := LoadChecksumCache(filepath.Join(tempDir, "checksums.json"))
+	info, _ := os.Stat(romPath)
+	cache.Store(romPath, info, ChecksumCRC32, []byte{0xde, 0xad, 0xbe, 0xef})
+
+	sums, err := cache.HashFileAll(romPath, ChecksumCRC32, ChecksumSHA1)
+	if err != nil {
+		t.Fatalf("HashFileAll() error = %v", err)
+	}
+	if got := hex.EncodeToString(sums[0]); got != "deadbeef" {
+		t.Errorf("crc32 = %s, expected the cached hash", got)
+	}
+	if got := hex.EncodeToString(sums[1]); got != "9dc51b2fa753deddc01848f0504d46a2d05e99c8" {
+		t.Errorf("sha1 = %s, want 9dc51b2fa753deddc01848f0504d46a2d05e99c8", got)
+	}
+	if _, ok := cache.Lookup(romPath, info, ChecksumSHA1); !ok {
+		t.Error("expected the computed sha1 to be cached")
+	}
+}
+
+func TestChecksumCacheCorrupt(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "checksums.json")
+	if err := os.WriteFile(cachePath, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("failed to write cache: %v", err)
+	}
+
+	cache, err := LoadChecksumCache(cachePath)
+	if err == nil {
+		t.Error("expected an error for a corrupt cache")
+	}
+	if cache == nil {
+		t.Fatal("expected an empty cache alongside the error")
+	}
+}
+
+func TestNilChecksumCache(t *testing.T) {
+	romPath := filepath.Join(t.TempDir(), "game.nes")
+	if err := os.WriteFile(romPath, []byte("rom data"), 0644); err != nil {
+		t.Fatalf("failed to create rom: %v", err)
+	}
+
+	var cache *ChecksumCache
+	sum, err := cache.HashFile(romPath, ChecksumCRC32)
+	if err != nil || hex.EncodeToString(sum) != "0fb56a77" {
+		t.Errorf("nil HashFile() = %x, %v", sum, err)
+	}
+	if err := cache.Save(); err != nil {
+		t.Errorf("nil Save() error = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package file_operations
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewChecksum(t *testing.T) {
+	// digests of "rom data"
+	expected := map[string]string{
+		ChecksumCRC32:  "0fb56a77",
+		ChecksumXXHash: "da8635524eaff1eb",
+		ChecksumBLAKE3: "5b9e451372e83d98dfddbe648f85377d0fa1b2415d5e594cfbe868eb8822f120",
+		ChecksumMD5:    "541a9cc0d156fda7e34c445dd4e435f9",
+		ChecksumSHA1:   "9dc51b2fa753deddc01848f0504d46a2d05e99c8",
+	}
+
+	for _, algorithm := range ChecksumAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			hasher, err := NewChecksum(algorithm)
+			if err != nil {
+				t.Fatalf("NewChecksum(%q) error = %v", algorithm, err)
+			}
+
+			reader := newHashingReader(strings.NewReader("rom data"), hasher)
+			var out bytes.Buffer
+			if _, err := io.Copy(&out, reader); err != nil {
+				t.Fatalf("failed to read through hashingReader: %v", err)
+			}
+
+			if out.String() != "rom data" {
+				t.Errorf("hashingReader altered the stream: %q", out.String())
+			}
+			if got := hex.EncodeToString(reader.Sum()); got != expected[algorithm] {
+				t.Errorf("%s sum = %s, want %s", algorithm, got, expected[algorithm])
+			}
+		})
+	}
+
+	if _, err := NewChecksum("md4"); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+}
+
+func TestCopyFileWithOptionsChecksums(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"source.sfc": "rom data",
+	})
+	defer cleanup()
+
+	for _, algorithm := range ChecksumAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			report, err := NewVerifyReport(algorithm)
+			if err != nil {
+				t.Fatalf("NewVerifyReport() error = %v", err)
+			}
+
+			dest := filepath.Join(tmpDir, "dest-"+algorithm+".sfc")
+			if err := CopyFileWithOptions(filepath.Join(tmpDir, "source.sfc"), dest, StreamOptions{Verify: report}); err != nil {
+				t.Fatalf("CopyFileWithOptions() error = %v", err)
+			}
+
+			if report.Verified() != 1 || len(report.Failures()) != 0 {
+				t.Errorf("expected 1 verified file and no failures, got %d and %v", report.Verified(), report.Failures())
+			}
+			if report.Algorithm() != algorithm {
+				t.Errorf("Algorithm() = %q, want %q", report.Algorithm(), algorithm)
+			}
+		})
+	}
+
+	if _, err := NewVerifyReport("md4"); err == nil {
+		t.Error("expected an error for an unknown algorithm")
+	}
+}
+
+func TestChecksumRecorder(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"source.sfc": "rom data",
+	})
+	defer cleanup()
+
+	recorder, err := NewChecksumRecorder(ChecksumMD5)
+	if err != nil {
+		t.Fatalf("NewChecksumRecorder() error = %v", err)
+	}
+
+	// verifying with a different algorithm must not disturb the recorded checksum
+	report, err := NewVerifyReport(ChecksumCRC32)
+	if err != nil {
+		t.Fatalf("NewVerifyReport() error = %v", err)
+	}
+
+	dest := filepath.Join(tmpDir, "dest.sfc")
+	if err := CopyFileWithOptions(filepath.Join(tmpDir, "source.sfc"), dest, StreamOptions{Checksums: recorder, Verify: report}); err != nil {
+		t.Fatalf("CopyFileWithOptions() error = %v", err)
+	}
+
+	recorded, ok := recorder.Files()[dest]
+	if !ok {
+		t.Fatalf("no checksum recorded for %s", dest)
+	}
+	if got := hex.EncodeToString(recorded.Sum); got != "541a9cc0d156fda7e34c445dd4e435f9" {
+		t.Errorf("recorded sum = %s, want md5 of source", got)
+	}
+	if recorded.Size != 8 || recorded.ModTime.IsZero() {
+		t.Errorf("recorded size/mod time = %d/%v", recorded.Size, recorded.ModTime)
+	}
+	if report.Verified() != 1 {
+		t.Errorf("Verified() = %d, want 1", report.Verified())
+	}
+}
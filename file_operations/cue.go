@@ -0,0 +1,147 @@
+package file_operations
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+	"github.com/jkingsman/ROMCopyEngine/logging"
+)
+
+// a cue sheet FILE command: the leading keyword, the (optionally quoted) file name, and the file type and
+// anything after it, including a '\r' line ending
+var cueFileLine = regexp.MustCompile(`(?i)^(\s*FILE\s+)("[^"]*"|\S+)(.*)$`)
+
+// FixCueReferences rewrites the FILE commands of every cue sheet beneath dir that name a file which has since
+// been renamed, so the disc still loads. renamed maps each old path to its new one, both relative to dir and
+// using forward slashes; renaming a folder moves everything in it. A reference is only rewritten if it no
+// longer resolves and the file it moved to exists. Returns the paths of the rewritten sheets relative to dir.
+func FixCueReferences(dir string, renamed map[string]string) ([]string, error) {
+	fixed := make([]string, 0)
+	if len(renamed) == 0 {
+		return fixed, nil
+	}
+
+	err := fsys.Walk(targetFS, dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", filePath, err)
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(filePath), ".cue") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path for %s: %w", filePath, err)
+		}
+
+		data, err := targetFS.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+
+		newData, changed := fixCueSheet(data, dir, path.Dir(filepath.ToSlash(relPath)), renamed)
+		if !changed {
+			return nil
+		}
+
+		if err := targetFS.WriteFile(filePath, newData, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to write to file %s: %w", filePath, err)
+		}
+		logging.Log(logging.Detail, logging.IconRewrite, "Updated renamed track(s) in %s", relPath)
+		fixed = append(fixed, relPath)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(fixed)
+	return fixed, nil
+}
+
+// rewrites the FILE commands in a sheet located in sheetDir (relative to dir) that point at renamed files,
+// keeping everything else, including a byte order mark and line endings, as it was
+func fixCueSheet(data []byte, dir string, sheetDir string, renamed map[string]string) ([]byte, bool) {
+	bom := []byte("\xef\xbb\xbf")
+	hasBOM := bytes.HasPrefix(data, bom)
+	lines := strings.SplitAfter(string(bytes.TrimPrefix(data, bom)), "\n")
+
+	changed := false
+	for i, line := range lines {
+		body := strings.TrimSuffix(line, "\n")
+		match := cueFileLine.FindStringSubmatch(body)
+		if match == nil {
+			continue
+		}
+
+		ref, quoted := match[2], false
+		if strings.HasPrefix(ref, `"`) {
+			ref, quoted = strings.Trim(ref, `"`), true
+		}
+
+		newRef, ok := renamedReference(ref, dir, sheetDir, renamed)
+		if !ok {
+			continue
+		}
+
+		if quoted || strings.ContainsAny(newRef, " \t") {
+			newRef = `"` + newRef + `"`
+		}
+		lines[i] = match[1] + newRef + match[3] + line[len(body):]
+		changed = true
+	}
+
+	if !changed {
+		return data, false
+	}
+
+	newData := []byte(strings.Join(lines, ""))
+	if hasBOM {
+		newData = append(bom, newData...)
+	}
+	return newData, true
+}
+
+// returns what a sheet in sheetDir should now call ref, if ref no longer resolves because it was renamed.
+// The new reference keeps the original's slash style.
+func renamedReference(ref string, dir string, sheetDir string, renamed map[string]string) (string, bool) {
+	target, ok := device_paths.Resolve(sheetDir, ref)
+	if !ok || filepath.IsAbs(ref) {
+		return "", false
+	}
+	if _, err := targetFS.Stat(filepath.Join(dir, filepath.FromSlash(target))); !os.IsNotExist(err) {
+		return "", false
+	}
+
+	newTarget, ok := movedTo(target, renamed)
+	if !ok {
+		return "", false
+	}
+	if _, err := targetFS.Stat(filepath.Join(dir, filepath.FromSlash(newTarget))); err != nil {
+		return "", false
+	}
+
+	newRef := device_paths.Folder{}.Write(sheetDir, newTarget, device_paths.Relative)
+	if strings.Contains(ref, "\\") {
+		newRef = strings.ReplaceAll(newRef, "/", "\\")
+	}
+	return newRef, true
+}
+
+// returns where relPath ended up, if it or one of the folders it's in was renamed
+func movedTo(relPath string, renamed map[string]string) (string, bool) {
+	for p := relPath; p != "." && p != "/"; p = path.Dir(p) {
+		if newPath, ok := renamed[p]; ok {
+			return path.Join(newPath, strings.TrimPrefix(relPath, p)), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,78 @@
+package file_operations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFixCueReferences(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"Game.cue": "\xef\xbb\xbfFILE \"Game (Track 1).bin\" BINARY\r\n  TRACK 01 MODE2/2352\r\n" +
+			"FILE \"Game (Track 2).bin\" BINARY\r\n",
+		"Game (Track 1 renamed).bin": "t1",
+		"Game (Track 2).bin":         "t2",
+		"sub/Other.cue":              "FILE discs\\other.img BINARY\nFILE \"../unrelated.bin\" BINARY\n",
+		"tracks/other.img":           "img",
+		"Untouched.cue":              "FILE \"Gone.bin\" BINARY\n",
+	}
+	if err := createTestDir(tmpDir, files); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	renamed := map[string]string{
+		"Game (Track 1).bin": "Game (Track 1 renamed).bin",
+		"sub/discs":          "tracks",
+		// the file it moved to doesn't exist, so the reference is left as it was
+		"Gone.bin": "Missing.bin",
+	}
+
+	fixed, err := FixCueReferences(tmpDir, renamed)
+	if err != nil {
+		t.Fatalf("FixCueReferences() error = %v", err)
+	}
+	if want := []string{"Game.cue", filepath.Join("sub", "Other.cue")}; !reflect.DeepEqual(fixed, want) {
+		t.Errorf("FixCueReferences() = %v, want %v", fixed, want)
+	}
+
+	expected := map[string]string{
+		"Game.cue": "\xef\xbb\xbfFILE \"Game (Track 1 renamed).bin\" BINARY\r\n  TRACK 01 MODE2/2352\r\n" +
+			"FILE \"Game (Track 2).bin\" BINARY\r\n",
+		"sub/Other.cue": "FILE ..\\tracks\\other.img BINARY\nFILE \"../unrelated.bin\" BINARY\n",
+		"Untouched.cue": "FILE \"Gone.bin\" BINARY\n",
+	}
+	for name, want := range expected {
+		got, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFixCueReferencesQuotesSpaces(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"Game.cue":       "FILE Game.bin BINARY\n",
+		"Game (USA).bin": "data",
+	}
+	if err := createTestDir(tmpDir, files); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if _, err := FixCueReferences(tmpDir, map[string]string{"Game.bin": "Game (USA).bin"}); err != nil {
+		t.Fatalf("FixCueReferences() error = %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(tmpDir, "Game.cue"))
+	if err != nil {
+		t.Fatalf("Failed to read Game.cue: %v", err)
+	}
+	if want := "FILE \"Game (USA).bin\" BINARY\n"; string(got) != want {
+		t.Errorf("Game.cue = %q, want %q", got, want)
+	}
+}
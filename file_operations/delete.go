@@ -0,0 +1,104 @@
+package file_operations
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// FindMatching returns the files beneath dir whose paths relative to it match any of patterns, globs like '*.txt'
+// or '**/Thumbs.db' matched case-insensitively as IsProtected matches them, followed by those of them that match
+// the protect globs and are left out. Both are sorted slash separated paths relative to dir.
+func FindMatching(dir string, patterns []string, protect []string) ([]string, []string, error) {
+	matches := make([]string, 0)
+	kept := make([]string, 0)
+	err := walkFiles(dir, func(relPath string) {
+		if !IsProtected(relPath, patterns) {
+			return
+		}
+		if IsProtected(relPath, protect) {
+			kept = append(kept, relPath)
+		} else {
+			matches = append(matches, relPath)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(matches)
+	sort.Strings(kept)
+	return matches, kept, nil
+}
+
+// calls fn with the slash separated path, relative to dir, of every file beneath it
+func walkFiles(dir string, fn func(relPath string)) error {
+	return walkFilesIn(dir, "", fn)
+}
+
+func walkFilesIn(dir string, relDir string, fn func(relPath string)) error {
+	dirPath := filepath.Join(dir, filepath.FromSlash(relDir))
+	entries, err := targetFS.ReadDir(dirPath)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %s: %w", dirPath, err)
+	}
+	for _, entry := range entries {
+		relPath := path.Join(relDir, entry.Name())
+		if entry.IsDir() {
+			if err := walkFilesIn(dir, relPath, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		fn(relPath)
+	}
+	return nil
+}
+
+// DeleteFiles removes each file at the slash separated relPaths within dir, then the folders they leave empty,
+// up to but not including dir. A file already gone is skipped.
+func DeleteFiles(dir string, relPaths []string) error {
+	return deleteFiles(dir, relPaths, targetFS.Remove)
+}
+
+// TrashFiles removes files as DeleteFiles does, but moves them into trash, so they can be recovered until the
+// batch is pruned
+func TrashFiles(dir string, relPaths []string, trash Trash) error {
+	return deleteFiles(dir, relPaths, func(filePath string) error {
+		if _, err := targetFS.Stat(filePath); err != nil {
+			return err
+		}
+		return trash.move(dir, filePath)
+	})
+}
+
+func deleteFiles(dir string, relPaths []string, remove func(filePath string) error) error {
+	folders := make(map[string]bool)
+	for _, relPath := range relPaths {
+		filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := remove(filePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete %s: %w", filePath, err)
+		}
+		for folder := path.Dir(relPath); folder != "."; folder = path.Dir(folder) {
+			folders[folder] = true
+		}
+	}
+
+	// deepest first, so a folder holding only emptied folders is empty by the time it's reached
+	sorted := make([]string, 0, len(folders))
+	for folder := range folders {
+		sorted = append(sorted, folder)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, folder := range sorted {
+		folderPath := filepath.Join(dir, filepath.FromSlash(folder))
+		if entries, err := targetFS.ReadDir(folderPath); err != nil || len(entries) > 0 {
+			continue
+		}
+		if err := targetFS.Remove(folderPath); err != nil {
+			return fmt.Errorf("failed to remove empty directory %s: %w", folderPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package file_operations
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindMatchingAndDeleteFiles(t *testing.T) {
+	tmpDir, cleanup := testSetup(t)
+	defer cleanup()
+
+	if err := createTestDir(tmpDir, map[string]string{
+		"Tetris.gb":                  "rom",
+		"readme.TXT":                 "junk",
+		"images/Thumbs.db":           "junk",
+		"images/Tetris.png":          "art",
+		"__MACOSX/._Tetris.gb":       "junk",
+		"__MACOSX/images/._Tetris.p": "junk",
+		"saves/notes.txt":            "kept",
+	}); err != nil {
+		t.Fatalf("Failed to create test files: %v", err)
+	}
+
+	matches, kept, err := FindMatching(tmpDir, []string{"**/*.txt", "**/thumbs.db", "__MACOSX/**"}, []string{"**/saves/**", "saves/**"})
+	if err != nil {
+		t.Fatalf("FindMatching() error = %v", err)
+	}
+	wantMatches := []string{"__MACOSX/._Tetris.gb", "__MACOSX/images/._Tetris.p", "images/Thumbs.db", "readme.TXT"}
+	if !reflect.DeepEqual(matches, wantMatches) {
+		t.Errorf("FindMatching() matches = %v, want %v", matches, wantMatches)
+	}
+	if !reflect.DeepEqual(kept, []string{"saves/notes.txt"}) {
+		t.Errorf("FindMatching() kept = %v, want [saves/notes.txt]", kept)
+	}
+
+	if err := DeleteFiles(tmpDir, append(matches, "gone.txt")); err != nil {
+		t.Fatalf("DeleteFiles() error = %v", err)
+	}
+	for _, relPath := range []string{"readme.TXT", "images/Thumbs.db", "__MACOSX"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, relPath)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been deleted", relPath)
+		}
+	}
+	for _, relPath := range []string{"Tetris.gb", "images/Tetris.png", "saves/notes.txt"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, relPath)); err != nil {
+			t.Errorf("%s should have been kept: %v", relPath, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package file_operations
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// DefaultBuildTime is the timestamp deterministic runs give everything they write, unless SOURCE_DATE_EPOCH
+// says otherwise: the earliest time FAT and zip can record, so it survives on any card
+var DefaultBuildTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// BuildTime returns the timestamp for a deterministic run: the Unix time in the SOURCE_DATE_EPOCH environment
+// variable (see reproducible-builds.org) if it's set, or DefaultBuildTime otherwise
+func BuildTime() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return DefaultBuildTime, nil
+	}
+
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH '%s': must be a non-negative number of seconds", epoch)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// StampTree sets the modification time of dir and everything beneath it to mtime
+func StampTree(dir string, mtime time.Time) error {
+	return fsys.Walk(targetFS, dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", filePath, err)
+		}
+		if err := targetFS.Chtimes(filePath, mtime, mtime); err != nil {
+			return fmt.Errorf("failed to set the modification time of %s: %w", filePath, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package file_operations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		epoch   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"unset", "", DefaultBuildTime, false},
+		{"epoch", "1700000000", time.Unix(1700000000, 0).UTC(), false},
+		{"not a number", "yesterday", time.Time{}, true},
+		{"negative", "-1", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+			got, err := BuildTime()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("BuildTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStampTree(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"game.sfc":          "rom",
+		"images/boxart.png": "png",
+	}
+	if err := createTestDir(tmpDir, files); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if err := StampTree(tmpDir, DefaultBuildTime); err != nil {
+		t.Fatalf("StampTree() error = %v", err)
+	}
+
+	for _, name := range []string{".", "game.sfc", "images", "images/boxart.png"} {
+		info, err := os.Stat(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", name, err)
+		}
+		if !info.ModTime().Equal(DefaultBuildTime) {
+			t.Errorf("%s modified at %v, want %v", name, info.ModTime(), DefaultBuildTime)
+		}
+	}
+}
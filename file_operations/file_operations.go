@@ -1,24 +1,47 @@
 package file_operations
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jkingsman/ROMCopyEngine/artwork"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
 	"github.com/jkingsman/ROMCopyEngine/logging"
 )
 
+// the filesystem every operation in this package reads and writes through; read-only during a dry run
+var targetFS fsys.FS = fsys.OS
+
+// SetFilesystem points every file operation at fs
+func SetFilesystem(fs fsys.FS) {
+	targetFS = fs
+}
+
+func Filesystem() fsys.FS {
+	return targetFS
+}
+
 // copies all contents out of destPath/explodeDir into destPath, then removes destPath/explodeDir
 // bool: whether the folder was found
 func ExplodeFolder(destPath string, explodeDir string) (bool, error) {
 	folderPath := filepath.Join(destPath, explodeDir)
 
 	// Check if the folder exists and is a directory
-	info, err := os.Stat(folderPath)
+	info, err := targetFS.Stat(folderPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			logging.Log(logging.Detail, logging.IconSkip, "Unable to locate %s folder to explode; skipping", explodeDir)
@@ -32,7 +55,7 @@ func ExplodeFolder(destPath string, explodeDir string) (bool, error) {
 	}
 
 	// Read directory contents
-	items, err := os.ReadDir(folderPath)
+	items, err := targetFS.ReadDir(folderPath)
 	if err != nil {
 		return true, fmt.Errorf("failed to read contents of directory %s: %w", folderPath, err)
 	}
@@ -43,7 +66,7 @@ func ExplodeFolder(destPath string, explodeDir string) (bool, error) {
 		destPath := filepath.Join(destPath, item.Name())
 
 		// Check for naming conflicts
-		if _, err := os.Stat(destPath); err == nil {
+		if _, err := targetFS.Stat(destPath); err == nil {
 			return true, fmt.Errorf("cannot move %s: destination %s already exists", sourcePath, destPath)
 		}
 
@@ -54,21 +77,166 @@ func ExplodeFolder(destPath string, explodeDir string) (bool, error) {
 	}
 
 	// Remove the now-empty source directory
-	if err := os.Remove(folderPath); err != nil {
+	if err := targetFS.Remove(folderPath); err != nil {
 		return true, fmt.Errorf("failed to remove empty directory %s: %w", folderPath, err)
 	}
 
 	return true, nil
 }
 
+// IsExplodeGlob reports whether an '--explodeDir' name is a glob, e.g. 'im*g*' for 'images', 'imgs', and 'Imgs'
+func IsExplodeGlob(explodeDir string) bool {
+	return strings.ContainsAny(explodeDir, "*?[")
+}
+
+// whether the last components of a slash-separated path match explodeDir, a name, path, or glob of either;
+// globs match without regard to case. Returns the number of components matched.
+func matchExplodeDir(relPath string, explodeDir string) (int, bool) {
+	depth := strings.Count(explodeDir, "/") + 1
+	parts := strings.Split(relPath, "/")
+	if len(parts) < depth {
+		return depth, false
+	}
+	tail := path.Join(parts[len(parts)-depth:]...)
+	if !IsExplodeGlob(explodeDir) {
+		return depth, tail == explodeDir
+	}
+	matched, _ := path.Match(strings.ToLower(explodeDir), strings.ToLower(tail))
+	return depth, matched
+}
+
+// ExplodeMatchingFolders explodes each folder at the top of destPath whose path matches explodeDir, a glob like
+// 'im*g*' matched without regard to case, as ExplodeFolder does. Returns the folders exploded, relative to
+// destPath.
+func ExplodeMatchingFolders(destPath string, explodeDir string) ([]string, error) {
+	return explodeMatches(destPath, explodeDir, false)
+}
+
+// ExplodeFoldersRecursive explodes every folder at the end of a path matching explodeDir at any depth beneath
+// destPath, e.g. 'PS1/Game/images' for 'images', into the folder its match starts in, as ExplodeFolder does.
+// explodeDir may be a glob, as with ExplodeMatchingFolders. The deepest are exploded first, so a match inside
+// another is moved up with the other's contents. Returns the folders exploded, relative to destPath.
+func ExplodeFoldersRecursive(destPath string, explodeDir string) ([]string, error) {
+	return explodeMatches(destPath, explodeDir, true)
+}
+
+func explodeMatches(destPath string, explodeDir string, anyDepth bool) ([]string, error) {
+	explodeDir = filepath.ToSlash(filepath.Clean(explodeDir))
+	matches := make([]string, 0)
+	err := fsys.Walk(targetFS, destPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || filePath == destPath {
+			return nil
+		}
+		relPath, err := filepath.Rel(destPath, filePath)
+		if err != nil {
+			return err
+		}
+		depth, matched := matchExplodeDir(filepath.ToSlash(relPath), explodeDir)
+		if matched && (anyDepth || strings.Count(filepath.ToSlash(relPath), "/")+1 == depth) {
+			matches = append(matches, relPath)
+		}
+		if !anyDepth && strings.Count(filepath.ToSlash(relPath), "/")+1 >= depth {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s for %s folders: %w", destPath, explodeDir, err)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return strings.Count(matches[i], string(filepath.Separator)) > strings.Count(matches[j], string(filepath.Separator))
+	})
+	exploded := make([]string, 0, len(matches))
+	for _, match := range matches {
+		parts := strings.Split(filepath.ToSlash(match), "/")
+		depth, _ := matchExplodeDir(filepath.ToSlash(match), explodeDir)
+		parent := path.Join(parts[:len(parts)-depth]...)
+		if _, err := ExplodeFolder(filepath.Join(destPath, filepath.FromSlash(parent)), filepath.FromSlash(path.Join(parts[len(parts)-depth:]...))); err != nil {
+			return exploded, err
+		}
+		exploded = append(exploded, match)
+	}
+	return exploded, nil
+}
+
+// ExplodedPath returns where a file at relPath, slash separated and relative to the folder explodeDir is exploded
+// in, ends up once it's exploded: with the folders matching explodeDir taken out of its path, only at its top
+// unless anyDepth, as ExplodeFolder, ExplodeMatchingFolders, and ExplodeFoldersRecursive leave it
+func ExplodedPath(relPath string, explodeDir string, anyDepth bool) string {
+	explodeDir = filepath.ToSlash(filepath.Clean(explodeDir))
+	parts := strings.Split(relPath, "/")
+	for end := 1; end < len(parts); end++ {
+		depth, matched := matchExplodeDir(path.Join(parts[:end]...), explodeDir)
+		if !anyDepth && end > depth {
+			break
+		}
+		if matched && (anyDepth || end == depth) {
+			parts = append(parts[:end-depth], parts[end:]...)
+			end -= depth
+			if !anyDepth {
+				break
+			}
+		}
+	}
+	return path.Join(parts...)
+}
+
+// moves all contents out of folderPath into destDir, creating it if needed and replacing anything there of the
+// same name, then removes folderPath. Returns how many items were moved, and false if folderPath wasn't found.
+func MoveFolderContents(folderPath string, destDir string) (int, bool, error) {
+	info, err := targetFS.Stat(folderPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to access folder %s: %w", folderPath, err)
+	}
+	if !info.IsDir() {
+		return 0, true, fmt.Errorf("path %s exists but is not a directory", folderPath)
+	}
+
+	items, err := targetFS.ReadDir(folderPath)
+	if err != nil {
+		return 0, true, fmt.Errorf("failed to read contents of directory %s: %w", folderPath, err)
+	}
+	if err := targetFS.MkdirAll(destDir, 0755); err != nil {
+		return 0, true, fmt.Errorf("failed to create directory %s: %w", destDir, err)
+	}
+
+	moved := 0
+	for _, item := range items {
+		sourcePath := filepath.Join(folderPath, item.Name())
+		destPath := filepath.Join(destDir, item.Name())
+
+		// what an earlier copy left there is out of date
+		if err := targetFS.RemoveAll(destPath); err != nil {
+			return moved, true, fmt.Errorf("failed to replace %s: %w", destPath, err)
+		}
+		if err := moveItem(sourcePath, destPath); err != nil {
+			return moved, true, fmt.Errorf("failed to move %s to %s: %w", sourcePath, destPath, err)
+		}
+		logging.Log(logging.Detail, logging.IconCopy, "Moved %s to %s", item.Name(), destDir)
+		moved++
+	}
+
+	if err := targetFS.Remove(folderPath); err != nil {
+		return moved, true, fmt.Errorf("failed to remove empty directory %s: %w", folderPath, err)
+	}
+	return moved, true, nil
+}
+
 func moveItem(sourcePath string, destPath string) error {
 	// Try a direct move first
-	if err := os.Rename(sourcePath, destPath); err == nil {
+	if err := targetFS.Rename(sourcePath, destPath); err == nil {
 		return nil
 	}
 
 	// If direct move fails, try copy and delete approach
-	sourceInfo, err := os.Stat(sourcePath)
+	sourceInfo, err := targetFS.Stat(sourcePath)
 	if err != nil {
 		return fmt.Errorf("failed to get source info for %s: %w", sourcePath, err)
 	}
@@ -84,50 +252,214 @@ func moveItem(sourcePath string, destPath string) error {
 	}
 
 	// delete copied file
-	if err := os.RemoveAll(sourcePath); err != nil {
+	if err := targetFS.RemoveAll(sourcePath); err != nil {
 		return fmt.Errorf("failed to remove source after copy %s: %w", sourcePath, err)
 	}
 
 	return nil
 }
 
+// StreamOptions tunes how file contents are moved by CopyFileWithOptions
+type StreamOptions struct {
+	// shared throughput cap; nil for unlimited
+	Limiter *RateLimiter
+	// abort a file copy if no bytes are written for this long; 0 to wait forever
+	StallTimeout time.Duration
+	// how many times a stalled file copy is retried before giving up
+	StallRetries int
+	// fsync each destination file before closing it
+	Flush bool
+	// collects throughput and error statistics across the run; nil to disable
+	Health *HealthMonitor
+	// re-read each destination file after writing and compare it against the source; nil to disable
+	Verify *VerifyReport
+	// records the checksum of each copied file for the target's manifest; nil to disable
+	Checksums *ChecksumRecorder
+	// remembers source checksums between runs; nil to disable
+	Cache *ChecksumCache
+	// counts bytes as they're written for the progress display; nil to disable
+	Progress *logging.Progress
+	// permissions given to each destination file; 0 to copy the source's permissions, less the umask
+	FileMode os.FileMode
+	// downscale the file, if it's a PNG or JPEG larger than this, to fit within it; zero to copy it as it is
+	ResizeImage artwork.Size
+}
+
+// DestinationFileMode returns the permissions a copied file should have. Source permissions are masked with
+// the umask like any other newly created file, since modes from e.g. Windows mounts are often nonsense.
+func DestinationFileMode(sourceMode os.FileMode, override os.FileMode) os.FileMode {
+	if override != 0 {
+		return override
+	}
+	return sourceMode.Perm() &^ processUmask
+}
+
 // File operations
 func CopyFile(srcPath string, destPath string) error {
-	source, err := os.Open(srcPath)
+	return CopyFileWithOptions(srcPath, destPath, StreamOptions{})
+}
+
+func CopyFileWithOptions(srcPath string, destPath string, opts StreamOptions) error {
+	for attempt := 0; ; attempt++ {
+		err := copyFileOnce(srcPath, destPath, opts)
+		if !errors.Is(err, ErrStalled) || attempt >= opts.StallRetries {
+			return err
+		}
+
+		logging.Log(logging.Detail, logging.IconWarning, "Copy of %s stalled for %s; retrying (%d of %d)...", filepath.Base(srcPath), opts.StallTimeout, attempt+1, opts.StallRetries)
+		opts.Progress.RestartFile()
+	}
+}
+
+func copyFileOnce(srcPath string, destPath string, opts StreamOptions) error {
+	source, err := targetFS.Open(srcPath)
 	if err != nil {
 		return fmt.Errorf("failed to open source file %s: %w", srcPath, err)
 	}
 	defer source.Close()
 
-	dest, err := os.Create(destPath)
+	// written beside destPath and renamed over it once complete, so an interrupted copy never leaves a truncated
+	// file behind to be mistaken for a good one, or destroys the one it was replacing
+	tempPath := fsys.TempPath(destPath)
+	dest, err := targetFS.Create(tempPath)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file %s: %w", destPath, err)
 	}
 	defer dest.Close()
 
-	if _, err := io.Copy(dest, source); err != nil {
+	var reader io.Reader = source
+	var sourceHash, recordHash *hashingReader
+	if opts.Verify != nil {
+		sourceHash = newHashingReader(reader, opts.Verify.newHash())
+		reader = sourceHash
+	}
+	if opts.Checksums != nil {
+		if sourceHash != nil && opts.Verify.Algorithm() == opts.Checksums.Algorithm() {
+			recordHash = sourceHash
+		} else {
+			recordHash = newHashingReader(reader, opts.Checksums.newHash())
+			reader = recordHash
+		}
+	}
+	// waiting on the bandwidth limit doesn't count towards a stall
+	clock := newStallClock()
+	if opts.Limiter != nil {
+		reader = &throttledReader{reader: reader, limiter: opts.Limiter, clock: clock}
+	}
+	if opts.Progress != nil {
+		reader = io.TeeReader(reader, opts.Progress)
+	}
+
+	abort := func() {
+		source.Close()
+		dest.Close()
+	}
+	discard := func() {
+		dest.Close()
+		targetFS.Remove(tempPath)
+	}
+	start := time.Now()
+	var written int64
+	// what was written in place of the source, if it was resized
+	var resized []byte
+	if opts.ResizeImage != (artwork.Size{}) {
+		written, resized, err = copyResized(dest, reader, filepath.Base(srcPath), opts.ResizeImage, opts.StallTimeout, clock, abort)
+	} else {
+		written, err = copyStream(dest, reader, opts.StallTimeout, clock, abort)
+	}
+	if err != nil {
+		opts.Health.RecordError(destPath, written, err)
+		discard()
 		return fmt.Errorf("failed to copy file contents from %s to %s: %w", srcPath, destPath, err)
 	}
 
-	sourceInfo, err := os.Stat(srcPath)
+	if opts.Flush {
+		if err := dest.Sync(); err != nil {
+			opts.Health.RecordError(destPath, written, err)
+			discard()
+			return fmt.Errorf("failed to flush %s to disk: %w", destPath, err)
+		}
+	}
+	opts.Health.RecordTransfer(written, time.Since(start), opts.Flush)
+
+	sourceInfo, err := targetFS.Stat(srcPath)
 	if err != nil {
+		discard()
 		return fmt.Errorf("failed to get source file info for %s: %w", srcPath, err)
 	}
+	if err := dest.Close(); err != nil {
+		discard()
+		return fmt.Errorf("failed to write %s: %w", destPath, err)
+	}
+	if err := targetFS.Chmod(tempPath, DestinationFileMode(sourceInfo.Mode(), opts.FileMode)); err != nil {
+		discard()
+		return fmt.Errorf("failed to set permissions on %s: %w", destPath, err)
+	}
+	if err := targetFS.Rename(tempPath, destPath); err != nil {
+		discard()
+		return fmt.Errorf("failed to move %s into place: %w", destPath, err)
+	}
+
+	if opts.Verify != nil {
+		if resized != nil {
+			opts.Verify.check(destPath, sumOf(opts.Verify.newHash(), resized), written)
+		} else {
+			opts.Verify.check(destPath, sourceHash.Sum(), written)
+		}
+	}
 
-	return os.Chmod(destPath, sourceInfo.Mode())
+	// checksums taken from the source while copying are remembered so later runs needn't re-read it
+	if sourceHash != nil {
+		opts.Cache.Store(srcPath, sourceInfo, opts.Verify.Algorithm(), sourceHash.Sum())
+	}
+	if recordHash != nil && recordHash != sourceHash {
+		opts.Cache.Store(srcPath, sourceInfo, opts.Checksums.Algorithm(), recordHash.Sum())
+	}
+
+	if opts.Checksums != nil {
+		if resized != nil {
+			return opts.Checksums.record(destPath, sumOf(opts.Checksums.newHash(), resized))
+		}
+		return opts.Checksums.record(destPath, recordHash.Sum())
+	}
+	return nil
+}
+
+// reads all of reader, the image named name, watching for stalls as copyStream does, and writes it to dest downscaled to fit within
+// size. It returns what was written in its place if it was resized; nil if it was written as it is because it
+// already fit or couldn't be read as an image, which is left for the frontend to show or not.
+func copyResized(dest io.Writer, reader io.Reader, name string, size artwork.Size, stallTimeout time.Duration, clock *stallClock, abort func()) (int64, []byte, error) {
+	var source bytes.Buffer
+	if _, err := copyStream(&source, reader, stallTimeout, clock, abort); err != nil {
+		return 0, nil, err
+	}
+	data, ok, err := artwork.Downscale(source.Bytes(), size)
+	if err != nil {
+		logging.LogVerbose(logging.Detail, logging.IconWarning, "Copying %s as it is: %v", name, err)
+	}
+	written, err := dest.Write(data)
+	if err != nil || !ok {
+		return int64(written), nil, err
+	}
+	return int64(written), data, nil
+}
+
+func sumOf(hasher hash.Hash, data []byte) []byte {
+	hasher.Write(data)
+	return hasher.Sum(nil)
 }
 
 func copyDir(sourcePath string, destPath string) error {
-	sourceInfo, err := os.Stat(sourcePath)
+	sourceInfo, err := targetFS.Stat(sourcePath)
 	if err != nil {
 		return fmt.Errorf("failed to get source directory info for %s: %w", sourcePath, err)
 	}
 
-	if err := os.MkdirAll(destPath, sourceInfo.Mode()); err != nil {
+	if err := targetFS.MkdirAll(destPath, sourceInfo.Mode()); err != nil {
 		return fmt.Errorf("failed to create destination directory %s: %w", destPath, err)
 	}
 
-	entries, err := os.ReadDir(sourcePath)
+	entries, err := targetFS.ReadDir(sourcePath)
 	if err != nil {
 		return fmt.Errorf("failed to read source directory %s: %w", sourcePath, err)
 	}
@@ -151,46 +483,460 @@ func copyDir(sourcePath string, destPath string) error {
 }
 
 // Directory operations
-func ClearDirectory(dirPath string) error {
-	entries, err := os.ReadDir(dirPath)
+
+// IsProtected reports whether the file at relPath, relative to a platform folder, matches any of the protect
+// globs, which nothing that clears platform folders deletes. It's matched case-insensitively, so protection errs
+// towards keeping a file.
+func IsProtected(relPath string, protect []string) bool {
+	relPath = strings.ToLower(filepath.ToSlash(relPath))
+	for _, pattern := range protect {
+		if matched, _ := doublestar.Match(strings.ToLower(filepath.ToSlash(pattern)), relPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// FindProtected returns the files beneath dirPath matching the protect globs, as sorted slash separated paths
+// relative to it; a dirPath that doesn't exist yet holds none
+func FindProtected(dirPath string, protect []string) ([]string, error) {
+	if _, err := targetFS.Stat(dirPath); os.IsNotExist(err) {
+		return make([]string, 0), nil
+	}
+	protected, _, err := FindMatching(dirPath, protect, nil)
+	return protected, err
+}
+
+// HoldsProtected reports whether relPath, a file or folder, is or holds any of protected, slash separated paths
+// relative to the same folder as FindProtected returns, which renaming or moving would take out from under their
+// globs. It's matched case-insensitively, as a rename onto a name differing only in case overwrites it on FAT.
+func HoldsProtected(relPath string, protected []string) bool {
+	relPath = strings.ToLower(filepath.ToSlash(relPath))
+	for _, p := range protected {
+		p = strings.ToLower(p)
+		if p == relPath || strings.HasPrefix(p, relPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ClearDirectory removes everything in dirPath but the files matching the protect globs, and the folders holding
+// them, returning the protected files kept as slash separated paths relative to dirPath
+func ClearDirectory(dirPath string, protect []string) ([]string, error) {
+	kept := make([]string, 0)
+	if err := clearDirectory(dirPath, "", protect, targetFS.RemoveAll, &kept); err != nil {
+		return nil, err
+	}
+	return kept, nil
+}
+
+// TrashDirectory clears dirPath as ClearDirectory does, but moves what it clears into trash, so it can be
+// recovered until the batch is pruned
+func TrashDirectory(dirPath string, protect []string, trash Trash) ([]string, error) {
+	kept := make([]string, 0)
+	err := clearDirectory(dirPath, "", protect, func(entryPath string) error {
+		return trash.move(dirPath, entryPath)
+	}, &kept)
+	if err != nil {
+		return nil, err
+	}
+	return kept, nil
+}
+
+// clears the folder at relPath within dirPath with remove, adding the protected files in it to kept
+func clearDirectory(dirPath string, relPath string, protect []string, remove func(entryPath string) error, kept *[]string) error {
+	folderPath := filepath.Join(dirPath, relPath)
+	entries, err := targetFS.ReadDir(folderPath)
 	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", dirPath, err)
+		return fmt.Errorf("failed to read directory %s: %w", folderPath, err)
 	}
 
 	for _, entry := range entries {
-		path := filepath.Join(dirPath, entry.Name())
-		if err := os.RemoveAll(path); err != nil {
-			return fmt.Errorf("failed to remove %s: %w", path, err)
+		entryRelPath := path.Join(relPath, entry.Name())
+		entryPath := filepath.Join(folderPath, entry.Name())
+		if entry.IsDir() && len(protect) > 0 {
+			if err := clearDirectory(dirPath, entryRelPath, protect, remove, kept); err != nil {
+				return err
+			}
+			// a folder left holding protected files stays
+			if remaining, err := targetFS.ReadDir(entryPath); err != nil || len(remaining) > 0 {
+				continue
+			}
+		}
+		if !entry.IsDir() && IsProtected(entryRelPath, protect) {
+			*kept = append(*kept, entryRelPath)
+			continue
 		}
+		if err := remove(entryPath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entryPath, err)
+		}
+	}
+
+	return nil
+}
+
+// ClearedEntry is an entry ClearDirectory would remove from a directory
+type ClearedEntry struct {
+	Name  string
+	IsDir bool
+	// the files removed with it, as slash separated paths relative to the directory being cleared; just Name
+	// for a file
+	Files []string
+	// of every file removed with it
+	Size int64
+}
+
+// PlanClear returns what ClearDirectory would remove from dirPath, by entry, and the protected files it would
+// keep, without removing anything. A directory that doesn't exist has nothing to remove.
+func PlanClear(dirPath string, protect []string) ([]ClearedEntry, []string, error) {
+	entries, err := targetFS.ReadDir(dirPath)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read directory %s: %w", dirPath, err)
+	}
+
+	cleared := make([]ClearedEntry, 0, len(entries))
+	kept := make([]string, 0)
+	for _, entry := range entries {
+		clearedEntry := ClearedEntry{Name: entry.Name(), IsDir: entry.IsDir(), Files: make([]string, 0)}
+		protected := 0
+		err := fsys.Walk(targetFS, filepath.Join(dirPath, entry.Name()), func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			relPath, err := filepath.Rel(dirPath, filePath)
+			if err != nil {
+				return err
+			}
+			if IsProtected(relPath, protect) {
+				kept = append(kept, filepath.ToSlash(relPath))
+				protected++
+				return nil
+			}
+			clearedEntry.Files = append(clearedEntry.Files, filepath.ToSlash(relPath))
+			clearedEntry.Size += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan %s: %w", filepath.Join(dirPath, entry.Name()), err)
+		}
+		// an entry that's protected, or a folder of nothing else, stays
+		if protected > 0 && len(clearedEntry.Files) == 0 {
+			continue
+		}
+		cleared = append(cleared, clearedEntry)
+	}
+	sort.Slice(cleared, func(i, j int) bool { return cleared[i].Name < cleared[j].Name })
+	sort.Strings(kept)
+	return cleared, kept, nil
+}
+
+// Transactional staging operations
+
+// suffixes for the hidden sibling folders used while staging a platform folder
+const (
+	stagingSuffix = ".romcopy-staging"
+	swapOldSuffix = ".romcopy-old"
+)
+
+// StagingPath returns the hidden sibling folder that a transactional run builds destPath's new contents in.
+// Keeping it next to destPath guarantees it's on the same volume so the final swap is a cheap rename.
+func StagingPath(destPath string) string {
+	return filepath.Join(filepath.Dir(destPath), "."+filepath.Base(destPath)+stagingSuffix)
+}
+
+// the hidden sibling folder SwapInStaging moves destPath's old contents aside to
+func swapOldPath(destPath string) string {
+	return filepath.Join(filepath.Dir(destPath), "."+filepath.Base(destPath)+swapOldSuffix)
+}
+
+// PrepareStaging creates a fresh staging folder for destPath, removing any leftovers from an interrupted run.
+// Without seedFromExisting, the files in destPath matching the protect globs are still copied in, so swapping the
+// staging folder in doesn't delete them.
+// If seedFromExisting is set, the current contents of destPath are copied in so the staged copy starts from
+// the same state a normal run would.
+func PrepareStaging(destPath string, seedFromExisting bool, protect []string) (string, error) {
+	stagingPath := StagingPath(destPath)
+
+	if err := targetFS.RemoveAll(stagingPath); err != nil {
+		return "", fmt.Errorf("failed to remove stale staging directory %s: %w", stagingPath, err)
+	}
+
+	info, statErr := targetFS.Stat(destPath)
+	if seedFromExisting && statErr == nil && info.IsDir() {
+		if err := copyDir(destPath, stagingPath); err != nil {
+			return "", fmt.Errorf("failed to seed staging directory %s from %s: %w", stagingPath, destPath, err)
+		}
+		return stagingPath, nil
+	}
+
+	if err := targetFS.MkdirAll(stagingPath, 0755); err != nil {
+		return "", fmt.Errorf("failed to create staging directory %s: %w", stagingPath, err)
+	}
+	if statErr != nil || !info.IsDir() || len(protect) == 0 {
+		return stagingPath, nil
+	}
+	err := fsys.Walk(targetFS, destPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(destPath, filePath)
+		if err != nil || !IsProtected(relPath, protect) {
+			return err
+		}
+		stagedPath := filepath.Join(stagingPath, relPath)
+		if err := targetFS.MkdirAll(filepath.Dir(stagedPath), 0755); err != nil {
+			return err
+		}
+		return CopyFile(filePath, stagedPath)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to keep protected files from %s in staging directory %s: %w", destPath, stagingPath, err)
+	}
+	return stagingPath, nil
+}
+
+// SwapInStaging replaces destPath with its staging folder. The old contents are moved aside, where they're kept
+// until FinishSwap removes them or UndoSwap puts them back; if the swap fails, the old contents are restored.
+func SwapInStaging(destPath string) error {
+	stagingPath := StagingPath(destPath)
+	oldPath := swapOldPath(destPath)
+
+	if err := targetFS.RemoveAll(oldPath); err != nil {
+		return fmt.Errorf("failed to remove stale swap directory %s: %w", oldPath, err)
+	}
+
+	hadExisting := false
+	if _, err := targetFS.Stat(destPath); err == nil {
+		if err := targetFS.Rename(destPath, oldPath); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", destPath, err)
+		}
+		hadExisting = true
+	}
+
+	if err := targetFS.Rename(stagingPath, destPath); err != nil {
+		if hadExisting {
+			if restoreErr := targetFS.Rename(oldPath, destPath); restoreErr != nil {
+				return fmt.Errorf("failed to swap in %s (%v) and failed to restore original from %s: %w", stagingPath, err, oldPath, restoreErr)
+			}
+		}
+		return fmt.Errorf("failed to swap in %s: %w", stagingPath, err)
+	}
+
+	return nil
+}
+
+// FinishSwap deletes the old contents SwapInStaging moved aside from destPath, if it had any
+func FinishSwap(destPath string) error {
+	return finishSwap(destPath, targetFS.RemoveAll)
+}
+
+// FinishSwapToTrash moves the old contents SwapInStaging moved aside from destPath into trash rather than
+// deleting them
+func FinishSwapToTrash(destPath string, trash Trash) error {
+	return finishSwap(destPath, func(oldPath string) error {
+		return moveToTrash(oldPath, trash.RootDir, trash.Batch, trash.RelDir)
+	})
+}
+
+func finishSwap(destPath string, discard func(oldPath string) error) error {
+	oldPath := swapOldPath(destPath)
+	if _, err := targetFS.Stat(oldPath); os.IsNotExist(err) {
+		return nil
 	}
+	if err := discard(oldPath); err != nil {
+		return fmt.Errorf("swapped in new contents but failed to remove old contents at %s: %w", oldPath, err)
+	}
+	return nil
+}
+
+// UndoSwap reverses SwapInStaging: what was swapped into destPath goes back to being its staging folder, and the
+// old contents, if it had any, are put back
+func UndoSwap(destPath string) error {
+	stagingPath := StagingPath(destPath)
+	oldPath := swapOldPath(destPath)
 
+	if err := targetFS.Rename(destPath, stagingPath); err != nil {
+		return fmt.Errorf("failed to move %s back to %s: %w", destPath, stagingPath, err)
+	}
+	if _, err := targetFS.Stat(oldPath); os.IsNotExist(err) {
+		return nil
+	}
+	if err := targetFS.Rename(oldPath, destPath); err != nil {
+		return fmt.Errorf("failed to restore %s from %s: %w", destPath, oldPath, err)
+	}
+	return nil
+}
+
+// DiscardStaging removes destPath's staging folder, leaving destPath untouched
+func DiscardStaging(destPath string) error {
+	stagingPath := StagingPath(destPath)
+	if err := targetFS.RemoveAll(stagingPath); err != nil {
+		return fmt.Errorf("failed to remove staging directory %s: %w", stagingPath, err)
+	}
 	return nil
 }
 
 // Content operations
-func SearchAndReplace(path string, glob string, searchTerm string, replaceTerm string, isRegex bool) (bool, error) {
-	pattern := filepath.Join(path, glob)
-	matches, err := doublestar.FilepathGlob(pattern)
+
+// the most of a file LooksBinary reads
+const binarySniffSize = 8000
+
+// LooksBinary reports whether content looks like a binary file, such as a ROM, rather than text: if it holds a NUL
+// byte, or more than a tenth of it is control characters other than whitespace and escapes, in its first 8000
+// bytes. Bytes from 0x80 up are taken as text, so Latin-1 game lists aren't mistaken for binary ones.
+func LooksBinary(content []byte) bool {
+	if len(content) > binarySniffSize {
+		content = content[:binarySniffSize]
+	}
+	control := 0
+	for _, b := range content {
+		switch {
+		case b == 0:
+			return true
+		case b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == '\b' || b == 0x1b:
+		case b < 0x20 || b == 0x7f:
+			control++
+		}
+	}
+	return control*10 > len(content)
+}
+
+// whether the file at filePath LooksBinary, reading only as much of it as that needs
+func fileLooksBinary(filePath string) (bool, error) {
+	file, err := targetFS.Open(filePath)
 	if err != nil {
-		return false, fmt.Errorf("failed to process glob pattern %s: %w", pattern, err)
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
 	}
+	defer file.Close()
 
-	if len(matches) == 0 {
-		return false, nil
+	head := make([]byte, binarySniffSize)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+	return LooksBinary(head[:n]), nil
+}
+
+// CheckReplacement checks that every capture group replace refers to, as '$1' or '$name' or '${1}', is one search
+// has. Go would quietly expand any other to nothing: '$1_small' refers to a group named '1_small', not group 1
+// followed by '_small', which is written '${1}_small'. '$$' is a literal '$', as is a '$' not followed by a name.
+func CheckReplacement(search *regexp.Regexp, replace string) error {
+	for rest := replace; ; {
+		i := strings.IndexByte(rest, '$')
+		if i < 0 || i == len(rest)-1 {
+			return nil
+		}
+		rest = rest[i+1:]
+		if rest[0] == '$' {
+			rest = rest[1:]
+			continue
+		}
+
+		var name string
+		if rest[0] == '{' {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 || groupNameLength(rest[1:end]) != end-1 {
+				continue
+			}
+			name, rest = rest[1:end], rest[end+1:]
+		} else {
+			end := groupNameLength(rest)
+			name, rest = rest[:end], rest[end:]
+		}
+		if name == "" {
+			continue
+		}
+
+		if num, err := strconv.Atoi(name); err == nil {
+			if num > search.NumSubexp() {
+				return fmt.Errorf("replacement '%s' refers to group $%d, but '%s' has %d group(s)", replace, num, search, search.NumSubexp())
+			}
+			continue
+		}
+		if search.SubexpIndex(name) >= 0 {
+			continue
+		}
+		if text := strings.TrimLeft(name, "0123456789"); text != name {
+			return fmt.Errorf("replacement '%s' refers to a group named '%s', which '%s' doesn't have; write '${%s}%s' for group %s followed by '%s'", replace, name, search, name[:len(name)-len(text)], text, name[:len(name)-len(text)], text)
+		}
+		return fmt.Errorf("replacement '%s' refers to a group named '%s', which '%s' doesn't have; write '$$' for a literal '$'", replace, name, search)
+	}
+}
+
+// the length of the capture group name at the start of s: letters, digits, and underscores, as regexp.Expand reads it
+func groupNameLength(s string) int {
+	end := 0
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+// SearchAndReplace replaces searchTerm with replaceTerm in the files within path matching glob, leaving protected,
+// slash separated paths relative to path as FindProtected returns, unchanged, and unless rewriteBinary is set,
+// those that LooksBinary. Reports whether glob matched any, and the files left unchanged as binary.
+func SearchAndReplace(path string, glob string, searchTerm string, replaceTerm string, isRegex bool, rewriteBinary bool, protected []string) (bool, []string, error) {
+	// glob relative to path so characters like '[' in the destination folder's own name aren't treated as
+	// part of the pattern
+	pattern := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(glob)), "./")
+	relMatches, err := doublestar.Glob(fsys.IOFS(targetFS, path), pattern)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to process glob pattern %s in %s: %w", glob, path, err)
+	}
+
+	matches := make([]string, 0, len(relMatches))
+	for _, match := range relMatches {
+		if HoldsProtected(match, protected) {
+			logging.Log(logging.Detail, logging.IconSkip, "Left protected %s unchanged", match)
+			continue
+		}
+		matches = append(matches, filepath.Join(path, filepath.FromSlash(match)))
+	}
+
+	if len(relMatches) == 0 {
+		return false, nil, nil
 	}
 
 	var searchRegex *regexp.Regexp
 	if isRegex {
 		searchRegex, err = regexp.Compile(searchTerm)
 		if err != nil {
-			return true, fmt.Errorf("invalid regex pattern %s: %w", searchTerm, err)
+			return true, nil, fmt.Errorf("invalid regex pattern %s: %w", searchTerm, err)
+		}
+		if err := CheckReplacement(searchRegex, replaceTerm); err != nil {
+			return true, nil, err
 		}
 	}
 
+	binary := make([]string, 0)
 	for _, file := range matches {
-		content, err := os.ReadFile(file)
+		// sniffed before the whole file is read, as a glob gone wrong can match every disc image in a folder
+		if !rewriteBinary {
+			isBinary, err := fileLooksBinary(file)
+			if err != nil {
+				return true, nil, err
+			}
+			if isBinary {
+				logging.Log(logging.Detail, logging.IconSkip, "Left %s unchanged as it looks binary", file)
+				binary = append(binary, file)
+				continue
+			}
+		}
+
+		content, err := targetFS.ReadFile(file)
 		if err != nil {
-			return true, fmt.Errorf("failed to read file %s: %w", file, err)
+			return true, nil, fmt.Errorf("failed to read file %s: %w", file, err)
 		}
 
 		var newContent []byte
@@ -200,12 +946,12 @@ func SearchAndReplace(path string, glob string, searchTerm string, replaceTerm s
 			newContent = []byte(strings.ReplaceAll(string(content), searchTerm, replaceTerm))
 		}
 
-		if err := os.WriteFile(file, newContent, 0644); err != nil {
-			return true, fmt.Errorf("failed to write to file %s: %w", file, err)
+		if err := targetFS.WriteFile(file, newContent, 0644); err != nil {
+			return true, nil, fmt.Errorf("failed to write to file %s: %w", file, err)
 		}
 
 		logging.Log(logging.Detail, logging.IconRewrite, "Rewrote %s", file)
 	}
 
-	return true, nil
+	return true, binary, nil
 }
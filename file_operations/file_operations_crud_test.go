@@ -2,9 +2,14 @@ package file_operations
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
 )
 
 // testSetup creates a temporary directory and returns cleanup function
@@ -262,6 +267,15 @@ func TestCopyFile(t *testing.T) {
 	}
 }
 
+func TestDestinationFileMode(t *testing.T) {
+	if got := DestinationFileMode(0777, 0644); got != 0644 {
+		t.Errorf("DestinationFileMode() with override = %o, want 644", got)
+	}
+	if got, want := DestinationFileMode(0777|os.ModeSetuid, 0), 0777&^processUmask; got != want {
+		t.Errorf("DestinationFileMode() without override = %o, want %o", got, want)
+	}
+}
+
 func TestCopyDir(t *testing.T) {
 	tmpDir, cleanup := testSetup(t)
 	defer cleanup()
@@ -412,7 +426,7 @@ func TestClearDirectory(t *testing.T) {
 				t.Fatalf("Setup failed: %v", err)
 			}
 
-			err := ClearDirectory(testDir)
+			_, err := ClearDirectory(testDir, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ClearDirectory() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -424,3 +438,177 @@ func TestClearDirectory(t *testing.T) {
 		})
 	}
 }
+
+func TestPlanClear(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"SFC/Zelda.sfc":             "rom",
+		"SFC/Imgs/Zelda.png":        "image",
+		"SFC/Imgs/nested/Mario.png": "image",
+		"SFC/empty":                 "DIR",
+	})
+	defer cleanup()
+	destPath := filepath.Join(tmpDir, "SFC")
+
+	cleared, kept, err := PlanClear(destPath, nil)
+	if err != nil {
+		t.Fatalf("PlanClear() error = %v", err)
+	}
+	want := []ClearedEntry{
+		{Name: "Imgs", IsDir: true, Files: []string{"Imgs/Zelda.png", "Imgs/nested/Mario.png"}, Size: 10},
+		{Name: "Zelda.sfc", Files: []string{"Zelda.sfc"}, Size: 3},
+		{Name: "empty", IsDir: true, Files: []string{}},
+	}
+	if !reflect.DeepEqual(cleared, want) || len(kept) != 0 {
+		t.Errorf("PlanClear() = %+v, %v; want %+v and nothing kept", cleared, kept, want)
+	}
+	verifyFileContent(t, filepath.Join(destPath, "Zelda.sfc"), "rom")
+
+	if cleared, _, err := PlanClear(filepath.Join(tmpDir, "missing"), nil); err != nil || len(cleared) != 0 {
+		t.Errorf("PlanClear() of a missing directory = %+v, %v; want nothing", cleared, err)
+	}
+
+	cleared, kept, err = PlanClear(destPath, []string{"**/*.sfc", "**/nested/**"})
+	if err != nil {
+		t.Fatalf("PlanClear() error = %v", err)
+	}
+	want = []ClearedEntry{
+		{Name: "Imgs", IsDir: true, Files: []string{"Imgs/Zelda.png"}, Size: 5},
+		{Name: "empty", IsDir: true, Files: []string{}},
+	}
+	wantKept := []string{"Imgs/nested/Mario.png", "Zelda.sfc"}
+	if !reflect.DeepEqual(cleared, want) || !reflect.DeepEqual(kept, wantKept) {
+		t.Errorf("PlanClear() with protection = %+v, %v; want %+v, %v", cleared, kept, want, wantKept)
+	}
+}
+
+func TestIsProtected(t *testing.T) {
+	protect := []string{"**/*.srm", "**/*.state*", "**/saves/**", "bios.bin"}
+	tests := []struct {
+		relPath string
+		want    bool
+	}{
+		{"Zelda.srm", true},
+		{"saves/Zelda.SRM", true},
+		{"Zelda.state", true},
+		{"Zelda.state12", true},
+		{"Saves/SFC/Zelda.bin", true},
+		{"bios.bin", true},
+		{"Zelda.sfc", false},
+		{"Imgs/bios.bin", false},
+		{"Savestates.txt", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsProtected(filepath.FromSlash(tt.relPath), protect); got != tt.want {
+			t.Errorf("IsProtected(%q) = %v, want %v", tt.relPath, got, tt.want)
+		}
+	}
+	if IsProtected("Zelda.srm", nil) {
+		t.Error("IsProtected() with no globs should protect nothing")
+	}
+}
+
+func TestFindAndHoldsProtected(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"Zelda.srm":          "save",
+		"Zelda.sfc":          "rom",
+		"Hacks/Mario.sfc":    "rom",
+		"Hacks/sub/Mario.sr": "save",
+		"Imgs/Zelda.png":     "png",
+	}
+	if err := createTestDir(tmpDir, files); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	protected, err := FindProtected(tmpDir, []string{"**/*.srm", "hacks/sub/*.sr"})
+	if err != nil {
+		t.Fatalf("FindProtected() error = %v", err)
+	}
+	if want := []string{"Hacks/sub/Mario.sr", "Zelda.srm"}; !reflect.DeepEqual(protected, want) {
+		t.Errorf("FindProtected() = %v, want %v", protected, want)
+	}
+	if missing, err := FindProtected(filepath.Join(tmpDir, "missing"), []string{"**"}); err != nil || len(missing) != 0 {
+		t.Errorf("FindProtected() of a missing folder = %v, %v; want none, nil", missing, err)
+	}
+
+	tests := []struct {
+		relPath string
+		want    bool
+	}{
+		{"Zelda.srm", true},
+		{"zelda.SRM", true},
+		{"Zelda.sfc", false},
+		{"Hacks", true},
+		{"Hacks/sub", true},
+		{"Hacks/Mario.sfc", false},
+		{"Hack", false},
+		{"Imgs", false},
+		{"missing", false},
+	}
+	for _, tt := range tests {
+		if got := HoldsProtected(filepath.FromSlash(tt.relPath), protected); got != tt.want {
+			t.Errorf("HoldsProtected(%q) = %v, want %v", tt.relPath, got, tt.want)
+		}
+	}
+}
+
+func TestClearDirectoryProtect(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"SFC/Zelda.sfc":          "rom",
+		"SFC/Zelda.srm":          "80 hours",
+		"SFC/Zelda.state1":       "state",
+		"SFC/Saves/Mario.bin":    "save",
+		"SFC/Imgs/Zelda.png":     "image",
+		"SFC/Imgs/old/Mario.png": "image",
+	})
+	defer cleanup()
+	destPath := filepath.Join(tmpDir, "SFC")
+
+	kept, err := ClearDirectory(destPath, []string{"**/*.srm", "**/*.state*", "**/saves/**"})
+	if err != nil {
+		t.Fatalf("ClearDirectory() error = %v", err)
+	}
+	wantKept := []string{"Saves/Mario.bin", "Zelda.srm", "Zelda.state1"}
+	sort.Strings(kept)
+	if !reflect.DeepEqual(kept, wantKept) {
+		t.Errorf("ClearDirectory() kept %v, want %v", kept, wantKept)
+	}
+
+	verifyFileContent(t, filepath.Join(destPath, "Zelda.srm"), "80 hours")
+	verifyFileContent(t, filepath.Join(destPath, "Zelda.state1"), "state")
+	verifyFileContent(t, filepath.Join(destPath, "Saves", "Mario.bin"), "save")
+	for _, removed := range []string{"Zelda.sfc", "Imgs"} {
+		if _, err := os.Stat(filepath.Join(destPath, removed)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", removed)
+		}
+	}
+}
+
+func TestReadOnlyFilesystem(t *testing.T) {
+	tmpDir, cleanup := testSetup(t)
+	defer cleanup()
+
+	SetFilesystem(fsys.ReadOnly(fsys.OS))
+	defer SetFilesystem(fsys.OS)
+
+	srcPath := filepath.Join(tmpDir, "source.nes")
+	destPath := filepath.Join(tmpDir, "dest.nes")
+	if err := createTestFile(srcPath, "rom data"); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	if err := CopyFile(srcPath, destPath); !errors.Is(err, fsys.ErrReadOnly) {
+		t.Errorf("CopyFile() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := os.Stat(destPath); !os.IsNotExist(err) {
+		t.Errorf("destination file was created on a read-only filesystem")
+	}
+
+	if _, err := ClearDirectory(tmpDir, nil); !errors.Is(err, fsys.ErrReadOnly) {
+		t.Errorf("ClearDirectory() error = %v, want ErrReadOnly", err)
+	}
+	if _, err := os.Stat(srcPath); err != nil {
+		t.Errorf("source file was removed from a read-only filesystem: %v", err)
+	}
+}
@@ -155,3 +155,133 @@ func TestExplodeFolder(t *testing.T) {
 		})
 	}
 }
+
+func TestMoveFolderContents(t *testing.T) {
+	baseDir, cleanup := setupTestFolder(t, map[string]string{
+		"platform/images/game1.png": "new1",
+		"platform/images/game2.png": "new2",
+		"catalogue/box/game1.png":   "old1",
+		"catalogue/box/game3.png":   "old3",
+	})
+	defer cleanup()
+
+	moved, found, err := MoveFolderContents(filepath.Join(baseDir, "platform/images"), filepath.Join(baseDir, "catalogue/box"))
+	if err != nil || !found || moved != 2 {
+		t.Fatalf("MoveFolderContents() = %d, %v, %v; want 2 items moved", moved, found, err)
+	}
+	verifyFileContent(t, filepath.Join(baseDir, "catalogue/box/game1.png"), "new1")
+	verifyFileContent(t, filepath.Join(baseDir, "catalogue/box/game2.png"), "new2")
+	verifyFileContent(t, filepath.Join(baseDir, "catalogue/box/game3.png"), "old3")
+	if verifyFileExists(t, filepath.Join(baseDir, "platform/images")) {
+		t.Error("Moved folder should be removed")
+	}
+
+	// a new destination is created
+	baseDir, cleanup = setupTestFolder(t, map[string]string{"images/game.png": "art"})
+	defer cleanup()
+	if moved, _, err := MoveFolderContents(filepath.Join(baseDir, "images"), filepath.Join(baseDir, "a/b/box")); err != nil || moved != 1 {
+		t.Fatalf("MoveFolderContents() = %d, %v; want 1 item moved", moved, err)
+	}
+	verifyFileContent(t, filepath.Join(baseDir, "a/b/box/game.png"), "art")
+
+	if _, found, err := MoveFolderContents(filepath.Join(baseDir, "missing"), filepath.Join(baseDir, "box")); found || err != nil {
+		t.Errorf("MoveFolderContents() of a missing folder = %v, %v; want it not found", found, err)
+	}
+}
+
+func TestExplodeFoldersRecursive(t *testing.T) {
+	baseDir, cleanup := setupTestFolder(t, map[string]string{
+		"images/Tetris.png":                    "tetris",
+		"Final Fantasy VII/images/Disc 1.png":  "ff7 disc 1",
+		"Final Fantasy VII/Disc 1.cue":         "cue",
+		"Hacks/Zelda/images/images/Zelda.png":  "nested",
+		"Hacks/Zelda/images/Zelda Title.png":   "title",
+		"Hacks/Zelda/media/images/Zelda 2.png": "media",
+		"Hacks/Zelda/not-images/Zelda Map.png": "map",
+		"Hacks/Zelda/Zelda.sfc":                "rom",
+	})
+	defer cleanup()
+
+	exploded, err := ExplodeFoldersRecursive(baseDir, "images")
+	if err != nil {
+		t.Fatalf("ExplodeFoldersRecursive() error = %v", err)
+	}
+	if len(exploded) != 5 || exploded[0] != filepath.Join("Hacks", "Zelda", "images", "images") {
+		t.Errorf("ExplodeFoldersRecursive() exploded %v, want 5 folders, deepest first", exploded)
+	}
+
+	verifyFileContent(t, filepath.Join(baseDir, "Tetris.png"), "tetris")
+	verifyFileContent(t, filepath.Join(baseDir, "Final Fantasy VII", "Disc 1.png"), "ff7 disc 1")
+	verifyFileContent(t, filepath.Join(baseDir, "Hacks", "Zelda", "Zelda.png"), "nested")
+	verifyFileContent(t, filepath.Join(baseDir, "Hacks", "Zelda", "Zelda Title.png"), "title")
+	verifyFileContent(t, filepath.Join(baseDir, "Hacks", "Zelda", "media", "Zelda 2.png"), "media")
+	verifyFileContent(t, filepath.Join(baseDir, "Hacks", "Zelda", "not-images", "Zelda Map.png"), "map")
+	for _, removed := range []string{"images", filepath.Join("Final Fantasy VII", "images"), filepath.Join("Hacks", "Zelda", "images")} {
+		if verifyFileExists(t, filepath.Join(baseDir, removed)) {
+			t.Errorf("%s should have been exploded", removed)
+		}
+	}
+
+	// a match that's a path explodes into the folder where it starts
+	exploded, err = ExplodeFoldersRecursive(baseDir, filepath.Join("Zelda", "media"))
+	if err != nil || len(exploded) != 1 {
+		t.Fatalf("ExplodeFoldersRecursive() = %v, %v; want Hacks/Zelda/media exploded", exploded, err)
+	}
+	verifyFileContent(t, filepath.Join(baseDir, "Hacks", "Zelda 2.png"), "media")
+}
+
+func TestExplodeMatchingFolders(t *testing.T) {
+	baseDir, cleanup := setupTestFolder(t, map[string]string{
+		"images/Tetris.png":        "tetris",
+		"Imgs/Zelda.png":           "zelda",
+		"imgs/Mario.png":           "mario",
+		"manuals/Zelda.pdf":        "manual",
+		"Hacks/images/Hack.png":    "hack",
+		"Hacks/Hack.sfc":           "rom",
+		"media/images/Metroid.png": "metroid",
+	})
+	defer cleanup()
+
+	exploded, err := ExplodeMatchingFolders(baseDir, "IM*G*")
+	if err != nil {
+		t.Fatalf("ExplodeMatchingFolders() error = %v", err)
+	}
+	if len(exploded) != 3 {
+		t.Errorf("ExplodeMatchingFolders() exploded %v, want images, Imgs, and imgs", exploded)
+	}
+	verifyFileContent(t, filepath.Join(baseDir, "Tetris.png"), "tetris")
+	verifyFileContent(t, filepath.Join(baseDir, "Zelda.png"), "zelda")
+	verifyFileContent(t, filepath.Join(baseDir, "Mario.png"), "mario")
+	verifyFileContent(t, filepath.Join(baseDir, "manuals", "Zelda.pdf"), "manual")
+	verifyFileContent(t, filepath.Join(baseDir, "Hacks", "images", "Hack.png"), "hack")
+
+	if exploded, err = ExplodeMatchingFolders(baseDir, "med*/im?ges"); err != nil || len(exploded) != 1 {
+		t.Fatalf("ExplodeMatchingFolders() = %v, %v; want media/images exploded", exploded, err)
+	}
+	verifyFileContent(t, filepath.Join(baseDir, "Metroid.png"), "metroid")
+}
+
+func TestExplodedPath(t *testing.T) {
+	tests := []struct {
+		relPath    string
+		explodeDir string
+		anyDepth   bool
+		want       string
+	}{
+		{"images/Tetris.png", "images", false, "Tetris.png"},
+		{"Imgs/Tetris.png", "images", false, "Imgs/Tetris.png"},
+		{"Imgs/Tetris.png", "im*g*", false, "Tetris.png"},
+		{"Game/images/Game.png", "images", false, "Game/images/Game.png"},
+		{"Game/images/Game.png", "images", true, "Game/Game.png"},
+		{"Game/Images/images/Game.png", "IMAGES", true, "Game/Images/images/Game.png"},
+		{"Game/Images/images/Game.png", "[Ii]mages", true, "Game/Game.png"},
+		{"media/images/Game.png", "media/images", false, "Game.png"},
+		{"Game/media/images/Game.png", "media/im*", true, "Game/Game.png"},
+		{"images", "images", true, "images"},
+	}
+	for _, tt := range tests {
+		if got := ExplodedPath(tt.relPath, tt.explodeDir, tt.anyDepth); got != tt.want {
+			t.Errorf("ExplodedPath(%q, %q, %v) = %q, want %q", tt.relPath, tt.explodeDir, tt.anyDepth, got, tt.want)
+		}
+	}
+}
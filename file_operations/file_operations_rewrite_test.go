@@ -0,0 +1,172 @@
+package file_operations
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSearchAndReplace(t *testing.T) {
+	// brackets in the destination's own path must not be treated as part of the glob
+	tmpDir := filepath.Join(t.TempDir(), "Roms [USA]", "Consoles", "SNES")
+	files := map[string]string{
+		"gamelist.xml":        "<path>../images/a.png</path>",
+		"sub/gamelist.xml":    "<path>../images/b.png</path>",
+		"game.sfc":            "../images",
+		"images/boxart.png":   "png",
+		"sub/images/keep.txt": "../images",
+	}
+	if err := createTestDir(tmpDir, files); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	found, binary, err := SearchAndReplace(tmpDir, "**/*.xml", "../images", "./images", false, false, nil)
+	if err != nil || !found || len(binary) != 0 {
+		t.Fatalf("SearchAndReplace() = %v, %v, %v; want true, none binary, nil", found, binary, err)
+	}
+
+	expected := map[string]string{
+		"gamelist.xml":        "<path>./images/a.png</path>",
+		"sub/gamelist.xml":    "<path>./images/b.png</path>",
+		"game.sfc":            "../images",
+		"sub/images/keep.txt": "../images",
+	}
+	for name, want := range expected {
+		got, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	// protected files are left unchanged, but still count as matches
+	found, _, err = SearchAndReplace(tmpDir, "**/*.txt", "../images", "./images", false, false, []string{"sub/images/keep.txt"})
+	if err != nil || !found {
+		t.Fatalf("SearchAndReplace() of a protected file = %v, %v; want true, nil", found, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(tmpDir, "sub", "images", "keep.txt")); string(got) != "../images" {
+		t.Errorf("protected keep.txt = %q, want it unchanged", got)
+	}
+
+	found, _, err = SearchAndReplace(tmpDir, "*.m3u", "a", "b", false, false, nil)
+	if err != nil || found {
+		t.Errorf("SearchAndReplace() with no matches = %v, %v; want false, nil", found, err)
+	}
+}
+
+func TestSearchAndReplaceCaptureGroups(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"gamelist.xml": "<image>./images/a.png</image>\n<image>./images/b.png</image>",
+	}
+	if err := createTestDir(tmpDir, files); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	rewrites := []struct{ search, replace, want string }{
+		{`<image>./images/(.*)</image>`, `<image>/mnt/SDCARD/Imgs/$1</image>`, "<image>/mnt/SDCARD/Imgs/a.png</image>\n<image>/mnt/SDCARD/Imgs/b.png</image>"},
+		{`Imgs/(?P<game>\w+)\.png`, `Imgs/${game}_small.png`, "<image>/mnt/SDCARD/Imgs/a_small.png</image>\n<image>/mnt/SDCARD/Imgs/b_small.png</image>"},
+		{`/(\w)_small`, `/${1}_$$1`, "<image>/mnt/SDCARD/Imgs/a_$1.png</image>\n<image>/mnt/SDCARD/Imgs/b_$1.png</image>"},
+	}
+	for _, r := range rewrites {
+		if _, _, err := SearchAndReplace(tmpDir, "*.xml", r.search, r.replace, true, false, nil); err != nil {
+			t.Fatalf("SearchAndReplace(%q, %q) error = %v", r.search, r.replace, err)
+		}
+		if got, _ := os.ReadFile(filepath.Join(tmpDir, "gamelist.xml")); string(got) != r.want {
+			t.Errorf("after replacing %q with %q, gamelist.xml = %q, want %q", r.search, r.replace, got, r.want)
+		}
+	}
+
+	// a reference to a group the search doesn't have would quietly write nothing
+	if _, _, err := SearchAndReplace(tmpDir, "*.xml", `(\w)_`, `$1_x`, true, false, nil); err == nil {
+		t.Error("SearchAndReplace() with a replacement referring to a missing group should fail")
+	}
+}
+
+func TestCheckReplacement(t *testing.T) {
+	search := regexp.MustCompile(`(\w+)/(?P<name>\w+)`)
+	tests := []struct {
+		replace string
+		valid   bool
+	}{
+		{"plain text", true},
+		{"$1/$2", true},
+		{"${1}_small/${name}", true},
+		{"$name.png", true},
+		{"$$1 costs $$", true},
+		{"trailing $", true},
+		{"${not a name}", true},
+		{"$3", false},
+		{"$1_small", false},
+		{"${missing}", false},
+	}
+	for _, tt := range tests {
+		err := CheckReplacement(search, tt.replace)
+		if (err == nil) != tt.valid {
+			t.Errorf("CheckReplacement(%q) error = %v, want valid %v", tt.replace, err, tt.valid)
+		}
+	}
+}
+
+func TestSearchAndReplaceBinary(t *testing.T) {
+	tmpDir := t.TempDir()
+	rom := "NES\x1a\x02\x01\x00\x00../images"
+	files := map[string]string{
+		"game.nes":     rom,
+		"gamelist.xml": "<name>Pokémon</name>\r\n<path>../images</path>",
+	}
+	if err := createTestDir(tmpDir, files); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	// a glob that matches everything leaves what looks binary alone
+	found, binary, err := SearchAndReplace(tmpDir, "*", "../images", "./images", false, false, nil)
+	if err != nil || !found {
+		t.Fatalf("SearchAndReplace() = %v, %v; want true, nil", found, err)
+	}
+	if len(binary) != 1 || filepath.Base(binary[0]) != "game.nes" {
+		t.Errorf("SearchAndReplace() left %v unchanged as binary, want only game.nes", binary)
+	}
+	if got, _ := os.ReadFile(filepath.Join(tmpDir, "game.nes")); string(got) != rom {
+		t.Errorf("game.nes = %q, want it unchanged", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(tmpDir, "gamelist.xml")); !strings.Contains(string(got), "./images") || strings.Contains(string(got), "../images") {
+		t.Errorf("gamelist.xml = %q, want it rewritten", got)
+	}
+
+	// unless it's asked for
+	if _, binary, err = SearchAndReplace(tmpDir, "*.nes", "../images", "./images", false, true, nil); err != nil || len(binary) != 0 {
+		t.Fatalf("SearchAndReplace() with rewriteBinary = %v, %v; want none binary, nil", binary, err)
+	}
+	if got, _ := os.ReadFile(filepath.Join(tmpDir, "game.nes")); !strings.HasSuffix(string(got), "\x00./images") {
+		t.Errorf("game.nes = %q, want it rewritten", got)
+	}
+}
+
+func TestLooksBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"empty", "", false},
+		{"xml", "<?xml version=\"1.0\"?>\n<gameList>\n\t<game/>\n</gameList>\n", false},
+		{"latin-1", "Pok\xe9mon \xa9 Nintendo\r\n", false},
+		{"ansi colors", "\x1b[1mbold\x1b[0m\n", false},
+		{"nul byte", "text\x00text", true},
+		{"mostly control characters", "\x01\x02\x03\x04\x05text", true},
+		// only the start of the file is sniffed
+		{"nul byte past the start", strings.Repeat("a", binarySniffSize) + "\x00", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LooksBinary([]byte(tt.content)); got != tt.want {
+				t.Errorf("LooksBinary(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package file_operations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStagingPath(t *testing.T) {
+	got := StagingPath(filepath.Join("target", "SFC"))
+	want := filepath.Join("target", ".SFC.romcopy-staging")
+	if got != want {
+		t.Errorf("StagingPath() = %q, want %q", got, want)
+	}
+}
+
+func TestPrepareStaging(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"SFC/old.sfc":                       "old rom",
+		".SFC.romcopy-staging/leftover.sfc": "from a crashed run",
+	})
+	defer cleanup()
+
+	destPath := filepath.Join(tmpDir, "SFC")
+
+	t.Run("seeded from existing", func(t *testing.T) {
+		stagingPath, err := PrepareStaging(destPath, true, nil)
+		if err != nil {
+			t.Fatalf("PrepareStaging() error = %v", err)
+		}
+		verifyFileContent(t, filepath.Join(stagingPath, "old.sfc"), "old rom")
+		if verifyFileExists(t, filepath.Join(stagingPath, "leftover.sfc")) {
+			t.Error("stale staging contents should have been removed")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		stagingPath, err := PrepareStaging(destPath, false, nil)
+		if err != nil {
+			t.Fatalf("PrepareStaging() error = %v", err)
+		}
+		entries, err := os.ReadDir(stagingPath)
+		if err != nil {
+			t.Fatalf("failed to read staging dir: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("expected empty staging dir, found %d entries", len(entries))
+		}
+	})
+
+	t.Run("empty but for protected files", func(t *testing.T) {
+		os.MkdirAll(filepath.Join(destPath, "Saves"), 0755)
+		if err := createTestFile(filepath.Join(destPath, "Saves", "old.srm"), "save"); err != nil {
+			t.Fatalf("failed to create save: %v", err)
+		}
+		stagingPath, err := PrepareStaging(destPath, false, []string{"**/*.srm"})
+		if err != nil {
+			t.Fatalf("PrepareStaging() error = %v", err)
+		}
+		verifyFileContent(t, filepath.Join(stagingPath, "Saves", "old.srm"), "save")
+		if verifyFileExists(t, filepath.Join(stagingPath, "old.sfc")) {
+			t.Error("unprotected files should not have been staged")
+		}
+	})
+}
+
+func TestSwapInStaging(t *testing.T) {
+	tests := []struct {
+		name      string
+		structure map[string]string
+	}{
+		{
+			name: "replaces existing destination",
+			structure: map[string]string{
+				"SFC/old.sfc":                  "old rom",
+				".SFC.romcopy-staging/new.sfc": "new rom",
+			},
+		},
+		{
+			name: "creates missing destination",
+			structure: map[string]string{
+				".SFC.romcopy-staging/new.sfc": "new rom",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, cleanup := setupTestFolder(t, tt.structure)
+			defer cleanup()
+
+			destPath := filepath.Join(tmpDir, "SFC")
+			if err := SwapInStaging(destPath); err != nil {
+				t.Fatalf("SwapInStaging() error = %v", err)
+			}
+			if err := FinishSwap(destPath); err != nil {
+				t.Fatalf("FinishSwap() error = %v", err)
+			}
+
+			verifyFileContent(t, filepath.Join(destPath, "new.sfc"), "new rom")
+			if verifyFileExists(t, filepath.Join(destPath, "old.sfc")) {
+				t.Error("old contents should have been replaced")
+			}
+
+			entries, err := os.ReadDir(tmpDir)
+			if err != nil {
+				t.Fatalf("failed to read temp dir: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("expected only the destination folder to remain, found %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestUndoSwap(t *testing.T) {
+	tests := []struct {
+		name      string
+		structure map[string]string
+	}{
+		{
+			name: "puts back existing destination",
+			structure: map[string]string{
+				"SFC/old.sfc":                  "old rom",
+				".SFC.romcopy-staging/new.sfc": "new rom",
+			},
+		},
+		{
+			name: "removes destination that was missing",
+			structure: map[string]string{
+				".SFC.romcopy-staging/new.sfc": "new rom",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir, cleanup := setupTestFolder(t, tt.structure)
+			defer cleanup()
+
+			destPath := filepath.Join(tmpDir, "SFC")
+			if err := SwapInStaging(destPath); err != nil {
+				t.Fatalf("SwapInStaging() error = %v", err)
+			}
+			if err := UndoSwap(destPath); err != nil {
+				t.Fatalf("UndoSwap() error = %v", err)
+			}
+
+			verifyFileContent(t, filepath.Join(StagingPath(destPath), "new.sfc"), "new rom")
+			if _, existed := tt.structure["SFC/old.sfc"]; existed {
+				verifyFileContent(t, filepath.Join(destPath, "old.sfc"), "old rom")
+			} else if verifyFileExists(t, destPath) {
+				t.Error("destination that didn't exist before the swap should be gone")
+			}
+			if verifyFileExists(t, swapOldPath(destPath)) {
+				t.Error("old contents should have been moved back")
+			}
+		})
+	}
+}
+
+func TestDiscardStaging(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"SFC/old.sfc":                  "old rom",
+		".SFC.romcopy-staging/new.sfc": "new rom",
+	})
+	defer cleanup()
+
+	destPath := filepath.Join(tmpDir, "SFC")
+	if err := DiscardStaging(destPath); err != nil {
+		t.Fatalf("DiscardStaging() error = %v", err)
+	}
+
+	verifyFileContent(t, filepath.Join(destPath, "old.sfc"), "old rom")
+	if verifyFileExists(t, StagingPath(destPath)) {
+		t.Error("staging directory should have been removed")
+	}
+}
@@ -0,0 +1,159 @@
+package file_operations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// heuristics for spotting failing or counterfeit destination media
+const (
+	// transfers smaller than this are dominated by per-file overhead and say little about throughput
+	healthMinSampleBytes = 1024 * 1024
+	// number of large transfers at the start and end of the run compared against each other
+	healthWindow = 5
+	// recent throughput below this fraction of the early throughput counts as a collapse
+	healthCollapseRatio = 0.1
+	// this many I/O errors over a run is worth a warning on its own
+	healthIOErrorThreshold = 3
+)
+
+type throughputSample struct {
+	bytes   int64
+	elapsed time.Duration
+}
+
+func (s throughputSample) bytesPerSecond() float64 {
+	if s.elapsed <= 0 {
+		return 0
+	}
+	return float64(s.bytes) / s.elapsed.Seconds()
+}
+
+// where in which destination file an I/O error occurred
+type ioErrorSpot struct {
+	path   string
+	offset int64
+}
+
+// HealthMonitor watches write throughput and errors across a run for signs that the destination media is
+// failing or counterfeit. It is safe for concurrent use.
+type HealthMonitor struct {
+	mu sync.Mutex
+	// transfers flushed to the media, and those only timed until the operating system buffered them, which are
+	// kept apart as they can't be compared
+	samples         []throughputSample
+	bufferedSamples []throughputSample
+	// spot at which an I/O error occurred -> number of occurrences
+	ioErrorSpots map[ioErrorSpot]int
+	ioErrors     int
+	stalls       int
+}
+
+func NewHealthMonitor() *HealthMonitor {
+	return &HealthMonitor{ioErrorSpots: make(map[ioErrorSpot]int)}
+}
+
+// RecordTransfer notes a completed file copy, and whether it was flushed to the media. Only flushed copies time
+// the media itself; the rest time how fast the operating system buffers the writes, which only slows once its
+// cache is full and it's writing back as fast as the media takes it.
+func (m *HealthMonitor) RecordTransfer(bytes int64, elapsed time.Duration, flushed bool) {
+	if m == nil || bytes < healthMinSampleBytes {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sample := throughputSample{bytes: bytes, elapsed: elapsed}
+	if flushed {
+		m.samples = append(m.samples, sample)
+	} else {
+		m.bufferedSamples = append(m.bufferedSamples, sample)
+	}
+}
+
+// RecordError notes a failed copy to the file at path and how far into the file it got
+func (m *HealthMonitor) RecordError(path string, offset int64, err error) {
+	if m == nil || err == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case errors.Is(err, ErrStalled):
+		m.stalls++
+	case errors.Is(err, syscall.EIO):
+		m.ioErrors++
+		m.ioErrorSpots[ioErrorSpot{path: path, offset: offset}]++
+	}
+}
+
+// Warnings returns a human-readable description of each worrying pattern seen so far
+func (m *HealthMonitor) Warnings() []string {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	warnings := make([]string, 0)
+
+	if early, recent, collapsed := throughputCollapse(m.samples); collapsed {
+		warnings = append(warnings, fmt.Sprintf("write throughput collapsed from %.1f MB/s to %.1f MB/s over the run; this is typical of counterfeit or worn-out SD cards", early/(1024*1024), recent/(1024*1024)))
+	}
+	if early, recent, collapsed := throughputCollapse(m.bufferedSamples); collapsed {
+		warnings = append(warnings, fmt.Sprintf("write throughput collapsed from %.1f MB/s to %.1f MB/s over the run, as timed before the writes were flushed (pass '--flush' to time them reaching the media); this can be the operating system's write cache filling, but is also typical of counterfeit or worn-out SD cards", early/(1024*1024), recent/(1024*1024)))
+	}
+
+	if m.ioErrors >= healthIOErrorThreshold {
+		warnings = append(warnings, fmt.Sprintf("%d I/O errors occurred while writing; the destination media may be failing", m.ioErrors))
+	}
+
+	spots := make([]ioErrorSpot, 0)
+	for spot, count := range m.ioErrorSpots {
+		if count > 1 {
+			spots = append(spots, spot)
+		}
+	}
+	sort.Slice(spots, func(i, j int) bool {
+		if spots[i].path != spots[j].path {
+			return spots[i].path < spots[j].path
+		}
+		return spots[i].offset < spots[j].offset
+	})
+	for _, spot := range spots {
+		warnings = append(warnings, fmt.Sprintf("I/O errors repeated %d times at byte offset %d of %s; repeated failures at the same position suggest bad or fake capacity on the card", m.ioErrorSpots[spot], spot.offset, spot.path))
+	}
+
+	if m.stalls > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d file copies stalled with no write progress; the destination media may be failing", m.stalls))
+	}
+
+	return warnings
+}
+
+// the median throughput of the first and last few samples, and whether it fell far enough between them to count
+// as a collapse
+func throughputCollapse(samples []throughputSample) (float64, float64, bool) {
+	if len(samples) < healthWindow*2 {
+		return 0, 0, false
+	}
+	early := medianThroughput(samples[:healthWindow])
+	recent := medianThroughput(samples[len(samples)-healthWindow:])
+	return early, recent, early > 0 && recent < early*healthCollapseRatio
+}
+
+func medianThroughput(samples []throughputSample) float64 {
+	rates := make([]float64, len(samples))
+	for i, s := range samples {
+		rates[i] = s.bytesPerSecond()
+	}
+	sort.Float64s(rates)
+	return rates[len(rates)/2]
+}
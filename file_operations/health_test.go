@@ -0,0 +1,75 @@
+package file_operations
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitorThroughputCollapse(t *testing.T) {
+	tests := []struct {
+		name         string
+		earlyElapsed time.Duration
+		lateElapsed  time.Duration
+		flushed      bool
+		wantWarnings int
+	}{
+		{"steady throughput", 100 * time.Millisecond, 120 * time.Millisecond, true, 0},
+		{"collapsed throughput", 100 * time.Millisecond, 5 * time.Second, true, 1},
+		{"collapsed buffered throughput", 100 * time.Millisecond, 5 * time.Second, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := NewHealthMonitor()
+			for i := 0; i < healthWindow; i++ {
+				monitor.RecordTransfer(10*1024*1024, tt.earlyElapsed, tt.flushed)
+			}
+			// small files are ignored and shouldn't affect the result
+			monitor.RecordTransfer(1024, time.Hour, tt.flushed)
+			for i := 0; i < healthWindow; i++ {
+				monitor.RecordTransfer(10*1024*1024, tt.lateElapsed, tt.flushed)
+			}
+
+			warnings := monitor.Warnings()
+			if len(warnings) != tt.wantWarnings {
+				t.Fatalf("Warnings() = %v, want %d warning(s)", warnings, tt.wantWarnings)
+			}
+			if len(warnings) > 0 && strings.Contains(warnings[0], "--flush") == tt.flushed {
+				t.Errorf("Warnings()[0] = %q, should mention '--flush' only for unflushed copies", warnings[0])
+			}
+		})
+	}
+}
+
+func TestHealthMonitorErrors(t *testing.T) {
+	monitor := NewHealthMonitor()
+
+	monitor.RecordError("SFC/a.sfc", 4096, fmt.Errorf("failed to copy: %w", syscall.EIO))
+	monitor.RecordError("SFC/a.sfc", 4096, fmt.Errorf("failed to copy: %w", syscall.EIO))
+	monitor.RecordError("SFC/a.sfc", 8192, fmt.Errorf("failed to copy: %w", syscall.EIO))
+	// the same offset in another file is somewhere else on the card
+	monitor.RecordError("SFC/b.sfc", 8192, fmt.Errorf("failed to copy: %w", syscall.EIO))
+	monitor.RecordError("SFC/a.sfc", 0, fmt.Errorf("failed to copy: %w", ErrStalled))
+	monitor.RecordError("SFC/a.sfc", 0, fmt.Errorf("permission denied"))
+
+	// total I/O errors, the repeated offset in a.sfc, and stalls
+	warnings := monitor.Warnings()
+	if len(warnings) != 3 {
+		t.Fatalf("Warnings() = %v, want 3 warnings", warnings)
+	}
+	if !strings.Contains(warnings[1], "offset 4096 of SFC/a.sfc") {
+		t.Errorf("Warnings()[1] = %q, want the repeated offset in SFC/a.sfc", warnings[1])
+	}
+}
+
+func TestHealthMonitorNil(t *testing.T) {
+	var monitor *HealthMonitor
+	monitor.RecordTransfer(10*1024*1024, time.Second, true)
+	monitor.RecordError("a.sfc", 0, syscall.EIO)
+	if warnings := monitor.Warnings(); len(warnings) != 0 {
+		t.Errorf("nil monitor returned warnings: %v", warnings)
+	}
+}
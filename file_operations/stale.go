@@ -0,0 +1,134 @@
+package file_operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/disk_info"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// StaleAfter is how long something a run leaves on the target must go unmodified before it's taken to be from
+// an interrupted run, rather than from one still copying to the same target
+const StaleAfter = 15 * time.Minute
+
+// what a leftover was for
+const (
+	ArtifactPartialFile  = "partial file"
+	ArtifactStaging      = "staging folder"
+	ArtifactSwap         = "old contents being swapped out"
+	ArtifactCapacityTest = "capacity test file"
+)
+
+// Artifact is something a run writes to the target only while it's running, found still there
+type Artifact struct {
+	Path string
+	// one of the Artifact* kinds
+	Kind string
+	// everything in it, for folders
+	Size int64
+	// when it or anything in it was last written
+	ModTime time.Time
+}
+
+// Stale reports whether the artifact has gone unmodified long enough at now to be from an interrupted run
+func (a Artifact) Stale(now time.Time) bool {
+	return now.Sub(a.ModTime) >= StaleAfter
+}
+
+// FindArtifacts looks for what runs leave on the target while they're running: partial files anywhere within
+// destPaths, the staging and swap folders beside them, and partial and capacity test files at the top of
+// targetDir. Found while no run is copying, they're what interrupted runs left behind, taking up space.
+func FindArtifacts(targetDir string, destPaths []string) ([]Artifact, error) {
+	found := make(map[string]Artifact)
+	add := func(artifactPath string, kind string) error {
+		if _, ok := found[artifactPath]; ok {
+			return nil
+		}
+		artifact := Artifact{Path: artifactPath, Kind: kind}
+		err := fsys.Walk(targetFS, artifactPath, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				artifact.Size += info.Size()
+			}
+			if info.ModTime().After(artifact.ModTime) {
+				artifact.ModTime = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", artifactPath, err)
+		}
+		found[artifactPath] = artifact
+		return nil
+	}
+
+	entries, err := targetFS.ReadDir(targetDir)
+	if os.IsNotExist(err) {
+		// the copy creates the target
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target %s: %w", targetDir, err)
+	}
+	for _, entry := range entries {
+		entryPath := filepath.Join(targetDir, entry.Name())
+		if strings.HasPrefix(entry.Name(), disk_info.CapacityTestFilePrefix) && !entry.IsDir() {
+			err = add(entryPath, ArtifactCapacityTest)
+		} else if fsys.IsTempPath(entry.Name()) && !entry.IsDir() {
+			err = add(entryPath, ArtifactPartialFile)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, destPath := range destPaths {
+		for artifactPath, kind := range map[string]string{StagingPath(destPath): ArtifactStaging, swapOldPath(destPath): ArtifactSwap} {
+			if _, err := targetFS.Stat(artifactPath); err == nil {
+				if err := add(artifactPath, kind); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if _, err := targetFS.Stat(destPath); os.IsNotExist(err) {
+			continue
+		}
+		err := fsys.Walk(targetFS, destPath, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && fsys.IsTempPath(filePath) {
+				return add(filePath, ArtifactPartialFile)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", destPath, err)
+		}
+	}
+
+	artifacts := make([]Artifact, 0, len(found))
+	for _, artifact := range found {
+		artifacts = append(artifacts, artifact)
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		return artifacts[i].Path < artifacts[j].Path
+	})
+	return artifacts, nil
+}
+
+// RemoveArtifact deletes an artifact and everything in it
+func RemoveArtifact(artifact Artifact) error {
+	if err := targetFS.RemoveAll(artifact.Path); err != nil {
+		return fmt.Errorf("failed to remove %s %s: %w", artifact.Kind, artifact.Path, err)
+	}
+	return nil
+}
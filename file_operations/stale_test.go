@@ -0,0 +1,62 @@
+package file_operations
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFindArtifacts(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"Roms/SFC/Zelda.sfc":                             "rom",
+		"Roms/SFC/.Zelda.sfc.0a1b2c3d.romcopy-part":      "half a rom",
+		"Roms/SFC/Imgs/.Zelda.png.0a1b2c3d.romcopy-part": "half an image",
+		"Roms/.SFC.romcopy-staging/Zelda.sfc":            "staged rom",
+		"Roms/.GBA.romcopy-old/Metroid.gba":              "old rom",
+		".romcopy-capacity-test-0000.bin":                "pattern",
+		".romcopyengine-id.0a1b2c3d.romcopy-part":        "{",
+		"Roms/.NES.romcopy-staging/Mario.nes":            "another mapping's",
+		"Roms/SFC/Zelda.romcopy-part":                    "not hidden, so not ours",
+	})
+	defer cleanup()
+
+	destPaths := []string{filepath.Join(tmpDir, "Roms", "SFC"), filepath.Join(tmpDir, "Roms", "GBA")}
+	artifacts, err := FindArtifacts(tmpDir, destPaths)
+	if err != nil {
+		t.Fatalf("FindArtifacts() error = %v", err)
+	}
+
+	want := map[string]string{
+		".romcopy-capacity-test-0000.bin":                ArtifactCapacityTest,
+		".romcopyengine-id.0a1b2c3d.romcopy-part":        ArtifactPartialFile,
+		"Roms/.GBA.romcopy-old":                          ArtifactSwap,
+		"Roms/.SFC.romcopy-staging":                      ArtifactStaging,
+		"Roms/SFC/.Zelda.sfc.0a1b2c3d.romcopy-part":      ArtifactPartialFile,
+		"Roms/SFC/Imgs/.Zelda.png.0a1b2c3d.romcopy-part": ArtifactPartialFile,
+	}
+	if len(artifacts) != len(want) {
+		t.Fatalf("FindArtifacts() = %+v, want %d artifacts", artifacts, len(want))
+	}
+	for _, artifact := range artifacts {
+		relPath, _ := filepath.Rel(tmpDir, artifact.Path)
+		if kind, ok := want[filepath.ToSlash(relPath)]; !ok || kind != artifact.Kind {
+			t.Errorf("FindArtifacts() found %s as a %s, want %q", relPath, artifact.Kind, kind)
+		}
+		if relPath == filepath.Join("Roms", ".SFC.romcopy-staging") && artifact.Size != int64(len("staged rom")) {
+			t.Errorf("staging folder size = %d, want the size of everything in it", artifact.Size)
+		}
+		if artifact.Stale(time.Now()) || !artifact.Stale(time.Now().Add(StaleAfter)) {
+			t.Errorf("%s was just written, so shouldn't be stale until %s from now", relPath, StaleAfter)
+		}
+	}
+
+	for _, artifact := range artifacts {
+		if err := RemoveArtifact(artifact); err != nil {
+			t.Fatalf("RemoveArtifact() error = %v", err)
+		}
+	}
+	if remaining, err := FindArtifacts(tmpDir, destPaths); err != nil || len(remaining) != 0 {
+		t.Errorf("FindArtifacts() after removing them all = %+v, %v; want none", remaining, err)
+	}
+	verifyFileContent(t, filepath.Join(tmpDir, "Roms", "SFC", "Zelda.sfc"), "rom")
+}
@@ -0,0 +1,108 @@
+package file_operations
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// ErrStalled is returned when a copy makes no progress for longer than the configured stall timeout
+var ErrStalled = errors.New("no write progress within the stall timeout; the destination media may be failing")
+
+// how often the stall watchdog checks for progress, relative to the timeout
+const stallChecksPerTimeout = 10
+
+// stallClock times how long a copy has gone without progress. It's held while the copy waits on the bandwidth
+// limit, which is a deliberate pause rather than a stall, however low the limit.
+type stallClock struct {
+	lastProgress atomic.Int64
+	waiting      atomic.Int32
+}
+
+func newStallClock() *stallClock {
+	clock := &stallClock{}
+	clock.progressed()
+	return clock
+}
+
+// records progress now
+func (c *stallClock) progressed() {
+	c.lastProgress.Store(time.Now().UnixNano())
+}
+
+// stops the clock until resume is called
+func (c *stallClock) pause() {
+	if c != nil {
+		c.waiting.Add(1)
+	}
+}
+
+// restarts the clock from now
+func (c *stallClock) resume() {
+	if c != nil {
+		c.progressed()
+		c.waiting.Add(-1)
+	}
+}
+
+// how long it's been since the copy last made progress, not counting any wait still going on
+func (c *stallClock) stalledFor() time.Duration {
+	if c.waiting.Load() > 0 {
+		return 0
+	}
+	return time.Since(time.Unix(0, c.lastProgress.Load()))
+}
+
+// records the time of the last successful write and how much has been written
+type progressWriter struct {
+	writer  io.Writer
+	clock   *stallClock
+	written *atomic.Int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if n > 0 {
+		w.clock.progressed()
+		w.written.Add(int64(n))
+	}
+	return n, err
+}
+
+// copyStream copies src to dst, giving up with ErrStalled if no bytes are written for stallTimeout, and
+// returns the number of bytes written. clock is the one src pauses while it waits on the bandwidth limit, or nil.
+// On a stall, abort is called to unblock the stuck read/write (typically by closing the files); the copy goroutine
+// is abandoned if the underlying syscall can't be interrupted.
+func copyStream(dst io.Writer, src io.Reader, stallTimeout time.Duration, clock *stallClock, abort func()) (int64, error) {
+	if stallTimeout <= 0 {
+		return io.Copy(dst, src)
+	}
+
+	if clock == nil {
+		clock = newStallClock()
+	}
+	clock.progressed()
+	written := &atomic.Int64{}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(&progressWriter{writer: dst, clock: clock, written: written}, src)
+		done <- err
+	}()
+
+	ticker := time.NewTicker(stallTimeout / stallChecksPerTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case err := <-done:
+			return written.Load(), err
+		case <-ticker.C:
+			if clock.stalledFor() > stallTimeout {
+				abort()
+				return written.Load(), ErrStalled
+			}
+		}
+	}
+}
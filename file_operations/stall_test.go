@@ -0,0 +1,78 @@
+package file_operations
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// returns some data, then blocks until unblocked, like a write to a dying SD card
+type stallingReader struct {
+	data    io.Reader
+	unblock chan struct{}
+}
+
+func (r *stallingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if err == io.EOF {
+		<-r.unblock
+	}
+	return n, err
+}
+
+func TestCopyStream(t *testing.T) {
+	t.Run("completes without timeout", func(t *testing.T) {
+		var dst bytes.Buffer
+		n, err := copyStream(&dst, strings.NewReader("rom data"), 0, nil, func() {})
+		if err != nil {
+			t.Fatalf("copyStream() error = %v", err)
+		}
+		if n != 8 {
+			t.Errorf("copyStream() = %d bytes, want 8", n)
+		}
+		if dst.String() != "rom data" {
+			t.Errorf("copyStream() wrote %q, want %q", dst.String(), "rom data")
+		}
+	})
+
+	t.Run("completes within timeout", func(t *testing.T) {
+		var dst bytes.Buffer
+		n, err := copyStream(&dst, strings.NewReader("rom data"), time.Second, nil, func() {})
+		if err != nil {
+			t.Fatalf("copyStream() error = %v", err)
+		}
+		if n != 8 {
+			t.Errorf("copyStream() = %d bytes, want 8", n)
+		}
+		if dst.String() != "rom data" {
+			t.Errorf("copyStream() wrote %q, want %q", dst.String(), "rom data")
+		}
+	})
+
+	t.Run("detects stall", func(t *testing.T) {
+		src := &stallingReader{data: strings.NewReader("partial"), unblock: make(chan struct{})}
+		aborted := false
+		abort := func() {
+			aborted = true
+			close(src.unblock)
+		}
+
+		start := time.Now()
+		n, err := copyStream(io.Discard, src, 100*time.Millisecond, nil, abort)
+		if !errors.Is(err, ErrStalled) {
+			t.Fatalf("copyStream() error = %v, want ErrStalled", err)
+		}
+		if n != int64(len("partial")) {
+			t.Errorf("copyStream() = %d bytes before stall, want %d", n, len("partial"))
+		}
+		if !aborted {
+			t.Error("expected abort to be called on stall")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("stall took %v to detect", elapsed)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+//go:build (!unix && !windows) || aix
+
+package file_operations
+
+import (
+	"fmt"
+	"os"
+)
+
+// SyncTree makes sure everything written beneath path has reached the disk. Other platforms, and AIX, have no
+// way to fsync directories or flush the OS write cache, so this relies on each file having been flushed as it was
+// written (StreamOptions.Flush) and only confirms path is still reachable.
+func SyncTree(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to access %s for sync: %w", path, err)
+	}
+	return nil
+}
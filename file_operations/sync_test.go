@@ -0,0 +1,35 @@
+package file_operations
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncTree(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"SFC/game.sfc":        "rom",
+		"SFC/images/game.png": "image",
+	})
+	defer cleanup()
+
+	if err := SyncTree(filepath.Join(tmpDir, "SFC")); err != nil {
+		t.Errorf("SyncTree() error = %v", err)
+	}
+
+	if err := SyncTree(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("SyncTree() expected error for missing path")
+	}
+}
+
+func TestCopyFileWithOptionsFlush(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"source.sfc": "rom data",
+	})
+	defer cleanup()
+
+	dst := filepath.Join(tmpDir, "dest.sfc")
+	if err := CopyFileWithOptions(filepath.Join(tmpDir, "source.sfc"), dst, StreamOptions{Flush: true}); err != nil {
+		t.Fatalf("CopyFileWithOptions() error = %v", err)
+	}
+	verifyFileContent(t, dst, "rom data")
+}
@@ -0,0 +1,42 @@
+//go:build unix && !aix
+
+package file_operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// SyncTree makes sure everything written beneath path has reached the disk: every directory is fsynced so
+// newly created entries are durable, then the OS write cache is flushed.
+func SyncTree(path string) error {
+	err := filepath.Walk(path, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %s: %w", walkPath, err)
+		}
+
+		if !info.IsDir() {
+			return nil
+		}
+
+		dir, err := os.Open(walkPath)
+		if err != nil {
+			return fmt.Errorf("failed to open directory %s for sync: %w", walkPath, err)
+		}
+		defer dir.Close()
+
+		if err := dir.Sync(); err != nil {
+			return fmt.Errorf("failed to sync directory %s: %w", walkPath, err)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	syscall.Sync()
+	return nil
+}
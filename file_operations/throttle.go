@@ -0,0 +1,63 @@
+package file_operations
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// largest chunk read before the limiter is consulted, keeping throttled output smooth
+const throttleChunkSize = 64 * 1024
+
+// RateLimiter caps combined throughput across every copy that shares it
+type RateLimiter struct {
+	mu             sync.Mutex
+	bytesPerSecond float64
+	// the moment at which the bytes handed out so far will have been "paid for"
+	next time.Time
+}
+
+// NewRateLimiter returns a limiter allowing bytesPerSecond, or nil (no limit) if bytesPerSecond <= 0
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{bytesPerSecond: float64(bytesPerSecond)}
+}
+
+// Wait blocks until n more bytes may be transferred
+func (l *RateLimiter) Wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.bytesPerSecond * float64(time.Second)))
+	delay := l.next.Sub(now)
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+type throttledReader struct {
+	reader  io.Reader
+	limiter *RateLimiter
+	// the copy's stall clock, held while waiting on the limiter; nil if stalls aren't watched for
+	clock *stallClock
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > throttleChunkSize {
+		p = p[:throttleChunkSize]
+	}
+
+	n, err := r.reader.Read(p)
+	r.clock.pause()
+	r.limiter.Wait(n)
+	r.clock.resume()
+	return n, err
+}
@@ -0,0 +1,68 @@
+package file_operations
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiterUnlimited(t *testing.T) {
+	if NewRateLimiter(0) != nil {
+		t.Error("NewRateLimiter(0) should return nil for no limit")
+	}
+
+	// a nil limiter must be safe to use
+	var limiter *RateLimiter
+	limiter.Wait(1024 * 1024)
+}
+
+func TestCopyFileWithOptionsThrottled(t *testing.T) {
+	tmpDir, cleanup := testSetup(t)
+	defer cleanup()
+
+	content := bytes.Repeat([]byte("x"), 300*1024)
+	src := filepath.Join(tmpDir, "source.bin")
+	dst := filepath.Join(tmpDir, "dest.bin")
+	if err := createTestFile(src, string(content)); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	start := time.Now()
+	opts := StreamOptions{Limiter: NewRateLimiter(1024 * 1024)}
+	if err := CopyFileWithOptions(src, dst, opts); err != nil {
+		t.Fatalf("CopyFileWithOptions() error = %v", err)
+	}
+	elapsed := time.Since(start)
+
+	// 300KB at 1MB/s should take roughly 0.3s
+	if elapsed < 250*time.Millisecond {
+		t.Errorf("throttled copy took %v, expected at least 250ms", elapsed)
+	}
+
+	verifyFileContent(t, dst, string(content))
+}
+
+func TestCopyFileWithOptionsThrottledNotStalled(t *testing.T) {
+	tmpDir, cleanup := testSetup(t)
+	defer cleanup()
+
+	// a whole chunk takes a second at this limit, far longer than the stall timeout
+	content := bytes.Repeat([]byte("x"), 96*1024)
+	src := filepath.Join(tmpDir, "source.bin")
+	dst := filepath.Join(tmpDir, "dest.bin")
+	if err := createTestFile(src, string(content)); err != nil {
+		t.Fatalf("Failed to create source file: %v", err)
+	}
+
+	health := NewHealthMonitor()
+	opts := StreamOptions{Limiter: NewRateLimiter(64 * 1024), StallTimeout: 300 * time.Millisecond, Health: health}
+	if err := CopyFileWithOptions(src, dst, opts); err != nil {
+		t.Fatalf("CopyFileWithOptions() error = %v; waiting on the bandwidth limit isn't a stall", err)
+	}
+	if warnings := health.Warnings(); len(warnings) > 0 {
+		t.Errorf("throttled copy recorded media health warnings %v", warnings)
+	}
+
+	verifyFileContent(t, dst, string(content))
+}
@@ -0,0 +1,145 @@
+package file_operations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// TrashDirName is the folder at the top of the target that files are moved to instead of being deleted outright
+const TrashDirName = ".romcopy_trash"
+
+// TrashBatchLayout names the folders in the trash: each run that moves anything there gets one, named for when
+// it started in UTC, holding what it moved at the same paths it had on the target, and any record of what the
+// run did, so a batch is kept or pruned as a whole
+const TrashBatchLayout = "20060102T150405Z"
+
+// JournalBackupName is the folder in a batch that a run writing a '--journal' moves what it replaces or removes
+// into, so 'undo' can put it back
+const JournalBackupName = ".journal"
+
+// Trash is where a run moves what it removes from a folder on the target rather than deleting it: into Batch in
+// the trash of RootDir, the top of the target, at RelDir, the folder's path relative to RootDir. The folder may be
+// a staging folder standing in for the one at RelDir.
+type Trash struct {
+	RootDir string
+	Batch   string
+	RelDir  string
+}
+
+// moves the file or folder at entryPath, within dir, the folder the trash is for, into the trash
+func (t Trash) move(dir string, entryPath string) error {
+	relPath, err := filepath.Rel(dir, entryPath)
+	if err != nil {
+		return err
+	}
+	return moveToTrash(entryPath, t.RootDir, t.Batch, filepath.Join(t.RelDir, relPath))
+}
+
+// TrashBatch is a folder in the trash
+type TrashBatch struct {
+	Path string
+	// from the folder's name, or if it isn't named like a batch, when it was last modified
+	Created time.Time
+	Files   int
+	Size    int64
+}
+
+// TrashPath returns where the trash lives on the target
+func TrashPath(targetDir string) string {
+	return filepath.Join(targetDir, TrashDirName)
+}
+
+// ListTrash returns every batch in the target's trash, oldest first. A target with no trash has no batches.
+func ListTrash(targetDir string) ([]TrashBatch, error) {
+	trashPath := TrashPath(targetDir)
+	entries, err := targetFS.ReadDir(trashPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash %s: %w", trashPath, err)
+	}
+
+	batches := make([]TrashBatch, 0, len(entries))
+	for _, entry := range entries {
+		batch := TrashBatch{Path: filepath.Join(trashPath, entry.Name())}
+		created, nameErr := time.Parse(TrashBatchLayout, entry.Name())
+		err := fsys.Walk(targetFS, batch.Path, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				batch.Files++
+				batch.Size += info.Size()
+			}
+			if nameErr != nil && info.ModTime().After(created) {
+				created = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", batch.Path, err)
+		}
+		batch.Created = created
+		batches = append(batches, batch)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		if !batches[i].Created.Equal(batches[j].Created) {
+			return batches[i].Created.Before(batches[j].Created)
+		}
+		return batches[i].Path < batches[j].Path
+	})
+	return batches, nil
+}
+
+// ExpiredTrash returns the batches, as returned by ListTrash, that are older than retain at now
+func ExpiredTrash(batches []TrashBatch, retain time.Duration, now time.Time) []TrashBatch {
+	expired := make([]TrashBatch, 0)
+	for _, batch := range batches {
+		if now.Sub(batch.Created) > retain {
+			expired = append(expired, batch)
+		}
+	}
+	return expired
+}
+
+// RemoveTrashBatch permanently deletes a batch and everything in it
+func RemoveTrashBatch(batch TrashBatch) error {
+	if err := targetFS.RemoveAll(batch.Path); err != nil {
+		return fmt.Errorf("failed to remove trash %s: %w", batch.Path, err)
+	}
+	return nil
+}
+
+// NewTrashBatch returns the name of the batch a run started at startedAt moves what it replaces or removes into
+func NewTrashBatch(startedAt time.Time) string {
+	return startedAt.UTC().Format(TrashBatchLayout)
+}
+
+// MoveToTrash moves the file or folder at relPath within rootDir into batch in rootDir's trash, at the same path,
+// so it can be recovered until the batch is pruned
+func MoveToTrash(rootDir string, batch string, relPath string) error {
+	return moveToTrash(filepath.Join(rootDir, relPath), rootDir, batch, relPath)
+}
+
+// moves the file or folder at fromPath into batch in rootDir's trash, at relPath
+func moveToTrash(fromPath string, rootDir string, batch string, relPath string) error {
+	trashedPath := filepath.Join(TrashPath(rootDir), batch, relPath)
+	if err := targetFS.MkdirAll(filepath.Dir(trashedPath), 0755); err != nil {
+		return fmt.Errorf("failed to create trash folder %s: %w", filepath.Dir(trashedPath), err)
+	}
+	// a second replacement of the same path in one run keeps the first, which is what was there before the run
+	if _, err := targetFS.Stat(trashedPath); err == nil {
+		return targetFS.RemoveAll(fromPath)
+	}
+	if err := targetFS.Rename(fromPath, trashedPath); err != nil {
+		return fmt.Errorf("failed to move %s to the trash: %w", relPath, err)
+	}
+	return nil
+}
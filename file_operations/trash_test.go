@@ -0,0 +1,157 @@
+package file_operations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrashRetention(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		".romcopy_trash/20260901T080000Z/Roms/SFC/Zelda.sfc":   "old rom",
+		".romcopy_trash/20260901T080000Z/Roms/SFC/Mario.sfc":   "old",
+		".romcopy_trash/20261010T080000Z/Roms/GBA/Metroid.gba": "newer rom",
+		".romcopy_trash/copied by hand/Tetris.gb":              "tetris",
+		"Roms/SFC/Zelda.sfc":                                   "rom",
+	})
+	defer cleanup()
+
+	handCopied := filepath.Join(tmpDir, TrashDirName, "copied by hand")
+	handTime := time.Date(2026, 9, 20, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(handCopied, "Tetris.gb"), handTime, handTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+	if err := os.Chtimes(handCopied, handTime, handTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+
+	batches, err := ListTrash(tmpDir)
+	if err != nil {
+		t.Fatalf("ListTrash() error = %v", err)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("ListTrash() = %+v, want 3 batches", batches)
+	}
+	if filepath.Base(batches[0].Path) != "20260901T080000Z" || batches[0].Files != 2 || batches[0].Size != int64(len("old rom")+len("old")) {
+		t.Errorf("ListTrash()[0] = %+v, want the oldest batch, with 2 files", batches[0])
+	}
+	if batches[1].Path != handCopied || !batches[1].Created.Equal(handTime) {
+		t.Errorf("ListTrash()[1] = %+v, want the folder not named like a batch, dated by its contents", batches[1])
+	}
+
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	expired := ExpiredTrash(batches, 20*24*time.Hour, now)
+	if len(expired) != 2 || expired[0].Path != batches[0].Path || expired[1].Path != handCopied {
+		t.Fatalf("ExpiredTrash() = %+v, want the 2 batches older than 20 days", expired)
+	}
+	for _, batch := range expired {
+		if err := RemoveTrashBatch(batch); err != nil {
+			t.Fatalf("RemoveTrashBatch() error = %v", err)
+		}
+	}
+
+	remaining, err := ListTrash(tmpDir)
+	if err != nil || len(remaining) != 1 || filepath.Base(remaining[0].Path) != "20261010T080000Z" {
+		t.Errorf("ListTrash() after pruning = %+v, %v; want only the newest batch", remaining, err)
+	}
+	verifyFileContent(t, filepath.Join(tmpDir, "Roms", "SFC", "Zelda.sfc"), "rom")
+
+	if batches, err := ListTrash(filepath.Join(tmpDir, "Roms")); err != nil || len(batches) != 0 {
+		t.Errorf("ListTrash() without a trash = %+v, %v; want no batches", batches, err)
+	}
+}
+
+func TestMoveToTrash(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"Saves/Zelda.srm": "old save",
+	})
+	defer cleanup()
+
+	batch := NewTrashBatch(time.Date(2026, 10, 16, 8, 30, 0, 0, time.FixedZone("PDT", -7*60*60)))
+	if batch != "20261016T153000Z" {
+		t.Errorf("NewTrashBatch() = %q, want the time in UTC", batch)
+	}
+	if err := MoveToTrash(tmpDir, batch, filepath.Join("Saves", "Zelda.srm")); err != nil {
+		t.Fatalf("MoveToTrash() error = %v", err)
+	}
+	verifyFileContent(t, filepath.Join(tmpDir, TrashDirName, batch, "Saves", "Zelda.srm"), "old save")
+	if _, err := os.Stat(filepath.Join(tmpDir, "Saves", "Zelda.srm")); !os.IsNotExist(err) {
+		t.Errorf("MoveToTrash() left the file in place: %v", err)
+	}
+
+	// what was there first is kept
+	if err := os.WriteFile(filepath.Join(tmpDir, "Saves", "Zelda.srm"), []byte("newer save"), 0644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := MoveToTrash(tmpDir, batch, filepath.Join("Saves", "Zelda.srm")); err != nil {
+		t.Fatalf("MoveToTrash() error = %v", err)
+	}
+	verifyFileContent(t, filepath.Join(tmpDir, TrashDirName, batch, "Saves", "Zelda.srm"), "old save")
+}
+
+func TestTrashDirectory(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"Roms/SFC/Zelda.sfc":        "rom",
+		"Roms/SFC/Zelda.srm":        "save",
+		"Roms/SFC/images/Zelda.png": "art",
+		"Roms/SFC/junk.txt":         "junk",
+	})
+	defer cleanup()
+	destPath := filepath.Join(tmpDir, "Roms", "SFC")
+
+	kept, err := TrashDirectory(destPath, []string{"**/*.srm"}, Trash{RootDir: tmpDir, Batch: "20261016T080000Z", RelDir: filepath.Join("Roms", "SFC")})
+	if err != nil {
+		t.Fatalf("TrashDirectory() error = %v", err)
+	}
+	if len(kept) != 1 || kept[0] != "Zelda.srm" {
+		t.Errorf("TrashDirectory() kept %v, want [Zelda.srm]", kept)
+	}
+	verifyFileContent(t, filepath.Join(destPath, "Zelda.srm"), "save")
+	for _, relPath := range []string{"Zelda.sfc", "images", "junk.txt"} {
+		if verifyFileExists(t, filepath.Join(destPath, relPath)) {
+			t.Errorf("%s should have been moved to the trash", relPath)
+		}
+	}
+	trashed := filepath.Join(tmpDir, TrashDirName, "20261016T080000Z", "Roms", "SFC")
+	verifyFileContent(t, filepath.Join(trashed, "Zelda.sfc"), "rom")
+	verifyFileContent(t, filepath.Join(trashed, "images", "Zelda.png"), "art")
+
+	if err := createTestDir(destPath, map[string]string{"Thumbs.db": "junk", "images/Thumbs.db": "junk"}); err != nil {
+		t.Fatalf("failed to create test files: %v", err)
+	}
+	if err := TrashFiles(destPath, []string{"Thumbs.db", "images/Thumbs.db"}, Trash{RootDir: tmpDir, Batch: "20261016T090000Z", RelDir: filepath.Join("Roms", "SFC")}); err != nil {
+		t.Fatalf("TrashFiles() error = %v", err)
+	}
+	if verifyFileExists(t, filepath.Join(destPath, "images")) {
+		t.Error("images should have been removed once it was emptied")
+	}
+	verifyFileContent(t, filepath.Join(tmpDir, TrashDirName, "20261016T090000Z", "Roms", "SFC", "images", "Thumbs.db"), "junk")
+}
+
+func TestFinishSwapToTrash(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"Roms/SFC/Old.sfc": "old",
+	})
+	defer cleanup()
+	destPath := filepath.Join(tmpDir, "Roms", "SFC")
+
+	stagingPath, err := PrepareStaging(destPath, false, nil)
+	if err != nil {
+		t.Fatalf("PrepareStaging() error = %v", err)
+	}
+	if err := createTestFile(filepath.Join(stagingPath, "New.sfc"), "new"); err != nil {
+		t.Fatalf("failed to create staged file: %v", err)
+	}
+	if err := SwapInStaging(destPath); err != nil {
+		t.Fatalf("SwapInStaging() error = %v", err)
+	}
+	if err := FinishSwapToTrash(destPath, Trash{RootDir: tmpDir, Batch: "20261016T080000Z", RelDir: filepath.Join("Roms", "SFC")}); err != nil {
+		t.Fatalf("FinishSwapToTrash() error = %v", err)
+	}
+	verifyFileContent(t, filepath.Join(destPath, "New.sfc"), "new")
+	if verifyFileExists(t, filepath.Join(destPath, "Old.sfc")) || verifyFileExists(t, swapOldPath(destPath)) {
+		t.Error("the old contents should have been moved out of the way")
+	}
+	verifyFileContent(t, filepath.Join(tmpDir, TrashDirName, "20261016T080000Z", "Roms", "SFC", "Old.sfc"), "old")
+}
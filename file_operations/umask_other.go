@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package file_operations
+
+import "os"
+
+// other platforms have no umask to read, so file modes are applied as given
+var processUmask os.FileMode = 0
@@ -0,0 +1,17 @@
+//go:build unix
+
+package file_operations
+
+import (
+	"os"
+	"syscall"
+)
+
+// the process umask, read once at startup since the only way to read it is to briefly change it
+var processUmask = readUmask()
+
+func readUmask() os.FileMode {
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return os.FileMode(mask)
+}
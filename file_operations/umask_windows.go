@@ -0,0 +1,8 @@
+//go:build windows
+
+package file_operations
+
+import "os"
+
+// Windows has no umask; only the read-only attribute is affected by file modes there
+var processUmask os.FileMode = 0
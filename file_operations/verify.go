@@ -0,0 +1,105 @@
+package file_operations
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+)
+
+// VerifyFailure is a copied file whose destination didn't read back identical to the source
+type VerifyFailure struct {
+	Path   string
+	Reason string
+}
+
+// VerifyReport collects the results of verify-after-write for a set of copies. It is safe for concurrent use.
+type VerifyReport struct {
+	mu        sync.Mutex
+	algorithm string
+	verified  int
+	failures  []VerifyFailure
+}
+
+// NewVerifyReport creates a report that checks files with the given algorithm, one of ChecksumAlgorithms
+func NewVerifyReport(algorithm string) (*VerifyReport, error) {
+	if _, err := NewChecksum(algorithm); err != nil {
+		return nil, err
+	}
+	return &VerifyReport{algorithm: algorithm}, nil
+}
+
+// Algorithm returns the checksum algorithm files are verified with
+func (r *VerifyReport) Algorithm() string {
+	if r == nil {
+		return ""
+	}
+	return r.algorithm
+}
+
+func (r *VerifyReport) recordSuccess() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.verified++
+}
+
+func (r *VerifyReport) recordFailure(path string, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, VerifyFailure{Path: path, Reason: reason})
+}
+
+// Verified returns how many files read back correctly
+func (r *VerifyReport) Verified() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.verified
+}
+
+// Failures returns every file that failed verification
+func (r *VerifyReport) Failures() []VerifyFailure {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]VerifyFailure(nil), r.failures...)
+}
+
+// validated by NewVerifyReport, so this can't fail
+func (r *VerifyReport) newHash() hash.Hash {
+	hasher, _ := NewChecksum(r.algorithm)
+	return hasher
+}
+
+// re-reads destPath and compares its checksum to the one computed from the source during the copy
+func (r *VerifyReport) check(destPath string, sourceSum []byte, sourceSize int64) {
+	file, err := targetFS.Open(destPath)
+	if err != nil {
+		r.recordFailure(destPath, fmt.Sprintf("unable to re-open for verification: %v", err))
+		return
+	}
+	defer file.Close()
+
+	hasher := r.newHash()
+	size, err := io.Copy(hasher, file)
+	if err != nil {
+		r.recordFailure(destPath, fmt.Sprintf("unable to re-read for verification: %v", err))
+		return
+	}
+
+	if size != sourceSize {
+		r.recordFailure(destPath, fmt.Sprintf("size mismatch: source is %d bytes, destination is %d bytes", sourceSize, size))
+		return
+	}
+
+	if destSum := hasher.Sum(nil); string(destSum) != string(sourceSum) {
+		r.recordFailure(destPath, fmt.Sprintf("%s mismatch: source %x, destination %x", r.algorithm, sourceSum, destSum))
+		return
+	}
+
+	r.recordSuccess()
+}
@@ -0,0 +1,116 @@
+package file_operations
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/artwork"
+)
+
+func TestCopyFileWithOptionsVerify(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"source.sfc": "rom data",
+	})
+	defer cleanup()
+
+	report, err := NewVerifyReport(ChecksumCRC32)
+	if err != nil {
+		t.Fatalf("NewVerifyReport() error = %v", err)
+	}
+	opts := StreamOptions{Verify: report}
+	if err := CopyFileWithOptions(filepath.Join(tmpDir, "source.sfc"), filepath.Join(tmpDir, "dest.sfc"), opts); err != nil {
+		t.Fatalf("CopyFileWithOptions() error = %v", err)
+	}
+
+	if report.Verified() != 1 {
+		t.Errorf("Verified() = %d, want 1", report.Verified())
+	}
+	if len(report.Failures()) != 0 {
+		t.Errorf("Failures() = %v, want none", report.Failures())
+	}
+}
+
+func TestCopyFileWithOptionsResizeImage(t *testing.T) {
+	var art bytes.Buffer
+	if err := png.Encode(&art, image.NewRGBA(image.Rect(0, 0, 500, 720))); err != nil {
+		t.Fatalf("failed to encode PNG: %v", err)
+	}
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"box.png":    art.String(),
+		"broken.png": "not an image",
+	})
+	defer cleanup()
+
+	report, _ := NewVerifyReport(ChecksumCRC32)
+	recorder, _ := NewChecksumRecorder(ChecksumSHA1)
+	opts := StreamOptions{Verify: report, Checksums: recorder, ResizeImage: artwork.Size{Width: 250, Height: 360}}
+	for _, name := range []string{"box.png", "broken.png"} {
+		if err := CopyFileWithOptions(filepath.Join(tmpDir, name), filepath.Join(tmpDir, "dest-"+name), opts); err != nil {
+			t.Fatalf("CopyFileWithOptions(%s) error = %v", name, err)
+		}
+	}
+
+	data, _ := os.ReadFile(filepath.Join(tmpDir, "dest-box.png"))
+	if config, err := png.DecodeConfig(bytes.NewReader(data)); err != nil || config.Width != 250 || config.Height != 360 {
+		t.Errorf("copied image is %dx%d, %v; want 250x360", config.Width, config.Height, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(tmpDir, "dest-broken.png")); string(data) != "not an image" {
+		t.Errorf("copied non-image = %q, want it copied as it is", data)
+	}
+
+	// what was written is what's verified and recorded, not the source
+	if report.Verified() != 2 || len(report.Failures()) != 0 {
+		t.Errorf("Verified() = %d, Failures() = %v; want both verified", report.Verified(), report.Failures())
+	}
+	hasher := recorder.newHash()
+	hasher.Write(data)
+	if got := recorder.Files()[filepath.Join(tmpDir, "dest-box.png")]; !bytes.Equal(got.Sum, hasher.Sum(nil)) {
+		t.Errorf("recorded checksum %x, want %x", got.Sum, hasher.Sum(nil))
+	}
+}
+
+func TestVerifyReportCheck(t *testing.T) {
+	tmpDir, cleanup := setupTestFolder(t, map[string]string{
+		"good.sfc":      "rom data",
+		"corrupted.sfc": "rom dXta",
+		"truncated.sfc": "rom",
+	})
+	defer cleanup()
+
+	report, err := NewVerifyReport(ChecksumCRC32)
+	if err != nil {
+		t.Fatalf("NewVerifyReport() error = %v", err)
+	}
+
+	hasher := report.newHash()
+	hasher.Write([]byte("rom data"))
+	sourceSum := hasher.Sum(nil)
+
+	report.check(filepath.Join(tmpDir, "good.sfc"), sourceSum, 8)
+	report.check(filepath.Join(tmpDir, "corrupted.sfc"), sourceSum, 8)
+	report.check(filepath.Join(tmpDir, "truncated.sfc"), sourceSum, 8)
+	report.check(filepath.Join(tmpDir, "missing.sfc"), sourceSum, 8)
+
+	if report.Verified() != 1 {
+		t.Errorf("Verified() = %d, want 1", report.Verified())
+	}
+
+	failures := report.Failures()
+	if len(failures) != 3 {
+		t.Fatalf("Failures() returned %d failures, want 3: %v", len(failures), failures)
+	}
+	if failures[0].Path != filepath.Join(tmpDir, "corrupted.sfc") {
+		t.Errorf("first failure = %q, want corrupted.sfc", failures[0].Path)
+	}
+}
+
+func TestVerifyReportNil(t *testing.T) {
+	var report *VerifyReport
+	if report.Verified() != 0 || len(report.Failures()) != 0 {
+		t.Error("nil report should be empty")
+	}
+}
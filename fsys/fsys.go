@@ -0,0 +1,212 @@
+package fsys
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrReadOnly is returned (wrapped in an *os.PathError) for any write to a read-only filesystem
+var ErrReadOnly = errors.New("filesystem is read-only")
+
+// File is an open file on an FS
+type File interface {
+	io.Reader
+	// reads at an offset, e.g. for archives whose directory is at the end
+	io.ReaderAt
+	io.Writer
+	io.Closer
+	// flushes written data to stable storage
+	Sync() error
+}
+
+// FS is the set of filesystem operations the engine performs. Everything that touches the target goes through
+// an FS, so the engine can be pointed at something other than the real disk: a read-only view for dry runs,
+// or an in-memory filesystem for tests.
+type FS interface {
+	Open(name string) (File, error)
+	// creates or truncates name for writing
+	Create(name string) (File, error)
+	// opens name for writing at its end, creating it if needed
+	Append(name string) (File, error)
+	Stat(name string) (os.FileInfo, error)
+	// returns the entries of a directory sorted by name
+	ReadDir(name string) ([]os.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+	Remove(name string) error
+	RemoveAll(path string) error
+	Rename(oldPath string, newPath string) error
+	Chmod(name string, mode os.FileMode) error
+	Chtimes(name string, atime time.Time, mtime time.Time) error
+}
+
+// OS is the real filesystem
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Open(name string) (File, error)   { return os.Open(name) }
+func (osFS) Create(name string) (File, error) { return os.Create(name) }
+func (osFS) Append(name string) (File, error) {
+	return os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+func (osFS) Stat(name string) (os.FileInfo, error)      { return os.Stat(name) }
+func (osFS) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return os.ReadFile(name) }
+func (osFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+func (osFS) MkdirAll(path string, perm os.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) Remove(name string) error                     { return os.Remove(name) }
+func (osFS) RemoveAll(path string) error                  { return os.RemoveAll(path) }
+func (osFS) Rename(oldPath string, newPath string) error  { return os.Rename(oldPath, newPath) }
+func (osFS) Chmod(name string, mode os.FileMode) error    { return os.Chmod(name, mode) }
+func (osFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+
+// ReadOnly wraps base so that reads pass through and every write fails with ErrReadOnly. Dry runs use it so
+// that nothing can be written to the target, whether or not each operation remembers to check for a dry run.
+func ReadOnly(base FS) FS {
+	return readOnlyFS{base: base}
+}
+
+type readOnlyFS struct {
+	base FS
+}
+
+func denied(op string, name string) error {
+	return &os.PathError{Op: op, Path: name, Err: ErrReadOnly}
+}
+
+func (r readOnlyFS) Open(name string) (File, error)             { return r.base.Open(name) }
+func (r readOnlyFS) Stat(name string) (os.FileInfo, error)      { return r.base.Stat(name) }
+func (r readOnlyFS) ReadDir(name string) ([]os.DirEntry, error) { return r.base.ReadDir(name) }
+func (r readOnlyFS) ReadFile(name string) ([]byte, error)       { return r.base.ReadFile(name) }
+func (r readOnlyFS) Create(name string) (File, error)           { return nil, denied("create", name) }
+func (r readOnlyFS) Append(name string) (File, error)           { return nil, denied("open", name) }
+func (r readOnlyFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return denied("write", name)
+}
+func (r readOnlyFS) MkdirAll(path string, perm os.FileMode) error { return denied("mkdir", path) }
+func (r readOnlyFS) Remove(name string) error                     { return denied("remove", name) }
+func (r readOnlyFS) RemoveAll(path string) error                  { return denied("remove", path) }
+func (r readOnlyFS) Rename(oldPath string, newPath string) error {
+	return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: ErrReadOnly}
+}
+func (r readOnlyFS) Chmod(name string, mode os.FileMode) error { return denied("chmod", name) }
+func (r readOnlyFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return denied("chtimes", name)
+}
+
+// TempSuffix ends the name of every temporary file written beside its destination and renamed into place once
+// complete; one still there belongs to a write that never finished
+const TempSuffix = ".romcopy-part"
+
+// TempPath returns a path beside name to write its contents to before renaming them into place. It's hidden so
+// devices don't list it, and unique to the call so concurrent runs writing the same file don't collide.
+func TempPath(name string) string {
+	token := make([]byte, 4)
+	rand.Read(token)
+
+	// long names are shortened to keep within the 255 character limit common filesystems put on names
+	base := filepath.Base(name)
+	for len(base) > 200 {
+		_, size := utf8.DecodeLastRuneInString(base)
+		base = base[:len(base)-size]
+	}
+	return filepath.Join(filepath.Dir(name), fmt.Sprintf(".%s.%s%s", base, hex.EncodeToString(token), TempSuffix))
+}
+
+// IsTempPath reports whether name is one returned by TempPath
+func IsTempPath(name string) bool {
+	return strings.HasPrefix(filepath.Base(name), ".") && strings.HasSuffix(name, TempSuffix)
+}
+
+// WriteFileAtomic replaces name with data such that a crash leaves either the old or the new contents, never a
+// mix: data is written and synced to a temporary file beside name, which is then renamed over it
+func WriteFileAtomic(fsys FS, name string, data []byte) error {
+	tempPath := TempPath(name)
+	file, err := fsys.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		fsys.Remove(tempPath)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		fsys.Remove(tempPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		fsys.Remove(tempPath)
+		return err
+	}
+
+	if err := fsys.Rename(tempPath, name); err != nil {
+		fsys.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// Walk is filepath.Walk over an FS: fn is called for root and everything beneath it, in lexical order
+func Walk(fsys FS, root string, fn filepath.WalkFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walk(fsys, root, info, fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+func walk(fsys FS, path string, info os.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(path, info, nil)
+	}
+
+	entries, err := fsys.ReadDir(path)
+	err1 := fn(path, info, err)
+	// as with filepath.Walk, a failed ReadDir is reported to fn once, with the directory's info
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		childPath := filepath.Join(path, entry.Name())
+		// like filepath.Walk, symlinks aren't followed
+		childInfo, err := entry.Info()
+		if err != nil {
+			if err := fn(childPath, nil, err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+
+		if err := walk(fsys, childPath, childInfo, fn); err != nil {
+			if !childInfo.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
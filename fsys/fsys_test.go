@@ -0,0 +1,83 @@
+package fsys
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "game.nes")
+	if err := os.WriteFile(existing, []byte("rom data"), 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	readOnly := ReadOnly(OS)
+
+	if data, err := readOnly.ReadFile(existing); err != nil || string(data) != "rom data" {
+		t.Errorf("ReadFile() = %q, %v; reads should pass through", data, err)
+	}
+
+	writes := map[string]func() error{
+		"Create":    func() error { _, err := readOnly.Create(filepath.Join(dir, "new.nes")); return err },
+		"WriteFile": func() error { return readOnly.WriteFile(existing, []byte("x"), 0644) },
+		"Append":    func() error { _, err := readOnly.Append(existing); return err },
+		"MkdirAll":  func() error { return readOnly.MkdirAll(filepath.Join(dir, "sub"), 0755) },
+		"Remove":    func() error { return readOnly.Remove(existing) },
+		"RemoveAll": func() error { return readOnly.RemoveAll(dir) },
+		"Rename":    func() error { return readOnly.Rename(existing, filepath.Join(dir, "moved.nes")) },
+		"Chmod":     func() error { return readOnly.Chmod(existing, 0600) },
+		"Chtimes":   func() error { return readOnly.Chtimes(existing, time.Time{}, time.Time{}) },
+	}
+	for name, write := range writes {
+		if err := write(); !errors.Is(err, ErrReadOnly) {
+			t.Errorf("%s() error = %v, want ErrReadOnly", name, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected the directory to be untouched, got %v (%v)", entries, err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "rom data" {
+		t.Errorf("file was modified: %q", data)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b/2.nes", "b/1.nes", "a.nes", "c/skip/x.nes"} {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(filePath, nil, 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+
+	visited := make([]string, 0)
+	err := Walk(OS, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := filepath.Rel(dir, path)
+		visited = append(visited, filepath.ToSlash(relPath))
+		if info.IsDir() && info.Name() == "skip" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	expected := []string{".", "a.nes", "b", "b/1.nes", "b/2.nes", "c", "c/skip"}
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("Walk() visited %v, want %v", visited, expected)
+	}
+}
@@ -0,0 +1,118 @@
+package fsys
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+)
+
+// IOFS exposes the tree rooted at dir as an io/fs.FS, for libraries (e.g. doublestar) that take one
+func IOFS(fsys FS, dir string) fs.FS {
+	return ioFS{base: fsys, dir: dir}
+}
+
+type ioFS struct {
+	base FS
+	dir  string
+}
+
+func (f ioFS) resolve(op string, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(f.dir, filepath.FromSlash(name)), nil
+}
+
+func (f ioFS) Open(name string) (fs.File, error) {
+	fullPath, err := f.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.base.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &ioDir{fs: f, name: name, info: info}, nil
+	}
+
+	file, err := f.base.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return ioFile{File: file, info: info}, nil
+}
+
+func (f ioFS) Stat(name string) (fs.FileInfo, error) {
+	fullPath, err := f.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.base.Stat(fullPath)
+}
+
+func (f ioFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fullPath, err := f.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	return f.base.ReadDir(fullPath)
+}
+
+type ioFile struct {
+	File
+	info fs.FileInfo
+}
+
+func (f ioFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+type ioDir struct {
+	fs      ioFS
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *ioDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *ioDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *ioDir) Close() error {
+	return nil
+}
+
+// ReadDir follows the fs.ReadDirFile contract: n > 0 returns at most n entries and io.EOF once exhausted
+func (d *ioDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fs.ReadDir(d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	if n <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(d.entries) {
+		n = len(d.entries)
+	}
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
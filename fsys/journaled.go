@@ -0,0 +1,280 @@
+package fsys
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// the kinds of Change a JournaledFS records
+const (
+	// a file written where there was none
+	ChangeCreate = "create"
+	// a file written over one, which was moved to Backup first
+	ChangeOverwrite = "overwrite"
+	// a file or folder moved from From to Path, over whatever was there, which was moved to Backup first
+	ChangeRename = "rename"
+	// a file or folder removed, by moving it to Backup
+	ChangeDelete = "delete"
+	// an empty folder created, with any folders it's in that didn't exist
+	ChangeMkdir = "mkdir"
+	// an empty folder removed
+	ChangeRmdir = "rmdir"
+)
+
+// Change is a write to a JournaledFS, as recorded in its journal
+type Change struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	// where a renamed file or folder was before
+	From string `json:"from,omitempty"`
+	// where what was at Path before the change was moved to, so undoing it can put it back; empty if nothing was
+	Backup string `json:"backup,omitempty"`
+}
+
+// Journaled wraps base so that every write is passed to record as a Change once it's made, and nothing is lost
+// along the way: a file about to be written or renamed over, or removed, is moved into backupDir instead, so Undo
+// can put it back. backupDir must be on the same volume as what's written, so the moves are renames, and is
+// created when first needed. Temporary files (see TempPath) aren't recorded until they're renamed into place.
+// If record fails, the write it reports has already been made; the error is returned so the caller stops.
+func Journaled(base FS, backupDir string, record func(Change) error) FS {
+	return &journaledFS{base: base, backupDir: backupDir, record: record}
+}
+
+type journaledFS struct {
+	base      FS
+	backupDir string
+	record    func(Change) error
+
+	mu sync.Mutex
+	// numbers the backups so two of the same name don't collide
+	backups int
+}
+
+func (j *journaledFS) Open(name string) (File, error)             { return j.base.Open(name) }
+func (j *journaledFS) Stat(name string) (os.FileInfo, error)      { return j.base.Stat(name) }
+func (j *journaledFS) ReadDir(name string) ([]os.DirEntry, error) { return j.base.ReadDir(name) }
+func (j *journaledFS) ReadFile(name string) ([]byte, error)       { return j.base.ReadFile(name) }
+func (j *journaledFS) Chmod(name string, mode os.FileMode) error  { return j.base.Chmod(name, mode) }
+func (j *journaledFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	return j.base.Chtimes(name, atime, mtime)
+}
+
+// moves what's at name, if anything, into the backup folder, returning where it went; empty if nothing was there
+func (j *journaledFS) backUp(name string) (string, error) {
+	if _, err := j.base.Stat(name); os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	j.mu.Lock()
+	j.backups++
+	backupPath := filepath.Join(j.backupDir, strconv.Itoa(j.backups)+"_"+filepath.Base(name))
+	j.mu.Unlock()
+
+	if err := j.base.MkdirAll(j.backupDir, 0755); err != nil {
+		return "", err
+	}
+	if err := j.base.Rename(name, backupPath); err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// records a file written at name, over the one backed up to backupPath if there was one
+func (j *journaledFS) recordWrite(name string, backupPath string) error {
+	if backupPath == "" {
+		return j.record(Change{Op: ChangeCreate, Path: name})
+	}
+	return j.record(Change{Op: ChangeOverwrite, Path: name, Backup: backupPath})
+}
+
+func (j *journaledFS) Create(name string) (File, error) {
+	if IsTempPath(name) {
+		return j.base.Create(name)
+	}
+	backupPath, err := j.backUp(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := j.base.Create(name)
+	if err != nil {
+		j.restore(backupPath, name)
+		return nil, err
+	}
+	if err := j.recordWrite(name, backupPath); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// Append keeps what was in an existing file, so only a new one is recorded; undoing it removes it
+func (j *journaledFS) Append(name string) (File, error) {
+	_, statErr := j.base.Stat(name)
+	file, err := j.base.Append(name)
+	if err != nil || IsTempPath(name) || !os.IsNotExist(statErr) {
+		return file, err
+	}
+	if err := j.recordWrite(name, ""); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func (j *journaledFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if IsTempPath(name) {
+		return j.base.WriteFile(name, data, perm)
+	}
+	backupPath, err := j.backUp(name)
+	if err != nil {
+		return err
+	}
+	if err := j.base.WriteFile(name, data, perm); err != nil {
+		j.restore(backupPath, name)
+		return err
+	}
+	return j.recordWrite(name, backupPath)
+}
+
+func (j *journaledFS) MkdirAll(path string, perm os.FileMode) error {
+	// the outermost folder that doesn't exist yet is the one undoing this removes
+	created := ""
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := j.base.Stat(dir); err == nil {
+			break
+		}
+		created = dir
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if err := j.base.MkdirAll(path, perm); err != nil || created == "" {
+		return err
+	}
+	return j.record(Change{Op: ChangeMkdir, Path: created})
+}
+
+func (j *journaledFS) Remove(name string) error {
+	info, err := j.base.Stat(name)
+	if err != nil || IsTempPath(name) {
+		return j.base.Remove(name)
+	}
+	// a folder can only be removed empty, so there's nothing to keep
+	if info.IsDir() {
+		if err := j.base.Remove(name); err != nil {
+			return err
+		}
+		return j.record(Change{Op: ChangeRmdir, Path: name})
+	}
+	return j.delete(name)
+}
+
+func (j *journaledFS) RemoveAll(path string) error {
+	if _, err := j.base.Stat(path); err != nil || IsTempPath(path) {
+		return j.base.RemoveAll(path)
+	}
+	return j.delete(path)
+}
+
+func (j *journaledFS) delete(name string) error {
+	backupPath, err := j.backUp(name)
+	if err != nil {
+		return err
+	}
+	return j.record(Change{Op: ChangeDelete, Path: name, Backup: backupPath})
+}
+
+func (j *journaledFS) Rename(oldPath string, newPath string) error {
+	// on a case-insensitive volume, a rename that only changes case finds the file itself at newPath
+	backupPath := ""
+	oldInfo, oldErr := j.base.Stat(oldPath)
+	if newInfo, err := j.base.Stat(newPath); err != nil || oldErr != nil || !os.SameFile(oldInfo, newInfo) {
+		if backupPath, err = j.backUp(newPath); err != nil {
+			return err
+		}
+	}
+	if err := j.base.Rename(oldPath, newPath); err != nil {
+		j.restore(backupPath, newPath)
+		return err
+	}
+	// a temporary file renamed into place is the file being written
+	if IsTempPath(oldPath) {
+		return j.recordWrite(newPath, backupPath)
+	}
+	return j.record(Change{Op: ChangeRename, Path: newPath, From: oldPath, Backup: backupPath})
+}
+
+// puts back what backUp moved aside for a write that then failed
+func (j *journaledFS) restore(backupPath string, name string) {
+	if backupPath != "" {
+		j.base.Rename(backupPath, name)
+	}
+}
+
+// Undo reverses change on fs: what it wrote is removed, what it renamed is moved back, and what it backed up is put
+// back where it was. Changes must be undone newest first, as each one expects the state the change left behind.
+func Undo(fs FS, change Change) error {
+	switch change.Op {
+	case ChangeCreate:
+		if err := fs.Remove(change.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	case ChangeOverwrite:
+		if err := fs.RemoveAll(change.Path); err != nil {
+			return err
+		}
+	case ChangeDelete:
+		if _, err := fs.Stat(change.Path); err == nil {
+			return fmt.Errorf("can't put %s back: something else is there now", change.Path)
+		}
+	case ChangeRename:
+		if _, err := fs.Stat(change.From); err == nil {
+			return fmt.Errorf("can't move %s back to %s: something else is there now", change.Path, change.From)
+		}
+		if err := fs.MkdirAll(filepath.Dir(change.From), 0755); err != nil {
+			return err
+		}
+		if err := fs.Rename(change.Path, change.From); err != nil {
+			return err
+		}
+	case ChangeMkdir:
+		// the folders are left if anything written since is still in them
+		files := 0
+		err := Walk(fs, change.Path, func(walkPath string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				files++
+			}
+			return err
+		})
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if files > 0 {
+			return fmt.Errorf("left folder %s, which isn't empty", change.Path)
+		}
+		return fs.RemoveAll(change.Path)
+	case ChangeRmdir:
+		return fs.MkdirAll(change.Path, 0755)
+	default:
+		return fmt.Errorf("unknown change '%s' to %s", change.Op, change.Path)
+	}
+
+	if change.Backup == "" {
+		return nil
+	}
+	if _, err := fs.Stat(change.Backup); err != nil {
+		return fmt.Errorf("the backup of %s at %s is gone: %w", change.Path, change.Backup, err)
+	}
+	if err := fs.MkdirAll(filepath.Dir(change.Path), 0755); err != nil {
+		return err
+	}
+	return fs.Rename(change.Backup, change.Path)
+}
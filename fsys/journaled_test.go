@@ -0,0 +1,100 @@
+package fsys
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// every file beneath dir and its contents, by slash separated path, and every folder as ""
+func readTree(t *testing.T, dir string) map[string]string {
+	tree := make(map[string]string)
+	err := filepath.Walk(dir, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil || walkPath == dir {
+			return err
+		}
+		relPath, _ := filepath.Rel(dir, walkPath)
+		if info.IsDir() {
+			tree[filepath.ToSlash(relPath)+"/"] = ""
+			return nil
+		}
+		data, err := os.ReadFile(walkPath)
+		tree[filepath.ToSlash(relPath)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", dir, err)
+	}
+	return tree
+}
+
+func TestJournaled(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "target")
+	backupDir := filepath.Join(t.TempDir(), "backups")
+	for name, data := range map[string]string{"game.nes": "old", "keep.txt": "keep", "gone/game.gb": "gb"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("failed to create file: %v", err)
+		}
+	}
+	before := readTree(t, dir)
+
+	changes := make([]Change, 0)
+	journaled := Journaled(OS, backupDir, func(change Change) error {
+		changes = append(changes, change)
+		return nil
+	})
+
+	steps := []func() error{
+		func() error { return WriteFileAtomic(journaled, filepath.Join(dir, "game.nes"), []byte("new")) },
+		func() error { return journaled.WriteFile(filepath.Join(dir, "new.nes"), []byte("added"), 0644) },
+		func() error { return journaled.MkdirAll(filepath.Join(dir, "sub", "deep"), 0755) },
+		func() error {
+			return journaled.Rename(filepath.Join(dir, "keep.txt"), filepath.Join(dir, "sub", "deep", "keep.txt"))
+		},
+		func() error { return journaled.RemoveAll(filepath.Join(dir, "gone")) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d error = %v", i, err)
+		}
+	}
+
+	ops := make([]string, 0, len(changes))
+	for _, change := range changes {
+		ops = append(ops, change.Op)
+	}
+	if want := []string{ChangeOverwrite, ChangeCreate, ChangeMkdir, ChangeRename, ChangeDelete}; !reflect.DeepEqual(ops, want) {
+		t.Fatalf("recorded %+v, want ops %v", changes, want)
+	}
+	if changes[2].Path != filepath.Join(dir, "sub") {
+		t.Errorf("mkdir recorded %s, want the outermost folder created", changes[2].Path)
+	}
+	if data, err := os.ReadFile(changes[0].Backup); err != nil || string(data) != "old" {
+		t.Errorf("backup of the overwritten file = %q, %v; want \"old\"", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "gone")); !os.IsNotExist(err) {
+		t.Errorf("removed folder is still there: %v", err)
+	}
+
+	for i := len(changes) - 1; i >= 0; i-- {
+		if err := Undo(OS, changes[i]); err != nil {
+			t.Fatalf("Undo(%+v) error = %v", changes[i], err)
+		}
+	}
+	if after := readTree(t, dir); !reflect.DeepEqual(after, before) {
+		t.Errorf("after undoing every change, target = %v, want %v", after, before)
+	}
+
+	// a change can't be undone over something that's since taken its place
+	rename := Change{Op: ChangeRename, Path: filepath.Join(dir, "game.nes"), From: filepath.Join(dir, "keep.txt")}
+	if err := Undo(OS, rename); err == nil {
+		t.Error("Undo() of a rename back onto an existing file should fail")
+	}
+	if err := Undo(OS, Change{Op: "chown", Path: dir}); err == nil {
+		t.Error("Undo() of an unknown change should fail")
+	}
+}
@@ -0,0 +1,387 @@
+package fsys
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemFS is an FS held entirely in memory, for running the engine in tests without touching the disk. Paths are
+// cleaned with filepath.Clean, and every volume root (e.g. '/') exists from the start.
+type MemFS struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	isDir   bool
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: make(map[string]*memNode)}
+}
+
+func memError(op string, name string, err error) error {
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// returns the node at a cleaned path; volume roots are always directories
+func (m *MemFS) lookup(name string) (*memNode, bool) {
+	if node, ok := m.nodes[name]; ok {
+		return node, true
+	}
+	if filepath.Dir(name) == name {
+		return &memNode{isDir: true, mode: os.ModeDir | 0755}, true
+	}
+	return nil, false
+}
+
+// the parent of name must exist and be a directory for name to be created
+func (m *MemFS) checkParent(op string, name string) error {
+	parent, ok := m.lookup(filepath.Dir(name))
+	if !ok {
+		return memError(op, name, fs.ErrNotExist)
+	}
+	if !parent.isDir {
+		return memError(op, name, fs.ErrInvalid)
+	}
+	return nil
+}
+
+// returns the cleaned paths of every node beneath dir
+func (m *MemFS) descendants(dir string) []string {
+	prefix := dir + string(filepath.Separator)
+	if strings.HasSuffix(dir, string(filepath.Separator)) {
+		prefix = dir
+	}
+	paths := make([]string, 0)
+	for nodePath := range m.nodes {
+		if strings.HasPrefix(nodePath, prefix) {
+			paths = append(paths, nodePath)
+		}
+	}
+	return paths
+}
+
+func (m *MemFS) Open(name string) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.lookup(name)
+	if !ok {
+		return nil, memError("open", name, fs.ErrNotExist)
+	}
+	if node.isDir {
+		return nil, memError("open", name, fs.ErrInvalid)
+	}
+	return &memFile{fs: m, name: name, reader: bytes.NewReader(node.data)}, nil
+}
+
+func (m *MemFS) Create(name string) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if node, ok := m.lookup(name); ok && node.isDir {
+		return nil, memError("open", name, fs.ErrExist)
+	}
+	if err := m.checkParent("open", name); err != nil {
+		return nil, err
+	}
+
+	mode := os.FileMode(0666)
+	if node, ok := m.nodes[name]; ok {
+		mode = node.mode
+	}
+	m.nodes[name] = &memNode{mode: mode, modTime: time.Now()}
+	return &memFile{fs: m, name: name, writable: true}, nil
+}
+
+func (m *MemFS) Append(name string) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.lookup(name)
+	if ok && node.isDir {
+		return nil, memError("open", name, fs.ErrExist)
+	}
+	if !ok {
+		if err := m.checkParent("open", name); err != nil {
+			return nil, err
+		}
+		m.nodes[name] = &memNode{mode: 0644, modTime: time.Now()}
+	}
+	return &memFile{fs: m, name: name, writable: true}, nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.lookup(name)
+	if !ok {
+		return nil, memError("stat", name, fs.ErrNotExist)
+	}
+	return memFileInfo{name: filepath.Base(name), node: *node}, nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.lookup(name)
+	if !ok {
+		return nil, memError("open", name, fs.ErrNotExist)
+	}
+	if !node.isDir {
+		return nil, memError("readdirent", name, fs.ErrInvalid)
+	}
+
+	entries := make([]os.DirEntry, 0)
+	for _, childPath := range m.descendants(name) {
+		if filepath.Dir(childPath) == name {
+			info := memFileInfo{name: filepath.Base(childPath), node: *m.nodes[childPath]}
+			entries = append(entries, fs.FileInfoToDirEntry(info))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.lookup(name)
+	if !ok {
+		return nil, memError("open", name, fs.ErrNotExist)
+	}
+	if node.isDir {
+		return nil, memError("read", name, fs.ErrInvalid)
+	}
+	return append([]byte(nil), node.data...), nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if node, ok := m.lookup(name); ok && node.isDir {
+		return memError("open", name, fs.ErrExist)
+	}
+	if err := m.checkParent("open", name); err != nil {
+		return err
+	}
+
+	// as with os.WriteFile, perm only applies to new files
+	if node, ok := m.nodes[name]; ok {
+		perm = node.mode
+	}
+	m.nodes[name] = &memNode{data: append([]byte(nil), data...), mode: perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) MkdirAll(path string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path = filepath.Clean(path)
+	missing := make([]string, 0)
+	for dir := path; ; dir = filepath.Dir(dir) {
+		node, ok := m.lookup(dir)
+		if ok {
+			if !node.isDir {
+				return memError("mkdir", dir, fs.ErrExist)
+			}
+			break
+		}
+		missing = append(missing, dir)
+	}
+
+	for _, dir := range missing {
+		m.nodes[dir] = &memNode{isDir: true, mode: os.ModeDir | perm.Perm(), modTime: time.Now()}
+	}
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if _, ok := m.nodes[name]; !ok {
+		return memError("remove", name, fs.ErrNotExist)
+	}
+	if len(m.descendants(name)) > 0 {
+		return memError("remove", name, fs.ErrExist)
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+func (m *MemFS) RemoveAll(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path = filepath.Clean(path)
+	for _, nodePath := range m.descendants(path) {
+		delete(m.nodes, nodePath)
+	}
+	delete(m.nodes, path)
+	return nil
+}
+
+func (m *MemFS) Rename(oldPath string, newPath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldPath = filepath.Clean(oldPath)
+	newPath = filepath.Clean(newPath)
+	linkError := func(err error) error {
+		return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: err}
+	}
+
+	node, ok := m.nodes[oldPath]
+	if !ok {
+		return linkError(fs.ErrNotExist)
+	}
+	if oldPath == newPath {
+		return nil
+	}
+	if err := m.checkParent("rename", newPath); err != nil {
+		return linkError(fs.ErrNotExist)
+	}
+	// like rename(2), an existing file is replaced, but a directory only if it's empty
+	if existing, ok := m.nodes[newPath]; ok {
+		if existing.isDir != node.isDir || len(m.descendants(newPath)) > 0 {
+			return linkError(fs.ErrExist)
+		}
+	}
+
+	for _, nodePath := range m.descendants(oldPath) {
+		m.nodes[newPath+strings.TrimPrefix(nodePath, oldPath)] = m.nodes[nodePath]
+		delete(m.nodes, nodePath)
+	}
+	m.nodes[newPath] = node
+	delete(m.nodes, oldPath)
+	return nil
+}
+
+func (m *MemFS) Chmod(name string, mode os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.nodes[name]
+	if !ok {
+		return memError("chmod", name, fs.ErrNotExist)
+	}
+	node.mode = node.mode&os.ModeType | mode.Perm()
+	return nil
+}
+
+// Chtimes sets name's modification time; access times aren't tracked
+func (m *MemFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	node, ok := m.nodes[name]
+	if !ok {
+		return memError("chtimes", name, fs.ErrNotExist)
+	}
+	node.modTime = mtime
+	return nil
+}
+
+// an open file; reads come from a snapshot taken when it was opened, and writes go straight to the node
+type memFile struct {
+	fs       *MemFS
+	name     string
+	reader   *bytes.Reader
+	writable bool
+	closed   bool
+}
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, memError("read", f.name, fs.ErrClosed)
+	}
+	if f.reader == nil {
+		return 0, memError("read", f.name, fs.ErrPermission)
+	}
+	return f.reader.Read(p)
+}
+
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, memError("read", f.name, fs.ErrClosed)
+	}
+	if f.reader == nil {
+		return 0, memError("read", f.name, fs.ErrPermission)
+	}
+	return f.reader.ReadAt(p, off)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, memError("write", f.name, fs.ErrClosed)
+	}
+	if !f.writable {
+		return 0, memError("write", f.name, fs.ErrPermission)
+	}
+	node, ok := f.fs.nodes[f.name]
+	if !ok {
+		return 0, memError("write", f.name, fs.ErrNotExist)
+	}
+	node.data = append(node.data, p...)
+	node.modTime = time.Now()
+	return len(p), nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	f.closed = true
+	return nil
+}
+
+func (f *memFile) Sync() error {
+	return nil
+}
+
+type memFileInfo struct {
+	name string
+	node memNode
+}
+
+func (i memFileInfo) Name() string       { return i.name }
+func (i memFileInfo) Size() int64        { return int64(len(i.node.data)) }
+func (i memFileInfo) Mode() os.FileMode  { return i.node.mode }
+func (i memFileInfo) ModTime() time.Time { return i.node.modTime }
+func (i memFileInfo) IsDir() bool        { return i.node.isDir }
+func (i memFileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,200 @@
+package fsys
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// nothing is written to disk; a temp dir just provides an absolute path that's valid on every OS
+func memRoot(t *testing.T) string {
+	return filepath.Join(t.TempDir(), "mem")
+}
+
+func TestMemFSFiles(t *testing.T) {
+	mem := NewMemFS()
+	root := memRoot(t)
+	gamePath := filepath.Join(root, "nes", "game.nes")
+
+	if err := mem.WriteFile(gamePath, []byte("x"), 0644); !os.IsNotExist(err) {
+		t.Errorf("WriteFile() without a parent error = %v, want not exist", err)
+	}
+
+	if err := mem.MkdirAll(filepath.Dir(gamePath), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	file, err := mem.Create(gamePath)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	file.Write([]byte("rom "))
+	file.Write([]byte("data"))
+	file.Close()
+
+	if data, err := mem.ReadFile(gamePath); err != nil || string(data) != "rom data" {
+		t.Errorf("ReadFile() = %q, %v; want 'rom data'", data, err)
+	}
+
+	file, err = mem.Open(gamePath)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if data, _ := io.ReadAll(file); string(data) != "rom data" {
+		t.Errorf("read %q through Open(), want 'rom data'", data)
+	}
+	if _, err := file.Write([]byte("x")); err == nil {
+		t.Errorf("Write() to a file opened for reading should fail")
+	}
+	file.Close()
+
+	if err := mem.Chmod(gamePath, 0600); err != nil {
+		t.Fatalf("Chmod() error = %v", err)
+	}
+	info, err := mem.Stat(gamePath)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Size() != 8 || info.Mode() != 0600 || info.IsDir() || info.Name() != "game.nes" {
+		t.Errorf("Stat() = %d bytes, mode %v, dir %v, name %s", info.Size(), info.Mode(), info.IsDir(), info.Name())
+	}
+
+	stamp := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := mem.Chtimes(gamePath, stamp, stamp); err != nil {
+		t.Fatalf("Chtimes() error = %v", err)
+	}
+	if info, _ := mem.Stat(gamePath); !info.ModTime().Equal(stamp) {
+		t.Errorf("ModTime() after Chtimes() = %v, want %v", info.ModTime(), stamp)
+	}
+
+	if err := mem.Remove(filepath.Dir(gamePath)); err == nil {
+		t.Errorf("Remove() of a non-empty directory should fail")
+	}
+	if err := mem.Remove(gamePath); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+	if _, err := mem.Stat(gamePath); !os.IsNotExist(err) {
+		t.Errorf("Stat() after Remove() error = %v, want not exist", err)
+	}
+}
+
+func TestMemFSDirectories(t *testing.T) {
+	mem := NewMemFS()
+	root := memRoot(t)
+	for _, name := range []string{"roms/nes/b.nes", "roms/nes/a.nes", "roms/snes/c.sfc"} {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := mem.WriteFile(filePath, []byte(name), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	entries, err := mem.ReadDir(filepath.Join(root, "roms", "nes"))
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	names := make([]string, 0)
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"a.nes", "b.nes"}) {
+		t.Errorf("ReadDir() = %v, want [a.nes b.nes]", names)
+	}
+
+	if err := mem.Rename(filepath.Join(root, "roms", "nes"), filepath.Join(root, "roms", "fc")); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if data, err := mem.ReadFile(filepath.Join(root, "roms", "fc", "a.nes")); err != nil || string(data) != "roms/nes/a.nes" {
+		t.Errorf("ReadFile() after Rename() = %q, %v", data, err)
+	}
+	if _, err := mem.Stat(filepath.Join(root, "roms", "nes", "a.nes")); !os.IsNotExist(err) {
+		t.Errorf("old path still exists after Rename()")
+	}
+	if err := mem.Rename(filepath.Join(root, "roms", "fc"), filepath.Join(root, "roms", "snes")); err == nil {
+		t.Errorf("Rename() onto a non-empty directory should fail")
+	}
+
+	if err := mem.RemoveAll(filepath.Join(root, "roms")); err != nil {
+		t.Fatalf("RemoveAll() error = %v", err)
+	}
+	if entries, err := mem.ReadDir(root); err != nil || len(entries) != 0 {
+		t.Errorf("ReadDir() after RemoveAll() = %v, %v; want empty", entries, err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	mem := NewMemFS()
+	root := memRoot(t)
+	filePath := filepath.Join(root, "manifest.json")
+	mem.MkdirAll(root, 0755)
+	mem.WriteFile(filePath, []byte("old"), 0644)
+
+	if err := WriteFileAtomic(mem, filePath, []byte("new")); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	if data, _ := mem.ReadFile(filePath); string(data) != "new" {
+		t.Errorf("contents = %q, want 'new'", data)
+	}
+	if entries, _ := mem.ReadDir(root); len(entries) != 1 {
+		t.Errorf("expected the temporary file to be gone, found %d entries", len(entries))
+	}
+
+	if err := WriteFileAtomic(ReadOnly(mem), filePath, []byte("blocked")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("WriteFileAtomic() on a read-only filesystem error = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestTempPath(t *testing.T) {
+	name := filepath.Join("roms", "snes", "Zelda (USA).sfc")
+	first, second := TempPath(name), TempPath(name)
+	if first == second {
+		t.Errorf("TempPath() returned %s twice, want a unique path each call", first)
+	}
+	if filepath.Dir(first) != filepath.Dir(name) || !strings.HasPrefix(filepath.Base(first), ".Zelda (USA).sfc.") {
+		t.Errorf("TempPath() = %s, want a hidden file beside %s", first, name)
+	}
+	if !IsTempPath(first) || IsTempPath(name) {
+		t.Errorf("IsTempPath() should recognize %s and not %s", first, name)
+	}
+
+	long := TempPath(strings.Repeat("é", 200) + ".iso")
+	if len(filepath.Base(long)) > 255 || !utf8.ValidString(long) {
+		t.Errorf("TempPath() of a long name = %s (%d bytes), want at most 255 bytes of valid UTF-8", long, len(filepath.Base(long)))
+	}
+}
+
+func TestIOFS(t *testing.T) {
+	mem := NewMemFS()
+	root := memRoot(t)
+	for _, name := range []string{"a.xml", "sub/b.xml", "sub/c.txt"} {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		mem.MkdirAll(filepath.Dir(filePath), 0755)
+		mem.WriteFile(filePath, []byte(name), 0644)
+	}
+
+	matches, err := doublestar.Glob(IOFS(mem, root), "**/*.xml")
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	if !reflect.DeepEqual(matches, []string{"a.xml", "sub/b.xml"}) {
+		t.Errorf("Glob() = %v, want [a.xml sub/b.xml]", matches)
+	}
+
+	data, err := fs.ReadFile(IOFS(mem, root), "sub/c.txt")
+	if err != nil || string(data) != "sub/c.txt" {
+		t.Errorf("fs.ReadFile() = %q, %v", data, err)
+	}
+	if _, err := IOFS(mem, root).Open("../escape"); !errors.Is(err, fs.ErrInvalid) {
+		t.Errorf("Open() of an invalid path error = %v, want ErrInvalid", err)
+	}
+}
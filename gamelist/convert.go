@@ -0,0 +1,251 @@
+package gamelist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// game list formats '--convertGamelist' can convert to
+const (
+	// the Miyoo Mini's stock firmware and Onion: miyoogamelist.xml, with box art in Imgs
+	FormatMiyoo = "miyoo"
+	// EmulationStation and its forks, e.g. Batocera and Knulli: gamelist.xml, with every path relative to the
+	// platform folder and starting with './'
+	FormatES = "es"
+)
+
+var ConvertFormats = []string{FormatMiyoo, FormatES}
+
+const (
+	// the game list the Miyoo reads in each platform folder
+	MiyooFileName = "miyoogamelist.xml"
+	// where the Miyoo looks for box art in each platform folder
+	MiyooImageDir = "Imgs"
+
+	// the plain declaration the game lists this package writes start with, as Miyoo and EmulationStation lists
+	// usually do
+	listHeader = "<?xml version=\"1.0\"?>\n"
+)
+
+// a game list giving only the path, name, and image of each game: all the Miyoo reads, and all a list generated
+// from file names knows
+type minimalList struct {
+	XMLName xml.Name      `xml:"gameList"`
+	Games   []minimalGame `xml:"game"`
+}
+
+type minimalGame struct {
+	Path        string `xml:"path"`
+	Name        string `xml:"name"`
+	Image       string `xml:"image,omitempty"`
+	Desc        string `xml:"desc,omitempty"`
+	ReleaseDate string `xml:"releasedate,omitempty"`
+	Developer   string `xml:"developer,omitempty"`
+	Publisher   string `xml:"publisher,omitempty"`
+	Genre       string `xml:"genre,omitempty"`
+}
+
+// ConvertResult is what converting a game list did
+type ConvertResult struct {
+	// games written to the converted list
+	Games int
+	// entries the format can't hold, e.g. folders, or games whose path isn't within the platform folder
+	Dropped int
+	// box art moved into the folder the format expects it in
+	MovedImages int
+	// paths rewritten into the form the format expects
+	Rewritten int
+}
+
+// the elements of a game list entry that refer to files, as EmulationStation and its forks write them
+var pathElements = map[string]bool{
+	"path": true, "image": true, "video": true, "marquee": true, "thumbnail": true, "manual": true, "fanart": true,
+	"titleshot": true, "map": true, "boxart": true, "boxback": true, "wheel": true, "mix": true, "bezel": true,
+	"cartridge": true, "magazine": true,
+}
+
+// ConvertToMiyoo replaces the gamelist.xml at the top of dir with the miyoogamelist.xml the Miyoo Mini reads:
+// each game keeps only its path, name, and image, every other element and folder entry is dropped, and each
+// game's image is moved into Imgs and pointed at there, unless a different image of the same name is already
+// there. Absolute paths are read, and paths written, by folder's rules; dir's own name is always one of the names
+// it's known by. It returns false if dir has no gamelist.xml.
+func ConvertToMiyoo(fs fsys.FS, dir string, folder device_paths.Folder) (ConvertResult, bool, error) {
+	var result ConvertResult
+	list, err := Load(fs, dir)
+	if list == nil || err != nil {
+		return result, false, err
+	}
+	folder.Names = append([]string{filepath.Base(dir)}, folder.Names...)
+
+	converted := minimalList{Games: make([]minimalGame, 0, len(list.Games))}
+	result.Dropped = len(list.Folders)
+	for _, game := range list.Games {
+		gamePath, ok := folder.Read(game.Path)
+		if !ok {
+			result.Dropped++
+			continue
+		}
+
+		entry := minimalGame{Path: folder.Write(".", gamePath, folder.Rules.ListStyle), Name: strings.TrimSpace(game.Name)}
+		if entry.Name == "" {
+			entry.Name = strings.TrimSuffix(path.Base(gamePath), path.Ext(gamePath))
+		}
+
+		if imagePath, ok := folder.Read(game.Image); ok {
+			newPath, moved, err := moveImage(fs, dir, imagePath, path.Join(MiyooImageDir, path.Base(imagePath)))
+			if err != nil {
+				return result, true, err
+			}
+			if moved {
+				result.MovedImages++
+			}
+			entry.Image = folder.Write(".", newPath, folder.Rules.ListStyle)
+		}
+
+		converted.Games = append(converted.Games, entry)
+	}
+	result.Games = len(converted.Games)
+
+	data, err := converted.encode()
+	if err != nil {
+		return result, true, fmt.Errorf("failed to encode %s: %w", MiyooFileName, err)
+	}
+
+	listPath := filepath.Join(dir, FileName)
+	info, err := fs.Stat(listPath)
+	if err != nil {
+		return result, true, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+	}
+	miyooListPath := filepath.Join(dir, MiyooFileName)
+	if err := fs.WriteFile(miyooListPath, data, info.Mode().Perm()); err != nil {
+		return result, true, fmt.Errorf("failed to write %s: %w", miyooListPath, err)
+	}
+	if err := fs.Remove(listPath); err != nil {
+		return result, true, fmt.Errorf("failed to remove %s: %w", listPath, err)
+	}
+	return result, true, nil
+}
+
+// the list as a document, indented with tabs
+func (l minimalList) encode() ([]byte, error) {
+	data, err := xml.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(listHeader), append(data, '\n')...), nil
+}
+
+// ConvertToES rewrites the gamelist.xml at the top of dir in the form EmulationStation and its forks read on any
+// device: every path relative to dir and starting with './', e.g. './images/Zelda.png' for
+// '/home/pi/RetroPie/roms/snes/images/Zelda.png'. Entries for games outside dir are dropped, and so are references
+// to artwork outside it, which the device won't have; everything
+// else is left as it was. Absolute paths are read by folder's rules; dir's own name is always one of the names it's
+// known by. It returns false if dir has no gamelist.xml.
+func ConvertToES(fs fsys.FS, dir string, folder device_paths.Folder) (ConvertResult, bool, error) {
+	var result ConvertResult
+	listPath := filepath.Join(dir, FileName)
+	data, err := fs.ReadFile(listPath)
+	if os.IsNotExist(err) {
+		return result, false, nil
+	}
+	if err != nil {
+		return result, false, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+	}
+	folder.Names = append([]string{filepath.Base(dir)}, folder.Names...)
+
+	children, _, err := childElements(data)
+	if err != nil {
+		return result, true, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+	}
+	edits := make([]edit, 0)
+	for _, child := range children {
+		game, ok, err := child.entry(data)
+		if err != nil {
+			return result, true, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, ok := folder.Read(game.Path); !ok {
+			edits = append(edits, cut(data, child.start, child.end))
+			result.Dropped++
+			continue
+		}
+		if child.name == "game" {
+			result.Games++
+		}
+
+		fields, _, err := childElements(data[child.start:child.end])
+		if err != nil {
+			return result, true, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+		}
+		for _, field := range fields {
+			if !pathElements[field.name] {
+				continue
+			}
+			var value struct {
+				Text string `xml:",chardata"`
+			}
+			if err := xml.Unmarshal(data[child.start+field.start:child.start+field.end], &value); err != nil {
+				return result, true, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+			}
+			if strings.TrimSpace(value.Text) == "" {
+				continue
+			}
+
+			relPath, ok := folder.Read(value.Text)
+			if !ok {
+				edits = append(edits, cut(data, child.start+field.start, child.start+field.end))
+				continue
+			}
+			if written := folder.Write(".", relPath, device_paths.DotRelative); written != value.Text {
+				edits = append(edits, edit{start: child.start + field.start, end: child.start + field.end, text: textElement(field.name, written)})
+				result.Rewritten++
+			}
+		}
+	}
+	if len(edits) == 0 {
+		return result, true, nil
+	}
+
+	info, err := fs.Stat(listPath)
+	if err != nil {
+		return result, true, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+	}
+	if err := fs.WriteFile(listPath, splice(data, edits), info.Mode().Perm()); err != nil {
+		return result, true, fmt.Errorf("failed to write %s: %w", listPath, err)
+	}
+	return result, true, nil
+}
+
+// moves the image at from to to, both relative to dir, returning where the image is now and whether it was
+// moved. An image that isn't in dir is expected at to, e.g. because a rename already put it there; one that is
+// stays put if to is already taken.
+func moveImage(fs fsys.FS, dir string, from string, to string) (string, bool, error) {
+	if from == to {
+		return to, false, nil
+	}
+	fromPath := filepath.Join(dir, filepath.FromSlash(from))
+	toPath := filepath.Join(dir, filepath.FromSlash(to))
+	if _, err := fs.Stat(fromPath); err != nil {
+		return to, false, nil
+	}
+	if _, err := fs.Stat(toPath); !os.IsNotExist(err) {
+		return from, false, nil
+	}
+
+	if err := fs.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return from, false, fmt.Errorf("failed to create %s: %w", filepath.Dir(toPath), err)
+	}
+	if err := fs.Rename(fromPath, toPath); err != nil {
+		return from, false, fmt.Errorf("failed to move %s to %s: %w", fromPath, toPath, err)
+	}
+	return to, true, nil
+}
@@ -0,0 +1,205 @@
+package gamelist
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestConvertToMiyoo(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	files := map[string]string{
+		FileName: `<?xml version="1.0"?>
+<gameList>
+	<folder>
+		<path>./Hacks</path>
+		<name>Hacks</name>
+	</folder>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<desc>Link's adventure &amp; more</desc>
+		<image>./media/images/Zelda (USA).png</image>
+		<playcount>3</playcount>
+	</game>
+	<game>
+		<path>/userdata/roms/snes/Tetris (World).sfc</path>
+		<image>./Imgs/Tetris (World).png</image>
+	</game>
+	<game>
+		<path>/userdata/roms/psx/Crash.chd</path>
+		<name>Crash</name>
+	</game>
+</gameList>
+`,
+		"Zelda (USA).sfc":              "",
+		"media/images/Zelda (USA).png": "zelda",
+		"Imgs/Tetris (World).png":      "tetris",
+		"Tetris (World).sfc":           "",
+		"Hacks/Zelda Redux (Hack).sfc": "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := mem.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	result, found, err := ConvertToMiyoo(mem, dir, device_paths.Folder{Names: []string{"snes"}})
+	if err != nil || !found {
+		t.Fatalf("ConvertToMiyoo() = %v, %v; want the list converted", found, err)
+	}
+	if result.Games != 2 || result.Dropped != 2 || result.MovedImages != 1 {
+		t.Errorf("ConvertToMiyoo() = %+v, want 2 games, 2 dropped, and 1 image moved", result)
+	}
+
+	want := `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<image>./Imgs/Zelda (USA).png</image>
+	</game>
+	<game>
+		<path>./Tetris (World).sfc</path>
+		<name>Tetris (World)</name>
+		<image>./Imgs/Tetris (World).png</image>
+	</game>
+</gameList>
+`
+	data, err := mem.ReadFile(filepath.Join(dir, MiyooFileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", MiyooFileName, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", MiyooFileName, data, want)
+	}
+
+	if _, err := mem.Stat(filepath.Join(dir, FileName)); err == nil {
+		t.Errorf("expected %s to be removed", FileName)
+	}
+	if moved, err := mem.ReadFile(filepath.Join(dir, "Imgs", "Zelda (USA).png")); err != nil || string(moved) != "zelda" {
+		t.Errorf("expected Zelda's image in Imgs, got %q, %v", moved, err)
+	}
+
+	// nothing left to convert
+	if _, found, err := ConvertToMiyoo(mem, dir, device_paths.Folder{}); found || err != nil {
+		t.Errorf("ConvertToMiyoo() without a game list = %v, %v; want nothing converted", found, err)
+	}
+}
+
+func TestConvertToMiyooKeepsClashingImages(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	files := map[string]string{
+		FileName:           "<gameList><game><path>./Zelda.sfc</path><image>./boxart/Zelda.png</image></game></gameList>",
+		"boxart/Zelda.png": "box",
+		"Imgs/Zelda.png":   "screenshot",
+		"Zelda.sfc":        "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := mem.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+
+	result, _, err := ConvertToMiyoo(mem, dir, device_paths.Folder{})
+	if err != nil || result.MovedImages != 0 {
+		t.Fatalf("ConvertToMiyoo() = %+v, %v; want no images moved", result, err)
+	}
+	data, err := mem.ReadFile(filepath.Join(dir, MiyooFileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", MiyooFileName, err)
+	}
+	list, err := Load(mem, dir)
+	if list != nil || err != nil {
+		t.Errorf("expected %s to be removed", FileName)
+	}
+	if want := "<image>./boxart/Zelda.png</image>"; !strings.Contains(string(data), want) {
+		t.Errorf("%s = %s, want the image left where it was", MiyooFileName, data)
+	}
+}
+
+func TestConvertToES(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "megadrive")
+	list := `<?xml version="1.0"?>
+<gameList>
+	<folder>
+		<path>/home/pi/RetroPie/roms/genesis/Hacks</path>
+		<name>Hacks</name>
+	</folder>
+	<game>
+		<path>/home/pi/RetroPie/roms/genesis/Sonic (USA).md</path>
+		<name>Sonic</name>
+		<desc>Gotta go fast</desc>
+		<image>/home/pi/.emulationstation/downloaded_images/Sonic (USA).png</image>
+		<video>media/videos/Sonic (USA).mp4</video>
+	</game>
+	<game>
+		<path>./Streets of Rage (USA).md</path>
+		<image>./images/Streets of Rage (USA).png</image>
+	</game>
+	<game>
+		<path>/home/pi/RetroPie/roms/snes/Zelda.sfc</path>
+		<name>Zelda</name>
+	</game>
+</gameList>
+`
+	if err := mem.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := mem.WriteFile(filepath.Join(dir, FileName), []byte(list), 0644); err != nil {
+		t.Fatalf("failed to create %s: %v", FileName, err)
+	}
+
+	result, found, err := ConvertToES(mem, dir, device_paths.Folder{Names: []string{"genesis"}})
+	if err != nil || !found {
+		t.Fatalf("ConvertToES() = %v, %v; want the list converted", found, err)
+	}
+	if result.Games != 2 || result.Dropped != 1 || result.Rewritten != 3 {
+		t.Errorf("ConvertToES() = %+v, want 2 games, 1 dropped, and 3 paths rewritten", result)
+	}
+
+	// artwork outside the platform folder isn't on the device, so it's dropped rather than pointed at
+	want := `<?xml version="1.0"?>
+<gameList>
+	<folder>
+		<path>./Hacks</path>
+		<name>Hacks</name>
+	</folder>
+	<game>
+		<path>./Sonic (USA).md</path>
+		<name>Sonic</name>
+		<desc>Gotta go fast</desc>
+		<video>./media/videos/Sonic (USA).mp4</video>
+	</game>
+	<game>
+		<path>./Streets of Rage (USA).md</path>
+		<image>./images/Streets of Rage (USA).png</image>
+	</game>
+</gameList>
+`
+	data, err := mem.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", FileName, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", FileName, data, want)
+	}
+
+	if _, found, err := ConvertToES(mem, filepath.Join(dir, "Hacks"), device_paths.Folder{}); found || err != nil {
+		t.Errorf("ConvertToES() without a game list = %v, %v; want nothing converted", found, err)
+	}
+}
@@ -0,0 +1,120 @@
+package gamelist
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+)
+
+// element is where an element sits in a document, so it can be cut out or replaced without disturbing the
+// formatting around it
+type element struct {
+	name string
+	// its 'lang' attribute, e.g. 'en' or 'fr', as multi-language scrapes mark the descriptions and genres of each
+	// language they give
+	lang string
+	// byte offsets of its start tag's '<' and just past its end tag's '>'
+	start int
+	end   int
+}
+
+// the elements directly inside the root element of data, and the offset of the root's end tag
+func childElements(data []byte) ([]element, int, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	children := make([]element, 0)
+	depth := 0
+	for {
+		start := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, 0, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth == 1 {
+				if err := decoder.Skip(); err != nil {
+					return nil, 0, err
+				}
+				child := element{name: token.Name.Local, start: start, end: int(decoder.InputOffset())}
+				for _, attr := range token.Attr {
+					if attr.Name.Local == "lang" {
+						child.lang = attr.Value
+					}
+				}
+				children = append(children, child)
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				return children, start, nil
+			}
+		}
+	}
+}
+
+// decodes the element in data if it's a game or folder entry
+func (e element) entry(data []byte) (Game, bool, error) {
+	var game Game
+	if e.name != "game" && e.name != "folder" {
+		return game, false, nil
+	}
+	err := xml.Unmarshal(data[e.start:e.end], &game)
+	return game, err == nil, err
+}
+
+// the whitespace immediately before offset
+func leadingSpace(data []byte, offset int) []byte {
+	start := offset
+	for start > 0 && (data[start-1] == ' ' || data[start-1] == '\t' || data[start-1] == '\r' || data[start-1] == '\n') {
+		start--
+	}
+	return data[start:offset]
+}
+
+// the edit removing data[start:end], taking its whole line with it if it's alone on its line
+func cut(data []byte, start int, end int) edit {
+	lineStart := start
+	for lineStart > 0 && (data[lineStart-1] == ' ' || data[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := end
+	for lineEnd < len(data) && (data[lineEnd] == ' ' || data[lineEnd] == '\t' || data[lineEnd] == '\r') {
+		lineEnd++
+	}
+	if (lineStart == 0 || data[lineStart-1] == '\n') && (lineEnd == len(data) || data[lineEnd] == '\n') {
+		if lineEnd < len(data) {
+			lineEnd++
+		}
+		return edit{start: lineStart, end: lineEnd}
+	}
+	return edit{start: start, end: end}
+}
+
+// a replacement of data[start:end] with text; an insertion if start and end are the same
+type edit struct {
+	start int
+	end   int
+	text  []byte
+}
+
+// applies edits, which mustn't overlap, to data
+func splice(data []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+
+	var spliced bytes.Buffer
+	written := 0
+	for _, e := range edits {
+		spliced.Write(data[written:e.start])
+		spliced.Write(e.text)
+		written = e.end
+	}
+	spliced.Write(data[written:])
+	return spliced.Bytes()
+}
@@ -0,0 +1,214 @@
+package gamelist
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// FillResult is what Fill changed in a game list
+type FillResult struct {
+	// games given an entry
+	Added int
+	// entries given details they were missing
+	Filled int
+}
+
+// the elements Fill gives each game, in the order it writes them, with the values info has for them
+func (info GameInfo) elements(folder device_paths.Folder) [][2]string {
+	image := ""
+	if info.Image != "" {
+		image = folder.Write(".", info.Image, folder.Rules.ListStyle)
+	}
+	return [][2]string{
+		{"name", info.Name},
+		{"image", image},
+		{"desc", info.Desc},
+		{"releasedate", info.ReleaseDate},
+		{"developer", info.Developer},
+		{"publisher", info.Publisher},
+		{"genre", info.Genre},
+	}
+}
+
+// Fill gives the games infos knows of, by their slash separated path relative to the platform folder, the details
+// the game list in data is missing: each game entry gets every detail it has no element for, or only an empty one,
+// and games without an entry get one after the last. Games infos has no name for are named by their file names
+// less tags. Paths are read and written by folder's rules.
+func Fill(data []byte, folder device_paths.Folder, infos map[string]GameInfo) ([]byte, FillResult, error) {
+	var result FillResult
+	children, rootEnd, err := childElements(data)
+	if err != nil {
+		return nil, result, err
+	}
+
+	edits := make([]edit, 0)
+	listed := make(map[string]bool)
+	for _, child := range children {
+		if child.name != "game" {
+			continue
+		}
+		entry := data[child.start:child.end]
+		game, _, err := child.entry(data)
+		if err != nil {
+			return nil, result, err
+		}
+		relPath, ok := folder.Read(game.Path)
+		if !ok {
+			continue
+		}
+		listed[relPath] = true
+		info, ok := infos[relPath]
+		if !ok {
+			continue
+		}
+
+		fields, fieldsEnd, err := childElements(entry)
+		if err != nil {
+			return nil, result, err
+		}
+		var added bytes.Buffer
+		indent := []byte("\n\t\t")
+		if len(fields) > 0 {
+			indent = leadingSpace(entry, fields[0].start)
+		}
+		filled := false
+		for _, detail := range info.elements(folder) {
+			if detail[1] == "" {
+				continue
+			}
+			present, empty := -1, false
+			for i, field := range fields {
+				if field.name == detail[0] {
+					present = i
+					empty = len(bytes.TrimSpace(innerText(entry[field.start:field.end]))) == 0
+					break
+				}
+			}
+			switch {
+			case present >= 0 && empty:
+				field := fields[present]
+				edits = append(edits, edit{start: child.start + field.start, end: child.start + field.end, text: textElement(detail[0], detail[1])})
+				filled = true
+			case present < 0:
+				added.Write(indent)
+				added.Write(textElement(detail[0], detail[1]))
+				filled = true
+			}
+		}
+		if added.Len() > 0 {
+			offset := child.start + fieldsEnd
+			if len(fields) > 0 {
+				offset = child.start + fields[len(fields)-1].end
+			}
+			edits = append(edits, edit{start: offset, end: offset, text: added.Bytes()})
+		}
+		if filled {
+			result.Filled++
+		}
+	}
+
+	unlisted := make([]string, 0)
+	for relPath := range infos {
+		if !listed[relPath] {
+			unlisted = append(unlisted, relPath)
+		}
+	}
+	sort.Strings(unlisted)
+	if len(unlisted) > 0 {
+		if !bytes.HasPrefix(data[rootEnd:], []byte("</")) {
+			return nil, result, fmt.Errorf("the list is an empty element with no end tag to add games before")
+		}
+		indent := []byte("\n\t")
+		offset := rootEnd - len(leadingSpace(data, rootEnd))
+		if len(children) > 0 {
+			indent = leadingSpace(data, children[0].start)
+			offset = children[len(children)-1].end
+		}
+		var added bytes.Buffer
+		for _, relPath := range unlisted {
+			info := infos[relPath]
+			if info.Name == "" {
+				info.Name = defaultName(relPath)
+			}
+			added.Write(indent)
+			added.WriteString("<game>")
+			fieldIndent := append(append([]byte{}, indent...), '\t')
+			added.Write(fieldIndent)
+			added.Write(textElement("path", folder.Write(".", relPath, folder.Rules.ListStyle)))
+			for _, detail := range info.elements(folder) {
+				if detail[1] != "" {
+					added.Write(fieldIndent)
+					added.Write(textElement(detail[0], detail[1]))
+				}
+			}
+			added.Write(indent)
+			added.WriteString("</game>")
+			result.Added++
+		}
+		edits = append(edits, edit{start: offset, end: offset, text: added.Bytes()})
+	}
+
+	if len(edits) == 0 {
+		return data, result, nil
+	}
+	return splice(data, edits), result, nil
+}
+
+// the text inside an element, undecoded
+func innerText(element []byte) []byte {
+	start := bytes.IndexByte(element, '>')
+	end := bytes.LastIndexByte(element, '<')
+	if start < 0 || end <= start {
+		// a self-closing element, e.g. '<image/>'
+		return nil
+	}
+	return element[start+1 : end]
+}
+
+// an element named name holding text
+func textElement(name string, text string) []byte {
+	var element bytes.Buffer
+	element.WriteString("<" + name + ">")
+	// writing to a buffer can't fail
+	_ = xml.EscapeText(&element, []byte(text))
+	element.WriteString("</" + name + ">")
+	return element.Bytes()
+}
+
+// FillList fills the gamelist.xml at the top of dir as Fill does. dir's own name is always one of the names folder
+// is known by. It returns false if dir has no gamelist.xml.
+func FillList(fs fsys.FS, dir string, folder device_paths.Folder, infos map[string]GameInfo) (FillResult, bool, error) {
+	var result FillResult
+	listPath := filepath.Join(dir, FileName)
+	info, err := fs.Stat(listPath)
+	if os.IsNotExist(err) {
+		return result, false, nil
+	}
+	if err != nil {
+		return result, false, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+	}
+	data, err := fs.ReadFile(listPath)
+	if err != nil {
+		return result, false, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+	}
+	folder.Names = append([]string{filepath.Base(dir)}, folder.Names...)
+
+	newData, result, err := Fill(data, folder, infos)
+	if err != nil {
+		return result, false, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+	}
+	if result.Added == 0 && result.Filled == 0 {
+		return result, true, nil
+	}
+	if err := fs.WriteFile(listPath, newData, info.Mode().Perm()); err != nil {
+		return result, false, fmt.Errorf("failed to write game list %s: %w", listPath, err)
+	}
+	return result, true, nil
+}
@@ -0,0 +1,110 @@
+package gamelist
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestFill(t *testing.T) {
+	list := `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<image></image>
+	</game>
+	<game>
+		<path>./Super Metroid (USA).sfc</path>
+		<name>Super Metroid</name>
+		<image>./images/Super Metroid (USA).png</image>
+	</game>
+	<folder>
+		<path>./hacks</path>
+	</folder>
+</gameList>
+`
+	infos := map[string]GameInfo{
+		"Zelda (USA).sfc": {Name: "The Legend of Zelda: A Link to the Past", Image: "images/Zelda (USA).png", Developer: "Nintendo EAD"},
+		// already has everything it's given
+		"Super Metroid (USA).sfc": {Name: "Super Metroid", Image: "images/other.png"},
+		"F-Zero (USA).sfc":        {Name: "F-Zero", Genre: "Racing & Driving", ReleaseDate: "19901121T000000"},
+		"Pilotwings (USA).sfc":    {},
+	}
+
+	filled, result, err := Fill([]byte(list), device_paths.Folder{}, infos)
+	if err != nil {
+		t.Fatalf("Fill() error = %v", err)
+	}
+	want := `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<image>./images/Zelda (USA).png</image>
+		<developer>Nintendo EAD</developer>
+	</game>
+	<game>
+		<path>./Super Metroid (USA).sfc</path>
+		<name>Super Metroid</name>
+		<image>./images/Super Metroid (USA).png</image>
+	</game>
+	<folder>
+		<path>./hacks</path>
+	</folder>
+	<game>
+		<path>./F-Zero (USA).sfc</path>
+		<name>F-Zero</name>
+		<releasedate>19901121T000000</releasedate>
+		<genre>Racing &amp; Driving</genre>
+	</game>
+	<game>
+		<path>./Pilotwings (USA).sfc</path>
+		<name>Pilotwings</name>
+	</game>
+</gameList>
+`
+	if string(filled) != want {
+		t.Errorf("Fill() =\n%s\nwant\n%s", filled, want)
+	}
+	if result.Added != 2 || result.Filled != 1 {
+		t.Errorf("Fill() = %+v, want 2 games added and 1 filled", result)
+	}
+
+	// an empty list gets its games indented like a generated one
+	filled, _, err = Fill([]byte("<gameList>\n</gameList>\n"), device_paths.Folder{}, map[string]GameInfo{"F-Zero (USA).sfc": {Name: "F-Zero"}})
+	if err != nil {
+		t.Fatalf("Fill() error = %v", err)
+	}
+	if want := "<gameList>\n\t<game>\n\t\t<path>./F-Zero (USA).sfc</path>\n\t\t<name>F-Zero</name>\n\t</game>\n</gameList>\n"; string(filled) != want {
+		t.Errorf("Fill() of an empty list =\n%s\nwant\n%s", filled, want)
+	}
+}
+
+func TestFillList(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	if err := mem.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	infos := map[string]GameInfo{"F-Zero (USA).sfc": {Name: "F-Zero"}}
+
+	if _, found, err := FillList(mem, dir, device_paths.Folder{}, infos); found || err != nil {
+		t.Fatalf("FillList() without a list = %v, %v; want false", found, err)
+	}
+
+	list := "<gameList>\n\t<game>\n\t\t<path>/userdata/roms/SFC/F-Zero (USA).sfc</path>\n\t</game>\n</gameList>\n"
+	if err := mem.WriteFile(filepath.Join(dir, FileName), []byte(list), 0644); err != nil {
+		t.Fatalf("failed to create %s: %v", FileName, err)
+	}
+	result, found, err := FillList(mem, dir, device_paths.Folder{}, infos)
+	if err != nil || !found || result.Filled != 1 {
+		t.Fatalf("FillList() = %+v, %v, %v; want the entry the folder's name finds filled", result, found, err)
+	}
+	want := "<gameList>\n\t<game>\n\t\t<path>/userdata/roms/SFC/F-Zero (USA).sfc</path>\n\t\t<name>F-Zero</name>\n\t</game>\n</gameList>\n"
+	if data, _ := mem.ReadFile(filepath.Join(dir, FileName)); string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", FileName, data, want)
+	}
+}
@@ -0,0 +1,159 @@
+package gamelist
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// FilterResult is what Filter removed from a game list
+type FilterResult struct {
+	// elements given in another language than the one asked for
+	OtherLanguages int
+	// elements not among the tags asked for
+	Stripped int
+}
+
+// Filter trims the game and folder entries of the game list in data. With lang set, an element given more than
+// once in different languages (e.g. '<desc lang="en">' and '<desc lang="fr">', as multi-language scrapes write
+// them) is kept only in lang, or if it isn't given in lang, only once: untranslated if it's given that way, or
+// else in the first language given. With keepTags set, only the elements named in it are kept, along with
+// '<path>', which every entry needs. Everything else in the list is left as it was.
+func Filter(data []byte, lang string, keepTags []string) ([]byte, FilterResult, error) {
+	var result FilterResult
+	children, _, err := childElements(data)
+	if err != nil {
+		return nil, result, err
+	}
+
+	cuts := make([]edit, 0)
+	for _, child := range children {
+		if child.name != "game" && child.name != "folder" {
+			continue
+		}
+		fields, _, err := childElements(data[child.start:child.end])
+		if err != nil {
+			return nil, result, err
+		}
+
+		keep := make([]bool, len(fields))
+		for i, field := range fields {
+			keep[i] = len(keepTags) == 0 || field.name == "path" || containsName(keepTags, field.name)
+			if !keep[i] {
+				result.Stripped++
+			}
+		}
+		if lang != "" {
+			for _, i := range otherLanguages(fields, lang) {
+				if keep[i] {
+					keep[i] = false
+					result.OtherLanguages++
+				}
+			}
+		}
+
+		for i, field := range fields {
+			if !keep[i] {
+				cuts = append(cuts, cut(data, child.start+field.start, child.start+field.end))
+			}
+		}
+	}
+
+	if len(cuts) == 0 {
+		return data, result, nil
+	}
+	return splice(data, cuts), result, nil
+}
+
+// the indexes of the fields to drop so that each one given in several languages is given only once, in lang
+// where possible
+func otherLanguages(fields []element, lang string) []int {
+	byName := make(map[string][]int)
+	for i, field := range fields {
+		byName[field.name] = append(byName[field.name], i)
+	}
+
+	drop := make([]int, 0)
+	for _, indexes := range byName {
+		translated := false
+		for _, i := range indexes {
+			translated = translated || fields[i].lang != ""
+		}
+		if len(indexes) < 2 || !translated {
+			continue
+		}
+
+		chosen := -1
+		for _, i := range indexes {
+			if languageMatches(fields[i].lang, lang) {
+				chosen = i
+				break
+			}
+		}
+		for _, i := range indexes {
+			if chosen < 0 && fields[i].lang == "" {
+				chosen = i
+			}
+		}
+		if chosen < 0 {
+			chosen = indexes[0]
+		}
+
+		for _, i := range indexes {
+			if i != chosen {
+				drop = append(drop, i)
+			}
+		}
+	}
+	return drop
+}
+
+// whether an element's language is the one wanted, ignoring case and regional variants, so 'en' matches
+// 'en-US' and 'en_GB', and 'pt-BR' matches 'pt'
+func languageMatches(elementLang string, wanted string) bool {
+	normalize := func(lang string) string {
+		return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "_", "-")
+	}
+	elementLang, wanted = normalize(elementLang), normalize(wanted)
+	if elementLang == "" || wanted == "" {
+		return false
+	}
+	return elementLang == wanted || strings.HasPrefix(elementLang, wanted+"-") || strings.HasPrefix(wanted, elementLang+"-")
+}
+
+// FilterLists filters each game list at the top of dir (gamelist.xml, and variants like Miyoo's
+// miyoogamelist.xml) as Filter does, returning what was removed from each list that changed, by list name
+func FilterLists(fs fsys.FS, dir string, lang string, keepTags []string) (map[string]FilterResult, error) {
+	lists, err := listNames(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make(map[string]FilterResult)
+	for _, name := range lists {
+		listPath := filepath.Join(dir, name)
+		info, err := fs.Stat(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+		data, err := fs.ReadFile(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+
+		newData, result, err := Filter(data, lang, keepTags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+		}
+		if result.OtherLanguages == 0 && result.Stripped == 0 {
+			continue
+		}
+		if err := fs.WriteFile(listPath, newData, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write game list %s: %w", listPath, err)
+		}
+		filtered[name] = result
+	}
+	return filtered, nil
+}
@@ -0,0 +1,141 @@
+package gamelist
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+const multiLanguageList = `<?xml version="1.0"?>
+<gameList>
+	<provider>
+		<System>Super Nintendo</System>
+	</provider>
+	<game id="1">
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<desc lang="fr">L'aventure de Link</desc>
+		<desc lang="en-US">Link's adventure</desc>
+		<genre lang="fr">Aventure</genre>
+		<genre>Adventure</genre>
+		<rating>0.9</rating>
+		<md5>abc</md5>
+	</game>
+	<game>
+		<path>./Tetris (World).sfc</path>
+		<name>Tetris</name>
+		<desc lang="de">Klötzchen</desc><desc lang="ja">テトリス</desc>
+	</game>
+</gameList>
+`
+
+func TestFilter(t *testing.T) {
+	filtered, result, err := Filter([]byte(multiLanguageList), "en", nil)
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	want := `<?xml version="1.0"?>
+<gameList>
+	<provider>
+		<System>Super Nintendo</System>
+	</provider>
+	<game id="1">
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<desc lang="en-US">Link's adventure</desc>
+		<genre>Adventure</genre>
+		<rating>0.9</rating>
+		<md5>abc</md5>
+	</game>
+	<game>
+		<path>./Tetris (World).sfc</path>
+		<name>Tetris</name>
+		<desc lang="de">Klötzchen</desc>
+	</game>
+</gameList>
+`
+	if string(filtered) != want {
+		t.Errorf("Filter() =\n%s\nwant\n%s", filtered, want)
+	}
+	if result.OtherLanguages != 3 || result.Stripped != 0 {
+		t.Errorf("Filter() = %+v, want 3 elements in other languages dropped", result)
+	}
+
+	stripped, result, err := Filter([]byte(multiLanguageList), "fr", []string{"name", "desc", "genre"})
+	if err != nil {
+		t.Fatalf("Filter() error = %v", err)
+	}
+	wantStripped := `<?xml version="1.0"?>
+<gameList>
+	<provider>
+		<System>Super Nintendo</System>
+	</provider>
+	<game id="1">
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<desc lang="fr">L'aventure de Link</desc>
+		<genre lang="fr">Aventure</genre>
+	</game>
+	<game>
+		<path>./Tetris (World).sfc</path>
+		<name>Tetris</name>
+		<desc lang="de">Klötzchen</desc>
+	</game>
+</gameList>
+`
+	if string(stripped) != wantStripped {
+		t.Errorf("Filter() keeping tags =\n%s\nwant\n%s", stripped, wantStripped)
+	}
+	if result.OtherLanguages != 3 || result.Stripped != 2 {
+		t.Errorf("Filter() keeping tags = %+v, want 3 in other languages and 2 stripped", result)
+	}
+
+	if unchanged, result, err := Filter([]byte(deviceList), "en", nil); err != nil || string(unchanged) != deviceList || result != (FilterResult{}) {
+		t.Errorf("Filter() of a single-language list = %+v, %v; want it unchanged", result, err)
+	}
+}
+
+func TestLanguageMatches(t *testing.T) {
+	tests := []struct {
+		elementLang string
+		wanted      string
+		want        bool
+	}{
+		{"en", "en", true},
+		{"EN_us", "en", true},
+		{"pt", "pt-BR", true},
+		{"en", "es", false},
+		{"eng", "en", false},
+		{"", "en", false},
+	}
+	for _, tt := range tests {
+		if got := languageMatches(tt.elementLang, tt.wanted); got != tt.want {
+			t.Errorf("languageMatches(%q, %q) = %v, want %v", tt.elementLang, tt.wanted, got, tt.want)
+		}
+	}
+}
+
+func TestFilterLists(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	if err := mem.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := mem.WriteFile(filepath.Join(dir, MiyooFileName), []byte(multiLanguageList), 0644); err != nil {
+		t.Fatalf("failed to create game list: %v", err)
+	}
+
+	filtered, err := FilterLists(mem, dir, "en", []string{"name"})
+	if err != nil {
+		t.Fatalf("FilterLists() error = %v", err)
+	}
+	if result := filtered[MiyooFileName]; result.OtherLanguages != 0 || result.Stripped != 8 {
+		t.Errorf("FilterLists() = %+v, want 8 elements stripped from %s", filtered, MiyooFileName)
+	}
+
+	again, err := FilterLists(mem, dir, "en", []string{"name"})
+	if err != nil || len(again) != 0 {
+		t.Errorf("FilterLists() of a filtered list = %+v, %v; want nothing changed", again, err)
+	}
+}
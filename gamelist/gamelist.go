@@ -0,0 +1,174 @@
+package gamelist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/disc_refs"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// FileName is the game list EmulationStation (and its forks, e.g. ES-DE and Batocera) keeps in each platform folder
+const FileName = "gamelist.xml"
+
+// GameList is a parsed EmulationStation gamelist.xml
+type GameList struct {
+	Games []Game `xml:"game"`
+	// folders shown as a single entry, e.g. a multi-file game kept in its own folder
+	Folders []Game `xml:"folder"`
+}
+
+// Game is a single entry in a game list. Paths are as the list writes them: usually relative to the platform
+// folder and starting with './', but sometimes absolute paths on the device the list was scraped on.
+type Game struct {
+	Path      string `xml:"path"`
+	Name      string `xml:"name"`
+	Image     string `xml:"image"`
+	Video     string `xml:"video"`
+	Marquee   string `xml:"marquee"`
+	Thumbnail string `xml:"thumbnail"`
+	Favorite  bool   `xml:"favorite"`
+}
+
+// Parse reads a game list
+func Parse(r io.Reader) (*GameList, error) {
+	var list GameList
+	if err := xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// Load reads the game list in dir, returning nil if it doesn't have one
+func Load(fs fsys.FS, dir string) (*GameList, error) {
+	filePath := filepath.Join(dir, FileName)
+	file, err := fs.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open game list %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	list, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse game list %s: %w", filePath, err)
+	}
+	return list, nil
+}
+
+// Entries returns every game and folder in the list
+func (l *GameList) Entries() []Game {
+	return append(append([]Game{}, l.Games...), l.Folders...)
+}
+
+// Favorites returns the games and folders marked <favorite>true</favorite>
+func (l *GameList) Favorites() []Game {
+	favorites := make([]Game, 0)
+	for _, game := range l.Entries() {
+		if game.Favorite {
+			favorites = append(favorites, game)
+		}
+	}
+	return favorites
+}
+
+// Files returns the paths the entry refers to: the game itself and its image, video, marquee, and thumbnail
+func (g Game) Files() []string {
+	files := make([]string, 0, 5)
+	for _, file := range []string{g.Path, g.Image, g.Video, g.Marquee, g.Thumbnail} {
+		if strings.TrimSpace(file) != "" {
+			files = append(files, strings.TrimSpace(file))
+		}
+	}
+	return files
+}
+
+// resolves listPath against a folder known by any of folderNames; empty paths don't resolve
+func resolve(listPath string, folderNames []string) (string, bool) {
+	return device_paths.Folder{Names: folderNames}.Read(listPath)
+}
+
+// IsListName reports whether name is a game list: gamelist.xml, or a variant like Miyoo's miyoogamelist.xml
+func IsListName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), FileName)
+}
+
+// the names of the game lists at the top of dir
+func listNames(fs fsys.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	names := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && IsListName(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// ReferencedFiles returns the files in the platform folder dir that the entries in its game list need, or with
+// favoritesOnly, just its favorites, as slash separated paths relative to dir: each entry, its artwork and video,
+// the game list itself, and the discs and tracks of entries that are '.m3u' playlists or cue sheets. It returns
+// nil if dir has no game list.
+func ReferencedFiles(fs fsys.FS, dir string, favoritesOnly bool) (map[string]bool, error) {
+	list, err := Load(fs, dir)
+	if list == nil || err != nil {
+		return nil, err
+	}
+
+	games := list.Entries()
+	if favoritesOnly {
+		games = list.Favorites()
+	}
+
+	files := map[string]bool{FileName: true}
+	pending := make([]string, 0)
+	for _, game := range games {
+		for _, listPath := range game.Files() {
+			if relPath, ok := resolve(listPath, []string{filepath.Base(dir)}); ok {
+				pending = append(pending, relPath)
+			}
+		}
+	}
+
+	// sheets can refer to other sheets (a playlist of cue sheets), so references are followed until none are new
+	for len(pending) > 0 {
+		relPath := pending[0]
+		pending = pending[1:]
+		if files[relPath] {
+			continue
+		}
+		files[relPath] = true
+		if !disc_refs.IsSheet(relPath) {
+			continue
+		}
+
+		data, err := fs.ReadFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+		for _, ref := range disc_refs.References(relPath, data) {
+			if refPath, ok := device_paths.Resolve(path.Dir(relPath), ref); ok {
+				pending = append(pending, refPath)
+			}
+		}
+	}
+	return files, nil
+}
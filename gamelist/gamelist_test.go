@@ -0,0 +1,119 @@
+package gamelist
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+const testList = `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Super Metroid (Japan, USA) (En,Ja).sfc</path>
+		<name>Super Metroid</name>
+		<image>./media/images/Super Metroid (Japan, USA) (En,Ja).png</image>
+		<video>./media/videos/Super Metroid (Japan, USA) (En,Ja).mp4</video>
+		<favorite>true</favorite>
+	</game>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<image>./media/images/Zelda (USA).png</image>
+	</game>
+	<game>
+		<path>./Final Fantasy VII (USA).m3u</path>
+		<favorite>true</favorite>
+	</game>
+	<game>
+		<path>/home/pi/RetroPie/roms/snes/Tetris (World).sfc</path>
+		<favorite> true </favorite>
+	</game>
+	<folder>
+		<path>./Chrono Trigger</path>
+		<favorite>true</favorite>
+	</folder>
+</gameList>
+`
+
+func TestParseFavorites(t *testing.T) {
+	list, err := Parse(strings.NewReader(testList))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(list.Games) != 4 || len(list.Folders) != 1 {
+		t.Fatalf("Parse() found %d games and %d folders, want 4 and 1", len(list.Games), len(list.Folders))
+	}
+
+	names := make([]string, 0)
+	for _, game := range list.Favorites() {
+		names = append(names, game.Path)
+	}
+	want := []string{
+		"./Super Metroid (Japan, USA) (En,Ja).sfc",
+		"./Final Fantasy VII (USA).m3u",
+		"/home/pi/RetroPie/roms/snes/Tetris (World).sfc",
+		"./Chrono Trigger",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Favorites() = %v, want %v", names, want)
+	}
+}
+
+func TestReferencedFiles(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "snes")
+	files := map[string]string{
+		FileName:                                 testList,
+		"Final Fantasy VII (USA).m3u":            "discs/FF7 (Disc 1).cue\ndiscs/FF7 (Disc 2).cue\n",
+		"discs/FF7 (Disc 1).cue":                 "FILE \"FF7 (Disc 1).bin\" BINARY\n",
+		"discs/FF7 (Disc 2).cue":                 "FILE \"FF7 (Disc 2).bin\" BINARY\n",
+		"Super Metroid (Japan, USA) (En,Ja).sfc": "rom",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		if err := mem.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	got, err := ReferencedFiles(mem, dir, true)
+	if err != nil {
+		t.Fatalf("ReferencedFiles() error = %v", err)
+	}
+	want := map[string]bool{
+		FileName:                                 true,
+		"Super Metroid (Japan, USA) (En,Ja).sfc": true,
+		"media/images/Super Metroid (Japan, USA) (En,Ja).png": true,
+		"media/videos/Super Metroid (Japan, USA) (En,Ja).mp4": true,
+		"Final Fantasy VII (USA).m3u":                         true,
+		"discs/FF7 (Disc 1).cue":                              true,
+		"discs/FF7 (Disc 1).bin":                              true,
+		"discs/FF7 (Disc 2).cue":                              true,
+		"discs/FF7 (Disc 2).bin":                              true,
+		"Tetris (World).sfc":                                  true,
+		"Chrono Trigger":                                      true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReferencedFiles() favorites = %v, want %v", got, want)
+	}
+
+	all, err := ReferencedFiles(mem, dir, false)
+	if err != nil {
+		t.Fatalf("ReferencedFiles() error = %v", err)
+	}
+	want["Zelda (USA).sfc"] = true
+	want["media/images/Zelda (USA).png"] = true
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("ReferencedFiles() = %v, want %v", all, want)
+	}
+
+	if got, err := ReferencedFiles(mem, filepath.Join(t.TempDir(), "gba"), false); got != nil || err != nil {
+		t.Errorf("ReferencedFiles() without a game list = %v, %v; want nil, nil", got, err)
+	}
+}
@@ -0,0 +1,162 @@
+package gamelist
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/disc_refs"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+	"github.com/jkingsman/ROMCopyEngine/romtags"
+)
+
+// the box art Generate looks for, in the order it prefers them
+var imageExtensions = []string{".png", ".jpg", ".jpeg"}
+
+// GameInfo is what's known about a game from elsewhere than its file name, e.g. a LaunchBox library
+type GameInfo struct {
+	Name string
+	Desc string
+	// as EmulationStation writes it, e.g. '19920413T000000'
+	ReleaseDate string
+	Developer   string
+	Publisher   string
+	Genre       string
+	// its box art, slash separated and relative to the platform folder; empty to look for art named like the game
+	Image string
+}
+
+// GenerateResult is what Generate wrote
+type GenerateResult struct {
+	Games int
+	// games given the box art found for them
+	Images int
+}
+
+// Generate writes a gamelist.xml to dir listing every game in it, for platform folders that were never scraped.
+// isGame tells games apart from other files by their path relative to dir; discs and tracks a playlist or cue
+// sheet refers to are left to it. Each game is named by its title, its file name before any tags, e.g. 'Super
+// Metroid' for 'Super Metroid (Japan, USA) (En,Ja).sfc', and given the image named like it in imageDir, a folder
+// relative to dir, if there is one. Games describe knows of, by their slash separated path relative to dir, are
+// given the name and details it returns instead; describe may be nil. It does nothing and returns false if dir
+// already has a gamelist.xml. Paths are written as folder's rules have game lists write them.
+func Generate(fs fsys.FS, dir string, folder device_paths.Folder, isGame func(relPath string) bool, imageDir string, describe func(relPath string) (GameInfo, bool)) (GenerateResult, bool, error) {
+	var result GenerateResult
+	listPath := filepath.Join(dir, FileName)
+	if _, err := fs.Stat(listPath); !os.IsNotExist(err) {
+		if err != nil {
+			return result, false, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+		return result, false, nil
+	}
+
+	games, err := Games(fs, dir, isGame)
+	if err != nil {
+		return result, false, err
+	}
+
+	list := minimalList{Games: make([]minimalGame, 0, len(games))}
+	for _, relPath := range games {
+		game := minimalGame{Path: folder.Write(".", relPath, folder.Rules.ListStyle), Name: defaultName(relPath)}
+		image := ""
+		if describe != nil {
+			if info, ok := describe(relPath); ok {
+				if info.Name != "" {
+					game.Name = info.Name
+				}
+				game.Desc, game.ReleaseDate, game.Developer, game.Publisher, game.Genre = info.Desc, info.ReleaseDate, info.Developer, info.Publisher, info.Genre
+				image = info.Image
+			}
+		}
+		if image == "" {
+			image = findImage(fs, dir, imageDir, relPath)
+		}
+		if image != "" {
+			game.Image = folder.Write(".", image, folder.Rules.ListStyle)
+			result.Images++
+		}
+		list.Games = append(list.Games, game)
+	}
+	result.Games = len(list.Games)
+
+	data, err := list.encode()
+	if err != nil {
+		return result, false, fmt.Errorf("failed to encode %s: %w", FileName, err)
+	}
+	if err := fs.WriteFile(listPath, data, 0644); err != nil {
+		return result, false, fmt.Errorf("failed to write game list %s: %w", listPath, err)
+	}
+	return result, true, nil
+}
+
+// Games returns the games in dir a frontend lists, slash separated and relative to dir, in the order they're
+// walked. isGame tells games apart from other files by their path relative to dir; discs and tracks a playlist or
+// cue sheet refers to are left to it.
+func Games(fs fsys.FS, dir string, isGame func(relPath string) bool) ([]string, error) {
+	candidates := make([]string, 0)
+	err := fsys.Walk(fs, dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && isGame(relPath) {
+			candidates = append(candidates, filepath.ToSlash(relPath))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	// a disc listed in a playlist, or a track in a cue sheet, is launched through it rather than on its own
+	referenced := make(map[string]bool)
+	for _, relPath := range candidates {
+		if !disc_refs.IsSheet(relPath) {
+			continue
+		}
+		data, err := fs.ReadFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+		}
+		for _, ref := range disc_refs.References(relPath, data) {
+			if refPath, ok := device_paths.Resolve(path.Dir(relPath), ref); ok {
+				referenced[refPath] = true
+			}
+		}
+	}
+
+	games := make([]string, 0, len(candidates))
+	for _, relPath := range candidates {
+		if !referenced[relPath] {
+			games = append(games, relPath)
+		}
+	}
+	return games, nil
+}
+
+// the box art named like the game at relPath in imageDir, both relative to dir, slash separated; empty if there's
+// none
+func findImage(fs fsys.FS, dir string, imageDir string, relPath string) string {
+	stem := strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+	for _, ext := range imageExtensions {
+		imagePath := path.Join(filepath.ToSlash(imageDir), stem+ext)
+		if _, err := fs.Stat(filepath.Join(dir, filepath.FromSlash(imagePath))); err == nil {
+			return imagePath
+		}
+	}
+	return ""
+}
+
+// the name a game is listed under when nothing better is known: its file name before any tags
+func defaultName(relPath string) string {
+	if title := romtags.Parse(path.Base(relPath)).Title; title != "" {
+		return title
+	}
+	return strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+}
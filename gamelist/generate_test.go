@@ -0,0 +1,132 @@
+package gamelist
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestGenerate(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "PS")
+	files := map[string]string{
+		"Crash Bandicoot (USA).chd":                      "",
+		"Dr. Mario (Japan) [!].cue":                      "FILE \"Dr. Mario (Japan) [!].bin\" BINARY\n",
+		"Dr. Mario (Japan) [!].bin":                      "",
+		"Final Fantasy VII (USA).m3u":                    "multidisc/Final Fantasy VII (USA) (Disc 1).chd\nmultidisc/Final Fantasy VII (USA) (Disc 2).chd\n",
+		"multidisc/Final Fantasy VII (USA) (Disc 1).chd": "",
+		"multidisc/Final Fantasy VII (USA) (Disc 2).chd": "",
+		"images/Crash Bandicoot (USA).jpg":               "",
+		"images/Final Fantasy VII (USA).png":             "",
+		"images/Final Fantasy VII (USA).jpg":             "",
+		"readme.txt":                                     "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := mem.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+	}
+	isGame := func(relPath string) bool {
+		switch path.Ext(relPath) {
+		case ".chd", ".cue", ".bin", ".m3u":
+			return path.Dir(filepath.ToSlash(relPath)) != "images"
+		}
+		return false
+	}
+
+	result, generated, err := Generate(mem, dir, device_paths.Folder{}, isGame, "images", nil)
+	if err != nil || !generated {
+		t.Fatalf("Generate() = %v, %v; want a game list generated", generated, err)
+	}
+	if result.Games != 3 || result.Images != 2 {
+		t.Errorf("Generate() = %+v, want 3 games, 2 with images", result)
+	}
+
+	want := `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Crash Bandicoot (USA).chd</path>
+		<name>Crash Bandicoot</name>
+		<image>./images/Crash Bandicoot (USA).jpg</image>
+	</game>
+	<game>
+		<path>./Dr. Mario (Japan) [!].cue</path>
+		<name>Dr. Mario</name>
+	</game>
+	<game>
+		<path>./Final Fantasy VII (USA).m3u</path>
+		<name>Final Fantasy VII</name>
+		<image>./images/Final Fantasy VII (USA).png</image>
+	</game>
+</gameList>
+`
+	data, err := mem.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", FileName, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", FileName, data, want)
+	}
+
+	// an existing list, scraped or generated, is left alone
+	if _, generated, err := Generate(mem, dir, device_paths.Folder{}, isGame, "images", nil); generated || err != nil {
+		t.Errorf("Generate() over an existing list = %v, %v; want nothing generated", generated, err)
+	}
+
+	// details from elsewhere, e.g. a LaunchBox library, replace the title from the file name
+	if err := mem.Remove(filepath.Join(dir, FileName)); err != nil {
+		t.Fatalf("failed to remove %s: %v", FileName, err)
+	}
+	describe := func(relPath string) (GameInfo, bool) {
+		if relPath == "Dr. Mario (Japan) [!].cue" {
+			// art found elsewhere, e.g. scraped, is used as it is
+			return GameInfo{Image: "scraped/Dr. Mario.png"}, true
+		}
+		if relPath != "Crash Bandicoot (USA).chd" {
+			return GameInfo{}, false
+		}
+		return GameInfo{Name: "Crash Bandicoot: Warped", Desc: "Time travel.", ReleaseDate: "19981031T000000", Developer: "Naughty Dog"}, true
+	}
+	if _, generated, err := Generate(mem, dir, device_paths.Folder{}, isGame, "images", describe); !generated || err != nil {
+		t.Fatalf("Generate() with details = %v, %v; want a game list generated", generated, err)
+	}
+	wantCrash := `	<game>
+		<path>./Crash Bandicoot (USA).chd</path>
+		<name>Crash Bandicoot: Warped</name>
+		<image>./images/Crash Bandicoot (USA).jpg</image>
+		<desc>Time travel.</desc>
+		<releasedate>19981031T000000</releasedate>
+		<developer>Naughty Dog</developer>
+	</game>
+	<game>
+		<path>./Dr. Mario (Japan) [!].cue</path>
+		<name>Dr. Mario</name>
+		<image>./scraped/Dr. Mario.png</image>
+	</game>`
+	if data, err := mem.ReadFile(filepath.Join(dir, FileName)); err != nil || !strings.Contains(string(data), wantCrash) {
+		t.Errorf("%s with details =\n%s\nwant it to contain\n%s", FileName, data, wantCrash)
+	}
+
+	// a device that wants absolute paths gets them, where it mounts the target
+	if err := mem.Remove(filepath.Join(dir, FileName)); err != nil {
+		t.Fatalf("failed to remove %s: %v", FileName, err)
+	}
+	folder := device_paths.Folder{Path: "Roms/PS", Rules: device_paths.Rules{MountPath: "/mnt/SDCARD", ListStyle: device_paths.Absolute}}
+	if _, generated, err := Generate(mem, dir, folder, isGame, "images", nil); !generated || err != nil {
+		t.Fatalf("Generate() with absolute paths = %v, %v; want a game list generated", generated, err)
+	}
+	wantAbsolute := `		<path>/mnt/SDCARD/Roms/PS/Crash Bandicoot (USA).chd</path>
+		<name>Crash Bandicoot</name>
+		<image>/mnt/SDCARD/Roms/PS/images/Crash Bandicoot (USA).jpg</image>`
+	if data, err := mem.ReadFile(filepath.Join(dir, FileName)); err != nil || !strings.Contains(string(data), wantAbsolute) {
+		t.Errorf("%s with absolute paths =\n%s\nwant it to contain\n%s", FileName, data, wantAbsolute)
+	}
+}
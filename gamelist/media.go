@@ -0,0 +1,155 @@
+package gamelist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// MediaResult is what RemapMedia changed in a game list
+type MediaResult struct {
+	// entries pointed at artwork that was moved
+	Remapped int
+	// elements removed because they referred to media that was left behind
+	Dropped int
+}
+
+// RemapMedia points the game and folder entries of the game list in data at where their media was moved. moved
+// maps the slash separated paths, relative to the platform folder, that files were copied from to where they
+// were copied to. Each element referring to a moved file is pointed at its new path, and so is the entry's
+// '<image>', which is added if the entry has none, so a frontend that only shows images shows the moved art.
+// Elements referring to files in any of leftOut, folders that weren't copied, are removed. Paths are read and
+// written by folder's rules.
+func RemapMedia(data []byte, folder device_paths.Folder, moved map[string]string, leftOut []string) ([]byte, MediaResult, error) {
+	var result MediaResult
+	children, _, err := childElements(data)
+	if err != nil {
+		return nil, result, err
+	}
+
+	edits := make([]edit, 0)
+	for _, child := range children {
+		if child.name != "game" && child.name != "folder" {
+			continue
+		}
+		fields, _, err := childElements(data[child.start:child.end])
+		if err != nil {
+			return nil, result, err
+		}
+
+		image, pathField := -1, -1
+		art := ""
+		fieldEdits := make(map[int]edit)
+		dropped := make(map[int]bool)
+		for i, field := range fields {
+			switch field.name {
+			case "path":
+				pathField = i
+				continue
+			case "image":
+				image = i
+			}
+
+			var value struct {
+				Text string `xml:",chardata"`
+			}
+			if err := xml.Unmarshal(data[child.start+field.start:child.start+field.end], &value); err != nil {
+				return nil, result, err
+			}
+			relPath, ok := folder.Read(value.Text)
+			if !ok {
+				continue
+			}
+			if newPath, ok := moved[relPath]; ok {
+				if art == "" || field.name == "image" {
+					art = newPath
+				}
+				fieldEdits[i] = edit{start: child.start + field.start, end: child.start + field.end, text: mediaElement(field.name, folder, newPath)}
+			} else if withinAny(relPath, leftOut) {
+				fieldEdits[i] = cut(data, child.start+field.start, child.start+field.end)
+				dropped[i] = true
+			}
+		}
+
+		if art != "" {
+			result.Remapped++
+			switch {
+			case image >= 0:
+				// pointed at the moved art instead of being dropped, if it referred to media left behind
+				delete(dropped, image)
+				field := fields[image]
+				fieldEdits[image] = edit{start: child.start + field.start, end: child.start + field.end, text: mediaElement("image", folder, art)}
+			case pathField >= 0:
+				field := fields[pathField]
+				offset := child.start + field.end
+				text := append(append([]byte{}, leadingSpace(data, child.start+field.start)...), mediaElement("image", folder, art)...)
+				edits = append(edits, edit{start: offset, end: offset, text: text})
+			}
+		}
+		result.Dropped += len(dropped)
+		for _, e := range fieldEdits {
+			edits = append(edits, e)
+		}
+	}
+
+	if len(edits) == 0 {
+		return data, result, nil
+	}
+	return splice(data, edits), result, nil
+}
+
+// an element named name referring to relPath
+func mediaElement(name string, folder device_paths.Folder, relPath string) []byte {
+	return textElement(name, folder.Write(".", relPath, folder.Rules.ListStyle))
+}
+
+// whether the slash separated relPath is within any of dirs, ignoring case
+func withinAny(relPath string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(strings.ToLower(relPath), strings.ToLower(dir)+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// RemapMediaLists remaps each game list at the top of dir (gamelist.xml, and variants like Miyoo's
+// miyoogamelist.xml) as RemapMedia does, returning what changed in each list that changed, by list name. dir's
+// own name is always one of the names folder is known by.
+func RemapMediaLists(fs fsys.FS, dir string, folder device_paths.Folder, moved map[string]string, leftOut []string) (map[string]MediaResult, error) {
+	lists, err := listNames(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	folder.Names = append([]string{filepath.Base(dir)}, folder.Names...)
+
+	remapped := make(map[string]MediaResult)
+	for _, name := range lists {
+		listPath := filepath.Join(dir, name)
+		info, err := fs.Stat(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+		data, err := fs.ReadFile(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+
+		newData, result, err := RemapMedia(data, folder, moved, leftOut)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+		}
+		if result.Remapped == 0 && result.Dropped == 0 {
+			continue
+		}
+		if err := fs.WriteFile(listPath, newData, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write game list %s: %w", listPath, err)
+		}
+		remapped[name] = result
+	}
+	return remapped, nil
+}
@@ -0,0 +1,87 @@
+package gamelist
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+const skraperList = `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<image>./media/screenshot/Zelda (USA).png</image>
+		<thumbnail>./media/box2d/Zelda (USA).png</thumbnail>
+		<video>./media/video/Zelda (USA).mp4</video>
+	</game>
+	<game>
+		<path>./Tetris &amp; Dr. Mario (USA).sfc</path>
+		<name>Tetris &amp; Dr. Mario</name>
+		<marquee>/home/pi/RetroPie/roms/snes/media/box2d/Tetris &amp; Dr. Mario (USA).png</marquee>
+	</game>
+	<game>
+		<path>./Mario Paint (USA).sfc</path>
+		<name>Mario Paint</name>
+		<image>./images/Mario Paint (USA).png</image>
+	</game>
+</gameList>
+`
+
+func TestRemapMedia(t *testing.T) {
+	moved := map[string]string{
+		"media/box2d/Zelda (USA).png":              "Imgs/Zelda (USA).png",
+		"media/box2d/Tetris & Dr. Mario (USA).png": "Imgs/Tetris & Dr. Mario (USA).png",
+	}
+	leftOut := []string{"media/screenshot", "media/video"}
+
+	remapped, result, err := RemapMedia([]byte(skraperList), device_paths.Folder{Names: []string{"snes"}}, moved, leftOut)
+	if err != nil {
+		t.Fatalf("RemapMedia() error = %v", err)
+	}
+	want := `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<image>./Imgs/Zelda (USA).png</image>
+		<thumbnail>./Imgs/Zelda (USA).png</thumbnail>
+	</game>
+	<game>
+		<path>./Tetris &amp; Dr. Mario (USA).sfc</path>
+		<image>./Imgs/Tetris &amp; Dr. Mario (USA).png</image>
+		<name>Tetris &amp; Dr. Mario</name>
+		<marquee>./Imgs/Tetris &amp; Dr. Mario (USA).png</marquee>
+	</game>
+	<game>
+		<path>./Mario Paint (USA).sfc</path>
+		<name>Mario Paint</name>
+		<image>./images/Mario Paint (USA).png</image>
+	</game>
+</gameList>
+`
+	if string(remapped) != want {
+		t.Errorf("RemapMedia() =\n%s\nwant\n%s", remapped, want)
+	}
+	if result.Remapped != 2 || result.Dropped != 1 {
+		t.Errorf("RemapMedia() = %+v, want 2 entries remapped and the video dropped", result)
+	}
+
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	if err := mem.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := mem.WriteFile(filepath.Join(dir, FileName), []byte(skraperList), 0644); err != nil {
+		t.Fatalf("failed to create %s: %v", FileName, err)
+	}
+	lists, err := RemapMediaLists(mem, dir, device_paths.Folder{Names: []string{"snes"}}, moved, leftOut)
+	if err != nil || lists[FileName] != result {
+		t.Fatalf("RemapMediaLists() = %v, %v; want %s remapped", lists, err, FileName)
+	}
+	if data, _ := mem.ReadFile(filepath.Join(dir, FileName)); string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", FileName, data, want)
+	}
+}
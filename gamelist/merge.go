@@ -0,0 +1,228 @@
+package gamelist
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// how Merge settles a game that's in both lists
+const (
+	// the device's entry is kept as it is
+	MergeKeepDevice = "device"
+	// the source's entry replaces the device's, but the device's play data is kept
+	MergeKeepSource = "source"
+)
+
+var MergePolicies = []string{MergeKeepDevice, MergeKeepSource}
+
+// what the device records about playing a game, as opposed to scraped metadata; kept from the device's entry
+// when the source's replaces it. 'gametime' is Batocera's, 'timeplayed' ES-DE's.
+var playDataElements = []string{"favorite", "hidden", "playcount", "lastplayed", "gametime", "timeplayed"}
+
+// MergeResult is what Merge changed in the device's list
+type MergeResult struct {
+	// games only in the source's list, added to the device's
+	Added int
+	// games in both lists whose device entry was replaced
+	Updated int
+}
+
+// Merge merges the game and folder entries of the source's game list into the device's, matching entries by
+// path, and returns the merged list. Games only in the source's list are added after the device's entries, and
+// games in both are settled by policy, one of MergePolicies. Everything else in the device's list, including
+// entries for games only on the device, is left as it was. Absolute paths are resolved against any of
+// folderNames, the names the platform folder is known by.
+func Merge(device []byte, source []byte, policy string, folderNames ...string) ([]byte, MergeResult, error) {
+	var result MergeResult
+
+	deviceChildren, deviceEnd, err := childElements(device)
+	if err != nil {
+		return nil, result, fmt.Errorf("device's game list: %w", err)
+	}
+	sourceChildren, _, err := childElements(source)
+	if err != nil {
+		return nil, result, fmt.Errorf("source's game list: %w", err)
+	}
+
+	key := func(data []byte, child element) (string, error) {
+		game, ok, err := child.entry(data)
+		if !ok || err != nil {
+			return "", err
+		}
+		relPath, ok := resolve(game.Path, folderNames)
+		if !ok {
+			relPath = game.Path
+		}
+		return child.name + ":" + relPath, nil
+	}
+
+	onDevice := make(map[string]element)
+	for _, child := range deviceChildren {
+		k, err := key(device, child)
+		if err != nil {
+			return nil, result, fmt.Errorf("device's game list: %w", err)
+		}
+		if _, seen := onDevice[k]; k != "" && !seen {
+			onDevice[k] = child
+		}
+	}
+
+	edits := make([]edit, 0)
+	added := make([][]byte, 0)
+	addedKeys := make(map[string]bool)
+	for _, child := range sourceChildren {
+		k, err := key(source, child)
+		if err != nil {
+			return nil, result, fmt.Errorf("source's game list: %w", err)
+		}
+		if k == "" || addedKeys[k] {
+			continue
+		}
+
+		entry := source[child.start:child.end]
+		existing, found := onDevice[k]
+		if !found {
+			added = append(added, entry)
+			addedKeys[k] = true
+			continue
+		}
+		if policy == MergeKeepDevice {
+			continue
+		}
+
+		deviceEntry := device[existing.start:existing.end]
+		merged, err := withPlayData(entry, deviceEntry)
+		if err != nil {
+			return nil, result, fmt.Errorf("device's game list: %w", err)
+		}
+		if !bytes.Equal(merged, deviceEntry) {
+			edits = append(edits, edit{start: existing.start, end: existing.end, text: merged})
+			result.Updated++
+		}
+	}
+
+	if len(added) > 0 {
+		// new entries follow the device's last one, laid out the same way
+		at, separator := deviceEnd, []byte("\n\t")
+		if len(deviceChildren) > 0 {
+			last := deviceChildren[len(deviceChildren)-1]
+			at, separator = last.end, leadingSpace(device, last.start)
+		}
+
+		var text bytes.Buffer
+		for _, entry := range added {
+			text.Write(separator)
+			text.Write(entry)
+		}
+		if len(deviceChildren) == 0 {
+			text.Write(leadingSpace(device, deviceEnd))
+		}
+		edits = append(edits, edit{start: at, end: at, text: text.Bytes()})
+		result.Added = len(added)
+	}
+
+	if len(edits) == 0 {
+		return device, result, nil
+	}
+	return splice(device, edits), result, nil
+}
+
+// returns the source's entry with the play data elements in the device's entry copied over it, replacing the
+// source's own where it has them
+func withPlayData(sourceEntry []byte, deviceEntry []byte) ([]byte, error) {
+	sourceChildren, sourceEnd, err := childElements(sourceEntry)
+	if err != nil {
+		return nil, err
+	}
+	deviceChildren, _, err := childElements(deviceEntry)
+	if err != nil {
+		return nil, err
+	}
+
+	bySourceName := make(map[string]element)
+	for _, child := range sourceChildren {
+		bySourceName[child.name] = child
+	}
+
+	// elements the source doesn't have go after its last one, laid out the same way
+	at, separator := sourceEnd, []byte{}
+	if len(sourceChildren) > 0 {
+		last := sourceChildren[len(sourceChildren)-1]
+		at, separator = last.end, leadingSpace(sourceEntry, last.start)
+	}
+
+	edits := make([]edit, 0)
+	for _, child := range deviceChildren {
+		if !containsName(playDataElements, child.name) {
+			continue
+		}
+		value := deviceEntry[child.start:child.end]
+		if existing, ok := bySourceName[child.name]; ok {
+			edits = append(edits, edit{start: existing.start, end: existing.end, text: value})
+			continue
+		}
+		edits = append(edits, edit{start: at, end: at, text: append(append([]byte{}, separator...), value...)})
+	}
+	return splice(sourceEntry, edits), nil
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadLists returns the contents of each game list at the top of dir, by name, so they can be merged into once
+// a copy has replaced them
+func ReadLists(fs fsys.FS, dir string) (map[string][]byte, error) {
+	names, err := listNames(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make(map[string][]byte)
+	for _, name := range names {
+		data, err := fs.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", filepath.Join(dir, name), err)
+		}
+		lists[name] = data
+	}
+	return lists, nil
+}
+
+// MergeInto merges the game list now at the top of dir named name into its earlier contents, device, as Merge
+// does, and writes the result in its place. It does nothing if the list is unchanged or gone.
+func MergeInto(fs fsys.FS, dir string, name string, device []byte, policy string, folderNames ...string) (MergeResult, error) {
+	listPath := filepath.Join(dir, name)
+	info, err := fs.Stat(listPath)
+	if os.IsNotExist(err) {
+		return MergeResult{}, nil
+	}
+	if err != nil {
+		return MergeResult{}, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+	}
+	source, err := fs.ReadFile(listPath)
+	if err != nil {
+		return MergeResult{}, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+	}
+	if bytes.Equal(source, device) {
+		return MergeResult{}, nil
+	}
+
+	merged, result, err := Merge(device, source, policy, append([]string{filepath.Base(dir)}, folderNames...)...)
+	if err != nil {
+		return result, fmt.Errorf("failed to merge game list %s: %w", listPath, err)
+	}
+	if err := fs.WriteFile(listPath, merged, info.Mode().Perm()); err != nil {
+		return result, fmt.Errorf("failed to write game list %s: %w", listPath, err)
+	}
+	return result, nil
+}
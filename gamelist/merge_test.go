@@ -0,0 +1,137 @@
+package gamelist
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+const deviceList = `<?xml version="1.0"?>
+<gameList>
+	<game>
+		<path>./Zelda (USA).sfc</path>
+		<name>Zelda</name>
+		<playcount>12</playcount>
+		<favorite>true</favorite>
+	</game>
+	<game>
+		<path>/userdata/roms/snes/Tetris (World).sfc</path>
+		<name>Tetris</name>
+	</game>
+	<game>
+		<path>./Homebrew.sfc</path>
+	</game>
+</gameList>
+`
+
+const sourceList = `<?xml version="1.0"?>
+<gameList>
+  <game>
+    <path>./Zelda (USA).sfc</path>
+    <name>The Legend of Zelda</name>
+    <favorite>false</favorite>
+  </game>
+  <game>
+    <path>./Tetris (World).sfc</path>
+    <name>Tetris</name>
+  </game>
+  <game>
+    <path>./Metroid (USA).sfc</path>
+  </game>
+</gameList>
+`
+
+func TestMerge(t *testing.T) {
+	merged, result, err := Merge([]byte(deviceList), []byte(sourceList), MergeKeepSource, "snes")
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+
+	want := `<?xml version="1.0"?>
+<gameList>
+	<game>
+    <path>./Zelda (USA).sfc</path>
+    <name>The Legend of Zelda</name>
+    <favorite>true</favorite>
+    <playcount>12</playcount>
+  </game>
+	<game>
+    <path>./Tetris (World).sfc</path>
+    <name>Tetris</name>
+  </game>
+	<game>
+		<path>./Homebrew.sfc</path>
+	</game>
+	<game>
+    <path>./Metroid (USA).sfc</path>
+  </game>
+</gameList>
+`
+	if string(merged) != want {
+		t.Errorf("Merge() =\n%s\nwant\n%s", merged, want)
+	}
+	if result.Added != 1 || result.Updated != 2 {
+		t.Errorf("Merge() = %+v, want 1 added and 2 updated", result)
+	}
+
+	kept, result, err := Merge([]byte(deviceList), []byte(sourceList), MergeKeepDevice, "snes")
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	wantKept := deviceList[:len(deviceList)-len("</gameList>\n")] + "\t<game>\n    <path>./Metroid (USA).sfc</path>\n  </game>\n</gameList>\n"
+	if string(kept) != wantKept {
+		t.Errorf("Merge() keeping the device's entries =\n%s\nwant\n%s", kept, wantKept)
+	}
+	if result.Added != 1 || result.Updated != 0 {
+		t.Errorf("Merge() keeping the device's entries = %+v, want 1 added", result)
+	}
+
+	empty, _, err := Merge([]byte("<gameList>\n</gameList>\n"), []byte(sourceList), MergeKeepDevice)
+	if err != nil {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if list, err := Parse(bytes.NewReader(empty)); err != nil || len(list.Games) != 3 {
+		t.Errorf("Merge() into an empty list = %s, want all 3 source games", empty)
+	}
+}
+
+func TestMergeInto(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	listPath := filepath.Join(dir, FileName)
+	if err := mem.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := mem.WriteFile(listPath, []byte(deviceList), 0644); err != nil {
+		t.Fatalf("failed to create game list: %v", err)
+	}
+
+	lists, err := ReadLists(mem, dir)
+	if err != nil || len(lists) != 1 {
+		t.Fatalf("ReadLists() = %v, %v; want the device's list", lists, err)
+	}
+
+	// unchanged by the copy
+	if result, err := MergeInto(mem, dir, FileName, lists[FileName], MergeKeepDevice); err != nil || result.Added != 0 {
+		t.Errorf("MergeInto() of an unchanged list = %+v, %v; want nothing merged", result, err)
+	}
+
+	if err := mem.WriteFile(listPath, []byte(sourceList), 0644); err != nil {
+		t.Fatalf("failed to overwrite game list: %v", err)
+	}
+	result, err := MergeInto(mem, dir, FileName, lists[FileName], MergeKeepDevice, "snes")
+	if err != nil || result.Added != 1 {
+		t.Fatalf("MergeInto() = %+v, %v; want 1 added", result, err)
+	}
+
+	data, err := mem.ReadFile(listPath)
+	if err != nil {
+		t.Fatalf("failed to read merged list: %v", err)
+	}
+	list, err := Parse(bytes.NewReader(data))
+	if err != nil || len(list.Games) != 4 || list.Games[0].Name != "Zelda" {
+		t.Errorf("merged list = %s, want the device's 3 games and Metroid", data)
+	}
+}
@@ -0,0 +1,84 @@
+package gamelist
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// Prune removes the game and folder entries that keep rejects from the game list in data, leaving everything
+// else (comments, formatting, and elements this package doesn't know about) as it was. Entries on lines of their
+// own are removed along with their line. It returns the new list and the entries removed.
+func Prune(data []byte, keep func(Game) bool) ([]byte, []Game, error) {
+	cuts := make([]edit, 0)
+	removed := make([]Game, 0)
+
+	children, _, err := childElements(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, child := range children {
+		game, ok, err := child.entry(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok || keep(game) {
+			continue
+		}
+
+		cuts = append(cuts, cut(data, child.start, child.end))
+		removed = append(removed, game)
+	}
+
+	if len(cuts) == 0 {
+		return data, removed, nil
+	}
+	return splice(data, cuts), removed, nil
+}
+
+// PruneMissing removes the entries whose game isn't in dir from each game list at the top of dir (gamelist.xml,
+// and variants like Miyoo's miyoogamelist.xml), returning the entries removed from each by list name. Absolute
+// paths are resolved against any of folderNames, the names the platform folder is known by on the device the
+// list was written on; entries whose path can't be resolved are kept.
+func PruneMissing(fs fsys.FS, dir string, folderNames ...string) (map[string][]Game, error) {
+	lists, err := listNames(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	exists := func(game Game) bool {
+		relPath, ok := resolve(game.Path, append([]string{filepath.Base(dir)}, folderNames...))
+		if !ok {
+			return true
+		}
+		_, err := fs.Stat(filepath.Join(dir, filepath.FromSlash(relPath)))
+		return err == nil
+	}
+
+	pruned := make(map[string][]Game)
+	for _, name := range lists {
+		listPath := filepath.Join(dir, name)
+		info, err := fs.Stat(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+		data, err := fs.ReadFile(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+
+		newData, removed, err := Prune(data, exists)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		if err := fs.WriteFile(listPath, newData, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write game list %s: %w", listPath, err)
+		}
+		pruned[name] = removed
+	}
+	return pruned, nil
+}
@@ -0,0 +1,99 @@
+package gamelist
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestPrune(t *testing.T) {
+	list := "<?xml version=\"1.0\"?>\n" +
+		"<gameList>\n" +
+		"\t<!-- scraped 2024-03-01 -->\n" +
+		"\t<provider><System>snes</System></provider>\n" +
+		"\t<game id=\"1\">\n\t\t<path>./Zelda (USA).sfc</path>\n\t\t<name>Zelda</name>\n\t</game>\n" +
+		"\t<game>\r\n\t\t<path>./Missing (USA).sfc</path>\r\n\t</game>\r\n" +
+		"\t<folder><path>./Chrono Trigger</path></folder>\n" +
+		"\t<game><path>./Gone.sfc</path></game><game><path>./Kept.sfc</path></game>\n" +
+		"</gameList>\n"
+
+	keep := func(game Game) bool {
+		return game.Path == "./Zelda (USA).sfc" || game.Path == "./Kept.sfc"
+	}
+	pruned, removed, err := Prune([]byte(list), keep)
+	if err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+
+	want := "<?xml version=\"1.0\"?>\n" +
+		"<gameList>\n" +
+		"\t<!-- scraped 2024-03-01 -->\n" +
+		"\t<provider><System>snes</System></provider>\n" +
+		"\t<game id=\"1\">\n\t\t<path>./Zelda (USA).sfc</path>\n\t\t<name>Zelda</name>\n\t</game>\n" +
+		"\t<game><path>./Kept.sfc</path></game>\n" +
+		"</gameList>\n"
+	if string(pruned) != want {
+		t.Errorf("Prune() =\n%s\nwant\n%s", pruned, want)
+	}
+
+	paths := make([]string, 0)
+	for _, game := range removed {
+		paths = append(paths, game.Path)
+	}
+	if wantRemoved := []string{"./Missing (USA).sfc", "./Chrono Trigger", "./Gone.sfc"}; !reflect.DeepEqual(paths, wantRemoved) {
+		t.Errorf("Prune() removed %v, want %v", paths, wantRemoved)
+	}
+
+	if _, _, err := Prune([]byte("<gameList><game>"), keep); err == nil {
+		t.Error("Prune() of a truncated list didn't fail")
+	}
+}
+
+func TestPruneMissing(t *testing.T) {
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	files := map[string]string{
+		FileName:                                 testList,
+		"miyoogamelist.xml":                      "<gameList><game><path>./Gone.sfc</path></game></gameList>",
+		"Super Metroid (Japan, USA) (En,Ja).sfc": "rom",
+		"Tetris (World).sfc":                     "rom",
+		"Chrono Trigger/Chrono Trigger.cue":      "sheet",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := mem.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		if err := mem.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create file %s: %v", name, err)
+		}
+	}
+
+	pruned, err := PruneMissing(mem, dir, "snes")
+	if err != nil {
+		t.Fatalf("PruneMissing() error = %v", err)
+	}
+	if len(pruned[FileName]) != 2 || len(pruned["miyoogamelist.xml"]) != 1 {
+		t.Fatalf("PruneMissing() = %v, want 2 entries removed from %s and 1 from miyoogamelist.xml", pruned, FileName)
+	}
+
+	data, err := mem.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatalf("failed to read pruned list: %v", err)
+	}
+	list, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to parse pruned list: %v", err)
+	}
+	paths := make([]string, 0)
+	for _, game := range list.Entries() {
+		paths = append(paths, game.Path)
+	}
+	want := []string{"./Super Metroid (Japan, USA) (En,Ja).sfc", "/home/pi/RetroPie/roms/snes/Tetris (World).sfc", "./Chrono Trigger"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("pruned list has %v, want %v", paths, want)
+	}
+}
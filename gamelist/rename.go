@@ -0,0 +1,100 @@
+package gamelist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// RenamePaths points the game and folder entries of the game list in data at the new names of files and folders
+// renamed as they were copied. rename returns the new path of a file or folder from the one the list knows it
+// by, both slash separated and relative to the platform folder; each element referring to one it gives a new
+// path, its '<path>' or media, is pointed at the new path, written by folder's rules. It returns the number of
+// entries changed.
+func RenamePaths(data []byte, folder device_paths.Folder, rename func(relPath string) string) ([]byte, int, error) {
+	children, _, err := childElements(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	changed := 0
+	edits := make([]edit, 0)
+	for _, child := range children {
+		if child.name != "game" && child.name != "folder" {
+			continue
+		}
+		fields, _, err := childElements(data[child.start:child.end])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		entryChanged := false
+		for _, field := range fields {
+			if !pathElements[field.name] {
+				continue
+			}
+			var value struct {
+				Text string `xml:",chardata"`
+			}
+			if err := xml.Unmarshal(data[child.start+field.start:child.start+field.end], &value); err != nil {
+				return nil, 0, err
+			}
+			relPath, ok := folder.Read(value.Text)
+			if !ok {
+				continue
+			}
+			if newPath := rename(relPath); newPath != relPath {
+				edits = append(edits, edit{start: child.start + field.start, end: child.start + field.end, text: mediaElement(field.name, folder, newPath)})
+				entryChanged = true
+			}
+		}
+		if entryChanged {
+			changed++
+		}
+	}
+
+	if len(edits) == 0 {
+		return data, 0, nil
+	}
+	return splice(data, edits), changed, nil
+}
+
+// RenamePathsLists renames the paths in each game list at the top of dir (gamelist.xml, and variants like
+// Miyoo's miyoogamelist.xml) as RenamePaths does, returning the number of entries changed in each list that
+// changed, by list name. dir's own name is always one of the names folder is known by.
+func RenamePathsLists(fs fsys.FS, dir string, folder device_paths.Folder, rename func(relPath string) string) (map[string]int, error) {
+	lists, err := listNames(fs, dir)
+	if err != nil {
+		return nil, err
+	}
+	folder.Names = append([]string{filepath.Base(dir)}, folder.Names...)
+
+	changed := make(map[string]int)
+	for _, name := range lists {
+		listPath := filepath.Join(dir, name)
+		info, err := fs.Stat(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+		data, err := fs.ReadFile(listPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read game list %s: %w", listPath, err)
+		}
+
+		newData, entries, err := RenamePaths(data, folder, rename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse game list %s: %w", listPath, err)
+		}
+		if entries == 0 {
+			continue
+		}
+		if err := fs.WriteFile(listPath, newData, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to write game list %s: %w", listPath, err)
+		}
+		changed[name] = entries
+	}
+	return changed, nil
+}
@@ -0,0 +1,85 @@
+package gamelist
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/device_paths"
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestRenamePaths(t *testing.T) {
+	list := `<?xml version="1.0"?>
+<gameList>
+	<folder>
+		<path>./Hacks: Vol. 1</path>
+		<name>Hacks</name>
+	</folder>
+	<game>
+		<path>./Zelda: A Link to the Past.sfc</path>
+		<name>Zelda: A Link to the Past</name>
+		<image>./Imgs/Zelda: A Link to the Past.png</image>
+	</game>
+	<game>
+		<path>/home/pi/RetroPie/roms/snes/Hacks: Vol. 1/Mario?.sfc</path>
+		<name>Mario?</name>
+	</game>
+	<game>
+		<path>./Contra.sfc</path>
+		<name>Contra</name>
+	</game>
+</gameList>
+`
+	rename := func(relPath string) string {
+		return strings.NewReplacer(":", "_", "?", "_").Replace(relPath)
+	}
+
+	got, changed, err := RenamePaths([]byte(list), device_paths.Folder{Names: []string{"snes"}}, rename)
+	if err != nil {
+		t.Fatalf("RenamePaths() error = %v", err)
+	}
+	want := `<?xml version="1.0"?>
+<gameList>
+	<folder>
+		<path>./Hacks_ Vol. 1</path>
+		<name>Hacks</name>
+	</folder>
+	<game>
+		<path>./Zelda_ A Link to the Past.sfc</path>
+		<name>Zelda: A Link to the Past</name>
+		<image>./Imgs/Zelda_ A Link to the Past.png</image>
+	</game>
+	<game>
+		<path>./Hacks_ Vol. 1/Mario_.sfc</path>
+		<name>Mario?</name>
+	</game>
+	<game>
+		<path>./Contra.sfc</path>
+		<name>Contra</name>
+	</game>
+</gameList>
+`
+	if string(got) != want {
+		t.Errorf("RenamePaths() =\n%s\nwant\n%s", got, want)
+	}
+	if changed != 3 {
+		t.Errorf("RenamePaths() changed %d entries, want 3", changed)
+	}
+
+	mem := fsys.NewMemFS()
+	dir := filepath.Join(t.TempDir(), "SFC")
+	if err := mem.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := mem.WriteFile(filepath.Join(dir, FileName), []byte(list), 0644); err != nil {
+		t.Fatalf("failed to create %s: %v", FileName, err)
+	}
+	lists, err := RenamePathsLists(mem, dir, device_paths.Folder{Names: []string{"snes"}}, rename)
+	if err != nil || lists[FileName] != 3 {
+		t.Fatalf("RenamePathsLists() = %v, %v; want 3 entries of %s changed", lists, err, FileName)
+	}
+	if data, _ := mem.ReadFile(filepath.Join(dir, FileName)); string(data) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", FileName, data, want)
+	}
+}
@@ -4,4 +4,12 @@ go 1.20
 
 require github.com/alecthomas/kong v1.7.0
 
-require github.com/bmatcuk/doublestar/v4 v4.8.1
+require (
+	github.com/bmatcuk/doublestar/v4 v4.8.1
+	github.com/cespare/xxhash/v2 v2.3.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+	lukechampine.com/blake3 v1.3.0
+)
+
+require github.com/klauspost/cpuid/v2 v2.0.9 // indirect
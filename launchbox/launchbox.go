@@ -0,0 +1,267 @@
+package launchbox
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Game is a game in a LaunchBox library
+type Game struct {
+	ID       string
+	Title    string
+	Platform string
+	// as LaunchBox names it, e.g. 'North America'; see Regions
+	Region string
+	// zero if LaunchBox doesn't know
+	Released  time.Time
+	Developer string
+	Publisher string
+	// LaunchBox separates several genres with ';', e.g. 'Action; Platform'
+	Genre string
+	// the description LaunchBox shows
+	Notes string
+	// the file LaunchBox launches, as it records it: usually relative to the LaunchBox folder, with backslashes
+	ApplicationPath string
+}
+
+// Library is the games of a LaunchBox install, as listed in its Data/Platforms folder
+type Library struct {
+	dir   string
+	games []Game
+	// indexes into games, by the lowercase file name of each game's ApplicationPath
+	byFile map[string][]int
+	// image paths by platform, then by lowercase name before the '-01' number LaunchBox gives each image
+	images map[string]map[string][]string
+}
+
+// mirrors the platform XML files LaunchBox keeps in Data/Platforms
+type xmlLaunchBox struct {
+	Games []xmlGame `xml:"Game"`
+}
+
+type xmlGame struct {
+	ID              string `xml:"ID"`
+	Title           string `xml:"Title"`
+	Platform        string `xml:"Platform"`
+	Region          string `xml:"Region"`
+	ReleaseDate     string `xml:"ReleaseDate"`
+	Developer       string `xml:"Developer"`
+	Publisher       string `xml:"Publisher"`
+	Genre           string `xml:"Genre"`
+	Notes           string `xml:"Notes"`
+	ApplicationPath string `xml:"ApplicationPath"`
+}
+
+// the kinds of front box art LaunchBox keeps in each platform's images folder, in the order they're preferred
+var boxArtFolders = []string{"Box - Front", "Box - Front - Reconstructed", "Fanart - Box - Front"}
+
+// regions LaunchBox names differently from No-Intro and Redump file names, by lowercase LaunchBox name
+var regionNames = map[string]string{
+	"north america":   "USA",
+	"united states":   "USA",
+	"united kingdom":  "UK",
+	"the netherlands": "Netherlands",
+	"south korea":     "Korea",
+}
+
+// Load reads every platform XML in the Data/Platforms folder of the LaunchBox install at dir
+func Load(dir string) (*Library, error) {
+	platformsDir := filepath.Join(dir, "Data", "Platforms")
+	entries, err := os.ReadDir(platformsDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s; is %s a LaunchBox folder? %w", platformsDir, dir, err)
+	}
+
+	library := &Library{dir: dir, byFile: make(map[string][]int), images: make(map[string]map[string][]string)}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".xml") {
+			continue
+		}
+		filePath := filepath.Join(platformsDir, entry.Name())
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open LaunchBox platform %s: %w", filePath, err)
+		}
+		games, err := Parse(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse LaunchBox platform %s: %w", filePath, err)
+		}
+		for _, game := range games {
+			library.add(game)
+		}
+	}
+	return library, nil
+}
+
+// Parse reads the games from a LaunchBox platform XML
+func Parse(r io.Reader) ([]Game, error) {
+	var raw xmlLaunchBox
+	if err := xml.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	games := make([]Game, 0, len(raw.Games))
+	for _, rawGame := range raw.Games {
+		game := Game{
+			ID:              strings.TrimSpace(rawGame.ID),
+			Title:           strings.TrimSpace(rawGame.Title),
+			Platform:        strings.TrimSpace(rawGame.Platform),
+			Region:          strings.TrimSpace(rawGame.Region),
+			Developer:       strings.TrimSpace(rawGame.Developer),
+			Publisher:       strings.TrimSpace(rawGame.Publisher),
+			Genre:           strings.TrimSpace(rawGame.Genre),
+			Notes:           strings.TrimSpace(rawGame.Notes),
+			ApplicationPath: strings.TrimSpace(rawGame.ApplicationPath),
+		}
+		if released, err := time.Parse(time.RFC3339, strings.TrimSpace(rawGame.ReleaseDate)); err == nil {
+			game.Released = released
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+func (l *Library) add(game Game) {
+	if game.ApplicationPath == "" {
+		return
+	}
+	fileName := strings.ToLower(fileBase(game.ApplicationPath))
+	l.byFile[fileName] = append(l.byFile[fileName], len(l.games))
+	l.games = append(l.games, game)
+}
+
+// Len returns the number of games in the library
+func (l *Library) Len() int {
+	return len(l.games)
+}
+
+// Find returns the game that launches a file of the given name, compared case-insensitively. Where games on
+// several platforms launch files of that name, the first loaded is returned.
+func (l *Library) Find(fileName string) (Game, bool) {
+	indexes := l.byFile[strings.ToLower(fileName)]
+	if len(indexes) == 0 {
+		return Game{}, false
+	}
+	return l.games[indexes[0]], true
+}
+
+// the file name at the end of an ApplicationPath, which LaunchBox writes with Windows separators
+func fileBase(applicationPath string) string {
+	applicationPath = strings.ReplaceAll(applicationPath, "\\", "/")
+	return applicationPath[strings.LastIndex(applicationPath, "/")+1:]
+}
+
+// Regions returns the game's regions as No-Intro and Redump file names name them, e.g. 'USA' for LaunchBox's
+// 'North America'. Empty if LaunchBox doesn't know.
+func (g Game) Regions() []string {
+	regions := make([]string, 0)
+	for _, region := range strings.Split(g.Region, ",") {
+		region = strings.TrimSpace(region)
+		if region == "" {
+			continue
+		}
+		if name, ok := regionNames[strings.ToLower(region)]; ok {
+			region = name
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// FileName returns the game's title made safe to name a file with on any filesystem, followed by ext, e.g.
+// 'Zelda - A Link to the Past.sfc' for 'Zelda: A Link to the Past'
+func (g Game) FileName(ext string) string {
+	name := strings.ReplaceAll(g.Title, ": ", " - ")
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+	return strings.TrimRight(name, ". ") + ext
+}
+
+// LaunchBox names image files and folders after titles and platforms with the characters it can't use in file
+// names, and apostrophes, replaced with '_'
+func imageName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*'`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// BoxArt returns the path of the game's front box art in the library's Images folder, preferring the first
+// image of the first of boxArtFolders to have any, or "" if it has none
+func (l *Library) BoxArt(game Game) (string, error) {
+	images, err := l.platformImages(game.Platform)
+	if err != nil {
+		return "", err
+	}
+	found := images[strings.ToLower(imageName(game.Title))]
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0], nil
+}
+
+// indexes a platform's box art by the name LaunchBox gives it, so each game's can be found without searching
+// the whole folder; built the first time the platform's art is looked for
+func (l *Library) platformImages(platform string) (map[string][]string, error) {
+	if images, ok := l.images[platform]; ok {
+		return images, nil
+	}
+
+	images := make(map[string][]string)
+	rank := make(map[string]int)
+	for folderRank, folder := range boxArtFolders {
+		folderPath := filepath.Join(l.dir, "Images", imageName(platform), folder)
+		err := filepath.Walk(folderPath, func(filePath string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			stem := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+			dash := strings.LastIndex(stem, "-")
+			if dash < 0 {
+				return nil
+			}
+			// newer versions of LaunchBox add the game's ID, e.g. 'Zelda.0ac2e2a9-...-01.png'
+			name := strings.ToLower(stem[:dash])
+			if dot := strings.LastIndex(name, "."); dot >= 0 && len(name)-dot == 37 {
+				name = name[:dot]
+			}
+			images[name] = append(images[name], filePath)
+			rank[filePath] = folderRank
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LaunchBox images in %s: %w", folderPath, err)
+		}
+	}
+
+	for name := range images {
+		found := images[name]
+		sort.SliceStable(found, func(i, j int) bool {
+			if rank[found[i]] != rank[found[j]] {
+				return rank[found[i]] < rank[found[j]]
+			}
+			return filepath.Base(found[i]) < filepath.Base(found[j])
+		})
+	}
+	l.images[platform] = images
+	return images, nil
+}
@@ -0,0 +1,104 @@
+package launchbox
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testPlatform = `<?xml version="1.0" standalone="yes"?>
+<LaunchBox>
+  <Game>
+    <ApplicationPath>..\Games\Super Nintendo\zelda3.sfc</ApplicationPath>
+    <Developer>Nintendo EAD</Developer>
+    <Genre>Action; Adventure</Genre>
+    <ID>0ac2e2a9-6b5a-4e36-8d66-4f2a1bc8e3a1</ID>
+    <Notes>Link's adventure.</Notes>
+    <Platform>Super Nintendo Entertainment System</Platform>
+    <Publisher>Nintendo</Publisher>
+    <Region>North America</Region>
+    <ReleaseDate>1992-04-13T00:00:00-07:00</ReleaseDate>
+    <Title>The Legend of Zelda: A Link to the Past</Title>
+  </Game>
+  <Game>
+    <ApplicationPath>D:\Games\Super Nintendo\Kirby's Dream Land 3 (Japan).sfc</ApplicationPath>
+    <ID>f1d5c0b2-1d1e-4c9a-9d0c-6f5e4a3b2c1d</ID>
+    <Platform>Super Nintendo Entertainment System</Platform>
+    <Region>Japan</Region>
+    <Title>Kirby's Dream Land 3</Title>
+  </Game>
+  <AdditionalApplication>
+    <ApplicationPath>..\Games\Super Nintendo\manual.pdf</ApplicationPath>
+  </AdditionalApplication>
+</LaunchBox>
+`
+
+func writeFile(t *testing.T, filePath string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create %s: %v", filePath, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Data", "Platforms", "Super Nintendo Entertainment System.xml"), testPlatform)
+	images := filepath.Join(dir, "Images", "Super Nintendo Entertainment System")
+	for _, name := range []string{
+		"Box - Front/North America/The Legend of Zelda_ A Link to the Past-02.png",
+		"Box - Front/North America/The Legend of Zelda_ A Link to the Past-01.png",
+		"Fanart - Box - Front/The Legend of Zelda_ A Link to the Past-01.jpg",
+		"Box - Front - Reconstructed/Kirby_s Dream Land 3.f1d5c0b2-1d1e-4c9a-9d0c-6f5e4a3b2c1d-01.jpg",
+		"Screenshot - Gameplay/Kirby_s Dream Land 3-01.png",
+	} {
+		writeFile(t, filepath.Join(images, filepath.FromSlash(name)), "art")
+	}
+
+	library, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if library.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2 games", library.Len())
+	}
+
+	zelda, ok := library.Find("ZELDA3.sfc")
+	if !ok {
+		t.Fatal("Find() didn't find zelda3.sfc by its file name")
+	}
+	if zelda.Title != "The Legend of Zelda: A Link to the Past" || zelda.Developer != "Nintendo EAD" || zelda.Notes != "Link's adventure." {
+		t.Errorf("Find() = %+v, want Zelda's details", zelda)
+	}
+	if want := time.Date(1992, 4, 13, 7, 0, 0, 0, time.UTC); !zelda.Released.Equal(want) {
+		t.Errorf("Released = %v, want %v", zelda.Released, want)
+	}
+	if regions := zelda.Regions(); !reflect.DeepEqual(regions, []string{"USA"}) {
+		t.Errorf("Regions() = %v, want [USA]", regions)
+	}
+	if name := zelda.FileName(".sfc"); name != "The Legend of Zelda - A Link to the Past.sfc" {
+		t.Errorf("FileName() = %q", name)
+	}
+	if _, ok := library.Find("manual.pdf"); ok {
+		t.Error("Find() found an additional application, want only games")
+	}
+
+	art, err := library.BoxArt(zelda)
+	if err != nil || art != filepath.Join(images, "Box - Front", "North America", "The Legend of Zelda_ A Link to the Past-01.png") {
+		t.Errorf("BoxArt() = %q, %v; want the first front box art", art, err)
+	}
+
+	kirby, _ := library.Find("Kirby's Dream Land 3 (Japan).sfc")
+	art, err = library.BoxArt(kirby)
+	if err != nil || art != filepath.Join(images, "Box - Front - Reconstructed", "Kirby_s Dream Land 3.f1d5c0b2-1d1e-4c9a-9d0c-6f5e4a3b2c1d-01.jpg") {
+		t.Errorf("BoxArt() = %q, %v; want the reconstructed box art named with the game's ID", art, err)
+	}
+
+	if _, err := Load(t.TempDir()); err == nil {
+		t.Error("Load() of a folder that isn't a LaunchBox install succeeded")
+	}
+}
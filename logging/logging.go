@@ -2,6 +2,17 @@ package logging
 
 import "fmt"
 
+// whether per-file detail is logged; set from '--verbose'
+var verbose bool
+
+func SetVerbose(enabled bool) {
+	verbose = enabled
+}
+
+func IsVerbose() bool {
+	return verbose
+}
+
 // log level == indentation
 type LogLevel int
 
@@ -26,6 +37,7 @@ const (
 	IconReplace  = "🔀"
 	IconRewrite  = "🔀"
 	IconClean    = "🧹"
+	IconUndo     = "↩️"
 	IconError    = "❌"
 )
 
@@ -42,32 +54,85 @@ func getIndentation(level LogLevel) string {
 
 // log message with icon and level
 func Log(level LogLevel, icon, message string, args ...interface{}) {
+	formatted := fmt.Sprintf(message, args...)
+	writeRecord(levelName(level), formatted)
+
+	clearProgress()
 	indent := getIndentation(level)
 	if icon != "" {
-		fmt.Printf("%s%s %s\n", indent, icon, fmt.Sprintf(message, args...))
+		fmt.Printf("%s%s %s\n", indent, icon, formatted)
 	} else {
-		fmt.Printf("%s%s\n", indent, fmt.Sprintf(message, args...))
+		fmt.Printf("%s%s\n", indent, formatted)
 	}
 }
 
+// same as Log but only printed when verbose logging is enabled; used for per-file output. Always
+// written to the structured log.
+func LogVerbose(level LogLevel, icon, message string, args ...interface{}) {
+	if verbose {
+		Log(level, icon, message, args...)
+		return
+	}
+	writeRecord(levelName(level), fmt.Sprintf(message, args...))
+}
+
 // same as Log but with [DRY RUN] prefix
 func LogDryRun(level LogLevel, icon, message string, args ...interface{}) {
+	formatted := fmt.Sprintf(message, args...)
+	writeRecord("dryRun", formatted)
+
+	clearProgress()
 	indent := getIndentation(level)
 	if icon != "" {
-		fmt.Printf("%s%s [DRY RUN] %s\n", indent, icon, fmt.Sprintf(message, args...))
+		fmt.Printf("%s%s [DRY RUN] %s\n", indent, icon, formatted)
 	} else {
-		fmt.Printf("%s[DRY RUN] %s\n", indent, fmt.Sprintf(message, args...))
+		fmt.Printf("%s[DRY RUN] %s\n", indent, formatted)
 	}
 }
 
+// warnings and errors carry the current mapping and operation IDs so they can be traced back later in a run
 func LogWarning(message string, args ...interface{}) {
-	fmt.Printf("%s WARNING %s\n", IconWarning, fmt.Sprintf(message, args...))
+	formatted := fmt.Sprintf(message, args...)
+	writeRecord("warning", formatted)
+
+	clearProgress()
+	fmt.Printf("%s WARNING %s\n", IconWarning, withTag(formatted))
 }
 
 func LogComplete(message string) {
+	writeRecord("complete", message+" complete!")
+
+	clearProgress()
 	fmt.Printf("%s%s complete!\n", getIndentation(Action), message)
 }
 
 func LogError(message string, args ...interface{}) {
-	fmt.Printf("%s %s\n", IconError, fmt.Sprintf(message, args...))
+	formatted := fmt.Sprintf(message, args...)
+	writeRecord("error", formatted)
+
+	clearProgress()
+	fmt.Printf("%s %s\n", IconError, withTag(formatted))
+}
+
+func withTag(message string) string {
+	if tag := CorrelationTag(); tag != "" {
+		return tag + " " + message
+	}
+	return message
+}
+
+// human-readable byte count, e.g. 1536 -> "1.5 KB"
+func FormatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
@@ -178,6 +178,7 @@ func TestIconConstants(t *testing.T) {
 		"IconRewrite":  IconRewrite,
 		"IconClean":    IconClean,
 		"IconError":    IconError,
+		"IconUndo":     IconUndo,
 	}
 
 	// Check for empty icons
@@ -196,3 +197,25 @@ func TestIconConstants(t *testing.T) {
 		seen[icon] = name
 	}
 }
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes    uint64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := FormatBytes(tt.bytes); got != tt.expected {
+				t.Errorf("FormatBytes(%d) = %q, want %q", tt.bytes, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// minimum time between redraws so fast copies of small files don't flood the terminal
+	progressRedrawInterval = 200 * time.Millisecond
+	progressBarWidth       = 24
+	// longer file names are truncated from the left so the file name itself stays visible
+	progressNameWidth = 40
+)
+
+var (
+	// guards the active progress line, which is shared between the copy goroutine and regular logging
+	progressMu     sync.Mutex
+	activeProgress *Progress
+	progressDrawn  bool
+)
+
+// Progress draws a single self-updating status line with overall file and byte progress, the current
+// file, throughput, and ETA. Regular log messages clear the line before printing; it's redrawn on the
+// next update. All methods are safe to call on a nil *Progress, which does nothing.
+type Progress struct {
+	totalFiles int
+	doneFiles  int
+	totalBytes int64
+	doneBytes  int64
+	// bytes of the current file counted so far, so a retried file can be rolled back
+	fileBytes int64
+	current   string
+	start     time.Time
+	lastDraw  time.Time
+}
+
+func NewProgress() *Progress {
+	return &Progress{}
+}
+
+// whether stdout is an interactive terminal that can redraw a progress line in place
+func StdoutIsTerminal() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// SetTotal sets the expected totals, usually from a pre-pass over the resolved file set
+func (p *Progress) SetTotal(files int, bytes int64) {
+	if p == nil {
+		return
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	p.totalFiles = files
+	p.totalBytes = bytes
+}
+
+// Start begins timing and drawing the progress line
+func (p *Progress) Start() {
+	if p == nil {
+		return
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	p.start = time.Now()
+	activeProgress = p
+	p.draw(true)
+}
+
+// Stop draws the final state and leaves it on screen
+func (p *Progress) Stop() {
+	if p == nil {
+		return
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if activeProgress != p {
+		return
+	}
+	p.current = ""
+	p.draw(true)
+	fmt.Println()
+	progressDrawn = false
+	activeProgress = nil
+}
+
+// StartFile marks name as the file currently being copied
+func (p *Progress) StartFile(name string) {
+	if p == nil {
+		return
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	p.current = name
+	p.fileBytes = 0
+	p.draw(false)
+}
+
+// RestartFile discards the bytes counted for the current file, e.g. before a stalled copy is retried
+func (p *Progress) RestartFile() {
+	if p == nil {
+		return
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	p.doneBytes -= p.fileBytes
+	p.fileBytes = 0
+}
+
+// FinishFile counts the current file as done
+func (p *Progress) FinishFile() {
+	if p == nil {
+		return
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	p.doneFiles++
+	p.fileBytes = 0
+	p.current = ""
+	p.draw(false)
+}
+
+// Write counts len(b) bytes as transferred, so a Progress can be teed into a copy stream
+func (p *Progress) Write(b []byte) (int, error) {
+	if p == nil {
+		return len(b), nil
+	}
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	p.doneBytes += int64(len(b))
+	p.fileBytes += int64(len(b))
+	p.draw(false)
+	return len(b), nil
+}
+
+// must be called with progressMu held
+func (p *Progress) draw(force bool) {
+	if activeProgress != p {
+		return
+	}
+
+	now := time.Now()
+	if !force && now.Sub(p.lastDraw) < progressRedrawInterval {
+		return
+	}
+
+	fmt.Printf("\r\033[K%s", p.render(now))
+	progressDrawn = true
+	p.lastDraw = now
+}
+
+func (p *Progress) render(now time.Time) string {
+	// the pre-pass can undercount (e.g. loopback copies), so never report more than 100%
+	totalBytes, totalFiles := p.totalBytes, p.totalFiles
+	if p.doneBytes > totalBytes {
+		totalBytes = p.doneBytes
+	}
+	if p.doneFiles > totalFiles {
+		totalFiles = p.doneFiles
+	}
+
+	fraction := 1.0
+	if totalBytes > 0 {
+		fraction = float64(p.doneBytes) / float64(totalBytes)
+	}
+	filled := int(fraction * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+
+	var speed float64
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		speed = float64(p.doneBytes) / elapsed
+	}
+
+	eta := "--"
+	if p.doneBytes >= totalBytes {
+		eta = "0s"
+	} else if speed > 0 {
+		eta = (time.Duration(float64(totalBytes-p.doneBytes) / speed * float64(time.Second))).Round(time.Second).String()
+	}
+
+	line := fmt.Sprintf("[%s] %3.0f%%  %d/%d files  %s/%s  %s/s  ETA %s",
+		bar, fraction*100, p.doneFiles, totalFiles, FormatBytes(uint64(p.doneBytes)), FormatBytes(uint64(totalBytes)), FormatBytes(uint64(speed)), eta)
+
+	if p.current != "" {
+		line += "  " + truncateLeft(p.current, progressNameWidth)
+	}
+	return line
+}
+
+func truncateLeft(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return "..." + string(runes[len(runes)-(width-3):])
+}
+
+// erases the progress line, if drawn, so a log message can take its place
+func clearProgress() {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	if progressDrawn {
+		fmt.Print("\r\033[K")
+		progressDrawn = false
+	}
+}
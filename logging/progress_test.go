@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressRender(t *testing.T) {
+	start := time.Now()
+	p := NewProgress()
+	p.SetTotal(4, 4*1024*1024)
+	p.start = start
+
+	p.StartFile("snes/Chrono Trigger (USA).sfc")
+	p.Write(make([]byte, 1024*1024))
+	p.FinishFile()
+	p.StartFile("snes/Super Metroid (Japan, USA) (En,Ja).sfc")
+	p.Write(make([]byte, 1024*1024))
+
+	line := p.render(start.Add(2 * time.Second))
+	for _, want := range []string{
+		"[============            ]",
+		" 50%",
+		"1/4 files",
+		"2.0 MB/4.0 MB",
+		"1.0 MB/s",
+		"ETA 2s",
+		"...uper Metroid (Japan, USA) (En,Ja).sfc",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("render() = %q, missing %q", line, want)
+		}
+	}
+}
+
+func TestProgressRestartFile(t *testing.T) {
+	p := NewProgress()
+	p.SetTotal(1, 100)
+
+	p.StartFile("a.gba")
+	p.Write(make([]byte, 60))
+	p.RestartFile()
+	if p.doneBytes != 0 {
+		t.Fatalf("expected restarted file's bytes to be discarded, got %d done", p.doneBytes)
+	}
+
+	p.Write(make([]byte, 100))
+	p.FinishFile()
+	if p.doneBytes != 100 || p.doneFiles != 1 {
+		t.Errorf("expected 100 bytes and 1 file done, got %d bytes and %d files", p.doneBytes, p.doneFiles)
+	}
+}
+
+func TestProgressNeverExceedsTotal(t *testing.T) {
+	start := time.Now()
+	p := NewProgress()
+	p.SetTotal(1, 10)
+	p.start = start
+
+	p.Write(make([]byte, 20))
+	p.FinishFile()
+	p.FinishFile()
+
+	line := p.render(start.Add(time.Second))
+	if !strings.Contains(line, "100%") || !strings.Contains(line, "2/2 files") || !strings.Contains(line, "ETA 0s") {
+		t.Errorf("render() = %q, expected a complete bar when totals are undercounted", line)
+	}
+}
+
+func TestNilProgress(t *testing.T) {
+	var p *Progress
+	p.SetTotal(1, 1)
+	p.Start()
+	p.StartFile("a")
+	if n, err := p.Write([]byte("abc")); n != 3 || err != nil {
+		t.Errorf("nil Write() = %d, %v", n, err)
+	}
+	p.RestartFile()
+	p.FinishFile()
+	p.Stop()
+}
+
+func TestLogVerbose(t *testing.T) {
+	defer SetVerbose(false)
+
+	if output := captureOutput(func() { LogVerbose(Detail, "", "hidden") }); output != "" {
+		t.Errorf("LogVerbose() printed %q with verbose disabled", output)
+	}
+
+	SetVerbose(true)
+	if output := captureOutput(func() { LogVerbose(Detail, "", "shown") }); output != "    shown\n" {
+		t.Errorf("LogVerbose() output = %q, want %q", output, "    shown\n")
+	}
+}
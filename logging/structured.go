@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// what's currently running, so every message (and especially a late error) can be traced back to the
+// mapping and flag that produced it
+type correlation struct {
+	mapping     string
+	description string
+	operation   string
+	// per-mapping counter for NextOperation
+	counter int
+}
+
+var (
+	correlationMu sync.Mutex
+	current       correlation
+
+	// JSON-lines log written alongside the console output; nil if '--logFile' isn't set
+	logFileMu sync.Mutex
+	logFile   *os.File
+	logWriter *json.Encoder
+)
+
+// a single line of the structured log
+type logRecord struct {
+	Time time.Time `json:"time"`
+	// base, action, detail, dryRun, warning, error, or complete
+	Level string `json:"level"`
+	// e.g. 'm2'; the second '--mapping'
+	Mapping string `json:"mapping,omitempty"`
+	// e.g. 'snes -> SFC'
+	MappingDescription string `json:"mappingDescription,omitempty"`
+	// e.g. 'f14' (the 14th file copied for the mapping) or 'rewrite1' (the first '--rewrite')
+	Operation string `json:"operation,omitempty"`
+	Message   string `json:"message"`
+}
+
+// SetMapping marks the start of a mapping's operations. id should be stable for the run (e.g. 'm2' for the
+// second '--mapping'); description is a human-readable summary like 'snes -> SFC'.
+func SetMapping(id string, description string) {
+	correlationMu.Lock()
+	defer correlationMu.Unlock()
+	current = correlation{mapping: id, description: description}
+}
+
+// ClearMapping marks the end of a mapping's operations
+func ClearMapping() {
+	correlationMu.Lock()
+	defer correlationMu.Unlock()
+	current = correlation{}
+}
+
+// SetOperation marks the start of an operation within the current mapping, e.g. 'rewrite1' for the first
+// '--rewrite'
+func SetOperation(id string) {
+	correlationMu.Lock()
+	defer correlationMu.Unlock()
+	current.operation = id
+}
+
+// NextOperation starts the next numbered operation of a kind within the current mapping (e.g. 'f1', 'f2',
+// ... for copied files) and returns its ID
+func NextOperation(kind string) string {
+	correlationMu.Lock()
+	defer correlationMu.Unlock()
+	current.counter++
+	current.operation = fmt.Sprintf("%s%d", kind, current.counter)
+	return current.operation
+}
+
+// CorrelationTag returns the current IDs formatted for the console, e.g. '[m2/f14]', or "" outside a mapping
+func CorrelationTag() string {
+	correlationMu.Lock()
+	defer correlationMu.Unlock()
+	switch {
+	case current.mapping == "":
+		return ""
+	case current.operation == "":
+		return fmt.Sprintf("[%s]", current.mapping)
+	default:
+		return fmt.Sprintf("[%s/%s]", current.mapping, current.operation)
+	}
+}
+
+// OpenLogFile starts writing every message, including per-file detail hidden without '--verbose', to
+// filePath as JSON lines tagged with mapping and operation IDs
+func OpenLogFile(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", filePath, err)
+	}
+
+	logFileMu.Lock()
+	defer logFileMu.Unlock()
+	logFile = file
+	logWriter = json.NewEncoder(file)
+	logWriter.SetEscapeHTML(false)
+	return nil
+}
+
+// CloseLogFile stops structured logging
+func CloseLogFile() error {
+	logFileMu.Lock()
+	defer logFileMu.Unlock()
+	if logFile == nil {
+		return nil
+	}
+
+	err := logFile.Close()
+	logFile = nil
+	logWriter = nil
+	return err
+}
+
+// writes a message to the structured log, if one is open
+func writeRecord(level string, message string) {
+	logFileMu.Lock()
+	defer logFileMu.Unlock()
+	if logWriter == nil {
+		return
+	}
+
+	correlationMu.Lock()
+	record := logRecord{
+		Time:               time.Now().UTC(),
+		Level:              level,
+		Mapping:            current.mapping,
+		MappingDescription: current.description,
+		Operation:          current.operation,
+		Message:            message,
+	}
+	correlationMu.Unlock()
+
+	// a failing log file shouldn't take the copy down with it
+	_ = logWriter.Encode(record)
+}
+
+func levelName(level LogLevel) string {
+	switch level {
+	case Action:
+		return "action"
+	case Detail:
+		return "detail"
+	default:
+		return "base"
+	}
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCorrelationTag(t *testing.T) {
+	defer ClearMapping()
+
+	if tag := CorrelationTag(); tag != "" {
+		t.Errorf("CorrelationTag() outside a mapping = %q, want empty", tag)
+	}
+
+	SetMapping("m2", "snes -> SFC")
+	if tag := CorrelationTag(); tag != "[m2]" {
+		t.Errorf("CorrelationTag() = %q, want [m2]", tag)
+	}
+
+	NextOperation("f")
+	if id := NextOperation("f"); id != "f2" {
+		t.Errorf("NextOperation() = %q, want f2", id)
+	}
+	if tag := CorrelationTag(); tag != "[m2/f2]" {
+		t.Errorf("CorrelationTag() = %q, want [m2/f2]", tag)
+	}
+
+	output := captureOutput(func() { LogError("Error: %s", "boom") })
+	if output != "❌ [m2/f2] Error: boom\n" {
+		t.Errorf("LogError() output = %q, want tagged error", output)
+	}
+
+	// a new mapping restarts the numbering
+	SetMapping("m3", "gba -> GBA")
+	if id := NextOperation("f"); id != "f1" {
+		t.Errorf("NextOperation() after SetMapping() = %q, want f1", id)
+	}
+}
+
+func TestLogFile(t *testing.T) {
+	defer ClearMapping()
+	logPath := filepath.Join(t.TempDir(), "run.jsonl")
+
+	if err := OpenLogFile(logPath); err != nil {
+		t.Fatalf("OpenLogFile() error = %v", err)
+	}
+
+	captureOutput(func() {
+		Log(Base, "", "starting")
+		SetMapping("m1", "snes -> SFC")
+		SetOperation("rewrite1")
+		LogVerbose(Detail, IconRewrite, "Rewrote %s", "gamelist.xml")
+		LogWarning("careful")
+	})
+
+	if err := CloseLogFile(); err != nil {
+		t.Fatalf("CloseLogFile() error = %v", err)
+	}
+
+	file, err := os.Open(logPath)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	defer file.Close()
+
+	var records []logRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record logRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("log line %q isn't JSON: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records (verbose detail included), got %d: %+v", len(records), records)
+	}
+	if records[0].Mapping != "" || records[0].Message != "starting" {
+		t.Errorf("unexpected first record %+v", records[0])
+	}
+	if records[1].Mapping != "m1" || records[1].MappingDescription != "snes -> SFC" || records[1].Operation != "rewrite1" || records[1].Level != "detail" {
+		t.Errorf("unexpected verbose record %+v", records[1])
+	}
+	if records[2].Level != "warning" || records[2].Message != "careful" {
+		t.Errorf("unexpected warning record %+v", records[2])
+	}
+}
@@ -0,0 +1,314 @@
+package platforms
+
+import (
+	"bytes"
+	"crypto/md5"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Platform is a system ROMs are organized by, and the file types its emulators load
+type Platform struct {
+	Name string `yaml:"name"`
+	// lowercase platform folder names used by common firmwares and frontends, e.g. 'snes' and 'sfc'
+	Folders []string `yaml:"folders"`
+	// lowercase extensions, with the dot, of the ROM and disc image files the platform's emulators load
+	Extensions []string `yaml:"extensions"`
+	// its emulators need BIOS files from the original hardware to boot games: any one of its Bios that isn't
+	// Optional, e.g. a PlayStation BIOS of any region
+	NeedsBios bool `yaml:"needsBios"`
+	// the BIOS files its emulators look for
+	Bios []BiosFile `yaml:"bios"`
+	// games can span several discs, launched through an '.m3u' playlist of them
+	MultiDisc bool `yaml:"multiDisc"`
+	// the system ID ScreenScraper gives it, e.g. 4 for the Super Nintendo; zero if it has none
+	ScreenScraperID int `yaml:"screenscraperID"`
+	// the name of its folder in the libretro-thumbnails repository, e.g. 'Nintendo - Super Nintendo Entertainment
+	// System'; empty if it has none
+	LibretroName string `yaml:"libretroName"`
+}
+
+// BiosFile is a file from a platform's original hardware that its emulators look for, e.g. 'scph5501.bin'
+type BiosFile struct {
+	// the file's name, in the case emulators on case-sensitive filesystems look for it
+	Name string `yaml:"name"`
+	// lowercase MD5s of known-good copies; empty if good copies differ, e.g. a console's firmware with its owner's
+	// settings in it
+	MD5 []string `yaml:"md5"`
+	// emulators boot games without it, if less accurately or with fewer features
+	Optional bool `yaml:"optional"`
+}
+
+// the layout of platforms.yaml and of the files given to Load
+type table struct {
+	CommonExtensions []string   `yaml:"commonExtensions"`
+	MediaFolders     []string   `yaml:"mediaFolders"`
+	Platforms        []Platform `yaml:"platforms"`
+}
+
+//go:embed platforms.yaml
+var builtin []byte
+
+// All is every platform known by folder name: the built-in ones from platforms.yaml, and any added by Load
+var All []Platform
+
+// every platform accepts these: archives and frontend game lists
+var commonExtensions []string
+
+// folders of artwork, videos, and manuals that frontends read alongside ROMs; files in them aren't ROMs
+var mediaFolders []string
+
+func init() {
+	t, err := parse(builtin)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in platform table: %v", err))
+	}
+	All, commonExtensions, mediaFolders = t.Platforms, t.CommonExtensions, t.MediaFolders
+}
+
+// Load reads a platform table laid out like the built-in platforms.yaml from filePath and merges it into All:
+// its platforms replace those of the same name (case-insensitively) and the rest are added ahead of the
+// built-in ones, so they're recognized first when they share a folder name. Its common extensions and media
+// folders, if given, replace the built-in lists.
+func Load(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("unable to read platform table %s: %w", filePath, err)
+	}
+	t, err := parse(data)
+	if err != nil {
+		return fmt.Errorf("invalid platform table %s: %w", filePath, err)
+	}
+
+	added := make([]Platform, 0)
+	for _, platform := range t.Platforms {
+		replaced := false
+		for i := range All {
+			if strings.EqualFold(All[i].Name, platform.Name) {
+				All[i] = platform
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			added = append(added, platform)
+		}
+	}
+	All = append(added, All...)
+
+	if t.CommonExtensions != nil {
+		commonExtensions = t.CommonExtensions
+	}
+	if t.MediaFolders != nil {
+		mediaFolders = t.MediaFolders
+	}
+	return nil
+}
+
+// decodes a platform table, rejecting unknown keys so a misspelled 'extension:' isn't silently ignored, and
+// lowercases its folder names and extensions
+func parse(data []byte) (*table, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var t table
+	if err := decoder.Decode(&t); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i := range t.Platforms {
+		platform := &t.Platforms[i]
+		if strings.TrimSpace(platform.Name) == "" {
+			return nil, fmt.Errorf("platform %d has no name", i+1)
+		}
+		if seen[strings.ToLower(platform.Name)] {
+			return nil, fmt.Errorf("platform '%s' is listed more than once", platform.Name)
+		}
+		seen[strings.ToLower(platform.Name)] = true
+		if len(platform.Folders) == 0 {
+			return nil, fmt.Errorf("platform '%s' has no folders", platform.Name)
+		}
+		for j, folder := range platform.Folders {
+			platform.Folders[j] = strings.ToLower(folder)
+		}
+		if platform.Extensions == nil {
+			platform.Extensions = []string{}
+		}
+		if err := lowerExtensions(platform.Extensions); err != nil {
+			return nil, fmt.Errorf("platform '%s': %w", platform.Name, err)
+		}
+		if err := checkBios(platform); err != nil {
+			return nil, fmt.Errorf("platform '%s': %w", platform.Name, err)
+		}
+	}
+	if err := lowerExtensions(t.CommonExtensions); err != nil {
+		return nil, fmt.Errorf("commonExtensions: %w", err)
+	}
+	return &t, nil
+}
+
+func lowerExtensions(extensions []string) error {
+	for i, ext := range extensions {
+		if !strings.HasPrefix(ext, ".") {
+			return fmt.Errorf("extension '%s' must start with a dot", ext)
+		}
+		extensions[i] = strings.ToLower(ext)
+	}
+	return nil
+}
+
+// checks each of platform's BIOS files has a name and well-formed MD5s, which are lowercased, and is optional
+// unless the platform needs BIOS files to boot
+func checkBios(platform *Platform) error {
+	for i := range platform.Bios {
+		file := &platform.Bios[i]
+		if strings.TrimSpace(file.Name) == "" {
+			return fmt.Errorf("BIOS file %d has no name", i+1)
+		}
+		if !platform.NeedsBios && !file.Optional {
+			return fmt.Errorf("BIOS file '%s' must be optional, as the platform doesn't need BIOS files", file.Name)
+		}
+		for j, sum := range file.MD5 {
+			if _, err := hex.DecodeString(sum); err != nil || len(sum) != 2*md5.Size {
+				return fmt.Errorf("BIOS file '%s' has MD5 '%s', which must be 32 hex digits", file.Name, sum)
+			}
+			file.MD5[j] = strings.ToLower(sum)
+		}
+	}
+	return nil
+}
+
+// Find returns the platform a folder of that name holds, case-insensitively, or nil if it isn't known
+func Find(folder string) *Platform {
+	folder = strings.ToLower(folder)
+	for i := range All {
+		for _, name := range All[i].Folders {
+			if name == folder {
+				return &All[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ForMapping returns the platform a mapping copies, by the name of its source folder or, failing that, its
+// destination folder, e.g. 'snes' or 'Roms/SFC'; nil if neither is known
+func ForMapping(source string, destination string) *Platform {
+	for _, folder := range []string{source, destination} {
+		if platform := Find(path.Base(filepath.ToSlash(strings.TrimRight(folder, "/\\")))); platform != nil {
+			return platform
+		}
+	}
+	return nil
+}
+
+// Accepts reports whether a file at relPath (relative to the platform folder) belongs there: it has one of the
+// platform's extensions or one every platform accepts, is an '.m3u' playlist of a multi-disc platform, is
+// hidden, or is inside an artwork or video folder
+func (p *Platform) Accepts(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, part := range strings.Split(relPath, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	if InMediaFolder(relPath) {
+		return true
+	}
+
+	ext := strings.ToLower(path.Ext(relPath))
+	if ext == ".m3u" && p.MultiDisc {
+		return true
+	}
+	return containsFold(p.Extensions, ext) || containsFold(commonExtensions, ext)
+}
+
+// IsGame reports whether the file at relPath (relative to the platform folder) is a game a frontend lists: one of
+// the platform's ROMs or disc images, an archive of one, or an '.m3u' playlist of a multi-disc platform, outside
+// any hidden, artwork, or video folder
+func (p *Platform) IsGame(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, part := range strings.Split(relPath, "/") {
+		if strings.HasPrefix(part, ".") {
+			return false
+		}
+	}
+	if InMediaFolder(relPath) {
+		return false
+	}
+
+	ext := strings.ToLower(path.Ext(relPath))
+	if ext == ".m3u" {
+		return p.MultiDisc
+	}
+	// game lists are the one kind of common file that isn't a game
+	return containsFold(p.Extensions, ext) || (ext != ".xml" && containsFold(commonExtensions, ext))
+}
+
+// InMediaFolder reports whether the file at relPath (relative to the platform folder) is inside an artwork or
+// video folder, at any depth
+func InMediaFolder(relPath string) bool {
+	for _, dir := range strings.Split(path.Dir(filepath.ToSlash(relPath)), "/") {
+		if containsFold(mediaFolders, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindBios returns the BIOS file named name, case-insensitively, and the platform it's for; nil if no platform
+// looks for it
+func FindBios(name string) (*Platform, *BiosFile) {
+	for i := range All {
+		for j := range All[i].Bios {
+			if strings.EqualFold(All[i].Bios[j].Name, name) {
+				return &All[i], &All[i].Bios[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Good reports whether a copy of the file with the MD5 sum is known to be good; false if it has no known-good MD5s
+func (f *BiosFile) Good(sum string) bool {
+	return containsFold(f.MD5, sum)
+}
+
+// MissingBios returns the BIOS files the platform's emulators could boot games with, if none of them is among
+// present, which holds lowercase file names; nil if the platform doesn't need one or has one
+func (p *Platform) MissingBios(present map[string]bool) []string {
+	if !p.NeedsBios {
+		return nil
+	}
+	needed := make([]string, 0)
+	for _, file := range p.Bios {
+		if file.Optional {
+			continue
+		}
+		if present[strings.ToLower(file.Name)] {
+			return nil
+		}
+		needed = append(needed, file.Name)
+	}
+	if len(needed) == 0 {
+		return nil
+	}
+	return needed
+}
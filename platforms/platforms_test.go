@@ -0,0 +1,211 @@
+package platforms
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestForMapping(t *testing.T) {
+	tests := []struct {
+		source      string
+		destination string
+		want        string
+	}{
+		{"snes", "SFC", "Super Nintendo"},
+		{"roms-i-like", "Roms/GBA", "Game Boy Advance"},
+		{"Genesis", "MD", "Sega Genesis"},
+		{"misc", "Stuff", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got := ForMapping(tt.source, tt.destination)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Errorf("ForMapping(%q, %q) = %s, want nil", tt.source, tt.destination, got.Name)
+			case tt.want != "" && (got == nil || got.Name != tt.want):
+				t.Errorf("ForMapping(%q, %q) = %v, want %s", tt.source, tt.destination, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	gba := Find("gba")
+	if gba == nil {
+		t.Fatal("Find(\"gba\") = nil")
+	}
+
+	tests := []struct {
+		relPath string
+		want    bool
+	}{
+		{"Advance Wars (USA).gba", true},
+		{"Advance Wars (USA).GBA", true},
+		{"Pokemon Emerald (USA).zip", true},
+		{"miyoogamelist.xml", true},
+		{"sub/Golden Sun (USA).gba", true},
+		{"Imgs/Advance Wars (USA).png", true},
+		{"media/videos/clip.mp4", true},
+		{".romcopyignore", true},
+		{"Super Metroid (USA).sfc", false},
+		{"readme.txt", false},
+		{"Golden Sun.m3u", false},
+		{"sub/notes.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.relPath, func(t *testing.T) {
+			if got := gba.Accepts(tt.relPath); got != tt.want {
+				t.Errorf("Accepts(%q) = %v, want %v", tt.relPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptsMultiDiscPlaylist(t *testing.T) {
+	psx := Find("psx")
+	if psx == nil {
+		t.Fatal("Find(\"psx\") = nil")
+	}
+	if !psx.Accepts("Final Fantasy VII (USA).m3u") {
+		t.Error("Accepts() = false for an .m3u playlist of a multi-disc platform")
+	}
+}
+
+func TestIsGame(t *testing.T) {
+	psx := Find("psx")
+	if psx == nil {
+		t.Fatal("Find(\"psx\") = nil")
+	}
+
+	tests := []struct {
+		relPath string
+		want    bool
+	}{
+		{"Crash Bandicoot (USA).chd", true},
+		{"Final Fantasy VII (USA).m3u", true},
+		{"Tekken 3 (USA).zip", true},
+		{"multidisc/Final Fantasy VII (USA) (Disc 1).chd", true},
+		{"gamelist.xml", false},
+		{"images/Crash Bandicoot (USA).png", false},
+		{".hidden/Crash Bandicoot (USA).chd", false},
+		{"readme.txt", false},
+	}
+	for _, tt := range tests {
+		if got := psx.IsGame(tt.relPath); got != tt.want {
+			t.Errorf("IsGame(%q) = %v, want %v", tt.relPath, got, tt.want)
+		}
+	}
+
+	if Find("gba").IsGame("Golden Sun.m3u") {
+		t.Error("IsGame() = true for an .m3u playlist of a single-disc platform")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	all, common, media := All, commonExtensions, mediaFolders
+	t.Cleanup(func() {
+		All, commonExtensions, mediaFolders = all, common, media
+	})
+
+	tablePath := filepath.Join(t.TempDir(), "platforms.yaml")
+	table := `
+platforms:
+  - name: game boy advance
+    folders: [gba, agb]
+    extensions: [.GBA, .agb]
+  - name: Pico-8
+    folders: [pico8, p8]
+    extensions: [.p8]
+  - name: Famicom Disk System
+    folders: [fds]
+    extensions: [.fds]
+    needsBios: true
+`
+	if err := os.WriteFile(tablePath, []byte(table), 0644); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if err := Load(tablePath); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	gba := Find("AGB")
+	if gba == nil || gba.Name != "game boy advance" {
+		t.Fatalf("Find(\"AGB\") = %v, want the replaced Game Boy Advance", gba)
+	}
+	if !gba.Accepts("Advance Wars.agb") || !gba.Accepts("Pokemon Emerald.zip") {
+		t.Error("the replaced platform doesn't accept its own or common extensions")
+	}
+	if got := len(All); got != len(all)+2 {
+		t.Errorf("len(All) = %d, want %d", got, len(all)+2)
+	}
+	if pico := Find("p8"); pico == nil || pico.Name != "Pico-8" {
+		t.Errorf("Find(\"p8\") = %v, want Pico-8", pico)
+	}
+	if fds := Find("fds"); fds == nil || !fds.NeedsBios {
+		t.Errorf("Find(\"fds\") = %v, want Famicom Disk System needing a BIOS", fds)
+	}
+}
+
+func TestLoadRejectsInvalidTables(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		wantErr string
+	}{
+		{"unknown key", "platforms:\n  - name: Pico-8\n    folders: [pico8]\n    extension: [.p8]\n", "extension"},
+		{"no name", "platforms:\n  - folders: [pico8]\n", "no name"},
+		{"no folders", "platforms:\n  - name: Pico-8\n", "no folders"},
+		{"extension without dot", "platforms:\n  - name: Pico-8\n    folders: [pico8]\n    extensions: [p8]\n", "must start with a dot"},
+		{"duplicate", "platforms:\n  - name: Pico-8\n    folders: [pico8]\n  - name: PICO-8\n    folders: [p8]\n", "more than once"},
+		{"required BIOS without needsBios", "platforms:\n  - name: Pico-8\n    folders: [pico8]\n    bios: [{name: pico8.rom}]\n", "must be optional"},
+		{"malformed BIOS MD5", "platforms:\n  - name: Pico-8\n    folders: [pico8]\n    needsBios: true\n    bios: [{name: pico8.rom, md5: [abc]}]\n", "32 hex digits"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tablePath := filepath.Join(t.TempDir(), "platforms.yaml")
+			if err := os.WriteFile(tablePath, []byte(tt.table), 0644); err != nil {
+				t.Fatalf("Setup failed: %v", err)
+			}
+			err := Load(tablePath)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want one mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBios(t *testing.T) {
+	platform, file := FindBios("SCPH5501.BIN")
+	if platform == nil || platform.Name != "PlayStation" || file.Name != "scph5501.bin" {
+		t.Fatalf("FindBios(\"SCPH5501.BIN\") = %v, %v; want the PlayStation's scph5501.bin", platform, file)
+	}
+	if !file.Good("490F666E1AFB15B7362B406ED1CEA246") || file.Good("00000000000000000000000000000000") {
+		t.Error("Good() doesn't match the known-good MD5 alone")
+	}
+	if platform, _ := FindBios("readme.txt"); platform != nil {
+		t.Errorf("FindBios(\"readme.txt\") = %v, want nil", platform)
+	}
+
+	psx := Find("psx")
+	if missing := psx.MissingBios(map[string]bool{}); len(missing) != 4 {
+		t.Errorf("MissingBios() with no files = %v, want every PlayStation BIOS", missing)
+	}
+	// any one region's BIOS boots games
+	if missing := psx.MissingBios(map[string]bool{"scph5502.bin": true}); missing != nil {
+		t.Errorf("MissingBios() with scph5502.bin = %v, want nil", missing)
+	}
+	// an optional file isn't enough
+	if missing := Find("dc").MissingBios(map[string]bool{"dc_flash.bin": true}); len(missing) != 1 || missing[0] != "dc_boot.bin" {
+		t.Errorf("MissingBios() with only dc_flash.bin = %v, want [dc_boot.bin]", missing)
+	}
+	// nor is a platform that boots without one missing anything
+	if missing := Find("gba").MissingBios(map[string]bool{}); missing != nil {
+		t.Errorf("MissingBios() for the Game Boy Advance = %v, want nil", missing)
+	}
+}
@@ -0,0 +1,170 @@
+package profiles
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// the file at the top of a profile repository listing the profiles in it
+const indexFileName = "index.yaml"
+
+// the index of a profile repository: each profile's name, its file, and the SHA-256 of the file. It's served from
+// the same place as the files, so its SHA-256 only shows a file arrived whole, not that it's the one expected.
+type index struct {
+	Profiles []struct {
+		Name   string `yaml:"name"`
+		File   string `yaml:"file"`
+		SHA256 string `yaml:"sha256"`
+	} `yaml:"profiles"`
+}
+
+// DefaultUserDir returns where fetched profiles are kept in the user's config directory
+func DefaultUserDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "ROMCopyEngine", "profiles"), nil
+}
+
+// LoadUserDir adds the profiles in the '.yaml' files in dir to All, each replacing the profile of the same name
+// there, if any. A dir that doesn't exist holds no profiles.
+func LoadUserDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read profile directory %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".yaml") {
+			continue
+		}
+		filePath := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return fmt.Errorf("unable to read profile %s: %w", filePath, err)
+		}
+		t, err := parse(data)
+		if err != nil {
+			return fmt.Errorf("invalid profile %s: %w", filePath, err)
+		}
+		for _, profile := range t.Profiles {
+			add(profile)
+		}
+	}
+	return nil
+}
+
+// adds profile to All, replacing the profile of the same name
+func add(profile Profile) {
+	for i := range All {
+		if strings.EqualFold(All[i].Name, profile.Name) {
+			All[i] = profile
+			return
+		}
+	}
+	All = append(All, profile)
+}
+
+// Fetch downloads the profile named name from the profile repository at repoURL, returning the file holding it.
+// The file must have the SHA-256 the repository's index gives it, which catches a download cut short or damaged,
+// and sum too, if it isn't empty. Anyone able to change the file can change the index, so only sum pins it: a
+// profile checked once can be fetched again knowing it hasn't changed since.
+func Fetch(repoURL string, name string, sum string) ([]byte, error) {
+	client := &http.Client{Timeout: 60 * time.Second}
+	repoURL = strings.TrimRight(repoURL, "/")
+
+	data, err := get(client, repoURL+"/"+indexFileName)
+	if err != nil {
+		return nil, err
+	}
+	var idx index
+	if err := yaml.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("invalid profile index: %w", err)
+	}
+
+	names := make([]string, 0, len(idx.Profiles))
+	for _, entry := range idx.Profiles {
+		names = append(names, entry.Name)
+		if !strings.EqualFold(entry.Name, name) {
+			continue
+		}
+		// the index is the repository's to write, but a file outside it isn't
+		if entry.File == "" || strings.Contains(entry.File, "..") || strings.HasPrefix(entry.File, "/") {
+			return nil, fmt.Errorf("the profile index gives '%s' the file '%s', which isn't in the repository", entry.Name, entry.File)
+		}
+
+		if entry.SHA256 == "" {
+			return nil, fmt.Errorf("the profile index gives no SHA-256 for '%s'", entry.Name)
+		}
+
+		data, err := get(client, repoURL+"/"+entry.File)
+		if err != nil {
+			return nil, err
+		}
+		digest := sha256.Sum256(data)
+		got := hex.EncodeToString(digest[:])
+		if !strings.EqualFold(got, entry.SHA256) {
+			return nil, fmt.Errorf("profile '%s' has the SHA-256 %s, not the %s the profile index gives; the download may be damaged, or the index out of date", entry.Name, got, entry.SHA256)
+		}
+		if sum != "" && !strings.EqualFold(got, sum) {
+			return nil, fmt.Errorf("profile '%s' has the SHA-256 %s, not the %s pinned with '--sha256'; it has changed since", entry.Name, got, sum)
+		}
+
+		t, err := parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid profile '%s': %w", entry.Name, err)
+		}
+		if len(t.Profiles) != 1 || !strings.EqualFold(t.Profiles[0].Name, name) {
+			return nil, fmt.Errorf("the file for profile '%s' must hold just that profile", entry.Name)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("the profile repository has no profile '%s'; it has %s", name, strings.Join(names, ", "))
+}
+
+// Save writes a fetched profile file to dir as '<name>.yaml', creating dir if needed, and returns its path
+func Save(dir string, name string, data []byte) (string, error) {
+	if name == "" || strings.ContainsAny(name, "/\\") || strings.Contains(name, "..") {
+		return "", fmt.Errorf("invalid profile name '%s'", name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create profile directory %s: %w", dir, err)
+	}
+	filePath := filepath.Join(dir, strings.ToLower(name)+".yaml")
+	if err := fsys.WriteFileAtomic(fsys.OS, filePath, data); err != nil {
+		return "", fmt.Errorf("failed to write profile %s: %w", filePath, err)
+	}
+	return filePath, nil
+}
+
+// fetches address
+func get(client *http.Client, address string) ([]byte, error) {
+	resp, err := client.Get(address)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach the profile repository: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the profile repository answered %s for %s", resp.Status, address)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from the profile repository: %w", err)
+	}
+	return body, nil
+}
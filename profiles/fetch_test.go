@@ -0,0 +1,102 @@
+package profiles
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const trimuiProfile = "profiles:\n  - name: trimui\n    title: TrimUI\n    romsDir: Roms\n    folders: {Game Boy: GB}\n"
+
+func TestFetch(t *testing.T) {
+	sum := sha256.Sum256([]byte(trimuiProfile))
+	files := map[string]string{
+		"/index.yaml": "profiles:\n" +
+			"  - {name: trimui, file: profiles/trimui.yaml, sha256: " + hex.EncodeToString(sum[:]) + "}\n" +
+			"  - {name: stale, file: profiles/trimui.yaml, sha256: " + strings.Repeat("0", 64) + "}\n" +
+			"  - {name: escape, file: ../secrets.yaml, sha256: " + hex.EncodeToString(sum[:]) + "}\n",
+		"/profiles/trimui.yaml": trimuiProfile,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	data, err := Fetch(server.URL+"/", "TrimUI", "")
+	if err != nil || string(data) != trimuiProfile {
+		t.Fatalf("Fetch() = %q, %v; want the profile", data, err)
+	}
+	if _, err := Fetch(server.URL, "trimui", hex.EncodeToString(sum[:])); err != nil {
+		t.Errorf("Fetch() with the right pinned sum error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		sum     string
+		wantErr string
+	}{
+		{"pinned sum differs", "trimui", strings.Repeat("1", 64), "pinned"},
+		{"index sum differs", "stale", "", "profile index gives"},
+		{"file outside the repository", "escape", "", "isn't in the repository"},
+		{"unknown profile", "toaster", "", "has trimui, stale, escape"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Fetch(server.URL, tt.profile, tt.sum); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Fetch(%s) error = %v, want one mentioning %q", tt.profile, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadUserDir(t *testing.T) {
+	builtin := append([]Profile{}, All...)
+	defer func() { All = builtin }()
+
+	dir := t.TempDir()
+	if _, err := Save(dir, "trimui", []byte(trimuiProfile)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := Save(dir, "../escape", []byte(trimuiProfile)); err == nil {
+		t.Error("Save() of a name with a path in it succeeded, want an error")
+	}
+	onion := "profiles:\n  - name: onion\n    title: My Onion\n    romsDir: Roms\n    folders: {Game Boy: GB}\n"
+	if err := os.WriteFile(filepath.Join(dir, "onion.yaml"), []byte(onion), 0644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+
+	if err := LoadUserDir(dir); err != nil {
+		t.Fatalf("LoadUserDir() error = %v", err)
+	}
+	if profile, ok := Find("trimui"); !ok || profile.Title != "TrimUI" {
+		t.Errorf("Find(trimui) = %v, %v; want the fetched profile", profile, ok)
+	}
+	// a user's profile replaces the built-in one of the same name
+	if profile, ok := Find("onion"); !ok || profile.Title != "My Onion" {
+		t.Errorf("Find(onion) = %v, %v; want the user's profile", profile, ok)
+	}
+	if len(All) != len(builtin)+1 {
+		t.Errorf("len(All) = %d, want %d", len(All), len(builtin)+1)
+	}
+
+	if err := LoadUserDir(filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("LoadUserDir() of a missing directory error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("profiles:\n  - name: broken\n"), 0644); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	if err := LoadUserDir(dir); err == nil || !strings.Contains(err.Error(), "broken.yaml") {
+		t.Errorf("LoadUserDir() with an invalid profile error = %v, want the file named", err)
+	}
+}
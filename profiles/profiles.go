@@ -0,0 +1,194 @@
+package profiles
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jkingsman/ROMCopyEngine/platforms"
+)
+
+// Profile is how a device's firmware lays its card out: where it keeps platform folders, what it names them, and
+// the options a copy to it needs
+type Profile struct {
+	Name  string `yaml:"name"`
+	Title string `yaml:"title"`
+	// relative to the target root, e.g. 'Roms', or '.' for the root itself
+	RomsDir string `yaml:"romsDir"`
+	// relative to the target root, where the firmware's emulators look for BIOS files, e.g. 'BIOS'; empty if unknown
+	BiosDir string `yaml:"biosDir"`
+	// the firmware's folder for each platform, by the platform's name, e.g. 'SFC' for 'Super Nintendo'
+	Folders map[string]string `yaml:"folders"`
+	// relative to the target root, where the firmware shows box art from if not the platform folders, with
+	// '{catalogue}' standing for the platform's name in Catalogues and '{folder}' for its name in Folders, e.g.
+	// 'MUOS/info/catalogue/{catalogue}/box'
+	ArtworkPath string `yaml:"artworkPath"`
+	// the firmware's name for each platform's artwork, by the platform's name, e.g. 'Nintendo SNES - SFC'; needed
+	// if ArtworkPath has '{catalogue}'
+	Catalogues map[string]string `yaml:"catalogues"`
+	// '--name=value' options, applied before the rest of the command line
+	Options []string `yaml:"options"`
+}
+
+// the layout of profiles.yaml
+type table struct {
+	Profiles []Profile `yaml:"profiles"`
+}
+
+// the source folder BIOS files are mapped from
+const biosFolder = "bios"
+
+// what ArtworkPath names a platform's catalogue and folder by
+const (
+	catalogueVar = "{catalogue}"
+	folderVar    = "{folder}"
+)
+
+//go:embed profiles.yaml
+var builtin []byte
+
+// All is every built-in profile, in the order they're listed
+var All []Profile
+
+func init() {
+	t, err := parse(builtin)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in profiles: %v", err))
+	}
+	All = t.Profiles
+}
+
+// decodes a profile table, rejecting unknown keys so a misspelled 'folder:' isn't silently ignored
+func parse(data []byte) (*table, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var t table
+	if err := decoder.Decode(&t); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i, profile := range t.Profiles {
+		if strings.TrimSpace(profile.Name) == "" {
+			return nil, fmt.Errorf("profile %d has no name", i+1)
+		}
+		if seen[strings.ToLower(profile.Name)] {
+			return nil, fmt.Errorf("profile '%s' is listed more than once", profile.Name)
+		}
+		seen[strings.ToLower(profile.Name)] = true
+		if len(profile.Folders) == 0 {
+			return nil, fmt.Errorf("profile '%s' has no folders", profile.Name)
+		}
+		if profile.ArtworkPath != "" && !strings.Contains(profile.ArtworkPath, catalogueVar) && !strings.Contains(profile.ArtworkPath, folderVar) {
+			return nil, fmt.Errorf("profile '%s' has artworkPath '%s', which must contain '%s' or '%s'", profile.Name, profile.ArtworkPath, catalogueVar, folderVar)
+		}
+		if strings.Contains(profile.ArtworkPath, catalogueVar) != (len(profile.Catalogues) > 0) {
+			return nil, fmt.Errorf("profile '%s' must have catalogues if and only if its artworkPath has '%s'", profile.Name, catalogueVar)
+		}
+		for _, option := range profile.Options {
+			if !strings.HasPrefix(option, "--") {
+				return nil, fmt.Errorf("profile '%s' has option '%s', which must be given as '--name=value'", profile.Name, option)
+			}
+		}
+	}
+	return &t, nil
+}
+
+// Find returns the profile named name, case-insensitively
+func Find(name string) (*Profile, bool) {
+	for i := range All {
+		if strings.EqualFold(All[i].Name, name) {
+			return &All[i], true
+		}
+	}
+	return nil, false
+}
+
+// Names lists the name of every profile
+func Names() []string {
+	names := make([]string, 0, len(All))
+	for _, profile := range All {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// Folder returns the folder, relative to the target root, the firmware keeps platform in, e.g. 'Roms/SFC'; false
+// if it has none
+func (p *Profile) Folder(platform *platforms.Platform) (string, bool) {
+	folder, ok := lookup(p.Folders, platform)
+	if !ok {
+		return "", false
+	}
+	return path.Join(p.RomsDir, folder), true
+}
+
+// the value table has for platform, by its name
+func lookup(table map[string]string, platform *platforms.Platform) (string, bool) {
+	for name, value := range table {
+		if strings.EqualFold(name, platform.Name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// ArtworkFolder returns the folder, relative to the target root, the firmware shows platform's box art from, e.g.
+// 'MUOS/info/catalogue/Nintendo SNES - SFC/box'; false if it's shown from the platform folder
+func (p *Profile) ArtworkFolder(platform *platforms.Platform) (string, bool) {
+	if p.ArtworkPath == "" {
+		return "", false
+	}
+	artworkPath := p.ArtworkPath
+	for _, v := range []struct {
+		name  string
+		table map[string]string
+	}{{catalogueVar, p.Catalogues}, {folderVar, p.Folders}} {
+		if !strings.Contains(artworkPath, v.name) {
+			continue
+		}
+		value, ok := lookup(v.table, platform)
+		if !ok {
+			return "", false
+		}
+		artworkPath = strings.ReplaceAll(artworkPath, v.name, value)
+	}
+	return artworkPath, true
+}
+
+// Mappings returns a 'source:destination' mapping for each folder in sourceDir holding a platform the profile
+// has a folder for, and for a 'bios' folder if the profile knows where BIOS files go, in order of the source
+// folders' names, leaving out those in mapped
+func (p *Profile) Mappings(sourceDir string, mapped map[string]bool) ([]string, error) {
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read source directory %s: %w", sourceDir, err)
+	}
+
+	mappings := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() || mapped[entry.Name()] {
+			continue
+		}
+		if strings.EqualFold(entry.Name(), biosFolder) {
+			if p.BiosDir != "" {
+				mappings = append(mappings, entry.Name()+":"+p.BiosDir)
+			}
+			continue
+		}
+		platform := platforms.Find(entry.Name())
+		if platform == nil {
+			continue
+		}
+		if folder, ok := p.Folder(platform); ok {
+			mappings = append(mappings, entry.Name()+":"+folder)
+		}
+	}
+	return mappings, nil
+}
@@ -0,0 +1,128 @@
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jkingsman/ROMCopyEngine/platforms"
+)
+
+func TestBuiltinProfiles(t *testing.T) {
+	for _, profile := range All {
+		if profile.Title == "" || profile.RomsDir == "" {
+			t.Errorf("profile %s has no title or romsDir", profile.Name)
+		}
+		if profile.Name != strings.ToLower(profile.Name) {
+			t.Errorf("profile %s isn't named in lowercase", profile.Name)
+		}
+		// a platform named differently than in the platform table would never be mapped
+		for name := range profile.Folders {
+			if !isPlatform(name) {
+				t.Errorf("profile %s has a folder for '%s', which isn't a platform", profile.Name, name)
+			}
+		}
+		for name := range profile.Catalogues {
+			if !isPlatform(name) {
+				t.Errorf("profile %s has a catalogue for '%s', which isn't a platform", profile.Name, name)
+			}
+		}
+	}
+}
+
+func isPlatform(name string) bool {
+	for _, platform := range platforms.All {
+		if strings.EqualFold(platform.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"unknown key", "profiles:\n  - name: x\n    folder: {Game Boy: GB}\n"},
+		{"no name", "profiles:\n  - folders: {Game Boy: GB}\n"},
+		{"no folders", "profiles:\n  - name: x\n"},
+		{"duplicate", "profiles:\n  - name: x\n    folders: {Game Boy: GB}\n  - name: X\n    folders: {Game Boy: GB}\n"},
+		{"artwork path without catalogues", "profiles:\n  - name: x\n    folders: {Game Boy: GB}\n    artworkPath: art/{catalogue}\n"},
+		{"catalogues without artwork path", "profiles:\n  - name: x\n    folders: {Game Boy: GB}\n    catalogues: {Game Boy: Nintendo Game Boy}\n"},
+		{"catalogues without a catalogue in the artwork path", "profiles:\n  - name: x\n    folders: {Game Boy: GB}\n    artworkPath: art/{folder}\n    catalogues: {Game Boy: Nintendo Game Boy}\n"},
+		{"artwork path without a catalogue", "profiles:\n  - name: x\n    folders: {Game Boy: GB}\n    artworkPath: art\n    catalogues: {Game Boy: Nintendo Game Boy}\n"},
+		{"bare option", "profiles:\n  - name: x\n    folders: {Game Boy: GB}\n    options: [artworkDir=Imgs]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parse([]byte(tt.yaml)); err == nil {
+				t.Errorf("parse() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestMappings(t *testing.T) {
+	sourceDir := t.TempDir()
+	for _, dir := range []string{"snes", "GBA", "gb", "misc", "n64", "BIOS"} {
+		if err := os.MkdirAll(filepath.Join(sourceDir, dir), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(sourceDir, "nes"), nil, 0644); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+
+	profile := &Profile{Name: "test", RomsDir: "Roms", Folders: map[string]string{
+		"Super Nintendo":                "SFC",
+		"game boy advance":              "GBA",
+		"Game Boy":                      "GB",
+		"Nintendo Entertainment System": "FC",
+	}}
+	// files, unknown folders, and platforms the profile has no folder for aren't mapped
+	got, err := profile.Mappings(sourceDir, map[string]bool{"gb": true})
+	if err != nil {
+		t.Fatalf("Mappings() error = %v", err)
+	}
+	if want := []string{"GBA:Roms/GBA", "snes:Roms/SFC"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mappings() = %v, want %v", got, want)
+	}
+
+	// BIOS files go where the firmware looks for them, if the profile knows
+	profile.BiosDir = "Roms/bios"
+	got, err = profile.Mappings(sourceDir, map[string]bool{"gb": true})
+	if err != nil {
+		t.Fatalf("Mappings() error = %v", err)
+	}
+	if want := []string{"BIOS:Roms/bios", "GBA:Roms/GBA", "snes:Roms/SFC"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mappings() with a BIOS folder = %v, want %v", got, want)
+	}
+
+	if _, err := profile.Mappings(filepath.Join(sourceDir, "missing"), nil); err == nil {
+		t.Error("Mappings() of a missing source succeeded, want an error")
+	}
+}
+
+func TestArtworkFolder(t *testing.T) {
+	profile := &Profile{Name: "test", ArtworkPath: "MUOS/info/catalogue/{catalogue}/box", Catalogues: map[string]string{
+		"super nintendo": "Nintendo SNES - SFC",
+	}}
+	if got, ok := profile.ArtworkFolder(platforms.Find("snes")); !ok || got != "MUOS/info/catalogue/Nintendo SNES - SFC/box" {
+		t.Errorf("ArtworkFolder(snes) = %q, %v; want the SNES catalogue", got, ok)
+	}
+	if got, ok := profile.ArtworkFolder(platforms.Find("gba")); ok {
+		t.Errorf("ArtworkFolder(gba) = %q; want none for a platform without a catalogue", got)
+	}
+	// named for the platform's folder
+	profile = &Profile{Name: "test", ArtworkPath: "media/{folder}/covers", Folders: map[string]string{"Super Nintendo": "snes"}}
+	if got, ok := profile.ArtworkFolder(platforms.Find("sfc")); !ok || got != "media/snes/covers" {
+		t.Errorf("ArtworkFolder(sfc) = %q, %v; want the platform folder's media", got, ok)
+	}
+	if got, ok := (&Profile{Name: "plain"}).ArtworkFolder(platforms.Find("snes")); ok {
+		t.Errorf("ArtworkFolder() = %q; want none for a profile without an artwork path", got)
+	}
+}
@@ -0,0 +1,435 @@
+package romtags
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Tags is the metadata No-Intro, Redump, and GoodTools encode in ROM file names, e.g.
+// 'Super Metroid (Japan, USA) (En,Ja).sfc'
+type Tags struct {
+	// the name up to the first tag, e.g. 'Super Metroid'
+	Title string
+	// from the first parenthesized group made up entirely of known regions, e.g. ['Japan', 'USA']
+	Regions []string
+	// from the first parenthesized group made up entirely of language codes, e.g. ['En', 'Ja']; No-Intro leaves
+	// this out when the region implies the language, so see SpokenLanguages
+	Languages []string
+	// release types from both No-Intro '(Beta)' style and GoodTools '[b1]' style tags, as ReleaseTags names,
+	// e.g. ['beta', 'bad']
+	Releases []string
+	// from a '(Rev 1)' or '(v1.1)' tag, e.g. '1' or '1.1'; empty for the original release
+	Revision string
+}
+
+// ReleaseTags are the release types Parse recognizes, by the name Tags.Releases uses
+var ReleaseTags = []string{
+	"beta", "proto", "demo", "sample", "promo", "kiosk", "program", "pirate", "hack", "unlicensed", "aftermarket",
+	"homebrew", "bios", "bad", "overdump", "fixed", "trained", "alternate", "translation",
+}
+
+// No-Intro and Redump release types, by the lowercase first word of a parenthesized tag
+var parenReleaseTags = map[string]string{
+	"beta": "beta", "proto": "proto", "prototype": "proto", "demo": "demo", "sample": "sample", "promo": "promo",
+	"kiosk": "kiosk", "program": "program", "pirate": "pirate", "hack": "hack", "unl": "unlicensed",
+	"unlicensed": "unlicensed", "aftermarket": "aftermarket", "homebrew": "homebrew", "alt": "alternate",
+}
+
+// GoodTools dump flags, by the case-sensitive letter that opens a bracketed tag, e.g. 'b' for '[b2]'
+var bracketReleaseTags = map[byte]string{
+	'b': "bad", 'o': "overdump", 'f': "fixed", 't': "trained", 'a': "alternate", 'h': "hack", 'p': "pirate",
+	'T': "translation",
+}
+
+// region names used in No-Intro and Redump file names, lowercased
+var knownRegions = map[string]bool{
+	"world": true, "usa": true, "europe": true, "japan": true, "asia": true, "australia": true,
+	"brazil": true, "canada": true, "china": true, "france": true, "germany": true, "hong kong": true,
+	"italy": true, "korea": true, "netherlands": true, "spain": true, "sweden": true, "taiwan": true,
+	"uk": true, "russia": true, "scandinavia": true, "latin america": true, "mexico": true,
+	"denmark": true, "finland": true, "norway": true, "poland": true, "portugal": true, "greece": true,
+	"india": true, "unknown": true,
+}
+
+// two-letter language codes used in No-Intro and Redump file names, lowercased; some carry a script or
+// country suffix, e.g. 'Zh-Hant' or 'Pt-BR', which is ignored when matching
+var knownLanguages = map[string]bool{
+	"en": true, "ja": true, "fr": true, "de": true, "es": true, "it": true, "nl": true, "pt": true, "sv": true,
+	"no": true, "da": true, "fi": true, "zh": true, "ko": true, "pl": true, "ru": true, "el": true, "ca": true,
+	"cs": true, "hu": true, "tr": true, "ar": true, "he": true, "hr": true, "sl": true, "sq": true, "ro": true,
+	"uk": true, "vi": true, "th": true, "id": true,
+}
+
+// the language a single-region release is in when its name doesn't say, by lowercase region
+var regionLanguages = map[string]string{
+	"usa": "En", "europe": "En", "world": "En", "uk": "En", "australia": "En", "canada": "En",
+	"japan": "Ja", "france": "Fr", "germany": "De", "spain": "Es", "italy": "It", "netherlands": "Nl",
+	"sweden": "Sv", "brazil": "Pt", "portugal": "Pt", "korea": "Ko", "china": "Zh", "taiwan": "Zh",
+	"hong kong": "Zh", "russia": "Ru", "poland": "Pl", "denmark": "Da", "finland": "Fi", "norway": "No",
+	"greece": "El", "mexico": "Es", "latin america": "Es",
+}
+
+// Parse reads the tags from a ROM or game name; a file extension, if present, is ignored
+func Parse(name string) Tags {
+	name = strings.TrimSuffix(name, path.Ext(name))
+
+	tags := Tags{Title: strings.TrimSpace(name)}
+	if cut := strings.IndexAny(name, "(["); cut >= 0 {
+		tags.Title = strings.TrimSpace(name[:cut])
+	}
+
+	for _, group := range tagGroups(name, "(", ")") {
+		if tags.Regions == nil && isRegionGroup(group) {
+			tags.Regions = splitGroup(group)
+		} else if tags.Languages == nil && isLanguageGroup(group) {
+			tags.Languages = splitGroup(group)
+		} else if revision, ok := parseRevision(group); ok && tags.Revision == "" {
+			tags.Revision = revision
+		} else if words := strings.Fields(group); len(words) > 0 {
+			if release, ok := parenReleaseTags[strings.ToLower(words[0])]; ok {
+				tags.addRelease(release)
+			}
+		}
+	}
+
+	for _, group := range tagGroups(name, "[", "]") {
+		if strings.EqualFold(group, "bios") {
+			tags.addRelease("bios")
+		} else if release, ok := bracketFlag(group); ok {
+			tags.addRelease(release)
+		}
+	}
+
+	return tags
+}
+
+// Shorten cuts cut characters from the end of the title of a ROM or game name and appends suffix to what's left,
+// keeping its tags and extension, e.g. cutting 10 from 'Super Mario Bros. 3 (USA) (Rev 1).nes' gives
+// 'Super Mari (USA) (Rev 1).nes'. Spaces and punctuation left dangling at the cut are dropped too. It returns
+// false if that would leave nothing of the title.
+func Shorten(name string, cut int, suffix string) (string, bool) {
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	rest := ""
+	if tagStart := strings.IndexAny(stem, "(["); tagStart >= 0 {
+		stem, rest = stem[:tagStart], stem[tagStart:]
+	}
+	trimmed := strings.TrimRight(stem, " ")
+	rest = stem[len(trimmed):] + rest
+
+	shortened, ok := ShortenTitle(trimmed, cut, suffix)
+	if !ok {
+		return "", false
+	}
+	return shortened + rest + ext, true
+}
+
+// ShortenTitle cuts cut characters from the end of title, a name's title alone, as Shorten does, and appends
+// suffix to what's left, e.g. for a game's folder and the files in it to be cut alike
+func ShortenTitle(title string, cut int, suffix string) (string, bool) {
+	runes := []rune(title)
+	if cut >= len(runes) {
+		return "", false
+	}
+	shortened := strings.TrimRight(string(runes[:len(runes)-cut]), " -,.:;_~")
+	if shortened == "" {
+		return "", false
+	}
+	return shortened + suffix, true
+}
+
+// returns the contents of every top-level group in name delimited by open and close, e.g. '(' and ')'
+func tagGroups(name string, open string, close string) []string {
+	groups := make([]string, 0)
+	for {
+		start := strings.Index(name, open)
+		if start < 0 {
+			return groups
+		}
+		end := strings.Index(name[start:], close)
+		if end < 0 {
+			return groups
+		}
+		groups = append(groups, name[start+1:start+end])
+		name = name[start+end+1:]
+	}
+}
+
+func splitGroup(group string) []string {
+	parts := strings.Split(group, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+func isRegionGroup(group string) bool {
+	for _, part := range splitGroup(group) {
+		if !knownRegions[strings.ToLower(part)] {
+			return false
+		}
+	}
+	return true
+}
+
+func isLanguageGroup(group string) bool {
+	for _, part := range splitGroup(group) {
+		if !knownLanguages[languageCode(part)] {
+			return false
+		}
+	}
+	return true
+}
+
+// returns the lowercase base language of a tag, e.g. 'zh' for 'Zh-Hant'
+func languageCode(language string) string {
+	code, _, _ := strings.Cut(language, "-")
+	return strings.ToLower(code)
+}
+
+// SpokenLanguages returns the release's languages: those listed in its name, or if there are none, the language
+// implied by a single region (e.g. Ja for '(Japan)'). Empty if neither says.
+func (t Tags) SpokenLanguages() []string {
+	if len(t.Languages) > 0 {
+		return t.Languages
+	}
+	if len(t.Regions) == 1 {
+		if language, ok := regionLanguages[strings.ToLower(t.Regions[0])]; ok {
+			return []string{language}
+		}
+	}
+	return nil
+}
+
+// InRegion reports whether any of the release's regions is one of regions (compared case-insensitively)
+func (t Tags) InRegion(regions []string) bool {
+	for _, have := range t.Regions {
+		for _, want := range regions {
+			if strings.EqualFold(have, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InLanguage reports whether any of the release's spoken languages is one of languages. Codes are compared by
+// base language, so 'Zh' matches 'Zh-Hant'.
+func (t Tags) InLanguage(languages []string) bool {
+	for _, have := range t.SpokenLanguages() {
+		for _, want := range languages {
+			if languageCode(have) == languageCode(want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LanguageRank returns the position in preferred of the first listed language the release is in, compared like
+// InLanguage, or -1 if it's in none of them
+func (t Tags) LanguageRank(preferred []string) int {
+	for i, language := range preferred {
+		if t.InLanguage([]string{language}) {
+			return i
+		}
+	}
+	return -1
+}
+
+// PreferLanguage chooses between the language variants of each game among relPaths: files in the same folder with
+// the same title, revision, release types, and extension, but different regions or languages, e.g.
+// 'Tintin (Europe) (En,Fr,De).sfc' and 'Tintin (Europe) (Es,It).sfc'. Where any variant is in one of the
+// preferred languages, those in an earlier listed language win. It returns each variant that loses, mapped to one
+// that's kept in its place. Files whose language can't be told from their name are never chosen against.
+func PreferLanguage(relPaths []string, preferred []string) map[string]string {
+	type variant struct {
+		relPath string
+		rank    int
+	}
+	groups := make(map[string][]variant)
+	for _, relPath := range relPaths {
+		name := filepath.Base(relPath)
+		tags := Parse(name)
+		if tags.Title == "" || len(tags.SpokenLanguages()) == 0 {
+			continue
+		}
+
+		key := variantKey(relPath, tags.Title, tags.Revision, tags.sortedReleases())
+		groups[key] = append(groups[key], variant{relPath: relPath, rank: tags.LanguageRank(preferred)})
+	}
+
+	losers := make(map[string]string)
+	for _, variants := range groups {
+		best := -1
+		for i, v := range variants {
+			if v.rank >= 0 && (best < 0 || v.rank < variants[best].rank) {
+				best = i
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		for _, v := range variants {
+			if v.rank < 0 || v.rank > variants[best].rank {
+				losers[v.relPath] = variants[best].relPath
+			}
+		}
+	}
+	return losers
+}
+
+// PreferRevision chooses between the revisions of each game among relPaths: files in the same folder with the same
+// title, regions, languages, release types, and extension, e.g. 'Zelda (USA).nes' and 'Zelda (USA) (Rev 1).nes'.
+// The latest revision is kept, or with latest false, the earliest (the original release, if it's there). It
+// returns each revision that loses, mapped to the one kept in its place.
+func PreferRevision(relPaths []string, latest bool) map[string]string {
+	groups := make(map[string][]string)
+	revisions := make(map[string]string, len(relPaths))
+	for _, relPath := range relPaths {
+		tags := Parse(filepath.Base(relPath))
+		if tags.Title == "" {
+			continue
+		}
+		key := variantKey(relPath, tags.Title, strings.Join(tags.Regions, ","), strings.Join(tags.Languages, ","), tags.sortedReleases())
+		groups[key] = append(groups[key], relPath)
+		revisions[relPath] = tags.Revision
+	}
+
+	losers := make(map[string]string)
+	for _, variants := range groups {
+		best := variants[0]
+		for _, relPath := range variants[1:] {
+			cmp := CompareRevisions(revisions[relPath], revisions[best])
+			if (latest && cmp > 0) || (!latest && cmp < 0) {
+				best = relPath
+			}
+		}
+		for _, relPath := range variants {
+			if CompareRevisions(revisions[relPath], revisions[best]) != 0 {
+				losers[relPath] = best
+			}
+		}
+	}
+	return losers
+}
+
+// groups files in the same folder with the same extension whose names agree on the given tags, case-insensitively
+func variantKey(relPath string, tags ...string) string {
+	parts := append([]string{filepath.ToSlash(filepath.Dir(relPath)), filepath.Ext(relPath)}, tags...)
+	return strings.ToLower(strings.Join(parts, "\x00"))
+}
+
+// the release types, sorted and comma separated, so names listing them in a different order agree
+func (t Tags) sortedReleases() string {
+	releases := append([]string{}, t.Releases...)
+	sort.Strings(releases)
+	return strings.Join(releases, ",")
+}
+
+// reads a GoodTools flag like '[b1]', '[hM04]', or '[T+Eng]'; the letter must stand alone or be followed by
+// something other than a lowercase letter, so words in brackets aren't mistaken for flags
+func bracketFlag(group string) (string, bool) {
+	if group == "" {
+		return "", false
+	}
+	release, ok := bracketReleaseTags[group[0]]
+	if !ok {
+		return "", false
+	}
+	if len(group) > 1 && group[1] >= 'a' && group[1] <= 'z' {
+		return "", false
+	}
+	return release, true
+}
+
+func (t *Tags) addRelease(release string) {
+	for _, existing := range t.Releases {
+		if existing == release {
+			return
+		}
+	}
+	t.Releases = append(t.Releases, release)
+}
+
+// NormalizeReleaseTag returns the ReleaseTags name for a user-supplied tag (e.g. 'Prototype' or 'unl'), and
+// whether it's recognized
+func NormalizeReleaseTag(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if release, ok := parenReleaseTags[tag]; ok {
+		return release, true
+	}
+	for _, release := range ReleaseTags {
+		if tag == release {
+			return release, true
+		}
+	}
+	return "", false
+}
+
+// HasRelease reports whether the release is any of releases, given as ReleaseTags names
+func (t Tags) HasRelease(releases []string) bool {
+	for _, have := range t.Releases {
+		for _, want := range releases {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reads a revision tag's version: 'Rev 1' and 'Rev A' (No-Intro) or 'v1.1' (GoodTools and No-Intro)
+func parseRevision(group string) (string, bool) {
+	lower := strings.ToLower(strings.TrimSpace(group))
+	if strings.HasPrefix(lower, "rev ") {
+		return strings.TrimSpace(group[4:]), true
+	}
+	if len(lower) > 1 && lower[0] == 'v' && unicode.IsDigit(rune(lower[1])) && !strings.Contains(lower, " ") {
+		return group[1:], true
+	}
+	return "", false
+}
+
+// CompareRevisions orders revisions as Tags.Revision holds them, returning -1, 0, or 1. The original release
+// ("") comes before any revision, and dotted parts compare numerically where both are numbers, so '1.10' is
+// later than '1.9'.
+func CompareRevisions(a string, b string) int {
+	aParts := strings.Split(strings.ToLower(a), ".")
+	bParts := strings.Split(strings.ToLower(b), ".")
+	if a == "" {
+		aParts = nil
+	}
+	if b == "" {
+		bParts = nil
+	}
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		if i >= len(aParts) {
+			return -1
+		}
+		if i >= len(bParts) {
+			return 1
+		}
+
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil && aNum != bNum:
+			if aNum < bNum {
+				return -1
+			}
+			return 1
+		case (aErr != nil || bErr != nil) && aParts[i] != bParts[i]:
+			if aParts[i] < bParts[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
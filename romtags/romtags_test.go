@@ -0,0 +1,248 @@
+package romtags
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		title     string
+		regions   []string
+		languages []string
+	}{
+		{"Super Metroid (Japan, USA) (En,Ja).sfc", "Super Metroid", []string{"Japan", "USA"}, []string{"En", "Ja"}},
+		{"Tetris (World) (Rev 1).gb", "Tetris", []string{"World"}, nil},
+		{"Sonic the Hedgehog (Europe)", "Sonic the Hedgehog", []string{"Europe"}, nil},
+		{"Mega Man 2 (Beta) (USA).nes", "Mega Man 2", []string{"USA"}, nil},
+		{"Asterix (Europe) (En,Fr,De,Es,It).sms", "Asterix", []string{"Europe"}, []string{"En", "Fr", "De", "Es", "It"}},
+		{"Pokemon (Taiwan) (Zh-Hant).gba", "Pokemon", []string{"Taiwan"}, []string{"Zh-Hant"}},
+		{"Contra [b1].nes", "Contra", nil, nil},
+		{"Homebrew.nes", "Homebrew", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := Parse(tt.name)
+			if tags.Title != tt.title {
+				t.Errorf("Title = %q, want %q", tags.Title, tt.title)
+			}
+			if !reflect.DeepEqual(tags.Regions, tt.regions) {
+				t.Errorf("Regions = %v, want %v", tags.Regions, tt.regions)
+			}
+			if !reflect.DeepEqual(tags.Languages, tt.languages) {
+				t.Errorf("Languages = %v, want %v", tags.Languages, tt.languages)
+			}
+		})
+	}
+}
+
+func TestShorten(t *testing.T) {
+	tests := []struct {
+		name   string
+		cut    int
+		suffix string
+		want   string
+		ok     bool
+	}{
+		{"Super Mario Bros. 3 (USA) (Rev 1).nes", 9, "", "Super Mari (USA) (Rev 1).nes", true},
+		{"Super Mario Bros. 3 (USA) (Rev 1).nes", 3, "", "Super Mario Bros (USA) (Rev 1).nes", true},
+		{"Castlevania - Symphony of the Night (USA) (Track 1).bin", 24, "~2", "Castlevania~2 (USA) (Track 1).bin", true},
+		{"Contra[b1].nes", 3, "", "Con[b1].nes", true},
+		{"Homebrew Game.nes", 5, "", "Homebrew.nes", true},
+		{"Pokémon Rouge (France).gb", 6, "", "Pokémon (France).gb", true},
+		{"Tetris (World).gb", 6, "", "", false},
+		{"(Untitled).nes", 1, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Shorten(tt.name, tt.cut, tt.suffix)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Shorten(%q, %d, %q) = %q, %v; want %q, %v", tt.name, tt.cut, tt.suffix, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestShortenTitle(t *testing.T) {
+	// a folder named for a game has no extension to keep, however many dots its title has
+	if got, ok := ShortenTitle("Super Mario Bros. 3", 9, ""); got != "Super Mari" || !ok {
+		t.Errorf("ShortenTitle() = %q, %v; want \"Super Mari\", true", got, ok)
+	}
+	if got, ok := ShortenTitle("Alpha", 3, "~2"); got != "Al~2" || !ok {
+		t.Errorf("ShortenTitle() with a suffix = %q, %v; want \"Al~2\", true", got, ok)
+	}
+	if _, ok := ShortenTitle("Alpha", 5, ""); ok {
+		t.Error("ShortenTitle() cutting the whole title should fail")
+	}
+}
+
+func TestInRegionAndLanguage(t *testing.T) {
+	tests := []struct {
+		name       string
+		regions    []string
+		languages  []string
+		inRegion   bool
+		inLanguage bool
+	}{
+		{"Zelda (USA).nes", []string{"usa"}, []string{"En"}, true, true},
+		{"Zelda (Japan).nes", []string{"USA", "World"}, []string{"En"}, false, false},
+		{"Asterix (Europe) (En,Fr,De).sms", []string{"Europe"}, []string{"fr"}, true, true},
+		{"Dragon Quest (Japan) (En).sfc", []string{"Japan"}, []string{"En"}, true, true},
+		{"Pokemon (Taiwan) (Zh-Hant).gba", []string{"USA"}, []string{"Zh"}, false, true},
+		{"Super Metroid (Japan, USA).sfc", []string{"USA"}, []string{"En"}, true, false},
+		{"Homebrew.nes", []string{"USA"}, []string{"En"}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := Parse(tt.name)
+			if got := tags.InRegion(tt.regions); got != tt.inRegion {
+				t.Errorf("InRegion(%v) = %v, want %v", tt.regions, got, tt.inRegion)
+			}
+			if got := tags.InLanguage(tt.languages); got != tt.inLanguage {
+				t.Errorf("InLanguage(%v) = %v, want %v", tt.languages, got, tt.inLanguage)
+			}
+		})
+	}
+}
+
+func TestParseReleases(t *testing.T) {
+	tests := []struct {
+		name     string
+		releases []string
+	}{
+		{"Mega Man 2 (Beta) (USA).nes", []string{"beta"}},
+		{"Star Fox 2 (Japan) (Proto 1).sfc", []string{"proto"}},
+		{"Sonic (USA) (Demo) (Kiosk).md", []string{"demo", "kiosk"}},
+		{"Action 52 (USA) (Unl).nes", []string{"unlicensed"}},
+		{"Contra (U) [b1].nes", []string{"bad"}},
+		{"Super Mario Bros (W) [hM04][!].nes", []string{"hack"}},
+		{"Final Fantasy V (J) [T+Eng1.1_RPGe].sfc", []string{"translation"}},
+		{"Zelda (U) [t1][a2].nes", []string{"trained", "alternate"}},
+		{"[BIOS] PlayStation (USA).bin", []string{"bios"}},
+		{"Tetris (World) (Rev 1) [!].gb", nil},
+		{"Doom [beta build notes].txt", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.name).Releases; !reflect.DeepEqual(got, tt.releases) {
+				t.Errorf("Releases = %v, want %v", got, tt.releases)
+			}
+		})
+	}
+}
+
+func TestNormalizeReleaseTag(t *testing.T) {
+	tests := map[string]string{"Beta": "beta", "prototype": "proto", " unl ": "unlicensed", "bad": "bad", "translation": "translation"}
+	for tag, want := range tests {
+		if got, ok := NormalizeReleaseTag(tag); !ok || got != want {
+			t.Errorf("NormalizeReleaseTag(%q) = %q, %v; want %q", tag, got, ok, want)
+		}
+	}
+	if _, ok := NormalizeReleaseTag("final"); ok {
+		t.Error("NormalizeReleaseTag('final') should not be recognized")
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	revisions := map[string]string{
+		"Zelda (USA) (Rev 1).nes":      "1",
+		"Zelda (USA) (Rev A).nes":      "A",
+		"Doom (USA) (v1.10).sfc":       "1.10",
+		"Zelda (USA).nes":              "",
+		"Virtua Racing (Europe) (En).": "",
+	}
+	for name, want := range revisions {
+		if got := Parse(name).Revision; got != want {
+			t.Errorf("Parse(%q).Revision = %q, want %q", name, got, want)
+		}
+	}
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "1", -1},
+		{"1", "2", -1},
+		{"1.10", "1.9", 1},
+		{"A", "B", -1},
+		{"1.1", "1.1", 0},
+		{"1", "1.1", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareRevisions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareRevisions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPreferLanguage(t *testing.T) {
+	relPaths := []string{
+		"Tintin (Europe) (Es,It).sfc",
+		"Tintin (Europe) (En,Fr,De).sfc",
+		"Tintin (France).sfc",
+		"Asterix (Europe) (Fr,De).sms",
+		"Asterix (Germany).sms",
+		"Asterix (Spain).sms",
+		"Zelda (Japan).nes",
+		"Zelda (Japan) (Rev 1).nes",
+		"Zelda (Japan).zip",
+		"Mario (Japan).nes",
+		"Mario.nes",
+		"sub/Tintin (Europe) (Es,It).sfc",
+	}
+
+	got := PreferLanguage(relPaths, []string{"en", "fr"})
+	want := map[string]string{
+		"Tintin (Europe) (Es,It).sfc": "Tintin (Europe) (En,Fr,De).sfc",
+		"Tintin (France).sfc":         "Tintin (Europe) (En,Fr,De).sfc",
+		"Asterix (Germany).sms":       "Asterix (Europe) (Fr,De).sms",
+		"Asterix (Spain).sms":         "Asterix (Europe) (Fr,De).sms",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PreferLanguage() = %v, want %v", got, want)
+	}
+}
+
+func TestPreferRevision(t *testing.T) {
+	relPaths := []string{
+		"Zelda (USA).nes",
+		"Zelda (USA) (Rev 1).nes",
+		"Zelda (USA) (Rev 2).nes",
+		"Zelda (Europe).nes",
+		"Zelda (USA) (Beta).nes",
+		"Sonic (World) (v1.1).md",
+		"Sonic (World) (v1.10).md",
+		"sub/Zelda (USA).nes",
+		"Homebrew.nes",
+	}
+
+	tests := []struct {
+		name   string
+		latest bool
+		want   map[string]string
+	}{
+		{"latest", true, map[string]string{
+			"Zelda (USA).nes":         "Zelda (USA) (Rev 2).nes",
+			"Zelda (USA) (Rev 1).nes": "Zelda (USA) (Rev 2).nes",
+			"Sonic (World) (v1.1).md": "Sonic (World) (v1.10).md",
+		}},
+		{"earliest", false, map[string]string{
+			"Zelda (USA) (Rev 1).nes":  "Zelda (USA).nes",
+			"Zelda (USA) (Rev 2).nes":  "Zelda (USA).nes",
+			"Sonic (World) (v1.10).md": "Sonic (World) (v1.1).md",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PreferRevision(relPaths, tt.latest); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PreferRevision() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
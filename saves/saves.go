@@ -0,0 +1,157 @@
+package saves
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// Extensions are the lowercase extensions, with the dot, of the save files Plan looks for: battery saves and
+// their clocks, N64 and DS saves, and memory cards. Save states aren't among them; emulators write them often
+// and they only load in the emulator version that wrote them.
+var Extensions = []string{".srm", ".sav", ".rtc", ".eep", ".sra", ".fla", ".mpk", ".dsv", ".mcr", ".mcd", ".brm"}
+
+// how far apart two differing copies' modification times can be and still count as saved at the same time; FAT
+// records them to the nearest 2 seconds
+const timeTolerance = 2 * time.Second
+
+// Direction is which way a save file is copied
+type Direction int
+
+const (
+	// from the device to the backup
+	ToBackup Direction = iota
+	// from the backup to the device
+	ToDevice
+)
+
+// Change is a save file to copy from one side to the other
+type Change struct {
+	// relative to the device and backup folders
+	RelPath   string
+	Direction Direction
+	// the other side has an older copy, which the copy replaces
+	Replaces bool
+	// the time the copy was last saved, which the copy keeps so later runs see both sides match
+	ModTime time.Time
+}
+
+// IsSave reports whether name is a save file by its extension
+func IsSave(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, save := range Extensions {
+		if ext == save {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan compares the save files on the device in deviceDir with those in backupDir, matched by their paths
+// relative to each, and returns what to copy so both sides end up with the newest copy of each, in order of
+// their paths. A save on only one side is copied to the other. Where both have one, the one saved last wins;
+// copies saved at the same time but differing are resolved in the device's favour, as it's where games are
+// played. A backupDir that doesn't exist yet has no saves.
+func Plan(fs fsys.FS, deviceDir string, backupDir string) ([]Change, error) {
+	device, err := list(fs, deviceDir)
+	if err != nil {
+		return nil, err
+	}
+	backup, err := list(fs, backupDir)
+	if err != nil {
+		return nil, err
+	}
+
+	relPaths := make([]string, 0, len(device)+len(backup))
+	for relPath := range device {
+		relPaths = append(relPaths, relPath)
+	}
+	for relPath := range backup {
+		if _, ok := device[relPath]; !ok {
+			relPaths = append(relPaths, relPath)
+		}
+	}
+	sort.Strings(relPaths)
+
+	changes := make([]Change, 0)
+	for _, relPath := range relPaths {
+		onDevice, deviceOk := device[relPath]
+		inBackup, backupOk := backup[relPath]
+		switch {
+		case !backupOk:
+			changes = append(changes, Change{RelPath: relPath, Direction: ToBackup, ModTime: onDevice.ModTime()})
+		case !deviceOk:
+			changes = append(changes, Change{RelPath: relPath, Direction: ToDevice, ModTime: inBackup.ModTime()})
+		default:
+			same, err := sameSave(fs, filepath.Join(deviceDir, relPath), onDevice, filepath.Join(backupDir, relPath), inBackup)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+			if inBackup.ModTime().Sub(onDevice.ModTime()) > timeTolerance {
+				changes = append(changes, Change{RelPath: relPath, Direction: ToDevice, Replaces: true, ModTime: inBackup.ModTime()})
+			} else {
+				changes = append(changes, Change{RelPath: relPath, Direction: ToBackup, Replaces: true, ModTime: onDevice.ModTime()})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// lists the save files in dir by their paths relative to it, leaving out hidden files and folders such as the
+// trash
+func list(fs fsys.FS, dir string) (map[string]os.FileInfo, error) {
+	files := make(map[string]os.FileInfo)
+	err := fsys.Walk(fs, dir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if filePath == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && filePath != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !IsSave(info.Name()) {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		files[relPath] = info
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list save files in %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// reports whether two copies of a save hold the same bytes, whenever each was saved; saves are small enough to
+// compare whole
+func sameSave(fs fsys.FS, pathA string, infoA os.FileInfo, pathB string, infoB os.FileInfo) (bool, error) {
+	if infoA.Size() != infoB.Size() {
+		return false, nil
+	}
+	dataA, err := fs.ReadFile(pathA)
+	if err != nil {
+		return false, fmt.Errorf("failed to read save %s: %w", pathA, err)
+	}
+	dataB, err := fs.ReadFile(pathB)
+	if err != nil {
+		return false, fmt.Errorf("failed to read save %s: %w", pathB, err)
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
@@ -0,0 +1,88 @@
+package saves
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+func TestIsSave(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"Pokemon Emerald.sav", true},
+		{"Super Metroid.SRM", true},
+		{"Mario Kart 64.eep", true},
+		{"epsxe000.mcr", true},
+		{"Super Metroid.sfc", false},
+		{"Super Metroid.state1", false},
+		{"srm", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSave(tt.name); got != tt.want {
+			t.Errorf("IsSave(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	mem := fsys.NewMemFS()
+	deviceDir := filepath.Join(t.TempDir(), "device")
+	backupDir := filepath.Join(t.TempDir(), "backup")
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	write := func(dir string, relPath string, data string, modTime time.Time) {
+		filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+		mem.MkdirAll(filepath.Dir(filePath), 0755)
+		mem.WriteFile(filePath, []byte(data), 0644)
+		mem.Chtimes(filePath, modTime, modTime)
+	}
+
+	// only on one side
+	write(deviceDir, "Saves/GBA/Golden Sun.sav", "sun", base)
+	write(backupDir, "Saves/SFC/Chrono Trigger.srm", "chrono", base)
+	// played on the device since the backup
+	write(deviceDir, "Saves/SFC/Zelda.srm", "zelda 2", base.Add(time.Hour))
+	write(backupDir, "Saves/SFC/Zelda.srm", "zelda 1", base)
+	// restored to a card, then played on another device and backed up from there
+	write(deviceDir, "Saves/GB/Tetris.sav", "tetris 1", base)
+	write(backupDir, "Saves/GB/Tetris.sav", "tetris 2", base.Add(time.Hour))
+	// the same save, with FAT's rounding of the time, or copied by hand at another time
+	write(deviceDir, "Saves/PS/epsxe000.mcr", "card", base.Add(time.Second))
+	write(backupDir, "Saves/PS/epsxe000.mcr", "card", base)
+	write(deviceDir, "Saves/NES/Metroid.sav", "metroid", base.Add(24*time.Hour))
+	write(backupDir, "Saves/NES/Metroid.sav", "metroid", base)
+	// differing, but saved at the same time
+	write(deviceDir, "Saves/NES/Kirby.sav", "kirby a", base)
+	write(backupDir, "Saves/NES/Kirby.sav", "kirby b", base)
+	// not saves, or hidden
+	write(deviceDir, "Roms/SFC/Zelda.sfc", "rom", base)
+	write(deviceDir, ".romcopy_trash/20260901T080000Z/Saves/SFC/Zelda.srm", "zelda 0", base)
+	write(backupDir, "Saves/SFC/.Zelda.srm.1a2b3c4d.romcopy-part", "partial", base)
+
+	changes, err := Plan(mem, deviceDir, backupDir)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	expected := []Change{
+		{RelPath: filepath.FromSlash("Saves/GB/Tetris.sav"), Direction: ToDevice, Replaces: true, ModTime: base.Add(time.Hour)},
+		{RelPath: filepath.FromSlash("Saves/GBA/Golden Sun.sav"), Direction: ToBackup, ModTime: base},
+		{RelPath: filepath.FromSlash("Saves/NES/Kirby.sav"), Direction: ToBackup, Replaces: true, ModTime: base},
+		{RelPath: filepath.FromSlash("Saves/SFC/Chrono Trigger.srm"), Direction: ToDevice, ModTime: base},
+		{RelPath: filepath.FromSlash("Saves/SFC/Zelda.srm"), Direction: ToBackup, Replaces: true, ModTime: base.Add(time.Hour)},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Plan() = %+v, want %+v", changes, expected)
+	}
+
+	// nothing is backed up yet
+	changes, err = Plan(mem, deviceDir, filepath.Join(t.TempDir(), "new backup"))
+	if err != nil || len(changes) != 6 {
+		t.Errorf("Plan() to a new backup = %+v, %v; want every save on the device backed up", changes, err)
+	}
+}
@@ -0,0 +1,337 @@
+package screenscraper
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// the name ScreenScraper is told the requests come from
+const softwareName = "ROMCopyEngine"
+
+// where ScreenScraper's API lives
+const defaultBaseURL = "https://api.screenscraper.fr/api2"
+
+// Credentials are what ScreenScraper asks of each request: the developer credentials it issues to each program
+// using its API, and optionally a ScreenScraper account, whose quota and thread limits are used instead of the
+// shared anonymous ones
+type Credentials struct {
+	DevID       string `yaml:"devID"`
+	DevPassword string `yaml:"devPassword"`
+	User        string `yaml:"user"`
+	Password    string `yaml:"password"`
+}
+
+// LoadCredentials reads credentials from a YAML file laid out like Credentials, e.g. 'devID: ...'
+func LoadCredentials(filePath string) (Credentials, error) {
+	var creds Credentials
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return creds, fmt.Errorf("unable to read ScreenScraper credentials %s: %w", filePath, err)
+	}
+	if err := yaml.Unmarshal(data, &creds); err != nil {
+		return creds, fmt.Errorf("invalid ScreenScraper credentials %s: %w", filePath, err)
+	}
+	if creds.DevID == "" || creds.DevPassword == "" {
+		return creds, fmt.Errorf("ScreenScraper credentials %s need a 'devID' and 'devPassword'", filePath)
+	}
+	if (creds.User == "") != (creds.Password == "") {
+		return creds, fmt.Errorf("ScreenScraper credentials %s give a 'user' or 'password' without the other", filePath)
+	}
+	return creds, nil
+}
+
+// Rom is a file to look up, identified as ScreenScraper indexes files: by its hashes, name, and size
+type Rom struct {
+	// the system ID ScreenScraper gives the platform, e.g. 4 for the Super Nintendo
+	SystemID int
+	// the file name, e.g. 'Super Metroid (Japan, USA) (En,Ja).sfc'
+	Name string
+	Size int64
+	// lowercase hex
+	CRC  string
+	MD5  string
+	SHA1 string
+}
+
+// HashRom reads r to the end, returning it as a rom named name on the system systemID
+func HashRom(r io.Reader, systemID int, name string) (Rom, error) {
+	crc, md5Sum, sha1Sum := crc32.NewIEEE(), md5.New(), sha1.New()
+	size, err := io.Copy(io.MultiWriter(crc, md5Sum, sha1Sum), r)
+	if err != nil {
+		return Rom{}, err
+	}
+	return Rom{
+		SystemID: systemID,
+		Name:     name,
+		Size:     size,
+		CRC:      hex.EncodeToString(crc.Sum(nil)),
+		MD5:      hex.EncodeToString(md5Sum.Sum(nil)),
+		SHA1:     hex.EncodeToString(sha1Sum.Sum(nil)),
+	}, nil
+}
+
+// Text is a name, description, or date ScreenScraper gives in several regions or languages
+type Text struct {
+	// a ScreenScraper region code, e.g. 'us', 'eu', 'jp', 'wor', or 'ss' for ScreenScraper's own pick
+	Region string `json:"region"`
+	// a two-letter language code, e.g. 'en'
+	Language string `json:"langue"`
+	Text     string `json:"text"`
+}
+
+// Media is an image or video of a game
+type Media struct {
+	// e.g. 'box-2D' for front box art, 'ss' for a screenshot
+	Type   string `json:"type"`
+	Region string `json:"region"`
+	URL    string `json:"url"`
+	// the file extension, without the dot, e.g. 'png'
+	Format string `json:"format"`
+}
+
+// Game is what ScreenScraper knows about a game
+type Game struct {
+	ID        string `json:"id"`
+	Names     []Text `json:"noms"`
+	Synopses  []Text `json:"synopsis"`
+	Dates     []Text `json:"dates"`
+	Developer struct {
+		Text string `json:"text"`
+	} `json:"developpeur"`
+	Publisher struct {
+		Text string `json:"text"`
+	} `json:"editeur"`
+	Genres []struct {
+		Names []Text `json:"noms"`
+	} `json:"genres"`
+	Media []Media `json:"medias"`
+}
+
+// the regions a game's details are picked from after those the caller prefers
+var fallbackRegions = []string{"wor", "us", "eu", "jp", "ss"}
+
+// No-Intro and Redump region names ScreenScraper codes differently than by lowercasing them, by lowercase name
+var regionCodes = map[string]string{
+	"usa": "us", "europe": "eu", "japan": "jp", "world": "wor", "germany": "de", "france": "fr", "spain": "sp",
+	"italy": "it", "korea": "kr", "brazil": "br", "australia": "au", "china": "cn", "netherlands": "nl",
+	"sweden": "se", "uk": "uk", "canada": "ca", "taiwan": "tw", "asia": "asi",
+}
+
+// RegionCodes returns the ScreenScraper codes of regions as file names tag them, e.g. 'us' for 'USA', leaving out
+// those it doesn't know
+func RegionCodes(regions []string) []string {
+	codes := make([]string, 0, len(regions))
+	for _, region := range regions {
+		if code, ok := regionCodes[strings.ToLower(region)]; ok {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// the text of texts in the first of regions, then of the fallback regions, that has one; failing that, the first
+func pickRegion(texts []Text, regions []string) string {
+	for _, region := range append(append([]string{}, regions...), fallbackRegions...) {
+		for _, text := range texts {
+			if strings.EqualFold(text.Region, region) && strings.TrimSpace(text.Text) != "" {
+				return strings.TrimSpace(text.Text)
+			}
+		}
+	}
+	if len(texts) > 0 {
+		return strings.TrimSpace(texts[0].Text)
+	}
+	return ""
+}
+
+// the text of texts in language, or English, or failing that, the first
+func pickLanguage(texts []Text, language string) string {
+	for _, lang := range []string{language, "en"} {
+		for _, text := range texts {
+			if strings.EqualFold(text.Language, lang) && strings.TrimSpace(text.Text) != "" {
+				return strings.TrimSpace(text.Text)
+			}
+		}
+	}
+	if len(texts) > 0 {
+		return strings.TrimSpace(texts[0].Text)
+	}
+	return ""
+}
+
+// Name returns the game's name in the first of regions (ScreenScraper codes, e.g. 'us') it has one for
+func (g Game) Name(regions []string) string {
+	return pickRegion(g.Names, regions)
+}
+
+// Synopsis returns the game's description in language, e.g. 'en'
+func (g Game) Synopsis(language string) string {
+	return pickLanguage(g.Synopses, language)
+}
+
+// Genre returns the game's genres in language, separated by ', '
+func (g Game) Genre(language string) string {
+	genres := make([]string, 0, len(g.Genres))
+	for _, genre := range g.Genres {
+		if name := pickLanguage(genre.Names, language); name != "" {
+			genres = append(genres, name)
+		}
+	}
+	return strings.Join(genres, ", ")
+}
+
+// Released returns when the game was released in the first of regions it has a date for; zero if it has none.
+// ScreenScraper gives some dates only to the year or month.
+func (g Game) Released(regions []string) time.Time {
+	date := pickRegion(g.Dates, regions)
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if released, err := time.Parse(layout, date); err == nil {
+			return released
+		}
+	}
+	return time.Time{}
+}
+
+// BoxArt returns the game's front box art in the first of regions it has it for
+func (g Game) BoxArt(regions []string) (Media, bool) {
+	boxes := make([]Text, 0)
+	byURL := make(map[string]Media)
+	for _, media := range g.Media {
+		if media.Type == "box-2D" && media.URL != "" {
+			boxes = append(boxes, Text{Region: media.Region, Text: media.URL})
+			byURL[media.URL] = media
+		}
+	}
+	media, ok := byURL[pickRegion(boxes, regions)]
+	return media, ok
+}
+
+// the parts of a jeuInfos response Lookup reads
+type response struct {
+	Response struct {
+		Game Game `json:"jeu"`
+	} `json:"response"`
+}
+
+// Client looks games up on ScreenScraper, one request at a time as its thread limits ask. Once a request fails,
+// e.g. because the daily quota is used up or the credentials were refused, every request after it fails the same
+// way without being made; see Err.
+type Client struct {
+	creds   Credentials
+	baseURL string
+	http    *http.Client
+	err     error
+}
+
+// NewClient returns a client making requests with creds
+func NewClient(creds Credentials) *Client {
+	return &Client{creds: creds, baseURL: defaultBaseURL, http: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// Err returns the error that stopped the client, or nil if it hasn't stopped
+func (c *Client) Err() error {
+	return c.err
+}
+
+// Lookup finds the game rom is, returning false if ScreenScraper doesn't know it
+func (c *Client) Lookup(rom Rom) (Game, bool, error) {
+	query := c.query()
+	query.Set("output", "json")
+	query.Set("romtype", "rom")
+	query.Set("systemeid", strconv.Itoa(rom.SystemID))
+	query.Set("romnom", rom.Name)
+	query.Set("romtaille", strconv.FormatInt(rom.Size, 10))
+	query.Set("crc", rom.CRC)
+	query.Set("md5", rom.MD5)
+	query.Set("sha1", rom.SHA1)
+
+	body, found, err := c.get(c.baseURL + "/jeuInfos.php?" + query.Encode())
+	if err != nil || !found {
+		return Game{}, false, err
+	}
+	var parsed response
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		c.err = fmt.Errorf("unexpected response from ScreenScraper for %s: %w", rom.Name, err)
+		return Game{}, false, c.err
+	}
+	return parsed.Response.Game, parsed.Response.Game.ID != "", nil
+}
+
+// Download fetches a media file
+func (c *Client) Download(media Media) ([]byte, error) {
+	body, found, err := c.get(media.URL)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("ScreenScraper has no file at %s", media.URL)
+	}
+	return body, nil
+}
+
+// the credentials every request carries
+func (c *Client) query() url.Values {
+	query := url.Values{}
+	query.Set("devid", c.creds.DevID)
+	query.Set("devpassword", c.creds.DevPassword)
+	query.Set("softname", softwareName)
+	if c.creds.User != "" {
+		query.Set("ssid", c.creds.User)
+		query.Set("sspassword", c.creds.Password)
+	}
+	return query
+}
+
+// fetches address, returning false if ScreenScraper has nothing there
+func (c *Client) get(address string) ([]byte, bool, error) {
+	if c.err != nil {
+		return nil, false, c.err
+	}
+	resp, err := c.http.Get(address)
+	if err != nil {
+		// the error names the address, which carries the credentials
+		c.err = fmt.Errorf("unable to reach ScreenScraper: %w", redact(err))
+		return nil, false, c.err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.err = fmt.Errorf("failed to read ScreenScraper's response: %w", redact(err))
+		return nil, false, c.err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return body, true, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, false, nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		c.err = fmt.Errorf("ScreenScraper refused the credentials: %s", strings.TrimSpace(string(body)))
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == 430 || resp.StatusCode == 431:
+		c.err = fmt.Errorf("ScreenScraper's request limits were reached, try again later: %s", strings.TrimSpace(string(body)))
+	default:
+		c.err = fmt.Errorf("ScreenScraper answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil, false, c.err
+}
+
+// strips the address, and so the credentials in it, from a failed request's error
+func redact(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
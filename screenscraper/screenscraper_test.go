@@ -0,0 +1,154 @@
+package screenscraper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const zeldaResponse = `{
+	"header": {"success": "true"},
+	"response": {
+		"jeu": {
+			"id": "3052",
+			"noms": [
+				{"region": "ss", "text": "Zelda no Densetsu: Kamigami no Triforce"},
+				{"region": "us", "text": "The Legend of Zelda: A Link to the Past"}
+			],
+			"synopsis": [
+				{"langue": "fr", "text": "Link doit sauver Hyrule."},
+				{"langue": "en", "text": "Link must save Hyrule."}
+			],
+			"dates": [{"region": "us", "text": "1992-04-13"}, {"region": "jp", "text": "1991"}],
+			"developpeur": {"id": "1", "text": "Nintendo EAD"},
+			"editeur": {"id": "2", "text": "Nintendo"},
+			"genres": [
+				{"noms": [{"langue": "en", "text": "Action"}, {"langue": "fr", "text": "Action"}]},
+				{"noms": [{"langue": "en", "text": "Adventure"}, {"langue": "fr", "text": "Aventure"}]}
+			],
+			"medias": [
+				{"type": "ss", "region": "wor", "url": "SERVER/ss.png", "format": "png"},
+				{"type": "box-2D", "region": "jp", "url": "SERVER/box-jp.png", "format": "png"},
+				{"type": "box-2D", "region": "us", "url": "SERVER/box-us.png", "format": "png"}
+			]
+		}
+	}
+}`
+
+func TestLoadCredentials(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"developer only", "devID: dev\ndevPassword: secret\n", false},
+		{"with an account", "devID: dev\ndevPassword: secret\nuser: me\npassword: hunter2\n", false},
+		{"no developer password", "devID: dev\n", true},
+		{"user without a password", "devID: dev\ndevPassword: secret\nuser: me\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".yaml")
+			if err := os.WriteFile(filePath, []byte(tt.yaml), 0600); err != nil {
+				t.Fatalf("failed to create credentials: %v", err)
+			}
+			if _, err := LoadCredentials(filePath); (err != nil) != tt.wantErr {
+				t.Errorf("LoadCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashRom(t *testing.T) {
+	rom, err := HashRom(strings.NewReader("abc"), 4, "abc.sfc")
+	if err != nil {
+		t.Fatalf("HashRom() error = %v", err)
+	}
+	want := Rom{SystemID: 4, Name: "abc.sfc", Size: 3, CRC: "352441c2", MD5: "900150983cd24fb0d6963f7d28e17f72", SHA1: "a9993e364706816aba3e25717850c26c9cd0d89d"}
+	if rom != want {
+		t.Errorf("HashRom() = %+v, want %+v", rom, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.URL.Path == "/box-us.png":
+			w.Write([]byte("art"))
+		case query.Get("devid") != "dev" || query.Get("ssid") != "me":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Erreur de login"))
+		case query.Get("sha1") == "quota":
+			w.WriteHeader(430)
+			w.Write([]byte("Quota exceeded"))
+		case query.Get("crc") == "352441c2" && query.Get("systemeid") == "4":
+			w.Write([]byte(strings.ReplaceAll(zeldaResponse, "SERVER", server.URL)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("Erreur : Rom/Iso/Dossier non trouvée !"))
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(Credentials{DevID: "dev", DevPassword: "secret", User: "me", Password: "hunter2"})
+	client.baseURL = server.URL
+
+	rom, _ := HashRom(strings.NewReader("abc"), 4, "Zelda (USA).sfc")
+	game, found, err := client.Lookup(rom)
+	if err != nil || !found {
+		t.Fatalf("Lookup() = %v, %v; want the game", found, err)
+	}
+	regions := RegionCodes([]string{"USA"})
+	if got := game.Name(regions); got != "The Legend of Zelda: A Link to the Past" {
+		t.Errorf("Name() = %q", got)
+	}
+	if got := game.Name(nil); got != "The Legend of Zelda: A Link to the Past" {
+		t.Errorf("Name() with no regions = %q, want the worldwide or US name", got)
+	}
+	if got := game.Synopsis("fr"); got != "Link doit sauver Hyrule." {
+		t.Errorf("Synopsis(\"fr\") = %q", got)
+	}
+	if got := game.Genre("en"); got != "Action, Adventure" {
+		t.Errorf("Genre(\"en\") = %q", got)
+	}
+	if got := game.Released([]string{"jp"}); !got.Equal(time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Released([jp]) = %v, want 1991", got)
+	}
+	if game.Developer.Text != "Nintendo EAD" || game.Publisher.Text != "Nintendo" {
+		t.Errorf("developer, publisher = %q, %q", game.Developer.Text, game.Publisher.Text)
+	}
+	art, ok := game.BoxArt(regions)
+	if !ok || art.Region != "us" {
+		t.Fatalf("BoxArt() = %+v, %v; want the US box", art, ok)
+	}
+	if data, err := client.Download(art); err != nil || string(data) != "art" {
+		t.Errorf("Download() = %q, %v", data, err)
+	}
+
+	unknown, _ := HashRom(strings.NewReader("unknown"), 4, "Unknown.sfc")
+	if _, found, err := client.Lookup(unknown); found || err != nil {
+		t.Errorf("Lookup(unknown) = %v, %v; want not found", found, err)
+	}
+
+	// a used up quota stops the client
+	if _, _, err := client.Lookup(Rom{SystemID: 4, SHA1: "quota"}); err == nil || client.Err() == nil {
+		t.Fatalf("Lookup() past the quota = %v, want an error", err)
+	}
+	if _, _, err := client.Lookup(rom); err != client.Err() {
+		t.Errorf("Lookup() after stopping = %v, want %v", err, client.Err())
+	}
+
+	refused := NewClient(Credentials{DevID: "other", DevPassword: "secret"})
+	refused.baseURL = server.URL
+	if _, _, err := refused.Lookup(rom); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("Lookup() with refused credentials = %v, want an error without the password", err)
+	}
+}
@@ -679,10 +679,188 @@ class ROMCopyEngineTest(unittest.TestCase):
                 source_struct=source_struct,
                 dest_struct=destination_struct,
                 expected_struct=expected_struct,
-                options="--mapping snes:snes --rename gameslist.xml:miyoogamelist.xml --rename images:Imgs --rename saves:SaveData",
+                options="--mapping snes:snes --rename gameslist.xml:miyoogamelist.xml --rename images:Imgs --rename saves:SaveData --copyEmulatorArtifacts",
             )
         )
 
+    def test_rename_leaves_saves_on_target(self):
+        """Test that --rename leaves the save data already on the target where it is."""
+        source_struct = [
+            {"path": "snes/images/game.png"},
+        ]
+
+        destination_struct = [
+            {"path": "snes/saves/save.srm", "contents": "80 hours"},
+        ]
+
+        expected_struct = [
+            {"path": "snes", "is_dir": True},
+            {"path": "snes/Imgs", "is_dir": True},
+            {"path": "snes/Imgs/game.png"},
+            {"path": "snes/saves", "is_dir": True},
+            {"path": "snes/saves/save.srm", "contents": "80 hours"},
+        ]
+
+        self.run_copy_test(
+            TestFixture(
+                source_struct=source_struct,
+                dest_struct=destination_struct,
+                expected_struct=expected_struct,
+                options="--mapping snes:snes --rename images:Imgs --rename saves:SaveData",
+            )
+        )
+
+
+    def test_skips_emulator_artifacts(self):
+        """Test that emulator saves, states, and screenshots folders are skipped by default."""
+        source_struct = [
+            {"path": "snes/game.sfc"},
+            {"path": "snes/saves/game.srm"},
+            {"path": "snes/States/game.state1"},
+            {"path": "snes/screenshots/game.png"},
+        ]
+
+        destination_struct = [
+            {"path": "snes", "is_dir": True},
+        ]
+
+        expected_struct = [
+            {"path": "snes", "is_dir": True},
+            {"path": "snes/game.sfc"},
+        ]
+
+        self.run_copy_test(
+            TestFixture(
+                source_struct=source_struct,
+                dest_struct=destination_struct,
+                expected_struct=expected_struct,
+                options="--mapping snes:snes",
+            )
+        )
+
+    def test_per_game_folders_with_max_path_length(self):
+        """Test that --maxPathLength shortens a game's folder along with the game in it."""
+        source_struct = [
+            {"path": "snes/Alpha (World) [b].sfc"},
+            {"path": "snes/Beta (USA).sfc"},
+        ]
+
+        destination_struct = [
+            {"path": "SFC", "is_dir": True},
+        ]
+
+        expected_struct = [
+            {"path": "SFC", "is_dir": True},
+            {"path": "SFC/Alp (World) [b]", "is_dir": True},
+            {"path": "SFC/Alp (World) [b]/Alp (World) [b].sfc"},
+            {"path": "SFC/Beta (USA)", "is_dir": True},
+            {"path": "SFC/Beta (USA)/Beta (USA).sfc"},
+        ]
+
+        self.run_copy_test(
+            TestFixture(
+                source_struct=source_struct,
+                dest_struct=destination_struct,
+                expected_struct=expected_struct,
+                options="--mapping snes:SFC --perGameFolders --maxPathLength 40",
+            )
+        )
+
+    def test_per_game_folders_with_loopback_copy(self):
+        """Test that --loopbackCopy copies into the same game folders as the first pass."""
+        source_struct = [
+            {"path": "snes/Alpha (USA).sfc"},
+            {"path": "snes/Beta (USA).sfc"},
+        ]
+
+        destination_struct = [
+            {"path": "SFC", "is_dir": True},
+        ]
+
+        expected_struct = [
+            {"path": "SFC", "is_dir": True},
+            {"path": "SFC/Alpha (USA)", "is_dir": True},
+            {"path": "SFC/Alpha (USA)/Alpha (USA).sfc"},
+            {"path": "SFC/Beta (USA)", "is_dir": True},
+            {"path": "SFC/Beta (USA)/Beta (USA).sfc"},
+        ]
+
+        self.run_copy_test(
+            TestFixture(
+                source_struct=source_struct,
+                dest_struct=destination_struct,
+                expected_struct=expected_struct,
+                options="--mapping snes:SFC --perGameFolders --loopbackCopy",
+            )
+        )
+
+    def test_prefer_revision_with_loopback_copy(self):
+        """Test that --loopbackCopy doesn't copy back releases the first pass left out."""
+        source_struct = [
+            {"path": "snes/Zelda (USA).sfc"},
+            {"path": "snes/Zelda (USA) (Rev 1).sfc"},
+        ]
+
+        destination_struct = [
+            {"path": "snes", "is_dir": True},
+        ]
+
+        expected_struct = [
+            {"path": "snes", "is_dir": True},
+            {"path": "snes/Zelda (USA).sfc"},
+        ]
+
+        self.run_copy_test(
+            TestFixture(
+                source_struct=source_struct,
+                dest_struct=destination_struct,
+                expected_struct=expected_struct,
+                options="--mapping snes:snes --preferRevision earliest --loopbackCopy",
+            )
+        )
+
+    def test_verify_after_pruning_gamelist(self):
+        """Test that a copy which prunes its game list still verifies against the source."""
+        gamelist = (
+            "<gameList>\n"
+            "  <game>\n    <path>./game.sfc</path>\n    <name>Game</name>\n  </game>\n"
+            "  <game>\n    <path>./missing.sfc</path>\n    <name>Missing</name>\n  </game>\n"
+            "</gameList>"
+        )
+        source_struct = [
+            {"path": "snes/game.sfc", "contents": "rom"},
+            {"path": "snes/gamelist.xml", "contents": gamelist},
+        ]
+        self.create_files_folders(self.source_temp_folder, source_struct)
+
+        copied = self.execute_rom_copy_engine(
+            self.source_temp_folder, self.destination_temp_folder, "--mapping snes:snes"
+        )
+        self.assertEqual(copied.returncode, 0, f"copy failed:\n{copied.stdout}\n{copied.stderr}")
+        with open(os.path.join(self.destination_temp_folder, "snes", "gamelist.xml")) as f:
+            self.assertNotIn("missing.sfc", f.read())
+
+        verified = self.execute_rom_copy_engine(
+            self.source_temp_folder, self.destination_temp_folder, "verify --mapping snes:snes"
+        )
+        self.assertEqual(verified.returncode, 0, f"verify failed:\n{verified.stdout}\n{verified.stderr}")
+
+    def test_deterministic_target_state(self):
+        """Test that two --deterministic builds record byte-identical state files."""
+        self.create_files_folders(self.source_temp_folder, [{"path": "snes/game.sfc", "contents": "rom"}])
+
+        with tempfile.TemporaryDirectory() as other_destination:
+            states = []
+            for destination in (self.destination_temp_folder, other_destination):
+                copied = self.execute_rom_copy_engine(
+                    self.source_temp_folder, destination, "--mapping snes:snes --deterministic --targetState"
+                )
+                self.assertEqual(copied.returncode, 0, f"copy failed:\n{copied.stdout}\n{copied.stderr}")
+                state_dir = os.path.join(destination, ".romcopyengine")
+                with open(os.path.join(state_dir, "state.json"), "rb") as f:
+                    states.append((f.read(), os.stat(state_dir).st_mtime))
+
+            self.assertEqual(states[0], states[1])
 
 if __name__ == "__main__":
     unittest.main()
@@ -0,0 +1,137 @@
+package thumbnails
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jkingsman/ROMCopyEngine/fsys"
+)
+
+// where the libretro-thumbnails repository is served from
+const defaultBaseURL = "https://thumbnails.libretro.com"
+
+// the repository's folder of front box art in each system's folder
+const boxArtDir = "Named_Boxarts"
+
+// how long a fetch the repository had nothing for is remembered before it's tried again, as art is added over time
+const missTTL = 30 * 24 * time.Hour
+
+// the least time between requests, to go easy on a server run for free
+const requestInterval = 500 * time.Millisecond
+
+// characters the repository replaces with '_' in the names of its files
+const unsafeChars = "&*/:`<>?\\|\""
+
+// DefaultCacheDir returns where fetched box art is cached in the user's cache directory
+func DefaultCacheDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to locate user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "ROMCopyEngine", "thumbnails"), nil
+}
+
+// SafeName returns the name the repository gives the image of a game named name, e.g. 'Tetris _ Dr. Mario (USA)'
+// for 'Tetris & Dr. Mario (USA)'
+func SafeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(unsafeChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// Fetcher fetches box art from the libretro-thumbnails repository, no faster than a request every half second,
+// caching what it fetches and what the repository didn't have. Once a request fails, every
+// fetch after it that isn't cached fails the same way without being made; see Err.
+type Fetcher struct {
+	baseURL string
+	// empty to cache nothing
+	cacheDir string
+	http     *http.Client
+	// the least time between requests, and when the last was made
+	interval time.Duration
+	last     time.Time
+	err      error
+}
+
+// NewFetcher returns a fetcher caching in cacheDir, or caching nothing if cacheDir is empty
+func NewFetcher(cacheDir string) *Fetcher {
+	return &Fetcher{baseURL: defaultBaseURL, cacheDir: cacheDir, http: &http.Client{Timeout: 60 * time.Second}, interval: requestInterval}
+}
+
+// Err returns the error that stopped the fetcher, or nil if it hasn't stopped
+func (f *Fetcher) Err() error {
+	return f.err
+}
+
+// BoxArt returns the PNG front box art of the game named name, e.g. 'Super Metroid (Japan, USA) (En,Ja)', from
+// the folder of the repository named system, e.g. 'Nintendo - Super Nintendo Entertainment System'. It returns
+// false if the repository has none.
+func (f *Fetcher) BoxArt(system string, name string) ([]byte, bool, error) {
+	cachePath := ""
+	if f.cacheDir != "" {
+		cachePath = filepath.Join(f.cacheDir, SafeName(system), boxArtDir, SafeName(name)+".png")
+		if data, err := os.ReadFile(cachePath); err == nil {
+			return data, true, nil
+		}
+		if info, err := os.Stat(cachePath + ".missing"); err == nil && time.Since(info.ModTime()) < missTTL {
+			return nil, false, nil
+		}
+	}
+
+	data, found, err := f.get(f.baseURL + "/" + url.PathEscape(system) + "/" + boxArtDir + "/" + url.PathEscape(SafeName(name)+".png"))
+	if err != nil {
+		return nil, false, err
+	}
+	if cachePath != "" {
+		// a cache that can't be written only costs a fetch next time
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err == nil {
+			if found {
+				_ = fsys.WriteFileAtomic(fsys.OS, cachePath, data)
+			} else {
+				_ = os.WriteFile(cachePath+".missing", nil, 0644)
+			}
+		}
+	}
+	return data, found, nil
+}
+
+// fetches address, returning false if the repository has nothing there
+func (f *Fetcher) get(address string) ([]byte, bool, error) {
+	if f.err != nil {
+		return nil, false, f.err
+	}
+	if wait := f.interval - time.Since(f.last); wait > 0 {
+		time.Sleep(wait)
+	}
+	f.last = time.Now()
+
+	resp, err := f.http.Get(address)
+	if err != nil {
+		f.err = fmt.Errorf("unable to reach libretro-thumbnails: %w", err)
+		return nil, false, f.err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		f.err = fmt.Errorf("failed to read from libretro-thumbnails: %w", err)
+		return nil, false, f.err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, true, nil
+	case http.StatusNotFound:
+		return nil, false, nil
+	}
+	f.err = fmt.Errorf("libretro-thumbnails answered %s", resp.Status)
+	return nil, false, f.err
+}
@@ -0,0 +1,71 @@
+package thumbnails
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Super Metroid (Japan, USA) (En,Ja)", "Super Metroid (Japan, USA) (En,Ja)"},
+		{"Tetris & Dr. Mario (USA)", "Tetris _ Dr. Mario (USA)"},
+		{"Kirby's Dream Course: Special? <1/2>", "Kirby's Dream Course_ Special_ _1_2_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SafeName(tt.name); got != tt.want {
+				t.Errorf("SafeName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoxArt(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/Nintendo - Super Nintendo Entertainment System/Named_Boxarts/Tetris _ Dr. Mario (USA).png":
+			w.Write([]byte("art"))
+		case "/Broken/Named_Boxarts/Game.png":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := NewFetcher(t.TempDir())
+	fetcher.baseURL, fetcher.interval = server.URL, 0
+	system := "Nintendo - Super Nintendo Entertainment System"
+
+	for i := 0; i < 2; i++ {
+		data, found, err := fetcher.BoxArt(system, "Tetris & Dr. Mario (USA)")
+		if err != nil || !found || string(data) != "art" {
+			t.Fatalf("BoxArt() = %q, %v, %v; want the art", data, found, err)
+		}
+		if _, found, err := fetcher.BoxArt(system, "Unknown (USA)"); found || err != nil {
+			t.Fatalf("BoxArt(unknown) = %v, %v; want nothing found", found, err)
+		}
+	}
+	// the second round came from the cache
+	if requests != 2 {
+		t.Errorf("made %d requests, want 2", requests)
+	}
+
+	// a server error stops the fetcher, but not what's cached
+	if _, _, err := fetcher.BoxArt("Broken", "Game"); err == nil || fetcher.Err() == nil {
+		t.Fatalf("BoxArt() from a failing server = %v, want an error", err)
+	}
+	if _, found, err := fetcher.BoxArt(system, "Tetris & Dr. Mario (USA)"); !found || err != nil {
+		t.Errorf("BoxArt() of cached art after stopping = %v, %v; want the art", found, err)
+	}
+	if _, _, err := fetcher.BoxArt(system, "Other (USA)"); err != fetcher.Err() {
+		t.Errorf("BoxArt() after stopping = %v, want %v", err, fetcher.Err())
+	}
+}